@@ -2,33 +2,98 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/adeesh/log-analytics/internal/auth"
 	"github.com/adeesh/log-analytics/internal/config"
 	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database"
 	"github.com/adeesh/log-analytics/internal/database/alert_rules"
 	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"github.com/adeesh/log-analytics/internal/database/audit"
+	"github.com/adeesh/log-analytics/internal/database/fingerprints"
 	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/maintenance"
+	"github.com/adeesh/log-analytics/internal/database/migrations"
+	"github.com/adeesh/log-analytics/internal/database/notifications"
+	"github.com/adeesh/log-analytics/internal/database/retention"
+	"github.com/adeesh/log-analytics/internal/database/saved_searches"
+	"github.com/adeesh/log-analytics/internal/database/scheduler"
+	"github.com/adeesh/log-analytics/internal/database/storage"
+	"github.com/adeesh/log-analytics/internal/database/tenants"
 	"github.com/adeesh/log-analytics/internal/handlers"
+	"github.com/adeesh/log-analytics/internal/kafka/consumers"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/migrator"
+	"github.com/adeesh/log-analytics/internal/parsers"
 	"github.com/adeesh/log-analytics/internal/services"
+	"github.com/adeesh/log-analytics/pkg/slogkafka"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Initialize logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	// Initialize logger, at the configured level once the configuration is loaded
+	logLevel := new(slog.LevelVar)
+	stdoutHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: logLevel,
+	})
+	logger := slog.New(stdoutHandler)
 
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration, from the config file given with -config, or CONFIG_FILE, under the environment
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Parse()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Log.Level)
+
+	// Publish the server's own logs to the log topic as well, so that its errors show up in dashboards and alerts
+	if err := cfg.SelfIngestion.Validate(); err != nil {
+		logger.Error("Invalid self-ingestion configuration", "error", err)
+		os.Exit(1)
+	}
+	var selfLogHandler *slogkafka.Handler
+	if cfg.SelfIngestion.Enabled {
+		host, _ := os.Hostname()
+		handler, err := slogkafka.New(cfg.Kafka.Brokers, slogkafka.Options{
+			Service:       cfg.SelfIngestion.Service,
+			Topic:         cfg.Kafka.Topic,
+			PriorityTopic: cfg.Kafka.PriorityTopic,
+			Level:         cfg.SelfIngestion.Level,
+			Host:          host,
+			// Access logs share their message, so aggregating identical messages would fold all requests into summaries
+			AggregateWindow: -1,
+		})
+		if err != nil {
+			logger.Error("Failed to initialize self-ingestion", "error", err)
+			os.Exit(1)
+		}
+		selfLogHandler = handler
+		logger = slog.New(slogkafka.NewFanout(stdoutHandler, selfLogHandler))
+	}
+
+	// Components register how they stop as they are created; they are stopped stage by stage on shutdown
+	shutdown := services.NewShutdownCoordinator([]string{
+		constants.ShutdownStageHTTP,
+		constants.ShutdownStageWorkers,
+		constants.ShutdownStageNotifications,
+		constants.ShutdownStageFlush,
+		constants.ShutdownStageClose,
+	}, cfg.Server.ShutdownTimeout, logger)
+
+	// Bring the schema up to date first, when configured to
+	if err := migrator.RunOnStartup(cfg, logger); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize database
 	db, err := database.NewGormDB(&cfg.Database)
@@ -36,60 +101,337 @@ func main() {
 		logger.Error("Failed to initialize database", "error", err)
 		os.Exit(1)
 	}
-	defer db.Close()
+	shutdown.Register(constants.ShutdownStageClose, "database", constants.ShutdownCloseTimeout, func(context.Context) error {
+		return db.Close()
+	})
 
 	// Create repositories
+	if err := cfg.LogStore.Validate(&cfg.Routing); err != nil {
+		logger.Error("Invalid log store configuration", "error", err)
+		os.Exit(1)
+	}
 	logRepo := logs.NewLogRepository(db)
+	logQuerier := logs.NewLogQuerier(db)
+	logStorage := logs.NewLogStorage(db)
+	if cfg.LogStore.Backend == constants.LogStoreClickHouse {
+		clickHouseLogs, err := logs.NewClickHouseLogRepository(context.Background(), &cfg.LogStore.ClickHouse)
+		if err != nil {
+			logger.Error("Failed to initialize ClickHouse log store", "error", err)
+			os.Exit(1)
+		}
+		shutdown.Register(constants.ShutdownStageClose, "clickhouse", constants.ShutdownCloseTimeout, func(context.Context) error {
+			return clickHouseLogs.Close()
+		})
+		logRepo = clickHouseLogs
+		logQuerier = clickHouseLogs
+		logStorage = clickHouseLogs
+	}
+	if len(cfg.Routing.Rules) > 0 {
+		shards, err := logs.NewShardedLogRepository(context.Background(), db, &cfg.Database, &cfg.Routing)
+		if err != nil {
+			logger.Error("Failed to initialize log routing", "error", err)
+			os.Exit(1)
+		}
+		shutdown.Register(constants.ShutdownStageClose, "log shards", constants.ShutdownCloseTimeout, func(context.Context) error {
+			return shards.Close()
+		})
+		logRepo = shards
+		logQuerier = shards
+		logStorage = shards
+	}
 	alertRepo := alerts.NewAlertRepository(db.GetDB())
 	alertRuleRepo := alert_rules.NewAlertRuleRepository(db.GetDB())
+	storageRepo := storage.NewStorageRepository(db.GetDB())
+	maintenanceRepo := maintenance.NewMaintenanceRepository(db.GetDB())
+	notificationRepo := notifications.NewNotificationRepository(db.GetDB())
+	retentionRepo := retention.NewRetentionRepository(db.GetDB())
+	fingerprintRepo := fingerprints.NewFingerprintRepository(db.GetDB())
+	migrationRepo := migrations.NewMigrationRepository(db.GetDB())
+	auditRepo := audit.NewAuditRepository(db.GetDB())
+	schedulerRepo := scheduler.NewSchedulerRepository(db.GetDB())
+	savedSearchRepo := saved_searches.NewSavedSearchRepository(db.GetDB())
+	tenantRepo := tenants.NewTenantRepository(db.GetDB())
+
+	// Create services
+	storageService := services.NewStorageService(storageRepo, logStorage, cfg.Storage)
+	logFeed := services.NewLogFeed(logRepo, logger)
+
+	// The live tail follows the stored logs the processor publishes to the stream topic
+	var logStream *services.LogStream
+	var logStreamConsumer *consumers.LogStreamConsumer
+	if cfg.Kafka.StreamTopic != "" {
+		logStream = services.NewLogStream()
+		logStreamConsumer, err = consumers.NewLogStreamConsumer(&cfg.Kafka, logStream, logger)
+		if err != nil {
+			logger.Error("Failed to initialize live tail", "error", err)
+			os.Exit(1)
+		}
+		shutdown.Register(constants.ShutdownStageFlush, "live tail consumer", constants.ShutdownFlushTimeout, func(context.Context) error {
+			return logStreamConsumer.Close()
+		})
+	}
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo, cfg.Maintenance, logger)
+	if err := maintenanceService.Refresh(context.Background()); err != nil {
+		logger.Error("Failed to load maintenance state", "error", err)
+		os.Exit(1)
+	}
+	deadLetterService := services.NewDeadLetterService(&cfg.Kafka, logger)
+	shutdown.Register(constants.ShutdownStageFlush, "dead-letter client", constants.ShutdownFlushTimeout, func(context.Context) error {
+		return deadLetterService.Close()
+	})
+	exportService, err := services.NewComplianceExportService(logRepo, &cfg.Export, logger)
+	if err != nil {
+		logger.Error("Failed to initialize compliance export", "error", err)
+		os.Exit(1)
+	}
+
+	notificationService, err := services.NewNotificationService(notificationRepo, cfg.Notification, logger)
+	if err != nil {
+		logger.Error("Failed to initialize notifications", "error", err)
+		os.Exit(1)
+	}
+	shutdown.Register(constants.ShutdownStageNotifications, "alert notifications", constants.ShutdownNotificationTimeout, notificationService.Shutdown)
+
+	// Lag of the processor's consumer groups, checked periodically for metrics and the lag alert
+	kafkaLagService := services.NewKafkaLagService(&cfg.Kafka, notificationService, logger)
+	shutdown.Register(constants.ShutdownStageFlush, "kafka lag client", constants.ShutdownFlushTimeout, func(context.Context) error {
+		return kafkaLagService.Close()
+	})
+
+	authProvider, err := auth.NewProvider(&cfg.Auth)
+	if err != nil {
+		logger.Error("Failed to initialize authentication", "error", err)
+		os.Exit(1)
+	}
+	serviceTokens := auth.NewServiceTokens(cfg.Auth.ServiceTokens)
+	scopeLimiter := auth.NewScopeLimiter(map[string]config.ScopeLimits{
+		constants.ScopeLogsTail:   cfg.Auth.TailLimits,
+		constants.ScopeLogsExport: cfg.Auth.ExportLimits,
+	})
+	if err := cfg.Alert.Validate(); err != nil {
+		logger.Error("Invalid alert configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := cfg.Server.Validate(); err != nil {
+		logger.Error("Invalid server configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := cfg.Dashboard.Validate(); err != nil {
+		logger.Error("Invalid dashboard configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Default dashboard queries are cached, and precomputed before the server reports ready
+	dashboardCache := services.NewDashboardCache(logRepo, alertRepo, cfg.Dashboard, logger)
 
 	// Create handlers
-	logHandler := handlers.NewLogHandler(logRepo, logger)
-	alertHandler := handlers.NewAlertHandler(alertRepo, logger)
-	alertRuleHandler := handlers.NewAlertRuleHandler(alertRuleRepo, logger)
-	healthHandler := handlers.NewHealthHandler(db, logger)
+	logHandler := handlers.NewLogHandler(logRepo, dashboardCache, logger)
+	storageHandler := handlers.NewStorageHandler(storageService, logger)
+	exportHandler := handlers.NewExportHandler(exportService, logger)
+	deadLetterHandler := handlers.NewDeadLetterHandler(deadLetterService, logger)
+	kafkaLagHandler := handlers.NewKafkaLagHandler(kafkaLagService, logger)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService, logger)
+	authHandler := handlers.NewAuthHandler(authProvider, serviceTokens, scopeLimiter, auditRepo, logger)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo, notificationService, logger)
+	retentionHandler := handlers.NewRetentionHandler(retentionRepo, logger)
+	migrationHandler := handlers.NewMigrationHandler(migrationRepo, logger)
+	schedulerHandler := handlers.NewSchedulerHandler(schedulerRepo, logger)
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchRepo, logRepo, logger)
+	tenantHandler := handlers.NewTenantHandler(tenantRepo, logger)
+	logPollHandler := handlers.NewLogPollHandler(logRepo, logFeed, cfg.Server.WriteTimeout-constants.LogPollWriteMargin, logger)
+	logStreamHandler := handlers.NewLogStreamHandler(logStream, logger)
+	readinessHandler := handlers.NewReadinessHandler(db, dashboardCache)
 
 	// Create alert service
-	sqlDB, err := db.GetSQLDB()
+	alertEvents := services.NewAlertEventBus()
+	alertService := services.NewAlertService(alertRuleRepo, alertRepo, logQuerier, notificationService, alertEvents, logger)
+	alertHandler := handlers.NewAlertHandler(alertRepo, alertService, dashboardCache, logger)
+	alertRuleHandler := handlers.NewAlertRuleHandler(alertRuleRepo, alertService, cfg.Alert.CanaryPeriod, logger)
+	alertEventsHandler := handlers.NewAlertEventsHandler(alertEvents, cfg.Server.CORS.AllowedOrigins, logger)
+	healthHandler := handlers.NewHealthHandler(db, maintenanceService, alertService, logger)
+	alertRetentionService, err := services.NewAlertRetentionService(alertRepo, maintenanceService, cfg.Alert, logger)
+	if err != nil {
+		logger.Error("Failed to initialize alert retention", "error", err)
+		os.Exit(1)
+	}
+	logRetentionService, err := services.NewLogRetentionService(logRepo, retentionRepo, maintenanceService, cfg.Retention, logger)
+	if err != nil {
+		logger.Error("Failed to initialize log retention", "error", err)
+		os.Exit(1)
+	}
+
+	// Periodic tasks run on a single API server at a time, whichever holds the task's lease
+	taskScheduler := services.NewScheduler(schedulerRepo, cfg.Scheduler, logger)
+	taskScheduler.Register(constants.TaskLogRetention, cfg.Retention.Interval, logRetentionService.Purge)
+	if alertRetentionService.Enabled() {
+		taskScheduler.Register(constants.TaskAlertRetention, cfg.Alert.RetentionInterval, alertRetentionService.Prune)
+	}
+
+	// Backfills recompute fingerprints under the same rules the processor applies to new logs
+	fingerprintMasks, err := cfg.Pipeline.LoadFingerprintMasks()
+	if err != nil {
+		logger.Error("Failed to load fingerprint rules", "error", err)
+		os.Exit(1)
+	}
+	fingerprinter, err := parsers.NewFingerprinter(fingerprintMasks)
 	if err != nil {
-		logger.Error("Failed to get SQL DB", "error", err)
+		logger.Error("Invalid fingerprint rules", "error", err)
 		os.Exit(1)
 	}
-	alertService := services.NewAlertService(alertRuleRepo, alertRepo, sqlDB, logger)
+	fingerprintService := services.NewFingerprintBackfillService(logRepo, fingerprintRepo, fingerprinter, maintenanceService, logger)
+	fingerprintHandler := handlers.NewFingerprintHandler(fingerprintService, logger)
 
-	// Start alert checker in background
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Mutations are audited with snapshots of the records they change, loaded by their resource
+	auditHandler := handlers.NewAuditHandler(auditRepo, map[string]handlers.AuditSnapshotFunc{
+		"alerts": func(ctx context.Context, id uint) (any, error) {
+			return alertRepo.GetAlertByID(ctx, id)
+		},
+		"alert-rules": func(ctx context.Context, id uint) (any, error) {
+			return alertRuleRepo.GetAlertRuleByID(ctx, id)
+		},
+		"saved-searches": func(ctx context.Context, id uint) (any, error) {
+			return savedSearchRepo.GetSearchByID(ctx, id)
+		},
+		"admin/notification-channels": func(ctx context.Context, id uint) (any, error) {
+			return notificationRepo.GetChannelByID(ctx, id)
+		},
+		"admin/alert-rules": func(ctx context.Context, id uint) (any, error) {
+			return notificationRepo.GetRuleChannels(ctx, id)
+		},
+		"admin/retention": func(ctx context.Context, id uint) (any, error) {
+			return retentionRepo.GetPolicyByID(ctx, id)
+		},
+		"admin/tenants": func(ctx context.Context, id uint) (any, error) {
+			return tenantRepo.GetTenantByID(ctx, id)
+		},
+	}, logger)
 
-	go alertService.StartAlertChecker(ctx, time.Duration(constants.DefaultAlertCheckInterval)*time.Second)
+	// The alert checker, with its catch-up, re-notifications and escalations, and the Kafka lag monitor alert, so
+	// they run on a single API server at a time too
+	if cfg.Alert.CheckEnabled {
+		taskScheduler.RegisterWorker(constants.WorkerAlertChecker, func(ctx context.Context) {
+			alertService.StartAlertChecker(ctx, &cfg.Alert)
+		})
+	} else {
+		alertService.DisableChecker()
+	}
+	taskScheduler.RegisterWorker(constants.WorkerKafkaLag, kafkaLagService.Start)
+
+	// Start the workers in background, each cancelled and awaited on shutdown
+
+	// Apply the log level and alert check interval of the reloaded configuration on SIGHUP or config file changes
+	reloader := config.NewReloader(cfg, logger)
+	reloader.OnReload(func(cfg *config.Config) {
+		logLevel.Set(cfg.Log.Level)
+		alertService.SetCheckInterval(cfg.Alert.CheckInterval)
+	})
+	shutdown.Go(constants.ShutdownStageWorkers, "config reloader", constants.ShutdownWorkerTimeout, reloader.Start)
+	shutdown.Go(constants.ShutdownStageWorkers, "log feed", constants.ShutdownWorkerTimeout, logFeed.Start)
+	if logStreamConsumer != nil {
+		shutdown.Go(constants.ShutdownStageWorkers, "live tail", constants.ShutdownWorkerTimeout, logStreamConsumer.Start)
+	}
+	shutdown.Go(constants.ShutdownStageWorkers, "maintenance", constants.ShutdownWorkerTimeout, maintenanceService.Start)
+	shutdown.Go(constants.ShutdownStageWorkers, "scheduler", constants.ShutdownWorkerTimeout, taskScheduler.Start)
+	shutdown.Go(constants.ShutdownStageWorkers, "fingerprint backfill", constants.ShutdownWorkerTimeout, fingerprintService.Start)
+	shutdown.Go(constants.ShutdownStageWorkers, "dashboard warm-up", constants.ShutdownWorkerTimeout, dashboardCache.Warm)
+
+	// Live tails and long polls wait for new logs for as long as the client stays, so they are ended when the
+	// server starts draining rather than holding up the shutdown
+	draining, drain := context.WithCancel(context.Background())
+	defer drain()
 
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	// The request ID is assigned first so that the access log and error responses of every request carry it
+	router.Use(handlers.RequestContext())
+	var unpublished []string
+	if cfg.SelfIngestion.Enabled {
+		// Serving logs must not produce logs, or clients following them would be woken up by their own requests
+		unpublished = append([]string{
+			constants.APIPrefix + constants.APILogsPath + "/poll",
+			constants.APIPrefix + constants.APILogsPath + "/stream",
+		}, cfg.SelfIngestion.ExcludePaths...)
+	}
+	router.Use(handlers.AccessLog(logger, unpublished...))
+	router.Use(handlers.Recovery(logger))
+	if cfg.Server.SecurityHeaders {
+		router.Use(handlers.SecurityHeaders(cfg.Server.HSTSMaxAge))
+	}
+	// Preflight requests of browser apps on other origins are answered before authentication, which they skip
+	if cfg.Server.CORS.Enabled() {
+		router.Use(handlers.CORS(cfg.Server.CORS))
+	}
+	if cfg.Metrics.Enabled {
+		router.Use(metrics.Middleware())
+	}
+	// Large log pages and exports are gzipped for clients accepting it
+	if cfg.Server.Compression {
+		router.Use(handlers.Compress(cfg.Server.CompressionMinBytes, cfg.Server.CompressionLevel))
+	}
+
+	// Authenticate everything but the health and readiness checks and metrics, which load balancers and scrapers
+	// call anonymously, the status page, which stakeholders do, and the API documentation.
+	// The live tail and export endpoints are metered per scope, and are the only ones service tokens may call.
+	scopes := map[string]string{
+		constants.APIPrefix + constants.APILogsPath + "/poll":                constants.ScopeLogsTail,
+		constants.APIPrefix + constants.APILogsPath + "/stream":              constants.ScopeLogsTail,
+		constants.APIPrefix + constants.APILogsPath + "/export":              constants.ScopeLogsExport,
+		constants.APIPrefix + constants.APIAdminPath + "/exports/compliance": constants.ScopeLogsExport,
+	}
+	router.Use(authHandler.RequireAuth(scopes, constants.APIHealthPath, constants.LivenessPath, constants.ReadinessPath, constants.MetricsPath, constants.StatusPath,
+		constants.APIPrefix+constants.APIDocsPath, constants.APIPrefix+constants.APIDocsSpecPath))
+
+	// Reject mutations during maintenance, except for lifting maintenance and read-only admin operations
+	router.Use(maintenanceHandler.ReadOnlyGuard(
+		constants.APIPrefix+constants.APIAdminPath+"/maintenance",
+		constants.APIPrefix+constants.APIAdminPath+"/exports/verify",
+		constants.APIV1Prefix+"/alert-rules/:id/evaluate",
+		constants.APIPrefix+"/alert-rules/:id/evaluate",
+	))
+
+	// Attach the SQL run for a request to the responses of admins asking for it, in debug mode
+	router.Use(handlers.DebugSQL(cfg.Server.DebugSQL))
 
-	// Health check endpoint
+	// Record who changed what, except for the POST routes that only read or test
+	router.Use(auditHandler.Record(
+		constants.APIPrefix+constants.APIAdminPath+"/exports/verify",
+		constants.APIPrefix+constants.APIAdminPath+"/notification-channels/:id/test",
+		constants.APIV1Prefix+"/alert-rules/:id/evaluate",
+		constants.APIPrefix+"/alert-rules/:id/evaluate",
+	))
+
+	// Health and readiness check endpoints
 	router.GET(constants.APIHealthPath, healthHandler.HealthCheck)
+	router.GET(constants.LivenessPath, readinessHandler.Liveness)
+	router.GET(constants.ReadinessPath, readinessHandler.Readiness)
 
-	// API routes
-	api := router.Group(constants.APIPrefix)
-	{
+	// Public status page, summarizing component health for stakeholders without exposing log data
+	if cfg.StatusPage.Enabled {
+		statusService := services.NewStatusService(db, logRepo, dashboardCache, maintenanceService, alertService, cfg.StatusPage, logger)
+		router.GET(constants.StatusPath, handlers.NewStatusHandler(statusService).GetStatus)
+	}
+
+	// Alert events pushed to dashboards over WebSocket
+	router.GET(constants.AlertEventsPath, alertEventsHandler.StreamAlertEvents)
+
+	// Prometheus scrape endpoint
+	if cfg.Metrics.Enabled {
+		router.GET(constants.MetricsPath, gin.WrapH(metrics.Handler()))
+	}
+
+	// Log, alert and alert rule routes, served under /api/v1 with the response envelope
+	// and, while legacy routes are enabled, under /api with their original response shapes
+	registerVersionedRoutes := func(group *gin.RouterGroup) {
 		// Log endpoints
-		logsGroup := api.Group(constants.APILogsPath)
+		logsGroup := group.Group(constants.APILogsPath)
 		{
 			logsGroup.GET("", logHandler.GetLogs)
 			logsGroup.GET("/trace/:traceID", logHandler.GetLogsByTraceID)
 		}
 
-		// Metrics endpoint for combined summary of logs
-		metrics := api.Group(constants.APIMetricsPath)
-		{
-			metrics.GET("", logHandler.GetMetrics)
-		}
-
 		// Alert endpoints
-		alertsGroup := api.Group("/alerts")
+		alertsGroup := group.Group("/alerts")
 		{
 			alertsGroup.GET("", alertHandler.GetAlerts)
 			alertsGroup.GET("/stats", alertHandler.GetAlertStats)
@@ -97,18 +439,126 @@ func main() {
 			alertsGroup.GET("/:id", alertHandler.GetAlertByID)
 			alertsGroup.PUT("/:id/resolve", alertHandler.ResolveAlert)
 			alertsGroup.PUT("/:id/acknowledge", alertHandler.AcknowledgeAlert)
+			alertsGroup.PUT("/:id/snooze", alertHandler.SnoozeAlert)
+			alertsGroup.POST("/bulk", alertHandler.BulkUpdateAlerts)
 		}
 
 		// Alert rule endpoints
-		rulesGroup := api.Group("/alert-rules")
+		rulesGroup := group.Group("/alert-rules")
 		{
 			rulesGroup.POST("", alertRuleHandler.CreateAlertRule)
 			rulesGroup.GET("", alertRuleHandler.GetAlertRules)
 			rulesGroup.GET("/:id", alertRuleHandler.GetAlertRuleByID)
 			rulesGroup.PUT("/:id", alertRuleHandler.UpdateAlertRule)
 			rulesGroup.DELETE("/:id", alertRuleHandler.DeleteAlertRule)
+			rulesGroup.PUT("/:id/mute", alertRuleHandler.MuteAlertRule)
+			rulesGroup.POST("/:id/evaluate", alertRuleHandler.EvaluateAlertRule)
+			rulesGroup.GET("/:id/canary", alertRuleHandler.GetCanaryReport)
+			rulesGroup.PUT("/:id/canary/promote", alertRuleHandler.PromoteCanary)
+			rulesGroup.PUT("/:id/canary/rollback", alertRuleHandler.RollbackCanary)
 		}
 	}
+	registerVersionedRoutes(router.Group(constants.APIV1Prefix))
+
+	// API routes
+	api := router.Group(constants.APIPrefix)
+	{
+		if cfg.Server.LegacyRoutes {
+			registerVersionedRoutes(api.Group("", handlers.Deprecated()))
+		}
+
+		// Long polling for new logs
+		api.GET(constants.APILogsPath+"/poll", handlers.EndOnDrain(draining), logPollHandler.PollLogs)
+
+		// Live tail of newly stored logs over Server-Sent Events
+		api.GET(constants.APILogsPath+"/stream", handlers.EndOnDrain(draining), logStreamHandler.StreamLogs)
+
+		// CSV and NDJSON downloads of filtered logs
+		api.GET(constants.APILogsPath+"/export", logHandler.ExportLogs)
+
+		// Ad-hoc breakdowns of filtered logs, grouped by service, level, path, status or user
+		api.GET(constants.APILogsPath+"/aggregate", logHandler.AggregateLogs)
+
+		// Traces, combining the logs of every service a request went through
+		traces := api.Group(constants.APITracesPath)
+		{
+			traces.GET("", logHandler.GetTraces)
+			traces.GET("/:traceID", logHandler.GetTrace)
+		}
+
+		// Metrics endpoint for combined summary of logs
+		metrics := api.Group(constants.APIMetricsPath)
+		{
+			metrics.GET("", logHandler.GetMetrics)
+			metrics.GET("/services/compare", logHandler.CompareServices)
+			metrics.GET("/latency/heatmap", logHandler.GetLatencyHeatmap)
+			metrics.GET("/timeseries", logHandler.GetTimeSeries)
+			metrics.GET("/slow-requests", logHandler.GetSlowRequests)
+		}
+
+		// Saved searches, named log filters run like GET /api/logs
+		savedSearches := api.Group("/saved-searches")
+		{
+			savedSearches.POST("", savedSearchHandler.CreateSearch)
+			savedSearches.GET("", savedSearchHandler.GetSearches)
+			savedSearches.GET("/:id", savedSearchHandler.GetSearchByID)
+			savedSearches.PUT("/:id", savedSearchHandler.UpdateSearch)
+			savedSearches.DELETE("/:id", savedSearchHandler.DeleteSearch)
+			savedSearches.GET("/:id/execute", savedSearchHandler.ExecuteSearch)
+		}
+
+		// Auth endpoints
+		api.GET(constants.APIAuthPath+"/me", authHandler.GetCurrentUser)
+
+		// Admin endpoints
+		adminGroup := api.Group(constants.APIAdminPath)
+		{
+			adminGroup.GET("/storage/stats", storageHandler.GetStorageStats)
+			adminGroup.GET("/exports/compliance", exportHandler.ExportLogs)
+			adminGroup.POST("/exports/verify", exportHandler.VerifyExport)
+			adminGroup.GET("/audit", auditHandler.GetAuditRecords)
+			adminGroup.GET("/audit/exports", authHandler.GetExportAudits)
+			adminGroup.GET("/dlq/stats", deadLetterHandler.GetDeadLetterStats)
+			adminGroup.GET("/kafka/lag", kafkaLagHandler.GetLag)
+			adminGroup.GET("/maintenance", maintenanceHandler.GetMaintenanceStatus)
+			adminGroup.PUT("/maintenance", maintenanceHandler.UpdateMaintenanceStatus)
+			adminGroup.POST("/notification-channels", notificationHandler.CreateChannel)
+			adminGroup.GET("/notification-channels", notificationHandler.GetChannels)
+			adminGroup.GET("/notification-channels/:id", notificationHandler.GetChannelByID)
+			adminGroup.PUT("/notification-channels/:id", notificationHandler.UpdateChannel)
+			adminGroup.DELETE("/notification-channels/:id", notificationHandler.DeleteChannel)
+			adminGroup.POST("/notification-channels/:id/test", notificationHandler.TestChannel)
+			adminGroup.GET("/alert-rules/:id/channels", notificationHandler.GetRuleChannels)
+			adminGroup.PUT("/alert-rules/:id/channels", notificationHandler.SetRuleChannels)
+			adminGroup.POST("/retention", retentionHandler.CreatePolicy)
+			adminGroup.GET("/retention", retentionHandler.GetPolicies)
+			adminGroup.GET("/retention/:id", retentionHandler.GetPolicyByID)
+			adminGroup.PUT("/retention/:id", retentionHandler.UpdatePolicy)
+			adminGroup.DELETE("/retention/:id", retentionHandler.DeletePolicy)
+			adminGroup.POST("/fingerprints/backfill", fingerprintHandler.StartBackfill)
+			adminGroup.GET("/fingerprints/backfill", fingerprintHandler.GetStatus)
+			adminGroup.DELETE("/fingerprints/backfill", fingerprintHandler.CancelBackfill)
+			adminGroup.GET("/migrations", migrationHandler.GetMigrations)
+			adminGroup.GET("/scheduled-tasks", schedulerHandler.GetTasks)
+			adminGroup.GET("/scheduled-tasks/:name/runs", schedulerHandler.GetRuns)
+			adminGroup.POST("/tenants", tenantHandler.CreateTenant)
+			adminGroup.GET("/tenants", tenantHandler.GetTenants)
+			adminGroup.GET("/tenants/:id", tenantHandler.GetTenantByID)
+			adminGroup.PUT("/tenants/:id", tenantHandler.UpdateTenant)
+			adminGroup.DELETE("/tenants/:id", tenantHandler.DeleteTenant)
+		}
+	}
+
+	// OpenAPI document of the routes registered above, browsed with Swagger UI
+	if cfg.Server.Docs {
+		docsHandler, err := handlers.NewDocsHandler(handlers.APISpec(router.Routes()))
+		if err != nil {
+			logger.Error("Failed to build API documentation", "error", err)
+			os.Exit(1)
+		}
+		router.GET(constants.APIPrefix+constants.APIDocsPath, docsHandler.GetUI)
+		router.GET(constants.APIPrefix+constants.APIDocsSpecPath, docsHandler.GetSpec)
+	}
 
 	//Serve static files for dashboard
 	router.Static("/static", "./static")
@@ -127,6 +577,10 @@ func main() {
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	shutdown.Register(constants.ShutdownStageHTTP, "http server", constants.ShutdownHTTPTimeout, func(ctx context.Context) error {
+		drain()
+		return server.Shutdown(ctx)
+	})
 
 	// Start server in a goroutine
 	go func() {
@@ -144,16 +598,16 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
-	// Cancel alert checker context
-	cancel()
-
-	// Create a deadline for server shutdown
-	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
+	// Drain requests, then stop the workers, let notifications finish, flush Kafka clients and close the database.
+	// Components that fail or run out of time are reported, and the rest are stopped regardless.
+	if _, err := shutdown.Shutdown(); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	}
 
 	logger.Info("Server exited")
+
+	// Flush the server's own logs last, so that the shutdown is recorded as well
+	if selfLogHandler != nil {
+		selfLogHandler.Close()
+	}
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+
+	"github.com/IBM/sarama"
+)
+
+// broker is the Kafka cluster a run drives the pipeline through
+type broker struct {
+	mode      string
+	addrs     []string
+	mock      *sarama.MockBroker // set in mock mode, where produced messages are acknowledged and dropped
+	container string             // ID of the Kafka container started for the run, if any
+	logger    *slog.Logger
+}
+
+// startBroker provides the brokers of the given mode, with the topic created with the given partitions
+func startBroker(ctx context.Context, mode string, external []string, topic string, partitions int, logger *slog.Logger) (*broker, error) {
+	switch mode {
+	case constants.BenchBrokerMock:
+		return startMockBroker(topic, partitions, logger), nil
+
+	case constants.BenchBrokerDocker:
+		b, err := startKafkaContainer(logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := createTopic(ctx, b.addrs, topic, partitions, constants.BenchKafkaStartTimeout); err != nil {
+			b.Close()
+			return nil, err
+		}
+		return b, nil
+
+	case constants.BenchBrokerExternal:
+		b := &broker{mode: mode, addrs: external, logger: logger}
+		if err := createTopic(ctx, b.addrs, topic, partitions, 0); err != nil {
+			return nil, err
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("unknown broker %q, expected %s, %s or %s", mode,
+			constants.BenchBrokerMock, constants.BenchBrokerDocker, constants.BenchBrokerExternal)
+	}
+}
+
+// startMockBroker starts an in-process broker leading every partition of the topic. It acknowledges produced
+// messages without keeping them, so the processor is fed the messages the collector built instead.
+func startMockBroker(topic string, partitions int, logger *slog.Logger) *broker {
+	reporter := mockReporter{logger}
+	mock := sarama.NewMockBroker(reporter, 1)
+
+	metadata := sarama.NewMockMetadataResponse(reporter).
+		SetBroker(mock.Addr(), mock.BrokerID()).
+		SetController(mock.BrokerID())
+	for partition := 0; partition < partitions; partition++ {
+		metadata.SetLeader(topic, int32(partition), mock.BrokerID())
+	}
+	mock.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(reporter),
+		"MetadataRequest":    metadata,
+		"ProduceRequest":     sarama.NewMockProduceResponse(reporter),
+	})
+	return &broker{mode: constants.BenchBrokerMock, addrs: []string{mock.Addr()}, mock: mock, logger: logger}
+}
+
+// startKafkaContainer starts a single-node KRaft Kafka container. The broker advertises the host port, so it is
+// chosen up front rather than by Docker.
+func startKafkaContainer(logger *slog.Logger) (*broker, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	addr := fmt.Sprintf("localhost:%d", port)
+
+	id, err := docker("run", "-d", "--rm",
+		"-p", fmt.Sprintf("127.0.0.1:%d:9092", port),
+		"-e", "KAFKA_NODE_ID=1",
+		"-e", "KAFKA_PROCESS_ROLES=broker,controller",
+		"-e", "KAFKA_LISTENERS=PLAINTEXT://:9092,CONTROLLER://:9093",
+		"-e", "KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://"+addr,
+		"-e", "KAFKA_CONTROLLER_LISTENER_NAMES=CONTROLLER",
+		"-e", "KAFKA_LISTENER_SECURITY_PROTOCOL_MAP=CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+		"-e", "KAFKA_CONTROLLER_QUORUM_VOTERS=1@localhost:9093",
+		"-e", "KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=1",
+		"-e", "KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=1",
+		"-e", "KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
+		"-e", "KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS=0",
+		constants.BenchKafkaImage)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Started Kafka container", "container", id[:min(len(id), 12)], "addr", addr)
+	return &broker{mode: constants.BenchBrokerDocker, addrs: []string{addr}, container: id, logger: logger}, nil
+}
+
+// Mock reports whether produced messages are dropped by a mock broker
+func (b *broker) Mock() bool {
+	return b.mock != nil
+}
+
+// Close stops the mock broker or the Kafka container, if any
+func (b *broker) Close() {
+	if b.mock != nil {
+		b.mock.Close()
+	}
+	if b.container != "" {
+		if _, err := docker("stop", b.container); err != nil {
+			b.logger.Error("Failed to stop Kafka container", "error", err)
+		}
+	}
+}
+
+// createTopic creates the topic, retrying for up to the timeout while the brokers start
+func createTopic(ctx context.Context, brokers []string, topic string, partitions int, timeout time.Duration) error {
+	config := sarama.NewConfig()
+	config.Version = sarama.V3_0_0_0
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := func() error {
+			admin, err := sarama.NewClusterAdmin(brokers, config)
+			if err != nil {
+				return err
+			}
+			defer admin.Close()
+			return admin.CreateTopic(topic, &sarama.TopicDetail{NumPartitions: int32(partitions), ReplicationFactor: 1}, false)
+		}()
+		if err == nil || errors.Is(err, sarama.ErrTopicAlreadyExists) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to create topic %s: %w", topic, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// partitionOffsets returns the newest offset of each partition of the topic
+func partitionOffsets(brokers []string, topic string) (map[int32]int64, error) {
+	client, err := sarama.NewClient(brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[int32]int64, len(partitions))
+	for _, partition := range partitions {
+		offset, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offset of partition %d: %w", partition, err)
+		}
+		offsets[partition] = offset
+	}
+	return offsets, nil
+}
+
+// mockReporter logs the errors of the mock broker, which expects to run in a test
+type mockReporter struct {
+	logger *slog.Logger
+}
+
+func (r mockReporter) Error(args ...interface{}) {
+	r.logger.Error("Mock broker error", "error", fmt.Sprint(args...))
+}
+
+func (r mockReporter) Errorf(format string, args ...interface{}) {
+	r.logger.Error("Mock broker error", "error", fmt.Sprintf(format, args...))
+}
+
+func (r mockReporter) Fatal(args ...interface{}) {
+	r.Error(args...)
+}
+
+func (r mockReporter) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func (r mockReporter) Helper() {}
+
+// docker runs a docker CLI command and returns its trimmed output
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
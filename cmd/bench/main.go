@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/kafka/consumers"
+	"github.com/adeesh/log-analytics/internal/kafka/producers"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// Pipeline stages, in the order they run
+const (
+	stageProduce = "produce" // the collector publishes the logs
+	stageProcess = "process" // the processor consumes, parses and stores them
+	stageQuery   = "query"   // the log repository answers the dashboard's queries over them
+)
+
+// Bench drives generated logs through the collector, the processor and the log repository, one stage at a time
+type Bench struct {
+	cfg        *config.Config
+	broker     *broker
+	topic      string
+	service    string
+	logs       []*models.Log
+	batchSize  int
+	partitions int
+	queryRuns  int
+	logger     *slog.Logger
+}
+
+// produce publishes the logs through the collector in batches. Closing the collector waits for the deliveries of an
+// async producer.
+func (b *Bench) produce(ctx context.Context, collector *producers.LogCollectorService, closeCollector func() error) (int, error) {
+	for start := 0; start < len(b.logs); start += b.batchSize {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		batch := b.logs[start:min(start+b.batchSize, len(b.logs))]
+		if err := collector.SendLogs(ctx, batch); err != nil {
+			return 0, err
+		}
+	}
+	if err := closeCollector(); err != nil {
+		return 0, fmt.Errorf("failed to flush producer: %w", err)
+	}
+	return len(b.logs), nil
+}
+
+// claims returns the claims the processor consumes the produced logs from, along with the offset after the last
+// log of each partition. A mock broker dropped the logs, so they are served from memory instead.
+func (b *Bench) claims(collector *producers.LogCollectorService) ([]sarama.ConsumerGroupClaim, map[int32]int64, func(), error) {
+	if b.broker.Mock() {
+		messages := make([]*sarama.ProducerMessage, 0, len(b.logs))
+		for _, log := range b.logs {
+			message, err := collector.BuildMessage(log)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			messages = append(messages, message)
+		}
+		claims, targets, err := bufferedClaims(b.topic, messages, b.partitions)
+		return claims, targets, func() {}, err
+	}
+
+	targets, err := partitionOffsets(b.broker.addrs, b.topic)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get offsets of topic %s: %w", b.topic, err)
+	}
+	consumer, claims, err := kafkaClaims(b.broker.addrs, b.topic, targets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return claims, targets, func() { consumer.Close() }, nil
+}
+
+// process has the processor consume every claim until all logs are stored, as a consumer group session would
+func (b *Bench) process(ctx context.Context, processor *consumers.LogProcessorService, claims []sarama.ConsumerGroupClaim, targets map[int32]int64) (int, error) {
+	sessionCtx, endSession := context.WithCancel(ctx)
+	session := newBenchSession(sessionCtx, b.topic, targets)
+
+	var wg sync.WaitGroup
+	for _, claim := range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			processor.ConsumeClaim(session, claim)
+		}()
+	}
+
+	select {
+	case <-session.Stored():
+	case <-ctx.Done():
+	}
+	endSession()
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return len(b.logs), nil
+}
+
+// query runs the dashboard's queries over the run's logs, returning the number of queries run and their latencies
+func (b *Bench) query(ctx context.Context, repo logs.LogRepository) (int, []QueryLatency, error) {
+	end := time.Now().Add(time.Minute)
+	start := end.Add(-time.Hour - 2*time.Minute)
+	bounds := heatmapBounds()
+
+	queries := []struct {
+		name string
+		run  func() error
+	}{
+		{"logs", func() error {
+			_, err := repo.GetLogs(ctx, &models.LogFilter{Service: &b.service, Limit: constants.DefaultPageLimit})
+			return err
+		}},
+		{"time_series", func() error {
+			_, err := repo.GetLogTimeSeries(ctx, b.service, nil, start, end, time.Minute)
+			return err
+		}},
+		{"service_comparison", func() error {
+			_, err := repo.GetServiceComparison(ctx, []string{b.service}, nil, start, end)
+			return err
+		}},
+		{"latency_heatmap", func() error {
+			_, err := repo.GetLatencyHeatmap(ctx, b.service, nil, start, end, constants.DefaultHeatmapInterval, bounds)
+			return err
+		}},
+	}
+
+	var latencies []QueryLatency
+	for _, query := range queries {
+		runs := make([]time.Duration, 0, b.queryRuns)
+		for i := 0; i < b.queryRuns; i++ {
+			started := time.Now()
+			if err := query.run(); err != nil {
+				return 0, nil, fmt.Errorf("%s query failed: %w", query.name, err)
+			}
+			runs = append(runs, time.Since(started))
+		}
+		latencies = append(latencies, latency(query.name, runs))
+	}
+	return len(queries) * b.queryRuns, latencies, nil
+}
+
+// purge deletes the run's logs
+func (b *Bench) purge(ctx context.Context, repo logs.LogRepository) (int64, error) {
+	purge := &models.LogPurge{
+		LogRetentionScope: models.LogRetentionScope{Service: b.service},
+		Before:            time.Now().Add(time.Hour),
+	}
+	var total int64
+	for {
+		purged, err := repo.PurgeLogs(ctx, purge, constants.BenchPurgeBatchSize)
+		total += purged
+		if err != nil || purged < constants.BenchPurgeBatchSize {
+			return total, err
+		}
+	}
+}
+
+// generateLogs builds the run's logs from the seed, spread over the last hour with a realistic mix of levels,
+// endpoints and response times
+func generateLogs(n int, service string, seed int64) []*models.Log {
+	random := rand.New(rand.NewSource(seed))
+	levels := []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal}
+	levelWeights := []int{10, 70, 12, 7, 1}
+	methods := []string{"GET", "GET", "GET", "POST", "PUT", "DELETE"}
+	paths := []string{"/api/users", "/api/orders", "/api/orders/items", "/api/payments", "/api/search", "/health"}
+	messages := map[models.LogLevel]string{
+		models.LogLevelDebug: "Cache lookup completed",
+		models.LogLevelInfo:  "Request processed successfully",
+		models.LogLevelWarn:  "Slow response from upstream dependency",
+		models.LogLevelError: "Database connection timeout after retries",
+		models.LogLevelFatal: "Out of memory, shutting down worker",
+	}
+	hosts := []string{"bench-1", "bench-2", "bench-3"}
+
+	now := time.Now()
+	logs := make([]*models.Log, n)
+	for i := range logs {
+		pick := random.Intn(100)
+		level := levels[len(levels)-1]
+		for j, weight := range levelWeights {
+			if pick < weight {
+				level = levels[j]
+				break
+			}
+			pick -= weight
+		}
+
+		status := 200
+		switch level {
+		case models.LogLevelWarn:
+			status = 429
+		case models.LogLevelError, models.LogLevelFatal:
+			status = 500 + random.Intn(4)
+		}
+		responseTime := 5 + int(random.ExpFloat64()*80)
+		method := methods[random.Intn(len(methods))]
+		path := paths[random.Intn(len(paths))]
+		userID := "user-" + strconv.Itoa(random.Intn(1000))
+		host := hosts[random.Intn(len(hosts))]
+
+		logs[i] = &models.Log{
+			Timestamp:      now.Add(-time.Duration(random.Int63n(int64(time.Hour)))),
+			Level:          level,
+			Service:        service,
+			Message:        messages[level],
+			UserID:         &userID,
+			RequestMethod:  &method,
+			RequestPath:    &path,
+			ResponseStatus: &status,
+			ResponseTimeMs: &responseTime,
+			Host:           &host,
+		}
+	}
+	return logs
+}
+
+// heatmapBounds returns the dashboard's default latency bucket bounds
+func heatmapBounds() []int {
+	var bounds []int
+	for _, bound := range strings.Split(constants.DefaultHeatmapLatencyBuckets, ",") {
+		value, _ := strconv.Atoi(bound)
+		bounds = append(bounds, value)
+	}
+	return bounds
+}
+
+func main() {
+	// Initialize logger; the report is written to stdout
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	brokerMode := flag.String("broker", constants.BenchBrokerMock, "Kafka to drive the pipeline through: mock, docker or external (KAFKA_BROKERS)")
+	count := flag.Int("logs", constants.DefaultBenchLogs, "logs driven through the pipeline")
+	batchSize := flag.Int("batch-size", constants.DefaultBenchBatchSize, "logs sent per producer request")
+	partitions := flag.Int("partitions", constants.DefaultBenchPartitions, "partitions of the benchmark topic")
+	queryRuns := flag.Int("query-runs", constants.DefaultBenchQueryRuns, "runs of each query of the query stage")
+	seed := flag.Int64("seed", 1, "seed of the generated logs")
+	outDir := flag.String("out", constants.DefaultBenchOutputDir, "directory the report and profiles are written to")
+	format := flag.String("format", constants.BenchFormatMarkdown, "report printed to stdout: markdown or json")
+	baselinePath := flag.String("baseline", "", "JSON report of an earlier run to compare throughput against")
+	maxRegression := flag.Float64("max-regression", -1, "exit with status 3 when a stage's throughput drops by more than this percentage from the baseline")
+	keep := flag.Bool("keep", false, "keep the run's logs in the log store instead of deleting them")
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  bench [flags]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *count <= 0 || *count > constants.MaxBenchLogs {
+		logger.Error("Invalid number of logs", "logs", *count, "max", constants.MaxBenchLogs)
+		os.Exit(2)
+	}
+	if *batchSize <= 0 || *partitions <= 0 || *queryRuns <= 0 {
+		logger.Error("Batch size, partitions and query runs must be positive")
+		os.Exit(2)
+	}
+	if *format != constants.BenchFormatMarkdown && *format != constants.BenchFormatJSON {
+		logger.Error("Invalid report format", "format", *format)
+		os.Exit(2)
+	}
+	var baseline *Report
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = loadReport(*baselinePath); err != nil {
+			logger.Error("Failed to load baseline", "error", err)
+			os.Exit(2)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		logger.Error("Failed to create output directory", "error", err)
+		os.Exit(1)
+	}
+
+	// Stop the running stage on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The run gets its own topic and service, so it neither sees nor disturbs other data
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	runID := strconv.FormatInt(time.Now().Unix(), 10)
+	topic := "bench-logs-" + runID
+	b := &Bench{
+		cfg:        cfg,
+		topic:      topic,
+		service:    constants.BenchServicePrefix + runID,
+		batchSize:  *batchSize,
+		partitions: *partitions,
+		queryRuns:  *queryRuns,
+		logger:     logger,
+	}
+
+	broker, err := startBroker(ctx, *brokerMode, cfg.Kafka.Brokers, topic, *partitions, logger)
+	if err != nil {
+		logger.Error("Failed to start broker", "error", err)
+		os.Exit(1)
+	}
+	defer broker.Close()
+	b.broker = broker
+
+	// Only the plain pipeline is measured: logs go to the one topic, and nothing else is consumed or published.
+	// Enrichment runs as a stage of the processor's own loop, which the benchmark doesn't drive.
+	cfg.Kafka.Brokers = broker.addrs
+	cfg.Kafka.Topic = topic
+	cfg.Kafka.GroupID = "bench-" + runID
+	cfg.Kafka.PriorityTopic = ""
+	cfg.Kafka.TenantTopics = nil
+	cfg.Kafka.StreamTopic = ""
+	cfg.Kafka.ControlTopic = ""
+	cfg.Enrichment.Enabled = false
+	cfg.Pipeline.IngestionDelayThreshold = 0
+	// Every log is measured through the pipeline, none sampled out
+	cfg.Collector.SampleRates = nil
+	cfg.Collector.RateLimit = 0
+	cfg.Collector.ServiceRateLimits = nil
+
+	regressed, err := run(ctx, b, *count, *seed, *outDir, *format, *keep, baseline, *baselinePath, *maxRegression)
+	if err != nil {
+		logger.Error("Benchmark failed", "error", err)
+		broker.Close()
+		os.Exit(1)
+	}
+	if len(regressed) > 0 {
+		logger.Error("Throughput regressed beyond the allowance", "stages", regressed, "max_regression_percent", *maxRegression)
+		broker.Close()
+		os.Exit(3)
+	}
+}
+
+// run runs the stages and writes the report. It returns the stages whose throughput regressed from the baseline
+// beyond the allowance.
+func run(ctx context.Context, b *Bench, count int, seed int64, outDir, format string, keep bool, baseline *Report, baselinePath string, maxRegression float64) ([]string, error) {
+	collector, err := producers.NewLogCollectorService(b.cfg, b.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log collector: %w", err)
+	}
+	// The produce stage closes the collector to flush it; closing it again would panic
+	closeCollector := sync.OnceValue(collector.Close)
+	defer closeCollector()
+
+	processor, err := consumers.NewLogProcessorService(b.cfg, b.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log processor: %w", err)
+	}
+	defer processor.Close()
+
+	db, err := database.NewGormDB(&b.cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	repo := logs.NewLogRepository(db)
+	if b.cfg.LogStore.Backend == constants.LogStoreClickHouse {
+		clickHouse, err := logs.NewClickHouseLogRepository(ctx, &b.cfg.LogStore.ClickHouse)
+		if err != nil {
+			return nil, err
+		}
+		defer clickHouse.Close()
+		repo = clickHouse
+	}
+
+	report := &Report{
+		StartedAt:  time.Now(),
+		GoVersion:  runtime.Version(),
+		Broker:     b.broker.mode,
+		LogStore:   b.cfg.LogStore.Backend,
+		Logs:       count,
+		BatchSize:  b.batchSize,
+		Partitions: b.partitions,
+		Service:    b.service,
+	}
+	b.logs = generateLogs(count, b.service, seed)
+	b.logger.Info("Benchmark started", "logs", count, "broker", report.Broker, "topic", b.topic, "service", b.service)
+
+	if !keep {
+		defer func() {
+			purged, err := b.purge(context.WithoutCancel(ctx), repo)
+			if err != nil {
+				b.logger.Error("Failed to delete the run's logs", "error", err, "service", b.service)
+				return
+			}
+			b.logger.Info("Deleted the run's logs", "logs", purged, "service", b.service)
+		}()
+	}
+
+	produced, err := profileStage(outDir, stageProduce, func() (int, error) {
+		return b.produce(ctx, collector, closeCollector)
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Stages = append(report.Stages, *produced)
+	b.logger.Info("Stage completed", "stage", stageProduce, "duration_ms", produced.DurationMS)
+
+	claims, targets, closeClaims, err := b.claims(collector)
+	if err != nil {
+		return nil, err
+	}
+	processed, err := profileStage(outDir, stageProcess, func() (int, error) {
+		return b.process(ctx, processor, claims, targets)
+	})
+	closeClaims()
+	if err != nil {
+		return nil, err
+	}
+	report.Stages = append(report.Stages, *processed)
+	b.logger.Info("Stage completed", "stage", stageProcess, "duration_ms", processed.DurationMS)
+
+	// Measured between the stages, so that the layout of the logs table can be compared along with the query latency
+	if report.Storage, err = measureStorage(ctx, b, db); err != nil {
+		return nil, err
+	}
+
+	var latencies []QueryLatency
+	queried, err := profileStage(outDir, stageQuery, func() (int, error) {
+		n, runs, err := b.query(ctx, repo)
+		latencies = runs
+		return n, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	queried.Queries = latencies
+	report.Stages = append(report.Stages, *queried)
+	b.logger.Info("Stage completed", "stage", stageQuery, "duration_ms", queried.DurationMS)
+
+	var regressed []string
+	if baseline != nil {
+		regressed = report.Compare(baseline, baselinePath, maxRegression)
+	}
+
+	if err := writeReports(report, outDir); err != nil {
+		return nil, err
+	}
+	if format == constants.BenchFormatJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteMarkdown(os.Stdout)
+	}
+	return regressed, err
+}
+
+// writeReports writes the report as report.md and report.json to the output directory
+func writeReports(report *Report, dir string) error {
+	for name, write := range map[string]func(*os.File) error{
+		"report.md":   func(f *os.File) error { return report.WriteMarkdown(f) },
+		"report.json": func(f *os.File) error { return report.WriteJSON(f) },
+	} {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := write(file); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
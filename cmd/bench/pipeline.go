@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// benchSession stands in for a consumer group session owning every partition of the topic. It tracks the offsets
+// the processor marks, which it does once a batch is stored, and reports when every partition is stored up to
+// its target offset.
+type benchSession struct {
+	ctx     context.Context
+	topic   string
+	targets map[int32]int64 // partition -> offset after the last message produced
+	mu      sync.Mutex
+	marked  map[int32]int64 // partition -> offset after the last message stored
+	stored  chan struct{}   // closed once every partition reaches its target
+	once    sync.Once
+}
+
+func newBenchSession(ctx context.Context, topic string, targets map[int32]int64) *benchSession {
+	s := &benchSession{
+		ctx:     ctx,
+		topic:   topic,
+		targets: targets,
+		marked:  make(map[int32]int64, len(targets)),
+		stored:  make(chan struct{}),
+	}
+	s.check()
+	return s
+}
+
+func (s *benchSession) Claims() map[string][]int32 {
+	partitions := make([]int32, 0, len(s.targets))
+	for partition := range s.targets {
+		partitions = append(partitions, partition)
+	}
+	return map[string][]int32{s.topic: partitions}
+}
+
+func (s *benchSession) MemberID() string    { return "bench" }
+func (s *benchSession) GenerationID() int32 { return 1 }
+func (s *benchSession) Commit()             {}
+
+func (s *benchSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *benchSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	s.marked[partition] = max(s.marked[partition], offset)
+	s.mu.Unlock()
+	s.check()
+}
+
+func (s *benchSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	s.marked[partition] = offset
+	s.mu.Unlock()
+}
+
+func (s *benchSession) MarkMessage(message *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(message.Topic, message.Partition, message.Offset+1, metadata)
+}
+
+// Stored is closed once the messages of every partition are stored
+func (s *benchSession) Stored() <-chan struct{} {
+	return s.stored
+}
+
+// check closes the stored channel when every partition has reached its target
+func (s *benchSession) check() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for partition, target := range s.targets {
+		if s.marked[partition] < target {
+			return
+		}
+	}
+	s.once.Do(func() { close(s.stored) })
+}
+
+// benchClaim stands in for the claim of a partition. Its channel isn't closed when the messages run out, as the
+// processor stops on the end of the session instead.
+type benchClaim struct {
+	topic         string
+	partition     int32
+	initialOffset int64
+	messages      <-chan *sarama.ConsumerMessage
+	highWaterMark func() int64
+}
+
+func (c *benchClaim) Topic() string                            { return c.topic }
+func (c *benchClaim) Partition() int32                         { return c.partition }
+func (c *benchClaim) InitialOffset() int64                     { return c.initialOffset }
+func (c *benchClaim) HighWaterMarkOffset() int64               { return c.highWaterMark() }
+func (c *benchClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// bufferedClaims builds claims serving in-memory messages, numbering the messages of each partition from zero.
+// It returns the claims along with the offset after the last message of each partition.
+func bufferedClaims(topic string, messages []*sarama.ProducerMessage, partitions int) ([]sarama.ConsumerGroupClaim, map[int32]int64, error) {
+	byPartition := make([][]*sarama.ConsumerMessage, partitions)
+	for i, message := range messages {
+		partition := int32(i % partitions)
+		consumed, err := consumerMessage(message, partition, int64(len(byPartition[partition])))
+		if err != nil {
+			return nil, nil, err
+		}
+		byPartition[partition] = append(byPartition[partition], consumed)
+	}
+
+	claims := make([]sarama.ConsumerGroupClaim, 0, partitions)
+	targets := make(map[int32]int64, partitions)
+	for partition, consumed := range byPartition {
+		channel := make(chan *sarama.ConsumerMessage, len(consumed))
+		for _, message := range consumed {
+			channel <- message
+		}
+		end := int64(len(consumed))
+		claims = append(claims, &benchClaim{
+			topic:         topic,
+			partition:     int32(partition),
+			messages:      channel,
+			highWaterMark: func() int64 { return end },
+		})
+		targets[int32(partition)] = end
+	}
+	return claims, targets, nil
+}
+
+// consumerMessage turns a message built by the collector into the message the processor would consume
+func consumerMessage(message *sarama.ProducerMessage, partition int32, offset int64) (*sarama.ConsumerMessage, error) {
+	consumed := &sarama.ConsumerMessage{
+		Topic:     message.Topic,
+		Partition: partition,
+		Offset:    offset,
+		Timestamp: message.Timestamp,
+	}
+	var err error
+	if message.Key != nil {
+		if consumed.Key, err = message.Key.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode message key: %w", err)
+		}
+	}
+	if consumed.Value, err = message.Value.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode message value: %w", err)
+	}
+	for i := range message.Headers {
+		consumed.Headers = append(consumed.Headers, &message.Headers[i])
+	}
+	return consumed, nil
+}
+
+// kafkaClaims builds claims consuming each partition of the topic from the oldest offset. The consumer must be
+// closed once the claims are done.
+func kafkaClaims(brokers []string, topic string, targets map[int32]int64) (sarama.Consumer, []sarama.ConsumerGroupClaim, error) {
+	consumer, err := sarama.NewConsumer(brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	claims := make([]sarama.ConsumerGroupClaim, 0, len(targets))
+	for partition := range targets {
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			consumer.Close()
+			return nil, nil, fmt.Errorf("failed to consume partition %d: %w", partition, err)
+		}
+		claims = append(claims, &benchClaim{
+			topic:         topic,
+			partition:     partition,
+			initialOffset: sarama.OffsetOldest,
+			messages:      partitionConsumer.Messages(),
+			highWaterMark: partitionConsumer.HighWaterMarkOffset,
+		})
+	}
+	return consumer, claims, nil
+}
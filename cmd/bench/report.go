@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Report is the outcome of a benchmark run. Its JSON form is what later runs compare against with -baseline.
+type Report struct {
+	StartedAt  time.Time         `json:"started_at"`
+	GoVersion  string            `json:"go_version"`
+	Broker     string            `json:"broker"`
+	LogStore   string            `json:"log_store"`
+	Logs       int               `json:"logs"`
+	BatchSize  int               `json:"batch_size"`
+	Partitions int               `json:"partitions"`
+	Service    string            `json:"service"`
+	Stages     []StageResult     `json:"stages"`
+	Storage    *StorageFootprint `json:"storage,omitempty"`  // of the logs table, on MySQL
+	Baseline   string            `json:"baseline,omitempty"` // path of the report compared against
+}
+
+// StageResult measures a stage of the pipeline. Allocation figures cover the whole process while the stage ran.
+type StageResult struct {
+	Name           string         `json:"name"`
+	Items          int            `json:"items"` // logs produced or processed, or queries run
+	DurationMS     float64        `json:"duration_ms"`
+	Throughput     float64        `json:"throughput_per_sec"`
+	AllocBytes     uint64         `json:"alloc_bytes"`
+	Allocs         uint64         `json:"allocs"`
+	GCCycles       uint32         `json:"gc_cycles"`
+	HeapInuseBytes uint64         `json:"heap_inuse_bytes"` // after the stage, before collecting garbage
+	CPUProfile     string         `json:"cpu_profile"`
+	HeapProfile    string         `json:"heap_profile"`
+	Queries        []QueryLatency `json:"queries,omitempty"`
+
+	// Change of the throughput from the baseline's stage of the same name, in percent
+	BaselineChange *float64 `json:"baseline_change_percent,omitempty"`
+}
+
+// QueryLatency sums up the run times of a query of the query stage
+type QueryLatency struct {
+	Name  string  `json:"name"`
+	Runs  int     `json:"runs"`
+	P50MS float64 `json:"p50_ms"`
+	P95MS float64 `json:"p95_ms"`
+	MaxMS float64 `json:"max_ms"`
+
+	// Change of the p95 from the baseline's query of the same name, in percent
+	BaselineChange *float64 `json:"baseline_change_percent,omitempty"`
+}
+
+// profileStage runs a stage while profiling the CPU into <stage>.cpu.pprof, then writes the heap profile to
+// <stage>.heap.pprof. The stage returns the number of items it handled.
+func profileStage(dir, name string, run func() (int, error)) (*StageResult, error) {
+	result := &StageResult{
+		Name:        name,
+		CPUProfile:  name + ".cpu.pprof",
+		HeapProfile: name + ".heap.pprof",
+	}
+
+	cpu, err := os.Create(filepath.Join(dir, result.CPUProfile))
+	if err != nil {
+		return nil, err
+	}
+	defer cpu.Close()
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	start := time.Now()
+	items, err := run()
+	duration := time.Since(start)
+	pprof.StopCPUProfile()
+	if err != nil {
+		return nil, fmt.Errorf("%s stage failed: %w", name, err)
+	}
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	result.Items = items
+	result.DurationMS = float64(duration.Microseconds()) / 1000
+	result.Throughput = float64(items) / duration.Seconds()
+	result.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	result.Allocs = after.Mallocs - before.Mallocs
+	result.GCCycles = after.NumGC - before.NumGC
+	result.HeapInuseBytes = after.HeapInuse
+
+	heap, err := os.Create(filepath.Join(dir, result.HeapProfile))
+	if err != nil {
+		return nil, err
+	}
+	defer heap.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heap); err != nil {
+		return nil, fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return result, nil
+}
+
+// latency sums up the run times of a query
+func latency(name string, runs []time.Duration) QueryLatency {
+	sorted := slices.Clone(runs)
+	slices.Sort(sorted)
+	percentile := func(p float64) float64 {
+		// Nearest rank
+		rank := max(int(math.Ceil(p*float64(len(sorted))))-1, 0)
+		return milliseconds(sorted[rank])
+	}
+	return QueryLatency{
+		Name:  name,
+		Runs:  len(sorted),
+		P50MS: percentile(0.50),
+		P95MS: percentile(0.95),
+		MaxMS: milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// loadReport reads the JSON report of an earlier run
+func loadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Compare records the throughput change of each stage from the baseline's stage of the same name, along with the
+// changes of the query latencies and the storage footprint. It returns the stages whose throughput dropped by more
+// than the allowed regression, in percent; a negative allowance never fails.
+func (r *Report) Compare(baseline *Report, path string, allowedRegression float64) []string {
+	r.Baseline = path
+	var regressed []string
+	for i := range r.Stages {
+		stage := &r.Stages[i]
+		index := slices.IndexFunc(baseline.Stages, func(s StageResult) bool { return s.Name == stage.Name })
+		if index < 0 {
+			continue
+		}
+		base := &baseline.Stages[index]
+		for j := range stage.Queries {
+			query := &stage.Queries[j]
+			q := slices.IndexFunc(base.Queries, func(l QueryLatency) bool { return l.Name == query.Name })
+			if q >= 0 && base.Queries[q].P95MS > 0 {
+				change := (query.P95MS/base.Queries[q].P95MS - 1) * 100
+				query.BaselineChange = &change
+			}
+		}
+		if base.Throughput == 0 {
+			continue
+		}
+		change := (stage.Throughput/base.Throughput - 1) * 100
+		stage.BaselineChange = &change
+		if allowedRegression >= 0 && -change > allowedRegression {
+			regressed = append(regressed, stage.Name)
+		}
+	}
+
+	if r.Storage != nil && baseline.Storage != nil {
+		if size := baseline.Storage.size(r.Storage); size > 0 {
+			change := (float64(r.Storage.size(baseline.Storage))/float64(size) - 1) * 100
+			r.Storage.BaselineChange = &change
+		}
+	}
+	return regressed
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown document
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Pipeline Benchmark\n\n")
+	fmt.Fprintf(&b, "| Setting | Value |\n|---------|-------|\n")
+	fmt.Fprintf(&b, "| Started | %s |\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "| Go | %s |\n", r.GoVersion)
+	fmt.Fprintf(&b, "| Broker | %s |\n", r.Broker)
+	fmt.Fprintf(&b, "| Log store | %s |\n", r.LogStore)
+	fmt.Fprintf(&b, "| Logs | %d |\n", r.Logs)
+	fmt.Fprintf(&b, "| Batch size | %d |\n", r.BatchSize)
+	fmt.Fprintf(&b, "| Partitions | %d |\n", r.Partitions)
+	if r.Baseline != "" {
+		fmt.Fprintf(&b, "| Baseline | %s |\n", r.Baseline)
+	}
+
+	fmt.Fprintf(&b, "\n## Stages\n\n")
+	fmt.Fprintf(&b, "| Stage | Items | Duration | Throughput/s | vs baseline | Allocated | Allocations | GC cycles | Heap in use |\n")
+	fmt.Fprintf(&b, "|-------|------:|---------:|-------------:|------------:|----------:|------------:|----------:|------------:|\n")
+	for _, stage := range r.Stages {
+		fmt.Fprintf(&b, "| %s | %d | %s | %.0f | %s | %s | %d | %d | %s |\n",
+			stage.Name, stage.Items, time.Duration(stage.DurationMS*float64(time.Millisecond)).Round(time.Millisecond),
+			stage.Throughput, percentChange(stage.BaselineChange), bytes(stage.AllocBytes), stage.Allocs, stage.GCCycles, bytes(stage.HeapInuseBytes))
+	}
+
+	for _, stage := range r.Stages {
+		if len(stage.Queries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## Query Latency\n\n")
+		fmt.Fprintf(&b, "| Query | Runs | p50 | p95 | p95 vs baseline | Max |\n|-------|-----:|----:|----:|----------------:|----:|\n")
+		for _, query := range stage.Queries {
+			fmt.Fprintf(&b, "| %s | %d | %.1f ms | %.1f ms | %s | %.1f ms |\n",
+				query.Name, query.Runs, query.P50MS, query.P95MS, percentChange(query.BaselineChange), query.MaxMS)
+		}
+	}
+
+	if storage := r.Storage; storage != nil {
+		fileBytes := "-"
+		if storage.FileBytes > 0 {
+			fileBytes = bytes(uint64(storage.FileBytes))
+		}
+		fmt.Fprintf(&b, "\n## Storage\n\n")
+		fmt.Fprintf(&b, "| Table | Row format | Options | Primary key | Rows | Data | Indexes | File | Bytes/row | vs baseline |\n")
+		fmt.Fprintf(&b, "|-------|------------|---------|-------------|-----:|-----:|--------:|-----:|----------:|------------:|\n")
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %d | %s | %s | %s | %.0f | %s |\n",
+			storage.Table, storage.RowFormat, storage.CreateOptions, storage.PrimaryKey, storage.Rows,
+			bytes(uint64(storage.DataBytes)), bytes(uint64(storage.IndexBytes)), fileBytes, storage.BytesPerRow,
+			percentChange(storage.BaselineChange))
+	}
+
+	fmt.Fprintf(&b, "\n## Profiles\n\n")
+	for _, stage := range r.Stages {
+		fmt.Fprintf(&b, "- %s: `%s`, `%s`\n", stage.Name, stage.CPUProfile, stage.HeapProfile)
+	}
+	fmt.Fprintf(&b, "\nInspect a profile with `go tool pprof -top <profile>`.\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// percentChange formats a change from the baseline, or a dash without a baseline
+func percentChange(change *float64) string {
+	if change == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", *change)
+}
+
+// bytes formats a byte count with a binary unit
+func bytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
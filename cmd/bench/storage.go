@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/storage"
+)
+
+// StorageFootprint is the size of the logs table once the process stage stored the run's logs, along with the
+// layout it was stored in. It covers the whole table, so it compares runs against a database holding the same logs,
+// such as an empty one.
+type StorageFootprint struct {
+	Table         string  `json:"table"`
+	RowFormat     string  `json:"row_format"`
+	CreateOptions string  `json:"create_options,omitempty"` // compression options, e.g. KEY_BLOCK_SIZE=8 or COMPRESSION="zlib"
+	PrimaryKey    string  `json:"primary_key"`
+	Rows          int64   `json:"rows"` // estimated by InnoDB
+	DataBytes     int64   `json:"data_bytes"`
+	IndexBytes    int64   `json:"index_bytes"`
+	TotalBytes    int64   `json:"total_bytes"`
+	FileBytes     int64   `json:"file_bytes,omitempty"` // allocated on disk, which page compression shrinks but not the data length
+	BytesPerRow   float64 `json:"bytes_per_row"`
+
+	// Change of the footprint from the baseline's, in percent: of the file bytes when both runs have them
+	BaselineChange *float64 `json:"baseline_change_percent,omitempty"`
+}
+
+// size is the footprint compared between runs
+func (f *StorageFootprint) size(baseline *StorageFootprint) int64 {
+	if f.FileBytes > 0 && baseline.FileBytes > 0 {
+		return f.FileBytes
+	}
+	return f.TotalBytes
+}
+
+// measureStorage measures the footprint of the logs table on MySQL, refreshing the table's statistics first since
+// InnoDB only samples them. Other log stores have no footprint to measure, and nil is returned.
+func measureStorage(ctx context.Context, b *Bench, db *database.GormDB) (*StorageFootprint, error) {
+	if b.cfg.LogStore.Backend == constants.LogStoreClickHouse || b.cfg.Database.Driver != constants.DBDriverMySQL {
+		return nil, nil
+	}
+	gormDB := db.GetDB().WithContext(ctx)
+	table := constants.DefaultLogsTable
+	if err := gormDB.Exec("ANALYZE TABLE " + table).Error; err != nil {
+		return nil, fmt.Errorf("failed to analyze table %s: %w", table, err)
+	}
+
+	tables, err := storage.NewStorageRepository(db.GetDB()).GetTableStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	footprint := &StorageFootprint{Table: table}
+	for _, t := range tables {
+		if t.Table == table {
+			footprint.Rows, footprint.DataBytes, footprint.IndexBytes, footprint.TotalBytes = t.Rows, t.DataBytes, t.IndexBytes, t.TotalBytes
+		}
+	}
+	if footprint.Rows > 0 {
+		footprint.BytesPerRow = float64(footprint.TotalBytes) / float64(footprint.Rows)
+	}
+
+	var options struct {
+		RowFormat     string
+		CreateOptions string
+	}
+	err = gormDB.Raw(`SELECT COALESCE(ROW_FORMAT, '') AS row_format, COALESCE(CREATE_OPTIONS, '') AS create_options
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, table).Scan(&options).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options of table %s: %w", table, err)
+	}
+	footprint.RowFormat, footprint.CreateOptions = options.RowFormat, options.CreateOptions
+
+	var primaryKey []string
+	err = gormDB.Raw(`SELECT COLUMN_NAME FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = 'PRIMARY' ORDER BY SEQ_IN_INDEX`, table).Scan(&primaryKey).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of table %s: %w", table, err)
+	}
+	footprint.PrimaryKey = strings.Join(primaryKey, ", ")
+
+	// MariaDB, and users without the PROCESS privilege, can't read the tablespaces, leaving the file size out
+	var fileBytes []int64
+	err = gormDB.Raw(`SELECT ALLOCATED_SIZE FROM information_schema.INNODB_TABLESPACES WHERE NAME = CONCAT(DATABASE(), '/', ?)`, table).Scan(&fileBytes).Error
+	if err != nil {
+		b.logger.Warn("Failed to read tablespace size, leaving it out", "table", table, "error", err)
+	} else if len(fileBytes) > 0 {
+		footprint.FileBytes = fileBytes[0]
+	}
+	return footprint, nil
+}
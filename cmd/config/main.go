@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+)
+
+// config prints the effective configuration the binaries would run with, or checks it, for debugging deployments
+func main() {
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  config [flags] [print|check]\n\n"+
+			"  print  write the effective settings as a config file, commented with the source of each (default)\n"+
+			"  check  validate every section of the configuration\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := constants.ConfigCommandPrint
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+	if flag.NArg() > 1 || (command != constants.ConfigCommandPrint && command != constants.ConfigCommandCheck) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch command {
+	case constants.ConfigCommandPrint:
+		if err := cfg.WriteEffective(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case constants.ConfigCommandCheck:
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Configuration is valid")
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/maintenance"
+	"github.com/adeesh/log-analytics/internal/kafka/producers"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/parsers"
+	"github.com/adeesh/log-analytics/internal/services"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Importer maps the records of a source to logs and writes them in batches. Logs without a message ID get one
+// derived from the source and the record's position in it, so importing the same source again stores nothing twice.
+type Importer struct {
+	source     source
+	name       string // shown in log messages, e.g. the file path as given
+	identity   string // identifies the source in message IDs, e.g. the absolute file path
+	json       bool   // records are JSON lines rather than fields
+	mapper     *parsers.FieldMapper
+	sink       sink
+	batchSize  int
+	maxInvalid int
+	logger     *slog.Logger
+}
+
+// ImportStats counts the records of an import
+type ImportStats struct {
+	Read    int
+	Invalid int // skipped because they couldn't be read, mapped or validated
+	Written int // published to Kafka, or stored in direct mode
+}
+
+// Add accumulates the counts of another import
+func (s *ImportStats) Add(other ImportStats) {
+	s.Read += other.Read
+	s.Invalid += other.Invalid
+	s.Written += other.Written
+}
+
+// Run imports the source until it is exhausted, the context is cancelled, a batch fails to be written or too
+// many records are invalid. The returned stats cover the records handled until then.
+func (i *Importer) Run(ctx context.Context) (ImportStats, error) {
+	var stats ImportStats
+	batch := make([]*models.Log, 0, i.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written, err := i.sink.Write(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to write batch: %w", err)
+		}
+		stats.Written += written
+		batch = batch[:0]
+		return nil
+	}
+
+	lastProgress := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		rec, err := i.source.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Read++
+
+		log, err := i.mapRecord(rec)
+		if err == nil {
+			err = i.sink.Prepare(log)
+		}
+		if err != nil {
+			stats.Invalid++
+			i.logger.Warn("Skipping invalid record", "source", i.name, "record", rec.locator, "error", err)
+			if i.maxInvalid > 0 && stats.Invalid >= i.maxInvalid {
+				return stats, fmt.Errorf("aborted after %d invalid records", stats.Invalid)
+			}
+			continue
+		}
+
+		batch = append(batch, log)
+		if len(batch) >= i.batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+		if time.Since(lastProgress) >= constants.ImportProgressInterval {
+			i.logger.Info("Import progress", "source", i.name, "read", stats.Read, "invalid", stats.Invalid, "written", stats.Written)
+			lastProgress = time.Now()
+		}
+	}
+	return stats, flush()
+}
+
+// mapRecord builds a valid log from a record and identifies it
+func (i *Importer) mapRecord(rec *record) (*models.Log, error) {
+	if rec.invalid != "" {
+		return nil, errors.New(rec.invalid)
+	}
+
+	var log *models.Log
+	var err error
+	if i.json {
+		log, err = i.mapper.MapJSON(rec.raw)
+	} else {
+		log, err = i.mapper.Map(rec.fields, rec.raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Server-assigned fields are not taken from exports of another installation
+	log.ID = 0
+	log.CreatedAt = time.Time{}
+	if log.MessageID == nil || *log.MessageID == "" {
+		sum := sha256.Sum256([]byte(i.identity + "\x00" + rec.locator))
+		id := hex.EncodeToString(sum[:])[:constants.MessageIDHashLength]
+		log.MessageID = &id
+	}
+	return log, nil
+}
+
+// stringMap is a flag of comma-separated key=value pairs, e.g. timestamp=ts,level=severity
+type stringMap map[string]string
+
+// String formats the pairs
+func (m stringMap) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses the pairs, adding to those of earlier occurrences of the flag
+func (m stringMap) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			return fmt.Errorf("expected key=value pairs, got %q", pair)
+		}
+		m[key] = val
+	}
+	return nil
+}
+
+// newSink creates the destination of the imported logs
+func newSink(ctx context.Context, mode string, dryRun bool, cfg *config.Config, logger *slog.Logger) (sink, error) {
+	if dryRun {
+		return dryRunSink{}, nil
+	}
+
+	switch mode {
+	case constants.ImportModePipeline:
+		// An import replays history as fast as it can be read, so the collector's sampling would lose logs, and its
+		// rate limits, meant for logs as they happen, would drop most of them
+		cfg.Collector.SampleRates = nil
+		cfg.Collector.RateLimit = 0
+		cfg.Collector.ServiceRateLimits = nil
+		collector, err := producers.NewLogCollectorService(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+		}
+		return &pipelineSink{collector: collector}, nil
+
+	case constants.ImportModeDirect:
+		db, err := database.NewGormDB(&cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		fingerprintMasks, err := cfg.Pipeline.LoadFingerprintMasks()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		fingerprinter, err := parsers.NewFingerprinter(fingerprintMasks)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid fingerprint rules: %w", err)
+		}
+		redactionRules, err := cfg.Pipeline.LoadRedactionRules()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		redactor, err := parsers.NewRedactor(cfg.Pipeline.RedactBuiltins, redactionRules)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid redaction rules: %w", err)
+		}
+
+		if err := cfg.LogStore.Validate(&cfg.Routing); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid log store configuration: %w", err)
+		}
+		direct := &directSink{db: db, repo: logs.NewLogRepository(db), fingerprinter: fingerprinter, redactor: redactor}
+		if cfg.LogStore.Backend == constants.LogStoreClickHouse {
+			direct.clickHouse, err = logs.NewClickHouseLogRepository(ctx, &cfg.LogStore.ClickHouse)
+			if err != nil {
+				db.Close()
+				return nil, err
+			}
+			direct.repo = direct.clickHouse
+		}
+		if len(cfg.Routing.Rules) > 0 {
+			direct.shards, err = logs.NewShardedLogRepository(ctx, db, &cfg.Database, &cfg.Routing)
+			if err != nil {
+				db.Close()
+				return nil, err
+			}
+			direct.repo = direct.shards
+		}
+
+		// Writes pause while the shared maintenance switch is in read-only mode
+		direct.maintenance = services.NewMaintenanceService(maintenance.NewMaintenanceRepository(db.GetDB()), cfg.Maintenance, logger)
+		if err := direct.maintenance.Refresh(ctx); err != nil {
+			logger.Warn("Failed to load maintenance state", "error", err)
+		}
+		go direct.maintenance.Start(ctx)
+		return direct, nil
+
+	default:
+		return nil, fmt.Errorf("unknown mode %q, expected %s or %s", mode, constants.ImportModePipeline, constants.ImportModeDirect)
+	}
+}
+
+func main() {
+	// Initialize logger
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	fields, defaults := stringMap{}, stringMap{}
+	format := flag.String("format", "", "source format: ndjson, csv or table (default: csv for .csv files, ndjson otherwise)")
+	mode := flag.String("mode", constants.ImportModePipeline, "destination: pipeline publishes to Kafka, direct writes to the database")
+	flag.Var(fields, "map", "log columns to source fields, e.g. timestamp=ts,level=severity (default: same names)")
+	flag.Var(defaults, "default", "values of log columns missing from a record, e.g. service=billing")
+	timestampFormat := flag.String("timestamp-format", "", "Go time layout, unix or unix_ms (default: RFC 3339)")
+	dsn := flag.String("dsn", "", "DSN of the MySQL database holding the source table")
+	table := flag.String("table", "", "source table, optionally qualified by its database")
+	key := flag.String("key", constants.DefaultImportKeyColumn, "unique, ordered column the source table is read by")
+	where := flag.String("where", "", "SQL condition selecting the source table's rows")
+	batchSize := flag.Int("batch-size", constants.DefaultImportBatchSize, "logs written per batch")
+	maxInvalid := flag.Int("max-invalid", constants.DefaultImportMaxInvalid, "invalid records after which the import is aborted, 0 to never abort")
+	dryRun := flag.Bool("dry-run", false, "map and validate the records without writing them")
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  import [flags] FILE...\n  import -format table -dsn DSN -table TABLE [flags]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *batchSize <= 0 || *batchSize > constants.MaxImportBatchSize {
+		logger.Error("Invalid batch size", "batch_size", *batchSize, "max", constants.MaxImportBatchSize)
+		os.Exit(2)
+	}
+	if *format == constants.ImportFormatTable {
+		if *dsn == "" || *table == "" || flag.NArg() > 0 {
+			logger.Error("Table imports need -dsn and -table, and no files")
+			os.Exit(2)
+		}
+	} else if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	mapper, err := parsers.NewFieldMapper(config.ParserConfig{
+		Name:            constants.ImportParserName,
+		Fields:          fields,
+		Defaults:        defaults,
+		TimestampFormat: *timestampFormat,
+	})
+	if err != nil {
+		logger.Error("Invalid column mapping", "error", err)
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Stop between records on interrupt; logs published or stored until then stay, and a re-run skips them
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	destination, err := newSink(ctx, *mode, *dryRun, cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create import destination", "error", err)
+		os.Exit(1)
+	}
+
+	var importers []*Importer
+	newImporter := func(src source, name, identity string, json bool) *Importer {
+		return &Importer{
+			source:     src,
+			name:       name,
+			identity:   identity,
+			json:       json,
+			mapper:     mapper,
+			sink:       destination,
+			batchSize:  *batchSize,
+			maxInvalid: *maxInvalid,
+			logger:     logger,
+		}
+	}
+	openErr := func() error {
+		if *format == constants.ImportFormatTable {
+			src, err := newTableSource(ctx, *dsn, *table, *key, *where, *batchSize)
+			if err != nil {
+				return err
+			}
+			importers = append(importers, newImporter(src, *table, "table\x00"+*table, false))
+			return nil
+		}
+		for _, path := range flag.Args() {
+			identity, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", path, err)
+			}
+			fileFormat := *format
+			if fileFormat == "" {
+				fileFormat = constants.ImportFormatNDJSON
+				if strings.EqualFold(filepath.Ext(path), ".csv") {
+					fileFormat = constants.ImportFormatCSV
+				}
+			}
+
+			var src source
+			switch fileFormat {
+			case constants.ImportFormatNDJSON:
+				src, err = newNDJSONSource(path, constants.MaxImportLineBytes)
+			case constants.ImportFormatCSV:
+				src, err = newCSVSource(path)
+			default:
+				err = fmt.Errorf("unknown format %q, expected ndjson, csv or table", fileFormat)
+			}
+			if err != nil {
+				return err
+			}
+			importers = append(importers, newImporter(src, path, "file\x00"+identity, fileFormat == constants.ImportFormatNDJSON))
+		}
+		return nil
+	}()
+	defer func() {
+		for _, importer := range importers {
+			importer.source.Close()
+		}
+	}()
+	if openErr != nil {
+		logger.Error("Failed to open import source", "error", openErr)
+		destination.Close()
+		os.Exit(1)
+	}
+
+	logger.Info("Import started", "sources", len(importers), "mode", *mode, "dry_run", *dryRun)
+	started := time.Now()
+	var total ImportStats
+	var runErr error
+	for _, importer := range importers {
+		stats, err := importer.Run(ctx)
+		total.Add(stats)
+		logger.Info("Source imported", "source", importer.name, "read", stats.Read, "invalid", stats.Invalid, "written", stats.Written)
+		if err != nil {
+			runErr = fmt.Errorf("%s: %w", importer.name, err)
+			break
+		}
+	}
+
+	// Closing flushes logs still buffered by the producer
+	if err := destination.Close(); err != nil && runErr == nil {
+		runErr = fmt.Errorf("failed to close import destination: %w", err)
+	}
+	logger.Info("Import finished", "read", total.Read, "invalid", total.Invalid, "written", total.Written, "duration", time.Since(started))
+	if runErr != nil {
+		logger.Error("Import failed", "error", runErr)
+		os.Exit(1)
+	}
+}
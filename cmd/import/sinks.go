@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/kafka/producers"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/parsers"
+	"github.com/adeesh/log-analytics/internal/services"
+)
+
+// sink is where imported logs are written
+type sink interface {
+	// Prepare readies a valid log for the destination, returning an error when the destination rejects it
+	Prepare(log *models.Log) error
+	// Write writes a batch of prepared logs, returning how many were stored. Logs stored by an earlier run
+	// aren't counted where that is known.
+	Write(ctx context.Context, batch []*models.Log) (int, error)
+	Close() error
+}
+
+// pipelineSink publishes logs to Kafka through the collector, so the log processor parses, enriches, fingerprints
+// and stores them like live logs, applying the dynamic pipeline settings of their services
+type pipelineSink struct {
+	collector *producers.LogCollectorService
+}
+
+// Prepare applies the body capture settings and checks the log's tenant, as HTTP ingestion does
+func (s *pipelineSink) Prepare(log *models.Log) error {
+	s.collector.CaptureBodies(log)
+	if err := log.Validate(); err != nil {
+		return err
+	}
+	if log.Tenant != nil && !s.collector.HasTenant(*log.Tenant) {
+		return fmt.Errorf("unknown tenant %q", *log.Tenant)
+	}
+	return nil
+}
+
+// Write publishes the batch. Whether the processor finds logs already stored isn't known here, so every
+// published log is counted.
+func (s *pipelineSink) Write(ctx context.Context, batch []*models.Log) (int, error) {
+	if err := s.collector.SendLogs(ctx, batch); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// Close flushes logs buffered by the collector in async mode
+func (s *pipelineSink) Close() error {
+	return s.collector.Close()
+}
+
+// directSink writes logs to the log repository, bypassing Kafka. Logs are redacted and fingerprinted as the processor
+// would, but the dynamic pipeline settings and enrichment don't apply.
+type directSink struct {
+	db            *database.GormDB
+	repo          logs.LogRepository
+	shards        *logs.ShardedLogRepository    // nil without routing rules
+	clickHouse    *logs.ClickHouseLogRepository // nil unless logs are stored in ClickHouse
+	fingerprinter *parsers.Fingerprinter
+	redactor      *parsers.Redactor
+	maintenance   *services.MaintenanceService
+}
+
+// Prepare masks sensitive data and fingerprints ERROR and FATAL logs
+func (s *directSink) Prepare(log *models.Log) error {
+	s.redactor.Redact(log)
+	log.Fingerprint = s.fingerprinter.Fingerprint(log)
+	return nil
+}
+
+// Write stores the batch once writes aren't paused for maintenance. The repository leaves the IDs of logs it
+// found stored at zero.
+func (s *directSink) Write(ctx context.Context, batch []*models.Log) (int, error) {
+	if err := s.maintenance.WaitWritable(ctx); err != nil {
+		return 0, fmt.Errorf("writes paused for maintenance: %w", err)
+	}
+	if err := s.repo.CreateLogBatch(ctx, batch); err != nil {
+		return 0, err
+	}
+	stored := 0
+	for _, log := range batch {
+		if log.ID != 0 {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// Close closes the shard and ClickHouse connections and the database
+func (s *directSink) Close() error {
+	if s.shards != nil {
+		s.shards.Close()
+	}
+	if s.clickHouse != nil {
+		s.clickHouse.Close()
+	}
+	return s.db.Close()
+}
+
+// dryRunSink discards logs, so that a mapping can be checked against a source without writing anything
+type dryRunSink struct{}
+
+// Prepare accepts every valid log
+func (dryRunSink) Prepare(log *models.Log) error {
+	return nil
+}
+
+// Write discards the batch
+func (dryRunSink) Write(ctx context.Context, batch []*models.Log) (int, error) {
+	return 0, nil
+}
+
+// Close does nothing
+func (dryRunSink) Close() error {
+	return nil
+}
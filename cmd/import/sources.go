@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// record is a single historical log as read from a source, before it is mapped to a log
+type record struct {
+	locator string            // line number in files, key in tables, identifying the record across re-runs
+	raw     []byte            // the line or CSV row as read, nil for table rows
+	fields  map[string]string // columns of CSV rows and table rows; nil for NDJSON lines
+	invalid string            // why the record couldn't be read, e.g. a malformed CSV row
+}
+
+// source reads records one at a time, returning io.EOF once exhausted
+type source interface {
+	Next(ctx context.Context) (*record, error)
+	Close() error
+}
+
+// identifier matches the table and column names a table source accepts, optionally qualified by a database
+var identifier = regexp.MustCompile(`^[A-Za-z0-9_$]+(\.[A-Za-z0-9_$]+)?$`)
+
+// ndjsonSource reads one JSON object per line. Blank lines are skipped.
+type ndjsonSource struct {
+	path    string
+	file    *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+// newNDJSONSource opens an NDJSON file
+func newNDJSONSource(path string, maxLineBytes int) (*ndjsonSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	return &ndjsonSource{path: path, file: file, scanner: scanner}, nil
+}
+
+// Next returns the next non-blank line
+func (s *ndjsonSource) Next(ctx context.Context) (*record, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return &record{locator: strconv.Itoa(s.line), raw: bytes.Clone(line)}, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s after line %d: %w", s.path, s.line, err)
+	}
+	return nil, io.EOF
+}
+
+// Close closes the file
+func (s *ndjsonSource) Close() error {
+	return s.file.Close()
+}
+
+// csvSource reads CSV rows whose columns are named by the header row. Empty cells are left out of the fields.
+type csvSource struct {
+	path   string
+	file   *os.File
+	reader *csv.Reader
+	header []string
+}
+
+// newCSVSource opens a CSV file and reads its header row
+func newCSVSource(path string) (*csvSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // short and long rows are reported per record instead of ending the import
+
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read the header row of %s: %w", path, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	return &csvSource{path: path, file: file, reader: reader, header: header}, nil
+}
+
+// Next returns the next row. Malformed rows and rows with a different number of cells than the header are
+// returned as invalid, and reading continues with the next row.
+func (s *csvSource) Next(ctx context.Context) (*record, error) {
+	row, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &record{locator: strconv.Itoa(parseErr.StartLine), invalid: parseErr.Err.Error()}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	line, _ := s.reader.FieldPos(0)
+	locator := strconv.Itoa(line)
+
+	raw := []byte(strings.Join(row, ","))
+	if len(row) != len(s.header) {
+		return &record{locator: locator, invalid: fmt.Sprintf("row has %d columns, the header %d", len(row), len(s.header))}, nil
+	}
+	fields := make(map[string]string, len(row))
+	for i, value := range row {
+		if value != "" {
+			fields[s.header[i]] = value
+		}
+	}
+	return &record{locator: locator, raw: raw, fields: fields}, nil
+}
+
+// Close closes the file
+func (s *csvSource) Close() error {
+	return s.file.Close()
+}
+
+// tableSource pages through a logging table in another MySQL database by a unique, ordered key column, so
+// that large tables are read in bounded queries without OFFSET scans. NULL columns are left out of the fields.
+type tableSource struct {
+	db        *sql.DB
+	table     string
+	key       string
+	where     string
+	batchSize int
+	after     any // key of the last row read, nil before the first page
+	rows      []*record
+	done      bool
+}
+
+// newTableSource connects to the source database
+func newTableSource(ctx context.Context, dsn, table, key, where string, batchSize int) (*tableSource, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	if !identifier.MatchString(key) || strings.Contains(key, ".") {
+		return nil, fmt.Errorf("invalid key column %q", key)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source database: %w", err)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to source database: %w", err)
+	}
+	return &tableSource{db: db, table: table, key: key, where: where, batchSize: batchSize}, nil
+}
+
+// Next returns the next row, fetching the next page when the current one is exhausted
+func (s *tableSource) Next(ctx context.Context) (*record, error) {
+	if len(s.rows) == 0 {
+		if s.done {
+			return nil, io.EOF
+		}
+		if err := s.fetch(ctx); err != nil {
+			return nil, err
+		}
+		if len(s.rows) == 0 {
+			return nil, io.EOF
+		}
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+// fetch reads the page of rows after the last key read
+func (s *tableSource) fetch(ctx context.Context) error {
+	var conditions []string
+	var args []any
+	if s.where != "" {
+		conditions = append(conditions, "("+s.where+")")
+	}
+	if s.after != nil {
+		conditions = append(conditions, quoteIdentifier(s.key)+" > ?")
+		args = append(args, s.after)
+	}
+	query := "SELECT * FROM " + quoteIdentifier(s.table)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", quoteIdentifier(s.key), s.batchSize)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query source table: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read source table columns: %w", err)
+	}
+	keyIndex := -1
+	for i, column := range columns {
+		if strings.EqualFold(column, s.key) {
+			keyIndex = i
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("source table has no key column %q", s.key)
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to scan source row: %w", err)
+		}
+		fields := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if value, ok := columnString(values[i]); ok {
+				fields[column] = value
+			}
+		}
+		key, ok := fields[columns[keyIndex]]
+		if !ok {
+			return fmt.Errorf("source table has a row whose key column %q is NULL", s.key)
+		}
+		s.after = values[keyIndex]
+		if bytesValue, ok := s.after.([]byte); ok {
+			s.after = string(bytesValue)
+		}
+		s.rows = append(s.rows, &record{locator: key, fields: fields})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read source table: %w", err)
+	}
+	s.done = len(s.rows) < s.batchSize
+	return nil
+}
+
+// Close closes the source database
+func (s *tableSource) Close() error {
+	return s.db.Close()
+}
+
+// columnString formats a scanned column value, reporting false for NULL. Times, returned when the DSN sets
+// parseTime, are formatted as RFC 3339.
+func columnString(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case []byte:
+		return string(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// quoteIdentifier quotes a table or column name, and the database qualifying it
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + part + "`"
+	}
+	return strings.Join(parts, ".")
+}
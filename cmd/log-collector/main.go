@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/adeesh/log-analytics/internal/config"
 	"github.com/adeesh/log-analytics/internal/kafka/producers"
 	"log/slog"
@@ -9,13 +10,21 @@ import (
 )
 
 func main() {
-	// Initialize logger
+	// Initialize logger, at the configured level once the configuration is loaded
+	logLevel := new(slog.LevelVar)
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration, from the config file given with -config, or CONFIG_FILE, under the environment
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Parse()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Log.Level)
 
 	// Create log collector service
 	service, err := producers.NewLogCollectorService(cfg, logger)
@@ -25,6 +34,14 @@ func main() {
 	}
 	defer service.Close()
 
+	// Apply the log level and sampling of the reloaded configuration on SIGHUP or config file changes
+	reloader := config.NewReloader(cfg, logger)
+	reloader.OnReload(func(cfg *config.Config) {
+		logLevel.Set(cfg.Log.Level)
+		service.Reload(cfg)
+	})
+	go reloader.Start(context.Background())
+
 	// Start the service
 	if err := service.Start(context.Background()); err != nil {
 		logger.Error("Log collector service error", "error", err)
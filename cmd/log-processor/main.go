@@ -2,20 +2,36 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/adeesh/log-analytics/internal/config"
 	"github.com/adeesh/log-analytics/internal/kafka/consumers"
+	"github.com/adeesh/log-analytics/internal/migrator"
 	"log/slog"
 	"os"
 )
 
 func main() {
-	// Initialize logger
+	// Initialize logger, at the configured level once the configuration is loaded
+	logLevel := new(slog.LevelVar)
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration, from the config file given with -config, or CONFIG_FILE, under the environment
+	configFile := flag.String("config", "", "YAML config file, overridden by environment variables (default: CONFIG_FILE)")
+	flag.Parse()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logLevel.Set(cfg.Log.Level)
+
+	// Bring the schema up to date first, when configured to
+	if err := migrator.RunOnStartup(cfg, logger); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		os.Exit(1)
+	}
 
 	// Create log processor service
 	service, err := consumers.NewLogProcessorService(cfg, logger)
@@ -23,11 +39,23 @@ func main() {
 		logger.Error("Failed to create log processor service", "error", err)
 		os.Exit(1)
 	}
-	defer service.Close()
 
-	// Start the service
-	if err := service.Start(context.Background()); err != nil {
+	// Apply the log level and batching of the reloaded configuration on SIGHUP or config file changes, without
+	// leaving the consumer groups
+	reloader := config.NewReloader(cfg, logger)
+	reloader.OnReload(func(cfg *config.Config) {
+		logLevel.Set(cfg.Log.Level)
+		service.Reload(cfg)
+	})
+	go reloader.Start(context.Background())
+
+	// Start the service; it returns once a shutdown signal drained the batches in flight. The service is closed
+	// before exiting either way, so the consumer groups are left and the database closed.
+	err = service.Start(context.Background())
+	service.Close()
+	if err != nil {
 		logger.Error("Log processor service error", "error", err)
 		os.Exit(1)
 	}
+	logger.Info("Log processor stopped")
 }
@@ -0,0 +1,161 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code identifies the category of an application error
+type Code string
+
+const (
+	CodeNotFound     Code = "NOT_FOUND"
+	CodeValidation   Code = "VALIDATION"
+	CodeInvalid      Code = "INVALID" // well-formed request bodies with invalid fields
+	CodeConflict     Code = "CONFLICT"
+	CodeUnavailable  Code = "UNAVAILABLE"
+	CodeUnauthorized Code = "UNAUTHORIZED"
+	CodeForbidden    Code = "FORBIDDEN"
+	CodeRateLimited  Code = "RATE_LIMITED"
+	CodeInternal     Code = "INTERNAL"
+)
+
+// Error is a typed application error carrying a code and a client-safe message
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError // fields of the request body that are invalid, for CodeInvalid errors
+	Err     error
+}
+
+// FieldError tells why a field of a request body is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates a new application error with the given code
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap wraps an existing error with a code and message
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// NotFound creates a not found error
+func NotFound(format string, args ...interface{}) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+// Validation creates a validation error
+func Validation(format string, args ...interface{}) *Error {
+	return New(CodeValidation, format, args...)
+}
+
+// Invalid creates an error for a request body whose fields are invalid. Its message lists the fields' errors, for
+// clients that don't read them separately.
+func Invalid(fields ...FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return &Error{Code: CodeInvalid, Message: strings.Join(messages, "; "), Fields: fields}
+}
+
+// Conflict creates a conflict error
+func Conflict(format string, args ...interface{}) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+// Unavailable creates an unavailable error
+func Unavailable(format string, args ...interface{}) *Error {
+	return New(CodeUnavailable, format, args...)
+}
+
+// Unauthorized creates an error for missing or invalid credentials
+func Unauthorized(format string, args ...interface{}) *Error {
+	return New(CodeUnauthorized, format, args...)
+}
+
+// Forbidden creates an error for authenticated callers lacking permission
+func Forbidden(format string, args ...interface{}) *Error {
+	return New(CodeForbidden, format, args...)
+}
+
+// RateLimited creates an error for callers exceeding a request rate or volume cap
+func RateLimited(format string, args ...interface{}) *Error {
+	return New(CodeRateLimited, format, args...)
+}
+
+// CodeOf returns the code of the first application error in the chain, or CodeInternal
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// Is reports whether err carries the given code
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
+
+// FieldsOf returns the field errors of the first application error in the chain, if any
+func FieldsOf(err error) []FieldError {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Fields
+	}
+	return nil
+}
+
+// MessageOf returns the client-safe message of an application error, or the fallback
+func MessageOf(err error, fallback string) string {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Code != CodeInternal {
+		return appErr.Message
+	}
+	return fallback
+}
+
+// HTTPStatus maps an error to the matching HTTP status code
+func HTTPStatus(err error) int {
+	switch CodeOf(err) {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeInvalid:
+		return http.StatusUnprocessableEntity
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,157 @@
+package arrow
+
+import "encoding/binary"
+
+// The metadata of Arrow IPC messages is encoded as FlatBuffers. Only the few message kinds written here are
+// needed, so instead of generated code a message is described as a tree of tables, vectors and strings and
+// written front to back: every table, vector and string is followed by the objects it references, which keeps
+// all offsets pointing forward as FlatBuffers requires.
+
+// fbObject is a FlatBuffers object that can be referenced by offset
+type fbObject interface {
+	// write appends the object and the objects it references, returning the position offsets must point to
+	write(b *fbBuilder) int
+}
+
+// fbField is a table field: a little-endian scalar of size bytes, or a reference to an object
+type fbField struct {
+	size  int
+	value uint64
+	ref   fbObject
+}
+
+// fbTable is a table whose fields are indexed by field ID; unset fields are absent and read as defaults
+type fbTable []*fbField
+
+// fbString is a string
+type fbString string
+
+// fbVector is a vector of references to objects
+type fbVector []fbObject
+
+// fbPairs is a vector of structs made of two longs, the layout of both Arrow's FieldNode and Buffer
+type fbPairs [][2]int64
+
+func fbInt8(v uint8) *fbField     { return &fbField{size: 1, value: uint64(v)} }
+func fbInt16(v int16) *fbField    { return &fbField{size: 2, value: uint64(uint16(v))} }
+func fbInt32(v int32) *fbField    { return &fbField{size: 4, value: uint64(uint32(v))} }
+func fbInt64(v int64) *fbField    { return &fbField{size: 8, value: uint64(v)} }
+func fbRef(ref fbObject) *fbField { return &fbField{size: 4, ref: ref} }
+
+func fbBool(v bool) *fbField {
+	if v {
+		return fbInt8(1)
+	}
+	return fbInt8(0)
+}
+
+// fbBuilder accumulates an encoded buffer
+type fbBuilder struct {
+	buf []byte
+}
+
+// encodeFlatBuffer encodes a buffer whose root is the table
+func encodeFlatBuffer(root fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	b.putOffset(0, root.write(b))
+	return b.buf
+}
+
+// align pads the buffer to a multiple of n bytes
+func (b *fbBuilder) align(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// reserve appends n zero bytes, returning their position
+func (b *fbBuilder) reserve(n int) int {
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, n)...)
+	return pos
+}
+
+// putOffset stores at pos the offset of the object at target
+func (b *fbBuilder) putOffset(pos, target int) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(target-pos))
+}
+
+func (t fbTable) write(b *fbBuilder) int {
+	// The vtable precedes the table and lists the position of each field within the table
+	b.align(2)
+	vtable := b.reserve(4 + 2*len(t))
+
+	// Fields are laid out largest first, each aligned to its size, after the offset to the vtable
+	fieldAlign := 4
+	for _, field := range t {
+		if field != nil && field.size > fieldAlign {
+			fieldAlign = field.size
+		}
+	}
+	b.align(fieldAlign)
+	table := b.reserve(4)
+	binary.LittleEndian.PutUint32(b.buf[table:], uint32(int32(table-vtable)))
+
+	positions := make([]int, len(t))
+	for _, size := range []int{8, 4, 2, 1} {
+		for id, field := range t {
+			if field == nil || field.size != size {
+				continue
+			}
+			b.align(size)
+			positions[id] = b.reserve(size)
+			for i := 0; i < size; i++ {
+				b.buf[positions[id]+i] = byte(field.value >> (8 * i))
+			}
+		}
+	}
+
+	binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(4+2*len(t)))
+	binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(len(b.buf)-table))
+	for id, field := range t {
+		if field != nil {
+			binary.LittleEndian.PutUint16(b.buf[vtable+4+2*id:], uint16(positions[id]-table))
+		}
+	}
+
+	for id, field := range t {
+		if field != nil && field.ref != nil {
+			b.putOffset(positions[id], field.ref.write(b))
+		}
+	}
+	return table
+}
+
+func (s fbString) write(b *fbBuilder) int {
+	b.align(4)
+	pos := b.reserve(4)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+func (v fbVector) write(b *fbBuilder) int {
+	b.align(4)
+	pos := b.reserve(4 + 4*len(v))
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(v)))
+	for i, elem := range v {
+		b.putOffset(pos+4+4*i, elem.write(b))
+	}
+	return pos
+}
+
+func (p fbPairs) write(b *fbBuilder) int {
+	// The elements follow the length and must be aligned to 8 bytes
+	b.align(4)
+	if len(b.buf)%8 == 0 {
+		b.reserve(4)
+	}
+	pos := b.reserve(4 + 16*len(p))
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(p)))
+	for i, pair := range p {
+		binary.LittleEndian.PutUint64(b.buf[pos+4+16*i:], uint64(pair[0]))
+		binary.LittleEndian.PutUint64(b.buf[pos+12+16*i:], uint64(pair[1]))
+	}
+	return pos
+}
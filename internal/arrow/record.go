@@ -0,0 +1,186 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Type is the type of a column
+type Type int
+
+const (
+	// String columns hold UTF-8 strings
+	String Type = iota
+	// Int64 columns hold signed 64-bit integers
+	Int64
+	// Float64 columns hold double precision floats
+	Float64
+	// Timestamp columns hold UTC times with microsecond precision
+	Timestamp
+)
+
+// Field names a column and gives its type. All columns are nullable.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Record is a batch of rows built column by column
+type Record struct {
+	fields  []Field
+	columns []*column
+	rows    int
+}
+
+// column accumulates the validity bitmap, offsets and values of a column
+type column struct {
+	typ       Type
+	validity  []byte
+	nulls     int
+	offsets   []byte // of String columns, one more than there are rows
+	values    []byte
+	valueSize int
+}
+
+// NewRecord creates an empty record with the given columns
+func NewRecord(fields ...Field) *Record {
+	r := &Record{fields: fields}
+	for _, field := range fields {
+		col := &column{typ: field.Type}
+		if field.Type == String {
+			col.offsets = binary.LittleEndian.AppendUint32(nil, 0)
+		}
+		r.columns = append(r.columns, col)
+	}
+	return r
+}
+
+// Rows returns the number of rows appended
+func (r *Record) Rows() int {
+	return r.rows
+}
+
+// Append adds a row with a value for every column. A nil value, or a nil pointer, is null. String columns take
+// strings and types derived from string; Int64 columns int, int64 and uint; Float64 columns float64; Timestamp
+// columns time.Time; as well as pointers to those.
+func (r *Record) Append(values ...any) error {
+	if len(values) != len(r.fields) {
+		return fmt.Errorf("got %d values for %d columns", len(values), len(r.fields))
+	}
+	for i, value := range values {
+		if err := r.columns[i].append(r.rows, value); err != nil {
+			return fmt.Errorf("column %s: %w", r.fields[i].Name, err)
+		}
+	}
+	r.rows++
+	return nil
+}
+
+// append adds the value of the row-th row
+func (c *column) append(row int, value any) error {
+	value = deref(value)
+	if row%8 == 0 {
+		c.validity = append(c.validity, 0)
+	}
+
+	var (
+		bits  uint64
+		valid = value != nil
+	)
+	switch c.typ {
+	case String:
+		if valid {
+			s, ok := stringValue(value)
+			if !ok {
+				return fmt.Errorf("expected a string, got %T", value)
+			}
+			c.values = append(c.values, s...)
+		}
+		c.offsets = binary.LittleEndian.AppendUint32(c.offsets, uint32(len(c.values)))
+	case Int64:
+		switch v := value.(type) {
+		case nil:
+		case int:
+			bits = uint64(v)
+		case int64:
+			bits = uint64(v)
+		case uint:
+			bits = uint64(v)
+		default:
+			return fmt.Errorf("expected an integer, got %T", value)
+		}
+	case Float64:
+		switch v := value.(type) {
+		case nil:
+		case float64:
+			bits = math.Float64bits(v)
+		default:
+			return fmt.Errorf("expected a float, got %T", value)
+		}
+	case Timestamp:
+		switch v := value.(type) {
+		case nil:
+		case time.Time:
+			bits = uint64(v.UnixMicro())
+		default:
+			return fmt.Errorf("expected a time, got %T", value)
+		}
+	}
+	if c.typ != String {
+		c.values = binary.LittleEndian.AppendUint64(c.values, bits)
+	}
+
+	if valid {
+		c.validity[row/8] |= 1 << (row % 8)
+	} else {
+		c.nulls++
+	}
+	return nil
+}
+
+// deref replaces pointers by the values they point to, and nil pointers by nil
+func deref(value any) any {
+	switch v := value.(type) {
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case *int:
+		if v != nil {
+			return *v
+		}
+	case *int64:
+		if v != nil {
+			return *v
+		}
+	case *float64:
+		if v != nil {
+			return *v
+		}
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+	default:
+		// Pointers to named types, such as those derived from string
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil
+			}
+			return v.Elem().Interface()
+		}
+		return value
+	}
+	return nil
+}
+
+// stringValue returns the string of a string column value, including values of named string types
+func stringValue(value any) (string, bool) {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.String {
+		return v.String(), true
+	}
+	return "", false
+}
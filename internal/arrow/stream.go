@@ -0,0 +1,151 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ContentType is the media type of the Arrow IPC streaming format
+const ContentType = "application/vnd.apache.arrow.stream"
+
+// Flatbuffers enum values of the Arrow format (Schema.fbs and Message.fbs)
+const (
+	metadataV5 = 4
+
+	headerSchema      = 1
+	headerRecordBatch = 3
+
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeUtf8          = 5
+	typeTimestamp     = 10
+
+	precisionDouble  = 2
+	unitMicrosecond  = 2
+	endiannessLittle = 0
+)
+
+// continuationMarker precedes every message of a stream
+const continuationMarker = 0xFFFFFFFF
+
+// StreamWriter writes records in the Arrow IPC streaming format, which analytical clients such as pyarrow,
+// polars and DuckDB read without parsing: the schema, followed by each record as a record batch.
+type StreamWriter struct {
+	w      io.Writer
+	fields []Field
+	err    error
+}
+
+// NewStreamWriter writes the schema of a stream with the given columns
+func NewStreamWriter(w io.Writer, fields ...Field) *StreamWriter {
+	s := &StreamWriter{w: w, fields: fields}
+	s.writeMessage(headerSchema, schemaTable(fields), nil)
+	return s
+}
+
+// Write writes a record, whose columns must be those of the stream, as a record batch
+func (s *StreamWriter) Write(record *Record) error {
+	if s.err == nil && !slices.Equal(record.fields, s.fields) {
+		s.err = fmt.Errorf("record columns differ from the stream schema")
+	}
+
+	var (
+		body    []byte
+		nodes   fbPairs
+		buffers fbPairs
+	)
+	addBuffer := func(data []byte) {
+		buffers = append(buffers, [2]int64{int64(len(body)), int64(len(data))})
+		body = append(body, data...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+	for _, col := range record.columns {
+		nodes = append(nodes, [2]int64{int64(record.rows), int64(col.nulls)})
+		// Columns without nulls may omit their validity bitmap
+		if col.nulls > 0 {
+			addBuffer(col.validity)
+		} else {
+			addBuffer(nil)
+		}
+		if col.typ == String {
+			addBuffer(col.offsets)
+		}
+		addBuffer(col.values)
+	}
+
+	batch := fbTable{
+		fbInt64(int64(record.rows)),
+		fbRef(nodes),
+		fbRef(buffers),
+	}
+	s.writeMessage(headerRecordBatch, batch, body)
+	return s.err
+}
+
+// Close ends the stream. It doesn't close the underlying writer.
+func (s *StreamWriter) Close() error {
+	s.write(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, continuationMarker), 0))
+	return s.err
+}
+
+// writeMessage writes an encapsulated message: the continuation marker, the length of the metadata, the metadata
+// padded so that the body starts 8-byte aligned, and the body
+func (s *StreamWriter) writeMessage(headerType uint8, header fbTable, body []byte) {
+	metadata := encodeFlatBuffer(fbTable{
+		fbInt16(metadataV5),
+		fbInt8(headerType),
+		fbRef(header),
+		fbInt64(int64(len(body))),
+	})
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+
+	prefix := binary.LittleEndian.AppendUint32(nil, continuationMarker)
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(len(metadata)))
+	s.write(prefix)
+	s.write(metadata)
+	s.write(body)
+}
+
+// write writes data unless a previous write failed
+func (s *StreamWriter) write(data []byte) {
+	if s.err == nil {
+		_, s.err = s.w.Write(data)
+	}
+}
+
+// schemaTable describes the columns as a Schema table
+func schemaTable(fields []Field) fbTable {
+	var columns fbVector
+	for _, field := range fields {
+		var typeID uint8
+		var typ fbTable
+		switch field.Type {
+		case String:
+			typeID, typ = typeUtf8, fbTable{}
+		case Int64:
+			typeID, typ = typeInt, fbTable{fbInt32(64), fbBool(true)}
+		case Float64:
+			typeID, typ = typeFloatingPoint, fbTable{fbInt16(precisionDouble)}
+		case Timestamp:
+			typeID, typ = typeTimestamp, fbTable{fbInt16(unitMicrosecond), fbRef(fbString("UTC"))}
+		}
+		columns = append(columns, fbTable{
+			fbRef(fbString(field.Name)),
+			fbBool(true),
+			fbInt8(typeID),
+			fbRef(typ),
+			nil,
+			fbRef(fbVector{}),
+		})
+	}
+	return fbTable{
+		fbInt16(endiannessLittle),
+		fbRef(columns),
+	}
+}
@@ -0,0 +1,216 @@
+package arrow
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	arrowgo "github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// level is a named string type, as log levels are
+type level string
+
+var testFields = []Field{
+	{Name: "id", Type: Int64},
+	{Name: "timestamp", Type: Timestamp},
+	{Name: "level", Type: String},
+	{Name: "message", Type: String},
+	{Name: "response_time_ms", Type: Float64},
+}
+
+// testRow is a row of testFields, with nil for nulls
+type testRow struct {
+	id        int64
+	timestamp *time.Time
+	level     *string
+	message   *string
+	latency   *float64
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// testBatches are written as one record batch each. The first has no nulls, so it omits its validity bitmaps; the
+// second has nulls in every column but the first, including its first and ninth rows, across a bitmap byte.
+func testBatches() [][]testRow {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	var second []testRow
+	for i := range 10 {
+		row := testRow{
+			id:        int64(100 + i),
+			timestamp: ptr(at.Add(time.Duration(i) * time.Second)),
+			level:     ptr("WARN"),
+			message:   ptr(string(rune('a' + i))),
+			latency:   ptr(float64(i) / 4),
+		}
+		if i == 0 || i == 8 {
+			row.timestamp, row.level, row.message, row.latency = nil, nil, nil, nil
+		}
+		second = append(second, row)
+	}
+	return [][]testRow{
+		{
+			{id: 1, timestamp: &at, level: ptr("INFO"), message: ptr("started"), latency: ptr(12.5)},
+			{id: -2, timestamp: ptr(at.Add(time.Microsecond)), level: ptr("ERROR"), message: ptr("héllo, wörld"), latency: ptr(0.0)},
+			{id: 3, timestamp: ptr(at.In(time.FixedZone("CET", 3600))), level: ptr(""), message: ptr(""), latency: ptr(-1.25)},
+		},
+		second,
+	}
+}
+
+// writeTestStream writes the test batches as a stream, appending values of the types handlers pass
+func writeTestStream(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	stream := NewStreamWriter(&buf, testFields...)
+	for _, batch := range testBatches() {
+		record := NewRecord(testFields...)
+		for _, row := range batch {
+			var lvl *level
+			if row.level != nil {
+				lvl = ptr(level(*row.level))
+			}
+			var message any
+			if row.message != nil {
+				message = *row.message
+			}
+			if err := record.Append(int(row.id), row.timestamp, lvl, message, row.latency); err != nil {
+				t.Fatalf("failed to append row: %v", err)
+			}
+		}
+		if err := stream.Write(record); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("failed to close stream: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestStreamWriterGolden keeps the bytes of the stream from changing unnoticed. Run with -update to rewrite the
+// golden file after an intended change, which TestStreamWriterReadByArrow then checks.
+func TestStreamWriterGolden(t *testing.T) {
+	got := writeTestStream(t)
+	golden := filepath.Join("testdata", "logs.arrows")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("stream differs from %s, run go test -update if intended", golden)
+	}
+}
+
+// TestStreamWriterReadByArrow reads the golden stream with the Arrow Go implementation, checking its schema and that
+// every value, including nulls, reads back as written
+func TestStreamWriterReadByArrow(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "logs.arrows"))
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	reader, err := ipc.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer reader.Release()
+
+	wantTypes := []arrowgo.DataType{
+		arrowgo.PrimitiveTypes.Int64,
+		&arrowgo.TimestampType{Unit: arrowgo.Microsecond, TimeZone: "UTC"},
+		arrowgo.BinaryTypes.String,
+		arrowgo.BinaryTypes.String,
+		arrowgo.PrimitiveTypes.Float64,
+	}
+	schema := reader.Schema()
+	if schema.NumFields() != len(testFields) {
+		t.Fatalf("got %d fields, want %d", schema.NumFields(), len(testFields))
+	}
+	for i, field := range schema.Fields() {
+		if field.Name != testFields[i].Name || !arrowgo.TypeEqual(field.Type, wantTypes[i]) || !field.Nullable {
+			t.Errorf("field %d is %s, want nullable %s: %s", i, field, testFields[i].Name, wantTypes[i])
+		}
+	}
+
+	batches := testBatches()
+	read := 0
+	for reader.Next() {
+		if read >= len(batches) {
+			t.Fatalf("got more than %d record batches", len(batches))
+		}
+		record := reader.Record()
+		want := batches[read]
+		if int(record.NumRows()) != len(want) {
+			t.Fatalf("batch %d has %d rows, want %d", read, record.NumRows(), len(want))
+		}
+		ids := record.Column(0).(*array.Int64)
+		timestamps := record.Column(1).(*array.Timestamp)
+		levels := record.Column(2).(*array.String)
+		messages := record.Column(3).(*array.String)
+		latencies := record.Column(4).(*array.Float64)
+		for i, row := range want {
+			if ids.IsNull(i) || ids.Value(i) != row.id {
+				t.Errorf("batch %d row %d: id %v, want %d", read, i, ids.GetOneForMarshal(i), row.id)
+			}
+			checkValue(t, read, i, "timestamp", timestamps.IsNull(i), func() bool {
+				return time.UnixMicro(int64(timestamps.Value(i))).Equal(*row.timestamp)
+			}, row.timestamp == nil)
+			checkValue(t, read, i, "level", levels.IsNull(i), func() bool { return levels.Value(i) == *row.level }, row.level == nil)
+			checkValue(t, read, i, "message", messages.IsNull(i), func() bool { return messages.Value(i) == *row.message }, row.message == nil)
+			checkValue(t, read, i, "response_time_ms", latencies.IsNull(i), func() bool { return latencies.Value(i) == *row.latency }, row.latency == nil)
+		}
+		read++
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if read != len(batches) {
+		t.Errorf("got %d record batches, want %d", read, len(batches))
+	}
+}
+
+// checkValue checks that a value is null when the row has none, and equal to the row's value otherwise
+func checkValue(t *testing.T, batch, row int, column string, null bool, equal func() bool, wantNull bool) {
+	t.Helper()
+	switch {
+	case null != wantNull:
+		t.Errorf("batch %d row %d: %s null %v, want %v", batch, row, column, null, wantNull)
+	case !null && !equal():
+		t.Errorf("batch %d row %d: %s differs from the value written", batch, row, column)
+	}
+}
+
+func TestRecordAppendErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []any
+	}{
+		{name: "too few values", values: []any{1, time.Now(), "INFO", "message"}},
+		{name: "string for an integer", values: []any{"1", time.Now(), "INFO", "message", 1.0}},
+		{name: "integer for a time", values: []any{1, 1, "INFO", "message", 1.0}},
+		{name: "integer for a string", values: []any{1, time.Now(), 1, "message", 1.0}},
+		{name: "integer for a float", values: []any{1, time.Now(), "INFO", "message", 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := NewRecord(testFields...)
+			if err := record.Append(tt.values...); err == nil {
+				t.Error("Append() succeeded, want an error")
+			}
+		})
+	}
+}
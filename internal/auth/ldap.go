@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPProvider authenticates users by binding to an LDAP or Active Directory server as the user,
+// then maps the groups listed on the user's entry to a role and, optionally, a tenant
+type LDAPProvider struct {
+	cfg          config.LDAPConfig
+	tls          *tls.Config       // for ldaps:// and StartTLS
+	groupRoles   map[string]string // normalized group DN -> role
+	groupTenants map[string]string // normalized group DN -> tenant
+}
+
+// NewLDAPProvider creates a new LDAP authentication provider
+func NewLDAPProvider(cfg *config.LDAPConfig) (*LDAPProvider, error) {
+	parsed, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: parsed.Hostname(), MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LDAP CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in LDAP CA file %s", cfg.CAFile)
+		}
+	}
+
+	groupRoles := make(map[string]string, len(cfg.GroupRoles))
+	for _, mapping := range cfg.GroupRoles {
+		groupRoles[normalizeDN(mapping.Group)] = mapping.Role
+	}
+	groupTenants := make(map[string]string, len(cfg.GroupTenants))
+	for _, mapping := range cfg.GroupTenants {
+		groupTenants[normalizeDN(mapping.Group)] = mapping.Tenant
+	}
+	return &LDAPProvider{cfg: *cfg, tls: tlsConfig, groupRoles: groupRoles, groupTenants: groupTenants}, nil
+}
+
+// Name returns the provider identifier
+func (p *LDAPProvider) Name() string {
+	return constants.AuthProviderLDAP
+}
+
+// Authenticate binds as the user and looks up its group memberships
+func (p *LDAPProvider) Authenticate(ctx context.Context, username, password string) (*models.Principal, error) {
+	// An empty password would perform an unauthenticated bind, which servers accept
+	if username == "" || len(username) > constants.MaxLDAPUsernameLength || password == "" {
+		return nil, apperrors.Unauthorized("Invalid username or password")
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Authentication service unavailable")
+	}
+	defer conn.Close()
+
+	// The username is escaped so that it can't change the DN it is bound as, or the filter it is searched with
+	userDN := strings.ReplaceAll(p.cfg.UserDNTemplate, constants.LDAPUserPlaceholder, ldap.EscapeDN(username))
+	if err := conn.Bind(userDN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, apperrors.Unauthorized("Invalid username or password")
+		}
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, fmt.Errorf("bind failed: %w", err), "Authentication service unavailable")
+	}
+
+	groups, err := p.searchGroups(conn, username)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Authentication service unavailable")
+	}
+
+	role := p.cfg.DefaultRole
+	for _, group := range groups {
+		if mapped, ok := p.groupRoles[normalizeDN(group)]; ok && RoleAllows(mapped, role) {
+			role = mapped
+		}
+	}
+	if role == "" {
+		return nil, apperrors.Forbidden("User %s is not a member of any authorized group", username)
+	}
+
+	// Members of a tenant's groups are limited to that tenant; membership of several tenants is ambiguous
+	var tenant string
+	for _, group := range groups {
+		mapped, ok := p.groupTenants[normalizeDN(group)]
+		if !ok || mapped == tenant {
+			continue
+		}
+		if tenant != "" {
+			return nil, apperrors.Forbidden("User %s is a member of the groups of more than one tenant", username)
+		}
+		tenant = mapped
+	}
+
+	return &models.Principal{
+		Username: username,
+		Role:     role,
+		Groups:   groups,
+		Provider: p.Name(),
+		Tenant:   tenant,
+	}, nil
+}
+
+// dial connects to the directory over TLS, for ldaps:// URLs or by StartTLS, or in cleartext when allowed as insecure
+func (p *LDAPProvider) dial(ctx context.Context) (*ldap.Conn, error) {
+	timeout := p.cfg.Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	conn, err := ldap.DialURL(p.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}), ldap.DialWithTLSConfig(p.tls))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", p.cfg.URL, err)
+	}
+	conn.SetTimeout(timeout)
+
+	if strings.HasPrefix(p.cfg.URL, "ldap://") && p.cfg.StartTLS {
+		if err := conn.StartTLS(p.tls); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS with %s: %w", p.cfg.URL, err)
+		}
+	}
+	return conn, nil
+}
+
+// searchGroups finds the user's entry and returns the values of its group attribute. Referrals to other servers
+// aren't followed, but reported when they are all there is, so that LDAP_URL can be pointed at a server holding
+// the entry, such as an Active Directory global catalog.
+func (p *LDAPProvider) searchGroups(conn *ldap.Conn, username string) ([]string, error) {
+	request := ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		1, // size limit: a single user entry
+		0, // time limit: bounded by the connection timeout
+		false,
+		fmt.Sprintf("(%s=%s)", p.cfg.UserAttribute, ldap.EscapeFilter(username)),
+		[]string{p.cfg.GroupAttribute},
+		nil,
+	)
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		if len(result.Referrals) > 0 {
+			return nil, fmt.Errorf("user entry not found under %s, which refers to %s", p.cfg.BaseDN, strings.Join(result.Referrals, ", "))
+		}
+		return nil, errors.New("user entry not found under " + p.cfg.BaseDN)
+	}
+	return result.Entries[0].GetEqualFoldAttributeValues(p.cfg.GroupAttribute), nil
+}
+
+// normalizeDN lowercases a DN and removes spaces around RDN separators for comparison
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
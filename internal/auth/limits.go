@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"math"
+	"sync"
+	"time"
+)
+
+// ScopeLimiter caps the requests per minute and the logs per hour each caller may get from the endpoints of a
+// scope. Usage is counted in fixed windows on this API server only.
+type ScopeLimiter struct {
+	limits map[string]config.ScopeLimits
+	mu     sync.Mutex
+	usage  map[usageKey]*scopeUsage
+}
+
+type usageKey struct {
+	caller string
+	scope  string
+}
+
+// scopeUsage counts a caller's requests and rows in the current windows
+type scopeUsage struct {
+	minute   time.Time
+	requests int
+	hour     time.Time
+	rows     int64
+}
+
+// Quota is the volume a request may still stream or export. A nil quota is unlimited.
+type Quota struct {
+	limiter *ScopeLimiter
+	key     usageKey
+	limit   int64
+	used    int64 // rows taken by this request
+}
+
+// NewScopeLimiter creates a limiter with the caps of each scope
+func NewScopeLimiter(limits map[string]config.ScopeLimits) *ScopeLimiter {
+	return &ScopeLimiter{limits: limits, usage: make(map[usageKey]*scopeUsage)}
+}
+
+// Admit counts a request of the caller to an endpoint of the scope and returns its volume quota. Requests beyond
+// the scope's rate fail with a rate limited error.
+func (l *ScopeLimiter) Admit(caller, scope string) (*Quota, error) {
+	limits := l.limits[scope]
+	key := usageKey{caller: caller, scope: scope}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Drop usage of past windows while holding the lock so the map doesn't grow unbounded
+	for k, u := range l.usage {
+		if now.Sub(u.hour) >= time.Hour && now.Sub(u.minute) >= time.Minute {
+			delete(l.usage, k)
+		}
+	}
+
+	usage := l.current(key, now)
+	if limits.RequestsPerMinute > 0 && usage.requests >= limits.RequestsPerMinute {
+		return nil, apperrors.RateLimited("At most %d %s requests per minute are allowed", limits.RequestsPerMinute, scope)
+	}
+	usage.requests++
+
+	limit := int64(math.MaxInt64)
+	if limits.RowsPerHour > 0 {
+		limit = int64(limits.RowsPerHour)
+	}
+	return &Quota{limiter: l, key: key, limit: limit}, nil
+}
+
+// current returns the caller's usage, starting new windows once the previous ones have passed
+func (l *ScopeLimiter) current(key usageKey, now time.Time) *scopeUsage {
+	usage, ok := l.usage[key]
+	if !ok {
+		usage = &scopeUsage{}
+		l.usage[key] = usage
+	}
+	if minute := now.Truncate(time.Minute); usage.minute != minute {
+		usage.minute = minute
+		usage.requests = 0
+	}
+	if hour := now.Truncate(time.Hour); usage.hour != hour {
+		usage.hour = hour
+		usage.rows = 0
+	}
+	return usage
+}
+
+// Remaining returns the rows the caller may still get in the current hour
+func (q *Quota) Remaining() int64 {
+	if q == nil {
+		return math.MaxInt64
+	}
+	q.limiter.mu.Lock()
+	defer q.limiter.mu.Unlock()
+	return max(q.limit-q.limiter.current(q.key, time.Now()).rows, 0)
+}
+
+// Take counts up to n rows against the quota and returns how many were allowed
+func (q *Quota) Take(n int64) int64 {
+	if q == nil {
+		return n
+	}
+	q.limiter.mu.Lock()
+	defer q.limiter.mu.Unlock()
+	usage := q.limiter.current(q.key, time.Now())
+	allowed := min(n, max(q.limit-usage.rows, 0))
+	usage.rows += allowed
+	q.used += allowed
+	return allowed
+}
+
+// Charge counts n rows already sent against the quota, even beyond the cap
+func (q *Quota) Charge(n int64) {
+	if q == nil {
+		return
+	}
+	q.limiter.mu.Lock()
+	defer q.limiter.mu.Unlock()
+	q.limiter.current(q.key, time.Now()).rows += n
+	q.used += n
+}
+
+// Used returns the rows taken by the request
+func (q *Quota) Used() int64 {
+	if q == nil {
+		return 0
+	}
+	q.limiter.mu.Lock()
+	defer q.limiter.mu.Unlock()
+	return q.used
+}
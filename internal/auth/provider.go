@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"sync"
+	"time"
+)
+
+// Provider verifies user credentials against an identity backend
+type Provider interface {
+	// Name returns the provider identifier
+	Name() string
+	// Authenticate verifies the credentials and returns the caller with its role.
+	// Invalid credentials yield an Unauthorized error and users without a role a Forbidden error.
+	Authenticate(ctx context.Context, username, password string) (*models.Principal, error)
+}
+
+// NewProvider creates the configured authentication provider, or nil when authentication is disabled
+func NewProvider(cfg *config.AuthConfig) (Provider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth configuration: %w", err)
+	}
+
+	var provider Provider
+	switch cfg.Provider {
+	case constants.AuthProviderLDAP:
+		ldapProvider, err := NewLDAPProvider(&cfg.LDAP)
+		if err != nil {
+			return nil, err
+		}
+		provider = ldapProvider
+	default:
+		return nil, nil
+	}
+
+	if cfg.CacheTTL > 0 {
+		provider = newCachingProvider(provider, cfg.CacheTTL)
+	}
+	return provider, nil
+}
+
+// RoleAllows reports whether role grants at least the required role
+func RoleAllows(role, required string) bool {
+	return roleRank(role) >= roleRank(required)
+}
+
+// roleRank orders roles by privilege; unknown roles rank lowest
+func roleRank(role string) int {
+	switch role {
+	case constants.RoleAdmin:
+		return 2
+	case constants.RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// cachingProvider remembers successful authentications for a short time.
+// Entries are keyed by a hash of the credentials so changed passwords are re-verified.
+type cachingProvider struct {
+	Provider
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	principal *models.Principal
+	expiresAt time.Time
+}
+
+func newCachingProvider(provider Provider, ttl time.Duration) *cachingProvider {
+	return &cachingProvider{
+		Provider: provider,
+		ttl:      ttl,
+		entries:  make(map[[sha256.Size]byte]cachedPrincipal),
+	}
+}
+
+// Authenticate returns a cached principal or verifies the credentials with the wrapped provider
+func (p *cachingProvider) Authenticate(ctx context.Context, username, password string) (*models.Principal, error) {
+	key := sha256.Sum256([]byte(username + "\x00" + password))
+	now := time.Now()
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	p.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.principal, nil
+	}
+
+	principal, err := p.Provider.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Drop expired entries while holding the lock so the cache doesn't grow unbounded
+	for k, e := range p.entries {
+		if now.After(e.expiresAt) {
+			delete(p.entries, k)
+		}
+	}
+	p.entries[key] = cachedPrincipal{principal: principal, expiresAt: now.Add(p.ttl)}
+	return principal, nil
+}
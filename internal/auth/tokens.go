@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// ServiceTokens verifies the bearer tokens of services. Only hashes of the tokens are configured, so the
+// configuration doesn't reveal them.
+type ServiceTokens struct {
+	byHash map[string]config.ServiceToken
+}
+
+// NewServiceTokens creates a verifier of the configured tokens
+func NewServiceTokens(tokens []config.ServiceToken) *ServiceTokens {
+	t := &ServiceTokens{byHash: make(map[string]config.ServiceToken, len(tokens))}
+	for _, token := range tokens {
+		t.byHash[token.Hash] = token
+	}
+	return t
+}
+
+// Enabled reports whether any token is configured
+func (t *ServiceTokens) Enabled() bool {
+	return len(t.byHash) > 0
+}
+
+// Authenticate returns the service holding the token, with the token's scopes and tenant and no role
+func (t *ServiceTokens) Authenticate(token string) (*models.Principal, error) {
+	sum := sha256.Sum256([]byte(token))
+	service, ok := t.byHash[hex.EncodeToString(sum[:])]
+	if !ok {
+		return nil, apperrors.Unauthorized("Invalid service token")
+	}
+	return &models.Principal{
+		Username: service.Name,
+		Scopes:   service.Scopes,
+		Provider: constants.AuthProviderToken,
+		Tenant:   service.Tenant,
+	}, nil
+}
@@ -0,0 +1,391 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// errShortBuffer is returned when a value is cut off
+var errShortBuffer = errors.New("avro value cut off")
+
+// Encode writes a value in Avro's binary encoding. Records take map[string]any, missing fields being null; maps
+// take map[string]any or map[string]string; arrays []any; enums their symbol as a string; timestamps time.Time or
+// an integer; and unions the value of whichever branch the value fits first.
+func (s *Schema) Encode(value any) ([]byte, error) {
+	return s.encode(nil, value)
+}
+
+// Decode reads a value in Avro's binary encoding, written with this schema. Values are decoded as Encode takes
+// them: records and maps as map[string]any, arrays as []any, enums as strings, timestamps as UTC time.Time, ints
+// as int32 and longs as int64.
+func (s *Schema) Decode(data []byte) (any, error) {
+	value, rest, err := s.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes left after the avro value", len(rest))
+	}
+	return value, nil
+}
+
+// encode appends the encoding of the value
+func (s *Schema) encode(buf []byte, value any) ([]byte, error) {
+	switch s.Type {
+	case TypeNull:
+		if value != nil {
+			return nil, fmt.Errorf("expected null, got %T", value)
+		}
+		return buf, nil
+	case TypeBoolean:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, got %T", value)
+		}
+		if v {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case TypeInt, TypeLong:
+		if t, ok := value.(time.Time); ok && s.isTimestamp() {
+			if s.LogicalType == LogicalTimestampMillis {
+				return binary.AppendVarint(buf, t.UnixMilli()), nil
+			}
+			return binary.AppendVarint(buf, t.UnixMicro()), nil
+		}
+		v, ok := integer(value)
+		if !ok {
+			return nil, fmt.Errorf("expected an integer, got %T", value)
+		}
+		if s.Type == TypeInt && (v < math.MinInt32 || v > math.MaxInt32) {
+			return nil, fmt.Errorf("%d overflows an int", v)
+		}
+		return binary.AppendVarint(buf, v), nil
+	case TypeFloat, TypeDouble:
+		var v float64
+		switch n := value.(type) {
+		case float32:
+			v = float64(n)
+		case float64:
+			v = n
+		default:
+			i, ok := integer(value)
+			if !ok {
+				return nil, fmt.Errorf("expected a number, got %T", value)
+			}
+			v = float64(i)
+		}
+		if s.Type == TypeFloat {
+			return binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v))), nil
+		}
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case TypeBytes, TypeString:
+		var v []byte
+		switch b := value.(type) {
+		case []byte:
+			v = b
+		case string:
+			v = []byte(b)
+		default:
+			return nil, fmt.Errorf("expected a %s, got %T", s.Type, value)
+		}
+		buf = binary.AppendVarint(buf, int64(len(v)))
+		return append(buf, v...), nil
+	case TypeFixed:
+		v, ok := value.([]byte)
+		if !ok || len(v) != s.Size {
+			return nil, fmt.Errorf("expected %d bytes for fixed type %s", s.Size, s.Name)
+		}
+		return append(buf, v...), nil
+	case TypeEnum:
+		symbol, ok := value.(string)
+		index := slices.Index(s.Symbols, symbol)
+		if !ok || index < 0 {
+			return nil, fmt.Errorf("%v is not a symbol of enum %s", value, s.Name)
+		}
+		return binary.AppendVarint(buf, int64(index)), nil
+	case TypeRecord:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a record, got %T", value)
+		}
+		var err error
+		for _, field := range s.Fields {
+			if buf, err = field.Type.encode(buf, fields[field.Name]); err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+		return buf, nil
+	case TypeMap:
+		entries, ok := mapEntries(value)
+		if !ok {
+			return nil, fmt.Errorf("expected a map, got %T", value)
+		}
+		// Maps are written as a single block, sorted so that equal maps encode the same
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		if len(keys) > 0 {
+			buf = binary.AppendVarint(buf, int64(len(keys)))
+		}
+		var err error
+		for _, key := range keys {
+			buf = binary.AppendVarint(buf, int64(len(key)))
+			buf = append(buf, key...)
+			if buf, err = s.Values.encode(buf, entries[key]); err != nil {
+				return nil, fmt.Errorf("map value %s: %w", key, err)
+			}
+		}
+		return append(buf, 0), nil
+	case TypeArray:
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %T", value)
+		}
+		if len(items) > 0 {
+			buf = binary.AppendVarint(buf, int64(len(items)))
+		}
+		var err error
+		for i, item := range items {
+			if buf, err = s.Items.encode(buf, item); err != nil {
+				return nil, fmt.Errorf("array item %d: %w", i, err)
+			}
+		}
+		return append(buf, 0), nil
+	case TypeUnion:
+		for i, branch := range s.Branches {
+			if branch.fits(value) {
+				return branch.encode(binary.AppendVarint(buf, int64(i)), value)
+			}
+		}
+		return nil, fmt.Errorf("%T fits no branch of the union", value)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", s.Type)
+	}
+}
+
+// fits reports whether a value can be encoded with the schema, picking the branch of a union
+func (s *Schema) fits(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return s.Type == TypeNull
+	case bool:
+		return s.Type == TypeBoolean
+	case time.Time:
+		return s.isTimestamp()
+	case float32, float64:
+		return s.Type == TypeFloat || s.Type == TypeDouble
+	case string:
+		return s.Type == TypeString || (s.Type == TypeEnum && slices.Contains(s.Symbols, v))
+	case []byte:
+		return s.Type == TypeBytes || (s.Type == TypeFixed && len(v) == s.Size)
+	case []any:
+		return s.Type == TypeArray
+	case map[string]string:
+		return s.Type == TypeMap
+	case map[string]any:
+		return s.Type == TypeRecord || s.Type == TypeMap
+	}
+	if _, ok := integer(value); ok {
+		return s.Type == TypeInt || s.Type == TypeLong
+	}
+	return false
+}
+
+// isTimestamp reports whether the schema is a long holding a timestamp
+func (s *Schema) isTimestamp() bool {
+	return s.Type == TypeLong && (s.LogicalType == LogicalTimestampMillis || s.LogicalType == LogicalTimestampMicros)
+}
+
+// decode reads a value, returning the bytes after it
+func (s *Schema) decode(data []byte) (any, []byte, error) {
+	switch s.Type {
+	case TypeNull:
+		return nil, data, nil
+	case TypeBoolean:
+		if len(data) < 1 {
+			return nil, nil, errShortBuffer
+		}
+		return data[0] != 0, data[1:], nil
+	case TypeInt, TypeLong:
+		v, rest, err := readLong(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case s.Type == TypeInt:
+			return int32(v), rest, nil
+		case s.LogicalType == LogicalTimestampMillis:
+			return time.UnixMilli(v).UTC(), rest, nil
+		case s.LogicalType == LogicalTimestampMicros:
+			return time.UnixMicro(v).UTC(), rest, nil
+		}
+		return v, rest, nil
+	case TypeFloat:
+		if len(data) < 4 {
+			return nil, nil, errShortBuffer
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(data)), data[4:], nil
+	case TypeDouble:
+		if len(data) < 8 {
+			return nil, nil, errShortBuffer
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), data[8:], nil
+	case TypeBytes:
+		v, rest, err := readBytes(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return slices.Clone(v), rest, nil
+	case TypeString:
+		v, rest, err := readBytes(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return string(v), rest, nil
+	case TypeFixed:
+		if len(data) < s.Size {
+			return nil, nil, errShortBuffer
+		}
+		return slices.Clone(data[:s.Size]), data[s.Size:], nil
+	case TypeEnum:
+		index, rest, err := readLong(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if index < 0 || index >= int64(len(s.Symbols)) {
+			return nil, nil, fmt.Errorf("symbol %d out of range of enum %s", index, s.Name)
+		}
+		return s.Symbols[index], rest, nil
+	case TypeRecord:
+		record := make(map[string]any, len(s.Fields))
+		for _, field := range s.Fields {
+			value, rest, err := field.Type.decode(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			record[field.Name], data = value, rest
+		}
+		return record, data, nil
+	case TypeMap:
+		entries := make(map[string]any)
+		rest, err := readBlocks(data, func(data []byte) ([]byte, error) {
+			key, rest, err := readBytes(data)
+			if err != nil {
+				return nil, err
+			}
+			value, rest, err := s.Values.decode(rest)
+			if err != nil {
+				return nil, fmt.Errorf("map value %s: %w", key, err)
+			}
+			entries[string(key)] = value
+			return rest, nil
+		})
+		return entries, rest, err
+	case TypeArray:
+		items := []any{}
+		rest, err := readBlocks(data, func(data []byte) ([]byte, error) {
+			item, rest, err := s.Items.decode(data)
+			if err != nil {
+				return nil, fmt.Errorf("array item %d: %w", len(items), err)
+			}
+			items = append(items, item)
+			return rest, nil
+		})
+		return items, rest, err
+	case TypeUnion:
+		index, rest, err := readLong(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if index < 0 || index >= int64(len(s.Branches)) {
+			return nil, nil, fmt.Errorf("branch %d out of range of the union", index)
+		}
+		return s.Branches[index].decode(rest)
+	default:
+		return nil, nil, fmt.Errorf("unsupported type %s", s.Type)
+	}
+}
+
+// readLong reads a zigzag encoded variable-length integer
+func readLong(data []byte) (int64, []byte, error) {
+	v, n := binary.Varint(data)
+	if n <= 0 {
+		return 0, nil, errShortBuffer
+	}
+	return v, data[n:], nil
+}
+
+// readBytes reads a length-prefixed byte sequence
+func readBytes(data []byte) ([]byte, []byte, error) {
+	length, rest, err := readLong(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length < 0 || length > int64(len(rest)) {
+		return nil, nil, errShortBuffer
+	}
+	return rest[:length], rest[length:], nil
+}
+
+// readBlocks reads the blocks of an array or map, calling read for each item. A block with a negative count is
+// preceded by its size in bytes, which is skipped.
+func readBlocks(data []byte, read func([]byte) ([]byte, error)) ([]byte, error) {
+	for {
+		count, rest, err := readLong(data)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return rest, nil
+		}
+		if count < 0 {
+			count = -count
+			if _, rest, err = readLong(rest); err != nil {
+				return nil, err
+			}
+		}
+		for ; count > 0; count-- {
+			if rest, err = read(rest); err != nil {
+				return nil, err
+			}
+		}
+		data = rest
+	}
+}
+
+// integer converts the integer types to int64
+func integer(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// mapEntries returns the entries of a map value
+func mapEntries(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case map[string]string:
+		entries := make(map[string]any, len(v))
+		for key, entry := range v {
+			entries[key] = entry
+		}
+		return entries, true
+	}
+	return nil, false
+}
@@ -0,0 +1,204 @@
+package avro
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const nodeSchema = `{"type": "record", "name": "Node", "fields": [
+	{"name": "value", "type": "int"},
+	{"name": "next", "type": ["null", "Node"]}
+]}`
+
+func TestCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		value   any
+		encoded []byte
+		decoded any
+	}{
+		{name: "null", schema: `"null"`, value: nil, encoded: []byte{}, decoded: nil},
+		{name: "boolean", schema: `"boolean"`, value: true, encoded: []byte{0x01}, decoded: true},
+		{name: "int", schema: `"int"`, value: 1, encoded: []byte{0x02}, decoded: int32(1)},
+		{name: "negative long", schema: `"long"`, value: int64(-64), encoded: []byte{0x7f}, decoded: int64(-64)},
+		{name: "two byte long", schema: `"long"`, value: 64, encoded: []byte{0x80, 0x01}, decoded: int64(64)},
+		{name: "double", schema: `"double"`, value: 1.0, encoded: []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}, decoded: 1.0},
+		{name: "float from an integer", schema: `"float"`, value: 2, encoded: []byte{0, 0, 0, 0x40}, decoded: float32(2)},
+		{name: "string", schema: `"string"`, value: "foo", encoded: []byte{0x06, 'f', 'o', 'o'}, decoded: "foo"},
+		{name: "bytes", schema: `"bytes"`, value: []byte{0xff}, encoded: []byte{0x02, 0xff}, decoded: []byte{0xff}},
+		{
+			name:    "fixed",
+			schema:  `{"type": "fixed", "name": "Pair", "size": 2}`,
+			value:   []byte{1, 2},
+			encoded: []byte{1, 2},
+			decoded: []byte{1, 2},
+		},
+		{
+			name:    "timestamp millis",
+			schema:  `{"type": "long", "logicalType": "timestamp-millis"}`,
+			value:   time.UnixMilli(1000).In(time.FixedZone("CET", 3600)),
+			encoded: []byte{0xd0, 0x0f},
+			decoded: time.UnixMilli(1000).UTC(),
+		},
+		{
+			name:    "timestamp micros from an integer",
+			schema:  `{"type": "long", "logicalType": "timestamp-micros"}`,
+			value:   int64(1),
+			encoded: []byte{0x02},
+			decoded: time.UnixMicro(1).UTC(),
+		},
+		{
+			name:    "enum",
+			schema:  `{"type": "enum", "name": "Level", "symbols": ["INFO", "ERROR"]}`,
+			value:   "ERROR",
+			encoded: []byte{0x02},
+			decoded: "ERROR",
+		},
+		{
+			name:    "record with a missing field",
+			schema:  `{"type": "record", "name": "Log", "fields": [{"name": "a", "type": "long"}, {"name": "b", "type": ["null", "string"]}]}`,
+			value:   map[string]any{"a": 27},
+			encoded: []byte{0x36, 0x00},
+			decoded: map[string]any{"a": int64(27), "b": nil},
+		},
+		{
+			name:    "array",
+			schema:  `{"type": "array", "items": "long"}`,
+			value:   []any{3, 27},
+			encoded: []byte{0x04, 0x06, 0x36, 0x00},
+			decoded: []any{int64(3), int64(27)},
+		},
+		{
+			name:    "empty array",
+			schema:  `{"type": "array", "items": "long"}`,
+			value:   []any{},
+			encoded: []byte{0x00},
+			decoded: []any{},
+		},
+		{
+			name:    "map sorted by key",
+			schema:  `{"type": "map", "values": "string"}`,
+			value:   map[string]string{"b": "y", "a": "x"},
+			encoded: []byte{0x04, 0x02, 'a', 0x02, 'x', 0x02, 'b', 0x02, 'y', 0x00},
+			decoded: map[string]any{"a": "x", "b": "y"},
+		},
+		{
+			name:    "union picks the fitting branch",
+			schema:  `["null", "long", "string"]`,
+			value:   "a",
+			encoded: []byte{0x04, 0x02, 'a'},
+			decoded: "a",
+		},
+		{
+			name:    "recursive record",
+			schema:  nodeSchema,
+			value:   map[string]any{"value": 1, "next": map[string]any{"value": 2}},
+			encoded: []byte{0x02, 0x02, 0x04, 0x00},
+			decoded: map[string]any{"value": int32(1), "next": map[string]any{"value": int32(2), "next": nil}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Parse(tt.schema)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			encoded, err := schema.Encode(tt.value)
+			if err != nil {
+				t.Fatalf("Encode() error: %v", err)
+			}
+			if !bytes.Equal(encoded, tt.encoded) {
+				t.Errorf("Encode() = % x, want % x", encoded, tt.encoded)
+			}
+			decoded, err := schema.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode() error: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.decoded) {
+				t.Errorf("Decode() = %#v, want %#v", decoded, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  any
+	}{
+		{name: "int overflow", schema: `"int"`, value: int64(1) << 31},
+		{name: "string as long", schema: `"long"`, value: "1"},
+		{name: "unknown symbol", schema: `{"type": "enum", "name": "Level", "symbols": ["INFO"]}`, value: "DEBUG"},
+		{name: "fixed of another size", schema: `{"type": "fixed", "name": "Pair", "size": 2}`, value: []byte{1}},
+		{name: "no fitting branch", schema: `["null", "string"]`, value: 1},
+		{name: "invalid field", schema: nodeSchema, value: map[string]any{"value": "one"}},
+		{name: "invalid array item", schema: `{"type": "array", "items": "string"}`, value: []any{"a", 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Parse(tt.schema)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if encoded, err := schema.Encode(tt.value); err == nil {
+				t.Errorf("Encode() = % x, want an error", encoded)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		data    []byte
+		want    any
+		wantErr bool
+	}{
+		{
+			name:   "block with a byte size",
+			schema: `{"type": "array", "items": "long"}`,
+			data:   []byte{0x03, 0x04, 0x06, 0x36, 0x00},
+			want:   []any{int64(3), int64(27)},
+		},
+		{
+			name:   "several blocks",
+			schema: `{"type": "map", "values": "int"}`,
+			data:   []byte{0x02, 0x02, 'a', 0x02, 0x02, 0x02, 'b', 0x04, 0x00},
+			want:   map[string]any{"a": int32(1), "b": int32(2)},
+		},
+		{name: "empty", schema: `"long"`, data: []byte{}, wantErr: true},
+		{name: "string cut off", schema: `"string"`, data: []byte{0x06, 'f'}, wantErr: true},
+		{name: "negative length", schema: `"bytes"`, data: []byte{0x01}, wantErr: true},
+		{name: "fixed cut off", schema: `{"type": "fixed", "name": "Pair", "size": 2}`, data: []byte{1}, wantErr: true},
+		{name: "bytes left over", schema: `"boolean"`, data: []byte{0x01, 0x01}, wantErr: true},
+		{name: "symbol out of range", schema: `{"type": "enum", "name": "Level", "symbols": ["INFO"]}`, data: []byte{0x02}, wantErr: true},
+		{name: "branch out of range", schema: `["null", "string"]`, data: []byte{0x04}, wantErr: true},
+		{name: "unterminated array", schema: `{"type": "array", "items": "long"}`, data: []byte{0x02, 0x02}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Parse(tt.schema)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got, err := schema.Decode(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Decode() = %#v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package avro
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Confluent wire format: a magic byte and the big-endian schema ID precede the encoded value
+const (
+	magicByte   = 0
+	headerSize  = 5
+	contentType = "application/vnd.schemaregistry.v1+json"
+
+	// maxRegistryResponseSize bounds the registry responses read
+	maxRegistryResponseSize = 1 << 20
+)
+
+// ErrRegistryUnavailable marks failures to get an answer from the registry, such as network errors, timeouts and
+// server errors, which may succeed when retried. The registry answering that a schema doesn't exist isn't one.
+var ErrRegistryUnavailable = errors.New("schema registry unavailable")
+
+// Frame prefixes an encoded value with the ID of the schema it was written with
+func Frame(id int, value []byte) []byte {
+	framed := make([]byte, headerSize, headerSize+len(value))
+	framed[0] = magicByte
+	binary.BigEndian.PutUint32(framed[1:], uint32(id))
+	return append(framed, value...)
+}
+
+// Unframe splits a framed value into the ID of its schema and the encoded value, reporting whether it is framed
+func Unframe(data []byte) (int, []byte, bool) {
+	if len(data) < headerSize || data[0] != magicByte {
+		return 0, nil, false
+	}
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], true
+}
+
+// Registry is a client of a Confluent Schema Registry. Registered and fetched schemas are cached, as the schema
+// of an ID never changes.
+type Registry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+
+	mu       sync.Mutex
+	schemas  map[int]*Schema
+	versions map[string]int // subject and schema -> ID
+}
+
+// NewRegistry creates a client of the registry at the URL, authenticating with basic auth when a username is set
+func NewRegistry(registryURL, username, password string, timeout time.Duration) *Registry {
+	return &Registry{
+		url:      strings.TrimSuffix(registryURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: timeout},
+		schemas:  make(map[int]*Schema),
+		versions: make(map[string]int),
+	}
+}
+
+// Register registers the schema under the subject, returning its ID. Registering a schema the subject already has
+// returns the existing ID; the registry rejects schemas incompatible with the subject's compatibility setting.
+func (r *Registry) Register(ctx context.Context, subject string, schema *Schema) (int, error) {
+	key := subject + "\x00" + schema.String()
+	r.mu.Lock()
+	id, ok := r.versions[key]
+	r.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"schema": schema.String()})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	var response struct {
+		ID int `json:"id"`
+	}
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := r.do(ctx, http.MethodPost, path, body, &response); err != nil {
+		return 0, fmt.Errorf("failed to register schema of subject %s: %w", subject, err)
+	}
+
+	r.mu.Lock()
+	r.versions[key] = response.ID
+	r.schemas[response.ID] = schema
+	r.mu.Unlock()
+	return response.ID, nil
+}
+
+// Schema returns the schema with the ID. Only Avro schemas are supported.
+func (r *Registry) Schema(ctx context.Context, id int) (*Schema, error) {
+	r.mu.Lock()
+	schema, ok := r.schemas[id]
+	r.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	var response struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := r.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	if response.SchemaType != "" && response.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema %d is a %s schema, only AVRO is supported", id, response.SchemaType)
+	}
+	schema, err := Parse(response.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+
+	r.mu.Lock()
+	r.schemas[id] = schema
+	r.mu.Unlock()
+	return schema, nil
+}
+
+// do sends a request to the registry and decodes its JSON response
+func (r *Registry) do(ctx context.Context, method, path string, body []byte, response any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: failed to call schema registry: %w", ErrRegistryUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRegistryResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: failed to read response: %w", ErrRegistryUnavailable, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		// Errors carry a message, e.g. {"error_code": 40403, "message": "Schema not found"}
+		var registryErr struct {
+			Message string `json:"message"`
+		}
+		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if json.Unmarshal(data, &registryErr) == nil && registryErr.Message != "" {
+			err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, registryErr.Message)
+		}
+		if retryableStatus(resp.StatusCode) {
+			return fmt.Errorf("%w: %w", ErrRegistryUnavailable, err)
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		// A response that isn't the registry's, such as a proxy's error page
+		return fmt.Errorf("%w: failed to decode response: %w", ErrRegistryUnavailable, err)
+	}
+	return nil
+}
+
+// retryableStatus reports whether a response status means the registry couldn't answer the request for now
+func retryableStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
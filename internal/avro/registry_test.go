@@ -0,0 +1,53 @@
+package avro
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistrySchemaErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		closed      bool
+		wantErr     bool
+		unavailable bool
+	}{
+		{name: "found", status: http.StatusOK, body: `{"schema": "\"string\""}`},
+		{name: "unknown schema", status: http.StatusNotFound, body: `{"error_code": 40403, "message": "Schema not found"}`, wantErr: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: true},
+		{name: "not avro", status: http.StatusOK, body: `{"schema": "syntax = \"proto3\";", "schemaType": "PROTOBUF"}`, wantErr: true},
+		{name: "invalid schema", status: http.StatusOK, body: `{"schema": "{\"type\": \"nope\"}"}`, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true, unavailable: true},
+		{name: "overloaded", status: http.StatusTooManyRequests, wantErr: true, unavailable: true},
+		{name: "not the registry", status: http.StatusOK, body: "<html>bad gateway</html>", wantErr: true, unavailable: true},
+		{name: "unreachable", closed: true, wantErr: true, unavailable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			if tt.closed {
+				server.Close()
+			} else {
+				defer server.Close()
+			}
+
+			_, err := NewRegistry(server.URL, "", "", time.Second).Schema(context.Background(), 7)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Schema() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrRegistryUnavailable); got != tt.unavailable {
+				t.Errorf("Schema() error = %v, unavailable %v, want %v", err, got, tt.unavailable)
+			}
+		})
+	}
+}
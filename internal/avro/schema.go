@@ -0,0 +1,220 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Types of Avro schemas
+const (
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInt     = "int"
+	TypeLong    = "long"
+	TypeFloat   = "float"
+	TypeDouble  = "double"
+	TypeBytes   = "bytes"
+	TypeString  = "string"
+	TypeRecord  = "record"
+	TypeEnum    = "enum"
+	TypeArray   = "array"
+	TypeMap     = "map"
+	TypeUnion   = "union"
+	TypeFixed   = "fixed"
+)
+
+// Logical types of longs read and written as time.Time
+const (
+	LogicalTimestampMillis = "timestamp-millis"
+	LogicalTimestampMicros = "timestamp-micros"
+)
+
+// Schema is a parsed Avro schema. Named types referenced again share the same Schema, so recursive records are
+// cyclic.
+type Schema struct {
+	Type        string
+	Name        string // full name of records, enums and fixed types
+	LogicalType string
+	Fields      []*Field  // of records
+	Symbols     []string  // of enums
+	Items       *Schema   // of arrays
+	Values      *Schema   // of maps
+	Branches    []*Schema // of unions
+	Size        int       // of fixed types
+
+	text string // the schema as parsed, for registering it
+}
+
+// Field is a field of a record
+type Field struct {
+	Name string
+	Type *Schema
+}
+
+// String returns the schema's JSON as it was parsed
+func (s *Schema) String() string {
+	return s.text
+}
+
+// Parse parses a schema from its JSON
+func Parse(text string) (*Schema, error) {
+	var definition any
+	if err := json.Unmarshal([]byte(text), &definition); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	p := &schemaParser{named: make(map[string]*Schema)}
+	schema, err := p.parse(definition, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	schema.text = text
+	return schema, nil
+}
+
+// schemaParser parses a schema, remembering its named types so that they can be referenced by name
+type schemaParser struct {
+	named map[string]*Schema
+}
+
+// parse parses a schema defined in the given namespace
+func (p *schemaParser) parse(definition any, namespace string) (*Schema, error) {
+	switch d := definition.(type) {
+	case string:
+		return p.reference(d, namespace)
+	case []any:
+		union := &Schema{Type: TypeUnion}
+		for _, branch := range d {
+			schema, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if schema.Type == TypeUnion {
+				return nil, fmt.Errorf("unions may not immediately contain other unions")
+			}
+			union.Branches = append(union.Branches, schema)
+		}
+		return union, nil
+	case map[string]any:
+		return p.parseComplex(d, namespace)
+	default:
+		return nil, fmt.Errorf("unexpected schema %v", definition)
+	}
+}
+
+// reference resolves a primitive type or a named type defined earlier
+func (p *schemaParser) reference(name, namespace string) (*Schema, error) {
+	switch name {
+	case TypeNull, TypeBoolean, TypeInt, TypeLong, TypeFloat, TypeDouble, TypeBytes, TypeString:
+		return &Schema{Type: name}, nil
+	}
+	if schema, ok := p.named[fullName(name, namespace)]; ok {
+		return schema, nil
+	}
+	if schema, ok := p.named[name]; ok {
+		return schema, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", name)
+}
+
+// parseComplex parses a schema given as a JSON object
+func (p *schemaParser) parseComplex(definition map[string]any, namespace string) (*Schema, error) {
+	typ, _ := definition["type"].(string)
+	if typ == "" {
+		// The type of an object can itself be a schema, e.g. {"type": {"type": "array", ...}}
+		if nested, ok := definition["type"]; ok {
+			return p.parse(nested, namespace)
+		}
+		return nil, fmt.Errorf("schema without a type")
+	}
+	logicalType, _ := definition["logicalType"].(string)
+
+	switch typ {
+	case TypeRecord, TypeEnum, TypeFixed:
+		name, _ := definition["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s without a name", typ)
+		}
+		if ns, ok := definition["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		name = fullName(name, namespace)
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			namespace = name[:i]
+		}
+		if _, ok := p.named[name]; ok {
+			return nil, fmt.Errorf("type %q defined twice", name)
+		}
+		schema := &Schema{Type: typ, Name: name, LogicalType: logicalType}
+		p.named[name] = schema
+		return schema, p.parseNamed(schema, definition, namespace)
+	case TypeArray:
+		items, err := p.parse(definition["items"], namespace)
+		if err != nil {
+			return nil, fmt.Errorf("array items: %w", err)
+		}
+		return &Schema{Type: TypeArray, Items: items, LogicalType: logicalType}, nil
+	case TypeMap:
+		values, err := p.parse(definition["values"], namespace)
+		if err != nil {
+			return nil, fmt.Errorf("map values: %w", err)
+		}
+		return &Schema{Type: TypeMap, Values: values, LogicalType: logicalType}, nil
+	default:
+		schema, err := p.reference(typ, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if logicalType != "" && schema.Name == "" {
+			schema.LogicalType = logicalType
+		}
+		return schema, nil
+	}
+}
+
+// parseNamed parses the fields of a record, the symbols of an enum or the size of a fixed type
+func (p *schemaParser) parseNamed(schema *Schema, definition map[string]any, namespace string) error {
+	switch schema.Type {
+	case TypeRecord:
+		fields, _ := definition["fields"].([]any)
+		for _, raw := range fields {
+			field, _ := raw.(map[string]any)
+			name, _ := field["name"].(string)
+			if name == "" {
+				return fmt.Errorf("field of record %s without a name", schema.Name)
+			}
+			typ, err := p.parse(field["type"], namespace)
+			if err != nil {
+				return fmt.Errorf("field %s of record %s: %w", name, schema.Name, err)
+			}
+			schema.Fields = append(schema.Fields, &Field{Name: name, Type: typ})
+		}
+	case TypeEnum:
+		symbols, _ := definition["symbols"].([]any)
+		for _, raw := range symbols {
+			symbol, ok := raw.(string)
+			if !ok {
+				return fmt.Errorf("symbol of enum %s is not a string", schema.Name)
+			}
+			schema.Symbols = append(schema.Symbols, symbol)
+		}
+		if len(schema.Symbols) == 0 {
+			return fmt.Errorf("enum %s without symbols", schema.Name)
+		}
+	case TypeFixed:
+		size, ok := definition["size"].(float64)
+		if !ok || size < 0 {
+			return fmt.Errorf("fixed type %s without a size", schema.Name)
+		}
+		schema.Size = int(size)
+	}
+	return nil
+}
+
+// fullName qualifies a name with the namespace, unless it is qualified already
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
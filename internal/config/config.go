@@ -1,21 +1,57 @@
 package config
 
 import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Kafka    KafkaConfig    `json:"kafka"`
-	Log      LogConfig      `json:"log"`
+	Server       ServerConfig       `json:"server"`
+	Database     DatabaseConfig     `json:"database"`
+	Kafka        KafkaConfig        `json:"kafka"`
+	Log          LogConfig          `json:"log"`
+	Enrichment   EnrichmentConfig   `json:"enrichment"`
+	Storage      StorageConfig      `json:"storage"`
+	Alert        AlertConfig        `json:"alert"`
+	Routing      RoutingConfig      `json:"routing"`
+	Generator    GeneratorConfig    `json:"generator"`
+	Export       ExportConfig       `json:"export"`
+	Pipeline     PipelineConfig     `json:"pipeline"`
+	Maintenance  MaintenanceConfig  `json:"maintenance"`
+	Collector    CollectorConfig    `json:"collector"`
+	Auth         AuthConfig         `json:"auth"`
+	Metrics      MetricsConfig      `json:"metrics"`
+	Health       HealthConfig       `json:"health"`
+	Notification NotificationConfig `json:"notification"`
+	Migration    MigrationConfig    `json:"migration"`
+	Retention    RetentionConfig    `json:"retention"`
+	Dashboard    DashboardConfig    `json:"dashboard"`
+	Scheduler    SchedulerConfig    `json:"scheduler"`
+
+	SelfIngestion SelfIngestionConfig `json:"self_ingestion"`
+	LogStore      LogStoreConfig      `json:"log_store"`
+	StatusPage    StatusPageConfig    `json:"status_page"`
+
+	File          string        `json:"file"`           // YAML config file the settings were layered over; empty without one
+	WatchInterval time.Duration `json:"watch_interval"` // how often the config file is checked for changes to reload; 0 disables
+	Settings      []Setting     `json:"-"`              // effective settings, by environment variable
 }
 
 // ServerConfig holds server-related configuration
@@ -24,10 +60,40 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
 	IdleTimeout  time.Duration `json:"idle_timeout"`
+	LegacyRoutes bool          `json:"legacy_routes"` // serve the deprecated unversioned routes next to /api/v1
+	DebugSQL     bool          `json:"debug_sql"`     // let admins request the SQL run for a response with debug=sql
+	Docs         bool          `json:"docs"`          // serve the OpenAPI document and Swagger UI at /api/docs
+
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"` // overall bound of the staged shutdown
+
+	CORS            CORSConfig    `json:"cors"`
+	SecurityHeaders bool          `json:"security_headers"` // send nosniff, frame and referrer headers and a strict CSP
+	HSTSMaxAge      time.Duration `json:"hsts_max_age"`     // Strict-Transport-Security max age; 0 sends no HSTS header
+
+	Compression         bool `json:"compression"`           // gzip responses for clients accepting it
+	CompressionMinBytes int  `json:"compression_min_bytes"` // smaller responses are sent uncompressed
+	CompressionLevel    int  `json:"compression_level"`     // gzip level, from 1 (fastest) to 9 (smallest)
+}
+
+// CORSConfig holds the origins whose browser apps may call the API, e.g. dashboards hosted elsewhere
+type CORSConfig struct {
+	AllowedOrigins   []string      `json:"allowed_origins"` // scheme://host[:port] origins, or * for any; empty disables CORS
+	AllowedMethods   []string      `json:"allowed_methods"`
+	AllowedHeaders   []string      `json:"allowed_headers"`
+	ExposedHeaders   []string      `json:"exposed_headers"` // response headers scripts may read
+	AllowCredentials bool          `json:"allow_credentials"`
+	MaxAge           time.Duration `json:"max_age"` // how long browsers cache preflight answers
+}
+
+// Enabled reports whether any origin is allowed
+func (c *CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
 }
 
 // DatabaseConfig holds database-related configuration
 type DatabaseConfig struct {
+	Driver          string        `json:"driver"`   // mysql or postgres
+	SSLMode         string        `json:"ssl_mode"` // sslmode of Postgres connections
 	Host            string        `json:"host"`
 	Port            string        `json:"port"`
 	Username        string        `json:"username"`
@@ -40,99 +106,1592 @@ type DatabaseConfig struct {
 
 // KafkaConfig holds Kafka-related configuration
 type KafkaConfig struct {
-	Brokers          []string `json:"brokers"`
-	Topic            string   `json:"topic"`
-	GroupID          string   `json:"group_id"`
-	AutoOffsetReset  string   `json:"auto_offset_reset"`
-	EnableAutoCommit bool     `json:"enable_auto_commit"`
+	Brokers              []string             `json:"brokers"`
+	Topic                string               `json:"topic"`
+	GroupID              string               `json:"group_id"`
+	AutoOffsetReset      string               `json:"auto_offset_reset"`
+	EnableAutoCommit     bool                 `json:"enable_auto_commit"`
+	DeadLetterTopic      string               `json:"dead_letter_topic"`
+	PriorityTopic        string               `json:"priority_topic"` // ERROR and FATAL logs are sent here when set
+	PriorityBatchTimeout time.Duration        `json:"priority_batch_timeout"`
+	TenantTopics         []TenantTopic        `json:"tenant_topics"` // tenants whose logs are produced to and consumed from dedicated topics
+	StreamTopic          string               `json:"stream_topic"`  // stored logs are published here for the live tail; empty disables it
+	ControlTopic         string               `json:"control_topic"` // compacted topic of dynamic pipeline settings; empty disables it
+	StoreRetries         int                  `json:"store_retries"` // failed writes of a batch retried before it is dead-lettered
+	StoreRetryBackoff    time.Duration        `json:"store_retry_backoff"`
+	StoreBreaker         int                  `json:"store_breaker_threshold"` // consecutive failed writes with the log store unreachable pausing consumption; 0 disables it
+	StoreBreakerProbe    time.Duration        `json:"store_breaker_probe_interval"`
+	DedupCacheSize       int                  `json:"dedup_cache_size"` // message IDs of stored logs remembered by a processor; 0 disables the cache
+	LagCheckInterval     time.Duration        `json:"lag_check_interval"`
+	LagAlertThreshold    int64                `json:"lag_alert_threshold"` // messages a consumer group may lag behind before the lag alert fires; 0 disables it
+	MessageFormat        string               `json:"message_format"`      // json or avro, the format of the logs the collector produces
+	SchemaRegistry       SchemaRegistryConfig `json:"schema_registry"`
+}
+
+// SchemaRegistryConfig holds the Confluent Schema Registry the schemas of Avro messages are registered in
+type SchemaRegistryConfig struct {
+	URL      string        `json:"url"` // empty disables Avro messages
+	Username string        `json:"username"`
+	Password string        `json:"-"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
+// TenantTopic maps a tenant to its dedicated topic
+type TenantTopic struct {
+	Tenant string `json:"tenant"`
+	Topic  string `json:"topic"`
 }
 
 // LogConfig holds logging-related configuration
 type LogConfig struct {
-	Level  string `json:"level"`
-	Format string `json:"format"`
+	Level  slog.Level `json:"level"`
+	Format string     `json:"format"`
+}
+
+// EnrichmentConfig holds configuration for external HTTP log enrichment
+type EnrichmentConfig struct {
+	Enabled          bool                 `json:"enabled"`
+	Endpoints        []EnrichmentEndpoint `json:"endpoints"`
+	Timeout          time.Duration        `json:"timeout"`
+	CacheTTL         time.Duration        `json:"cache_ttl"`
+	CacheMaxEntries  int                  `json:"cache_max_entries"`
+	Concurrency      int                  `json:"concurrency"`
+	QueueSize        int                  `json:"queue_size"`
+	FailureThreshold int                  `json:"failure_threshold"`
+	BreakerCooldown  time.Duration        `json:"breaker_cooldown"`
+}
+
+// EnrichmentEndpoint describes an external lookup keyed by a log field
+type EnrichmentEndpoint struct {
+	Name string `json:"name"`
+	Key  string `json:"key"` // user_id or trace_id
+	URL  string `json:"url"` // may contain the {value} placeholder
+}
+
+// StorageConfig holds storage capacity planning configuration
+type StorageConfig struct {
+	GrowthWindowDays int `json:"growth_window_days"`
+	DiskCapacityGB   int `json:"disk_capacity_gb"`
+}
+
+// AlertConfig holds alert checker configuration
+type AlertConfig struct {
+	CheckEnabled               bool          `json:"check_enabled"` // whether the API server evaluates alert rules
+	CheckInterval              time.Duration `json:"check_interval"`
+	CatchUpEnabled             bool          `json:"catch_up_enabled"`
+	CatchUpMaxLookback         time.Duration `json:"catch_up_max_lookback"`
+	CanaryPeriod               time.Duration `json:"canary_period"`        // how long edited rules are shadow-evaluated before taking effect; 0 disables
+	MetaAlertIntervals         int           `json:"meta_alert_intervals"` // check intervals without a successful check before the meta-alert fires; 0 disables
+	RetentionAge               time.Duration `json:"retention_age"`        // resolved alerts older than this are pruned; 0 disables pruning
+	RetentionInterval          time.Duration `json:"retention_interval"`
+	RetentionBatchSize         int           `json:"retention_batch_size"`
+	RetentionOptimizeThreshold int           `json:"retention_optimize_threshold"` // 0 never optimizes
+}
+
+// RetentionConfig holds log retention purge configuration; the policies themselves are stored in the database
+type RetentionConfig struct {
+	Interval   time.Duration `json:"interval"`
+	BatchSize  int           `json:"batch_size"`
+	BatchPause time.Duration `json:"batch_pause"` // wait between batches of a purge
+}
+
+// DashboardConfig holds the caching of the default dashboard queries by the API server
+type DashboardConfig struct {
+	CacheTTL      time.Duration `json:"cache_ttl"`      // 0 disables caching and the warm-up
+	WarmupTimeout time.Duration `json:"warmup_timeout"` // the warm-up is abandoned after this long, and the server reports ready anyway
+	// Default alignment of time series buckets, which requests may override
+	WeekStart    time.Weekday  `json:"week_start"`    // first day of weekly buckets
+	BucketOffset time.Duration `json:"bucket_offset"` // shift of bucket boundaries from midnight UTC
+}
+
+// SelfIngestionConfig holds the publishing of the API server's own access and application logs to the log topic,
+// so that the system monitors itself. Requests to the excluded paths are still logged to stdout.
+type SelfIngestionConfig struct {
+	Enabled      bool       `json:"enabled"`
+	Service      string     `json:"service"` // service the logs are stored under
+	Level        slog.Level `json:"level"`   // minimum level published
+	ExcludePaths []string   `json:"exclude_paths"`
+}
+
+// LogStoreConfig selects the backend logs are stored in. Alerts, alert rules and the other metadata stay in the
+// MySQL database either way.
+type LogStoreConfig struct {
+	Backend    string           `json:"backend"` // mysql or clickhouse
+	ClickHouse ClickHouseConfig `json:"clickhouse"`
+}
+
+// ClickHouseConfig holds the connection to ClickHouse's HTTP interface
+type ClickHouseConfig struct {
+	URL       string        `json:"url"`
+	Database  string        `json:"database"`
+	Username  string        `json:"username"`
+	Password  string        `json:"-"`
+	Timeout   time.Duration `json:"timeout"`    // bound of every request
+	BatchSize int           `json:"batch_size"` // logs inserted per statement
+}
+
+// StatusPageConfig holds the public status page, an unauthenticated health summary for stakeholders
+type StatusPageConfig struct {
+	Enabled    bool          `json:"enabled"`
+	CacheTTL   time.Duration `json:"cache_ttl"`   // how long a summary is served before it is computed again
+	StaleAfter time.Duration `json:"stale_after"` // ingestion is degraded once the newest log was stored longer ago; 0 disables the check
+}
+
+// SchedulerConfig holds the leases that let a single API server at a time run each periodic task
+type SchedulerConfig struct {
+	LeaseDuration time.Duration `json:"lease_duration"` // renewed while a task runs; expires this long after its holder stops
+	PollInterval  time.Duration `json:"poll_interval"`  // how often each server checks whether a task is due
+}
+
+// RoutingConfig holds per-service log routing (sharding) configuration
+type RoutingConfig struct {
+	Rules []RoutingRule `json:"rules"`
+}
+
+// RoutingRule routes a service's logs to a dedicated table, optionally in a separate database
+type RoutingRule struct {
+	Service string `json:"service"`
+	Table   string `json:"table"`
+	DSN     string `json:"-"` // empty means the primary database
+}
+
+// GeneratorConfig holds sample log generator configuration
+type GeneratorConfig struct {
+	StatusWeights []StatusWeight `json:"status_weights"`
+}
+
+// StatusWeight is the relative frequency of an HTTP status code in generated logs
+type StatusWeight struct {
+	Status int `json:"status"`
+	Weight int `json:"weight"`
+}
+
+// ExportConfig holds compliance export configuration
+type ExportConfig struct {
+	SigningKey  string `json:"-"` // hex-encoded Ed25519 seed; exports are disabled when empty
+	RowsPerFile int    `json:"rows_per_file"`
+}
+
+// PipelineConfig holds log processing pipeline configuration
+type PipelineConfig struct {
+	HeaderAttributes []string       `json:"header_attributes"` // Kafka headers stored as log attributes
+	HeaderFilters    []HeaderFilter `json:"header_filters"`    // only messages matching all filters are processed
+	ParsersFile      string         `json:"parsers_file"`      // YAML file defining parsers for non-native message formats
+	FingerprintFile  string         `json:"fingerprint_file"`  // YAML file defining masks applied before fingerprinting errors
+	RedactBuiltins   []string       `json:"redact_builtins"`   // built-in redaction rules applied, * for all
+	RedactionFile    string         `json:"redaction_file"`    // YAML file defining redaction rules applied before the built-in ones
+
+	IngestionDelayThreshold time.Duration `json:"ingestion_delay_threshold"` // services whose logs are mostly stored later than this fire the ingestion delay alert; 0 disables it
+	IngestionDelayWindow    time.Duration `json:"ingestion_delay_window"`    // stored logs are checked against the threshold once per window
+
+	DrainTimeout time.Duration `json:"drain_timeout"` // how long the processor takes on shutdown to store batches in flight and commit their offsets
+
+	BatchSize    int           `json:"batch_size"`    // logs per batch; a batch is flushed once full
+	BatchTimeout time.Duration `json:"batch_timeout"` // longest a batch of the bulk lane is collected before it is flushed
+	WriteWorkers int           `json:"write_workers"` // batches of the bulk lane stored concurrently
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
+// ParsersFile is the layout of the YAML parser definitions file
+type ParsersFile struct {
+	Parsers []ParserConfig `yaml:"parsers"`
+}
+
+// ParserConfig defines a parser decoding messages that aren't in the native log JSON shape.
+// Fields maps log columns (timestamp, level, service, message, trace_id, ...) to extracted field names;
+// a column not listed is read from the field of the same name. Other fields become attributes.
+type ParserConfig struct {
+	Name            string              `json:"name" yaml:"name"`
+	Type            string              `json:"type" yaml:"type"`                         // json, logfmt, regex or grok
+	Pattern         string              `json:"pattern" yaml:"pattern"`                   // regex or grok pattern
+	Patterns        map[string]string   `json:"patterns" yaml:"patterns"`                 // additional grok patterns by name
+	Headers         map[string][]string `json:"headers" yaml:"headers"`                   // only messages with one of these header values
+	Fields          map[string]string   `json:"fields" yaml:"fields"`                     // log column -> extracted field
+	TimestampFormat string              `json:"timestamp_format" yaml:"timestamp_format"` // Go layout, unix or unix_ms; RFC 3339 by default
+	Defaults        map[string]string   `json:"defaults" yaml:"defaults"`                 // log column -> value used when the field is missing
+}
+
+// FingerprintFile is the layout of the YAML fingerprint rules file
+type FingerprintFile struct {
+	Masks []FingerprintMask `yaml:"masks"`
+}
+
+// FingerprintMask replaces the parts of error messages matching a pattern, so that errors differing only in
+// those parts share a fingerprint
+type FingerprintMask struct {
+	Name        string `json:"name" yaml:"name"`
+	Pattern     string `json:"pattern" yaml:"pattern"`         // regular expression
+	Replacement string `json:"replacement" yaml:"replacement"` // text matches are replaced with
+}
+
+// RedactionFile is the layout of the YAML redaction rules file
+type RedactionFile struct {
+	Rules []RedactionRule `yaml:"rules"`
+}
+
+// RedactionRule masks sensitive data in logs before they are stored: the parts of fields matching a pattern, or the
+// whole value of the fields when the rule has no pattern
+type RedactionRule struct {
+	Name        string   `json:"name" yaml:"name"`
+	Pattern     string   `json:"pattern" yaml:"pattern"`         // regular expression; the replacement may refer to its groups, e.g. ${1}
+	Replacement string   `json:"replacement" yaml:"replacement"` // [REDACTED] when empty
+	Fields      []string `json:"fields" yaml:"fields"`           // message and request_path by default; required without pattern
+}
+
+// HeaderFilter matches messages whose header has one of the given values
+type HeaderFilter struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// MaintenanceConfig holds maintenance mode configuration
+type MaintenanceConfig struct {
+	ReadOnly bool   `json:"read_only"` // forces read-only mode regardless of the admin switch
+	Banner   string `json:"banner"`    // default banner shown while no banner is set via the API
+}
+
+// CollectorConfig holds log collector configuration
+type CollectorConfig struct {
+	IngestEnabled   bool          `json:"ingest_enabled"`
+	IngestPort      string        `json:"ingest_port"`
+	MaxBodyBytes    int64         `json:"max_body_bytes"`
+	MaxBatchSize    int           `json:"max_batch_size"`
+	GenerateSamples bool          `json:"generate_samples"`
+	SyslogUDPAddr   string        `json:"syslog_udp_addr"`
+	SyslogTCPAddr   string        `json:"syslog_tcp_addr"`
+	OTLPHTTPAddr    string        `json:"otlp_http_addr"`
+	OTLPGRPCAddr    string        `json:"otlp_grpc_addr"`
+	BodyServices    []string      `json:"body_services"`    // services whose request/response bodies are kept; empty keeps none
+	BodyMaxBytes    int           `json:"body_max_bytes"`   // longer bodies are truncated
+	BodyRedactKeys  []string      `json:"body_redact_keys"` // fields whose values are redacted from bodies
+	TailPaths       []string      `json:"tail_paths"`
+	TailCheckpoint  string        `json:"tail_checkpoint"`
+	AsyncProducer   bool          `json:"async_producer"`
+	BufferSize      int           `json:"buffer_size"`
+	FlushMessages   int           `json:"flush_messages"`
+	FlushFrequency  time.Duration `json:"flush_frequency"`
+	Compression     string        `json:"compression"`   // none, gzip, snappy, lz4 or zstd
+	PartitionKey    string        `json:"partition_key"` // trace_id, service, tenant or round_robin
+	Partitioner     string        `json:"partitioner"`   // hash, murmur2 or crc32, mapping keys to partitions
+
+	// Spooling of the logs that can't be delivered while Kafka is unreachable, republished once it is back
+	SpoolDir           string        `json:"spool_dir"` // empty disables spooling
+	SpoolMaxBytes      int64         `json:"spool_max_bytes"`
+	SpoolDrainInterval time.Duration `json:"spool_drain_interval"`
+
+	// Sampling and rate limiting of the logs published, to keep high-volume services from overwhelming the pipeline
+	SampleRates       []SampleRate       `json:"sample_rates"`        // levels not listed are all published
+	RateLimit         int                `json:"rate_limit"`          // logs per second published per service; 0 is unlimited
+	ServiceRateLimits []ServiceRateLimit `json:"service_rate_limits"` // overrides of the rate limit for some services
+}
+
+// SampleRate is the share of the logs of a level the collector publishes, between 0 and 1
+type SampleRate struct {
+	Level string  `json:"level"`
+	Rate  float64 `json:"rate"`
+}
+
+// ServiceRateLimit is the number of logs per second the collector publishes for a service; 0 is unlimited
+type ServiceRateLimit struct {
+	Service string `json:"service"`
+	Limit   int    `json:"limit"`
+}
+
+// MetricsConfig holds Prometheus metrics configuration. The API server serves metrics on its own port;
+// the collector and processor have no API and serve them on dedicated ports.
+type MetricsConfig struct {
+	Enabled       bool   `json:"enabled"`
+	CollectorPort string `json:"collector_port"`
+	ProcessorPort string `json:"processor_port"`
+}
+
+// HealthConfig holds the configuration of the liveness and readiness probes of the collector and processor
+type HealthConfig struct {
+	Enabled       bool   `json:"enabled"`
+	CollectorPort string `json:"collector_port"`
+	ProcessorPort string `json:"processor_port"`
+}
+
+// NotificationConfig holds alert notification delivery configuration. The SMTP settings are shared by all email channels.
+type NotificationConfig struct {
+	Timeout        time.Duration `json:"timeout"`
+	MaxAttempts    int           `json:"max_attempts"`
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+	SMTPHost       string        `json:"smtp_host"`
+	SMTPPort       string        `json:"smtp_port"`
+	SMTPUsername   string        `json:"smtp_username"`
+	SMTPPassword   string        `json:"-"`
+	SMTPFrom       string        `json:"smtp_from"`
+}
+
+// MigrationConfig holds migration runner configuration
+type MigrationConfig struct {
+	OnlineDDLTool    string   `json:"online_ddl_tool"`    // gh-ost or pt-online-schema-change; empty runs every ALTER directly
+	OnlineDDLBinary  string   `json:"online_ddl_binary"`  // path of the tool, looked up in PATH by default
+	OnlineDDLOptions []string `json:"online_ddl_options"` // extra command line options passed to the tool
+	OnlineDDLMinRows int64    `json:"online_ddl_min_rows"`
+	AppliedBy        string   `json:"applied_by"` // recorded as who applied migrations; the OS user and host by default
+
+	LockTimeout time.Duration `json:"lock_timeout"` // how long a run waits for another instance's run to finish
+	Dir         string        `json:"dir"`          // directory of the migration scripts; those embedded in the binaries by default
+	OnStartup   bool          `json:"on_startup"`   // whether the API server and log processor apply pending migrations on startup
+
+	// Storage layout of the logs table on MySQL; empty values leave the table as it is
+	LogsCompression  string `json:"logs_compression"`    // none, row, zlib or lz4
+	LogsKeyBlockSize int    `json:"logs_key_block_size"` // KB of compressed pages with row compression
+	LogsPrimaryKey   string `json:"logs_primary_key"`    // id or timestamp_id
+}
+
+// AuthConfig holds API and dashboard authentication configuration
+type AuthConfig struct {
+	Provider      string         `json:"provider"`
+	CacheTTL      time.Duration  `json:"cache_ttl"`
+	LDAP          LDAPConfig     `json:"ldap"`
+	ServiceTokens []ServiceToken `json:"-"`
+	TailLimits    ScopeLimits    `json:"tail_limits"`
+	ExportLimits  ScopeLimits    `json:"export_limits"`
+}
+
+// ServiceToken is a bearer token granting a service access to the endpoints of its scopes, limited to the logs of
+// its tenant when it has one
+type ServiceToken struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"-"` // hex-encoded SHA256 of the token
+	Scopes []string `json:"scopes"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// ScopeLimits caps what each caller may do with the endpoints of a scope; 0 disables a cap
+type ScopeLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	RowsPerHour       int `json:"rows_per_hour"` // logs streamed or exported
+}
+
+// LDAPConfig holds LDAP/Active Directory authentication configuration
+type LDAPConfig struct {
+	URL            string        `json:"url"`
+	UserDNTemplate string        `json:"user_dn_template"`
+	BaseDN         string        `json:"base_dn"`
+	UserAttribute  string        `json:"user_attribute"`
+	GroupAttribute string        `json:"group_attribute"`
+	GroupRoles     []GroupRole   `json:"group_roles"`
+	GroupTenants   []GroupTenant `json:"group_tenants"` // limits members of the groups to the data of a tenant
+	DefaultRole    string        `json:"default_role"`
+	Timeout        time.Duration `json:"timeout"`
+	StartTLS       bool          `json:"start_tls"` // upgrade ldap:// connections to TLS before binding
+	Insecure       bool          `json:"insecure"`  // allow ldap:// without StartTLS, sending passwords in cleartext
+	CAFile         string        `json:"ca_file"`   // PEM certificates trusted for the directory; empty uses the system roots
+}
+
+// GroupRole maps a directory group to a role
+type GroupRole struct {
+	Group string `json:"group"`
+	Role  string `json:"role"`
+}
+
+// GroupTenant maps a directory group to the tenant its members are limited to
+type GroupTenant struct {
+	Group  string `json:"group"`
+	Tenant string `json:"tenant"`
+}
+
+// Load loads configuration from environment variables layered over the YAML config file at path, or at
+// CONFIG_FILE when path is empty, and the defaults. Malformed values and unknown settings of the file are
+// reported together in the returned error rather than replaced with defaults.
+func Load(path string) (*Config, error) {
 	godotenv.Load()
 
+	if path == "" {
+		path = os.Getenv(constants.EnvKeyConfigFile)
+	}
+	l, err := newLoader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The database port defaults to the driver's
+	dbDriver := l.getEnv(constants.EnvKeyDBDriver, constants.DefaultDBDriver)
+	dbPort := constants.DefaultDBPort
+	if dbDriver == constants.DBDriverPostgres {
+		dbPort = constants.DefaultPGPort
+	}
+
 	config := &Config{
+		File:          path,
+		WatchInterval: l.getEnvAsDuration(constants.EnvKeyConfigWatchInterval, 0),
 		Server: ServerConfig{
-			Port:         getEnv(constants.EnvKeyAPIPort, constants.DefaultServerPort),
-			ReadTimeout:  getEnvAsDuration(constants.EnvKeyServerReadTimeout, constants.DefaultServerReadTimeout),
-			WriteTimeout: getEnvAsDuration(constants.EnvKeyServerWriteTimeout, constants.DefaultServerWriteTimeout),
-			IdleTimeout:  getEnvAsDuration(constants.EnvKeyServerIdleTimeout, constants.DefaultServerIdleTimeout),
+			Port:         l.getEnv(constants.EnvKeyAPIPort, constants.DefaultServerPort),
+			ReadTimeout:  l.getEnvAsDuration(constants.EnvKeyServerReadTimeout, constants.DefaultServerReadTimeout),
+			WriteTimeout: l.getEnvAsDuration(constants.EnvKeyServerWriteTimeout, constants.DefaultServerWriteTimeout),
+			IdleTimeout:  l.getEnvAsDuration(constants.EnvKeyServerIdleTimeout, constants.DefaultServerIdleTimeout),
+			LegacyRoutes: l.getEnvAsBool(constants.EnvKeyAPILegacyRoutes, true),
+			DebugSQL:     l.getEnvAsBool(constants.EnvKeyAPIDebugSQL, false),
+			Docs:         l.getEnvAsBool(constants.EnvKeyAPIDocsEnabled, true),
+
+			ShutdownTimeout: l.getEnvAsPositiveDuration(constants.EnvKeyServerShutdownTimeout, constants.DefaultShutdownTimeout),
+
+			CORS: CORSConfig{
+				AllowedOrigins:   l.getEnvAsSlice(constants.EnvKeyCORSAllowedOrigins, nil),
+				AllowedMethods:   l.getEnvAsSlice(constants.EnvKeyCORSAllowedMethods, strings.Split(constants.DefaultCORSAllowedMethods, ",")),
+				AllowedHeaders:   l.getEnvAsSlice(constants.EnvKeyCORSAllowedHeaders, strings.Split(constants.DefaultCORSAllowedHeaders, ",")),
+				ExposedHeaders:   l.getEnvAsSlice(constants.EnvKeyCORSExposedHeaders, strings.Split(constants.DefaultCORSExposedHeaders, ",")),
+				AllowCredentials: l.getEnvAsBool(constants.EnvKeyCORSAllowCredentials, false),
+				MaxAge:           l.getEnvAsDuration(constants.EnvKeyCORSMaxAge, constants.DefaultCORSMaxAge),
+			},
+			SecurityHeaders: l.getEnvAsBool(constants.EnvKeySecurityHeadersEnabled, true),
+			HSTSMaxAge:      l.getEnvAsDuration(constants.EnvKeyHSTSMaxAge, 0),
+
+			Compression:         l.getEnvAsBool(constants.EnvKeyCompressionEnabled, true),
+			CompressionMinBytes: l.getEnvAsInt(constants.EnvKeyCompressionMinBytes, constants.DefaultCompressionMinBytes),
+			CompressionLevel:    l.getEnvAsInt(constants.EnvKeyCompressionLevel, constants.DefaultCompressionLevel),
 		},
 		Database: DatabaseConfig{
-			Host:            getEnv(constants.EnvKeyDBHost, constants.DefaultDBHost),
-			Port:            getEnv(constants.EnvKeyDBPort, constants.DefaultDBPort),
-			Username:        getEnv(constants.EnvKeyDBUser, constants.DefaultDBUser),
-			Password:        getEnv(constants.EnvKeyDBPassword, constants.DefaultDBPassword),
-			Database:        getEnv(constants.EnvKeyDBDatabase, constants.DefaultDBName),
-			MaxOpenConns:    getEnvAsInt(constants.EnvKeyDBMaxOpenConns, constants.DefaultMaxOpenConns),
-			MaxIdleConns:    getEnvAsInt(constants.EnvKeyDBMaxIdleConns, constants.DefaultMaxIdleConns),
-			ConnMaxLifetime: getEnvAsDuration(constants.EnvKeyDBConnMaxLifetime, constants.DefaultConnMaxLifetime),
+			Driver:          dbDriver,
+			SSLMode:         l.getEnv(constants.EnvKeyDBSSLMode, constants.DefaultPGSSLMode),
+			Host:            l.getEnv(constants.EnvKeyDBHost, constants.DefaultDBHost),
+			Port:            l.getEnv(constants.EnvKeyDBPort, dbPort),
+			Username:        l.getEnv(constants.EnvKeyDBUser, constants.DefaultDBUser),
+			Password:        l.getEnv(constants.EnvKeyDBPassword, constants.DefaultDBPassword),
+			Database:        l.getEnv(constants.EnvKeyDBDatabase, constants.DefaultDBName),
+			MaxOpenConns:    l.getEnvAsInt(constants.EnvKeyDBMaxOpenConns, constants.DefaultMaxOpenConns),
+			MaxIdleConns:    l.getEnvAsInt(constants.EnvKeyDBMaxIdleConns, constants.DefaultMaxIdleConns),
+			ConnMaxLifetime: l.getEnvAsDuration(constants.EnvKeyDBConnMaxLifetime, constants.DefaultConnMaxLifetime),
 		},
 		Kafka: KafkaConfig{
-			Brokers:          getEnvAsSlice(constants.EnvKeyKafkaBrokers, []string{constants.DefaultKafkaBroker}),
-			Topic:            getEnv(constants.EnvKeyKafkaTopic, constants.DefaultKafkaTopic),
-			GroupID:          getEnv(constants.EnvKeyKafkaGroupID, constants.DefaultConsumerGroupID),
-			AutoOffsetReset:  getEnv(constants.EnvKeyKafkaAutoOffsetReset, constants.DefaultAutoOffsetReset),
-			EnableAutoCommit: getEnvAsBool(constants.EnvKeyKafkaEnableAutoCommit, true),
+			Brokers:              l.getEnvAsSlice(constants.EnvKeyKafkaBrokers, []string{constants.DefaultKafkaBroker}),
+			Topic:                l.getEnv(constants.EnvKeyKafkaTopic, constants.DefaultKafkaTopic),
+			GroupID:              l.getEnv(constants.EnvKeyKafkaGroupID, constants.DefaultConsumerGroupID),
+			AutoOffsetReset:      l.getEnv(constants.EnvKeyKafkaAutoOffsetReset, constants.DefaultAutoOffsetReset),
+			EnableAutoCommit:     l.getEnvAsBool(constants.EnvKeyKafkaEnableAutoCommit, true),
+			DeadLetterTopic:      l.getEnv(constants.EnvKeyKafkaDeadLetterTopic, constants.DefaultDeadLetterTopic),
+			PriorityTopic:        l.getEnv(constants.EnvKeyKafkaPriorityTopic, ""),
+			PriorityBatchTimeout: l.getEnvAsDuration(constants.EnvKeyKafkaPriorityTimeout, constants.DefaultPriorityBatchTimeout),
+			TenantTopics:         parseTenantTopics(l.getEnvAsSlice(constants.EnvKeyKafkaTenantTopics, nil)),
+			StreamTopic:          l.getEnv(constants.EnvKeyKafkaStreamTopic, constants.DefaultKafkaStreamTopic),
+			ControlTopic:         l.getEnv(constants.EnvKeyKafkaControlTopic, ""),
+			StoreRetries:         l.getEnvAsInt(constants.EnvKeyKafkaStoreRetries, constants.DefaultStoreRetries),
+			StoreRetryBackoff:    l.getEnvAsPositiveDuration(constants.EnvKeyKafkaStoreBackoff, constants.DefaultStoreRetryBackoff),
+			StoreBreaker:         l.getEnvAsInt(constants.EnvKeyKafkaStoreBreaker, constants.DefaultStoreBreakerThreshold),
+			StoreBreakerProbe:    l.getEnvAsPositiveDuration(constants.EnvKeyKafkaStoreBreakerPing, constants.DefaultStoreBreakerProbeInterval),
+			DedupCacheSize:       l.getEnvAsInt(constants.EnvKeyKafkaDedupCacheSize, constants.DefaultDedupCacheSize),
+			LagCheckInterval:     l.getEnvAsPositiveDuration(constants.EnvKeyKafkaLagCheckInterval, constants.DefaultLagCheckInterval),
+			LagAlertThreshold:    int64(l.getEnvAsInt(constants.EnvKeyKafkaLagThreshold, 0)),
+			MessageFormat:        l.getEnv(constants.EnvKeyKafkaMessageFormat, constants.MessageFormatJSON),
+			SchemaRegistry: SchemaRegistryConfig{
+				URL:      l.getEnv(constants.EnvKeySchemaRegistryURL, ""),
+				Username: l.getEnv(constants.EnvKeySchemaRegistryUser, ""),
+				Password: l.getEnv(constants.EnvKeySchemaRegistryPass, ""),
+				Timeout:  l.getEnvAsPositiveDuration(constants.EnvKeySchemaRegistryTimeout, constants.DefaultSchemaRegistryTimeout),
+			},
 		},
 		Log: LogConfig{
-			Level:  getEnv(constants.EnvKeyLogLevel, constants.DefaultLogLevel),
-			Format: getEnv(constants.EnvKeyLogFormat, constants.DefaultLogFormat),
+			Level:  l.getEnvAsLevel(constants.EnvKeyLogLevel, constants.DefaultLogLevel),
+			Format: l.getEnv(constants.EnvKeyLogFormat, constants.DefaultLogFormat),
+		},
+		Enrichment: EnrichmentConfig{
+			Enabled:          l.getEnvAsBool(constants.EnvKeyEnrichmentEnabled, false),
+			Endpoints:        parseEnrichmentEndpoints(l.getEnvAsSlice(constants.EnvKeyEnrichmentEndpoints, nil)),
+			Timeout:          l.getEnvAsDuration(constants.EnvKeyEnrichmentTimeout, constants.DefaultEnrichmentTimeout),
+			CacheTTL:         l.getEnvAsDuration(constants.EnvKeyEnrichmentCacheTTL, constants.DefaultEnrichmentCacheTTL),
+			CacheMaxEntries:  l.getEnvAsInt(constants.EnvKeyEnrichmentCacheMaxEntries, constants.DefaultEnrichmentCacheMaxEntries),
+			Concurrency:      l.getEnvAsInt(constants.EnvKeyEnrichmentConcurrency, constants.DefaultEnrichmentConcurrency),
+			QueueSize:        l.getEnvAsInt(constants.EnvKeyEnrichmentQueueSize, constants.DefaultEnrichmentQueueSize),
+			FailureThreshold: l.getEnvAsInt(constants.EnvKeyEnrichmentFailureThreshold, constants.DefaultEnrichmentFailureThreshold),
+			BreakerCooldown:  l.getEnvAsDuration(constants.EnvKeyEnrichmentBreakerCooldown, constants.DefaultEnrichmentBreakerCooldown),
+		},
+		Storage: StorageConfig{
+			GrowthWindowDays: l.getEnvAsInt(constants.EnvKeyStorageGrowthWindowDays, constants.DefaultStorageGrowthWindowDays),
+			DiskCapacityGB:   l.getEnvAsInt(constants.EnvKeyStorageDiskCapacityGB, constants.DefaultStorageDiskCapacityGB),
+		},
+		Alert: AlertConfig{
+			CheckEnabled:               l.getEnvAsBool(constants.EnvKeyAlertCheckEnabled, constants.DefaultAlertCheckEnabled),
+			CheckInterval:              l.getEnvAsPositiveDuration(constants.EnvKeyAlertCheckInterval, constants.DefaultAlertCheckInterval*time.Second),
+			CatchUpEnabled:             l.getEnvAsBool(constants.EnvKeyAlertCatchUpEnabled, constants.DefaultAlertCatchUpEnabled),
+			CatchUpMaxLookback:         l.getEnvAsDuration(constants.EnvKeyAlertCatchUpMaxLookback, constants.DefaultAlertCatchUpMaxLookback),
+			CanaryPeriod:               l.getEnvAsDuration(constants.EnvKeyAlertCanaryPeriod, constants.DefaultAlertCanaryPeriod),
+			MetaAlertIntervals:         l.getEnvAsInt(constants.EnvKeyAlertMetaAlertIntervals, constants.DefaultAlertMetaAlertIntervals),
+			RetentionAge:               l.getEnvAsDuration(constants.EnvKeyAlertRetentionAge, constants.DefaultAlertRetentionAge),
+			RetentionInterval:          l.getEnvAsPositiveDuration(constants.EnvKeyAlertRetentionInterval, constants.DefaultAlertRetentionInterval),
+			RetentionBatchSize:         l.getEnvAsInt(constants.EnvKeyAlertRetentionBatchSize, constants.DefaultAlertRetentionBatchSize),
+			RetentionOptimizeThreshold: l.getEnvAsInt(constants.EnvKeyAlertRetentionOptimizeThreshold, constants.DefaultAlertRetentionOptimizeThreshold),
+		},
+		Routing: RoutingConfig{
+			Rules: parseRoutingRules(l.getEnvAsSlice(constants.EnvKeyLogRoutingRules, nil)),
+		},
+		Generator: GeneratorConfig{
+			StatusWeights: parseStatusWeights(l.getEnvAsSlice(constants.EnvKeyGeneratorStatusWeights,
+				strings.Split(constants.DefaultGeneratorStatusWeights, ","))),
+		},
+		Export: ExportConfig{
+			SigningKey:  l.getEnv(constants.EnvKeyExportSigningKey, ""),
+			RowsPerFile: l.getEnvAsInt(constants.EnvKeyExportRowsPerFile, constants.DefaultExportRowsPerFile),
+		},
+		Pipeline: PipelineConfig{
+			HeaderAttributes: l.getEnvAsSlice(constants.EnvKeyPipelineHeaderAttributes, nil),
+			HeaderFilters:    parseHeaderFilters(l.getEnvAsSlice(constants.EnvKeyPipelineHeaderFilters, nil)),
+			ParsersFile:      l.getEnv(constants.EnvKeyPipelineParsersFile, ""),
+			FingerprintFile:  l.getEnv(constants.EnvKeyPipelineFingerprintFile, ""),
+			RedactBuiltins:   l.getEnvAsSlice(constants.EnvKeyPipelineRedactBuiltins, nil),
+			RedactionFile:    l.getEnv(constants.EnvKeyPipelineRedactionFile, ""),
+
+			IngestionDelayThreshold: l.getEnvAsDuration(constants.EnvKeyIngestionDelayThreshold, constants.DefaultIngestionDelayThreshold),
+			IngestionDelayWindow:    l.getEnvAsPositiveDuration(constants.EnvKeyIngestionDelayWindow, constants.DefaultIngestionDelayWindow),
+
+			DrainTimeout: l.getEnvAsPositiveDuration(constants.EnvKeyPipelineDrainTimeout, constants.DefaultDrainTimeout),
+
+			BatchSize:    l.getEnvAsInt(constants.EnvKeyPipelineBatchSize, constants.DefaultBatchSize),
+			BatchTimeout: l.getEnvAsPositiveDuration(constants.EnvKeyPipelineBatchTimeout, constants.DefaultBatchTimeout),
+			WriteWorkers: l.getEnvAsInt(constants.EnvKeyPipelineWriteWorkers, constants.DefaultWriteWorkers),
+		},
+		Maintenance: MaintenanceConfig{
+			ReadOnly: l.getEnvAsBool(constants.EnvKeyReadOnlyMode, false),
+			Banner:   l.getEnv(constants.EnvKeyMaintenanceBanner, ""),
+		},
+		Collector: CollectorConfig{
+			IngestEnabled:   l.getEnvAsBool(constants.EnvKeyCollectorIngestEnabled, true),
+			IngestPort:      l.getEnv(constants.EnvKeyCollectorIngestPort, constants.DefaultIngestPort),
+			MaxBodyBytes:    int64(l.getEnvAsInt(constants.EnvKeyCollectorMaxBodyBytes, constants.DefaultIngestMaxBodyBytes)),
+			MaxBatchSize:    l.getEnvAsInt(constants.EnvKeyCollectorMaxBatchSize, constants.DefaultIngestMaxBatchSize),
+			GenerateSamples: l.getEnvAsBool(constants.EnvKeyCollectorGenerateSample, true),
+			SyslogUDPAddr:   l.getEnv(constants.EnvKeyCollectorSyslogUDPAddr, ""),
+			SyslogTCPAddr:   l.getEnv(constants.EnvKeyCollectorSyslogTCPAddr, ""),
+			OTLPHTTPAddr:    l.getEnv(constants.EnvKeyCollectorOTLPHTTPAddr, ""),
+			OTLPGRPCAddr:    l.getEnv(constants.EnvKeyCollectorOTLPGRPCAddr, ""),
+			BodyServices:    l.getEnvAsSlice(constants.EnvKeyCollectorBodyServices, nil),
+			BodyMaxBytes:    l.getEnvAsInt(constants.EnvKeyCollectorBodyMaxBytes, constants.DefaultBodyCaptureMaxBytes),
+			BodyRedactKeys:  l.getEnvAsSlice(constants.EnvKeyCollectorBodyRedactKeys, constants.DefaultBodyRedactKeys),
+			TailPaths:       l.getEnvAsSlice(constants.EnvKeyCollectorTailPaths, nil),
+			TailCheckpoint:  l.getEnv(constants.EnvKeyCollectorTailCheckpoint, constants.DefaultTailCheckpointFile),
+			AsyncProducer:   l.getEnvAsBool(constants.EnvKeyCollectorAsyncProducer, false),
+			BufferSize:      l.getEnvAsInt(constants.EnvKeyCollectorBufferSize, constants.DefaultProducerBufferSize),
+			FlushMessages:   l.getEnvAsInt(constants.EnvKeyCollectorFlushMessages, constants.DefaultProducerFlushMessages),
+			FlushFrequency:  l.getEnvAsDuration(constants.EnvKeyCollectorFlushFrequency, constants.DefaultProducerFlushFrequency),
+			Compression:     strings.ToLower(l.getEnv(constants.EnvKeyCollectorCompression, constants.DefaultProducerCompression)),
+			PartitionKey:    strings.ToLower(l.getEnv(constants.EnvKeyCollectorPartitionKey, constants.PartitionKeyTraceID)),
+			Partitioner:     strings.ToLower(l.getEnv(constants.EnvKeyCollectorPartitioner, constants.PartitionerHash)),
+
+			SpoolDir:           l.getEnv(constants.EnvKeyCollectorSpoolDir, ""),
+			SpoolMaxBytes:      int64(l.getEnvAsInt(constants.EnvKeyCollectorSpoolMaxBytes, constants.DefaultSpoolMaxBytes)),
+			SpoolDrainInterval: l.getEnvAsPositiveDuration(constants.EnvKeyCollectorSpoolDrain, constants.DefaultSpoolDrainInterval),
+
+			SampleRates:       parseSampleRates(l.getEnvAsSlice(constants.EnvKeyCollectorSampleRates, nil)),
+			RateLimit:         l.getEnvAsInt(constants.EnvKeyCollectorRateLimit, 0),
+			ServiceRateLimits: parseServiceRateLimits(l.getEnvAsSlice(constants.EnvKeyCollectorServiceLimits, nil)),
+		},
+		Auth: AuthConfig{
+			Provider: strings.ToLower(l.getEnv(constants.EnvKeyAuthProvider, constants.AuthProviderNone)),
+			CacheTTL: l.getEnvAsDuration(constants.EnvKeyAuthCacheTTL, constants.DefaultAuthCacheTTL),
+			LDAP: LDAPConfig{
+				URL:            l.getEnv(constants.EnvKeyLDAPURL, ""),
+				UserDNTemplate: l.getEnv(constants.EnvKeyLDAPUserDNTemplate, ""),
+				BaseDN:         l.getEnv(constants.EnvKeyLDAPBaseDN, ""),
+				UserAttribute:  l.getEnv(constants.EnvKeyLDAPUserAttribute, constants.DefaultLDAPUserAttribute),
+				GroupAttribute: l.getEnv(constants.EnvKeyLDAPGroupAttribute, constants.DefaultLDAPGroupAttribute),
+				GroupRoles:     parseGroupRoles(l.getEnv(constants.EnvKeyLDAPGroupRoles, "")),
+				GroupTenants:   parseGroupTenants(l.getEnv(constants.EnvKeyLDAPGroupTenants, "")),
+				DefaultRole:    l.getEnv(constants.EnvKeyLDAPDefaultRole, ""),
+				Timeout:        l.getEnvAsPositiveDuration(constants.EnvKeyLDAPTimeout, constants.DefaultLDAPTimeout),
+				StartTLS:       l.getEnvAsBool(constants.EnvKeyLDAPStartTLS, true),
+				Insecure:       l.getEnvAsBool(constants.EnvKeyLDAPInsecure, false),
+				CAFile:         l.getEnv(constants.EnvKeyLDAPCAFile, ""),
+			},
+			ServiceTokens: parseServiceTokens(l.getEnvAsSlice(constants.EnvKeyAuthServiceTokens, nil)),
+			TailLimits: ScopeLimits{
+				RequestsPerMinute: l.getEnvAsInt(constants.EnvKeyAuthTailRate, constants.DefaultTailRequestsPerMinute),
+				RowsPerHour:       l.getEnvAsInt(constants.EnvKeyAuthTailVolume, constants.DefaultTailLogsPerHour),
+			},
+			ExportLimits: ScopeLimits{
+				RequestsPerMinute: l.getEnvAsInt(constants.EnvKeyAuthExportRate, constants.DefaultExportRequestsPerMinute),
+				RowsPerHour:       l.getEnvAsInt(constants.EnvKeyAuthExportVolume, constants.DefaultExportRowsPerHour),
+			},
+		},
+		Metrics: MetricsConfig{
+			Enabled:       l.getEnvAsBool(constants.EnvKeyMetricsEnabled, constants.DefaultMetricsEnabled),
+			CollectorPort: l.getEnv(constants.EnvKeyCollectorMetricsPort, constants.DefaultCollectorMetricsPort),
+			ProcessorPort: l.getEnv(constants.EnvKeyProcessorMetricsPort, constants.DefaultProcessorMetricsPort),
+		},
+		Health: HealthConfig{
+			Enabled:       l.getEnvAsBool(constants.EnvKeyHealthEnabled, constants.DefaultHealthEnabled),
+			CollectorPort: l.getEnv(constants.EnvKeyCollectorHealthPort, constants.DefaultCollectorHealthPort),
+			ProcessorPort: l.getEnv(constants.EnvKeyProcessorHealthPort, constants.DefaultProcessorHealthPort),
+		},
+		Notification: NotificationConfig{
+			Timeout:        l.getEnvAsPositiveDuration(constants.EnvKeyNotificationTimeout, constants.DefaultNotificationTimeout),
+			MaxAttempts:    l.getEnvAsInt(constants.EnvKeyNotificationMaxAttempts, constants.DefaultNotificationMaxAttempts),
+			InitialBackoff: l.getEnvAsPositiveDuration(constants.EnvKeyNotificationInitialBackoff, constants.DefaultNotificationInitialBackoff),
+			MaxBackoff:     l.getEnvAsPositiveDuration(constants.EnvKeyNotificationMaxBackoff, constants.DefaultNotificationMaxBackoff),
+			SMTPHost:       l.getEnv(constants.EnvKeySMTPHost, ""),
+			SMTPPort:       l.getEnv(constants.EnvKeySMTPPort, constants.DefaultSMTPPort),
+			SMTPUsername:   l.getEnv(constants.EnvKeySMTPUsername, ""),
+			SMTPPassword:   l.getEnv(constants.EnvKeySMTPPassword, ""),
+			SMTPFrom:       l.getEnv(constants.EnvKeySMTPFrom, ""),
+		},
+		Migration: MigrationConfig{
+			OnlineDDLTool:    l.getEnv(constants.EnvKeyOnlineDDLTool, ""),
+			OnlineDDLBinary:  l.getEnv(constants.EnvKeyOnlineDDLBinary, ""),
+			OnlineDDLOptions: strings.Fields(l.getEnv(constants.EnvKeyOnlineDDLOptions, "")),
+			OnlineDDLMinRows: int64(l.getEnvAsInt(constants.EnvKeyOnlineDDLMinRows, constants.DefaultOnlineDDLMinRows)),
+			AppliedBy:        l.getEnv(constants.EnvKeyMigrationAppliedBy, ""),
+			LockTimeout:      l.getEnvAsPositiveDuration(constants.EnvKeyMigrationLockTimeout, constants.DefaultMigrationLockTimeout),
+			Dir:              l.getEnv(constants.EnvKeyMigrationDir, ""),
+			OnStartup:        l.getEnvAsBool(constants.EnvKeyMigrateOnStartup, false),
+			LogsCompression:  l.getEnv(constants.EnvKeyLogsCompression, ""),
+			LogsKeyBlockSize: l.getEnvAsInt(constants.EnvKeyLogsKeyBlockSize, constants.DefaultLogsKeyBlockSize),
+			LogsPrimaryKey:   l.getEnv(constants.EnvKeyLogsPrimaryKey, ""),
+		},
+		Retention: RetentionConfig{
+			Interval:   l.getEnvAsPositiveDuration(constants.EnvKeyLogRetentionInterval, constants.DefaultLogRetentionInterval),
+			BatchSize:  l.getEnvAsInt(constants.EnvKeyLogRetentionBatchSize, constants.DefaultLogRetentionBatchSize),
+			BatchPause: l.getEnvAsDuration(constants.EnvKeyLogRetentionBatchPause, constants.DefaultLogRetentionBatchPause),
 		},
+		Dashboard: DashboardConfig{
+			CacheTTL:      l.getEnvAsDuration(constants.EnvKeyDashboardCacheTTL, constants.DefaultDashboardCacheTTL),
+			WarmupTimeout: l.getEnvAsPositiveDuration(constants.EnvKeyDashboardWarmupTimeout, constants.DefaultDashboardWarmupTimeout),
+			WeekStart:     l.getEnvAsWeekday(constants.EnvKeyDashboardWeekStart, constants.DefaultDashboardWeekStart),
+			BucketOffset:  l.getEnvAsDuration(constants.EnvKeyDashboardBucketOffset, constants.DefaultDashboardBucketOffset),
+		},
+		Scheduler: SchedulerConfig{
+			LeaseDuration: l.getEnvAsPositiveDuration(constants.EnvKeySchedulerLeaseDuration, constants.DefaultSchedulerLeaseDuration),
+			PollInterval:  l.getEnvAsPositiveDuration(constants.EnvKeySchedulerPollInterval, constants.DefaultSchedulerPollInterval),
+		},
+		SelfIngestion: SelfIngestionConfig{
+			Enabled:      l.getEnvAsBool(constants.EnvKeySelfIngestionEnabled, false),
+			Service:      l.getEnv(constants.EnvKeySelfIngestionService, constants.DefaultSelfIngestionService),
+			Level:        l.getEnvAsLevel(constants.EnvKeySelfIngestionLevel, constants.DefaultSelfIngestionLevel),
+			ExcludePaths: l.getEnvAsSlice(constants.EnvKeySelfIngestionExcludePaths, strings.Split(constants.DefaultSelfIngestionExcludePaths, ",")),
+		},
+		LogStore: LogStoreConfig{
+			Backend: l.getEnv(constants.EnvKeyLogStoreBackend, constants.LogStoreMySQL),
+			ClickHouse: ClickHouseConfig{
+				URL:       l.getEnv(constants.EnvKeyClickHouseURL, constants.DefaultClickHouseURL),
+				Database:  l.getEnv(constants.EnvKeyClickHouseDatabase, constants.DefaultClickHouseDatabase),
+				Username:  l.getEnv(constants.EnvKeyClickHouseUser, constants.DefaultClickHouseUser),
+				Password:  l.getEnv(constants.EnvKeyClickHousePassword, ""),
+				Timeout:   l.getEnvAsPositiveDuration(constants.EnvKeyClickHouseTimeout, constants.DefaultClickHouseTimeout),
+				BatchSize: l.getEnvAsInt(constants.EnvKeyClickHouseBatchSize, constants.DefaultClickHouseBatchSize),
+			},
+		},
+		StatusPage: StatusPageConfig{
+			Enabled:    l.getEnvAsBool(constants.EnvKeyStatusPageEnabled, false),
+			CacheTTL:   l.getEnvAsPositiveDuration(constants.EnvKeyStatusPageCacheTTL, constants.DefaultStatusPageCacheTTL),
+			StaleAfter: l.getEnvAsDuration(constants.EnvKeyStatusPageStaleAfter, constants.DefaultStatusPageStaleAfter),
+		},
+	}
+
+	if err := l.finish(); err != nil {
+		return nil, err
+	}
+	config.Settings = l.effective()
+	return config, nil
+}
+
+// parseEnrichmentEndpoints parses endpoint definitions in the form name|key|url.
+// Malformed entries are kept with only the raw value as name so Validate can report them.
+func parseEnrichmentEndpoints(values []string) []EnrichmentEndpoint {
+	var endpoints []EnrichmentEndpoint
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		parts := strings.SplitN(value, "|", 3)
+		if len(parts) != 3 {
+			endpoints = append(endpoints, EnrichmentEndpoint{Name: value})
+			continue
+		}
+		endpoints = append(endpoints, EnrichmentEndpoint{
+			Name: strings.TrimSpace(parts[0]),
+			Key:  strings.TrimSpace(parts[1]),
+			URL:  strings.TrimSpace(parts[2]),
+		})
+	}
+	return endpoints
+}
+
+// Validate checks the dashboard's bucket offset
+func (c *DashboardConfig) Validate() error {
+	if c.BucketOffset <= -constants.MaxBucketOffset || c.BucketOffset >= constants.MaxBucketOffset || c.BucketOffset%time.Second != 0 {
+		return fmt.Errorf("dashboard bucket offset must be a whole number of seconds between -%s and %s", constants.MaxBucketOffset, constants.MaxBucketOffset)
+	}
+	return nil
+}
+
+// Validate checks the CORS origins, the HSTS max age and the compression settings
+func (c *ServerConfig) Validate() error {
+	if c.HSTSMaxAge < 0 {
+		return fmt.Errorf("HSTS max age must not be negative")
+	}
+	if c.Compression && c.CompressionMinBytes < 0 {
+		return fmt.Errorf("compression min bytes must not be negative")
+	}
+	if c.Compression && (c.CompressionLevel < gzip.BestSpeed || c.CompressionLevel > gzip.BestCompression) {
+		return fmt.Errorf("compression level must be between %d and %d", gzip.BestSpeed, gzip.BestCompression)
+	}
+	if !c.CORS.Enabled() {
+		return nil
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == constants.CORSAllowAllOrigins {
+			if c.CORS.AllowCredentials {
+				return fmt.Errorf("CORS credentials can't be allowed for any origin, list the origins instead of %s", origin)
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return fmt.Errorf("invalid CORS origin %q: must be scheme://host[:port]", origin)
+		}
+	}
+	if len(c.CORS.AllowedMethods) == 0 || slices.Contains(c.CORS.AllowedMethods, "") {
+		return fmt.Errorf("CORS allowed methods must not be empty")
+	}
+	if c.CORS.MaxAge < 0 {
+		return fmt.Errorf("CORS max age must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the self-ingestion service and excluded paths
+func (c *SelfIngestionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(c.Service) == "" {
+		return fmt.Errorf("self-ingestion service is required")
+	}
+	for _, path := range c.ExcludePaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid self-ingestion excluded path %q: must start with /", path)
+		}
+	}
+	return nil
+}
+
+// Validate checks the database driver
+func (c *DatabaseConfig) Validate() error {
+	switch c.Driver {
+	case constants.DBDriverMySQL:
+	case constants.DBDriverPostgres:
+		switch c.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			return fmt.Errorf("invalid database SSL mode %q: expected disable, allow, prefer, require, verify-ca or verify-full", c.SSLMode)
+		}
+	default:
+		return fmt.Errorf("invalid database driver %q: expected %s or %s", c.Driver, constants.DBDriverMySQL, constants.DBDriverPostgres)
+	}
+	return nil
+}
+
+// DSN returns the data source name connecting to the given database with the configured driver. An empty
+// database connects to the server only, which for Postgres means its administrative database.
+func (c *DatabaseConfig) DSN(database string) string {
+	if c.Driver == constants.DBDriverPostgres {
+		if database == "" {
+			database = constants.PGAdminDatabase
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.Username, c.Password),
+			Host:     net.JoinHostPort(c.Host, c.Port),
+			Path:     "/" + database,
+			RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		c.Username, c.Password, c.Host, c.Port, database)
+}
+
+// Validate checks the log store backend and, when logs are stored in ClickHouse, its connection settings.
+// Per-service routing only applies to MySQL, so it can't be combined with ClickHouse.
+func (c *LogStoreConfig) Validate(routing *RoutingConfig) error {
+	switch c.Backend {
+	case constants.LogStoreMySQL:
+		return nil
+	case constants.LogStoreClickHouse:
+	default:
+		return fmt.Errorf("invalid log store backend %q: expected %s or %s", c.Backend, constants.LogStoreMySQL, constants.LogStoreClickHouse)
+	}
+
+	if len(routing.Rules) > 0 {
+		return fmt.Errorf("log routing rules are not supported with the %s log store", constants.LogStoreClickHouse)
+	}
+	parsed, err := url.Parse(c.ClickHouse.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid ClickHouse URL %q: expected http(s)://host[:port]", c.ClickHouse.URL)
+	}
+	if !tableNamePattern.MatchString(c.ClickHouse.Database) {
+		return fmt.Errorf("invalid ClickHouse database %q: must contain only letters, digits and underscores", c.ClickHouse.Database)
+	}
+	if c.ClickHouse.BatchSize <= 0 {
+		return fmt.Errorf("ClickHouse batch size must be positive")
+	}
+	return nil
+}
+
+// Validate checks the notification delivery settings
+func (c *NotificationConfig) Validate() error {
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("notification max attempts must be positive")
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("notification max backoff must not be less than the initial backoff")
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		return fmt.Errorf("SMTP sender address is required when an SMTP host is set")
+	}
+	return nil
+}
+
+// Validate checks the online DDL and logs table layout settings
+func (c *MigrationConfig) Validate() error {
+	if c.OnlineDDLTool != "" && c.OnlineDDLTool != constants.OnlineDDLToolGhost && c.OnlineDDLTool != constants.OnlineDDLToolPTOSC {
+		return fmt.Errorf("invalid online DDL tool %q: expected %s or %s", c.OnlineDDLTool, constants.OnlineDDLToolGhost, constants.OnlineDDLToolPTOSC)
+	}
+	if c.OnlineDDLMinRows < 0 {
+		return fmt.Errorf("online DDL minimum rows must not be negative")
+	}
+	switch c.LogsCompression {
+	case "", constants.LogsCompressionNone, constants.LogsCompressionRow, constants.LogsCompressionZlib, constants.LogsCompressionLZ4:
+	default:
+		return fmt.Errorf("invalid logs compression %q: expected %s, %s, %s or %s", c.LogsCompression,
+			constants.LogsCompressionNone, constants.LogsCompressionRow, constants.LogsCompressionZlib, constants.LogsCompressionLZ4)
+	}
+	switch c.LogsKeyBlockSize {
+	case 1, 2, 4, 8, 16:
+	default:
+		return fmt.Errorf("invalid logs key block size %d: expected 1, 2, 4, 8 or 16", c.LogsKeyBlockSize)
+	}
+	if c.LogsPrimaryKey != "" && c.LogsPrimaryKey != constants.LogsPrimaryKeyID && c.LogsPrimaryKey != constants.LogsPrimaryKeyTimestamp {
+		return fmt.Errorf("invalid logs primary key %q: expected %s or %s", c.LogsPrimaryKey, constants.LogsPrimaryKeyID, constants.LogsPrimaryKeyTimestamp)
+	}
+	return nil
+}
+
+// Validate checks the log retention purge settings
+func (c *RetentionConfig) Validate() error {
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("log retention batch size must be positive")
+	}
+	if c.BatchPause < 0 {
+		return fmt.Errorf("log retention batch pause must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the alert canary, self-monitoring and retention settings
+func (c *AlertConfig) Validate() error {
+	if c.CanaryPeriod < 0 || c.CanaryPeriod > constants.MaxAlertCanaryPeriod {
+		return fmt.Errorf("alert canary period must be between 0 and %s", constants.MaxAlertCanaryPeriod)
+	}
+	if c.MetaAlertIntervals < 0 {
+		return fmt.Errorf("alert meta-alert intervals must not be negative")
+	}
+	if c.RetentionAge < 0 {
+		return fmt.Errorf("alert retention age must not be negative")
+	}
+	if c.RetentionBatchSize <= 0 {
+		return fmt.Errorf("alert retention batch size must be positive")
+	}
+	if c.RetentionOptimizeThreshold < 0 {
+		return fmt.Errorf("alert retention optimize threshold must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the priority lane, store retry and breaker, deduplication, lag alert, message format and tenant topic settings
+func (c *KafkaConfig) Validate() error {
+	if len(c.Brokers) == 0 {
+		return fmt.Errorf("at least one broker is required")
+	}
+	for _, broker := range c.Brokers {
+		if _, port, err := net.SplitHostPort(broker); err != nil || port == "" {
+			return fmt.Errorf("invalid broker %q: expected host:port", broker)
+		}
+	}
+	if c.StoreRetries < 0 {
+		return fmt.Errorf("store retries must not be negative")
+	}
+	if c.StoreBreaker < 0 {
+		return fmt.Errorf("store breaker threshold must not be negative")
+	}
+	if c.DedupCacheSize < 0 {
+		return fmt.Errorf("dedup cache size must not be negative")
+	}
+	if c.LagAlertThreshold < 0 {
+		return fmt.Errorf("lag alert threshold must not be negative")
+	}
+	switch c.MessageFormat {
+	case constants.MessageFormatJSON:
+	case constants.MessageFormatAvro:
+		if c.SchemaRegistry.URL == "" {
+			return fmt.Errorf("the avro message format requires a schema registry URL")
+		}
+	default:
+		return fmt.Errorf("message format must be %s or %s", constants.MessageFormatJSON, constants.MessageFormatAvro)
+	}
+	if c.PriorityTopic != "" {
+		if c.PriorityTopic == c.Topic || c.PriorityTopic == c.DeadLetterTopic {
+			return fmt.Errorf("priority topic %q must differ from the log and dead-letter topics", c.PriorityTopic)
+		}
+		if c.PriorityBatchTimeout <= 0 {
+			return fmt.Errorf("priority batch timeout must be positive")
+		}
 	}
 
-	return config
+	tenants := make(map[string]bool, len(c.TenantTopics))
+	topics := make(map[string]bool, len(c.TenantTopics))
+	for _, mapping := range c.TenantTopics {
+		if mapping.Topic == "" {
+			return fmt.Errorf("invalid tenant topic mapping %q: expected tenant|topic", mapping.Tenant)
+		}
+		if mapping.Tenant == "" {
+			return fmt.Errorf("invalid tenant topic mapping for topic %s: tenant is required", mapping.Topic)
+		}
+		if len(mapping.Tenant) > constants.MaxTenantLength {
+			return fmt.Errorf("tenant %q must be at most %d characters", mapping.Tenant, constants.MaxTenantLength)
+		}
+		if mapping.Topic == c.Topic || mapping.Topic == c.PriorityTopic || mapping.Topic == c.DeadLetterTopic {
+			return fmt.Errorf("topic %q of tenant %q must differ from the log, priority and dead-letter topics", mapping.Topic, mapping.Tenant)
+		}
+		if tenants[mapping.Tenant] {
+			return fmt.Errorf("duplicate topic mapping for tenant %q", mapping.Tenant)
+		}
+		if topics[mapping.Topic] {
+			return fmt.Errorf("topic %q is mapped to more than one tenant", mapping.Topic)
+		}
+		tenants[mapping.Tenant] = true
+		topics[mapping.Topic] = true
+	}
+
+	if c.StreamTopic != "" && (c.StreamTopic == c.Topic || c.StreamTopic == c.PriorityTopic || c.StreamTopic == c.DeadLetterTopic || topics[c.StreamTopic]) {
+		return fmt.Errorf("stream topic %q must differ from the log, priority, dead-letter and tenant topics", c.StreamTopic)
+	}
+	if c.ControlTopic != "" && (c.Consumes(c.ControlTopic) || c.ControlTopic == c.DeadLetterTopic || c.ControlTopic == c.StreamTopic) {
+		return fmt.Errorf("control topic %q must differ from the log, priority, dead-letter, stream and tenant topics", c.ControlTopic)
+	}
+	return nil
+}
+
+// Consumes reports whether the log processor consumes logs from the topic: the log topic, the priority topic
+// or a tenant topic
+func (c *KafkaConfig) Consumes(topic string) bool {
+	if topic == c.Topic || (c.PriorityTopic != "" && topic == c.PriorityTopic) {
+		return true
+	}
+	for _, mapping := range c.TenantTopics {
+		if topic == mapping.Topic {
+			return true
+		}
+	}
+	return false
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// TenantTopic returns the dedicated topic of a tenant
+func (c *KafkaConfig) TenantTopic(tenant string) (string, bool) {
+	for _, mapping := range c.TenantTopics {
+		if mapping.Tenant == tenant {
+			return mapping.Topic, true
+		}
 	}
-	return defaultValue
+	return "", false
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// PriorityGroupID returns the consumer group of the priority lane, kept apart from the main group
+// so rebalances and lag of bulk traffic don't hold up priority partitions
+func (c *KafkaConfig) PriorityGroupID() string {
+	return c.GroupID + constants.PriorityGroupIDSuffix
+}
+
+// Validate checks the enrichment endpoint definitions
+func (c *EnrichmentConfig) Validate() error {
+	names := make(map[string]bool, len(c.Endpoints))
+	for _, endpoint := range c.Endpoints {
+		if endpoint.Key == "" && endpoint.URL == "" {
+			return fmt.Errorf("invalid enrichment endpoint %q: expected name|key|url", endpoint.Name)
+		}
+		if endpoint.Name == "" {
+			return fmt.Errorf("invalid enrichment endpoint for %s: name is required", endpoint.URL)
+		}
+		if names[endpoint.Name] {
+			return fmt.Errorf("duplicate enrichment endpoint name %q", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+
+		if endpoint.Key != constants.EnrichmentKeyUserID && endpoint.Key != constants.EnrichmentKeyTraceID {
+			return fmt.Errorf("invalid enrichment endpoint %q: unsupported key %q (expected %s or %s)",
+				endpoint.Name, endpoint.Key, constants.EnrichmentKeyUserID, constants.EnrichmentKeyTraceID)
+		}
+
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid enrichment endpoint %q: url %q must be an absolute http(s) URL", endpoint.Name, endpoint.URL)
 		}
 	}
-	return defaultValue
+	return nil
 }
 
-func getEnvAsBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+// tableNamePattern restricts routed table names to plain SQL identifiers
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// parseRoutingRules parses routing rules in the form service|table or service|table|dsn.
+// An empty table routes to the default logs table of the given DSN.
+// Malformed entries are kept with only the raw value as service so Validate can report them.
+func parseRoutingRules(values []string) []RoutingRule {
+	var rules []RoutingRule
+	for _, value := range values {
+		if value == "" {
+			continue
 		}
+		parts := strings.SplitN(value, "|", 3)
+		if len(parts) < 2 {
+			rules = append(rules, RoutingRule{Service: value})
+			continue
+		}
+		rule := RoutingRule{
+			Service: strings.TrimSpace(parts[0]),
+			Table:   strings.TrimSpace(parts[1]),
+		}
+		if len(parts) == 3 {
+			rule.DSN = strings.TrimSpace(parts[2])
+		}
+		if rule.Table == "" {
+			rule.Table = constants.DefaultLogsTable
+		}
+		rules = append(rules, rule)
 	}
-	return defaultValue
+	return rules
 }
 
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+// parseTenantTopics parses tenant topic mappings in the form tenant|topic.
+// Malformed entries are kept with an empty topic so Validate can report them.
+func parseTenantTopics(values []string) []TenantTopic {
+	var mappings []TenantTopic
+	for _, value := range values {
+		if value == "" {
+			continue
 		}
+		tenant, topic, _ := strings.Cut(value, "|")
+		mappings = append(mappings, TenantTopic{
+			Tenant: strings.TrimSpace(tenant),
+			Topic:  strings.TrimSpace(topic),
+		})
 	}
-	return defaultValue
+	return mappings
 }
 
-func getEnvAsSlice(key string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
-		// Parse comma-separated values
-		values := strings.Split(value, ",")
-		// Trim whitespace from each value
-		for i, v := range values {
-			values[i] = strings.TrimSpace(v)
+// Validate checks the routing rules
+func (c *RoutingConfig) Validate() error {
+	services := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		if rule.Table == "" {
+			return fmt.Errorf("invalid routing rule %q: expected service|table or service|table|dsn", rule.Service)
+		}
+		if rule.Service == "" {
+			return fmt.Errorf("invalid routing rule for table %s: service is required", rule.Table)
+		}
+		if services[rule.Service] {
+			return fmt.Errorf("duplicate routing rule for service %q", rule.Service)
+		}
+		services[rule.Service] = true
+
+		if !tableNamePattern.MatchString(rule.Table) {
+			return fmt.Errorf("invalid routing rule %q: table %q must contain only letters, digits and underscores", rule.Service, rule.Table)
+		}
+		if rule.Table == constants.DefaultLogsTable && rule.DSN == "" {
+			return fmt.Errorf("invalid routing rule %q: routes to the default table", rule.Service)
 		}
-		return values
 	}
-	return defaultValue
+	return nil
+}
+
+// ValidateWithAlerts checks the rules and that none routes logs to a separate database while alert rules are
+// evaluated, since alert rules and reports only query the primary database
+func (c *RoutingConfig) ValidateWithAlerts(alert *AlertConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if !alert.CheckEnabled {
+		return nil
+	}
+	for _, rule := range c.Rules {
+		if rule.DSN != "" {
+			return fmt.Errorf("routing rule %q routes logs to a separate database, which alert rules can't query: route them to a table of the primary database or set %s=false", rule.Service, constants.EnvKeyAlertCheckEnabled)
+		}
+	}
+	return nil
+}
+
+// parseStatusWeights parses status distribution entries in the form status:weight.
+// Malformed entries are kept with a zero status so Validate can report them.
+func parseStatusWeights(values []string) []StatusWeight {
+	var weights []StatusWeight
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		status, weight, found := strings.Cut(value, ":")
+		statusCode, statusErr := strconv.Atoi(strings.TrimSpace(status))
+		weightValue, weightErr := strconv.Atoi(strings.TrimSpace(weight))
+		if !found || statusErr != nil || weightErr != nil {
+			weights = append(weights, StatusWeight{})
+			continue
+		}
+		weights = append(weights, StatusWeight{Status: statusCode, Weight: weightValue})
+	}
+	return weights
+}
+
+// Validate checks the generator status distribution
+func (c *GeneratorConfig) Validate() error {
+	if len(c.StatusWeights) == 0 {
+		return fmt.Errorf("status distribution is empty")
+	}
+	statuses := make(map[int]bool, len(c.StatusWeights))
+	for _, weight := range c.StatusWeights {
+		if weight.Status < constants.MinHTTPStatus || weight.Status > constants.MaxHTTPStatus {
+			return fmt.Errorf("invalid status weight: expected status:weight with a status between %d and %d",
+				constants.MinHTTPStatus, constants.MaxHTTPStatus)
+		}
+		if weight.Weight <= 0 {
+			return fmt.Errorf("invalid status weight for %d: weight must be positive", weight.Status)
+		}
+		if statuses[weight.Status] {
+			return fmt.Errorf("duplicate status weight for %d", weight.Status)
+		}
+		statuses[weight.Status] = true
+	}
+	return nil
+}
+
+// parseHeaderFilters parses header filters in the form key=value. Repeating a key allows several values.
+// Malformed entries are kept with only the raw value as key so Validate can report them.
+func parseHeaderFilters(values []string) []HeaderFilter {
+	var filters []HeaderFilter
+	index := make(map[string]int)
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		key, headerValue, found := strings.Cut(value, "=")
+		if !found {
+			filters = append(filters, HeaderFilter{Key: value})
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if i, ok := index[key]; ok {
+			filters[i].Values = append(filters[i].Values, strings.TrimSpace(headerValue))
+			continue
+		}
+		index[key] = len(filters)
+		filters = append(filters, HeaderFilter{Key: key, Values: []string{strings.TrimSpace(headerValue)}})
+	}
+	return filters
+}
+
+// Validate checks the pipeline header configuration
+func (c *PipelineConfig) Validate() error {
+	for _, header := range c.HeaderAttributes {
+		if header == "" {
+			return fmt.Errorf("header attribute names must not be empty")
+		}
+	}
+	for _, filter := range c.HeaderFilters {
+		if filter.Key == "" || len(filter.Values) == 0 {
+			return fmt.Errorf("invalid header filter %q: expected key=value", filter.Key)
+		}
+	}
+	for _, name := range c.RedactBuiltins {
+		if name != constants.RedactionAllBuiltins && !slices.Contains(constants.RedactionBuiltins, name) {
+			return fmt.Errorf("unknown built-in redaction rule %q: expected %s or %s", name,
+				strings.Join(constants.RedactionBuiltins, ", "), constants.RedactionAllBuiltins)
+		}
+	}
+	if c.IngestionDelayThreshold < 0 {
+		return fmt.Errorf("ingestion delay threshold must not be negative")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.WriteWorkers <= 0 {
+		return fmt.Errorf("write workers must be positive")
+	}
+	return nil
+}
+
+// LoadParsers reads the parser definitions from the configured YAML file.
+// No file configured means no parsers, so only native log JSON is accepted.
+func (c *PipelineConfig) LoadParsers() ([]ParserConfig, error) {
+	if c.ParsersFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.ParsersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parsers file: %w", err)
+	}
+	var file ParsersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode parsers file %s: %w", c.ParsersFile, err)
+	}
+
+	names := make(map[string]bool, len(file.Parsers))
+	for i := range file.Parsers {
+		parser := &file.Parsers[i]
+		if err := parser.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid parser %d in %s: %w", i+1, c.ParsersFile, err)
+		}
+		if names[parser.Name] {
+			return nil, fmt.Errorf("duplicate parser name %q in %s", parser.Name, c.ParsersFile)
+		}
+		names[parser.Name] = true
+	}
+	return file.Parsers, nil
+}
+
+// LoadFingerprintMasks reads the fingerprint masks from the configured YAML file.
+// No file configured means only the built-in masks apply.
+func (c *PipelineConfig) LoadFingerprintMasks() ([]FingerprintMask, error) {
+	if c.FingerprintFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.FingerprintFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprint file: %w", err)
+	}
+	var file FingerprintFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode fingerprint file %s: %w", c.FingerprintFile, err)
+	}
+	for i, mask := range file.Masks {
+		if mask.Name == "" || mask.Pattern == "" {
+			return nil, fmt.Errorf("invalid mask %d in %s: name and pattern are required", i+1, c.FingerprintFile)
+		}
+	}
+	return file.Masks, nil
+}
+
+// LoadRedactionRules reads the redaction rules from the configured YAML file.
+// No file configured means only the built-in rules selected apply.
+func (c *PipelineConfig) LoadRedactionRules() ([]RedactionRule, error) {
+	if c.RedactionFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.RedactionFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction file: %w", err)
+	}
+	var file RedactionFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode redaction file %s: %w", c.RedactionFile, err)
+	}
+	for i := range file.Rules {
+		if err := file.Rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %d in %s: %w", i+1, c.RedactionFile, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// Validate checks the redaction rule; patterns are compiled when the redactor is built
+func (r *RedactionRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Pattern == "" && len(r.Fields) == 0 {
+		return fmt.Errorf("rule %s: fields are required for rules without pattern", r.Name)
+	}
+	for _, field := range r.Fields {
+		if attribute, ok := strings.CutPrefix(field, constants.RedactionAttributePrefix); ok && attribute != "" {
+			continue
+		}
+		if !slices.Contains(constants.RedactionFields, field) {
+			return fmt.Errorf("rule %s: unknown field %q, expected one of %s or %s<key>", r.Name, field,
+				strings.Join(constants.RedactionFields, ", "), constants.RedactionAttributePrefix)
+		}
+	}
+	return nil
+}
+
+// Validate checks the parser definition; patterns are compiled when the parser is built
+func (c *ParserConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("parser name is required")
+	}
+	switch c.Type {
+	case constants.ParserTypeJSON, constants.ParserTypeLogfmt:
+	case constants.ParserTypeRegex, constants.ParserTypeGrok:
+		if c.Pattern == "" {
+			return fmt.Errorf("parser %s: pattern is required for %s parsers", c.Name, c.Type)
+		}
+	default:
+		return fmt.Errorf("parser %s: unknown type %q", c.Name, c.Type)
+	}
+	for key, values := range c.Headers {
+		if key == "" || len(values) == 0 {
+			return fmt.Errorf("parser %s: header conditions need a name and at least one value", c.Name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the tail paths, body capture, producer and sampling settings
+func (c *CollectorConfig) Validate() error {
+	for _, pattern := range c.TailPaths {
+		if pattern == "" {
+			return fmt.Errorf("empty tail path")
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tail path %q: %w", pattern, err)
+		}
+	}
+	if len(c.TailPaths) > 0 && c.TailCheckpoint == "" {
+		return fmt.Errorf("tail checkpoint file is required when tailing files")
+	}
+	if len(c.BodyServices) > 0 && (c.BodyMaxBytes <= len(constants.BodyTruncatedSuffix) || c.BodyMaxBytes > constants.MaxBodyExcerptLength) {
+		return fmt.Errorf("body capture max bytes must be between %d and %d", len(constants.BodyTruncatedSuffix)+1, constants.MaxBodyExcerptLength)
+	}
+	for _, key := range c.BodyRedactKeys {
+		if key == "" {
+			return fmt.Errorf("empty body redaction key")
+		}
+	}
+	if c.AsyncProducer && (c.BufferSize <= 0 || c.FlushMessages <= 0 || c.FlushFrequency <= 0) {
+		return fmt.Errorf("async producer buffer size, flush messages and flush frequency must be positive")
+	}
+	if !slices.Contains(constants.ProducerCompressions, c.Compression) {
+		return fmt.Errorf("producer compression must be one of %s", strings.Join(constants.ProducerCompressions, ", "))
+	}
+	if !slices.Contains(constants.PartitionKeys, c.PartitionKey) {
+		return fmt.Errorf("partition key must be one of %s", strings.Join(constants.PartitionKeys, ", "))
+	}
+	if !slices.Contains(constants.Partitioners, c.Partitioner) {
+		return fmt.Errorf("partitioner must be one of %s", strings.Join(constants.Partitioners, ", "))
+	}
+	if c.PartitionKey == constants.PartitionKeyRoundRobin && c.Partitioner != constants.PartitionerHash {
+		return fmt.Errorf("partitioner only applies to keyed messages, not with %s partition keys", constants.PartitionKeyRoundRobin)
+	}
+	if c.SpoolDir != "" && c.SpoolMaxBytes < constants.SpoolSegmentBytes {
+		return fmt.Errorf("spool max bytes must be at least %d", constants.SpoolSegmentBytes)
+	}
+
+	levels := make(map[string]bool, len(c.SampleRates))
+	for _, rate := range c.SampleRates {
+		if !slices.Contains(constants.SampleLevels, rate.Level) {
+			return fmt.Errorf("invalid sample rate %q: expected level=rate with a level among %s", rate.Level,
+				strings.Join(constants.SampleLevels, ", "))
+		}
+		if rate.Rate < 0 || rate.Rate > 1 {
+			return fmt.Errorf("invalid sample rate for %s: rate must be between 0 and 1", rate.Level)
+		}
+		if levels[rate.Level] {
+			return fmt.Errorf("duplicate sample rate for %s", rate.Level)
+		}
+		levels[rate.Level] = true
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	services := make(map[string]bool, len(c.ServiceRateLimits))
+	for _, limit := range c.ServiceRateLimits {
+		if limit.Service == "" || limit.Limit < 0 {
+			return fmt.Errorf("invalid service rate limit %q: expected service=limit with a non-negative limit", limit.Service)
+		}
+		if services[limit.Service] {
+			return fmt.Errorf("duplicate rate limit for service %s", limit.Service)
+		}
+		services[limit.Service] = true
+	}
+	return nil
+}
+
+// parseSampleRates parses sample rates in the form level=rate, e.g. DEBUG=0.1. Levels are case-insensitive.
+// Malformed entries are kept with the raw value as level and a negative rate so Validate can report them.
+func parseSampleRates(values []string) []SampleRate {
+	var rates []SampleRate
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		level, rate, found := strings.Cut(value, "=")
+		rateValue, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if !found || err != nil {
+			rates = append(rates, SampleRate{Level: value, Rate: -1})
+			continue
+		}
+		rates = append(rates, SampleRate{Level: strings.ToUpper(strings.TrimSpace(level)), Rate: rateValue})
+	}
+	return rates
+}
+
+// parseServiceRateLimits parses service rate limits in the form service=limit.
+// Malformed entries are kept with the raw value as service and a negative limit so Validate can report them.
+func parseServiceRateLimits(values []string) []ServiceRateLimit {
+	var limits []ServiceRateLimit
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		service, limit, found := strings.Cut(value, "=")
+		limitValue, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !found || err != nil {
+			limits = append(limits, ServiceRateLimit{Service: value, Limit: -1})
+			continue
+		}
+		limits = append(limits, ServiceRateLimit{Service: strings.TrimSpace(service), Limit: limitValue})
+	}
+	return limits
+}
+
+// parseGroupRoles parses group mappings in the form groupDN|role separated by semicolons,
+// since group DNs contain commas. Malformed entries are kept without role so Validate can report them.
+func parseGroupRoles(value string) []GroupRole {
+	var mappings []GroupRole
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "|")
+		if index < 0 {
+			mappings = append(mappings, GroupRole{Group: entry})
+			continue
+		}
+		mappings = append(mappings, GroupRole{
+			Group: strings.TrimSpace(entry[:index]),
+			Role:  strings.ToLower(strings.TrimSpace(entry[index+1:])),
+		})
+	}
+	return mappings
+}
+
+// parseGroupTenants parses group mappings in the form groupDN|tenant separated by semicolons, like group roles.
+// Malformed entries are kept without tenant so Validate can report them.
+func parseGroupTenants(value string) []GroupTenant {
+	var mappings []GroupTenant
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "|")
+		if index < 0 {
+			mappings = append(mappings, GroupTenant{Group: entry})
+			continue
+		}
+		mappings = append(mappings, GroupTenant{
+			Group:  strings.TrimSpace(entry[:index]),
+			Tenant: strings.TrimSpace(entry[index+1:]),
+		})
+	}
+	return mappings
+}
+
+// parseServiceTokens parses service tokens in the form name|sha256|scope+scope, optionally followed by |tenant.
+// Malformed entries are kept without hash so Validate can report them.
+func parseServiceTokens(values []string) []ServiceToken {
+	var tokens []ServiceToken
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		parts := strings.Split(value, "|")
+		if len(parts) != 3 && len(parts) != 4 {
+			tokens = append(tokens, ServiceToken{Name: strings.TrimSpace(parts[0])})
+			continue
+		}
+		token := ServiceToken{
+			Name: strings.TrimSpace(parts[0]),
+			Hash: strings.ToLower(strings.TrimSpace(parts[1])),
+		}
+		for _, scope := range strings.Split(parts[2], "+") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				token.Scopes = append(token.Scopes, scope)
+			}
+		}
+		if len(parts) == 4 {
+			token.Tenant = strings.TrimSpace(parts[3])
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// Validate checks the authentication provider, service token and scope limit settings
+func (c *AuthConfig) Validate() error {
+	names := make(map[string]bool, len(c.ServiceTokens))
+	for _, token := range c.ServiceTokens {
+		hash, err := hex.DecodeString(token.Hash)
+		if token.Name == "" || err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("invalid service token %q: expected name|sha256 hex|scope+scope[|tenant]", token.Name)
+		}
+		if len(token.Tenant) > constants.MaxTenantLength {
+			return fmt.Errorf("tenant of service token %q must be at most %d characters", token.Name, constants.MaxTenantLength)
+		}
+		if names[token.Name] {
+			return fmt.Errorf("duplicate service token %q", token.Name)
+		}
+		names[token.Name] = true
+		if len(token.Scopes) == 0 {
+			return fmt.Errorf("service token %q has no scopes", token.Name)
+		}
+		for _, scope := range token.Scopes {
+			if scope != constants.ScopeLogsTail && scope != constants.ScopeLogsExport {
+				return fmt.Errorf("invalid scope %q of service token %q: expected %s or %s", scope, token.Name, constants.ScopeLogsTail, constants.ScopeLogsExport)
+			}
+		}
+	}
+	for _, limits := range []ScopeLimits{c.TailLimits, c.ExportLimits} {
+		if limits.RequestsPerMinute < 0 || limits.RowsPerHour < 0 {
+			return fmt.Errorf("scope request and volume caps must not be negative")
+		}
+	}
+
+	switch c.Provider {
+	case constants.AuthProviderNone:
+		return nil
+	case constants.AuthProviderLDAP:
+		return c.LDAP.Validate()
+	default:
+		return fmt.Errorf("unsupported auth provider %q: expected %q", c.Provider, constants.AuthProviderLDAP)
+	}
+}
+
+// Validate checks the LDAP connection and role mapping settings
+func (c *LDAPConfig) Validate() error {
+	parsed, err := url.Parse(c.URL)
+	if err != nil || (parsed.Scheme != "ldap" && parsed.Scheme != "ldaps") || parsed.Host == "" {
+		return fmt.Errorf("invalid LDAP URL %q: expected ldap://host[:port] or ldaps://host[:port]", c.URL)
+	}
+	// Binding sends the user's password, which must not cross the network in cleartext unless explicitly allowed
+	if parsed.Scheme == "ldap" && !c.StartTLS && !c.Insecure {
+		return fmt.Errorf("LDAP over ldap:// without StartTLS sends passwords in cleartext: use ldaps://, enable StartTLS or allow it as insecure")
+	}
+	if !strings.Contains(c.UserDNTemplate, constants.LDAPUserPlaceholder) {
+		return fmt.Errorf("LDAP user DN template must contain %s", constants.LDAPUserPlaceholder)
+	}
+	if c.BaseDN == "" || c.UserAttribute == "" || c.GroupAttribute == "" {
+		return fmt.Errorf("LDAP base DN, user attribute and group attribute are required")
+	}
+	if c.DefaultRole != "" && !isRole(c.DefaultRole) {
+		return fmt.Errorf("invalid LDAP default role %q: expected %s or %s", c.DefaultRole, constants.RoleViewer, constants.RoleAdmin)
+	}
+	for _, mapping := range c.GroupRoles {
+		if mapping.Group == "" || !isRole(mapping.Role) {
+			return fmt.Errorf("invalid LDAP group role %q: expected groupDN|%s or groupDN|%s", mapping.Group, constants.RoleViewer, constants.RoleAdmin)
+		}
+	}
+	if len(c.GroupRoles) == 0 && c.DefaultRole == "" {
+		return fmt.Errorf("LDAP group roles or a default role are required")
+	}
+	for _, mapping := range c.GroupTenants {
+		if mapping.Group == "" || mapping.Tenant == "" || len(mapping.Tenant) > constants.MaxTenantLength {
+			return fmt.Errorf("invalid LDAP group tenant %q: expected groupDN|tenant with a tenant of at most %d characters", mapping.Group, constants.MaxTenantLength)
+		}
+	}
+	return nil
+}
+
+// isRole reports whether role is a known role
+func isRole(role string) bool {
+	return role == constants.RoleViewer || role == constants.RoleAdmin
 }
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+)
+
+func TestLDAPConfigValidate(t *testing.T) {
+	valid := func() LDAPConfig {
+		return LDAPConfig{
+			URL:            "ldaps://ldap.example.com:636",
+			UserDNTemplate: "uid={username},ou=people,dc=example,dc=com",
+			BaseDN:         "dc=example,dc=com",
+			UserAttribute:  constants.DefaultLDAPUserAttribute,
+			GroupAttribute: constants.DefaultLDAPGroupAttribute,
+			GroupRoles:     []GroupRole{{Group: "cn=ops,dc=example,dc=com", Role: constants.RoleAdmin}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *LDAPConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *LDAPConfig) {}},
+		{name: "ldap with StartTLS", modify: func(c *LDAPConfig) { c.URL, c.StartTLS = "ldap://ldap.example.com", true }},
+		{name: "ldap allowed as insecure", modify: func(c *LDAPConfig) { c.URL, c.Insecure = "ldap://ldap.example.com", true }},
+		{name: "cleartext ldap", modify: func(c *LDAPConfig) { c.URL = "ldap://ldap.example.com" }, wantErr: "cleartext"},
+		{name: "missing URL", modify: func(c *LDAPConfig) { c.URL = "" }, wantErr: "invalid LDAP URL"},
+		{name: "other scheme", modify: func(c *LDAPConfig) { c.URL = "https://ldap.example.com" }, wantErr: "invalid LDAP URL"},
+		{name: "missing host", modify: func(c *LDAPConfig) { c.URL = "ldaps://" }, wantErr: "invalid LDAP URL"},
+		{name: "template without placeholder", modify: func(c *LDAPConfig) { c.UserDNTemplate = "uid=admin" }, wantErr: constants.LDAPUserPlaceholder},
+		{name: "missing base DN", modify: func(c *LDAPConfig) { c.BaseDN = "" }, wantErr: "base DN"},
+		{name: "unknown default role", modify: func(c *LDAPConfig) { c.DefaultRole = "owner" }, wantErr: "default role"},
+		{name: "default role only", modify: func(c *LDAPConfig) { c.GroupRoles, c.DefaultRole = nil, constants.RoleViewer }},
+		{name: "no role", modify: func(c *LDAPConfig) { c.GroupRoles = nil }, wantErr: "group roles or a default role"},
+		{name: "group without role", modify: func(c *LDAPConfig) { c.GroupRoles = []GroupRole{{Group: "cn=ops"}} }, wantErr: "group role"},
+		{name: "role without group", modify: func(c *LDAPConfig) { c.GroupRoles = []GroupRole{{Role: constants.RoleAdmin}} }, wantErr: "group role"},
+		{
+			name:   "group tenant",
+			modify: func(c *LDAPConfig) { c.GroupTenants = []GroupTenant{{Group: "cn=acme", Tenant: "acme"}} },
+		},
+		{
+			name:    "group without tenant",
+			modify:  func(c *LDAPConfig) { c.GroupTenants = []GroupTenant{{Group: "cn=acme"}} },
+			wantErr: "group tenant",
+		},
+		{
+			name: "tenant too long",
+			modify: func(c *LDAPConfig) {
+				c.GroupTenants = []GroupTenant{{Group: "cn=acme", Tenant: strings.Repeat("a", constants.MaxTenantLength+1)}}
+			},
+			wantErr: "group tenant",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseGroupMappings(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantRoles   []GroupRole
+		wantTenants []GroupTenant
+	}{
+		{name: "empty", value: " ; "},
+		{
+			name:        "groups with pipes in their DN",
+			value:       "cn=ops|x,dc=example | Admin ; cn=dev|viewer",
+			wantRoles:   []GroupRole{{Group: "cn=ops|x,dc=example", Role: "admin"}, {Group: "cn=dev", Role: "viewer"}},
+			wantTenants: []GroupTenant{{Group: "cn=ops|x,dc=example", Tenant: "Admin"}, {Group: "cn=dev", Tenant: "viewer"}},
+		},
+		{
+			name:        "malformed entries kept for validation",
+			value:       "cn=ops",
+			wantRoles:   []GroupRole{{Group: "cn=ops"}},
+			wantTenants: []GroupTenant{{Group: "cn=ops"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGroupRoles(tt.value); !reflect.DeepEqual(got, tt.wantRoles) {
+				t.Errorf("parseGroupRoles(%q) = %+v, want %+v", tt.value, got, tt.wantRoles)
+			}
+			if got := parseGroupTenants(tt.value); !reflect.DeepEqual(got, tt.wantTenants) {
+				t.Errorf("parseGroupTenants(%q) = %+v, want %+v", tt.value, got, tt.wantTenants)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is the effective value of a setting and where it came from: env, file or default
+type Setting struct {
+	Key    string `json:"key"` // environment variable
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Secret reports whether the setting holds a password, secret or token, whose value isn't printed
+func (s Setting) Secret() bool {
+	for _, part := range constants.ConfigSecretKeyParts {
+		if strings.Contains(s.Key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSetting is a setting of the config file, with the line it is on for error messages
+type fileSetting struct {
+	value string
+	line  int
+}
+
+// loader reads settings from the environment, then the config file, recording the effective value of each
+// and every malformed one
+type loader struct {
+	path     string
+	file     map[string]fileSetting // by environment variable
+	settings map[string]Setting
+	errs     []error
+}
+
+// newLoader reads the YAML config file at path, a mapping of environment variables, case-insensitive, to values
+// or lists of values. Without a path only the environment is read.
+func newLoader(path string) (*loader, error) {
+	l := &loader{path: path, file: make(map[string]fileSetting), settings: make(map[string]Setting)}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return l, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: expected a mapping of settings to values", path)
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		key := strings.ToUpper(keyNode.Value)
+		if previous, ok := l.file[key]; ok {
+			errs = append(errs, fmt.Errorf("%s:%d: %s is already set on line %d", path, keyNode.Line, key, previous.line))
+			continue
+		}
+		value, err := settingValue(valueNode)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s %w", path, valueNode.Line, key, err))
+			continue
+		}
+		l.file[key] = fileSetting{value: value, line: keyNode.Line}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return l, nil
+}
+
+// settingValue returns the value of a setting of the config file, joining lists with commas like the
+// environment variables they stand for
+func settingValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", nil
+		}
+		return node.Value, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("must be a value or a list of values")
+			}
+			values = append(values, item.Value)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("must be a value or a list of values")
+	}
+}
+
+// lookup returns the value of a setting and its source, the environment taking precedence over the config file.
+// Empty values count as unset.
+func (l *loader) lookup(key string) (string, string) {
+	if value := os.Getenv(key); value != "" {
+		return value, constants.ConfigSourceEnv
+	}
+	if setting, ok := l.file[key]; ok && setting.value != "" {
+		return setting.value, constants.ConfigSourceFile
+	}
+	return "", constants.ConfigSourceDefault
+}
+
+// record keeps the effective value of a setting
+func (l *loader) record(key, value, source string) {
+	l.settings[key] = Setting{Key: key, Value: value, Source: source}
+}
+
+// invalid reports a malformed value, which is replaced with the default so loading can go on to report others
+func (l *loader) invalid(key, value, source, expected string) {
+	where := "environment"
+	if source == constants.ConfigSourceFile {
+		where = fmt.Sprintf("%s:%d", l.path, l.file[key].line)
+	}
+	l.errs = append(l.errs, fmt.Errorf("%s=%q (%s): %s", key, value, where, expected))
+}
+
+// finish reports the malformed values and the settings of the config file that aren't known, suggesting the
+// known setting closest to each
+func (l *loader) finish() error {
+	keys := make([]string, 0, len(l.file))
+	for key := range l.file {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int { return l.file[a].line - l.file[b].line })
+	for _, key := range keys {
+		if _, ok := l.settings[key]; ok {
+			continue
+		}
+		err := fmt.Errorf("%s:%d: unknown setting %s", l.path, l.file[key].line, key)
+		if suggestion := l.suggest(key); suggestion != "" {
+			err = fmt.Errorf("%w, did you mean %s?", err, suggestion)
+		}
+		l.errs = append(l.errs, err)
+	}
+	if len(l.errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(l.errs...))
+	}
+	return nil
+}
+
+// suggest returns the known setting closest to an unknown one, empty when none is close
+func (l *loader) suggest(key string) string {
+	best, bestDistance := "", constants.MaxConfigSuggestionDistance+1
+	for known := range l.settings {
+		if distance := editDistance(key, known); distance < bestDistance || (distance == bestDistance && known < best) {
+			best, bestDistance = known, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// effective returns the effective settings, sorted by environment variable
+func (l *loader) effective() []Setting {
+	settings := make([]Setting, 0, len(l.settings))
+	for _, setting := range l.settings {
+		settings = append(settings, setting)
+	}
+	slices.SortFunc(settings, func(a, b Setting) int { return strings.Compare(a.Key, b.Key) })
+	return settings
+}
+
+func (l *loader) getEnv(key, defaultValue string) string {
+	value, source := l.lookup(key)
+	if value == "" {
+		l.record(key, defaultValue, source)
+		return defaultValue
+	}
+	l.record(key, value, source)
+	return value
+}
+
+func (l *loader) getEnvAsInt(key string, defaultValue int) int {
+	value, source := l.lookup(key)
+	l.record(key, strconv.Itoa(defaultValue), constants.ConfigSourceDefault)
+	if value == "" {
+		return defaultValue
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		l.invalid(key, value, source, "expected an integer")
+		return defaultValue
+	}
+	l.record(key, value, source)
+	return intValue
+}
+
+func (l *loader) getEnvAsBool(key string, defaultValue bool) bool {
+	value, source := l.lookup(key)
+	l.record(key, strconv.FormatBool(defaultValue), constants.ConfigSourceDefault)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(key, value, source, "expected true or false")
+		return defaultValue
+	}
+	l.record(key, value, source)
+	return boolValue
+}
+
+func (l *loader) getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value, source := l.lookup(key)
+	l.record(key, defaultValue.String(), constants.ConfigSourceDefault)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.invalid(key, value, source, "expected a duration such as 500ms, 30s or 1h30m")
+		return defaultValue
+	}
+	l.record(key, value, source)
+	return duration
+}
+
+// getEnvAsPositiveDuration is like getEnvAsDuration but rejects zero and negative values
+func (l *loader) getEnvAsPositiveDuration(key string, defaultValue time.Duration) time.Duration {
+	errs := len(l.errs)
+	duration := l.getEnvAsDuration(key, defaultValue)
+	if duration > 0 {
+		return duration
+	}
+	if len(l.errs) == errs {
+		value, source := l.lookup(key)
+		l.invalid(key, value, source, "expected a positive duration such as 500ms, 30s or 1h30m")
+		l.record(key, defaultValue.String(), constants.ConfigSourceDefault)
+	}
+	return defaultValue
+}
+
+// getEnvAsWeekday parses an English weekday name such as monday, ignoring case
+func (l *loader) getEnvAsWeekday(key string, defaultValue time.Weekday) time.Weekday {
+	value, source := l.lookup(key)
+	l.record(key, strings.ToLower(defaultValue.String()), constants.ConfigSourceDefault)
+	if value == "" {
+		return defaultValue
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(value, day.String()) {
+			l.record(key, value, source)
+			return day
+		}
+	}
+	l.invalid(key, value, source, "expected a weekday such as monday")
+	return defaultValue
+}
+
+// getEnvAsLevel parses a slog level name such as warn, ignoring case
+func (l *loader) getEnvAsLevel(key string, defaultValue slog.Level) slog.Level {
+	value, source := l.lookup(key)
+	l.record(key, strings.ToLower(defaultValue.String()), constants.ConfigSourceDefault)
+	if value == "" {
+		return defaultValue
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		l.invalid(key, value, source, "expected debug, info, warn or error")
+		return defaultValue
+	}
+	l.record(key, value, source)
+	return level
+}
+
+func (l *loader) getEnvAsSlice(key string, defaultValue []string) []string {
+	value, source := l.lookup(key)
+	if value == "" {
+		l.record(key, strings.Join(defaultValue, ","), source)
+		return defaultValue
+	}
+	l.record(key, value, source)
+	// Parse comma-separated values
+	values := strings.Split(value, ",")
+	// Trim whitespace from each value
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// WriteEffective writes the effective settings as a YAML config file, commented with the source of each
+// setting, with the values of secrets masked
+func (c *Config) WriteEffective(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, setting := range c.Settings {
+		value := setting.Value
+		if setting.Secret() && value != "" {
+			value = constants.RedactedValue
+		}
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: setting.Key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, LineComment: setting.Source})
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("failed to write effective configuration: %w", err)
+	}
+	return encoder.Close()
+}
+
+// configSection is a section of the configuration checked by Validate
+type configSection struct {
+	name     string
+	validate func() error
+}
+
+// Validate checks every section of the configuration, reporting the invalid ones together
+func (c *Config) Validate() error {
+	sections := []configSection{
+		{"server", c.Server.Validate},
+		{"database", c.Database.Validate},
+		{"kafka", c.Kafka.Validate},
+		{"alert", c.Alert.Validate},
+		{"routing", func() error { return c.Routing.ValidateWithAlerts(&c.Alert) }},
+		{"generator", c.Generator.Validate},
+		{"pipeline", c.Pipeline.Validate},
+		{"collector", c.Collector.Validate},
+		{"auth", c.Auth.Validate},
+		{"notification", c.Notification.Validate},
+		{"migration", c.Migration.Validate},
+		{"retention", c.Retention.Validate},
+		{"dashboard", c.Dashboard.Validate},
+		{"self-ingestion", c.SelfIngestion.Validate},
+		{"log store", func() error { return c.LogStore.Validate(&c.Routing) }},
+	}
+	if c.Enrichment.Enabled {
+		sections = append(sections, configSection{"enrichment", c.Enrichment.Validate})
+	}
+
+	var errs []error
+	for _, section := range sections {
+		if err := section.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", section.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
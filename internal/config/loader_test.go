@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+)
+
+// writeConfigFile writes a config file to a temporary directory and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestNewLoader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "empty", content: "", want: map[string]string{}},
+		{
+			name:    "values by upper-cased key",
+			content: "kafka_brokers: a:9092\nLOG_LEVEL: warn\nPORT: 8080\n",
+			want:    map[string]string{"KAFKA_BROKERS": "a:9092", "LOG_LEVEL": "warn", "PORT": "8080"},
+		},
+		{
+			name:    "lists joined with commas",
+			content: "KAFKA_BROKERS:\n  - a:9092\n  - b:9092\n",
+			want:    map[string]string{"KAFKA_BROKERS": "a:9092,b:9092"},
+		},
+		{name: "null is unset", content: "LDAP_URL: ~\n", want: map[string]string{"LDAP_URL": ""}},
+		{name: "not a mapping", content: "- PORT\n", wantErr: "expected a mapping"},
+		{name: "nested mapping", content: "DB:\n  HOST: x\n", wantErr: ":2: DB must be a value or a list of values"},
+		{name: "nested list", content: "KAFKA_BROKERS:\n  - [a, b]\n", wantErr: "KAFKA_BROKERS must be a value"},
+		{name: "key set twice", content: "PORT: 1\nport: 2\n", wantErr: ":2: PORT is already set on line 1"},
+		{name: "invalid YAML", content: "PORT: [1\n", wantErr: "invalid config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newLoader(writeConfigFile(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newLoader() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newLoader() error: %v", err)
+			}
+			got := make(map[string]string, len(l.file))
+			for key, setting := range l.file {
+				got[key] = setting.value
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("newLoader() settings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderSources(t *testing.T) {
+	l, err := newLoader(writeConfigFile(t, "KAFKA_BROKERS: file:9092\nLOG_LEVEL: warn\nLDAP_URL: ''\n"))
+	if err != nil {
+		t.Fatalf("newLoader() error: %v", err)
+	}
+	t.Setenv("KAFKA_BROKERS", "env:9092")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LDAP_URL", "")
+	t.Setenv("PORT", "")
+
+	tests := []struct {
+		name       string
+		key        string
+		get        func(key string) any
+		want       string
+		wantSource string
+	}{
+		{
+			name:       "environment over file",
+			key:        "KAFKA_BROKERS",
+			get:        func(key string) any { return l.getEnvAsSlice(key, nil) },
+			want:       "[env:9092]",
+			wantSource: constants.ConfigSourceEnv,
+		},
+		{
+			name:       "file over default",
+			key:        "LOG_LEVEL",
+			get:        func(key string) any { return l.getEnvAsLevel(key, slog.LevelInfo) },
+			want:       "WARN",
+			wantSource: constants.ConfigSourceFile,
+		},
+		{
+			name:       "empty in file is unset",
+			key:        "LDAP_URL",
+			get:        func(key string) any { return l.getEnv(key, "ldaps://default") },
+			want:       "ldaps://default",
+			wantSource: constants.ConfigSourceDefault,
+		},
+		{
+			name:       "default",
+			key:        "PORT",
+			get:        func(key string) any { return l.getEnvAsDuration(key, time.Second) },
+			want:       "1s",
+			wantSource: constants.ConfigSourceDefault,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(tt.get(tt.key)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if source := l.settings[tt.key].Source; source != tt.wantSource {
+				t.Errorf("source %q, want %q", source, tt.wantSource)
+			}
+		})
+	}
+	if err := l.finish(); err != nil {
+		t.Errorf("finish() error: %v", err)
+	}
+}
+
+func TestLoaderFinish(t *testing.T) {
+	for _, key := range []string{"KAFKA_BROKERS", "LOG_LEVEL", "BATCH_SIZE", "KAFKA_BROKER", "UNRELATED"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("BATCH_SIZE", "many")
+	path := writeConfigFile(t, "KAFKA_BROKER: a:9092\nLOG_LEVEL: loud\nUNRELATED: x\n")
+	l, err := newLoader(path)
+	if err != nil {
+		t.Fatalf("newLoader() error: %v", err)
+	}
+	l.getEnvAsSlice("KAFKA_BROKERS", nil)
+	if level := l.getEnvAsLevel("LOG_LEVEL", slog.LevelInfo); level != slog.LevelInfo {
+		t.Errorf("malformed level loaded as %v, want the default", level)
+	}
+	l.getEnvAsInt("BATCH_SIZE", 100)
+
+	err = l.finish()
+	if err == nil {
+		t.Fatal("finish() succeeded, want the malformed and unknown settings reported")
+	}
+	for _, want := range []string{
+		`LOG_LEVEL="loud" (` + path + `:2): expected debug, info, warn or error`,
+		`BATCH_SIZE="many" (environment): expected an integer`,
+		path + ":1: unknown setting KAFKA_BROKER, did you mean KAFKA_BROKERS?",
+		path + ":3: unknown setting UNRELATED\n",
+	} {
+		if !strings.Contains(err.Error()+"\n", want) {
+			t.Errorf("finish() error %q doesn't report %q", err, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "PORT", b: "", want: 4},
+		{a: "KAFKA_BROKER", b: "KAFKA_BROKERS", want: 1},
+		{a: "LOG_LEVLE", b: "LOG_LEVEL", want: 2},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := editDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader reloads the configuration on SIGHUP, and when the config file changes if a watch interval is set,
+// handing it to the services to apply the reloadable settings without a restart. A configuration that fails to
+// load or validate is reported and the current one kept.
+type Reloader struct {
+	mu       sync.Mutex
+	current  *Config
+	modTime  time.Time // of the config file when it was last loaded
+	apply    []func(cfg *Config)
+	logger   *slog.Logger
+	interval time.Duration
+}
+
+// NewReloader creates a reloader of the given configuration
+func NewReloader(cfg *Config, logger *slog.Logger) *Reloader {
+	r := &Reloader{current: cfg, logger: logger, interval: cfg.WatchInterval}
+	r.modTime = r.fileModTime()
+	return r
+}
+
+// OnReload registers a function applying the reloadable settings of a reloaded configuration. Functions are
+// called in the order registered, one reload at a time.
+func (r *Reloader) OnReload(apply func(cfg *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply = append(r.apply, apply)
+}
+
+// Start reloads the configuration on SIGHUP and on changes of the config file until the context is done
+func (r *Reloader) Start(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	var watch <-chan time.Time
+	if r.interval > 0 && r.current.File != "" {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		watch = ticker.C
+		r.logger.Info("Watching config file for changes", "file", r.current.File, "interval", r.interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			r.logger.Info("Reload signal received")
+			r.Reload()
+		case <-watch:
+			if !r.fileModTime().Equal(r.loadedModTime()) {
+				r.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the configuration again and applies its reloadable settings. Changes of other settings are
+// reported as taking effect on restart.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A file that fails to load is only read again once it changes once more, rather than on every check
+	r.modTime = r.fileModTime()
+	next, err := Load(r.current.File)
+	if err == nil {
+		err = next.validateReloadable()
+	}
+	if err != nil {
+		r.logger.Error("Failed to reload configuration, keeping the current one", "error", err)
+		return err
+	}
+
+	reloaded, restart := changedSettings(r.current, next)
+	if len(restart) > 0 {
+		r.logger.Warn("Configuration changes take effect on restart", "settings", restart)
+	}
+	if len(reloaded) == 0 {
+		r.logger.Info("Configuration reloaded, no reloadable setting changed")
+		r.current = next
+		return nil
+	}
+	for _, apply := range r.apply {
+		apply(next)
+	}
+	r.current = next
+	r.logger.Info("Configuration reloaded", "settings", reloaded)
+	return nil
+}
+
+// loadedModTime returns the modification time of the config file when it was last loaded
+func (r *Reloader) loadedModTime() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.modTime
+}
+
+// fileModTime returns the modification time of the config file, zero without one or when it can't be read
+func (r *Reloader) fileModTime() time.Time {
+	if r.current.File == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(r.current.File)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// validateReloadable checks the sections holding reloadable settings
+func (c *Config) validateReloadable() error {
+	sections := []configSection{
+		{"alert", c.Alert.Validate},
+		{"pipeline", c.Pipeline.Validate},
+		{"collector", c.Collector.Validate},
+	}
+	var errs []error
+	for _, section := range sections {
+		if err := section.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", section.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// changedSettings returns the settings whose effective value differs between two configurations, split into the
+// reloadable ones and those that take effect on restart
+func changedSettings(current, next *Config) (reloaded, restart []string) {
+	values := make(map[string]string, len(current.Settings))
+	for _, setting := range current.Settings {
+		values[setting.Key] = setting.Value
+	}
+	for _, setting := range next.Settings {
+		if value, ok := values[setting.Key]; ok && value == setting.Value {
+			continue
+		}
+		if slices.Contains(constants.ReloadableSettings, setting.Key) {
+			reloaded = append(reloaded, setting.Key)
+		} else {
+			restart = append(restart, setting.Key)
+		}
+	}
+	return reloaded, restart
+}
@@ -0,0 +1,122 @@
+package constants
+
+import "time"
+
+// Alert Configuration Constants
+const (
+	// Alert Checker Settings
+	DefaultAlertCheckEnabled  = true
+	DefaultAlertCheckInterval = 30 // seconds
+
+	// Catch-up Evaluation Settings
+	DefaultAlertCatchUpEnabled     = true
+	DefaultAlertCatchUpMaxLookback = 6 * time.Hour
+
+	// Canary Evaluation Settings
+	DefaultAlertCanaryPeriod       = 0 // rule changes take effect immediately
+	MaxAlertCanaryPeriod           = 7 * 24 * time.Hour
+	AlertCanaryRecentDisagreements = 20
+
+	// Alert Rule Types
+	AlertRuleThreshold = "threshold" // fires when the condition's value reaches the threshold
+	AlertRuleAnomaly   = "anomaly"   // fires when the metric deviates from its value in the same window of previous days or weeks
+
+	// Anomaly Metrics
+	AnomalyMetricLogVolume = "log_volume"
+	AnomalyMetricErrorRate = "error_rate" // percentage of ERROR and FATAL logs
+
+	// Anomaly Seasonality, the period between a window and the baseline windows it is compared with
+	AnomalySeasonalityDay  = "day"
+	AnomalySeasonalityWeek = "week"
+
+	// Anomaly Deviation Measures, in which the threshold of anomaly rules is expressed
+	AnomalyDeviationStdDev  = "stddev"  // standard deviations from the baseline mean
+	AnomalyDeviationPercent = "percent" // percentage of the baseline mean
+
+	// Anomaly Directions
+	AnomalyDirectionUp   = "up"
+	AnomalyDirectionDown = "down"
+	AnomalyDirectionBoth = "both"
+
+	// Anomaly Baselines
+	DefaultAnomalyBaselinePeriods = 7
+	MinAnomalyBaselinePeriods     = 3 // fewer baseline windows with data and the rule isn't evaluated
+	MaxAnomalyBaselinePeriods     = 14
+
+	// Rule State Settings
+	AlertRuleStateSaveAttempts = 5 // saves of a stateful evaluator's state before giving up on concurrent updates
+
+	// Cooldown and Re-notification Limits, in minutes
+	MaxAlertCooldownMinutes = 7 * 24 * 60
+	MaxAlertRenotifyMinutes = 7 * 24 * 60
+
+	// Retention Settings
+	DefaultAlertRetentionAge               = 0 // resolved alerts are kept forever
+	DefaultAlertRetentionInterval          = 1 * time.Hour
+	DefaultAlertRetentionBatchSize         = 1000
+	DefaultAlertRetentionOptimizeThreshold = 10000 // deleted alerts in a run after which the table is optimized
+
+	// Self-monitoring Settings
+	DefaultAlertMetaAlertIntervals = 3 // check intervals without a successful check before the meta-alert fires
+	AlertMetaAlertRuleName         = "Alert checker"
+	AlertMetaAlertSeverity         = "critical"
+
+	// Alert Checker Health Statuses
+	AlertCheckerStarting = "starting" // no check has completed yet
+	AlertCheckerHealthy  = "healthy"
+	AlertCheckerDegraded = "degraded" // the last check failed or some rules failed to evaluate
+	AlertCheckerFailing  = "failing"  // the meta-alert is firing
+	AlertCheckerDisabled = "disabled" // alert rules aren't evaluated
+	AlertCheckerStandby  = "standby"  // another API server runs the checker
+
+	// Alert Statuses
+	AlertStatusActive       = "active"
+	AlertStatusResolved     = "resolved"
+	AlertStatusAcknowledged = "acknowledged"
+
+	// Alert Event Types, pushed over /ws/alerts
+	AlertEventCreated      = "created"
+	AlertEventResolved     = "resolved"
+	AlertEventAcknowledged = "acknowledged"
+	AlertEventHeartbeat    = "heartbeat" // sent to idle connections, without an alert
+
+	// Alert Severities
+	AlertSeverityLow      = "low"
+	AlertSeverityMedium   = "medium"
+	AlertSeverityHigh     = "high"
+	AlertSeverityCritical = "critical"
+
+	// Alert Rule Limits
+	MaxAlertRuleNameLength  = 255
+	MaxAlertConditionLength = 4096
+	MinAlertTimeWindow      = 1           // minutes
+	MaxAlertTimeWindow      = 7 * 24 * 60 // minutes
+
+	// Alert Rule Labels
+	MaxAlertRuleLabels      = 20
+	MaxAlertRuleLabelLength = 64 // of label names and values
+
+	// Alert Listing
+	AlertIncludeRule              = "rule"       // include value loading each alert's rule
+	AlertViewSummary              = "summary"    // view value selecting the summary projection
+	AlertLabelFilterPrefix        = "label."     // label.<name>=<value> lists alerts of rules with the label
+	AlertExcludeLabelFilterPrefix = "not_label." // not_label.<name>=<value> lists alerts of rules without it
+	MaxAlertLabelFilters          = 10           // label and not_label filters combined
+
+	// Bulk Alert Actions
+	AlertActionResolve     = "resolve"
+	AlertActionAcknowledge = "acknowledge"
+	MaxBulkAlertIDs        = 1000
+
+	// Environment Variable Keys
+	EnvKeyAlertCheckEnabled               = "ALERT_CHECK_ENABLED"
+	EnvKeyAlertCheckInterval              = "ALERT_CHECK_INTERVAL"
+	EnvKeyAlertCatchUpEnabled             = "ALERT_CATCHUP_ENABLED"
+	EnvKeyAlertCatchUpMaxLookback         = "ALERT_CATCHUP_MAX_LOOKBACK"
+	EnvKeyAlertCanaryPeriod               = "ALERT_CANARY_PERIOD"
+	EnvKeyAlertMetaAlertIntervals         = "ALERT_META_ALERT_INTERVALS"
+	EnvKeyAlertRetentionAge               = "ALERT_RETENTION_AGE"
+	EnvKeyAlertRetentionInterval          = "ALERT_RETENTION_INTERVAL"
+	EnvKeyAlertRetentionBatchSize         = "ALERT_RETENTION_BATCH_SIZE"
+	EnvKeyAlertRetentionOptimizeThreshold = "ALERT_RETENTION_OPTIMIZE_THRESHOLD"
+)
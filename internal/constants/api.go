@@ -1,6 +1,9 @@
 package constants
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
 
 // API Configuration Constants
 const (
@@ -17,10 +20,214 @@ const (
 	EnvKeyServerReadTimeout  = "SERVER_READ_TIMEOUT"
 	EnvKeyServerWriteTimeout = "SERVER_WRITE_TIMEOUT"
 	EnvKeyServerIdleTimeout  = "SERVER_IDLE_TIMEOUT"
+	EnvKeyAPILegacyRoutes    = "API_LEGACY_ROUTES"
+	EnvKeyAPIDebugSQL        = "API_DEBUG_SQL"
 
 	// API Base Paths
 	APIPrefix      = "/api"
 	APILogsPath    = "/logs"
 	APIMetricsPath = "/metrics"
 	APIHealthPath  = "/health"
+	APIAdminPath   = "/admin"
+	APIAuthPath    = "/auth"
+	APITracesPath  = "/traces"
+
+	// Versioned API, whose responses use the data/pagination/request_id/timing envelope
+	APIV1Prefix = "/api/v1"
+
+	// Graceful Shutdown, in stages run in this order
+	ShutdownStageHTTP           = "http"          // stop accepting requests, end live tails and drain requests in flight
+	ShutdownStageWorkers        = "workers"       // stop the alert checker, scheduler and other background workers
+	ShutdownStageNotifications  = "notifications" // let alert notifications in flight finish their attempts
+	ShutdownStageFlush          = "flush"         // flush and close Kafka producers and consumers
+	ShutdownStageClose          = "close"         // close database connections
+	DefaultShutdownTimeout      = 60 * time.Second
+	ShutdownHTTPTimeout         = 30 * time.Second
+	ShutdownWorkerTimeout       = 10 * time.Second
+	ShutdownNotificationTimeout = 20 * time.Second
+	ShutdownFlushTimeout        = 10 * time.Second
+	ShutdownCloseTimeout        = 5 * time.Second
+	ShutdownGracePeriod         = 1 * time.Second // given to components past their deadline to report what they abandoned
+	EnvKeyServerShutdownTimeout = "SERVER_SHUTDOWN_TIMEOUT"
+
+	// Request Tracking
+	HeaderRequestID     = "X-Request-ID"
+	MaxRequestIDLength  = 128
+	RequestIDKey        = "request_id"
+	RequestStartTimeKey = "request_start_time"
+
+	// CORS (browser apps hosted on other origins, such as external dashboards); disabled without allowed origins
+	CORSAllowAllOrigins       = "*"
+	DefaultCORSAllowedMethods = "GET,POST,PUT,PATCH,DELETE"
+	DefaultCORSAllowedHeaders = "Authorization,Content-Type,Accept," + HeaderRequestID
+	DefaultCORSExposedHeaders = HeaderRequestID + "," + HeaderNextCursor + "," + HeaderNextOffset + "," + HeaderLogColumns + "," + HeaderIntervalSeconds +
+		",Deprecation,Link,Content-Disposition," + HeaderLogExportTruncated + "," + HeaderDebugSQLQueries + "," + HeaderDebugSQLTime
+	DefaultCORSMaxAge          = 10 * time.Minute // how long browsers may cache the answer to a preflight request
+	EnvKeyCORSAllowedOrigins   = "CORS_ALLOWED_ORIGINS"
+	EnvKeyCORSAllowedMethods   = "CORS_ALLOWED_METHODS"
+	EnvKeyCORSAllowedHeaders   = "CORS_ALLOWED_HEADERS"
+	EnvKeyCORSExposedHeaders   = "CORS_EXPOSED_HEADERS"
+	EnvKeyCORSAllowCredentials = "CORS_ALLOW_CREDENTIALS"
+	EnvKeyCORSMaxAge           = "CORS_MAX_AGE"
+
+	// Security Headers (sent with every response; HSTS only when a max age is set, for servers behind TLS)
+	ContentSecurityPolicy        = "default-src 'none'; frame-ancestors 'none'" // the API serves no documents
+	ReferrerPolicy               = "no-referrer"
+	EnvKeySecurityHeadersEnabled = "SECURITY_HEADERS_ENABLED"
+	EnvKeyHSTSMaxAge             = "SECURITY_HSTS_MAX_AGE"
+
+	// Response Compression (gzip for clients accepting it, once a response outgrows the minimum size)
+	DefaultCompressionMinBytes = 1024 // smaller responses aren't worth the CPU and the gzip framing
+	DefaultCompressionLevel    = 6    // gzip's default, trading CPU for size; 1 is fastest, 9 smallest
+	EnvKeyCompressionEnabled   = "SERVER_COMPRESSION_ENABLED"
+	EnvKeyCompressionMinBytes  = "SERVER_COMPRESSION_MIN_BYTES"
+	EnvKeyCompressionLevel     = "SERVER_COMPRESSION_LEVEL"
+
+	// API Documentation (OpenAPI document built from the registered routes, browsed with Swagger UI)
+	APIDocsPath          = "/docs"
+	APIDocsSpecPath      = "/docs/openapi.json"
+	APIDocsTitle         = "Log Analytics API"
+	APIDocsVersion       = "1.0.0"
+	SwaggerUIURL         = "https://unpkg.com/swagger-ui-dist@5.17.14"
+	APIDocsCSP           = "default-src 'none'; script-src 'unsafe-inline' https://unpkg.com; style-src 'unsafe-inline' https://unpkg.com; img-src data: https:; connect-src 'self'; frame-ancestors 'none'"
+	EnvKeyAPIDocsEnabled = "API_DOCS_ENABLED"
+
+	// Pagination of /api/v1 list endpoints
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+	HeaderNextCursor = "X-Next-Cursor" // cursor of the next page on unversioned routes with keyset pagination
+	HeaderNextOffset = "X-Next-Offset" // offset of the next page of responses in the Arrow format
+	HeaderLogColumns = "X-Log-Columns" // columns of the rows of compact log listings
+
+	// Arrow Responses (Accept: application/vnd.apache.arrow.stream)
+	HeaderIntervalSeconds = "X-Interval-Seconds" // bucket width of time series in the Arrow format
+
+	// Long Polling
+	DefaultLogPollWait  = 30 * time.Second
+	MaxLogPollWait      = 60 * time.Second
+	DefaultLogPollLimit = 100
+	MaxLogPollLimit     = 1000            // larger limits are clamped to it
+	LogPollWriteMargin  = 5 * time.Second // kept between the longest wait and the server write timeout
+
+	// Log Export (CSV and NDJSON downloads of GET /api/logs/export)
+	LogExportFormatCSV        = "csv"
+	LogExportFormatNDJSON     = "ndjson"
+	LogExportPageSize         = 1000
+	MaxLogExportRows          = 100000
+	LogExportPageWriteTimeout = 30 * time.Second     // the write deadline is extended by this much for every page
+	HeaderLogExportTruncated  = "X-Export-Truncated" // trailer telling whether the row cap cut the export short
+
+	// Live Tail (Server-Sent Events of GET /api/logs/stream)
+	LogStreamSubscriberBuffer  = 256 // logs queued for a client before further logs are dropped
+	MaxLogStreamSubscribers    = 100
+	LogStreamHeartbeatInterval = 15 * time.Second
+	LogStreamWriteTimeout      = 30 * time.Second // the write deadline is extended by this much for every event
+
+	// Dashboard Cache (default dashboard queries, precomputed before the API server reports ready)
+	DefaultDashboardCacheTTL      = 15 * time.Second
+	DefaultDashboardWarmupTimeout = 60 * time.Second
+	EnvKeyDashboardCacheTTL       = "DASHBOARD_CACHE_TTL"
+	EnvKeyDashboardWarmupTimeout  = "DASHBOARD_WARMUP_TIMEOUT"
+
+	// Public Status Page (GET /status, served without authentication and naming no logs or alerts)
+	StatusPath                  = "/status"
+	StatusOperational           = "operational"
+	StatusDegraded              = "degraded"
+	StatusMaintenance           = "maintenance"
+	StatusOutage                = "outage"
+	StatusComponentAPI          = "api"
+	StatusComponentDatabase     = "database"
+	StatusComponentIngestion    = "ingestion"
+	StatusComponentAlerting     = "alerting"
+	DefaultStatusPageCacheTTL   = 30 * time.Second
+	DefaultStatusPageStaleAfter = 15 * time.Minute // ingestion is degraded once the newest log was stored longer ago
+	StatusPageTimeout           = 5 * time.Second
+	EnvKeyStatusPageEnabled     = "STATUS_PAGE_ENABLED"
+	EnvKeyStatusPageCacheTTL    = "STATUS_PAGE_CACHE_TTL"
+	EnvKeyStatusPageStaleAfter  = "STATUS_PAGE_STALE_AFTER"
+
+	// Alert Events (WebSocket of /ws/alerts)
+	AlertEventsPath             = "/ws/alerts"
+	AlertEventSubscriberBuffer  = 64 // events queued for a client before it is disconnected as too slow
+	MaxAlertEventSubscribers    = 100
+	AlertEventHeartbeatInterval = 30 * time.Second
+	AlertEventWriteTimeout      = 10 * time.Second
+
+	// Attribute Filters (e.g. attr.region=eu-west-1)
+	AttributeFilterPrefix = "attr."
+	MaxAttributeFilters   = 10
+
+	// SQL Debugging (debug=sql on requests of admins, when API_DEBUG_SQL is enabled)
+	DebugQueryParam       = "debug"
+	DebugSQL              = "sql"
+	DebugResponseKey      = "_debug"
+	MaxDebugQueries       = 200
+	HeaderDebugSQLQueries = "X-Debug-SQL-Queries" // statement count and time of responses that aren't JSON objects
+	HeaderDebugSQLTime    = "X-Debug-SQL-Time-Ms"
+
+	// Saved Searches
+	MaxSavedSearchNameLength = 100
+
+	// Log Statistics (GET /api/metrics)
+	DefaultMetricsRange = 24 * time.Hour // time range ending now used when none is given
+
+	// Log Time Series
+	DefaultTimeSeriesInterval = 5 * time.Minute
+	MaxTimeSeriesBuckets      = 1440
+	MetricsTimeSeriesPoints   = 100 // at most this many buckets are included in GET /api/metrics
+
+	// Time Series Bucket Alignment (defaults of the dashboard, overridable per request)
+	DefaultDashboardWeekStart    = time.Monday
+	DefaultDashboardBucketOffset = 0 // buckets start at midnight UTC
+	MaxBucketOffset              = 24 * time.Hour
+	EnvKeyDashboardWeekStart     = "DASHBOARD_WEEK_START"
+	EnvKeyDashboardBucketOffset  = "DASHBOARD_BUCKET_OFFSET"
+
+	// Service Comparison
+	MaxCompareServices = 20
+
+	// Slow Requests (GET /api/metrics/slow-requests)
+	SlowRequestsSortP95         = "p95"
+	SlowRequestsSortAvg         = "avg"
+	DefaultSlowRequestsLimit    = 10
+	MaxSlowRequestsLimit        = 100
+	DefaultSlowRequestsMinCount = 1
+
+	// Traces (GET /api/traces)
+	DefaultTraceListRange = 24 * time.Hour // time range ending now used when no start_time is given
+	DefaultTraceListLimit = 50
+	MaxTraceListLimit     = 500
+
+	// Log Aggregation (GET /api/logs/aggregate)
+	LogAggregationCount           = "count"
+	LogAggregationAvgResponseTime = "avg_response_time"
+	LogAggregationMaxResponseTime = "max_response_time"
+	DefaultLogAggregationRange    = 24 * time.Hour // time range ending now used when no start_time is given
+	DefaultLogAggregationGroups   = 100
+	MaxLogAggregationGroups       = 1000
+
+	// Latency Heatmap
+	DefaultHeatmapInterval       = 5 * time.Minute
+	MinHeatmapInterval           = 1 * time.Second
+	MaxHeatmapTimeBuckets        = 1440
+	DefaultHeatmapLatencyBuckets = "10,25,50,100,250,500,1000,2500,5000,10000" // upper bounds in milliseconds
+	MaxHeatmapLatencyBuckets     = 50
+
+	// Log Feed Settings
+	LogFeedPollInterval = 1 * time.Second
+	LogFeedBatchSize    = 1000
+
+	// Self-Ingestion (the API server's own access and application logs, published to the log topic)
+	DefaultSelfIngestionService      = "log-analytics-api"
+	DefaultSelfIngestionLevel        = slog.LevelInfo
+	DefaultSelfIngestionExcludePaths = APIHealthPath + "," + LivenessPath + "," + ReadinessPath + "," + MetricsPath // probes and scrapes
+	SelfIngestionAccessLogMessage    = "HTTP request"
+	EnvKeySelfIngestionEnabled       = "SELF_INGESTION_ENABLED"
+	EnvKeySelfIngestionService       = "SELF_INGESTION_SERVICE"
+	EnvKeySelfIngestionLevel         = "SELF_INGESTION_LEVEL"
+	EnvKeySelfIngestionExcludePaths  = "SELF_INGESTION_EXCLUDE_PATHS"
 )
+
+// UncompressedContentTypes are the response types never compressed: event streams, which proxies and clients must
+// get event by event, and archives that are compressed already
+var UncompressedContentTypes = []string{"text/event-stream", "application/gzip", "application/zip"}
@@ -0,0 +1,83 @@
+package constants
+
+import "time"
+
+// Authentication Constants
+const (
+	// Providers
+	AuthProviderNone = ""
+	AuthProviderLDAP = "ldap"
+
+	// Provider of the principals authenticated by service tokens
+	AuthProviderToken = "token"
+
+	// Roles, in increasing order of privilege
+	RoleViewer = "viewer"
+	RoleAdmin  = "admin"
+
+	// Basic authentication realm announced to browsers
+	AuthRealm = "Log Analytics"
+
+	// Scopes of service tokens, also metering every caller of the endpoints they grant
+	ScopeLogsTail   = "logs:tail"
+	ScopeLogsExport = "logs:export"
+
+	// Service tokens are sent as "Authorization: Bearer <token>"
+	BearerPrefix = "Bearer "
+
+	// Context keys holding the authenticated principal and the volume quota of scoped requests
+	AuthPrincipalKey = "auth_principal"
+	AuthQuotaKey     = "auth_quota"
+
+	// Default caps of each caller per scope; 0 disables a cap
+	DefaultTailRequestsPerMinute   = 60
+	DefaultTailLogsPerHour         = 1000000
+	DefaultExportRequestsPerMinute = 10
+	DefaultExportRowsPerHour       = 1000000
+
+	// Export audit listing
+	DefaultExportAuditLimit = 100
+	MaxExportAuditLimit     = 1000
+
+	// Audit records of API mutations: created, updated and deleted records, or the operation run on them
+	AuditActionCreate       = "create"
+	AuditActionUpdate       = "update"
+	AuditActionDelete       = "delete"
+	DefaultAuditRecordLimit = 100
+	MaxAuditRecordLimit     = 1000
+	MaxAuditSnapshotSize    = 64 << 10 // 64KB; larger snapshots are left out of the record
+
+	// Successful authentications are cached so every API call doesn't hit the directory
+	DefaultAuthCacheTTL = 1 * time.Minute
+
+	// LDAP Settings
+	DefaultLDAPUserAttribute  = "uid"
+	DefaultLDAPGroupAttribute = "memberOf"
+	DefaultLDAPTimeout        = 5 * time.Second
+	LDAPUserPlaceholder       = "{username}"
+	MaxLDAPUsernameLength     = 256
+
+	// Environment Variable Keys
+	EnvKeyAuthProvider       = "AUTH_PROVIDER"
+	EnvKeyAuthCacheTTL       = "AUTH_CACHE_TTL"
+	EnvKeyAuthServiceTokens  = "AUTH_SERVICE_TOKENS"
+	EnvKeyAuthTailRate       = "AUTH_TAIL_REQUESTS_PER_MINUTE"
+	EnvKeyAuthTailVolume     = "AUTH_TAIL_LOGS_PER_HOUR"
+	EnvKeyAuthExportRate     = "AUTH_EXPORT_REQUESTS_PER_MINUTE"
+	EnvKeyAuthExportVolume   = "AUTH_EXPORT_ROWS_PER_HOUR"
+	EnvKeyLDAPURL            = "LDAP_URL"
+	EnvKeyLDAPUserDNTemplate = "LDAP_USER_DN_TEMPLATE"
+	EnvKeyLDAPBaseDN         = "LDAP_BASE_DN"
+	EnvKeyLDAPUserAttribute  = "LDAP_USER_ATTRIBUTE"
+	EnvKeyLDAPGroupAttribute = "LDAP_GROUP_ATTRIBUTE"
+	EnvKeyLDAPGroupRoles     = "LDAP_GROUP_ROLES"
+	EnvKeyLDAPGroupTenants   = "LDAP_GROUP_TENANTS"
+	EnvKeyLDAPDefaultRole    = "LDAP_DEFAULT_ROLE"
+	EnvKeyLDAPTimeout        = "LDAP_TIMEOUT"
+	EnvKeyLDAPStartTLS       = "LDAP_START_TLS"
+	EnvKeyLDAPInsecure       = "LDAP_INSECURE"
+	EnvKeyLDAPCAFile         = "LDAP_CA_FILE"
+)
+
+// AuditRedactedKeys are the keys of snapshot fields whose values aren't kept in audit records, at any depth
+var AuditRedactedKeys = []string{"secret", "password", "token"}
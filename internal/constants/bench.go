@@ -0,0 +1,29 @@
+package constants
+
+import "time"
+
+// Benchmark Configuration Constants (cmd/bench)
+const (
+	// Brokers the pipeline is driven through
+	BenchBrokerMock     = "mock"     // an in-process mock broker acknowledging produced messages
+	BenchBrokerDocker   = "docker"   // a single-node Kafka container started for the run
+	BenchBrokerExternal = "external" // the brokers of KAFKA_BROKERS
+
+	// Run Settings
+	DefaultBenchLogs       = 50000
+	MaxBenchLogs           = 10000000
+	DefaultBenchBatchSize  = 500 // logs sent per producer request
+	DefaultBenchPartitions = 3
+	DefaultBenchQueryRuns  = 20 // runs of each query of the query stage
+	DefaultBenchOutputDir  = "bench-results"
+	BenchServicePrefix     = "bench-" // followed by the run ID; the run's logs are deleted afterwards unless kept
+	BenchPurgeBatchSize    = 5000
+
+	// Report Formats
+	BenchFormatMarkdown = "markdown"
+	BenchFormatJSON     = "json"
+
+	// Docker Broker
+	BenchKafkaImage        = "apache/kafka:3.7.0"
+	BenchKafkaStartTimeout = 2 * time.Minute
+)
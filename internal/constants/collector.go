@@ -0,0 +1,146 @@
+package constants
+
+import "time"
+
+// Log Collector Constants
+const (
+	// HTTP Ingestion Settings
+	DefaultIngestPort         = "8090"
+	DefaultIngestMaxBodyBytes = 5 << 20 // 5MB
+	DefaultIngestMaxBatchSize = 1000
+	IngestShutdownTimeout     = 10 * time.Second
+	APIIngestPath             = "/ingest"
+
+	// Syslog Listener Settings
+	SyslogMaxMessageSize    = 64 << 10 // 64KB, larger frames are truncated (UDP) or rejected (TCP)
+	SyslogQueueSize         = 10000
+	SyslogBatchSize         = 500
+	SyslogFlushInterval     = 1 * time.Second
+	SyslogTCPIdleTimeout    = 5 * time.Minute
+	SyslogDefaultService    = "syslog"
+	SyslogAttributePrefix   = "syslog."
+	SyslogNilValue          = "-"
+	SyslogRFC5424Version    = "1"
+	SyslogRFC3164TimeLayout = "Jan _2 15:04:05"
+
+	// OTLP Receiver Settings
+	OTLPLogsPath                = "/v1/logs"
+	OTLPContentTypeProtobuf     = "application/x-protobuf"
+	OTLPContentTypeJSON         = "application/json"
+	OTLPShutdownTimeout         = 10 * time.Second
+	OTLPDefaultService          = "unknown_service" // OpenTelemetry's service name for resources without service.name
+	OTLPAttributeServiceName    = "service.name"
+	OTLPAttributeParentSpanID   = "parent_span_id"
+	OTLPAttributeUserID         = "user.id"
+	OTLPAttributeEndUserID      = "enduser.id"
+	OTLPAttributeHTTPMethod     = "http.request.method"
+	OTLPAttributeURLPath        = "url.path"
+	OTLPAttributeHTTPStatus     = "http.response.status_code"
+	OTLPAttributeTenant         = "tenant"
+	OTLPAttributeRequestBody    = "http.request.body"
+	OTLPAttributeResponseBody   = "http.response.body"
+	OTLPAttributeHostName       = "host.name"
+	OTLPAttributeEnvironment    = "deployment.environment.name"
+	OTLPAttributeEnvironmentOld = "deployment.environment" // deprecated name still sent by older SDKs
+	OTLPAttributeCloudRegion    = "cloud.region"
+	OTLPAttributeClientAddr     = "client.address"
+
+	// Body Capture Settings
+	BodyCaptureAllServices     = "*"
+	DefaultBodyCaptureMaxBytes = 2048
+	BodyRedactedValue          = "[REDACTED]"
+	BodyTruncatedSuffix        = "...[truncated]"
+
+	// File Tail Settings
+	DefaultTailCheckpointFile = "tail-checkpoint.json"
+	TailPollInterval          = 1 * time.Second
+	TailReadChunkSize         = 1 << 20  // 1MB
+	TailMaxLineSize           = 64 << 10 // 64KB, longer lines are split
+	TailBatchSize             = 500
+	TailAttributePath         = "file.path"
+
+	// Spool Settings (logs kept on disk while Kafka is unreachable)
+	DefaultSpoolMaxBytes      = 1 << 30 // 1GB
+	DefaultSpoolDrainInterval = 5 * time.Second
+	SpoolSegmentBytes         = 16 << 20 // segments are rotated past this size and removed once drained
+	SpoolDrainBatchSize       = 500
+	SpoolSegmentExt           = ".spool"
+
+	// Producer Compression (codec of the message batches the collector produces; zstd needs Kafka 2.1 or later)
+	ProducerCompressionNone    = "none"
+	ProducerCompressionGzip    = "gzip"
+	ProducerCompressionSnappy  = "snappy"
+	ProducerCompressionLZ4     = "lz4"
+	ProducerCompressionZstd    = "zstd"
+	DefaultProducerCompression = ProducerCompressionSnappy
+
+	// Partitioning Settings (what messages are keyed by, and how keys are mapped to partitions)
+	PartitionKeyTraceID    = "trace_id"    // a trace's logs share a partition, a service's are spread over all of them
+	PartitionKeyService    = "service"     // a service's logs share a partition, and are consumed in order
+	PartitionKeyTenant     = "tenant"      // a tenant's logs share a partition; logs without a tenant are keyed by service
+	PartitionKeyRoundRobin = "round_robin" // messages are unkeyed and spread evenly over the partitions
+	PartitionerHash        = "hash"        // FNV-1a, sarama's default
+	PartitionerMurmur2     = "murmur2"     // the Java client's default, mapping keys to the same partitions as Java producers
+	PartitionerCRC32       = "crc32"       // librdkafka's default, mapping keys to the same partitions as librdkafka producers
+
+	// Environment Variable Keys
+	EnvKeyCollectorIngestEnabled  = "COLLECTOR_INGEST_ENABLED"
+	EnvKeyCollectorIngestPort     = "COLLECTOR_INGEST_PORT"
+	EnvKeyCollectorGenerateSample = "COLLECTOR_GENERATE_SAMPLES"
+	EnvKeyCollectorMaxBodyBytes   = "COLLECTOR_INGEST_MAX_BODY_BYTES"
+	EnvKeyCollectorMaxBatchSize   = "COLLECTOR_INGEST_MAX_BATCH_SIZE"
+	EnvKeyCollectorSyslogUDPAddr  = "COLLECTOR_SYSLOG_UDP_ADDR"
+	EnvKeyCollectorSyslogTCPAddr  = "COLLECTOR_SYSLOG_TCP_ADDR"
+	EnvKeyCollectorOTLPHTTPAddr   = "COLLECTOR_OTLP_HTTP_ADDR"
+	EnvKeyCollectorOTLPGRPCAddr   = "COLLECTOR_OTLP_GRPC_ADDR"
+	EnvKeyCollectorBodyServices   = "COLLECTOR_BODY_CAPTURE_SERVICES"
+	EnvKeyCollectorBodyMaxBytes   = "COLLECTOR_BODY_CAPTURE_MAX_BYTES"
+	EnvKeyCollectorBodyRedactKeys = "COLLECTOR_BODY_CAPTURE_REDACT_KEYS"
+	EnvKeyCollectorTailPaths      = "COLLECTOR_TAIL_PATHS"
+	EnvKeyCollectorTailCheckpoint = "COLLECTOR_TAIL_CHECKPOINT_FILE"
+	EnvKeyCollectorAsyncProducer  = "COLLECTOR_ASYNC_PRODUCER"
+	EnvKeyCollectorBufferSize     = "COLLECTOR_PRODUCER_BUFFER_SIZE"
+	EnvKeyCollectorFlushMessages  = "COLLECTOR_PRODUCER_FLUSH_MESSAGES"
+	EnvKeyCollectorFlushFrequency = "COLLECTOR_PRODUCER_FLUSH_FREQUENCY"
+	EnvKeyCollectorSampleRates    = "COLLECTOR_SAMPLE_RATES"
+	EnvKeyCollectorRateLimit      = "COLLECTOR_RATE_LIMIT"
+	EnvKeyCollectorServiceLimits  = "COLLECTOR_SERVICE_RATE_LIMITS"
+	EnvKeyCollectorSpoolDir       = "COLLECTOR_SPOOL_DIR"
+	EnvKeyCollectorSpoolMaxBytes  = "COLLECTOR_SPOOL_MAX_BYTES"
+	EnvKeyCollectorSpoolDrain     = "COLLECTOR_SPOOL_DRAIN_INTERVAL"
+	EnvKeyCollectorCompression    = "COLLECTOR_PRODUCER_COMPRESSION"
+	EnvKeyCollectorPartitionKey   = "COLLECTOR_PARTITION_KEY"
+	EnvKeyCollectorPartitioner    = "COLLECTOR_PARTITIONER"
+)
+
+// ProducerCompressions are the codecs the collector can compress messages with
+var ProducerCompressions = []string{
+	ProducerCompressionNone, ProducerCompressionGzip, ProducerCompressionSnappy, ProducerCompressionLZ4, ProducerCompressionZstd,
+}
+
+// PartitionKeys are the strategies the collector can key messages by
+var PartitionKeys = []string{PartitionKeyTraceID, PartitionKeyService, PartitionKeyTenant, PartitionKeyRoundRobin}
+
+// Partitioners are the partitioners keyed messages can be mapped to partitions with
+var Partitioners = []string{PartitionerHash, PartitionerMurmur2, PartitionerCRC32}
+
+// SampleLevels are the log levels sample rates can be set for
+var SampleLevels = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// DefaultBodyRedactKeys are the fields whose values are redacted from captured bodies by default
+var DefaultBodyRedactKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token", "api_key", "apikey",
+	"authorization", "cookie", "credit_card", "card_number", "cvv", "ssn",
+}
+
+// slog Handler Constants (pkg/slogkafka)
+const (
+	SlogKafkaDefaultAggregateWindow  = 1 * time.Second
+	SlogKafkaDefaultMaxAggregateKeys = 1000 // messages tracked per window; further ones are sent without aggregation
+	SlogKafkaDefaultBufferSize       = 1000 // messages awaiting delivery before more are dropped
+
+	// Attributes of the summaries of repeated messages
+	SlogKafkaAttributeCount     = "aggregate.count"
+	SlogKafkaAttributeFirstSeen = "aggregate.first_seen"
+	SlogKafkaAttributeLastSeen  = "aggregate.last_seen"
+)
@@ -0,0 +1,36 @@
+package constants
+
+// Configuration File Constants
+const (
+	// Config file read by every binary when no --config flag is given
+	EnvKeyConfigFile = "CONFIG_FILE"
+
+	// How often the config file is checked for changes to reload; 0 reloads on SIGHUP only
+	EnvKeyConfigWatchInterval = "CONFIG_WATCH_INTERVAL"
+
+	// Sources of effective settings, in decreasing order of precedence
+	ConfigSourceEnv     = "env"
+	ConfigSourceFile    = "file"
+	ConfigSourceDefault = "default"
+
+	// Commands of the config binary
+	ConfigCommandPrint = "print"
+	ConfigCommandCheck = "check"
+
+	// Unknown settings of config files are suggested the known setting this close to them, in edits
+	MaxConfigSuggestionDistance = 3
+)
+
+// ReloadableSettings are the settings a configuration reload applies to running services; others take effect on restart
+var ReloadableSettings = []string{
+	EnvKeyLogLevel,
+	EnvKeyPipelineBatchSize,
+	EnvKeyPipelineBatchTimeout,
+	EnvKeyAlertCheckInterval,
+	EnvKeyCollectorSampleRates,
+	EnvKeyCollectorRateLimit,
+	EnvKeyCollectorServiceLimits,
+}
+
+// ConfigSecretKeyParts mark the settings whose values are masked when the effective configuration is printed
+var ConfigSecretKeyParts = []string{"PASSWORD", "SECRET", "SIGNING_KEY", "TOKENS"}
@@ -4,19 +4,31 @@ import "time"
 
 // Database Configuration Constants
 const (
+	// Database Drivers
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+
 	// Default Database Settings
+	DefaultDBDriver   = DBDriverMySQL
 	DefaultDBHost     = "localhost"
 	DefaultDBPort     = "3306"
 	DefaultDBUser     = "root"
 	DefaultDBPassword = "password"
 	DefaultDBName     = "log_analytics"
 
+	// Postgres Settings
+	DefaultPGPort    = "5432"     // default port when the driver is postgres
+	DefaultPGSSLMode = "prefer"   // sslmode of Postgres connections
+	PGAdminDatabase  = "postgres" // connected to by the migration tool until the database exists
+
 	// Connection Pool Settings
 	DefaultMaxOpenConns    = 25
 	DefaultMaxIdleConns    = 5
 	DefaultConnMaxLifetime = 5 * time.Minute
 
 	// Environment Variable Keys
+	EnvKeyDBDriver          = "DB_DRIVER"
+	EnvKeyDBSSLMode         = "DB_SSL_MODE"
 	EnvKeyDBHost            = "MYSQL_HOST"
 	EnvKeyDBPort            = "MYSQL_PORT"
 	EnvKeyDBUser            = "MYSQL_USER"
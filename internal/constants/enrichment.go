@@ -0,0 +1,37 @@
+package constants
+
+import "time"
+
+// Enrichment Configuration Constants
+const (
+	// Lookup Settings
+	DefaultEnrichmentTimeout     = 2 * time.Second
+	DefaultEnrichmentCacheTTL    = 5 * time.Minute
+	DefaultEnrichmentConcurrency = 8
+
+	// Cache and Stage Settings
+	DefaultEnrichmentCacheMaxEntries = 10000
+	DefaultEnrichmentQueueSize       = 16 // batches buffered between consumption and enrichment
+
+	// Circuit Breaker Settings
+	DefaultEnrichmentFailureThreshold = 5
+	DefaultEnrichmentBreakerCooldown  = 30 * time.Second
+
+	// Lookup Keys
+	EnrichmentKeyUserID  = "user_id"
+	EnrichmentKeyTraceID = "trace_id"
+
+	// URL placeholder replaced with the (escaped) lookup key value
+	EnrichmentURLPlaceholder = "{value}"
+
+	// Environment Variable Keys
+	EnvKeyEnrichmentEnabled          = "ENRICHMENT_ENABLED"
+	EnvKeyEnrichmentEndpoints        = "ENRICHMENT_ENDPOINTS"
+	EnvKeyEnrichmentTimeout          = "ENRICHMENT_TIMEOUT"
+	EnvKeyEnrichmentCacheTTL         = "ENRICHMENT_CACHE_TTL"
+	EnvKeyEnrichmentConcurrency      = "ENRICHMENT_CONCURRENCY"
+	EnvKeyEnrichmentCacheMaxEntries  = "ENRICHMENT_CACHE_MAX_ENTRIES"
+	EnvKeyEnrichmentQueueSize        = "ENRICHMENT_QUEUE_SIZE"
+	EnvKeyEnrichmentFailureThreshold = "ENRICHMENT_FAILURE_THRESHOLD"
+	EnvKeyEnrichmentBreakerCooldown  = "ENRICHMENT_BREAKER_COOLDOWN"
+)
@@ -0,0 +1,22 @@
+package constants
+
+// Compliance Export Constants
+const (
+	// Archive Layout
+	ExportManifestFile       = "manifest.json"
+	ExportSignatureFile      = "manifest.sig"
+	ExportDataFileFormat     = "logs-%05d.ndjson"
+	ExportManifestVersion    = 1
+	ExportChainGenesis       = "log-analytics-export"
+	DefaultExportRowsPerFile = 10000
+
+	// Limits
+	ExportPageSize      = 1000
+	MaxExportRows       = 1000000 // archives can't be cut short, so larger exports are refused
+	MaxExportVerifySize = 1 << 30 // 1GB
+	MaxExportEntrySize  = 256 << 20
+
+	// Environment Variable Keys
+	EnvKeyExportSigningKey  = "EXPORT_SIGNING_KEY"
+	EnvKeyExportRowsPerFile = "EXPORT_ROWS_PER_FILE"
+)
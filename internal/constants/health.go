@@ -0,0 +1,32 @@
+package constants
+
+import "time"
+
+// Health Probes
+const (
+	// Liveness and readiness probes, served by the API server and by the collector and processor on dedicated ports
+	LivenessPath               = "/healthz"
+	ReadinessPath              = "/readyz"
+	DefaultHealthEnabled       = true
+	DefaultCollectorHealthPort = "9111"
+	DefaultProcessorHealthPort = "9112"
+	ProbeCheckTimeout          = 5 * time.Second // readiness checks failing to answer in time count as failed
+	HealthShutdownTimeout      = 5 * time.Second
+
+	// Probe statuses
+	ProbeStatusAlive    = "alive"
+	ProbeStatusReady    = "ready"
+	ProbeStatusNotReady = "not_ready"
+	ProbeCheckOK        = "ok"
+
+	// Readiness checks
+	ProbeCheckDatabase  = "database"
+	ProbeCheckKafka     = "kafka"
+	ProbeCheckConsumer  = "consumer"
+	ProbeCheckDashboard = "dashboard_cache"
+
+	// Environment Variable Keys
+	EnvKeyHealthEnabled       = "HEALTH_ENABLED"
+	EnvKeyCollectorHealthPort = "COLLECTOR_HEALTH_PORT"
+	EnvKeyProcessorHealthPort = "PROCESSOR_HEALTH_PORT"
+)
@@ -0,0 +1,28 @@
+package constants
+
+import "time"
+
+// Import Configuration Constants (cmd/import)
+const (
+	// Source Formats
+	ImportFormatNDJSON = "ndjson"
+	ImportFormatCSV    = "csv"
+	ImportFormatTable  = "table" // a logging table in another MySQL database
+
+	// Destinations
+	ImportModePipeline = "pipeline" // published to Kafka and stored by the log processor
+	ImportModeDirect   = "direct"   // validated and written to the log repository
+
+	// Batching and Progress
+	DefaultImportBatchSize  = 500
+	MaxImportBatchSize      = 10000
+	DefaultImportMaxInvalid = 1000 // invalid records after which the import is aborted; 0 never aborts
+	ImportProgressInterval  = 10 * time.Second
+	MaxImportLineBytes      = 1024 * 1024 // longest NDJSON line accepted
+
+	// Table Sources
+	DefaultImportKeyColumn = "id" // unique, ordered column the table is paged by
+
+	// Name recorded as the parser attribute of logs built from mapped fields
+	ImportParserName = "import"
+)
@@ -11,6 +11,11 @@ const (
 	// Producer Configuration
 	DefaultProducerRetryMax = 5
 
+	// Async Producer Configuration
+	DefaultProducerBufferSize     = 10000
+	DefaultProducerFlushMessages  = 500
+	DefaultProducerFlushFrequency = 100 * time.Millisecond
+
 	// Consumer Group Configuration
 	DefaultConsumerAutoCommitInterval = 1 * time.Second
 
@@ -19,6 +24,48 @@ const (
 	DefaultKafkaBroker     = "localhost:9092"
 	DefaultConsumerGroupID = "log-processor-final"
 	DefaultAutoOffsetReset = "latest"
+	DefaultDeadLetterTopic = "logs-dlq"
+
+	// Live Tail Configuration (stored logs published back by the processor for the API server's live tail)
+	DefaultKafkaStreamTopic         = "logs-stream"
+	DefaultStreamProducerBufferSize = 1000 // stored logs awaiting delivery to the stream topic before more are dropped
+	StreamConsumerRetryInterval     = 5 * time.Second
+
+	// Control Topic Configuration (compacted topic of dynamic pipeline settings, keyed by service)
+	ControlSettingsDefaultKey    = "*" // key of the settings of services without their own
+	ControlConsumerRetryInterval = 5 * time.Second
+	ControlReplayTimeout         = 30 * time.Second // longest wait for the published settings before consuming logs
+
+	// Priority Lane Configuration (ERROR/FATAL logs on a separate topic)
+	DefaultPriorityBatchTimeout = 200 * time.Millisecond
+	PriorityGroupIDSuffix       = "-priority"
+
+	// Batch Store Retries (offsets are marked only once a batch is stored or dead-lettered)
+	DefaultStoreRetries      = 3
+	DefaultStoreRetryBackoff = 1 * time.Second
+	MaxStoreRetryBackoff     = 30 * time.Second
+
+	// Store Circuit Breaker (consumption is paused while the log store is unreachable)
+	DefaultStoreBreakerThreshold     = 3               // consecutive failed writes with the store unreachable opening the breaker
+	DefaultStoreBreakerProbeInterval = 5 * time.Second // how often an open breaker checks whether the store is back
+	StoreBreakerPingTimeout          = 5 * time.Second
+
+	// Deduplication (message IDs of recently stored logs remembered by each processor)
+	DefaultDedupCacheSize = 100000
+	MessageIDHashLength   = 32 // hex characters of the IDs derived from a log's content or Kafka position
+
+	// Message Formats of the logs the collector produces; the processor reads both
+	MessageFormatJSON = "json"
+	MessageFormatAvro = "avro" // Avro framed with the schema's ID, registered in the schema registry
+
+	// Schema Registry Configuration (schemas of Avro messages are registered under the topic's value subject)
+	SchemaRegistrySubjectSuffix  = "-value"
+	DefaultSchemaRegistryTimeout = 5 * time.Second
+
+	// Consumer Lag Monitoring (committed offsets of the processor's consumer groups against the newest offsets)
+	DefaultLagCheckInterval = 30 * time.Second
+	KafkaLagAlertName       = "Kafka consumer lag"
+	KafkaLagAlertSeverity   = "high"
 
 	// Environment Variable Keys
 	EnvKeyKafkaBrokers          = "KAFKA_BROKERS"
@@ -26,9 +73,43 @@ const (
 	EnvKeyKafkaGroupID          = "KAFKA_GROUP_ID"
 	EnvKeyKafkaAutoOffsetReset  = "KAFKA_AUTO_OFFSET_RESET"
 	EnvKeyKafkaEnableAutoCommit = "KAFKA_ENABLE_AUTO_COMMIT"
+	EnvKeyKafkaDeadLetterTopic  = "KAFKA_DEAD_LETTER_TOPIC"
+	EnvKeyKafkaPriorityTopic    = "KAFKA_PRIORITY_TOPIC"
+	EnvKeyKafkaPriorityTimeout  = "KAFKA_PRIORITY_BATCH_TIMEOUT"
+	EnvKeyKafkaLagCheckInterval = "KAFKA_LAG_CHECK_INTERVAL"
+	EnvKeyKafkaLagThreshold     = "KAFKA_LAG_ALERT_THRESHOLD"
+	EnvKeyKafkaTenantTopics     = "KAFKA_TENANT_TOPICS"
+	EnvKeyKafkaStreamTopic      = "KAFKA_STREAM_TOPIC"
+	EnvKeyKafkaControlTopic     = "KAFKA_CONTROL_TOPIC"
+	EnvKeyKafkaStoreRetries     = "KAFKA_STORE_RETRIES"
+	EnvKeyKafkaStoreBackoff     = "KAFKA_STORE_RETRY_BACKOFF"
+	EnvKeyKafkaStoreBreaker     = "KAFKA_STORE_BREAKER_THRESHOLD"
+	EnvKeyKafkaStoreBreakerPing = "KAFKA_STORE_BREAKER_PROBE_INTERVAL"
+	EnvKeyKafkaDedupCacheSize   = "KAFKA_DEDUP_CACHE_SIZE"
+	EnvKeyKafkaMessageFormat    = "KAFKA_MESSAGE_FORMAT"
+	EnvKeySchemaRegistryURL     = "SCHEMA_REGISTRY_URL"
+	EnvKeySchemaRegistryUser    = "SCHEMA_REGISTRY_USERNAME"
+	EnvKeySchemaRegistryPass    = "SCHEMA_REGISTRY_PASSWORD"
+	EnvKeySchemaRegistryTimeout = "SCHEMA_REGISTRY_TIMEOUT"
 
 	// Kafka Headers
 	HeaderService   = "service"
 	HeaderLevel     = "level"
 	HeaderTimestamp = "timestamp"
+	HeaderTenant    = "tenant" // accepted on the log and priority topics for registered tenants
+
+	// Trace Context Headers, set by producers of logs carrying a trace and span
+	HeaderTraceID      = "trace_id"
+	HeaderSpanID       = "span_id"
+	HeaderParentSpanID = "parent_span_id"
+
+	// Dead-Letter Headers
+	HeaderDeadLetterError     = "dlq_error"
+	HeaderDeadLetterTopic     = "dlq_original_topic"
+	HeaderDeadLetterPartition = "dlq_original_partition"
+	HeaderDeadLetterOffset    = "dlq_original_offset"
+	HeaderDeadLetterFailedAt  = "dlq_failed_at"
+
+	// Header added to messages forwarded by the control topic's settings
+	HeaderForwardedFrom = "forwarded_from_topic"
 )
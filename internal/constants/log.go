@@ -1,5 +1,7 @@
 package constants
 
+import "log/slog"
+
 // Log Generation Constants
 const (
 	// Sample Services
@@ -26,6 +28,13 @@ const (
 	StatusOK    = 200
 	StatusError = 500
 
+	// Valid HTTP Status Code Range
+	MinHTTPStatus = 100
+	MaxHTTPStatus = 599
+
+	// Default generator status distribution as status:weight pairs
+	DefaultGeneratorStatusWeights = "200:60,201:8,204:4,301:2,302:3,304:6,400:5,401:3,403:2,404:6,409:1,422:2,429:2,500:3,502:1,503:2,504:1"
+
 	// Response Time Ranges (in milliseconds)
 	MinResponseTime = 10
 	MaxResponseTime = 2010
@@ -34,17 +43,53 @@ const (
 	UserIDFormat = "user_%d"
 	MaxUserID    = 1000
 
+	// Span ID Format: 8 random bytes in hex, like OpenTelemetry span IDs
+	SpanIDFormat = "%016x"
+
+	// Sample Environments
+	EnvironmentProduction  = "production"
+	EnvironmentStaging     = "staging"
+	EnvironmentDevelopment = "development"
+
+	// Sample Regions
+	RegionUSEast1  = "us-east-1"
+	RegionEUWest1  = "eu-west-1"
+	RegionAPSouth1 = "ap-south-1"
+
+	// Sample host names: service, environment, region and instance number
+	SampleHostFormat = "%s-%s-%s-%02d"
+
+	// Sample client addresses come from the documentation networks, so they never reach real hosts
+	SampleClientNetworkA = "192.0.2.%d"
+	SampleClientNetworkB = "198.51.100.%d"
+	SampleClientNetworkC = "203.0.113.%d"
+
+	// Field Length Limits (match the logs table columns)
+	MaxServiceLength       = 100
+	MaxTraceIDLength       = 50
+	MaxSpanIDLength        = 32
+	MaxMessageIDLength     = 64
+	MaxUserIDLength        = 50
+	MaxRequestMethodLength = 10
+	MaxRequestPathLength   = 500
+	MaxTenantLength        = 64
+	MaxHostLength          = 255
+	MaxEnvironmentLength   = 32
+	MaxRegionLength        = 32
+	MaxBodyExcerptLength   = 65535 // TEXT
+
 	// Log Generation Timing
 	LogGenerationInterval = 1 // seconds
 	MaxLogsPerSecond      = 5
 
 	// Log Configuration
-	DefaultLogLevel  = "info"
+	DefaultLogLevel  = slog.LevelInfo
 	DefaultLogFormat = "json"
 
 	// Environment Variable Keys
-	EnvKeyLogLevel  = "LOG_LEVEL"
-	EnvKeyLogFormat = "LOG_FORMAT"
+	EnvKeyLogLevel               = "LOG_LEVEL"
+	EnvKeyLogFormat              = "LOG_FORMAT"
+	EnvKeyGeneratorStatusWeights = "GENERATOR_STATUS_WEIGHTS"
 )
 
 // Log Message Templates
@@ -0,0 +1,28 @@
+package constants
+
+import "time"
+
+// Log Store Constants
+const (
+	// Backends logs can be stored in; alerts, rules and other metadata always stay in MySQL
+	LogStoreMySQL      = "mysql"
+	LogStoreClickHouse = "clickhouse"
+
+	// Default ClickHouse Settings
+	DefaultClickHouseURL      = "http://localhost:8123"
+	DefaultClickHouseDatabase = "log_analytics"
+	DefaultClickHouseUser     = "default"
+	DefaultClickHouseTimeout  = 30 * time.Second
+
+	// Logs inserted per INSERT statement; ClickHouse favours few large inserts over many small ones
+	DefaultClickHouseBatchSize = 10000
+
+	// Environment Variable Keys
+	EnvKeyLogStoreBackend     = "LOG_STORE_BACKEND"
+	EnvKeyClickHouseURL       = "CLICKHOUSE_URL"
+	EnvKeyClickHouseDatabase  = "CLICKHOUSE_DATABASE"
+	EnvKeyClickHouseUser      = "CLICKHOUSE_USER"
+	EnvKeyClickHousePassword  = "CLICKHOUSE_PASSWORD"
+	EnvKeyClickHouseTimeout   = "CLICKHOUSE_TIMEOUT"
+	EnvKeyClickHouseBatchSize = "CLICKHOUSE_BATCH_SIZE"
+)
@@ -0,0 +1,16 @@
+package constants
+
+import "time"
+
+// Maintenance Mode Constants
+const (
+	// Interval at which services reload the shared maintenance state
+	MaintenanceRefreshInterval = 5 * time.Second
+
+	// Maximum length of the maintenance banner
+	MaxMaintenanceBannerLength = 500
+
+	// Environment Variable Keys
+	EnvKeyReadOnlyMode      = "READ_ONLY_MODE"
+	EnvKeyMaintenanceBanner = "MAINTENANCE_BANNER"
+)
@@ -0,0 +1,24 @@
+package constants
+
+import "time"
+
+// Prometheus Metrics Constants
+const (
+	// Prefix of every exported metric name
+	MetricsNamespace = "log_analytics"
+
+	// Scrape endpoint, served by the API server on its own port and by the collector and processor on dedicated ports
+	MetricsPath                 = "/metrics"
+	DefaultMetricsEnabled       = true
+	DefaultCollectorMetricsPort = "9101"
+	DefaultProcessorMetricsPort = "9102"
+	MetricsShutdownTimeout      = 5 * time.Second
+
+	// Label used for requests that matched no route, keeping route cardinality bounded
+	MetricsUnmatchedRoute = "unmatched"
+
+	// Environment Variable Keys
+	EnvKeyMetricsEnabled       = "METRICS_ENABLED"
+	EnvKeyCollectorMetricsPort = "COLLECTOR_METRICS_PORT"
+	EnvKeyProcessorMetricsPort = "PROCESSOR_METRICS_PORT"
+)
@@ -0,0 +1,42 @@
+package constants
+
+import "time"
+
+// Migration Configuration Constants
+const (
+	// Online DDL tools that can run ALTERs of large tables without locking them
+	OnlineDDLToolGhost = "gh-ost"
+	OnlineDDLToolPTOSC = "pt-online-schema-change"
+
+	// Tables with fewer estimated rows are altered directly
+	DefaultOnlineDDLMinRows = 1000000
+
+	// Advisory lock serializing migration runs, named after the migrated database
+	MigrationLockPrefix         = "log_analytics_migrations:"
+	MaxMigrationLockNameLength  = 64 // longest lock name MySQL accepts
+	DefaultMigrationLockTimeout = 10 * time.Minute
+	MigrationLockPollInterval   = 1 * time.Second // of Postgres, which can't wait for a lock with a timeout
+
+	// Storage layout of the logs table, applied by the migration runs of MySQL once configured
+	LogsCompressionNone     = "none" // uncompressed dynamic rows, reverting any compression
+	LogsCompressionRow      = "row"  // ROW_FORMAT=COMPRESSED with the configured key block size
+	LogsCompressionZlib     = "zlib" // InnoDB page compression, which needs hole punching by the file system
+	LogsCompressionLZ4      = "lz4"
+	DefaultLogsKeyBlockSize = 8              // KB of compressed pages, half of the default 16KB page
+	LogsPrimaryKeyID        = "id"           // clustered by id, as created
+	LogsPrimaryKeyTimestamp = "timestamp_id" // clustered by (timestamp, id), storing logs of the same time together
+	LogsIDIndex             = "idx_id"       // unique index keeping id auto incremented once it leads no primary key
+
+	// Environment Variable Keys
+	EnvKeyOnlineDDLTool        = "MIGRATION_ONLINE_DDL_TOOL"
+	EnvKeyOnlineDDLBinary      = "MIGRATION_ONLINE_DDL_BINARY"
+	EnvKeyOnlineDDLOptions     = "MIGRATION_ONLINE_DDL_OPTIONS"
+	EnvKeyOnlineDDLMinRows     = "MIGRATION_ONLINE_DDL_MIN_ROWS"
+	EnvKeyMigrationAppliedBy   = "MIGRATION_APPLIED_BY"
+	EnvKeyMigrationLockTimeout = "MIGRATION_LOCK_TIMEOUT"
+	EnvKeyMigrationDir         = "MIGRATION_DIR"
+	EnvKeyMigrateOnStartup     = "MIGRATE_ON_STARTUP"
+	EnvKeyLogsCompression      = "MIGRATION_LOGS_COMPRESSION"
+	EnvKeyLogsKeyBlockSize     = "MIGRATION_LOGS_KEY_BLOCK_SIZE"
+	EnvKeyLogsPrimaryKey       = "MIGRATION_LOGS_PRIMARY_KEY"
+)
@@ -0,0 +1,54 @@
+package constants
+
+import "time"
+
+// Notification Configuration Constants
+const (
+	// Channel Types
+	NotificationChannelEmail   = "email"
+	NotificationChannelSlack   = "slack"
+	NotificationChannelWebhook = "webhook"
+	NotificationChannelTeams   = "teams"
+	NotificationChannelDiscord = "discord"
+
+	// Delivery Settings
+	DefaultNotificationTimeout        = 10 * time.Second
+	DefaultNotificationMaxAttempts    = 5
+	DefaultNotificationInitialBackoff = 1 * time.Second
+	DefaultNotificationMaxBackoff     = 1 * time.Minute
+
+	// SMTP Settings
+	DefaultSMTPPort = "587"
+
+	// Channel Limits
+	MaxNotificationChannelNameLength = 100
+
+	// Alert Report Settings
+	AlertReportTopErrors        = 3
+	AlertReportMaxMessageLength = 200 // longer error messages are truncated in emails and chat messages
+
+	// Discord Embed Limits
+	DiscordMaxTitleLength       = 256
+	DiscordMaxDescriptionLength = 4096
+	DiscordMaxFieldLength       = 1024
+
+	// Environment Variable Keys
+	EnvKeyNotificationTimeout        = "NOTIFICATION_TIMEOUT"
+	EnvKeyNotificationMaxAttempts    = "NOTIFICATION_MAX_ATTEMPTS"
+	EnvKeyNotificationInitialBackoff = "NOTIFICATION_INITIAL_BACKOFF"
+	EnvKeyNotificationMaxBackoff     = "NOTIFICATION_MAX_BACKOFF"
+	EnvKeySMTPHost                   = "SMTP_HOST"
+	EnvKeySMTPPort                   = "SMTP_PORT"
+	EnvKeySMTPUsername               = "SMTP_USERNAME"
+	EnvKeySMTPPassword               = "SMTP_PASSWORD"
+	EnvKeySMTPFrom                   = "SMTP_FROM"
+)
+
+// Webhook Signature Constants (pkg/client)
+const (
+	WebhookTimestampHeader           = "X-Log-Analytics-Timestamp" // Unix time in seconds the payload was signed at
+	WebhookSignatureHeader           = "X-Log-Analytics-Signature"
+	WebhookSignaturePrefix           = "sha256="
+	MinWebhookSecretLength           = 16
+	DefaultWebhookSignatureTolerance = 5 * time.Minute // how far a signed timestamp may be from the receiver's clock
+)
@@ -0,0 +1,87 @@
+package constants
+
+import "time"
+
+// Processing Pipeline Constants
+const (
+	// Prefix of attributes holding propagated Kafka headers
+	HeaderAttributePrefix = "header."
+
+	// Parser types for messages that aren't in the native log JSON shape
+	ParserTypeJSON   = "json"
+	ParserTypeLogfmt = "logfmt"
+	ParserTypeRegex  = "regex"
+	ParserTypeGrok   = "grok"
+
+	// Attribute recording which parser decoded a message
+	ParserAttributeName = "parser"
+
+	// Timestamp formats accepted besides Go time layouts
+	ParserTimestampUnix   = "unix"
+	ParserTimestampUnixMs = "unix_ms"
+
+	// Maximum nesting of grok pattern references, guarding against reference cycles
+	MaxGrokPatternDepth = 16
+
+	// Length of error fingerprints, in hex characters of the hash of the service and masked message
+	FingerprintLength = 16
+
+	// Fingerprint Backfill Statuses
+	FingerprintBackfillRunning   = "running"
+	FingerprintBackfillCompleted = "completed"
+	FingerprintBackfillFailed    = "failed"
+	FingerprintBackfillCancelled = "cancelled"
+
+	// Fingerprint Backfill Settings
+	FingerprintBackfillBatchSize  = 1000
+	FingerprintBackfillBatchPause = 100 * time.Millisecond
+	FingerprintBackfillStaleAfter = 5 * time.Minute // a running job without progress for this long is taken over
+
+	// Ingestion Delay (time from a log's timestamp until the processor stores it)
+	DefaultIngestionDelayThreshold = 10 * time.Minute // services whose logs are mostly later than this fire the ingestion delay alert
+	DefaultIngestionDelayWindow    = 1 * time.Minute  // stored logs are checked against the threshold once per window
+	IngestionDelayMinLogs          = 5                // logs a service must store in a window to be checked
+	IngestionDelayLateShare        = 50               // percentage of a window's logs that must be late for the alert to fire
+	IngestionDelayAlertName        = "Ingestion delay"
+	IngestionDelayAlertSeverity    = "high"
+
+	// Redaction of sensitive data before logs are stored
+	RedactionBuiltinEmail       = "email"
+	RedactionBuiltinCreditCard  = "credit_card"
+	RedactionBuiltinBearerToken = "bearer_token"
+	RedactionAllBuiltins        = "*"
+	RedactedValue               = "[REDACTED]"
+	RedactionFieldAttributes    = "attributes" // every attribute
+	RedactionAttributePrefix    = "attr."      // a single attribute, e.g. attr.customer_email
+
+	// Graceful shutdown of the processor: batches in flight are stored and their offsets committed within this time
+	DefaultDrainTimeout = 30 * time.Second
+
+	// Batches of the bulk lane stored concurrently by the processor's write workers
+	DefaultWriteWorkers = 4
+
+	// Environment Variable Keys
+	EnvKeyPipelineHeaderAttributes = "PIPELINE_HEADER_ATTRIBUTES"
+	EnvKeyPipelineHeaderFilters    = "PIPELINE_HEADER_FILTERS"
+	EnvKeyPipelineParsersFile      = "PIPELINE_PARSERS_FILE"
+	EnvKeyPipelineFingerprintFile  = "PIPELINE_FINGERPRINT_FILE"
+	EnvKeyPipelineRedactBuiltins   = "PIPELINE_REDACT_BUILTINS"
+	EnvKeyPipelineRedactionFile    = "PIPELINE_REDACTION_FILE"
+	EnvKeyIngestionDelayThreshold  = "PIPELINE_INGESTION_DELAY_THRESHOLD"
+	EnvKeyIngestionDelayWindow     = "PIPELINE_INGESTION_DELAY_WINDOW"
+	EnvKeyPipelineDrainTimeout     = "PIPELINE_DRAIN_TIMEOUT"
+	EnvKeyPipelineBatchSize        = "PIPELINE_BATCH_SIZE"
+	EnvKeyPipelineBatchTimeout     = "PIPELINE_BATCH_TIMEOUT"
+	EnvKeyPipelineWriteWorkers     = "PIPELINE_WRITE_WORKERS"
+)
+
+// RedactionBuiltins are the built-in redaction rules, applied to the message and request path
+var RedactionBuiltins = []string{RedactionBuiltinEmail, RedactionBuiltinCreditCard, RedactionBuiltinBearerToken}
+
+// RedactionFields are the log fields redaction rules apply to, besides single attributes
+var RedactionFields = []string{
+	"message", "request_path", "user_id", "client_ip", "host", "request_body", "response_body", RedactionFieldAttributes,
+}
+
+// DefaultRedactionFields are the fields redaction rules with a pattern apply to when they don't list any
+var DefaultRedactionFields = []string{"message", "request_path"}
@@ -0,0 +1,26 @@
+package constants
+
+import "time"
+
+// Log Retention Constants
+const (
+	// Policy Actions
+	RetentionActionDelete  = "delete"
+	RetentionActionArchive = "archive"
+
+	// Archived logs are moved to a table named after the log table with this suffix
+	RetentionArchiveSuffix = "_archive"
+
+	// Purge Settings
+	DefaultLogRetentionInterval   = 1 * time.Hour
+	DefaultLogRetentionBatchSize  = 1000
+	DefaultLogRetentionBatchPause = 100 * time.Millisecond // lets writers waiting on the purge's locks through between batches
+
+	// Policy Limits
+	MaxRetentionDays = 3650
+
+	// Environment Variable Keys
+	EnvKeyLogRetentionInterval   = "LOG_RETENTION_INTERVAL"
+	EnvKeyLogRetentionBatchSize  = "LOG_RETENTION_BATCH_SIZE"
+	EnvKeyLogRetentionBatchPause = "LOG_RETENTION_BATCH_PAUSE"
+)
@@ -0,0 +1,10 @@
+package constants
+
+// Log Routing Constants
+const (
+	// Default table holding log entries
+	DefaultLogsTable = "logs"
+
+	// Environment Variable Keys
+	EnvKeyLogRoutingRules = "LOG_ROUTING_RULES"
+)
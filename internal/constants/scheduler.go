@@ -0,0 +1,36 @@
+package constants
+
+import "time"
+
+// Scheduler Constants
+const (
+	// Task Names
+	TaskLogRetention     = "log_retention"
+	TaskAlertRetention   = "alert_retention"
+	TaskSchedulerHistory = "scheduler_history"
+
+	// Worker Names
+	WorkerAlertChecker = "alert_checker"
+	WorkerKafkaLag     = "kafka_lag_monitor"
+
+	// Run Statuses
+	TaskRunRunning   = "running"
+	TaskRunSucceeded = "succeeded"
+	TaskRunFailed    = "failed"
+
+	// Leases
+	DefaultSchedulerLeaseDuration = 1 * time.Minute  // renewed while a task runs; a replica that dies loses it after this long
+	DefaultSchedulerPollInterval  = 30 * time.Second // how often replicas check whether a task is due
+	SchedulerJitter               = 0.2              // fraction of the poll interval added at random, spreading replicas' checks
+
+	// Run History
+	SchedulerRunRetention    = 7 * 24 * time.Hour
+	SchedulerHistoryInterval = 1 * time.Hour
+	DefaultTaskRunsLimit     = 20
+	MaxTaskRunsLimit         = 500
+	MaxTaskRunErrorLength    = 1000
+
+	// Environment Variable Keys
+	EnvKeySchedulerLeaseDuration = "SCHEDULER_LEASE_DURATION"
+	EnvKeySchedulerPollInterval  = "SCHEDULER_POLL_INTERVAL"
+)
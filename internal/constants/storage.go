@@ -0,0 +1,20 @@
+package constants
+
+// Storage Statistics Constants
+const (
+	// Default number of days used to compute average daily growth
+	DefaultStorageGrowthWindowDays = 14
+
+	// Default disk capacity in GB (0 disables the exhaustion projection)
+	DefaultStorageDiskCapacityGB = 0
+
+	// Projections further out than this are omitted (about 100 years)
+	MaxStorageProjectionDays = 36500
+
+	// Bytes per GB
+	BytesPerGB = 1 << 30
+
+	// Environment Variable Keys
+	EnvKeyStorageGrowthWindowDays = "STORAGE_GROWTH_WINDOW_DAYS"
+	EnvKeyStorageDiskCapacityGB   = "STORAGE_DISK_CAPACITY_GB"
+)
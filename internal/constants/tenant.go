@@ -0,0 +1,11 @@
+package constants
+
+import "time"
+
+// Tenant Constants
+const (
+	MaxTenantDescriptionLength = 255
+
+	// How often the log processor reloads the registered tenants accepted in the tenant header
+	TenantRefreshInterval = time.Minute
+)
@@ -2,18 +2,40 @@ package alert_rules
 
 import (
 	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
 	"github.com/adeesh/log-analytics/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AlertRuleRepository defines the interface for alert rule operations
 type AlertRuleRepository interface {
 	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
-	GetAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	GetAlertRules(ctx context.Context, tenant *string) ([]models.AlertRule, error)
 	GetAlertRuleByID(ctx context.Context, id uint) (*models.AlertRule, error)
+	// AlertRuleNameTaken reports whether a rule other than the given one has the name among the rules of the
+	// tenant, or among the rules over every tenant's logs for a nil tenant
+	AlertRuleNameTaken(ctx context.Context, name string, tenant *string, exceptID uint) (bool, error)
 	UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error
 	DeleteAlertRule(ctx context.Context, id uint) error
+	UpdateLastEvaluatedAt(ctx context.Context, id uint, evaluatedAt time.Time) error
+	MuteAlertRule(ctx context.Context, id uint, until time.Time) error
+	RecordCanaryEvaluation(ctx context.Context, evaluation *models.AlertRuleCanaryEvaluation) error
+	ClearCanaryEvaluations(ctx context.Context, id uint) error
+	GetCanaryReport(ctx context.Context, id uint) (*models.AlertRuleCanaryReport, error)
+	PromoteCanary(ctx context.Context, id uint) error
+	RollbackCanary(ctx context.Context, id uint) error
+	// GetRuleState returns a rule's evaluator state of the given kind, or an empty state of version 0 if it was
+	// never saved
+	GetRuleState(ctx context.Context, id uint, kind string) (*models.AlertRuleState, error)
+	// SaveRuleState stores an evaluator state loaded by GetRuleState and increments its version. It fails with a
+	// conflict error when the state was saved by someone else since it was loaded.
+	SaveRuleState(ctx context.Context, state *models.AlertRuleState) error
 }
 
 // GormAlertRuleRepository implements AlertRuleRepository using GORM
@@ -28,14 +50,22 @@ func NewAlertRuleRepository(db *gorm.DB) AlertRuleRepository {
 
 // CreateAlertRule creates a new alert rule
 func (r *GormAlertRuleRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
-	return r.db.WithContext(ctx).Create(rule).Error
+	// Evaluation bookkeeping is owned by the alert checker and mutes by the mute endpoint, so neither can be set here.
+	// New rules have no previous version to canary against.
+	err := r.db.WithContext(ctx).Omit("last_evaluated_at", "muted_until", "previous_condition", "previous_threshold",
+		"previous_time_window", "canary_until").Create(rule).Error
+	return database.TranslateError(err, "failed to create alert rule")
 }
 
-// GetAlertRules retrieves all alert rules
-func (r *GormAlertRuleRepository) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+// GetAlertRules retrieves all alert rules, or those of a single tenant
+func (r *GormAlertRuleRepository) GetAlertRules(ctx context.Context, tenant *string) ([]models.AlertRule, error) {
+	query := r.db.WithContext(ctx)
+	if tenant != nil {
+		query = query.Where("tenant = ?", *tenant)
+	}
 	var rules []models.AlertRule
-	err := r.db.WithContext(ctx).Find(&rules).Error
-	return rules, err
+	err := query.Find(&rules).Error
+	return rules, database.TranslateError(err, "failed to get alert rules")
 }
 
 // GetAlertRuleByID retrieves an alert rule by ID
@@ -43,17 +73,225 @@ func (r *GormAlertRuleRepository) GetAlertRuleByID(ctx context.Context, id uint)
 	var rule models.AlertRule
 	err := r.db.WithContext(ctx).First(&rule, id).Error
 	if err != nil {
-		return nil, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Alert rule not found")
+		}
+		return nil, database.TranslateError(err, "failed to get alert rule")
 	}
 	return &rule, nil
 }
 
+// AlertRuleNameTaken reports whether another rule of the tenant has the name
+func (r *GormAlertRuleRepository) AlertRuleNameTaken(ctx context.Context, name string, tenant *string, exceptID uint) (bool, error) {
+	query := r.db.WithContext(ctx).Model(&models.AlertRule{}).Where("name = ? AND id <> ?", name, exceptID)
+	if tenant != nil {
+		query = query.Where("tenant = ?", *tenant)
+	} else {
+		query = query.Where("tenant IS NULL")
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, database.TranslateError(err, "failed to check alert rule name")
+}
+
 // UpdateAlertRule updates an alert rule
 func (r *GormAlertRuleRepository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
-	return r.db.WithContext(ctx).Save(rule).Error
+	// Save inserts when no row matches, so missing rules must be rejected explicitly
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.AlertRule{}).Where("id = ?", rule.ID).Count(&count).Error; err != nil {
+		return database.TranslateError(err, "failed to update alert rule")
+	}
+	if count == 0 {
+		return apperrors.NotFound("Alert rule not found")
+	}
+
+	// Evaluation bookkeeping and mutes must not be overwritten by API updates
+	err := r.db.WithContext(ctx).Omit("last_evaluated_at", "muted_until").Save(rule).Error
+	return database.TranslateError(err, "failed to update alert rule")
 }
 
 // DeleteAlertRule deletes an alert rule
 func (r *GormAlertRuleRepository) DeleteAlertRule(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&models.AlertRule{}, id).Error
+	result := r.db.WithContext(ctx).Delete(&models.AlertRule{}, id)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to delete alert rule")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Alert rule not found")
+	}
+	return nil
+}
+
+// UpdateLastEvaluatedAt records the time a rule was last successfully evaluated
+func (r *GormAlertRuleRepository) UpdateLastEvaluatedAt(ctx context.Context, id uint, evaluatedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.AlertRule{}).Where("id = ?", id).
+		UpdateColumn("last_evaluated_at", evaluatedAt).Error
+	return database.TranslateError(err, "failed to update last evaluated time")
+}
+
+// MuteAlertRule suppresses new alerts of a rule until the given time
+func (r *GormAlertRuleRepository) MuteAlertRule(ctx context.Context, id uint, until time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.AlertRule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"muted_until": until,
+		"updated_at":  time.Now(),
+	})
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to mute alert rule")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Alert rule not found")
+	}
+	return nil
+}
+
+// RecordCanaryEvaluation stores the outcome of evaluating both versions of a canaried rule
+func (r *GormAlertRuleRepository) RecordCanaryEvaluation(ctx context.Context, evaluation *models.AlertRuleCanaryEvaluation) error {
+	err := r.db.WithContext(ctx).Create(evaluation).Error
+	return database.TranslateError(err, "failed to record canary evaluation")
+}
+
+// ClearCanaryEvaluations deletes the canary evaluations of a rule, e.g. before a new canary starts
+func (r *GormAlertRuleRepository) ClearCanaryEvaluations(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Where("rule_id = ?", id).Delete(&models.AlertRuleCanaryEvaluation{}).Error
+	return database.TranslateError(err, "failed to clear canary evaluations")
+}
+
+// GetCanaryReport compares how often each version of a canaried rule fired, with its most recent disagreements
+func (r *GormAlertRuleRepository) GetCanaryReport(ctx context.Context, id uint) (*models.AlertRuleCanaryReport, error) {
+	rule, err := r.GetAlertRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.HasCanary() {
+		return nil, apperrors.Conflict("Alert rule has no canary in progress")
+	}
+
+	report := &models.AlertRuleCanaryReport{
+		RuleID:      rule.ID,
+		Active:      rule.InCanary(time.Now()),
+		CanaryUntil: *rule.CanaryUntil,
+		Previous:    models.RuleVersion{Condition: *rule.PreviousCondition, Threshold: *rule.PreviousThreshold, TimeWindow: *rule.PreviousTimeWindow},
+		Canary:      models.RuleVersion{Condition: rule.Condition, Threshold: rule.Threshold, TimeWindow: rule.TimeWindow},
+	}
+	err = r.db.WithContext(ctx).Model(&models.AlertRuleCanaryEvaluation{}).
+		Select(`COUNT(*) as evaluations,
+			COUNT(CASE WHEN previous_fired THEN 1 END) as previous_fired,
+			COUNT(CASE WHEN canary_fired THEN 1 END) as canary_fired,
+			COUNT(CASE WHEN previous_fired AND NOT canary_fired THEN 1 END) as only_previous_fired,
+			COUNT(CASE WHEN canary_fired AND NOT previous_fired THEN 1 END) as only_canary_fired`).
+		Where("rule_id = ?", id).
+		Row().Scan(&report.Evaluations, &report.PreviousFired, &report.CanaryFired, &report.OnlyPreviousFired, &report.OnlyCanaryFired)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get canary report")
+	}
+
+	err = r.db.WithContext(ctx).Where("rule_id = ? AND previous_fired <> canary_fired", id).
+		Order("evaluated_at DESC").Limit(constants.AlertCanaryRecentDisagreements).
+		Find(&report.RecentDisagreements).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get canary disagreements")
+	}
+	if report.RecentDisagreements == nil {
+		report.RecentDisagreements = []models.AlertRuleCanaryEvaluation{}
+	}
+	return report, nil
+}
+
+// PromoteCanary ends a rule's canary early, putting its edited version into effect
+func (r *GormAlertRuleRepository) PromoteCanary(ctx context.Context, id uint) error {
+	return r.endCanary(ctx, id, false)
+}
+
+// RollbackCanary ends a rule's canary by restoring its previous version
+func (r *GormAlertRuleRepository) RollbackCanary(ctx context.Context, id uint) error {
+	return r.endCanary(ctx, id, true)
+}
+
+// endCanary clears a rule's previous version and canary evaluations, restoring the previous version first on rollback
+func (r *GormAlertRuleRepository) endCanary(ctx context.Context, id uint, rollback bool) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rule models.AlertRule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rule, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperrors.NotFound("Alert rule not found")
+			}
+			return err
+		}
+		if !rule.HasCanary() {
+			return apperrors.Conflict("Alert rule has no canary in progress")
+		}
+
+		updates := map[string]interface{}{
+			"previous_condition":   nil,
+			"previous_threshold":   nil,
+			"previous_time_window": nil,
+			"canary_until":         nil,
+			"updated_at":           time.Now(),
+		}
+		if rollback {
+			updates["condition"] = *rule.PreviousCondition
+			updates["threshold"] = *rule.PreviousThreshold
+			updates["time_window"] = *rule.PreviousTimeWindow
+		}
+		if err := tx.Model(&models.AlertRule{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Where("rule_id = ?", id).Delete(&models.AlertRuleCanaryEvaluation{}).Error
+	})
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return database.TranslateError(err, "failed to end canary")
+}
+
+// GetRuleState returns a rule's evaluator state of the given kind
+func (r *GormAlertRuleRepository) GetRuleState(ctx context.Context, id uint, kind string) (*models.AlertRuleState, error) {
+	var state models.AlertRuleState
+	err := r.db.WithContext(ctx).Where("rule_id = ? AND kind = ?", id, kind).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.AlertRuleState{RuleID: id, Kind: kind}, nil
+	}
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get alert rule state")
+	}
+	return &state, nil
+}
+
+// SaveRuleState stores an evaluator state if its version is still the stored one. States of version 0 are
+// created, so two first saves conflict on the primary key.
+func (r *GormAlertRuleRepository) SaveRuleState(ctx context.Context, state *models.AlertRuleState) error {
+	now := time.Now()
+	if state.Version == 0 {
+		saved := *state
+		saved.Version = 1
+		saved.UpdatedAt = now
+		if err := r.db.WithContext(ctx).Create(&saved).Error; err != nil {
+			err = database.TranslateError(err, "failed to save alert rule state")
+			if apperrors.Is(err, apperrors.CodeConflict) {
+				return apperrors.Conflict("Alert rule state was saved concurrently")
+			}
+			return err
+		}
+		*state = saved
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.AlertRuleState{}).
+		Where("rule_id = ? AND kind = ? AND version = ?", state.RuleID, state.Kind, state.Version).
+		Updates(map[string]interface{}{
+			"state":      state.State,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to save alert rule state")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.Conflict("Alert rule state was saved concurrently")
+	}
+	state.Version++
+	state.UpdatedAt = now
+	return nil
 }
@@ -2,22 +2,35 @@ package alerts
 
 import (
 	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
 	"github.com/adeesh/log-analytics/internal/models"
+	"maps"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AlertRepository defines the interface for alert operations
 type AlertRepository interface {
 	CreateAlert(ctx context.Context, alert *models.Alert) error
 	GetAlerts(ctx context.Context, filter *models.AlertFilter) ([]models.Alert, error)
+	GetAlertSummaries(ctx context.Context, filter *models.AlertFilter) ([]models.AlertSummary, error)
 	GetAlertByID(ctx context.Context, id uint) (*models.Alert, error)
 	UpdateAlert(ctx context.Context, alert *models.Alert) error
-	GetAlertStats(ctx context.Context) (*models.AlertStats, error)
+	GetAlertStats(ctx context.Context, startTime, endTime *time.Time, tenant *string) (*models.AlertStats, error)
 	GetActiveAlerts(ctx context.Context) ([]models.Alert, error)
 	ResolveAlert(ctx context.Context, id uint) error
 	AcknowledgeAlert(ctx context.Context, id uint) error
+	UpdateAlertsStatus(ctx context.Context, filter *models.AlertFilter, status string) ([]models.Alert, error)
+	SnoozeAlert(ctx context.Context, id uint, until time.Time) error
+	RecordNotification(ctx context.Context, id uint, at time.Time) error
+	PruneResolvedAlerts(ctx context.Context, before time.Time, limit int) (int64, error)
+	OptimizeAlertsTable(ctx context.Context) error
 }
 
 // GormAlertRepository implements AlertRepository using GORM
@@ -32,27 +45,89 @@ func NewAlertRepository(db *gorm.DB) AlertRepository {
 
 // CreateAlert creates a new alert
 func (r *GormAlertRepository) CreateAlert(ctx context.Context, alert *models.Alert) error {
-	return r.db.WithContext(ctx).Create(alert).Error
+	return database.TranslateError(r.db.WithContext(ctx).Create(alert).Error, "failed to create alert")
 }
 
-// GetAlerts retrieves alerts with filters
+// GetAlerts retrieves alerts with filters, newest first. Rules are loaded only when the filter asks for them.
 func (r *GormAlertRepository) GetAlerts(ctx context.Context, filter *models.AlertFilter) ([]models.Alert, error) {
-	query := r.db.WithContext(ctx).Preload("Rule")
+	query := r.db.WithContext(ctx)
+	if filter.IncludeRule {
+		query = query.Preload("Rule")
+	}
+
+	var alerts []models.Alert
+	err := applyAlertFilter(query, filter).Find(&alerts).Error
+	return alerts, database.TranslateError(err, "failed to get alerts")
+}
+
+// GetAlertSummaries retrieves the summaries of alerts with filters, newest first, with the name of their rule
+func (r *GormAlertRepository) GetAlertSummaries(ctx context.Context, filter *models.AlertFilter) ([]models.AlertSummary, error) {
+	query := r.db.WithContext(ctx).Model(&models.Alert{}).
+		Select("alerts.id, alerts.rule_id, alert_rules.name AS rule_name, alerts.tenant, alerts.message, alerts.severity, alerts.value, " +
+			"alerts.status, alerts.late_detected, alerts.created_at, alerts.snoozed_until").
+		Joins("JOIN alert_rules ON alert_rules.id = alerts.rule_id")
+
+	var summaries []models.AlertSummary
+	err := applyAlertFilter(query, filter).Find(&summaries).Error
+	return summaries, database.TranslateError(err, "failed to get alert summaries")
+}
 
-	if filter.Status != nil {
-		query = query.Where("status = ?", *filter.Status)
+// applyAlertFilter restricts an alert query to the filter and orders it newest first.
+// Pages continue after the cursor when one is given; ties in creation time are broken by ID.
+func applyAlertFilter(query *gorm.DB, filter *models.AlertFilter) *gorm.DB {
+	if len(filter.Statuses) > 0 {
+		query = query.Where("alerts.status IN ?", filter.Statuses)
 	}
 	if filter.Severity != nil {
-		query = query.Where("severity = ?", *filter.Severity)
+		query = query.Where("alerts.severity = ?", *filter.Severity)
+	}
+	if len(filter.ExcludeSeverities) > 0 {
+		query = query.Where("alerts.severity NOT IN ?", filter.ExcludeSeverities)
 	}
 	if filter.RuleID != nil {
-		query = query.Where("rule_id = ?", *filter.RuleID)
+		query = query.Where("alerts.rule_id = ?", *filter.RuleID)
+	}
+	if len(filter.ExcludeRuleIDs) > 0 {
+		query = query.Where("alerts.rule_id NOT IN ?", filter.ExcludeRuleIDs)
+	}
+	// Labels are matched in the small rules table, so alerts are still selected through the rule_id index.
+	// Sorted so equal filters produce the same statement.
+	for _, key := range slices.Sorted(maps.Keys(filter.Labels)) {
+		query = query.Where("alerts.rule_id IN (SELECT labeled.id FROM alert_rules labeled WHERE "+database.JSONContains(query, "labeled.labels")+")",
+			key, filter.Labels[key])
+	}
+	for _, key := range slices.Sorted(maps.Keys(filter.ExcludeLabels)) {
+		query = query.Where("alerts.rule_id NOT IN (SELECT labeled.id FROM alert_rules labeled WHERE "+database.JSONContains(query, "labeled.labels")+")",
+			key, filter.ExcludeLabels[key])
+	}
+	if filter.Search != nil {
+		query = query.Where(database.FullTextMatch(query, "alerts.message"), *filter.Search)
+	}
+	if filter.Tenant != nil {
+		query = query.Where("alerts.tenant = ?", *filter.Tenant)
+	}
+	if filter.Snoozed != nil {
+		if *filter.Snoozed {
+			query = query.Where("alerts.snoozed_until > ?", time.Now())
+		} else {
+			query = query.Where("alerts.snoozed_until IS NULL OR alerts.snoozed_until <= ?", time.Now())
+		}
 	}
 	if filter.From != nil {
-		query = query.Where("created_at >= ?", *filter.From)
+		query = query.Where("alerts.created_at >= ?", *filter.From)
 	}
 	if filter.To != nil {
-		query = query.Where("created_at <= ?", *filter.To)
+		query = query.Where("alerts.created_at <= ?", *filter.To)
+	}
+	if filter.ResolvedAfter != nil {
+		query = query.Where("alerts.resolved_at > ?", *filter.ResolvedAfter)
+	}
+	if len(filter.IDs) > 0 {
+		query = query.Where("alerts.id IN ?", filter.IDs)
+	}
+	if filter.After != nil {
+		query = query.Where("alerts.created_at < ? OR (alerts.created_at = ? AND alerts.id < ?)",
+			filter.After.CreatedAt, filter.After.CreatedAt, filter.After.ID)
 	}
 
 	// Apply pagination
@@ -62,10 +137,7 @@ func (r *GormAlertRepository) GetAlerts(ctx context.Context, filter *models.Aler
 	if filter.Offset != nil {
 		query = query.Offset(*filter.Offset)
 	}
-
-	var alerts []models.Alert
-	err := query.Order("created_at DESC").Find(&alerts).Error
-	return alerts, err
+	return query.Order("alerts.created_at DESC, alerts.id DESC")
 }
 
 // GetAlertByID retrieves an alert by ID
@@ -73,75 +145,229 @@ func (r *GormAlertRepository) GetAlertByID(ctx context.Context, id uint) (*model
 	var alert models.Alert
 	err := r.db.WithContext(ctx).Preload("Rule").First(&alert, id).Error
 	if err != nil {
-		return nil, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Alert not found")
+		}
+		return nil, database.TranslateError(err, "failed to get alert")
 	}
 	return &alert, nil
 }
 
 // UpdateAlert updates an alert
 func (r *GormAlertRepository) UpdateAlert(ctx context.Context, alert *models.Alert) error {
-	return r.db.WithContext(ctx).Save(alert).Error
-}
+	// Save inserts when no row matches, so missing alerts must be rejected explicitly
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", alert.ID).Count(&count).Error; err != nil {
+		return database.TranslateError(err, "failed to update alert")
+	}
+	if count == 0 {
+		return apperrors.NotFound("Alert not found")
+	}
 
-// GetAlertStats retrieves alert statistics
-func (r *GormAlertRepository) GetAlertStats(ctx context.Context) (*models.AlertStats, error) {
-	var stats models.AlertStats
+	return database.TranslateError(r.db.WithContext(ctx).Save(alert).Error, "failed to update alert")
+}
 
-	// Total alerts
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Count(&stats.TotalAlerts).Error; err != nil {
-		return nil, err
-	}
+// GetAlertStats retrieves statistics of the alerts created in the optional time range, optionally of a single
+// tenant, in a single query. Counts include the resolved alerts pruned by retention, whose rollups are matched by
+// the day they were created and the tenant of their rule; the average times to acknowledge and resolve only cover
+// stored alerts.
+func (r *GormAlertRepository) GetAlertStats(ctx context.Context, startTime, endTime *time.Time, tenant *string) (*models.AlertStats, error) {
+	counts := r.db.Model(&models.Alert{}).Select(`COUNT(*) AS total_alerts,
+		COUNT(CASE WHEN status = ? THEN 1 END) AS active_alerts,
+		COUNT(CASE WHEN status = ? THEN 1 END) AS resolved_alerts,
+		COUNT(CASE WHEN severity = 'critical' THEN 1 END) AS critical_alerts,
+		COUNT(CASE WHEN severity = 'high' THEN 1 END) AS high_alerts,
+		COUNT(CASE WHEN severity = 'medium' THEN 1 END) AS medium_alerts,
+		COUNT(CASE WHEN severity = 'low' THEN 1 END) AS low_alerts,
+		AVG(`+database.SecondsBetween(r.db, "created_at", "acknowledged_at")+`) AS avg_time_to_acknowledge_seconds,
+		AVG(`+database.SecondsBetween(r.db, "created_at", "resolved_at")+`) AS avg_time_to_resolve_seconds`,
+		constants.AlertStatusActive, constants.AlertStatusResolved)
 
-	// Active alerts
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("status = ?", "active").Count(&stats.ActiveAlerts).Error; err != nil {
-		return nil, err
-	}
+	// Resolved alerts pruned by retention
+	pruned := r.db.Model(&models.AlertStatsRollup{}).Select(`COALESCE(SUM(alert_count), 0) AS pruned_alerts,
+		COALESCE(SUM(CASE WHEN severity = 'critical' THEN alert_count END), 0) AS pruned_critical,
+		COALESCE(SUM(CASE WHEN severity = 'high' THEN alert_count END), 0) AS pruned_high,
+		COALESCE(SUM(CASE WHEN severity = 'medium' THEN alert_count END), 0) AS pruned_medium,
+		COALESCE(SUM(CASE WHEN severity = 'low' THEN alert_count END), 0) AS pruned_low`)
 
-	// Resolved alerts
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("status = ?", "resolved").Count(&stats.ResolvedAlerts).Error; err != nil {
-		return nil, err
+	if startTime != nil {
+		counts = counts.Where("created_at >= ?", *startTime)
+		pruned = pruned.Where("day >= CAST(? AS DATE)", *startTime)
 	}
-
-	// Alerts by severity
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("severity = ?", "critical").Count(&stats.CriticalAlerts).Error; err != nil {
-		return nil, err
+	if endTime != nil {
+		counts = counts.Where("created_at < ?", *endTime)
+		pruned = pruned.Where("day < ?", *endTime)
 	}
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("severity = ?", "high").Count(&stats.HighAlerts).Error; err != nil {
-		return nil, err
+	if tenant != nil {
+		counts = counts.Where("tenant = ?", *tenant)
+		pruned = pruned.Where("rule_id IN (?)", r.db.Model(&models.AlertRule{}).Select("id").Where("tenant = ?", *tenant))
 	}
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("severity = ?", "medium").Count(&stats.MediumAlerts).Error; err != nil {
-		return nil, err
+
+	var row struct {
+		models.AlertStats
+		PrunedCritical int64
+		PrunedHigh     int64
+		PrunedMedium   int64
+		PrunedLow      int64
 	}
-	if err := r.db.WithContext(ctx).Model(&models.Alert{}).Where("severity = ?", "low").Count(&stats.LowAlerts).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).
+		Raw("SELECT * FROM (?) AS alert_counts CROSS JOIN (?) AS pruned_counts", counts, pruned).
+		Scan(&row).Error; err != nil {
+		return nil, database.TranslateError(err, "failed to get alert stats")
 	}
 
+	stats := row.AlertStats
+	stats.TotalAlerts += stats.PrunedAlerts
+	stats.ResolvedAlerts += stats.PrunedAlerts
+	stats.CriticalAlerts += row.PrunedCritical
+	stats.HighAlerts += row.PrunedHigh
+	stats.MediumAlerts += row.PrunedMedium
+	stats.LowAlerts += row.PrunedLow
 	return &stats, nil
 }
 
 // GetActiveAlerts retrieves all active alerts
 func (r *GormAlertRepository) GetActiveAlerts(ctx context.Context) ([]models.Alert, error) {
 	var alerts []models.Alert
-	err := r.db.WithContext(ctx).Preload("Rule").Where("status = ?", "active").Order("created_at DESC").Find(&alerts).Error
-	return alerts, err
+	err := r.db.WithContext(ctx).Preload("Rule").Where("status = ?", constants.AlertStatusActive).Order("created_at DESC").Find(&alerts).Error
+	return alerts, database.TranslateError(err, "failed to get active alerts")
 }
 
 // ResolveAlert resolves an alert
 func (r *GormAlertRepository) ResolveAlert(ctx context.Context, id uint) error {
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"status":      "resolved",
+	result := r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      constants.AlertStatusResolved,
 		"resolved_at": &now,
 		"updated_at":  now,
-	}).Error
+	})
+	return checkAlertUpdate(result, "failed to resolve alert")
 }
 
 // AcknowledgeAlert acknowledges an alert
 func (r *GormAlertRepository) AcknowledgeAlert(ctx context.Context, id uint) error {
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"status":          "acknowledged",
+	result := r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          constants.AlertStatusAcknowledged,
 		"acknowledged_at": &now,
 		"updated_at":      now,
-	}).Error
+	})
+	return checkAlertUpdate(result, "failed to acknowledge alert")
+}
+
+// UpdateAlertsStatus resolves or acknowledges every alert matching the filter in a single transaction, so that
+// either all of them or none are updated. It returns the updated alerts with their rules.
+func (r *GormAlertRepository) UpdateAlertsStatus(ctx context.Context, filter *models.AlertFilter, status string) ([]models.Alert, error) {
+	column := "resolved_at"
+	if status == constants.AlertStatusAcknowledged {
+		column = "acknowledged_at"
+	}
+
+	var alerts []models.Alert
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := applyAlertFilter(tx.Model(&models.Alert{}), filter).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("alerts.id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Alert{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":     status,
+			column:       &now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Rule").Where("id IN ?", ids).Order("created_at DESC, id DESC").Find(&alerts).Error
+	})
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to update alerts")
+	}
+	return alerts, nil
+}
+
+// SnoozeAlert suppresses re-firing of the alert's rule until the given time
+func (r *GormAlertRepository) SnoozeAlert(ctx context.Context, id uint, until time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"snoozed_until": until,
+		"updated_at":    time.Now(),
+	})
+	return checkAlertUpdate(result, "failed to snooze alert")
+}
+
+// RecordNotification records that the alert's notification was sent at the given time
+func (r *GormAlertRepository) RecordNotification(ctx context.Context, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_notified_at":   at,
+		"notification_count": gorm.Expr("notification_count + 1"),
+		"updated_at":         time.Now(),
+	})
+	return checkAlertUpdate(result, "failed to record alert notification")
+}
+
+// PruneResolvedAlerts deletes up to limit alerts resolved before the given time, oldest first,
+// adding them to the stats rollups so alert counts survive. It returns the number of deleted alerts.
+func (r *GormAlertRepository) PruneResolvedAlerts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.Alert{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND resolved_at < ?", constants.AlertStatusResolved, before).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		upsert := "ON DUPLICATE KEY UPDATE alert_count = alert_count + pruned.pruned_count"
+		if database.IsPostgres(tx) {
+			upsert = "ON CONFLICT (day, rule_id, severity) DO UPDATE SET alert_count = alert_stats_rollups.alert_count + EXCLUDED.alert_count"
+		}
+		if err := tx.Exec(`INSERT INTO alert_stats_rollups (day, rule_id, severity, alert_count)
+			SELECT * FROM (
+				SELECT DATE(created_at) AS day, rule_id, severity, COUNT(*) AS pruned_count
+				FROM alerts WHERE id IN ? GROUP BY DATE(created_at), rule_id, severity
+			) AS pruned
+			`+upsert, ids).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id IN ?", ids).Delete(&models.Alert{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, database.TranslateError(err, "failed to prune resolved alerts")
+	}
+	return deleted, nil
+}
+
+// OptimizeAlertsTable rebuilds the alerts table to reclaim the space of deleted rows. Postgres vacuums it instead,
+// which makes the space reusable without locking the table.
+func (r *GormAlertRepository) OptimizeAlertsTable(ctx context.Context) error {
+	statement := "OPTIMIZE TABLE alerts"
+	if database.IsPostgres(r.db) {
+		statement = "VACUUM ANALYZE alerts"
+	}
+	return database.TranslateError(r.db.WithContext(ctx).Exec(statement).Error, "failed to optimize alerts table")
+}
+
+// checkAlertUpdate translates the result of a single-alert update, reporting missing alerts as not found
+func checkAlertUpdate(result *gorm.DB, message string) error {
+	if result.Error != nil {
+		return database.TranslateError(result.Error, message)
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Alert not found")
+	}
+	return nil
 }
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository defines the interface for export audit and audit record operations
+type AuditRepository interface {
+	// CreateExportAudit records an export request
+	CreateExportAudit(ctx context.Context, entry *models.ExportAudit) error
+	// GetExportAudits returns the export requests selected by the filter, newest first
+	GetExportAudits(ctx context.Context, filter *models.ExportAuditFilter) ([]models.ExportAudit, error)
+	// CreateAuditRecord records an API mutation
+	CreateAuditRecord(ctx context.Context, record *models.AuditRecord) error
+	// GetAuditRecords returns the API mutations selected by the filter, newest first
+	GetAuditRecords(ctx context.Context, filter *models.AuditRecordFilter) ([]models.AuditRecord, error)
+}
+
+// GormAuditRepository implements AuditRepository using GORM
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// CreateExportAudit records an export request
+func (r *GormAuditRepository) CreateExportAudit(ctx context.Context, entry *models.ExportAudit) error {
+	err := r.db.WithContext(ctx).Create(entry).Error
+	return database.TranslateError(err, "failed to record export audit")
+}
+
+// GetExportAudits returns the export requests selected by the filter, newest first
+func (r *GormAuditRepository) GetExportAudits(ctx context.Context, filter *models.ExportAuditFilter) ([]models.ExportAudit, error) {
+	query := r.db.WithContext(ctx).Model(&models.ExportAudit{})
+	if filter.Caller != nil {
+		query = query.Where("caller = ?", *filter.Caller)
+	}
+	if filter.Since != nil {
+		query = query.Where("started_at >= ?", *filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []models.ExportAudit
+	err := query.Order("id DESC").Find(&entries).Error
+	return entries, database.TranslateError(err, "failed to get export audits")
+}
+
+// CreateAuditRecord records an API mutation
+func (r *GormAuditRepository) CreateAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	err := r.db.WithContext(ctx).Create(record).Error
+	return database.TranslateError(err, "failed to record audit record")
+}
+
+// GetAuditRecords returns the API mutations selected by the filter, newest first
+func (r *GormAuditRepository) GetAuditRecords(ctx context.Context, filter *models.AuditRecordFilter) ([]models.AuditRecord, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditRecord{})
+	if filter.Caller != nil {
+		query = query.Where("caller = ?", *filter.Caller)
+	}
+	if filter.Resource != nil {
+		query = query.Where("resource = ?", *filter.Resource)
+	}
+	if filter.ResourceID != nil {
+		query = query.Where("resource_id = ?", *filter.ResourceID)
+	}
+	if filter.Action != nil {
+		query = query.Where("action = ?", *filter.Action)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var records []models.AuditRecord
+	err := query.Order("id DESC").Find(&records).Error
+	return records, database.TranslateError(err, "failed to get audit records")
+}
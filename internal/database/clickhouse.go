@@ -0,0 +1,305 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clickHouseTimeFormat is how time arguments are sent; columns are in UTC
+const clickHouseTimeFormat = "2006-01-02 15:04:05.000000"
+
+// clickHouseParamPrefix marks the URL parameters holding the values of query parameters
+const clickHouseParamPrefix = "param_"
+
+// clickHouseParamEscaper escapes the characters that the escaped format of parameter values reads specially
+var clickHouseParamEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+
+// clickHouseSettings are sent with every statement: times are read and written as RFC 3339, and 64-bit integers
+// are written as JSON numbers
+var clickHouseSettings = map[string]string{
+	"date_time_input_format":                  "best_effort",
+	"date_time_output_format":                 "iso",
+	"output_format_json_quote_64bit_integers": "0",
+	"input_format_skip_unknown_fields":        "1",
+}
+
+// ClickHouseDB is a client of ClickHouse's HTTP interface. Statements use ? placeholders, whose arguments are sent
+// as query parameters alongside the statement.
+type ClickHouseDB struct {
+	client   *http.Client
+	endpoint string
+	database string
+	username string
+	password string
+}
+
+// ClickHouseError is an error reported by the ClickHouse server
+type ClickHouseError struct {
+	Code    string // exception code, empty when the server didn't send one
+	Message string
+}
+
+func (e *ClickHouseError) Error() string {
+	if e.Code == "" {
+		return "clickhouse: " + e.Message
+	}
+	return fmt.Sprintf("clickhouse: code %s: %s", e.Code, e.Message)
+}
+
+// NewClickHouseDB creates a ClickHouse client and checks that the server is reachable
+func NewClickHouseDB(ctx context.Context, cfg *config.ClickHouseConfig) (*ClickHouseDB, error) {
+	db := &ClickHouseDB{
+		client:   &http.Client{Timeout: cfg.Timeout},
+		endpoint: strings.TrimSuffix(cfg.URL, "/") + "/",
+		database: cfg.Database,
+		username: cfg.Username,
+		password: cfg.Password,
+	}
+	if err := db.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	return db, nil
+}
+
+// Database returns the database statements run in by default
+func (db *ClickHouseDB) Database() string {
+	return db.database
+}
+
+// Ping checks if the server is accessible
+func (db *ClickHouseDB) Ping(ctx context.Context) error {
+	return db.Exec(ctx, "SELECT 1")
+}
+
+// Close releases the idle connections
+func (db *ClickHouseDB) Close() error {
+	db.client.CloseIdleConnections()
+	return nil
+}
+
+// Exec runs a statement returning no rows
+func (db *ClickHouseDB) Exec(ctx context.Context, statement string, args ...any) error {
+	statement, params, err := Bind(statement, args...)
+	if err != nil {
+		return err
+	}
+	body, err := db.do(ctx, strings.NewReader(statement), params)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// Mutate runs an ALTER TABLE UPDATE or DELETE and waits for the mutation to finish on all replicas
+func (db *ClickHouseDB) Mutate(ctx context.Context, statement string, args ...any) error {
+	statement, params, err := Bind(statement, args...)
+	if err != nil {
+		return err
+	}
+	params["mutations_sync"] = "2"
+	body, err := db.do(ctx, strings.NewReader(statement), params)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// Query runs a SELECT and decodes its rows into dest, a pointer to a slice of structs whose JSON field names match
+// the selected columns
+func (db *ClickHouseDB) Query(ctx context.Context, dest any, query string, args ...any) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("clickhouse: query destination must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+
+	query, params, err := Bind(query, args...)
+	if err != nil {
+		return err
+	}
+	body, err := db.do(ctx, strings.NewReader(query+" FORMAT JSONEachRow"), params)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	rows := reflect.MakeSlice(slice.Type(), 0, 0)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		row := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal(scanner.Bytes(), row.Interface()); err != nil {
+			return fmt.Errorf("clickhouse: failed to decode row: %w", err)
+		}
+		rows = reflect.Append(rows, row.Elem())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	slice.Set(rows)
+	return nil
+}
+
+// Insert inserts rows into a table in a single statement, encoding each row as a JSON object whose field names
+// match the table's columns. Columns missing from a row get their default.
+func (db *ClickHouseDB) Insert(ctx context.Context, table string, rows []any, settings map[string]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "INSERT INTO `%s` FORMAT JSONEachRow\n", table)
+	encoder := json.NewEncoder(&body)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("clickhouse: failed to encode row: %w", err)
+		}
+	}
+
+	response, err := db.do(ctx, &body, settings)
+	if err != nil {
+		return err
+	}
+	return response.Close()
+}
+
+// do sends a statement with its settings and query parameters, and returns the response body, which the caller
+// must close
+func (db *ClickHouseDB) do(ctx context.Context, statement io.Reader, settings map[string]string) (io.ReadCloser, error) {
+	params := url.Values{}
+	params.Set("database", db.database)
+	for key, value := range clickHouseSettings {
+		params.Set(key, value)
+	}
+	for key, value := range settings {
+		params.Set(key, value)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, db.endpoint+"?"+params.Encode(), statement)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-ClickHouse-User", db.username)
+	if db.password != "" {
+		request.Header.Set("X-ClickHouse-Key", db.password)
+	}
+
+	response, err := db.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return nil, &ClickHouseError{
+			Code:    response.Header.Get("X-ClickHouse-Exception-Code"),
+			Message: strings.TrimSpace(string(message)),
+		}
+	}
+	return response.Body, nil
+}
+
+// Bind replaces the ? placeholders of a statement with ClickHouse query parameters, {pN:Type}, returning the
+// statement and the parameter values to send along with it. The server substitutes the values after parsing the
+// statement, so they can't alter it. Placeholders inside string literals and quoted identifiers are left alone.
+// Slices are written as tuples of parameters, so that "IN ?" takes a slice as in GORM, and nil arguments as NULL.
+func Bind(statement string, args ...any) (string, map[string]string, error) {
+	var bound strings.Builder
+	params := make(map[string]string)
+	next := 0
+	var quote byte
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(statement) {
+				bound.WriteByte(c)
+				i++
+				c = statement[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '`' || c == '"':
+			quote = c
+		case c == '?':
+			if next == len(args) {
+				return "", nil, fmt.Errorf("clickhouse: statement has more placeholders than the %d arguments", len(args))
+			}
+			placeholder, err := bindClickHouseParam(params, reflect.ValueOf(args[next]))
+			if err != nil {
+				return "", nil, err
+			}
+			bound.WriteString(placeholder)
+			next++
+			continue
+		}
+		bound.WriteByte(c)
+	}
+	if next != len(args) {
+		return "", nil, fmt.Errorf("clickhouse: statement has %d placeholders but %d arguments", next, len(args))
+	}
+	return bound.String(), params, nil
+}
+
+// bindClickHouseParam adds a value to the parameters of a statement and returns the placeholder standing for it
+func bindClickHouseParam(params map[string]string, value reflect.Value) (string, error) {
+	if !value.IsValid() {
+		return "NULL", nil
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return addClickHouseParam(params, "DateTime64(6, 'UTC')", t.UTC().Format(clickHouseTimeFormat)), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return "NULL", nil
+		}
+		return bindClickHouseParam(params, value.Elem())
+	case reflect.String:
+		return addClickHouseParam(params, "String", value.String()), nil
+	case reflect.Bool:
+		return addClickHouseParam(params, "Bool", strconv.FormatBool(value.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return addClickHouseParam(params, "Int64", strconv.FormatInt(value.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return addClickHouseParam(params, "UInt64", strconv.FormatUint(value.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return addClickHouseParam(params, "Float64", strconv.FormatFloat(value.Float(), 'g', -1, 64)), nil
+	case reflect.Slice, reflect.Array:
+		// An empty tuple isn't valid SQL; (NULL) matches nothing, as GORM's expansion of empty slices
+		if value.Len() == 0 {
+			return "(NULL)", nil
+		}
+		elements := make([]string, value.Len())
+		for i := range elements {
+			placeholder, err := bindClickHouseParam(params, value.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elements[i] = placeholder
+		}
+		return "(" + strings.Join(elements, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("clickhouse: unsupported argument type %s", value.Type())
+	}
+}
+
+// addClickHouseParam adds a parameter of the type and returns its placeholder. Values are sent in the escaped
+// format ClickHouse reads parameters in.
+func addClickHouseParam(params map[string]string, dataType, value string) string {
+	name := "p" + strconv.Itoa(len(params))
+	params[clickHouseParamPrefix+name] = clickHouseParamEscaper.Replace(value)
+	return "{" + name + ":" + dataType + "}"
+}
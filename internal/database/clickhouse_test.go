@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/config"
+)
+
+func TestBind(t *testing.T) {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 890000000, time.FixedZone("CET", 3600))
+	service := "checkout"
+	var missing *string
+
+	tests := []struct {
+		name       string
+		statement  string
+		args       []any
+		want       string
+		wantParams map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "string",
+			statement:  "SELECT * FROM logs WHERE service = ?",
+			args:       []any{"checkout"},
+			want:       "SELECT * FROM logs WHERE service = {p0:String}",
+			wantParams: map[string]string{"param_p0": "checkout"},
+		},
+		{
+			name:       "quotes stay in the value",
+			statement:  "SELECT * FROM logs WHERE message = ?",
+			args:       []any{"x' OR 1=1 --"},
+			want:       "SELECT * FROM logs WHERE message = {p0:String}",
+			wantParams: map[string]string{"param_p0": "x' OR 1=1 --"},
+		},
+		{
+			name:       "escaped characters",
+			statement:  "SELECT ?",
+			args:       []any{"a\\b\tc\nd\re\x00"},
+			want:       "SELECT {p0:String}",
+			wantParams: map[string]string{"param_p0": `a\\b\tc\nd\re\0`},
+		},
+		{
+			name:       "numbers and booleans",
+			statement:  "SELECT ?, ?, ?, ?",
+			args:       []any{-3, uint64(18446744073709551615), 1.5, true},
+			want:       "SELECT {p0:Int64}, {p1:UInt64}, {p2:Float64}, {p3:Bool}",
+			wantParams: map[string]string{"param_p0": "-3", "param_p1": "18446744073709551615", "param_p2": "1.5", "param_p3": "true"},
+		},
+		{
+			name:       "time in UTC",
+			statement:  "SELECT * FROM logs WHERE timestamp >= ?",
+			args:       []any{at},
+			want:       "SELECT * FROM logs WHERE timestamp >= {p0:DateTime64(6, 'UTC')}",
+			wantParams: map[string]string{"param_p0": "2025-03-04 04:06:07.890000"},
+		},
+		{
+			name:       "pointers and nil",
+			statement:  "SELECT ?, ?, ?",
+			args:       []any{&service, missing, nil},
+			want:       "SELECT {p0:String}, NULL, NULL",
+			wantParams: map[string]string{"param_p0": "checkout"},
+		},
+		{
+			name:       "slice as tuple",
+			statement:  "SELECT * FROM logs WHERE id IN ? AND level = ?",
+			args:       []any{[]uint{1, 2}, "ERROR"},
+			want:       "SELECT * FROM logs WHERE id IN ({p0:UInt64}, {p1:UInt64}) AND level = {p2:String}",
+			wantParams: map[string]string{"param_p0": "1", "param_p1": "2", "param_p2": "ERROR"},
+		},
+		{
+			name:       "empty slice",
+			statement:  "SELECT * FROM logs WHERE id IN ?",
+			args:       []any{[]uint{}},
+			want:       "SELECT * FROM logs WHERE id IN (NULL)",
+			wantParams: map[string]string{},
+		},
+		{
+			name:       "placeholders in literals and identifiers",
+			statement:  "SELECT '?', `a?`, \"b?\", 'it\\'s ?' FROM logs WHERE level = ?",
+			args:       []any{"INFO"},
+			want:       "SELECT '?', `a?`, \"b?\", 'it\\'s ?' FROM logs WHERE level = {p0:String}",
+			wantParams: map[string]string{"param_p0": "INFO"},
+		},
+		{
+			name:      "too few arguments",
+			statement: "SELECT ?, ?",
+			args:      []any{1},
+			wantErr:   true,
+		},
+		{
+			name:      "too many arguments",
+			statement: "SELECT ?",
+			args:      []any{1, 2},
+			wantErr:   true,
+		},
+		{
+			name:      "unsupported argument",
+			statement: "SELECT ?",
+			args:      []any{map[string]string{}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, params, err := Bind(tt.statement, tt.args...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Bind() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Bind() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Bind() statement = %q, want %q", got, tt.want)
+			}
+			if !maps.Equal(params, tt.wantParams) {
+				t.Errorf("Bind() params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+// TestClickHouseQuerySendsParameters checks that arguments travel as URL parameters, never in the statement body
+func TestClickHouseQuerySendsParameters(t *testing.T) {
+	var statement string
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		statement, query = string(body), r.URL.Query()
+		io.WriteString(w, "{\"count\":2}\n")
+	}))
+	defer server.Close()
+
+	db, err := NewClickHouseDB(context.Background(), &config.ClickHouseConfig{URL: server.URL, Database: "logs", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	var rows []struct {
+		Count int `json:"count"`
+	}
+	if err := db.Query(context.Background(), &rows, "SELECT count() AS count FROM logs WHERE service = ?", "x'); DROP TABLE logs; --"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	if want := "SELECT count() AS count FROM logs WHERE service = {p0:String} FORMAT JSONEachRow"; statement != want {
+		t.Errorf("sent statement %q, want %q", statement, want)
+	}
+	if got := query["param_p0"]; len(got) != 1 || got[0] != "x'); DROP TABLE logs; --" {
+		t.Errorf("sent param_p0 %q, want the argument", got)
+	}
+	if got := query["database"]; len(got) != 1 || got[0] != "logs" {
+		t.Errorf("sent database %q, want logs", got)
+	}
+	if len(rows) != 1 || rows[0].Count != 2 {
+		t.Errorf("decoded rows %+v, want one row counting 2", rows)
+	}
+}
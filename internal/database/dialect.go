@@ -0,0 +1,40 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// IsPostgres reports whether a connection is to Postgres, whose SQL differs from MySQL's for full-text search,
+// JSON and date arithmetic
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// FullTextMatch returns the condition matching a text column against a search query, taken as its argument. MySQL
+// searches in boolean mode; Postgres parses the query as web search syntax, which has the same quoted phrases and
+// -exclusions.
+func FullTextMatch(db *gorm.DB, column string) string {
+	if IsPostgres(db) {
+		return fmt.Sprintf("to_tsvector('simple', %s) @@ websearch_to_tsquery('simple', ?)", column)
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST(? IN BOOLEAN MODE)", column)
+}
+
+// JSONContains returns the condition matching rows whose JSON object column holds a string value under a key, taking
+// the key and the value as its arguments
+func JSONContains(db *gorm.DB, column string) string {
+	if IsPostgres(db) {
+		return fmt.Sprintf("CAST(%s AS jsonb) @> jsonb_build_object(CAST(? AS text), CAST(? AS text))", column)
+	}
+	return fmt.Sprintf("JSON_CONTAINS(%s, JSON_OBJECT(?, ?))", column)
+}
+
+// SecondsBetween returns the expression of the seconds elapsed from start to end, including fractions of a second
+func SecondsBetween(db *gorm.DB, start, end string) string {
+	if IsPostgres(db) {
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s))", end, start)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(MICROSECOND, %s, %s) / 1000000", start, end)
+}
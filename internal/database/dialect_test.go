@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestDialectExpressions(t *testing.T) {
+	mysqlDB := &gorm.DB{Config: &gorm.Config{Dialector: mysql.Dialector{Config: &mysql.Config{}}}}
+	postgresDB := &gorm.DB{Config: &gorm.Config{Dialector: postgres.Dialector{Config: &postgres.Config{}}}}
+
+	tests := []struct {
+		name string
+		got  func(db *gorm.DB) string
+		want map[*gorm.DB]string
+	}{
+		{
+			name: "full-text match",
+			got:  func(db *gorm.DB) string { return FullTextMatch(db, "alerts.message") },
+			want: map[*gorm.DB]string{
+				mysqlDB:    "MATCH(alerts.message) AGAINST(? IN BOOLEAN MODE)",
+				postgresDB: "to_tsvector('simple', alerts.message) @@ websearch_to_tsquery('simple', ?)",
+			},
+		},
+		{
+			name: "JSON contains",
+			got:  func(db *gorm.DB) string { return JSONContains(db, "attributes") },
+			want: map[*gorm.DB]string{
+				mysqlDB:    "JSON_CONTAINS(attributes, JSON_OBJECT(?, ?))",
+				postgresDB: "CAST(attributes AS jsonb) @> jsonb_build_object(CAST(? AS text), CAST(? AS text))",
+			},
+		},
+		{
+			name: "seconds between",
+			got:  func(db *gorm.DB) string { return SecondsBetween(db, "created_at", "resolved_at") },
+			want: map[*gorm.DB]string{
+				mysqlDB:    "TIMESTAMPDIFF(MICROSECOND, created_at, resolved_at) / 1000000",
+				postgresDB: "EXTRACT(EPOCH FROM (resolved_at - created_at))",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for db, want := range tt.want {
+				if got := tt.got(db); got != want {
+					t.Errorf("%s: got %q, want %q", db.Dialector.Name(), got, want)
+				}
+			}
+		})
+	}
+
+	if IsPostgres(mysqlDB) || !IsPostgres(postgresDB) {
+		t.Error("IsPostgres doesn't tell the dialects apart")
+	}
+}
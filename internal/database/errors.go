@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number for unique key violations
+const mysqlErrDuplicateEntry = 1062
+
+// pgErrUniqueViolation is the Postgres SQLSTATE of unique key violations
+const pgErrUniqueViolation = "23505"
+
+// TranslateError converts a database error into a typed application error
+func TranslateError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var mysqlErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperrors.Wrap(apperrors.CodeNotFound, err, "Record not found")
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry,
+		errors.As(err, &pgErr) && pgErr.Code == pgErrUniqueViolation:
+		return apperrors.Wrap(apperrors.CodeConflict, err, "Record already exists")
+	case errors.Is(err, mysql.ErrInvalidConn), errors.Is(err, driver.ErrBadConn), errors.As(err, &netErr),
+		errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return apperrors.Wrap(apperrors.CodeUnavailable, err, "Database unavailable")
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+}
@@ -0,0 +1,165 @@
+package fingerprints
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FingerprintRepository defines the interface for fingerprint backfill job operations
+type FingerprintRepository interface {
+	// CreateBackfill creates a running job unless another job is running. A running job whose progress was last
+	// recorded before staleBefore was abandoned by its API server and is marked failed instead of blocking the new one.
+	CreateBackfill(ctx context.Context, backfill *models.FingerprintBackfill, staleBefore time.Time) error
+	// GetLatestBackfill returns the most recently started job
+	GetLatestBackfill(ctx context.Context) (*models.FingerprintBackfill, error)
+	// RecordBackfillProgress stores the position and counts of a running job; it fails with a conflict once the
+	// job is no longer running
+	RecordBackfillProgress(ctx context.Context, backfill *models.FingerprintBackfill) error
+	// FinishBackfill stores the final status, position and counts of a running job
+	FinishBackfill(ctx context.Context, backfill *models.FingerprintBackfill) error
+	// CancelBackfill marks the running job cancelled and returns it
+	CancelBackfill(ctx context.Context) (*models.FingerprintBackfill, error)
+}
+
+// GormFingerprintRepository implements FingerprintRepository using GORM
+type GormFingerprintRepository struct {
+	db *gorm.DB
+}
+
+// NewFingerprintRepository creates a new fingerprint repository
+func NewFingerprintRepository(db *gorm.DB) FingerprintRepository {
+	return &GormFingerprintRepository{db: db}
+}
+
+// CreateBackfill creates a running job, locking the running ones so that concurrent requests start a single job
+func (r *GormFingerprintRepository) CreateBackfill(ctx context.Context, backfill *models.FingerprintBackfill, staleBefore time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var running []models.FingerprintBackfill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", constants.FingerprintBackfillRunning).
+			Find(&running).Error; err != nil {
+			return err
+		}
+		for _, job := range running {
+			if job.UpdatedAt.After(staleBefore) {
+				return apperrors.Conflict("Fingerprint backfill %d is already running", job.ID)
+			}
+			if err := tx.Model(&job).UpdateColumns(map[string]interface{}{
+				"status":      constants.FingerprintBackfillFailed,
+				"error":       "interrupted",
+				"finished_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(backfill).Error
+	})
+	return database.TranslateError(err, "failed to create fingerprint backfill")
+}
+
+// GetLatestBackfill returns the most recently started job
+func (r *GormFingerprintRepository) GetLatestBackfill(ctx context.Context) (*models.FingerprintBackfill, error) {
+	var backfill models.FingerprintBackfill
+	err := r.db.WithContext(ctx).Order("id DESC").First(&backfill).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("No fingerprint backfill has been started")
+		}
+		return nil, database.TranslateError(err, "failed to get fingerprint backfill")
+	}
+	return &backfill, nil
+}
+
+// RecordBackfillProgress stores the position and counts of a running job
+func (r *GormFingerprintRepository) RecordBackfillProgress(ctx context.Context, backfill *models.FingerprintBackfill) error {
+	backfill.UpdatedAt = time.Now()
+	result := r.db.WithContext(ctx).Model(&models.FingerprintBackfill{}).
+		Where("id = ? AND status = ?", backfill.ID, constants.FingerprintBackfillRunning).
+		UpdateColumns(map[string]interface{}{
+			"cursor":     backfill.Cursor,
+			"processed":  backfill.Processed,
+			"updated":    backfill.Updated,
+			"updated_at": backfill.UpdatedAt,
+		})
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to record fingerprint backfill progress")
+	}
+	if result.RowsAffected == 0 {
+		return r.ensureRunning(ctx, backfill.ID)
+	}
+	return nil
+}
+
+// FinishBackfill stores the final status, position and counts of a running job
+func (r *GormFingerprintRepository) FinishBackfill(ctx context.Context, backfill *models.FingerprintBackfill) error {
+	now := time.Now()
+	backfill.UpdatedAt = now
+	backfill.FinishedAt = &now
+	result := r.db.WithContext(ctx).Model(&models.FingerprintBackfill{}).
+		Where("id = ? AND status = ?", backfill.ID, constants.FingerprintBackfillRunning).
+		UpdateColumns(map[string]interface{}{
+			"status":      backfill.Status,
+			"cursor":      backfill.Cursor,
+			"processed":   backfill.Processed,
+			"updated":     backfill.Updated,
+			"error":       backfill.Error,
+			"updated_at":  now,
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to finish fingerprint backfill")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.Conflict("Fingerprint backfill %d is no longer running", backfill.ID)
+	}
+	return nil
+}
+
+// CancelBackfill marks the running job cancelled; the API server running it stops after its current batch
+func (r *GormFingerprintRepository) CancelBackfill(ctx context.Context) (*models.FingerprintBackfill, error) {
+	var backfill models.FingerprintBackfill
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", constants.FingerprintBackfillRunning).
+			Order("id DESC").
+			First(&backfill).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		backfill.Status = constants.FingerprintBackfillCancelled
+		backfill.FinishedAt = &now
+		return tx.Model(&backfill).UpdateColumns(map[string]interface{}{
+			"status":      backfill.Status,
+			"finished_at": now,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("No fingerprint backfill is running")
+		}
+		return nil, database.TranslateError(err, "failed to cancel fingerprint backfill")
+	}
+	return &backfill, nil
+}
+
+// ensureRunning fails with a conflict unless the job is still running. An update matching a running job may
+// affect no rows when it changes nothing, so the status is checked separately.
+func (r *GormFingerprintRepository) ensureRunning(ctx context.Context, id uint) error {
+	var status string
+	err := r.db.WithContext(ctx).Model(&models.FingerprintBackfill{}).Where("id = ?", id).Select("status").Scan(&status).Error
+	if err != nil {
+		return database.TranslateError(err, "failed to get fingerprint backfill")
+	}
+	if status != constants.FingerprintBackfillRunning {
+		return apperrors.Conflict("Fingerprint backfill %d is no longer running", id)
+	}
+	return nil
+}
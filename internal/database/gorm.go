@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/models"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -17,18 +19,39 @@ type GormDB struct {
 	db *gorm.DB
 }
 
-// NewGormDB creates a new GORM database connection
+// NewGormDB creates a new GORM database connection with the configured driver
 func NewGormDB(cfg *config.DatabaseConfig) (*GormDB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return openGormDB(cfg.DSN(cfg.Database), cfg,
+		&models.Log{},
+		&models.AlertRule{},
+		&models.Alert{},
+		&models.MaintenanceState{},
+	)
+}
+
+// NewLogShardDB creates a connection to a separate database holding routed logs only. The DSN is in the format
+// of the configured driver.
+func NewLogShardDB(dsn string, cfg *config.DatabaseConfig) (*GormDB, error) {
+	return openGormDB(dsn, cfg, &models.Log{})
+}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+// openGormDB opens a connection with the configured pool settings and migrates the given models
+func openGormDB(dsn string, cfg *config.DatabaseConfig, migrate ...interface{}) (*GormDB, error) {
+	db, err := gorm.Open(dialector(cfg.Driver, dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := registerQueryTrace(db); err != nil {
+		return nil, fmt.Errorf("failed to register query tracing: %w", err)
+	}
+
 	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -40,17 +63,21 @@ func NewGormDB(cfg *config.DatabaseConfig) (*GormDB, error) {
 	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	// Auto migrate tables
-	if err := db.AutoMigrate(
-		&models.Log{},
-		&models.AlertRule{},
-		&models.Alert{},
-	); err != nil {
+	if err := db.AutoMigrate(migrate...); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
 	return &GormDB{db: db}, nil
 }
 
+// dialector returns the GORM dialector of the database driver
+func dialector(driver, dsn string) gorm.Dialector {
+	if driver == constants.DBDriverPostgres {
+		return postgres.Open(dsn)
+	}
+	return mysql.Open(dsn)
+}
+
 // Close closes the database connection
 func (g *GormDB) Close() error {
 	sqlDB, err := g.db.DB()
@@ -0,0 +1,846 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// clickHouseLogTable is the structure of the logs table in ClickHouse. Logs are partitioned by day, so retention
+// purges and time range scans only touch the partitions of their range, and sorted by service, level and time,
+// which most filters and aggregates follow. Columns mirror the MySQL table so that alert rule conditions run
+// unchanged.
+const clickHouseLogTable = "CREATE TABLE IF NOT EXISTS `%s` (" + `
+	id UInt64,
+	timestamp DateTime64(3, 'UTC'),
+	level LowCardinality(String),
+	service LowCardinality(String),
+	tenant Nullable(String),
+	host Nullable(String),
+	environment LowCardinality(Nullable(String)),
+	region LowCardinality(Nullable(String)),
+	client_ip Nullable(String),
+	message String,
+	fingerprint Nullable(String),
+	trace_id Nullable(String),
+	span_id Nullable(String),
+	parent_span_id Nullable(String),
+	message_id Nullable(String),
+	user_id Nullable(String),
+	request_method LowCardinality(Nullable(String)),
+	request_path Nullable(String),
+	response_status Nullable(Int32),
+	response_time_ms Nullable(Int32),
+	request_body Nullable(String),
+	response_body Nullable(String),
+	attributes Map(String, String),
+	created_at DateTime64(3, 'UTC'),
+	INDEX idx_trace_id trace_id TYPE bloom_filter GRANULARITY 4,
+	INDEX idx_message_id message_id TYPE bloom_filter GRANULARITY 4,
+	INDEX idx_user_id user_id TYPE bloom_filter GRANULARITY 4,
+	INDEX idx_message lower(message) TYPE tokenbf_v1(32768, 3, 0) GRANULARITY 4
+) ENGINE = MergeTree
+PARTITION BY toYYYYMMDD(timestamp)
+ORDER BY (service, level, timestamp)`
+
+// clickHouseAddedLogColumns are the columns added to the logs table since it was first created, added on startup
+// to tables created before them
+var clickHouseAddedLogColumns = []string{
+	"span_id Nullable(String) AFTER trace_id",
+	"parent_span_id Nullable(String) AFTER span_id",
+}
+
+// ClickHouseLogRepository stores logs in ClickHouse. Batches are inserted in as few statements as possible, and
+// statistics are computed with ClickHouse's aggregate functions; percentiles are exact.
+//
+// ClickHouse has no auto-increment, so IDs are assigned on insert from the microsecond clock, increasing within
+// each writer. As with MySQL, logs inserted concurrently by several writers may be skipped by cursors.
+type ClickHouseLogRepository struct {
+	db        *database.ClickHouseDB
+	table     string
+	batchSize int
+	lastID    atomic.Uint64
+}
+
+// NewClickHouseLogRepository connects to ClickHouse and creates a log repository backed by its logs table, creating
+// the table if it doesn't exist and adding the columns it lacks
+func NewClickHouseLogRepository(ctx context.Context, cfg *config.ClickHouseConfig) (*ClickHouseLogRepository, error) {
+	db, err := database.NewClickHouseDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &ClickHouseLogRepository{db: db, table: constants.DefaultLogsTable, batchSize: cfg.BatchSize}
+	if err := db.Exec(ctx, fmt.Sprintf(clickHouseLogTable, r.table)); err != nil {
+		db.Close()
+		return nil, database.TranslateError(err, "failed to create ClickHouse log table")
+	}
+	for _, column := range clickHouseAddedLogColumns {
+		if err := db.Exec(ctx, fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN IF NOT EXISTS %s", r.table, column)); err != nil {
+			db.Close()
+			return nil, database.TranslateError(err, "failed to add ClickHouse log table columns")
+		}
+	}
+	return r, nil
+}
+
+// Close closes the connection to ClickHouse
+func (r *ClickHouseLogRepository) Close() error {
+	return r.db.Close()
+}
+
+// Ping checks that ClickHouse is reachable
+func (r *ClickHouseLogRepository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// nextID returns an ID greater than those previously assigned by this repository
+func (r *ClickHouseLogRepository) nextID() uint {
+	for {
+		last := r.lastID.Load()
+		id := max(uint64(time.Now().UnixMicro()), last+1)
+		if r.lastID.CompareAndSwap(last, id) {
+			return uint(id)
+		}
+	}
+}
+
+// CreateLog inserts a new log entry. Single logs are buffered by the server's asynchronous inserts, which the
+// call waits for.
+func (r *ClickHouseLogRepository) CreateLog(ctx context.Context, log *models.Log) error {
+	log.ID = r.nextID()
+	log.CreatedAt = time.Now()
+	err := r.db.Insert(ctx, r.table, []any{log}, map[string]string{"async_insert": "1", "wait_for_async_insert": "1"})
+	return database.TranslateError(err, "failed to create log")
+}
+
+// CreateLogBatch inserts multiple log entries in statements of up to the batch size, skipping those whose message
+// ID is already stored
+func (r *ClickHouseLogRepository) CreateLogBatch(ctx context.Context, logs []*models.Log) error {
+	logs, err := r.withoutStoredMessages(ctx, logs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for batch := range slices.Chunk(logs, r.batchSize) {
+		rows := make([]any, len(batch))
+		for i, log := range batch {
+			log.ID = r.nextID()
+			log.CreatedAt = now
+			rows[i] = log
+		}
+		if err := r.db.Insert(ctx, r.table, rows, nil); err != nil {
+			return database.TranslateError(err, "failed to create log batch")
+		}
+	}
+	return nil
+}
+
+// withoutStoredMessages returns the logs whose message ID isn't stored yet. ClickHouse has no unique keys, so
+// this check is all that keeps redeliveries from being stored twice.
+func (r *ClickHouseLogRepository) withoutStoredMessages(ctx context.Context, logs []*models.Log) ([]*models.Log, error) {
+	var ids []string
+	for _, log := range logs {
+		if log.MessageID != nil {
+			ids = append(ids, *log.MessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return logs, nil
+	}
+
+	var rows []struct {
+		MessageID string `json:"message_id"`
+	}
+	if err := r.query(ctx, &rows, "SELECT DISTINCT message_id FROM %s WHERE message_id IN ?", ids); err != nil {
+		return nil, database.TranslateError(err, "failed to check stored message IDs")
+	}
+	if len(rows) == 0 {
+		return logs, nil
+	}
+	stored := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		stored[row.MessageID] = true
+	}
+	return slices.DeleteFunc(slices.Clone(logs), func(log *models.Log) bool {
+		return log.MessageID != nil && stored[*log.MessageID]
+	}), nil
+}
+
+// query runs a SELECT whose %s verbs are replaced with the quoted table name
+func (r *ClickHouseLogRepository) query(ctx context.Context, dest any, query string, args ...any) error {
+	table := "`" + r.table + "`"
+	return r.db.Query(ctx, dest, strings.ReplaceAll(query, "%s", table), args...)
+}
+
+// clickHouseConditions collects the conditions of a WHERE clause and their arguments
+type clickHouseConditions struct {
+	clauses []string
+	args    []any
+}
+
+// add appends a condition
+func (c *clickHouseConditions) add(clause string, args ...any) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+// where returns the WHERE clause, which is empty without conditions
+func (c *clickHouseConditions) where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// addLogFilter adds the filter conditions
+func (c *clickHouseConditions) addLogFilter(filter *models.LogFilter) {
+	if filter.Level != nil {
+		c.add("level = ?", *filter.Level)
+	}
+	if filter.Service != nil {
+		c.add("service = ?", *filter.Service)
+	}
+	c.addLogDimensions(filter.LogDimensions)
+	if filter.TraceID != nil {
+		c.add("trace_id = ?", *filter.TraceID)
+	}
+	if filter.SpanID != nil {
+		c.add("span_id = ?", *filter.SpanID)
+	}
+	if filter.ParentSpanID != nil {
+		c.add("parent_span_id = ?", *filter.ParentSpanID)
+	}
+	if filter.UserID != nil {
+		c.add("user_id = ?", *filter.UserID)
+	}
+	if filter.StartTime != nil {
+		c.add("timestamp >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		c.add("timestamp <= ?", *filter.EndTime)
+	}
+	if filter.Search != nil {
+		c.addSearch(*filter.Search)
+	}
+	if filter.Fingerprint != nil {
+		c.add("fingerprint = ?", *filter.Fingerprint)
+	}
+	if filter.HasRequestBody != nil {
+		c.add(nullCondition("request_body", !*filter.HasRequestBody))
+	}
+	if filter.HasResponseBody != nil {
+		c.add(nullCondition("response_body", !*filter.HasResponseBody))
+	}
+	for _, key := range slices.Sorted(maps.Keys(filter.Attributes)) {
+		c.add("attributes[?] = ?", key, filter.Attributes[key])
+	}
+}
+
+// addLogDimensions adds the dimension conditions
+func (c *clickHouseConditions) addLogDimensions(dimensions models.LogDimensions) {
+	c.addTenant(dimensions.Tenant)
+	if dimensions.Host != nil {
+		c.add("host = ?", *dimensions.Host)
+	}
+	if dimensions.Environment != nil {
+		c.add("environment = ?", *dimensions.Environment)
+	}
+	if dimensions.Region != nil {
+		c.add("region = ?", *dimensions.Region)
+	}
+	if dimensions.ClientIP != nil {
+		c.add("client_ip = ?", *dimensions.ClientIP)
+	}
+}
+
+// addTenant restricts the logs to a tenant, when one is given
+func (c *clickHouseConditions) addTenant(tenant *string) {
+	if tenant != nil {
+		c.add("tenant = ?", *tenant)
+	}
+}
+
+// addSearch approximates MySQL's boolean full-text search: every word of the search must appear in the message,
+// ignoring case, except words prefixed with - which must not. Quotes and the + and * operators are ignored.
+func (c *clickHouseConditions) addSearch(search string) {
+	for _, word := range strings.Fields(search) {
+		exclude := strings.HasPrefix(word, "-")
+		word = strings.Trim(word, `+-*"()<>~`)
+		if word == "" {
+			continue
+		}
+		if exclude {
+			c.add("positionCaseInsensitiveUTF8(message, ?) = 0", word)
+		} else {
+			c.add("positionCaseInsensitiveUTF8(message, ?) > 0", word)
+		}
+	}
+}
+
+// GetLogs retrieves logs based on filters
+func (r *ClickHouseLogRepository) GetLogs(ctx context.Context, filter *models.LogFilter) ([]*models.Log, error) {
+	var conditions clickHouseConditions
+	conditions.addLogFilter(filter)
+	columns := "*"
+	if len(filter.Fields) > 0 {
+		columns = strings.Join(filter.Fields, ", ")
+	}
+	query := "SELECT " + columns + " FROM %s" + conditions.where() + " ORDER BY timestamp DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+		if filter.Offset > 0 {
+			query += " OFFSET " + strconv.Itoa(filter.Offset)
+		}
+	}
+
+	var logs []*models.Log
+	if err := r.query(ctx, &logs, query, conditions.args...); err != nil {
+		return nil, database.TranslateError(err, "failed to get logs")
+	}
+	return logs, nil
+}
+
+// GetLogStats retrieves aggregated log statistics
+func (r *ClickHouseLogRepository) GetLogStats(ctx context.Context, startTime, endTime time.Time, dimensions models.LogDimensions, alignment models.BucketAlignment) (*models.LogStats, error) {
+	var conditions clickHouseConditions
+	conditions.addLogDimensions(dimensions)
+	conditions.add("timestamp BETWEEN ? AND ?", startTime, endTime)
+	where := conditions.where()
+
+	var totals []*models.LogStats
+	err := r.query(ctx, &totals, `
+		SELECT
+			count() AS total_logs,
+			countIf(level = 'ERROR') AS error_count,
+			countIf(level = 'WARN') AS warning_count,
+			countIf(level = 'INFO') AS info_count,
+			countIf(level = 'DEBUG') AS debug_count,
+			countIf(level = 'FATAL') AS fatal_count,
+			ifNull(avg(response_time_ms), 0) AS avg_response_time,
+			countIf(response_status BETWEEN 200 AND 299) AS status_2xx_count,
+			countIf(response_status BETWEEN 300 AND 399) AS status_3xx_count,
+			countIf(response_status BETWEEN 400 AND 499) AS status_4xx_count,
+			countIf(response_status BETWEEN 500 AND 599) AS status_5xx_count,
+			quantileExactLow(0.50)(response_time_ms) AS p50_response_time,
+			quantileExactLow(0.95)(response_time_ms) AS p95_response_time,
+			quantileExactLow(0.99)(response_time_ms) AS p99_response_time
+		FROM %s`+where, conditions.args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get log stats")
+	}
+	stats := &models.LogStats{}
+	if len(totals) > 0 {
+		stats = totals[0]
+	}
+
+	stats.ServiceLatency = []models.ServiceLatency{}
+	err = r.query(ctx, &stats.ServiceLatency, `
+		SELECT
+			service,
+			count() AS count,
+			quantileExactLow(0.50)(response_time_ms) AS p50_response_time,
+			quantileExactLow(0.95)(response_time_ms) AS p95_response_time,
+			quantileExactLow(0.99)(response_time_ms) AS p99_response_time
+		FROM %s`+where+` AND response_time_ms IS NOT NULL
+		GROUP BY service`, conditions.args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get response time percentiles")
+	}
+
+	err = r.query(ctx, &stats.TopServices, `
+		SELECT service, count() AS count
+		FROM %s`+where+`
+		GROUP BY service
+		ORDER BY count DESC
+		LIMIT ?`, append(conditions.args, topStatsLimit)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get service stats")
+	}
+
+	err = r.query(ctx, &stats.TopErrors, `
+		SELECT message, count() AS count
+		FROM %s`+where+` AND level IN ('ERROR', 'FATAL')
+		GROUP BY message
+		ORDER BY count DESC
+		LIMIT ?`, append(conditions.args, topStatsLimit)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get error stats")
+	}
+
+	// The first bucket starts at its aligned boundary but only counts logs from the start of the range
+	interval := TimeSeriesIntervalFor(startTime, endTime, constants.MetricsTimeSeriesPoints)
+	stats.TimeSeries, err = r.getLogTimeSeries(ctx, "", dimensions, alignment.Truncate(startTime, interval), startTime, endTime, interval)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetServiceComparison computes the figures of every requested service in a single grouped query
+func (r *ClickHouseLogRepository) GetServiceComparison(ctx context.Context, services []string, tenant *string, startTime, endTime time.Time) ([]models.ServiceComparison, error) {
+	if len(services) == 0 {
+		return []models.ServiceComparison{}, nil
+	}
+
+	var conditions clickHouseConditions
+	conditions.addTenant(tenant)
+	conditions.add("service IN ? AND timestamp BETWEEN ? AND ?", services, startTime, endTime)
+
+	var comparisons []models.ServiceComparison
+	err := r.query(ctx, &comparisons, `
+		SELECT
+			service,
+			count() AS total_logs,
+			countIf(level IN ('ERROR', 'FATAL')) AS error_count,
+			countIf(response_status BETWEEN 400 AND 499) AS status_4xx_count,
+			countIf(response_status BETWEEN 500 AND 599) AS status_5xx_count,
+			avg(response_time_ms) AS avg_response_time,
+			quantileExactLow(0.50)(response_time_ms) AS p50_response_time,
+			quantileExactLow(0.95)(response_time_ms) AS p95_response_time,
+			quantileExactLow(0.99)(response_time_ms) AS p99_response_time
+		FROM %s`+conditions.where()+`
+		GROUP BY service`, conditions.args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get service comparison")
+	}
+	return comparisons, nil
+}
+
+// GetSlowRequests computes the figures of every endpoint in a single grouped query
+func (r *ClickHouseLogRepository) GetSlowRequests(ctx context.Context, query *models.SlowRequestQuery) ([]models.SlowRequest, error) {
+	var conditions clickHouseConditions
+	conditions.addTenant(query.Tenant)
+	if query.Service != "" {
+		conditions.add("service = ?", query.Service)
+	}
+	conditions.add("timestamp BETWEEN ? AND ?", query.StartTime, query.EndTime)
+	conditions.add("request_method IS NOT NULL AND request_path IS NOT NULL AND response_time_ms IS NOT NULL")
+
+	var requests []models.SlowRequest
+	err := r.query(ctx, &requests, `
+		SELECT
+			request_method,
+			request_path,
+			count() AS count,
+			avg(response_time_ms) AS avg_response_time,
+			quantileExactLow(0.95)(response_time_ms) AS p95_response_time,
+			max(response_time_ms) AS max_response_time
+		FROM %s`+conditions.where()+`
+		GROUP BY request_method, request_path
+		HAVING count >= ?
+		ORDER BY `+slowRequestsOrder(query.Sort)+`
+		LIMIT `+strconv.Itoa(query.Limit), append(conditions.args, query.MinCount)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get slow requests")
+	}
+	return requests, nil
+}
+
+// AggregateLogs groups and aggregates the logs in a single grouped query
+func (r *ClickHouseLogRepository) AggregateLogs(ctx context.Context, aggregation *models.LogAggregation) ([]models.LogGroup, error) {
+	var conditions clickHouseConditions
+	conditions.addLogFilter(&aggregation.Filter)
+
+	// Group by fields are checked against models.LogGroupByFields, so they are safe to use as column names
+	columns := strings.Join(aggregation.GroupBy, ", ")
+	var rows []logGroupRow
+	err := r.query(ctx, &rows, `
+		SELECT
+			`+columns+`,
+			count() AS count,
+			sum(response_time_ms) AS response_time_sum,
+			count(response_time_ms) AS response_time_count,
+			max(response_time_ms) AS max_response_time
+		FROM %s`+conditions.where()+`
+		GROUP BY `+columns+`
+		ORDER BY `+logAggregationOrder(aggregation)+`
+		LIMIT `+strconv.Itoa(aggregation.Limit), conditions.args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to aggregate logs")
+	}
+	return logGroups(rows, aggregation), nil
+}
+
+// GetLogTimeSeries counts logs per bucket in a single grouped query and fills in empty buckets.
+// Logs exactly at endTime are counted in the last bucket.
+func (r *ClickHouseLogRepository) GetLogTimeSeries(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error) {
+	return r.getLogTimeSeries(ctx, service, models.LogDimensions{Tenant: tenant}, startTime, startTime, endTime, interval)
+}
+
+// getLogTimeSeries counts the logs matching the dimensions per bucket, optionally for a single service. Buckets
+// start at bucketStart, which may precede startTime so that bucket boundaries stay aligned.
+func (r *ClickHouseLogRepository) getLogTimeSeries(ctx context.Context, service string, dimensions models.LogDimensions, bucketStart, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error) {
+	var conditions clickHouseConditions
+	conditions.addLogDimensions(dimensions)
+	conditions.add("timestamp BETWEEN ? AND ?", startTime, endTime)
+	if service != "" {
+		conditions.add("service = ?", service)
+	}
+
+	var rows []struct {
+		Bucket int `json:"bucket"`
+		models.TimeSeriesData
+	}
+	err := r.query(ctx, &rows, `
+		SELECT
+			intDiv(dateDiff('second', toDateTime64(?, 3, 'UTC'), timestamp), ?) AS bucket,
+			count() AS count,
+			countIf(level = 'DEBUG') AS debug_count,
+			countIf(level = 'INFO') AS info_count,
+			countIf(level = 'WARN') AS warning_count,
+			countIf(level = 'ERROR') AS error_count,
+			countIf(level = 'FATAL') AS fatal_count
+		FROM %s`+conditions.where()+`
+		GROUP BY bucket`, append([]any{bucketStart, int64(interval / time.Second)}, conditions.args...)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get log time series")
+	}
+
+	series := newTimeSeries(bucketStart, endTime, interval)
+	for _, row := range rows {
+		bucket := min(max(row.Bucket, 0), len(series)-1)
+		series[bucket].Add(row.TimeSeriesData)
+	}
+	return series, nil
+}
+
+// GetLatencyHeatmap buckets the service's logs carrying a response time in a single grouped query
+func (r *ClickHouseLogRepository) GetLatencyHeatmap(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration, bounds []int) ([]models.LatencyHeatmapCell, error) {
+	args := []any{startTime, int64(interval / time.Second)}
+	var latencyBucket strings.Builder
+	latencyBucket.WriteString("CASE")
+	for i, bound := range bounds {
+		fmt.Fprintf(&latencyBucket, " WHEN response_time_ms < ? THEN %d", i)
+		args = append(args, bound)
+	}
+	fmt.Fprintf(&latencyBucket, " ELSE %d END", len(bounds))
+
+	var conditions clickHouseConditions
+	conditions.addTenant(tenant)
+	conditions.add("service = ? AND timestamp BETWEEN ? AND ? AND response_time_ms IS NOT NULL", service, startTime, endTime)
+
+	var cells []models.LatencyHeatmapCell
+	err := r.query(ctx, &cells, `
+		SELECT
+			intDiv(dateDiff('second', toDateTime64(?, 3, 'UTC'), timestamp), ?) AS time_bucket,
+			`+latencyBucket.String()+` AS latency_bucket,
+			count() AS count
+		FROM %s`+conditions.where()+`
+		GROUP BY time_bucket, latency_bucket`, append(args, conditions.args...)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get latency heatmap")
+	}
+	return cells, nil
+}
+
+// GetLogsByTraceID retrieves all logs for a specific trace ID
+func (r *ClickHouseLogRepository) GetLogsByTraceID(ctx context.Context, traceID string, tenant *string) ([]*models.Log, error) {
+	var conditions clickHouseConditions
+	conditions.addTenant(tenant)
+	conditions.add("trace_id = ?", traceID)
+
+	var logs []*models.Log
+	if err := r.query(ctx, &logs, "SELECT * FROM %s"+conditions.where()+" ORDER BY timestamp ASC", conditions.args...); err != nil {
+		return nil, database.TranslateError(err, "failed to get logs by trace ID")
+	}
+	return logs, nil
+}
+
+// GetTraces rolls up the logs of every trace in a single grouped query
+func (r *ClickHouseLogRepository) GetTraces(ctx context.Context, query *models.TraceQuery) ([]models.TraceRollup, error) {
+	var conditions clickHouseConditions
+	conditions.addTenant(query.Tenant)
+	conditions.add("trace_id IS NOT NULL AND trace_id != ''")
+	conditions.add("timestamp BETWEEN ? AND ?", query.StartTime, query.EndTime)
+	if query.Service != "" {
+		conditions.add("trace_id IN (SELECT trace_id FROM %s WHERE service = ? AND timestamp BETWEEN ? AND ?)",
+			query.Service, query.StartTime, query.EndTime)
+	}
+	having := ""
+	if query.ErrorsOnly {
+		having = " HAVING error_count > 0"
+	}
+
+	var traces []models.TraceRollup
+	err := r.query(ctx, &traces, `
+		SELECT
+			trace_id,
+			min(timestamp) AS start_time,
+			max(timestamp) AS end_time,
+			count() AS log_count,
+			uniqExact(service) AS service_count,
+			countIf(level IN ?) AS error_count,
+			max(response_time_ms) AS max_response_time
+		FROM %s`+conditions.where()+`
+		GROUP BY trace_id`+having+`
+		ORDER BY end_time DESC, trace_id
+		LIMIT `+strconv.Itoa(query.Limit)+` OFFSET `+strconv.Itoa(query.Offset), append([]any{errorLevels}, conditions.args...)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get traces")
+	}
+	for i := range traces {
+		traces[i].SetDuration()
+	}
+	return traces, nil
+}
+
+// GetLogCursor returns the ID of the most recently stored log as cursor
+func (r *ClickHouseLogRepository) GetLogCursor(ctx context.Context) (string, error) {
+	var rows []struct {
+		ID uint64 `json:"id"`
+	}
+	if err := r.query(ctx, &rows, "SELECT max(id) AS id FROM %s"); err != nil {
+		return "", database.TranslateError(err, "failed to get log cursor")
+	}
+	var lastID uint64
+	if len(rows) > 0 {
+		lastID = rows[0].ID
+	}
+	return strconv.FormatUint(lastID, 10), nil
+}
+
+// GetLogsAfterCursor retrieves logs with an ID greater than the cursor, oldest first
+func (r *ClickHouseLogRepository) GetLogsAfterCursor(ctx context.Context, filter *models.LogFilter, cursor string) ([]*models.Log, string, error) {
+	afterID, err := parseIDCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var conditions clickHouseConditions
+	conditions.addLogFilter(filter)
+	conditions.add("id > ?", afterID)
+	query := "SELECT * FROM %s" + conditions.where() + " ORDER BY id ASC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	var logs []*models.Log
+	if err := r.query(ctx, &logs, query, conditions.args...); err != nil {
+		return nil, "", database.TranslateError(err, "failed to get logs after cursor")
+	}
+	if len(logs) > 0 {
+		cursor = strconv.FormatUint(uint64(logs[len(logs)-1].ID), 10)
+	}
+	return logs, cursor, nil
+}
+
+// parseIDCursor parses a cursor holding a log ID; the empty cursor starts from the oldest log
+func parseIDCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	afterID, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, apperrors.Validation("Invalid cursor")
+	}
+	return afterID, nil
+}
+
+// CountErrorLogs counts the ERROR and FATAL logs
+func (r *ClickHouseLogRepository) CountErrorLogs(ctx context.Context) (int64, error) {
+	var rows []struct {
+		Count int64 `json:"count"`
+	}
+	if err := r.query(ctx, &rows, "SELECT count() AS count FROM %s WHERE level IN ?", errorLevels); err != nil {
+		return 0, database.TranslateError(err, "failed to count error logs")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Count, nil
+}
+
+// GetErrorLogsAfterCursor retrieves ERROR and FATAL logs with an ID greater than the cursor, oldest first
+func (r *ClickHouseLogRepository) GetErrorLogsAfterCursor(ctx context.Context, cursor string, limit int) ([]*models.Log, string, error) {
+	afterID, err := parseIDCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var logs []*models.Log
+	err = r.query(ctx, &logs, `
+		SELECT id, service, level, message
+		FROM %s
+		WHERE id > ? AND level IN ?
+		ORDER BY id ASC
+		LIMIT ?`, afterID, errorLevels, limit)
+	if err != nil {
+		return nil, "", database.TranslateError(err, "failed to get error logs after cursor")
+	}
+	if len(logs) > 0 {
+		cursor = strconv.FormatUint(uint64(logs[len(logs)-1].ID), 10)
+	}
+	return logs, cursor, nil
+}
+
+// SetLogFingerprints updates the fingerprints of the logs whose fingerprint changed in a single mutation, which
+// the call waits for. Mutations rewrite the affected parts, so backfills are far slower than in MySQL.
+func (r *ClickHouseLogRepository) SetLogFingerprints(ctx context.Context, logs []*models.Log) (int64, error) {
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(logs))
+	for i, log := range logs {
+		ids[i] = log.ID
+	}
+	var stored []struct {
+		ID          uint    `json:"id"`
+		Fingerprint *string `json:"fingerprint"`
+	}
+	if err := r.query(ctx, &stored, "SELECT id, fingerprint FROM %s WHERE id IN ?", ids); err != nil {
+		return 0, database.TranslateError(err, "failed to get log fingerprints")
+	}
+	current := make(map[uint]*string, len(stored))
+	for _, log := range stored {
+		current[log.ID] = log.Fingerprint
+	}
+
+	var fingerprint strings.Builder
+	var args []any
+	var changed []uint
+	fingerprint.WriteString("CASE id")
+	for _, log := range logs {
+		previous, ok := current[log.ID]
+		if !ok || equalStrings(previous, log.Fingerprint) {
+			continue
+		}
+		fingerprint.WriteString(" WHEN ? THEN ?")
+		args = append(args, log.ID, log.Fingerprint)
+		changed = append(changed, log.ID)
+	}
+	fingerprint.WriteString(" ELSE fingerprint END")
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	statement := fmt.Sprintf("ALTER TABLE `%s` UPDATE fingerprint = %s WHERE id IN ?", r.table, fingerprint.String())
+	if err := r.db.Mutate(ctx, statement, append(args, changed)...); err != nil {
+		return 0, database.TranslateError(err, "failed to set log fingerprints")
+	}
+	return int64(len(changed)), nil
+}
+
+// equalStrings reports whether two optional strings are both unset or equal
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// PurgeLogs deletes up to limit logs selected by the purge, oldest first, copying them to the archive table first
+// when archiving. Logs are removed with lightweight deletes, which hide them at once and drop them as parts merge.
+// ClickHouse has no transactions, so a purge interrupted after archiving leaves logs in both tables; the next purge
+// archives them again.
+func (r *ClickHouseLogRepository) PurgeLogs(ctx context.Context, purge *models.LogPurge, limit int) (int64, error) {
+	var conditions clickHouseConditions
+	conditions.add("timestamp < ?", purge.Before)
+	if condition, args := retentionScopeCondition(purge.LogRetentionScope); condition != "" {
+		conditions.add(condition, args...)
+	}
+	for _, scope := range purge.Exclude {
+		if condition, args := retentionScopeCondition(scope); condition != "" {
+			conditions.add("NOT ("+condition+")", args...)
+		}
+	}
+
+	var rows []struct {
+		ID uint64 `json:"id"`
+	}
+	if err := r.query(ctx, &rows, "SELECT id FROM %s"+conditions.where()+" ORDER BY timestamp LIMIT ?", append(conditions.args, limit)...); err != nil {
+		return 0, database.TranslateError(err, "failed to purge logs")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	ids := make([]uint64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	if purge.Archive {
+		archive := r.table + constants.RetentionArchiveSuffix
+		if err := r.db.Exec(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` AS `%s`", archive, r.table)); err != nil {
+			return 0, database.TranslateError(err, fmt.Sprintf("failed to create log table %s", archive))
+		}
+		if err := r.db.Exec(ctx, fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s` WHERE id IN ?", archive, r.table), ids); err != nil {
+			return 0, database.TranslateError(err, "failed to purge logs")
+		}
+	}
+	if err := r.db.Exec(ctx, fmt.Sprintf("DELETE FROM `%s` WHERE id IN ?", r.table), ids); err != nil {
+		return 0, database.TranslateError(err, "failed to purge logs")
+	}
+	return int64(len(ids)), nil
+}
+
+// GetLogTableStorage retrieves the size and row count of the logs table and its retention archive from their
+// active parts. Index bytes are the marks and skipping indexes stored beside the compressed columns.
+func (r *ClickHouseLogRepository) GetLogTableStorage(ctx context.Context) ([]models.TableStorage, error) {
+	var tables []models.TableStorage
+	err := r.db.Query(ctx, &tables, `
+		SELECT
+			table,
+			sum(rows) AS rows,
+			sum(data_compressed_bytes) AS data_bytes,
+			sum(bytes_on_disk) - sum(data_compressed_bytes) AS index_bytes,
+			sum(bytes_on_disk) AS total_bytes,
+			intDiv(sum(bytes_on_disk), greatest(sum(rows), 1)) AS avg_row_size
+		FROM system.parts
+		WHERE active AND database = currentDatabase() AND table IN ?
+		GROUP BY table
+		ORDER BY total_bytes DESC`, []string{r.table, r.table + constants.RetentionArchiveSuffix})
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get log table storage")
+	}
+	for i := range tables {
+		tables[i].Store = constants.LogStoreClickHouse
+	}
+	return tables, nil
+}
+
+// GetDailyLogGrowth retrieves the number of logs ingested per UTC day in [since, until)
+func (r *ClickHouseLogRepository) GetDailyLogGrowth(ctx context.Context, since, until time.Time) ([]models.DailyGrowth, error) {
+	var growth []models.DailyGrowth
+	err := r.query(ctx, &growth, `
+		SELECT toStartOfDay(created_at) AS day, count() AS rows
+		FROM %s
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY day
+		ORDER BY day ASC`, since, until)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get daily log growth")
+	}
+	return growth, nil
+}
+
+// GetServiceLogCounts retrieves the number of logs ingested per service in [since, until)
+func (r *ClickHouseLogRepository) GetServiceLogCounts(ctx context.Context, since, until time.Time) ([]models.ServiceStorageShare, error) {
+	var shares []models.ServiceStorageShare
+	err := r.query(ctx, &shares, `
+		SELECT service, count() AS rows
+		FROM %s
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY service
+		ORDER BY rows DESC`, since, until)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get service log counts")
+	}
+	return shares, nil
+}
+
+// QueryLogs runs a SELECT over the logs table
+func (r *ClickHouseLogRepository) QueryLogs(ctx context.Context, dest any, query string, args ...any) error {
+	return r.db.Query(ctx, dest, query, args...)
+}
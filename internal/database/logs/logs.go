@@ -3,68 +3,227 @@ package logs
 import (
 	"context"
 	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/storage"
 	"github.com/adeesh/log-analytics/internal/models"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GormLogRepository represents log-related database operations using GORM
 type GormLogRepository struct {
-	db *database.GormDB
+	db    *database.GormDB
+	table string
 }
 
 // LogRepository defines the interface for log-related database operations
 type LogRepository interface {
 	// CreateLog inserts a new log entry
 	CreateLog(ctx context.Context, log *models.Log) error
-	// CreateLogBatch inserts multiple log entries
+	// CreateLogBatch inserts multiple log entries. Logs whose message ID is already stored are skipped and keep
+	// a zero ID.
 	CreateLogBatch(ctx context.Context, logs []*models.Log) error
 	// GetLogs retrieves logs based on filters
 	GetLogs(ctx context.Context, filter *models.LogFilter) ([]*models.Log, error)
-	// GetLogStats retrieves aggregated log statistics
-	GetLogStats(ctx context.Context, startTime, endTime time.Time) (*models.LogStats, error)
-	// GetLogsByTraceID retrieves all logs for a specific trace ID
-	GetLogsByTraceID(ctx context.Context, traceID string) ([]*models.Log, error)
+	// GetLogStats retrieves aggregated log statistics of the logs matching the dimensions, with a time series
+	// whose buckets follow the alignment
+	GetLogStats(ctx context.Context, startTime, endTime time.Time, dimensions models.LogDimensions, alignment models.BucketAlignment) (*models.LogStats, error)
+	// GetServiceComparison retrieves per-service volume, error and latency figures for the given services,
+	// optionally of a single tenant
+	GetServiceComparison(ctx context.Context, services []string, tenant *string, startTime, endTime time.Time) ([]models.ServiceComparison, error)
+	// GetSlowRequests returns the endpoints with the highest p95 or average response times, per the query's sort,
+	// highest first. Percentiles use the nearest-rank method.
+	GetSlowRequests(ctx context.Context, query *models.SlowRequestQuery) ([]models.SlowRequest, error)
+	// AggregateLogs groups the logs matching the aggregation's filter by its group_by fields and returns the groups
+	// with the highest values of the aggregation, highest first
+	AggregateLogs(ctx context.Context, aggregation *models.LogAggregation) ([]models.LogGroup, error)
+	// GetLatencyHeatmap counts a service's logs, optionally of a single tenant, per time bucket of the given
+	// interval and latency bucket.
+	// Latency bucket i holds response times below bounds[i] and at or above the previous bound;
+	// the last bucket holds everything at or above the final bound. Empty cells are omitted.
+	GetLatencyHeatmap(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration, bounds []int) ([]models.LatencyHeatmapCell, error)
+	// GetLogTimeSeries counts logs per level in consecutive buckets of the interval from startTime to endTime,
+	// optionally for a single service and tenant. Buckets without logs are included.
+	GetLogTimeSeries(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error)
+	// GetLogsByTraceID retrieves all logs for a specific trace ID, optionally of a single tenant
+	GetLogsByTraceID(ctx context.Context, traceID string, tenant *string) ([]*models.Log, error)
+	// GetTraces returns the rollups of the traces with logs in the query's time range, most recent last log first.
+	// Traces filtered by service are rolled up over the logs of all their services.
+	GetTraces(ctx context.Context, query *models.TraceQuery) ([]models.TraceRollup, error)
+	// GetLogCursor returns a cursor positioned after the most recently stored log
+	GetLogCursor(ctx context.Context) (string, error)
+	// GetLogsAfterCursor retrieves logs matching the filter stored after the cursor, oldest first,
+	// along with the cursor to continue from. An empty cursor starts from the oldest log.
+	GetLogsAfterCursor(ctx context.Context, filter *models.LogFilter, cursor string) ([]*models.Log, string, error)
+	// PurgeLogs deletes, or moves to the archive table, up to limit logs selected by the purge, oldest first.
+	// It returns the number of logs removed.
+	PurgeLogs(ctx context.Context, purge *models.LogPurge, limit int) (int64, error)
+	// CountErrorLogs counts the ERROR and FATAL logs
+	CountErrorLogs(ctx context.Context) (int64, error)
+	// GetErrorLogsAfterCursor retrieves up to limit ERROR and FATAL logs after the cursor, oldest first, with the
+	// columns their fingerprint is computed from, and the cursor of the last one
+	GetErrorLogsAfterCursor(ctx context.Context, cursor string, limit int) ([]*models.Log, string, error)
+	// SetLogFingerprints stores the fingerprints of logs and returns the number of logs whose fingerprint changed
+	SetLogFingerprints(ctx context.Context, logs []*models.Log) (int64, error)
+}
+
+// LogQuerier runs SQL over the logs table that isn't written against a repository method, like the conditions of
+// alert rules. The SQL has to be valid in every log store backend.
+type LogQuerier interface {
+	// QueryLogs runs a SELECT and decodes its rows into dest, a pointer to a slice of structs whose JSON names
+	// and snake-cased field names match the selected columns
+	QueryLogs(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// LogStorage reports the storage taken by logs for capacity planning, in whichever tables and stores hold them
+type LogStorage interface {
+	// GetLogTableStorage retrieves the size and row count of the tables holding logs, archived ones included
+	GetLogTableStorage(ctx context.Context) ([]models.TableStorage, error)
+	// GetDailyLogGrowth retrieves the number of logs ingested per day in [since, until), oldest first
+	GetDailyLogGrowth(ctx context.Context, since, until time.Time) ([]models.DailyGrowth, error)
+	// GetServiceLogCounts retrieves the number of logs ingested per service in [since, until), most first
+	GetServiceLogCounts(ctx context.Context, since, until time.Time) ([]models.ServiceStorageShare, error)
+}
+
+// TimeSeriesIntervals are the supported time series bucket sizes, smallest first
+var TimeSeriesIntervals = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+// TimeSeriesIntervalFor returns the smallest supported interval splitting the range into at most the given number of buckets
+func TimeSeriesIntervalFor(startTime, endTime time.Time, maxBuckets int) time.Duration {
+	for _, interval := range TimeSeriesIntervals {
+		if timeSeriesBuckets(startTime, endTime, interval) <= maxBuckets {
+			return interval
+		}
+	}
+	return TimeSeriesIntervals[len(TimeSeriesIntervals)-1]
+}
+
+// timeSeriesBuckets returns the number of interval buckets needed to cover the range
+func timeSeriesBuckets(startTime, endTime time.Time, interval time.Duration) int {
+	return max(int((endTime.Sub(startTime)+interval-1)/interval), 1)
 }
 
 // NewLogRepository creates a new log repository
 func NewLogRepository(db *database.GormDB) LogRepository {
-	return &GormLogRepository{db: db}
+	return NewTableLogRepository(db, constants.DefaultLogsTable)
+}
+
+// NewTableLogRepository creates a log repository backed by the given table
+func NewTableLogRepository(db *database.GormDB, table string) LogRepository {
+	return &GormLogRepository{db: db, table: table}
+}
+
+// NewLogQuerier creates a querier of the logs table in MySQL
+func NewLogQuerier(db *database.GormDB) LogQuerier {
+	return &GormLogRepository{db: db, table: constants.DefaultLogsTable}
+}
+
+// NewLogStorage creates a reporter of the storage of the logs table in MySQL
+func NewLogStorage(db *database.GormDB) LogStorage {
+	return &GormLogRepository{db: db, table: constants.DefaultLogsTable}
+}
+
+// query starts a statement against the repository's table
+func (r *GormLogRepository) query(ctx context.Context) *gorm.DB {
+	return r.db.GetDB().WithContext(ctx).Table(r.table)
 }
 
 // CreateLog inserts a new log entry
 func (r *GormLogRepository) CreateLog(ctx context.Context, log *models.Log) error {
-	result := r.db.GetDB().WithContext(ctx).Create(log)
+	result := r.query(ctx).Create(log)
 	if result.Error != nil {
-		return fmt.Errorf("failed to create log: %w", result.Error)
+		return database.TranslateError(result.Error, "failed to create log")
 	}
 	return nil
 }
 
-// CreateLogBatch inserts multiple log entries
+// CreateLogBatch inserts multiple log entries, skipping those whose message ID is already stored
 func (r *GormLogRepository) CreateLogBatch(ctx context.Context, logs []*models.Log) error {
+	logs, err := r.withoutStoredMessages(ctx, logs)
+	if err != nil {
+		return err
+	}
 	if len(logs) == 0 {
 		return nil
 	}
-	result := r.db.GetDB().WithContext(ctx).CreateInBatches(logs, 100)
+	// Stored duplicates are filtered out above; ignoring conflicts covers a concurrent insert of the same log
+	result := r.query(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(logs, 100)
 	if result.Error != nil {
-		return fmt.Errorf("failed to create log batch: %w", result.Error)
+		return database.TranslateError(result.Error, "failed to create log batch")
 	}
 	return nil
 }
 
+// withoutStoredMessages returns the logs whose message ID isn't stored yet
+func (r *GormLogRepository) withoutStoredMessages(ctx context.Context, logs []*models.Log) ([]*models.Log, error) {
+	var ids []string
+	for _, log := range logs {
+		if log.MessageID != nil {
+			ids = append(ids, *log.MessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return logs, nil
+	}
+
+	var stored []string
+	if err := r.query(ctx).Where("message_id IN ?", ids).Pluck("message_id", &stored).Error; err != nil {
+		return nil, database.TranslateError(err, "failed to check stored message IDs")
+	}
+	if len(stored) == 0 {
+		return logs, nil
+	}
+	return slices.DeleteFunc(slices.Clone(logs), func(log *models.Log) bool {
+		return log.MessageID != nil && slices.Contains(stored, *log.MessageID)
+	}), nil
+}
+
 // GetLogs retrieves logs based on filters
 func (r *GormLogRepository) GetLogs(ctx context.Context, filter *models.LogFilter) ([]*models.Log, error) {
-	query := r.db.GetDB().WithContext(ctx).Model(&models.Log{})
+	query := applyLogFilter(r.query(ctx), filter)
+	if len(filter.Fields) > 0 {
+		query = query.Select(filter.Fields)
+	}
+	query = query.Order("timestamp DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	var logs []*models.Log
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, database.TranslateError(err, "failed to get logs")
+	}
+	return logs, nil
+}
+
+// applyLogFilter adds the filter conditions to a query
+func applyLogFilter(query *gorm.DB, filter *models.LogFilter) *gorm.DB {
 	if filter.Level != nil {
 		query = query.Where("level = ?", *filter.Level)
 	}
 	if filter.Service != nil {
 		query = query.Where("service = ?", *filter.Service)
 	}
+	query = applyLogDimensions(query, filter.LogDimensions)
 	if filter.TraceID != nil {
 		query = query.Where("trace_id = ?", *filter.TraceID)
 	}
+	if filter.SpanID != nil {
+		query = query.Where("span_id = ?", *filter.SpanID)
+	}
+	if filter.ParentSpanID != nil {
+		query = query.Where("parent_span_id = ?", *filter.ParentSpanID)
+	}
 	if filter.UserID != nil {
 		query = query.Where("user_id = ?", *filter.UserID)
 	}
@@ -75,24 +234,52 @@ func (r *GormLogRepository) GetLogs(ctx context.Context, filter *models.LogFilte
 		query = query.Where("timestamp <= ?", *filter.EndTime)
 	}
 	if filter.Search != nil {
-		query = query.Where("MATCH(message) AGAINST(? IN BOOLEAN MODE)", *filter.Search)
+		query = query.Where(database.FullTextMatch(query, "message"), *filter.Search)
 	}
-	query = query.Order("timestamp DESC")
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
+	if filter.Fingerprint != nil {
+		query = query.Where("fingerprint = ?", *filter.Fingerprint)
 	}
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
+	if filter.HasRequestBody != nil {
+		query = query.Where(nullCondition("request_body", !*filter.HasRequestBody))
 	}
-	var logs []*models.Log
-	if err := query.Find(&logs).Error; err != nil {
-		return nil, fmt.Errorf("failed to get logs: %w", err)
+	if filter.HasResponseBody != nil {
+		query = query.Where(nullCondition("response_body", !*filter.HasResponseBody))
 	}
-	return logs, nil
+	// Sorted so equal filters produce the same statement
+	for _, key := range slices.Sorted(maps.Keys(filter.Attributes)) {
+		query = query.Where(database.JSONContains(query, "attributes"), key, filter.Attributes[key])
+	}
+	return query
+}
+
+// applyLogDimensions adds the dimension conditions to a query
+func applyLogDimensions(query *gorm.DB, dimensions models.LogDimensions) *gorm.DB {
+	query = applyTenant(query, dimensions.Tenant)
+	if dimensions.Host != nil {
+		query = query.Where("host = ?", *dimensions.Host)
+	}
+	if dimensions.Environment != nil {
+		query = query.Where("environment = ?", *dimensions.Environment)
+	}
+	if dimensions.Region != nil {
+		query = query.Where("region = ?", *dimensions.Region)
+	}
+	if dimensions.ClientIP != nil {
+		query = query.Where("client_ip = ?", *dimensions.ClientIP)
+	}
+	return query
+}
+
+// applyTenant restricts a query to the logs of a tenant, when one is given
+func applyTenant(query *gorm.DB, tenant *string) *gorm.DB {
+	if tenant != nil {
+		query = query.Where("tenant = ?", *tenant)
+	}
+	return query
 }
 
 // GetLogStats retrieves aggregated log statistics
-func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime time.Time) (*models.LogStats, error) {
+func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime time.Time, dimensions models.LogDimensions, alignment models.BucketAlignment) (*models.LogStats, error) {
 	stats := &models.LogStats{}
 
 	// Get total counts by level
@@ -104,9 +291,13 @@ func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime
 		DebugCount      int64   `json:"debug_count"`
 		FatalCount      int64   `json:"fatal_count"`
 		AvgResponseTime float64 `json:"avg_response_time"`
+		Status2xxCount  int64   `json:"status_2xx_count"`
+		Status3xxCount  int64   `json:"status_3xx_count"`
+		Status4xxCount  int64   `json:"status_4xx_count"`
+		Status5xxCount  int64   `json:"status_5xx_count"`
 	}
 
-	err := r.db.GetDB().WithContext(ctx).Model(&models.Log{}).
+	err := applyLogDimensions(r.query(ctx), dimensions).
 		Select(`
 			COUNT(*) as total_logs,
 			SUM(CASE WHEN level = 'ERROR' THEN 1 ELSE 0 END) as error_count,
@@ -114,13 +305,17 @@ func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime
 			SUM(CASE WHEN level = 'INFO' THEN 1 ELSE 0 END) as info_count,
 			SUM(CASE WHEN level = 'DEBUG' THEN 1 ELSE 0 END) as debug_count,
 			SUM(CASE WHEN level = 'FATAL' THEN 1 ELSE 0 END) as fatal_count,
-			AVG(response_time_ms) as avg_response_time
+			AVG(response_time_ms) as avg_response_time,
+			SUM(CASE WHEN response_status BETWEEN 200 AND 299 THEN 1 ELSE 0 END) as status2xx_count,
+			SUM(CASE WHEN response_status BETWEEN 300 AND 399 THEN 1 ELSE 0 END) as status3xx_count,
+			SUM(CASE WHEN response_status BETWEEN 400 AND 499 THEN 1 ELSE 0 END) as status4xx_count,
+			SUM(CASE WHEN response_status BETWEEN 500 AND 599 THEN 1 ELSE 0 END) as status5xx_count
 		`).
 		Where("timestamp BETWEEN ? AND ?", startTime, endTime).
 		Scan(&result).Error
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get log stats: %w", err)
+		return nil, database.TranslateError(err, "failed to get log stats")
 	}
 
 	stats.TotalLogs = result.TotalLogs
@@ -130,10 +325,20 @@ func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime
 	stats.DebugCount = result.DebugCount
 	stats.FatalCount = result.FatalCount
 	stats.AvgResponseTime = result.AvgResponseTime
+	stats.Status2xxCount = result.Status2xxCount
+	stats.Status3xxCount = result.Status3xxCount
+	stats.Status4xxCount = result.Status4xxCount
+	stats.Status5xxCount = result.Status5xxCount
+
+	// Averages hide tail latency, so percentiles are computed per service and overall
+	stats.ServiceLatency, stats.ResponseTimePercentiles, err = r.getResponseTimePercentiles(ctx, startTime, endTime, dimensions)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get top services
 	var serviceCounts []models.ServiceCount
-	err = r.db.GetDB().WithContext(ctx).Model(&models.Log{}).
+	err = applyLogDimensions(r.query(ctx), dimensions).
 		Select("service, COUNT(*) as count").
 		Where("timestamp BETWEEN ? AND ?", startTime, endTime).
 		Group("service").
@@ -142,13 +347,13 @@ func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime
 		Scan(&serviceCounts).Error
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service stats: %w", err)
+		return nil, database.TranslateError(err, "failed to get service stats")
 	}
 	stats.TopServices = serviceCounts
 
 	// Get top errors
 	var errorCounts []models.ErrorCount
-	err = r.db.GetDB().WithContext(ctx).Model(&models.Log{}).
+	err = applyLogDimensions(r.query(ctx), dimensions).
 		Select("message, COUNT(*) as count").
 		Where("timestamp BETWEEN ? AND ? AND level IN (?, ?)", startTime, endTime, "ERROR", "FATAL").
 		Group("message").
@@ -157,23 +362,582 @@ func (r *GormLogRepository) GetLogStats(ctx context.Context, startTime, endTime
 		Scan(&errorCounts).Error
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get error stats: %w", err)
+		return nil, database.TranslateError(err, "failed to get error stats")
 	}
 	stats.TopErrors = errorCounts
 
+	// The first bucket starts at its aligned boundary but only counts logs from the start of the range
+	interval := TimeSeriesIntervalFor(startTime, endTime, constants.MetricsTimeSeriesPoints)
+	stats.TimeSeries, err = r.getLogTimeSeries(ctx, "", dimensions, alignment.Truncate(startTime, interval), startTime, endTime, interval)
+	if err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
+// getResponseTimePercentiles computes nearest-rank response time percentiles of every service and of all logs
+// together in a single query, whose rollup row carries the overall percentiles
+func (r *GormLogRepository) getResponseTimePercentiles(ctx context.Context, startTime, endTime time.Time, dimensions models.LogDimensions) ([]models.ServiceLatency, models.ResponseTimePercentiles, error) {
+	ranked := applyLogDimensions(r.query(ctx), dimensions).
+		Select(`
+			service, response_time_ms,
+			ROW_NUMBER() OVER (PARTITION BY service ORDER BY response_time_ms) as service_rank,
+			COUNT(*) OVER (PARTITION BY service) as service_count,
+			ROW_NUMBER() OVER (ORDER BY response_time_ms) as overall_rank,
+			COUNT(*) OVER () as overall_count
+		`).
+		Where("timestamp BETWEEN ? AND ? AND response_time_ms IS NOT NULL", startTime, endTime)
+
+	var rows []struct {
+		Service *string // nil on the rollup row
+		Count   int64
+		models.ResponseTimePercentiles
+		OverallP50 *int `gorm:"column:overall_p50"`
+		OverallP95 *int `gorm:"column:overall_p95"`
+		OverallP99 *int `gorm:"column:overall_p99"`
+	}
+	err := r.db.GetDB().WithContext(ctx).
+		Table("(?) as ranked", ranked).
+		Select(`
+			service,
+			COUNT(*) as count,
+			MIN(CASE WHEN service_rank >= CEIL(0.50 * service_count) THEN response_time_ms END) as p50_response_time,
+			MIN(CASE WHEN service_rank >= CEIL(0.95 * service_count) THEN response_time_ms END) as p95_response_time,
+			MIN(CASE WHEN service_rank >= CEIL(0.99 * service_count) THEN response_time_ms END) as p99_response_time,
+			MIN(CASE WHEN overall_rank >= CEIL(0.50 * overall_count) THEN response_time_ms END) as overall_p50,
+			MIN(CASE WHEN overall_rank >= CEIL(0.95 * overall_count) THEN response_time_ms END) as overall_p95,
+			MIN(CASE WHEN overall_rank >= CEIL(0.99 * overall_count) THEN response_time_ms END) as overall_p99
+		`).
+		Group("service WITH ROLLUP").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, models.ResponseTimePercentiles{}, database.TranslateError(err, "failed to get response time percentiles")
+	}
+
+	latencies := []models.ServiceLatency{}
+	var overall models.ResponseTimePercentiles
+	for _, row := range rows {
+		if row.Service == nil {
+			overall = models.ResponseTimePercentiles{
+				P50ResponseTime: row.OverallP50,
+				P95ResponseTime: row.OverallP95,
+				P99ResponseTime: row.OverallP99,
+			}
+			continue
+		}
+		latencies = append(latencies, models.ServiceLatency{
+			Service:                 *row.Service,
+			Count:                   row.Count,
+			ResponseTimePercentiles: row.ResponseTimePercentiles,
+		})
+	}
+	return latencies, overall, nil
+}
+
+// GetServiceComparison computes the figures of every requested service in a single query.
+// Percentiles use the nearest-rank method over each service's logs carrying a response time.
+func (r *GormLogRepository) GetServiceComparison(ctx context.Context, services []string, tenant *string, startTime, endTime time.Time) ([]models.ServiceComparison, error) {
+	if len(services) == 0 {
+		return []models.ServiceComparison{}, nil
+	}
+
+	ranked := applyTenant(r.query(ctx), tenant).
+		Select(`
+			service, level, response_status, response_time_ms,
+			ROW_NUMBER() OVER (PARTITION BY service, response_time_ms IS NULL ORDER BY response_time_ms) as latency_rank,
+			COUNT(response_time_ms) OVER (PARTITION BY service) as latency_count
+		`).
+		Where("service IN ? AND timestamp BETWEEN ? AND ?", services, startTime, endTime)
+
+	var comparisons []models.ServiceComparison
+	err := r.db.GetDB().WithContext(ctx).
+		Table("(?) as ranked", ranked).
+		Select(`
+			service,
+			COUNT(*) as total_logs,
+			SUM(CASE WHEN level IN ('ERROR', 'FATAL') THEN 1 ELSE 0 END) as error_count,
+			SUM(CASE WHEN response_status BETWEEN 400 AND 499 THEN 1 ELSE 0 END) as status4xx_count,
+			SUM(CASE WHEN response_status BETWEEN 500 AND 599 THEN 1 ELSE 0 END) as status5xx_count,
+			AVG(response_time_ms) as avg_response_time,
+			MIN(CASE WHEN response_time_ms IS NOT NULL AND latency_rank >= CEIL(0.50 * latency_count) THEN response_time_ms END) as p50_response_time,
+			MIN(CASE WHEN response_time_ms IS NOT NULL AND latency_rank >= CEIL(0.95 * latency_count) THEN response_time_ms END) as p95_response_time,
+			MIN(CASE WHEN response_time_ms IS NOT NULL AND latency_rank >= CEIL(0.99 * latency_count) THEN response_time_ms END) as p99_response_time
+		`).
+		Group("service").
+		Scan(&comparisons).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get service comparison")
+	}
+	return comparisons, nil
+}
+
+// GetSlowRequests ranks the response times of every endpoint and computes their figures in a single query
+func (r *GormLogRepository) GetSlowRequests(ctx context.Context, query *models.SlowRequestQuery) ([]models.SlowRequest, error) {
+	ranked := applyTenant(r.query(ctx), query.Tenant).
+		Select(`
+			request_method, request_path, response_time_ms,
+			ROW_NUMBER() OVER (PARTITION BY request_method, request_path ORDER BY response_time_ms) as latency_rank,
+			COUNT(*) OVER (PARTITION BY request_method, request_path) as latency_count
+		`).
+		Where("timestamp BETWEEN ? AND ?", query.StartTime, query.EndTime).
+		Where("request_method IS NOT NULL AND request_path IS NOT NULL AND response_time_ms IS NOT NULL")
+	if query.Service != "" {
+		ranked = ranked.Where("service = ?", query.Service)
+	}
+
+	var requests []models.SlowRequest
+	err := r.db.GetDB().WithContext(ctx).
+		Table("(?) as ranked", ranked).
+		Select(`
+			request_method, request_path,
+			COUNT(*) as count,
+			AVG(response_time_ms) as avg_response_time,
+			MIN(CASE WHEN latency_rank >= CEIL(0.95 * latency_count) THEN response_time_ms END) as p95_response_time,
+			MAX(response_time_ms) as max_response_time
+		`).
+		Group("request_method, request_path").
+		Having("COUNT(*) >= ?", query.MinCount).
+		Order(slowRequestsOrder(query.Sort)).
+		Limit(query.Limit).
+		Scan(&requests).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get slow requests")
+	}
+	return requests, nil
+}
+
+// slowRequestsOrder orders endpoints by the sorted response time, then the other one, highest first
+func slowRequestsOrder(sort string) string {
+	if sort == constants.SlowRequestsSortAvg {
+		return "avg_response_time DESC, p95_response_time DESC, request_method, request_path"
+	}
+	return "p95_response_time DESC, avg_response_time DESC, request_method, request_path"
+}
+
+// AggregateLogs groups and aggregates the logs in a single grouped query
+func (r *GormLogRepository) AggregateLogs(ctx context.Context, aggregation *models.LogAggregation) ([]models.LogGroup, error) {
+	// Group by fields are checked against models.LogGroupByFields, so they are safe to use as column names
+	columns := strings.Join(aggregation.GroupBy, ", ")
+	var rows []logGroupRow
+	err := applyLogFilter(r.query(ctx), &aggregation.Filter).
+		Select(columns + `,
+			COUNT(*) as count,
+			SUM(response_time_ms) as response_time_sum,
+			COUNT(response_time_ms) as response_time_count,
+			MAX(response_time_ms) as max_response_time
+		`).
+		Group(columns).
+		Order(logAggregationOrder(aggregation)).
+		Limit(aggregation.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to aggregate logs")
+	}
+	return logGroups(rows, aggregation), nil
+}
+
+// logGroupRow is a row of an aggregation query, with a column for each field logs can be grouped by
+type logGroupRow struct {
+	Service           *string  `json:"service"`
+	Level             *string  `json:"level"`
+	RequestPath       *string  `json:"request_path"`
+	ResponseStatus    *int     `json:"response_status"`
+	UserID            *string  `json:"user_id"`
+	Count             int64    `json:"count"`
+	ResponseTimeSum   *float64 `json:"response_time_sum"`
+	ResponseTimeCount int64    `json:"response_time_count"`
+	MaxResponseTime   *float64 `json:"max_response_time"`
+}
+
+// logGroups turns the rows of an aggregation query into groups keyed by the aggregation's group_by fields
+func logGroups(rows []logGroupRow, aggregation *models.LogAggregation) []models.LogGroup {
+	groups := make([]models.LogGroup, len(rows))
+	for i, row := range rows {
+		fields := map[string]any{
+			"service":         row.Service,
+			"level":           row.Level,
+			"request_path":    row.RequestPath,
+			"response_status": row.ResponseStatus,
+			"user_id":         row.UserID,
+		}
+		key := make(map[string]any, len(aggregation.GroupBy))
+		for _, field := range aggregation.GroupBy {
+			key[field] = fields[field]
+		}
+		groups[i] = models.LogGroup{
+			Key:               key,
+			Count:             row.Count,
+			ResponseTimeCount: row.ResponseTimeCount,
+			MaxResponseTime:   row.MaxResponseTime,
+		}
+		if row.ResponseTimeSum != nil {
+			groups[i].ResponseTimeSum = *row.ResponseTimeSum
+		}
+		groups[i].Aggregate(aggregation.Aggregation)
+	}
+	return groups
+}
+
+// logAggregationOrder orders the groups of an aggregation query by their value, highest first, then by their key so
+// that equal values are returned in a stable order. Groups without a response time come last.
+func logAggregationOrder(aggregation *models.LogAggregation) string {
+	value := "count DESC"
+	switch aggregation.Aggregation {
+	case constants.LogAggregationAvgResponseTime:
+		value = "AVG(response_time_ms) IS NULL, AVG(response_time_ms) DESC"
+	case constants.LogAggregationMaxResponseTime:
+		value = "max_response_time IS NULL, max_response_time DESC"
+	}
+	return value + ", " + strings.Join(aggregation.GroupBy, ", ")
+}
+
+// GetLogTimeSeries counts logs per bucket in a single grouped query and fills in empty buckets.
+// Logs exactly at endTime are counted in the last bucket.
+func (r *GormLogRepository) GetLogTimeSeries(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error) {
+	return r.getLogTimeSeries(ctx, service, models.LogDimensions{Tenant: tenant}, startTime, startTime, endTime, interval)
+}
+
+// getLogTimeSeries counts the logs matching the dimensions per bucket, optionally for a single service. Buckets
+// start at bucketStart, which may precede startTime so that bucket boundaries stay aligned.
+func (r *GormLogRepository) getLogTimeSeries(ctx context.Context, service string, dimensions models.LogDimensions, bucketStart, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error) {
+	var rows []struct {
+		Bucket int
+		models.TimeSeriesData
+	}
+	query := applyLogDimensions(r.query(ctx), dimensions)
+	query = query.
+		Select(`
+			FLOOR(`+database.SecondsBetween(query, "?", "timestamp")+` / ?) as bucket,
+			COUNT(*) as count,
+			SUM(CASE WHEN level = 'DEBUG' THEN 1 ELSE 0 END) as debug_count,
+			SUM(CASE WHEN level = 'INFO' THEN 1 ELSE 0 END) as info_count,
+			SUM(CASE WHEN level = 'WARN' THEN 1 ELSE 0 END) as warning_count,
+			SUM(CASE WHEN level = 'ERROR' THEN 1 ELSE 0 END) as error_count,
+			SUM(CASE WHEN level = 'FATAL' THEN 1 ELSE 0 END) as fatal_count
+		`, bucketStart, int64(interval/time.Second)).
+		Where("timestamp BETWEEN ? AND ?", startTime, endTime)
+	if service != "" {
+		query = query.Where("service = ?", service)
+	}
+	if err := query.Group("bucket").Scan(&rows).Error; err != nil {
+		return nil, database.TranslateError(err, "failed to get log time series")
+	}
+
+	series := newTimeSeries(bucketStart, endTime, interval)
+	for _, row := range rows {
+		bucket := min(max(row.Bucket, 0), len(series)-1)
+		series[bucket].Add(row.TimeSeriesData)
+	}
+	return series, nil
+}
+
+// newTimeSeries returns empty buckets covering the range
+func newTimeSeries(startTime, endTime time.Time, interval time.Duration) []models.TimeSeriesData {
+	series := make([]models.TimeSeriesData, timeSeriesBuckets(startTime, endTime, interval))
+	for i := range series {
+		series[i].Timestamp = startTime.Add(time.Duration(i) * interval)
+	}
+	return series
+}
+
+// GetLatencyHeatmap buckets the service's logs carrying a response time in a single grouped query
+func (r *GormLogRepository) GetLatencyHeatmap(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration, bounds []int) ([]models.LatencyHeatmapCell, error) {
+	args := []interface{}{startTime, int64(interval / time.Second)}
+	var latencyBucket strings.Builder
+	latencyBucket.WriteString("CASE")
+	for i, bound := range bounds {
+		fmt.Fprintf(&latencyBucket, " WHEN response_time_ms < ? THEN %d", i)
+		args = append(args, bound)
+	}
+	fmt.Fprintf(&latencyBucket, " ELSE %d END", len(bounds))
+
+	var cells []models.LatencyHeatmapCell
+	query := applyTenant(r.query(ctx), tenant)
+	err := query.
+		Select("FLOOR("+database.SecondsBetween(query, "?", "timestamp")+" / ?) as time_bucket, "+latencyBucket.String()+" as latency_bucket, COUNT(*) as count", args...).
+		Where("service = ? AND timestamp BETWEEN ? AND ? AND response_time_ms IS NOT NULL", service, startTime, endTime).
+		Group("time_bucket, latency_bucket").
+		Scan(&cells).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get latency heatmap")
+	}
+	return cells, nil
+}
+
 // GetLogsByTraceID retrieves all logs for a specific trace ID
-func (r *GormLogRepository) GetLogsByTraceID(ctx context.Context, traceID string) ([]*models.Log, error) {
+func (r *GormLogRepository) GetLogsByTraceID(ctx context.Context, traceID string, tenant *string) ([]*models.Log, error) {
 	var logs []*models.Log
-	err := r.db.GetDB().WithContext(ctx).
+	err := applyTenant(r.query(ctx), tenant).
 		Where("trace_id = ?", traceID).
 		Order("timestamp ASC").
 		Find(&logs).Error
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get logs by trace ID: %w", err)
+		return nil, database.TranslateError(err, "failed to get logs by trace ID")
 	}
 	return logs, nil
 }
+
+// GetTraces rolls up the logs of every trace in a single grouped query
+func (r *GormLogRepository) GetTraces(ctx context.Context, query *models.TraceQuery) ([]models.TraceRollup, error) {
+	db := applyTenant(r.query(ctx), query.Tenant).
+		Select(`
+			trace_id,
+			MIN(timestamp) as start_time,
+			MAX(timestamp) as end_time,
+			COUNT(*) as log_count,
+			COUNT(DISTINCT service) as service_count,
+			SUM(CASE WHEN level IN ? THEN 1 ELSE 0 END) as error_count,
+			MAX(response_time_ms) as max_response_time
+		`, errorLevels).
+		Where("trace_id IS NOT NULL AND trace_id <> ''").
+		Where("timestamp BETWEEN ? AND ?", query.StartTime, query.EndTime)
+	if query.Service != "" {
+		db = db.Where("trace_id IN (?)", applyTenant(r.query(ctx), query.Tenant).
+			Select("trace_id").
+			Where("service = ? AND timestamp BETWEEN ? AND ?", query.Service, query.StartTime, query.EndTime))
+	}
+	db = db.Group("trace_id")
+	if query.ErrorsOnly {
+		db = db.Having("SUM(CASE WHEN level IN ? THEN 1 ELSE 0 END) > 0", errorLevels)
+	}
+
+	var traces []models.TraceRollup
+	err := db.Order("end_time DESC, trace_id").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&traces).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get traces")
+	}
+	for i := range traces {
+		traces[i].SetDuration()
+	}
+	return traces, nil
+}
+
+// GetLogCursor returns the ID of the most recently stored log as cursor
+func (r *GormLogRepository) GetLogCursor(ctx context.Context) (string, error) {
+	var lastID uint64
+	err := r.query(ctx).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error
+	if err != nil {
+		return "", database.TranslateError(err, "failed to get log cursor")
+	}
+	return strconv.FormatUint(lastID, 10), nil
+}
+
+// GetLogsAfterCursor retrieves logs with an ID greater than the cursor, oldest first.
+// IDs are assigned on insert, so a row committed out of order by a concurrent writer may be skipped.
+func (r *GormLogRepository) GetLogsAfterCursor(ctx context.Context, filter *models.LogFilter, cursor string) ([]*models.Log, string, error) {
+	var afterID uint64
+	if cursor != "" {
+		var err error
+		if afterID, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", apperrors.Validation("Invalid cursor")
+		}
+	}
+
+	query := applyLogFilter(r.query(ctx), filter).
+		Where("id > ?", afterID).
+		Order("id ASC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []*models.Log
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, "", database.TranslateError(err, "failed to get logs after cursor")
+	}
+	if len(logs) > 0 {
+		cursor = strconv.FormatUint(uint64(logs[len(logs)-1].ID), 10)
+	}
+	return logs, cursor, nil
+}
+
+// errorLevels are the levels of the logs that are fingerprinted
+var errorLevels = []models.LogLevel{models.LogLevelError, models.LogLevelFatal}
+
+// CountErrorLogs counts the ERROR and FATAL logs
+func (r *GormLogRepository) CountErrorLogs(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.query(ctx).Where("level IN ?", errorLevels).Count(&count).Error
+	return count, database.TranslateError(err, "failed to count error logs")
+}
+
+// GetErrorLogsAfterCursor retrieves ERROR and FATAL logs with an ID greater than the cursor, oldest first
+func (r *GormLogRepository) GetErrorLogsAfterCursor(ctx context.Context, cursor string, limit int) ([]*models.Log, string, error) {
+	var afterID uint64
+	if cursor != "" {
+		var err error
+		if afterID, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", apperrors.Validation("Invalid cursor")
+		}
+	}
+
+	var logs []*models.Log
+	err := r.query(ctx).
+		Select("id", "service", "level", "message").
+		Where("id > ? AND level IN ?", afterID, errorLevels).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, "", database.TranslateError(err, "failed to get error logs after cursor")
+	}
+	if len(logs) > 0 {
+		cursor = strconv.FormatUint(uint64(logs[len(logs)-1].ID), 10)
+	}
+	return logs, cursor, nil
+}
+
+// SetLogFingerprints updates the fingerprints of a batch of logs in a single statement
+func (r *GormLogRepository) SetLogFingerprints(ctx context.Context, logs []*models.Log) (int64, error) {
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	var fingerprint strings.Builder
+	args := make([]any, 0, 2*len(logs))
+	ids := make([]uint, 0, len(logs))
+	fingerprint.WriteString("CASE id")
+	for _, log := range logs {
+		fingerprint.WriteString(" WHEN ? THEN ?")
+		args = append(args, log.ID, log.Fingerprint)
+		ids = append(ids, log.ID)
+	}
+	fingerprint.WriteString(" END")
+
+	// MySQL counts the rows actually changed, so logs already holding their fingerprint aren't counted
+	result := r.query(ctx).Where("id IN ?", ids).UpdateColumn("fingerprint", gorm.Expr(fingerprint.String(), args...))
+	if result.Error != nil {
+		return 0, database.TranslateError(result.Error, "failed to set log fingerprints")
+	}
+	return result.RowsAffected, nil
+}
+
+// QueryLogs runs a SELECT over the logs table
+func (r *GormLogRepository) QueryLogs(ctx context.Context, dest any, query string, args ...any) error {
+	return r.db.GetDB().WithContext(ctx).Raw(query, args...).Scan(dest).Error
+}
+
+// nullCondition builds an IS NULL or IS NOT NULL condition on a column
+func nullCondition(column string, null bool) string {
+	if null {
+		return column + " IS NULL"
+	}
+	return column + " IS NOT NULL"
+}
+
+// PurgeLogs deletes up to limit logs selected by the purge, oldest first, copying them to the archive table first
+// when archiving. The batch is locked and removed in a single transaction.
+func (r *GormLogRepository) PurgeLogs(ctx context.Context, purge *models.LogPurge, limit int) (int64, error) {
+	archive := r.table + constants.RetentionArchiveSuffix
+	if purge.Archive {
+		// DDL commits implicitly, so the archive table is created before the transaction
+		if err := ensureLogTable(ctx, r.db, archive); err != nil {
+			return 0, err
+		}
+	}
+
+	var purged int64
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := applyLogPurge(tx.Table(r.table), purge).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Order("timestamp").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if purge.Archive {
+			statement := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE id IN ?", tx.Statement.Quote(archive), tx.Statement.Quote(r.table))
+			if err := tx.Exec(statement, ids).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Table(r.table).Where("id IN ?", ids).Delete(&models.Log{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, database.TranslateError(err, "failed to purge logs")
+	}
+	return purged, nil
+}
+
+// applyLogPurge adds the conditions selecting the logs of a purge to a query
+func applyLogPurge(query *gorm.DB, purge *models.LogPurge) *gorm.DB {
+	query = query.Where("timestamp < ?", purge.Before)
+	if condition, args := retentionScopeCondition(purge.LogRetentionScope); condition != "" {
+		query = query.Where(condition, args...)
+	}
+	for _, scope := range purge.Exclude {
+		if condition, args := retentionScopeCondition(scope); condition != "" {
+			query = query.Where("NOT ("+condition+")", args...)
+		}
+	}
+	return query
+}
+
+// retentionScopeCondition returns the condition matching the logs of a retention scope; it is empty for the scope matching everything
+func retentionScopeCondition(scope models.LogRetentionScope) (string, []any) {
+	var conditions []string
+	var args []any
+	if scope.Service != "" {
+		conditions = append(conditions, "service = ?")
+		args = append(args, scope.Service)
+	}
+	if scope.Level != "" {
+		conditions = append(conditions, "level = ?")
+		args = append(args, scope.Level)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetLogTableStorage retrieves the size and row count of the logs table and its retention archive
+func (r *GormLogRepository) GetLogTableStorage(ctx context.Context) ([]models.TableStorage, error) {
+	tables, err := storage.NewStorageRepository(r.db.GetDB()).GetTableStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tables, func(table models.TableStorage) bool {
+		return table.Table != r.table && table.Table != r.table+constants.RetentionArchiveSuffix
+	}), nil
+}
+
+// GetDailyLogGrowth retrieves the number of logs ingested per day in [since, until)
+func (r *GormLogRepository) GetDailyLogGrowth(ctx context.Context, since, until time.Time) ([]models.DailyGrowth, error) {
+	var growth []models.DailyGrowth
+	err := r.query(ctx).
+		Select("DATE(created_at) AS day, COUNT(*) AS row_count").
+		Where("created_at >= ? AND created_at < ?", since, until).
+		Group("DATE(created_at)").
+		Order("day ASC").
+		Scan(&growth).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get daily log growth")
+	}
+	return growth, nil
+}
+
+// GetServiceLogCounts retrieves the number of logs ingested per service in [since, until).
+// The range is bounded so the query stays on the created_at index instead of scanning the whole table.
+func (r *GormLogRepository) GetServiceLogCounts(ctx context.Context, since, until time.Time) ([]models.ServiceStorageShare, error) {
+	var shares []models.ServiceStorageShare
+	err := r.query(ctx).
+		Select("service, COUNT(*) AS row_count").
+		Where("created_at >= ? AND created_at < ?", since, until).
+		Group("service").
+		Order("row_count DESC").
+		Scan(&shares).Error
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get service log counts")
+	}
+	return shares, nil
+}
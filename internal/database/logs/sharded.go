@@ -0,0 +1,873 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// topStatsLimit is the number of top services and errors returned in log statistics
+	topStatsLimit = 10
+
+	// shardCursorSeparator joins per-shard positions into a composite cursor
+	shardCursorSeparator = "."
+)
+
+// logsTablePattern matches the logs table in the FROM clauses of queries run through QueryLogs
+var logsTablePattern = regexp.MustCompile(`(?i)\bFROM\s+logs\b`)
+
+// ShardedLogRepository routes logs of configured services to dedicated tables or databases.
+// Writes go to the shard owning the log's service; reads fan out across all shards and are merged
+// so API consumers see a single logical log store.
+type ShardedLogRepository struct {
+	db     *database.GormDB // primary database
+	tables []string         // log tables in the primary database, the default table first
+	shards []LogRepository
+	routes map[string]LogRepository // service -> dedicated shard
+	conns  []*database.GormDB       // connections opened for separate databases
+
+	storages []shardStorage // storage of each shard, in the order of shards
+}
+
+// shardStorage reports the storage of a shard, labelled with the database holding it
+type shardStorage struct {
+	LogStorage
+	store string // database of a shard in a separate database; empty for the primary database
+}
+
+// NewShardedLogRepository creates a log repository applying the routing rules on top of the primary database.
+// Dedicated tables are created with the same structure as the primary logs table if they don't exist.
+func NewShardedLogRepository(ctx context.Context, db *database.GormDB, dbCfg *config.DatabaseConfig, routing *config.RoutingConfig) (*ShardedLogRepository, error) {
+	if err := routing.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid routing configuration: %w", err)
+	}
+
+	r := &ShardedLogRepository{
+		db:     db,
+		tables: []string{constants.DefaultLogsTable},
+		shards: []LogRepository{NewLogRepository(db)},
+		routes: make(map[string]LogRepository, len(routing.Rules)),
+
+		storages: []shardStorage{{LogStorage: NewLogStorage(db)}},
+	}
+
+	// Services routed to the same table and database share a single shard
+	type shardKey struct{ dsn, table string }
+	shards := make(map[shardKey]LogRepository)
+	conns := make(map[string]*database.GormDB)
+
+	for _, rule := range routing.Rules {
+		key := shardKey{dsn: rule.DSN, table: rule.Table}
+		shard, ok := shards[key]
+		if !ok {
+			shardDB := db
+			if rule.DSN != "" {
+				if shardDB, ok = conns[rule.DSN]; !ok {
+					var err error
+					shardDB, err = database.NewLogShardDB(rule.DSN, dbCfg)
+					if err != nil {
+						r.Close()
+						return nil, fmt.Errorf("failed to open database for service %q: %w", rule.Service, err)
+					}
+					conns[rule.DSN] = shardDB
+					r.conns = append(r.conns, shardDB)
+				}
+			}
+			if err := ensureLogTable(ctx, shardDB, rule.Table); err != nil {
+				r.Close()
+				return nil, err
+			}
+			shard = NewTableLogRepository(shardDB, rule.Table)
+			shards[key] = shard
+			r.shards = append(r.shards, shard)
+			storage := shardStorage{LogStorage: &GormLogRepository{db: shardDB, table: rule.Table}}
+			if rule.DSN == "" {
+				r.tables = append(r.tables, rule.Table)
+			} else {
+				storage.store = shardDB.GetDB().Migrator().CurrentDatabase()
+			}
+			r.storages = append(r.storages, storage)
+		}
+		r.routes[rule.Service] = shard
+	}
+
+	return r, nil
+}
+
+// ensureLogTable creates a routed table mirroring the logs table, including its indexes
+func ensureLogTable(ctx context.Context, db *database.GormDB, table string) error {
+	if table == constants.DefaultLogsTable {
+		return nil
+	}
+	conn := db.GetDB().WithContext(ctx)
+	format := "CREATE TABLE IF NOT EXISTS %s LIKE %s"
+	if database.IsPostgres(conn) {
+		// Postgres copies a table inside the column list; INCLUDING ALL copies its defaults and indexes too
+		format = "CREATE TABLE IF NOT EXISTS %s (LIKE %s INCLUDING ALL)"
+	}
+	statement := fmt.Sprintf(format, conn.Statement.Quote(table), conn.Statement.Quote(constants.DefaultLogsTable))
+	if err := conn.Exec(statement).Error; err != nil {
+		return database.TranslateError(err, fmt.Sprintf("failed to create log table %s", table))
+	}
+	return nil
+}
+
+// Close closes the connections opened for separate databases
+func (r *ShardedLogRepository) Close() error {
+	var firstErr error
+	for _, conn := range r.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Ping checks that the separate databases of the shards are reachable
+func (r *ShardedLogRepository) Ping(ctx context.Context) error {
+	for _, conn := range r.conns {
+		if err := conn.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardFor returns the shard storing logs of the given service
+func (r *ShardedLogRepository) shardFor(service string) LogRepository {
+	if shard, ok := r.routes[service]; ok {
+		return shard
+	}
+	return r.shards[0]
+}
+
+// fanOut runs fn concurrently against every shard and returns the first error
+func (r *ShardedLogRepository) fanOut(fn func(i int, shard LogRepository) error) error {
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+	for i, shard := range r.shards {
+		wg.Add(1)
+		go func(i int, shard LogRepository) {
+			defer wg.Done()
+			errs[i] = fn(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateLog inserts a new log entry into the shard owning its service
+func (r *ShardedLogRepository) CreateLog(ctx context.Context, log *models.Log) error {
+	return r.shardFor(log.Service).CreateLog(ctx, log)
+}
+
+// CreateLogBatch splits the batch by shard and inserts each part
+func (r *ShardedLogRepository) CreateLogBatch(ctx context.Context, logs []*models.Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	parts := make(map[LogRepository][]*models.Log)
+	for _, log := range logs {
+		shard := r.shardFor(log.Service)
+		parts[shard] = append(parts[shard], log)
+	}
+
+	for shard, part := range parts {
+		if err := shard.CreateLogBatch(ctx, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLogs retrieves logs based on filters, merging results across shards
+func (r *ShardedLogRepository) GetLogs(ctx context.Context, filter *models.LogFilter) ([]*models.Log, error) {
+	// A service filter targets exactly one shard
+	if filter.Service != nil {
+		return r.shardFor(*filter.Service).GetLogs(ctx, filter)
+	}
+
+	// Each shard must return enough rows to cover the requested page after merging
+	shardFilter := *filter
+	shardFilter.Offset = 0
+	if filter.Limit > 0 {
+		shardFilter.Limit = filter.Offset + filter.Limit
+	}
+	// Logs are merged by timestamp, so it's read even when not selected
+	if len(filter.Fields) > 0 && !slices.Contains(filter.Fields, "timestamp") {
+		shardFilter.Fields = append(slices.Clip(filter.Fields), "timestamp")
+	}
+
+	results := make([][]*models.Log, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		logs, err := shard.GetLogs(ctx, &shardFilter)
+		results[i] = logs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := mergeLogs(results, func(a, b *models.Log) bool { return a.Timestamp.After(b.Timestamp) })
+	if filter.Offset >= len(merged) {
+		return []*models.Log{}, nil
+	}
+	merged = merged[filter.Offset:]
+	if filter.Limit > 0 && len(merged) > filter.Limit {
+		merged = merged[:filter.Limit]
+	}
+	return merged, nil
+}
+
+// GetLogStats retrieves aggregated log statistics across shards
+func (r *ShardedLogRepository) GetLogStats(ctx context.Context, startTime, endTime time.Time, dimensions models.LogDimensions, alignment models.BucketAlignment) (*models.LogStats, error) {
+	results := make([]*models.LogStats, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		stats, err := shard.GetLogStats(ctx, startTime, endTime, dimensions, alignment)
+		results[i] = stats
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.LogStats{}
+	serviceCounts := make(map[string]int64)
+	errorCounts := make(map[string]int64)
+	var weightedResponseTime float64
+	var latencyCounts []int64
+	for _, shardStats := range results {
+		stats.TotalLogs += shardStats.TotalLogs
+		stats.ErrorCount += shardStats.ErrorCount
+		stats.WarningCount += shardStats.WarningCount
+		stats.InfoCount += shardStats.InfoCount
+		stats.DebugCount += shardStats.DebugCount
+		stats.FatalCount += shardStats.FatalCount
+		stats.Status2xxCount += shardStats.Status2xxCount
+		stats.Status3xxCount += shardStats.Status3xxCount
+		stats.Status4xxCount += shardStats.Status4xxCount
+		stats.Status5xxCount += shardStats.Status5xxCount
+		weightedResponseTime += shardStats.AvgResponseTime * float64(shardStats.TotalLogs)
+		// A service lives in exactly one shard, so per-service percentiles stay exact
+		var latencyCount int64
+		for _, latency := range shardStats.ServiceLatency {
+			stats.ServiceLatency = append(stats.ServiceLatency, latency)
+			latencyCount += latency.Count
+		}
+		latencyCounts = append(latencyCounts, latencyCount)
+		for _, service := range shardStats.TopServices {
+			serviceCounts[service.Service] += service.Count
+		}
+		// Each shard reports only its own top errors, so merged counts are a close approximation
+		for _, errorCount := range shardStats.TopErrors {
+			errorCounts[errorCount.Message] += errorCount.Count
+		}
+	}
+	if stats.TotalLogs > 0 {
+		stats.AvgResponseTime = weightedResponseTime / float64(stats.TotalLogs)
+	}
+	sort.Slice(stats.ServiceLatency, func(i, j int) bool { return stats.ServiceLatency[i].Service < stats.ServiceLatency[j].Service })
+	stats.ResponseTimePercentiles = mergePercentiles(results, latencyCounts)
+
+	for service, count := range serviceCounts {
+		stats.TopServices = append(stats.TopServices, models.ServiceCount{Service: service, Count: count})
+	}
+	sort.Slice(stats.TopServices, func(i, j int) bool { return stats.TopServices[i].Count > stats.TopServices[j].Count })
+	if len(stats.TopServices) > topStatsLimit {
+		stats.TopServices = stats.TopServices[:topStatsLimit]
+	}
+
+	for message, count := range errorCounts {
+		stats.TopErrors = append(stats.TopErrors, models.ErrorCount{Message: message, Count: count})
+	}
+	sort.Slice(stats.TopErrors, func(i, j int) bool { return stats.TopErrors[i].Count > stats.TopErrors[j].Count })
+	if len(stats.TopErrors) > topStatsLimit {
+		stats.TopErrors = stats.TopErrors[:topStatsLimit]
+	}
+
+	seriesResults := make([][]models.TimeSeriesData, len(results))
+	for i, shardStats := range results {
+		seriesResults[i] = shardStats.TimeSeries
+	}
+	stats.TimeSeries = mergeTimeSeries(seriesResults)
+
+	return stats, nil
+}
+
+// mergePercentiles approximates the overall percentiles by the shards' percentiles weighted by the number of logs
+// with a response time each shard holds; exact figures would need every shard's response times
+func mergePercentiles(results []*models.LogStats, counts []int64) models.ResponseTimePercentiles {
+	weighted := func(percentile func(*models.LogStats) *int) *int {
+		var sum float64
+		var total int64
+		for i, shardStats := range results {
+			if value := percentile(shardStats); value != nil {
+				sum += float64(*value) * float64(counts[i])
+				total += counts[i]
+			}
+		}
+		if total == 0 {
+			return nil
+		}
+		merged := int(math.Round(sum / float64(total)))
+		return &merged
+	}
+	return models.ResponseTimePercentiles{
+		P50ResponseTime: weighted(func(s *models.LogStats) *int { return s.P50ResponseTime }),
+		P95ResponseTime: weighted(func(s *models.LogStats) *int { return s.P95ResponseTime }),
+		P99ResponseTime: weighted(func(s *models.LogStats) *int { return s.P99ResponseTime }),
+	}
+}
+
+// GetLogTimeSeries queries the shard owning the service, or all shards when no service is given
+func (r *ShardedLogRepository) GetLogTimeSeries(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration) ([]models.TimeSeriesData, error) {
+	if service != "" {
+		return r.shardFor(service).GetLogTimeSeries(ctx, service, tenant, startTime, endTime, interval)
+	}
+
+	results := make([][]models.TimeSeriesData, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		series, err := shard.GetLogTimeSeries(ctx, service, tenant, startTime, endTime, interval)
+		results[i] = series
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeTimeSeries(results), nil
+}
+
+// mergeTimeSeries sums per-shard series bucket by bucket; shards share the range and interval,
+// so their buckets line up
+func mergeTimeSeries(results [][]models.TimeSeriesData) []models.TimeSeriesData {
+	var merged []models.TimeSeriesData
+	for _, series := range results {
+		if merged == nil {
+			merged = make([]models.TimeSeriesData, len(series))
+			for i, point := range series {
+				merged[i].Timestamp = point.Timestamp
+			}
+		}
+		for i := range min(len(merged), len(series)) {
+			merged[i].Add(series[i])
+		}
+	}
+	return merged
+}
+
+// GetServiceComparison queries each shard for the services it owns.
+// A service lives in exactly one shard, so per-service percentiles stay exact.
+func (r *ShardedLogRepository) GetServiceComparison(ctx context.Context, services []string, tenant *string, startTime, endTime time.Time) ([]models.ServiceComparison, error) {
+	owned := make(map[LogRepository][]string)
+	for _, service := range services {
+		shard := r.shardFor(service)
+		owned[shard] = append(owned[shard], service)
+	}
+
+	results := make([][]models.ServiceComparison, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		if len(owned[shard]) == 0 {
+			return nil
+		}
+		comparisons, err := shard.GetServiceComparison(ctx, owned[shard], tenant, startTime, endTime)
+		results[i] = comparisons
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make([]models.ServiceComparison, 0, len(services))
+	for _, comparisons := range results {
+		merged = append(merged, comparisons...)
+	}
+	return merged, nil
+}
+
+// GetSlowRequests merges the slowest endpoints of every shard, or queries the shard owning the service filtered on.
+// Endpoints served from several shards are merged from each shard's figures, with their p95 weighted by the shards'
+// request counts, and endpoints outside a shard's own top ones are missed, so merged figures are a close
+// approximation.
+func (r *ShardedLogRepository) GetSlowRequests(ctx context.Context, query *models.SlowRequestQuery) ([]models.SlowRequest, error) {
+	if query.Service != "" {
+		return r.shardFor(query.Service).GetSlowRequests(ctx, query)
+	}
+
+	results := make([][]models.SlowRequest, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		requests, err := shard.GetSlowRequests(ctx, query)
+		results[i] = requests
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type endpoint struct{ method, path string }
+	merged := []models.SlowRequest{}
+	byEndpoint := make(map[endpoint]int)
+	weightedP95 := make(map[endpoint]float64)
+	for _, requests := range results {
+		for _, request := range requests {
+			key := endpoint{request.RequestMethod, request.RequestPath}
+			weightedP95[key] += float64(request.P95ResponseTime) * float64(request.Count)
+			i, ok := byEndpoint[key]
+			if !ok {
+				byEndpoint[key] = len(merged)
+				merged = append(merged, request)
+				continue
+			}
+			total := merged[i].Count + request.Count
+			merged[i].AvgResponseTime = (merged[i].AvgResponseTime*float64(merged[i].Count) + request.AvgResponseTime*float64(request.Count)) / float64(total)
+			merged[i].Count = total
+			merged[i].MaxResponseTime = max(merged[i].MaxResponseTime, request.MaxResponseTime)
+		}
+	}
+	for i := range merged {
+		key := endpoint{merged[i].RequestMethod, merged[i].RequestPath}
+		merged[i].P95ResponseTime = int(math.Round(weightedP95[key] / float64(merged[i].Count)))
+	}
+
+	slowest := func(a, b models.SlowRequest) bool {
+		if query.Sort == constants.SlowRequestsSortAvg {
+			return a.AvgResponseTime > b.AvgResponseTime
+		}
+		return a.P95ResponseTime > b.P95ResponseTime
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return slowest(merged[i], merged[j]) })
+	if len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+	return merged, nil
+}
+
+// AggregateLogs merges the top groups of every shard, or queries the shard owning the service filtered on. A
+// service lives in exactly one shard, so groups by service are exact; other groups are merged from each shard's own
+// top groups, a close approximation.
+func (r *ShardedLogRepository) AggregateLogs(ctx context.Context, aggregation *models.LogAggregation) ([]models.LogGroup, error) {
+	if aggregation.Filter.Service != nil {
+		return r.shardFor(*aggregation.Filter.Service).AggregateLogs(ctx, aggregation)
+	}
+
+	results := make([][]models.LogGroup, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		groups, err := shard.AggregateLogs(ctx, aggregation)
+		results[i] = groups
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := []models.LogGroup{}
+	byKey := make(map[string]int)
+	for _, groups := range results {
+		for _, group := range groups {
+			key := fmt.Sprintf("%#v", groupKeyValues(group, aggregation.GroupBy))
+			i, ok := byKey[key]
+			if !ok {
+				byKey[key] = len(merged)
+				merged = append(merged, group)
+				continue
+			}
+			merged[i].Count += group.Count
+			merged[i].ResponseTimeSum += group.ResponseTimeSum
+			merged[i].ResponseTimeCount += group.ResponseTimeCount
+			if group.MaxResponseTime != nil && (merged[i].MaxResponseTime == nil || *group.MaxResponseTime > *merged[i].MaxResponseTime) {
+				merged[i].MaxResponseTime = group.MaxResponseTime
+			}
+		}
+	}
+	for i := range merged {
+		merged[i].Aggregate(aggregation.Aggregation)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i].Value, merged[j].Value
+		return a != nil && (b == nil || *a > *b)
+	})
+	if len(merged) > aggregation.Limit {
+		merged = merged[:aggregation.Limit]
+	}
+	return merged, nil
+}
+
+// groupKeyValues returns the values of a group's key in the order of the group_by fields, dereferenced so that
+// equal keys of different shards print alike, and missing values apart from any string
+func groupKeyValues(group models.LogGroup, groupBy []string) []any {
+	values := make([]any, len(groupBy))
+	for i, field := range groupBy {
+		switch value := group.Key[field].(type) {
+		case *string:
+			if value != nil {
+				values[i] = *value
+			}
+		case *int:
+			if value != nil {
+				values[i] = *value
+			}
+		}
+	}
+	return values
+}
+
+// GetLatencyHeatmap queries the shard owning the service
+func (r *ShardedLogRepository) GetLatencyHeatmap(ctx context.Context, service string, tenant *string, startTime, endTime time.Time, interval time.Duration, bounds []int) ([]models.LatencyHeatmapCell, error) {
+	return r.shardFor(service).GetLatencyHeatmap(ctx, service, tenant, startTime, endTime, interval, bounds)
+}
+
+// GetLogsByTraceID retrieves all logs for a specific trace ID across shards
+func (r *ShardedLogRepository) GetLogsByTraceID(ctx context.Context, traceID string, tenant *string) ([]*models.Log, error) {
+	results := make([][]*models.Log, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		logs, err := shard.GetLogsByTraceID(ctx, traceID, tenant)
+		results[i] = logs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeLogs(results, func(a, b *models.Log) bool { return a.Timestamp.Before(b.Timestamp) }), nil
+}
+
+// GetTraces merges the traces of every shard. A trace goes through services of several shards, so the rollups of
+// each shard, which cover the logs of its own services, are added up. Parts of a trace outside a shard's own page,
+// or, when filtering by service or errors, in shards that don't match the filter, are missed, so merged figures are a
+// close approximation.
+func (r *ShardedLogRepository) GetTraces(ctx context.Context, query *models.TraceQuery) ([]models.TraceRollup, error) {
+	// Every shard returns its traces up to the end of the page, which the merged page is taken from
+	shardQuery := *query
+	shardQuery.Limit = query.Offset + query.Limit
+	shardQuery.Offset = 0
+
+	results := make([][]models.TraceRollup, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		traces, err := shard.GetTraces(ctx, &shardQuery)
+		results[i] = traces
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := []models.TraceRollup{}
+	byTraceID := make(map[string]int)
+	for _, traces := range results {
+		for _, trace := range traces {
+			i, ok := byTraceID[trace.TraceID]
+			if !ok {
+				byTraceID[trace.TraceID] = len(merged)
+				merged = append(merged, trace)
+				continue
+			}
+			if trace.StartTime.Before(merged[i].StartTime) {
+				merged[i].StartTime = trace.StartTime
+			}
+			if trace.EndTime.After(merged[i].EndTime) {
+				merged[i].EndTime = trace.EndTime
+			}
+			merged[i].LogCount += trace.LogCount
+			merged[i].ServiceCount += trace.ServiceCount // a service lives in exactly one shard
+			merged[i].ErrorCount += trace.ErrorCount
+			if merged[i].MaxResponseTime == nil || (trace.MaxResponseTime != nil && *trace.MaxResponseTime > *merged[i].MaxResponseTime) {
+				merged[i].MaxResponseTime = trace.MaxResponseTime
+			}
+		}
+	}
+	for i := range merged {
+		merged[i].SetDuration()
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if !merged[i].EndTime.Equal(merged[j].EndTime) {
+			return merged[i].EndTime.After(merged[j].EndTime)
+		}
+		return merged[i].TraceID < merged[j].TraceID
+	})
+	if query.Offset >= len(merged) {
+		return []models.TraceRollup{}, nil
+	}
+	merged = merged[query.Offset:]
+	if len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+	return merged, nil
+}
+
+// mergeLogs concatenates per-shard results and sorts them with the given ordering
+func mergeLogs(results [][]*models.Log, less func(a, b *models.Log) bool) []*models.Log {
+	merged := make([]*models.Log, 0)
+	for _, logs := range results {
+		merged = append(merged, logs...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return less(merged[i], merged[j]) })
+	return merged
+}
+
+// GetLogCursor returns a composite cursor holding the position of every shard
+func (r *ShardedLogRepository) GetLogCursor(ctx context.Context) (string, error) {
+	cursors := make([]string, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		cursor, err := shard.GetLogCursor(ctx)
+		cursors[i] = cursor
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(cursors, shardCursorSeparator), nil
+}
+
+// GetLogsAfterCursor retrieves logs after a composite cursor, merging shards oldest first.
+// Shards are merged by taking the oldest head entry so every shard's position stays consistent with what was returned.
+func (r *ShardedLogRepository) GetLogsAfterCursor(ctx context.Context, filter *models.LogFilter, cursor string) ([]*models.Log, string, error) {
+	cursors := make([]string, len(r.shards))
+	if cursor != "" {
+		cursors = strings.Split(cursor, shardCursorSeparator)
+		if len(cursors) != len(r.shards) {
+			return nil, "", apperrors.Validation("Invalid cursor")
+		}
+	}
+
+	results := make([][]*models.Log, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		// A service filter only matches logs in that service's shard
+		if filter.Service != nil && r.shardFor(*filter.Service) != shard {
+			return nil
+		}
+		logs, _, err := shard.GetLogsAfterCursor(ctx, filter, cursors[i])
+		results[i] = logs
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	merged := make([]*models.Log, 0)
+	heads := make([]int, len(results))
+	for filter.Limit <= 0 || len(merged) < filter.Limit {
+		next := -1
+		for i, logs := range results {
+			if heads[i] < len(logs) && (next < 0 || logs[heads[i]].CreatedAt.Before(results[next][heads[next]].CreatedAt)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		log := results[next][heads[next]]
+		merged = append(merged, log)
+		cursors[next] = strconv.FormatUint(uint64(log.ID), 10)
+		heads[next]++
+	}
+
+	return merged, strings.Join(cursors, shardCursorSeparator), nil
+}
+
+// GetLogTableStorage retrieves the size and row count of the tables of every shard, labelling those of shards in
+// separate databases with their database
+func (r *ShardedLogRepository) GetLogTableStorage(ctx context.Context) ([]models.TableStorage, error) {
+	results := make([][]models.TableStorage, len(r.storages))
+	err := r.fanOut(func(i int, _ LogRepository) error {
+		tables, err := r.storages[i].GetLogTableStorage(ctx)
+		for j := range tables {
+			tables[j].Store = r.storages[i].store
+		}
+		results[i] = tables
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+// GetDailyLogGrowth retrieves the number of logs ingested per day in [since, until), summed across shards
+func (r *ShardedLogRepository) GetDailyLogGrowth(ctx context.Context, since, until time.Time) ([]models.DailyGrowth, error) {
+	results := make([][]models.DailyGrowth, len(r.storages))
+	err := r.fanOut(func(i int, _ LogRepository) error {
+		var err error
+		results[i], err = r.storages[i].GetDailyLogGrowth(ctx, since, until)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeDailyGrowth(results), nil
+}
+
+// mergeDailyGrowth sums per-shard growth by day, oldest first
+func mergeDailyGrowth(results [][]models.DailyGrowth) []models.DailyGrowth {
+	merged := make([]models.DailyGrowth, 0)
+	index := make(map[int64]int)
+	for _, growth := range results {
+		for _, day := range growth {
+			if i, ok := index[day.Day.Unix()]; ok {
+				merged[i].Rows += day.Rows
+				continue
+			}
+			index[day.Day.Unix()] = len(merged)
+			merged = append(merged, day)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Day.Before(merged[j].Day) })
+	return merged
+}
+
+// GetServiceLogCounts retrieves the number of logs ingested per service in [since, until), summed across shards
+func (r *ShardedLogRepository) GetServiceLogCounts(ctx context.Context, since, until time.Time) ([]models.ServiceStorageShare, error) {
+	results := make([][]models.ServiceStorageShare, len(r.storages))
+	err := r.fanOut(func(i int, _ LogRepository) error {
+		var err error
+		results[i], err = r.storages[i].GetServiceLogCounts(ctx, since, until)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeServiceLogCounts(results), nil
+}
+
+// mergeServiceLogCounts sums per-shard counts by service, most first. Services are normally stored in one shard
+// only, but may have logs in several after their routing changed.
+func mergeServiceLogCounts(results [][]models.ServiceStorageShare) []models.ServiceStorageShare {
+	merged := make([]models.ServiceStorageShare, 0)
+	index := make(map[string]int)
+	for _, shares := range results {
+		for _, share := range shares {
+			if i, ok := index[share.Service]; ok {
+				merged[i].Rows += share.Rows
+				continue
+			}
+			index[share.Service] = len(merged)
+			merged = append(merged, share)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Rows > merged[j].Rows })
+	return merged
+}
+
+// PurgeLogs purges the shard owning the purge's service, or every shard when it isn't limited to a service.
+// Each shard removes up to limit logs, so more than limit logs may be removed in total.
+func (r *ShardedLogRepository) PurgeLogs(ctx context.Context, purge *models.LogPurge, limit int) (int64, error) {
+	if purge.Service != "" {
+		return r.shardFor(purge.Service).PurgeLogs(ctx, purge, limit)
+	}
+
+	purged := make([]int64, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		var err error
+		purged[i], err = shard.PurgeLogs(ctx, purge, limit)
+		return err
+	})
+	var total int64
+	for _, count := range purged {
+		total += count
+	}
+	return total, err
+}
+
+// CountErrorLogs counts the error logs of every shard
+func (r *ShardedLogRepository) CountErrorLogs(ctx context.Context) (int64, error) {
+	counts := make([]int64, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		var err error
+		counts[i], err = shard.CountErrorLogs(ctx)
+		return err
+	})
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total, err
+}
+
+// GetErrorLogsAfterCursor retrieves error logs after a composite cursor from every shard. Each shard returns up to
+// limit logs, so more than limit logs may be returned in total; shards are not merged since callers only need
+// to visit every log once.
+func (r *ShardedLogRepository) GetErrorLogsAfterCursor(ctx context.Context, cursor string, limit int) ([]*models.Log, string, error) {
+	cursors := make([]string, len(r.shards))
+	if cursor != "" {
+		cursors = strings.Split(cursor, shardCursorSeparator)
+		if len(cursors) != len(r.shards) {
+			return nil, "", apperrors.Validation("Invalid cursor")
+		}
+	}
+
+	results := make([][]*models.Log, len(r.shards))
+	err := r.fanOut(func(i int, shard LogRepository) error {
+		var err error
+		results[i], cursors[i], err = shard.GetErrorLogsAfterCursor(ctx, cursors[i], limit)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return slices.Concat(results...), strings.Join(cursors, shardCursorSeparator), nil
+}
+
+// SetLogFingerprints splits the logs by shard and updates each part
+func (r *ShardedLogRepository) SetLogFingerprints(ctx context.Context, logs []*models.Log) (int64, error) {
+	parts := make(map[LogRepository][]*models.Log)
+	for _, log := range logs {
+		shard := r.shardFor(log.Service)
+		parts[shard] = append(parts[shard], log)
+	}
+
+	var total int64
+	for shard, part := range parts {
+		updated, err := shard.SetLogFingerprints(ctx, part)
+		total += updated
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// QueryLogs runs a SELECT over the logs of every shard, by reading the logs table of the query as the union of the
+// log tables in the primary database. Aggregates of alert rules and reports are computed over all logs at once,
+// which merging per-shard results couldn't do for averages and percentiles. Shards in separate databases can't be
+// joined in, so querying fails when there are any.
+func (r *ShardedLogRepository) QueryLogs(ctx context.Context, dest any, query string, args ...any) error {
+	if len(r.conns) > 0 {
+		return apperrors.Unavailable("Logs routed to a separate database can't be queried by alert rules and reports")
+	}
+	conn := r.db.GetDB().WithContext(ctx)
+	return conn.Raw(unionLogTables(conn.Statement.Quote, query, r.tables), args...).Scan(dest).Error
+}
+
+// unionLogTables replaces the logs table in the FROM clauses of a query with the union of the tables, keeping the
+// logs alias so that qualified columns still resolve. The routed tables are copies of the logs table, so their
+// columns line up.
+func unionLogTables(quote func(field any) string, query string, tables []string) string {
+	if len(tables) < 2 {
+		return query
+	}
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		selects[i] = "SELECT * FROM " + quote(table)
+	}
+	union := fmt.Sprintf("FROM (%s) AS %s", strings.Join(selects, " UNION ALL "), constants.DefaultLogsTable)
+	return logsTablePattern.ReplaceAllLiteralString(query, union)
+}
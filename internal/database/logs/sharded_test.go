@@ -0,0 +1,298 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// cursorShard serves the cursor reads of a shard from memory
+type cursorShard struct {
+	LogRepository
+	logs []*models.Log // by ID
+}
+
+func (s *cursorShard) GetLogCursor(ctx context.Context) (string, error) {
+	return strconv.FormatUint(uint64(s.logs[len(s.logs)-1].ID), 10), nil
+}
+
+func (s *cursorShard) GetLogsAfterCursor(ctx context.Context, filter *models.LogFilter, cursor string) ([]*models.Log, string, error) {
+	var found []*models.Log
+	for _, log := range s.after(cursor) {
+		if (filter.Service == nil || log.Service == *filter.Service) && (filter.Limit <= 0 || len(found) < filter.Limit) {
+			found = append(found, log)
+		}
+	}
+	return found, cursor, nil
+}
+
+func (s *cursorShard) GetErrorLogsAfterCursor(ctx context.Context, cursor string, limit int) ([]*models.Log, string, error) {
+	var found []*models.Log
+	for _, log := range s.after(cursor) {
+		if log.Level == models.LogLevelError && len(found) < limit {
+			found = append(found, log)
+			cursor = strconv.FormatUint(uint64(log.ID), 10)
+		}
+	}
+	return found, cursor, nil
+}
+
+// after returns the logs after a shard's position
+func (s *cursorShard) after(cursor string) []*models.Log {
+	afterID, _ := strconv.ParseUint(cursor, 10, 64)
+	return slices.DeleteFunc(slices.Clone(s.logs), func(log *models.Log) bool { return uint64(log.ID) <= afterID })
+}
+
+// newCursorRepository returns a repository over a default shard and a shard for the checkout service, whose logs
+// were stored alternately. Messages name the shard and the ID of the log.
+func newCursorRepository() *ShardedLogRepository {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newLog := func(service string, id uint, level models.LogLevel, at int) *models.Log {
+		return &models.Log{
+			ID:        id,
+			Service:   service,
+			Level:     level,
+			Message:   fmt.Sprintf("%s-%d", service, id),
+			CreatedAt: start.Add(time.Duration(at) * time.Second),
+		}
+	}
+	defaultShard := &cursorShard{logs: []*models.Log{
+		newLog("api", 1, models.LogLevelError, 0),
+		newLog("api", 2, models.LogLevelInfo, 2),
+		newLog("api", 3, models.LogLevelError, 4),
+	}}
+	checkoutShard := &cursorShard{logs: []*models.Log{
+		newLog("checkout", 1, models.LogLevelInfo, 1),
+		newLog("checkout", 2, models.LogLevelError, 3),
+	}}
+	return &ShardedLogRepository{
+		shards: []LogRepository{defaultShard, checkoutShard},
+		routes: map[string]LogRepository{"checkout": checkoutShard},
+	}
+}
+
+// messages returns the messages of logs
+func messages(logs []*models.Log) []string {
+	found := make([]string, 0, len(logs))
+	for _, log := range logs {
+		found = append(found, log.Message)
+	}
+	return found
+}
+
+func TestShardedLogCursor(t *testing.T) {
+	cursor, err := newCursorRepository().GetLogCursor(context.Background())
+	if err != nil || cursor != "3.2" {
+		t.Errorf("GetLogCursor() = %q, %v, want the last ID of every shard", cursor, err)
+	}
+}
+
+func TestShardedLogsAfterCursor(t *testing.T) {
+	checkout := "checkout"
+
+	tests := []struct {
+		name       string
+		cursor     string
+		filter     models.LogFilter
+		want       []string
+		wantCursor string
+		wantErr    bool
+	}{
+		{
+			name:       "merged oldest first",
+			filter:     models.LogFilter{Limit: 3},
+			want:       []string{"api-1", "checkout-1", "api-2"},
+			wantCursor: "2.1",
+		},
+		{
+			name:       "resumed from every shard's position",
+			cursor:     "2.1",
+			want:       []string{"checkout-2", "api-3"},
+			wantCursor: "3.2",
+		},
+		{
+			name:       "nothing new keeps the cursor",
+			cursor:     "3.2",
+			want:       []string{},
+			wantCursor: "3.2",
+		},
+		{
+			name:       "service filter reads its shard only",
+			cursor:     "1.0",
+			filter:     models.LogFilter{Service: &checkout},
+			want:       []string{"checkout-1", "checkout-2"},
+			wantCursor: "1.2",
+		},
+		{
+			name:    "cursor of another shard count",
+			cursor:  "3",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, cursor, err := newCursorRepository().GetLogsAfterCursor(context.Background(), &tt.filter, tt.cursor)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetLogsAfterCursor() = %q, want an error", messages(logs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLogsAfterCursor() error: %v", err)
+			}
+			if got := messages(logs); !slices.Equal(got, tt.want) {
+				t.Errorf("GetLogsAfterCursor() logs = %q, want %q", got, tt.want)
+			}
+			if cursor != tt.wantCursor {
+				t.Errorf("GetLogsAfterCursor() cursor = %q, want %q", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestShardedErrorLogsAfterCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     string
+		limit      int
+		want       []string
+		wantCursor string
+		wantErr    bool
+	}{
+		{name: "limit per shard", limit: 1, want: []string{"api-1", "checkout-2"}, wantCursor: "1.2"},
+		{name: "resumed", cursor: "1.2", limit: 1, want: []string{"api-3"}, wantCursor: "3.2"},
+		{name: "all", limit: 10, want: []string{"api-1", "api-3", "checkout-2"}, wantCursor: "3.2"},
+		{name: "cursor of another shard count", cursor: "1.2.3", limit: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, cursor, err := newCursorRepository().GetErrorLogsAfterCursor(context.Background(), tt.cursor, tt.limit)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetErrorLogsAfterCursor() = %q, want an error", messages(logs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetErrorLogsAfterCursor() error: %v", err)
+			}
+			if got := messages(logs); !slices.Equal(got, tt.want) {
+				t.Errorf("GetErrorLogsAfterCursor() logs = %q, want %q", got, tt.want)
+			}
+			if cursor != tt.wantCursor {
+				t.Errorf("GetErrorLogsAfterCursor() cursor = %q, want %q", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestUnionLogTables(t *testing.T) {
+	quote := func(field any) string { return fmt.Sprintf("`%s`", field) }
+
+	tests := []struct {
+		name   string
+		query  string
+		tables []string
+		want   string
+	}{
+		{
+			name:   "default table only",
+			query:  "SELECT COUNT(*) as value FROM logs WHERE timestamp >= ?",
+			tables: []string{"logs"},
+			want:   "SELECT COUNT(*) as value FROM logs WHERE timestamp >= ?",
+		},
+		{
+			name:   "routed tables",
+			query:  "SELECT COUNT(*) as value FROM logs WHERE timestamp >= ?",
+			tables: []string{"logs", "logs_checkout", "logs_search"},
+			want:   "SELECT COUNT(*) as value FROM (SELECT * FROM `logs` UNION ALL SELECT * FROM `logs_checkout` UNION ALL SELECT * FROM `logs_search`) AS logs WHERE timestamp >= ?",
+		},
+		{
+			name:   "subquery and case",
+			query:  "SELECT MAX(p) FROM (SELECT response_time_ms AS p\n\t\tfrom LOGS WHERE level = ?) ranked",
+			tables: []string{"logs", "logs_checkout"},
+			want:   "SELECT MAX(p) FROM (SELECT response_time_ms AS p\n\t\tFROM (SELECT * FROM `logs` UNION ALL SELECT * FROM `logs_checkout`) AS logs WHERE level = ?) ranked",
+		},
+		{
+			name:   "other tables kept",
+			query:  "SELECT id FROM logs_archive WHERE service IN (SELECT service FROM logs)",
+			tables: []string{"logs", "logs_checkout"},
+			want:   "SELECT id FROM logs_archive WHERE service IN (SELECT service FROM (SELECT * FROM `logs` UNION ALL SELECT * FROM `logs_checkout`) AS logs)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unionLogTables(quote, tt.query, tt.tables); got != tt.want {
+				t.Errorf("unionLogTables()\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDailyGrowth(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		name    string
+		results [][]models.DailyGrowth
+		want    []models.DailyGrowth
+	}{
+		{name: "no shards", want: []models.DailyGrowth{}},
+		{
+			name: "summed by day",
+			results: [][]models.DailyGrowth{
+				{{Day: day(1), Rows: 10}, {Day: day(3), Rows: 30}},
+				nil,
+				{{Day: day(2), Rows: 2}, {Day: day(3), Rows: 3}},
+			},
+			want: []models.DailyGrowth{{Day: day(1), Rows: 10}, {Day: day(2), Rows: 2}, {Day: day(3), Rows: 33}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeDailyGrowth(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeDailyGrowth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeServiceLogCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		results [][]models.ServiceStorageShare
+		want    []models.ServiceStorageShare
+	}{
+		{name: "no shards", want: []models.ServiceStorageShare{}},
+		{
+			name: "most first",
+			results: [][]models.ServiceStorageShare{
+				{{Service: "api", Rows: 50}, {Service: "web", Rows: 5}},
+				{{Service: "checkout", Rows: 80}},
+			},
+			want: []models.ServiceStorageShare{{Service: "checkout", Rows: 80}, {Service: "api", Rows: 50}, {Service: "web", Rows: 5}},
+		},
+		{
+			name: "service in several shards after rerouting",
+			results: [][]models.ServiceStorageShare{
+				{{Service: "api", Rows: 50}, {Service: "checkout", Rows: 20}},
+				{{Service: "checkout", Rows: 40}},
+			},
+			want: []models.ServiceStorageShare{{Service: "checkout", Rows: 60}, {Service: "api", Rows: 50}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeServiceLogCounts(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeServiceLogCounts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maintenanceStateID is the primary key of the single maintenance state row
+const maintenanceStateID = 1
+
+// MaintenanceRepository defines the interface for maintenance state operations
+type MaintenanceRepository interface {
+	// GetState returns the maintenance state, or the zero state if it was never set
+	GetState(ctx context.Context) (*models.MaintenanceState, error)
+	// SaveState stores the maintenance state
+	SaveState(ctx context.Context, state *models.MaintenanceState) error
+}
+
+// GormMaintenanceRepository implements MaintenanceRepository using GORM
+type GormMaintenanceRepository struct {
+	db *gorm.DB
+}
+
+// NewMaintenanceRepository creates a new maintenance repository
+func NewMaintenanceRepository(db *gorm.DB) MaintenanceRepository {
+	return &GormMaintenanceRepository{db: db}
+}
+
+// GetState returns the maintenance state
+func (r *GormMaintenanceRepository) GetState(ctx context.Context) (*models.MaintenanceState, error) {
+	var state models.MaintenanceState
+	err := r.db.WithContext(ctx).First(&state, maintenanceStateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.MaintenanceState{ID: maintenanceStateID}, nil
+	}
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get maintenance state")
+	}
+	return &state, nil
+}
+
+// SaveState stores the maintenance state, creating the row if necessary
+func (r *GormMaintenanceRepository) SaveState(ctx context.Context, state *models.MaintenanceState) error {
+	state.ID = maintenanceStateID
+	return database.TranslateError(r.db.WithContext(ctx).Save(state).Error, "failed to save maintenance state")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MigrationRepository defines the interface for reading the schema migration history
+type MigrationRepository interface {
+	// GetMigrations returns the applied migrations in the order they were applied
+	GetMigrations(ctx context.Context) ([]models.Migration, error)
+}
+
+// GormMigrationRepository implements MigrationRepository using GORM
+type GormMigrationRepository struct {
+	db *gorm.DB
+}
+
+// NewMigrationRepository creates a new migration repository
+func NewMigrationRepository(db *gorm.DB) MigrationRepository {
+	return &GormMigrationRepository{db: db}
+}
+
+// GetMigrations returns the applied migrations in the order they were applied
+func (r *GormMigrationRepository) GetMigrations(ctx context.Context) ([]models.Migration, error) {
+	var migrations []models.Migration
+	err := r.db.WithContext(ctx).Order("applied_at, id").Find(&migrations).Error
+	return migrations, database.TranslateError(err, "failed to get migrations")
+}
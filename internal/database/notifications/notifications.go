@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository defines the interface for notification channel operations
+type NotificationRepository interface {
+	CreateChannel(ctx context.Context, channel *models.NotificationChannel) error
+	GetChannels(ctx context.Context) ([]models.NotificationChannel, error)
+	GetChannelByID(ctx context.Context, id uint) (*models.NotificationChannel, error)
+	UpdateChannel(ctx context.Context, channel *models.NotificationChannel) error
+	DeleteChannel(ctx context.Context, id uint) error
+	GetRuleChannels(ctx context.Context, ruleID uint) ([]models.NotificationChannel, error)
+	SetRuleChannels(ctx context.Context, ruleID uint, channelIDs []uint) error
+}
+
+// GormNotificationRepository implements NotificationRepository using GORM
+type GormNotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &GormNotificationRepository{db: db}
+}
+
+// CreateChannel creates a new notification channel
+func (r *GormNotificationRepository) CreateChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	return database.TranslateError(r.db.WithContext(ctx).Create(channel).Error, "failed to create notification channel")
+}
+
+// GetChannels retrieves all notification channels
+func (r *GormNotificationRepository) GetChannels(ctx context.Context) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := r.db.WithContext(ctx).Order("id").Find(&channels).Error
+	return channels, database.TranslateError(err, "failed to get notification channels")
+}
+
+// GetChannelByID retrieves a notification channel by ID
+func (r *GormNotificationRepository) GetChannelByID(ctx context.Context, id uint) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	err := r.db.WithContext(ctx).First(&channel, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Notification channel not found")
+		}
+		return nil, database.TranslateError(err, "failed to get notification channel")
+	}
+	return &channel, nil
+}
+
+// UpdateChannel updates a notification channel
+func (r *GormNotificationRepository) UpdateChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	// Save inserts when no row matches, so missing channels must be rejected explicitly
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.NotificationChannel{}).Where("id = ?", channel.ID).Count(&count).Error; err != nil {
+		return database.TranslateError(err, "failed to update notification channel")
+	}
+	if count == 0 {
+		return apperrors.NotFound("Notification channel not found")
+	}
+
+	err := r.db.WithContext(ctx).Omit("created_at").Save(channel).Error
+	return database.TranslateError(err, "failed to update notification channel")
+}
+
+// DeleteChannel deletes a notification channel, unbinding it from all alert rules
+func (r *GormNotificationRepository) DeleteChannel(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.NotificationChannel{}, id)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to delete notification channel")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Notification channel not found")
+	}
+	return nil
+}
+
+// GetRuleChannels retrieves the channels an alert rule notifies
+func (r *GormNotificationRepository) GetRuleChannels(ctx context.Context, ruleID uint) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := r.db.WithContext(ctx).
+		Joins("JOIN alert_rule_channels ON alert_rule_channels.channel_id = notification_channels.id").
+		Where("alert_rule_channels.rule_id = ?", ruleID).
+		Order("notification_channels.id").
+		Find(&channels).Error
+	return channels, database.TranslateError(err, "failed to get alert rule channels")
+}
+
+// SetRuleChannels replaces the channels an alert rule notifies
+func (r *GormNotificationRepository) SetRuleChannels(ctx context.Context, ruleID uint, channelIDs []uint) error {
+	slices.Sort(channelIDs)
+	channelIDs = slices.Compact(channelIDs)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rules int64
+		if err := tx.Model(&models.AlertRule{}).Where("id = ?", ruleID).Count(&rules).Error; err != nil {
+			return err
+		}
+		if rules == 0 {
+			return apperrors.NotFound("Alert rule not found")
+		}
+
+		if len(channelIDs) > 0 {
+			var channels int64
+			if err := tx.Model(&models.NotificationChannel{}).Where("id IN ?", channelIDs).Count(&channels).Error; err != nil {
+				return err
+			}
+			if channels != int64(len(channelIDs)) {
+				return apperrors.Validation("Unknown notification channel")
+			}
+		}
+
+		if err := tx.Where("rule_id = ?", ruleID).Delete(&models.AlertRuleChannel{}).Error; err != nil {
+			return err
+		}
+		if len(channelIDs) == 0 {
+			return nil
+		}
+
+		bindings := make([]models.AlertRuleChannel, len(channelIDs))
+		for i, channelID := range channelIDs {
+			bindings[i] = models.AlertRuleChannel{RuleID: ruleID, ChannelID: channelID}
+		}
+		return tx.Create(&bindings).Error
+	})
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return database.TranslateError(err, "failed to set alert rule channels")
+}
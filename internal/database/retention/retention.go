@@ -0,0 +1,98 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RetentionRepository defines the interface for log retention policy operations
+type RetentionRepository interface {
+	CreatePolicy(ctx context.Context, policy *models.LogRetentionPolicy) error
+	GetPolicies(ctx context.Context) ([]models.LogRetentionPolicy, error)
+	GetPolicyByID(ctx context.Context, id uint) (*models.LogRetentionPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *models.LogRetentionPolicy) error
+	DeletePolicy(ctx context.Context, id uint) error
+	RecordPolicyRun(ctx context.Context, id uint, at time.Time, purged int64) error
+}
+
+// GormRetentionRepository implements RetentionRepository using GORM
+type GormRetentionRepository struct {
+	db *gorm.DB
+}
+
+// NewRetentionRepository creates a new retention repository
+func NewRetentionRepository(db *gorm.DB) RetentionRepository {
+	return &GormRetentionRepository{db: db}
+}
+
+// CreatePolicy creates a new retention policy
+func (r *GormRetentionRepository) CreatePolicy(ctx context.Context, policy *models.LogRetentionPolicy) error {
+	return database.TranslateError(r.db.WithContext(ctx).Create(policy).Error, "failed to create retention policy")
+}
+
+// GetPolicies retrieves all retention policies
+func (r *GormRetentionRepository) GetPolicies(ctx context.Context) ([]models.LogRetentionPolicy, error) {
+	var policies []models.LogRetentionPolicy
+	err := r.db.WithContext(ctx).Order("id").Find(&policies).Error
+	return policies, database.TranslateError(err, "failed to get retention policies")
+}
+
+// GetPolicyByID retrieves a retention policy by ID
+func (r *GormRetentionRepository) GetPolicyByID(ctx context.Context, id uint) (*models.LogRetentionPolicy, error) {
+	var policy models.LogRetentionPolicy
+	err := r.db.WithContext(ctx).First(&policy, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Retention policy not found")
+		}
+		return nil, database.TranslateError(err, "failed to get retention policy")
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy replaces a retention policy's scope, maximum age, action and enabled flag, keeping the
+// outcome of its last run, and reloads the policy
+func (r *GormRetentionRepository) UpdatePolicy(ctx context.Context, policy *models.LogRetentionPolicy) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.LogRetentionPolicy{}).Where("id = ?", policy.ID).Count(&count).Error; err != nil {
+		return database.TranslateError(err, "failed to update retention policy")
+	}
+	if count == 0 {
+		return apperrors.NotFound("Retention policy not found")
+	}
+
+	err := r.db.WithContext(ctx).Model(&models.LogRetentionPolicy{}).
+		Where("id = ?", policy.ID).
+		Select("service", "level", "max_age_days", "action", "enabled", "updated_at").
+		Updates(policy).Error
+	if err != nil {
+		return database.TranslateError(err, "failed to update retention policy")
+	}
+	return database.TranslateError(r.db.WithContext(ctx).First(policy, policy.ID).Error, "failed to get retention policy")
+}
+
+// DeletePolicy deletes a retention policy
+func (r *GormRetentionRepository) DeletePolicy(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.LogRetentionPolicy{}, id)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to delete retention policy")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Retention policy not found")
+	}
+	return nil
+}
+
+// RecordPolicyRun records when a policy last ran and how many logs it removed
+func (r *GormRetentionRepository) RecordPolicyRun(ctx context.Context, id uint, at time.Time, purged int64) error {
+	err := r.db.WithContext(ctx).Model(&models.LogRetentionPolicy{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_run_at": at, "last_purged": purged}).Error
+	return database.TranslateError(err, "failed to record retention policy run")
+}
@@ -0,0 +1,87 @@
+package saved_searches
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SavedSearchRepository defines the interface for saved search operations
+type SavedSearchRepository interface {
+	CreateSearch(ctx context.Context, search *models.SavedSearch) error
+	GetSearches(ctx context.Context) ([]models.SavedSearch, error)
+	GetSearchByID(ctx context.Context, id uint) (*models.SavedSearch, error)
+	UpdateSearch(ctx context.Context, search *models.SavedSearch) error
+	DeleteSearch(ctx context.Context, id uint) error
+}
+
+// GormSavedSearchRepository implements SavedSearchRepository using GORM
+type GormSavedSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchRepository creates a new saved search repository
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return &GormSavedSearchRepository{db: db}
+}
+
+// CreateSearch creates a new saved search
+func (r *GormSavedSearchRepository) CreateSearch(ctx context.Context, search *models.SavedSearch) error {
+	return database.TranslateError(r.db.WithContext(ctx).Create(search).Error, "failed to create saved search")
+}
+
+// GetSearches retrieves all saved searches by name
+func (r *GormSavedSearchRepository) GetSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.db.WithContext(ctx).Order("name").Find(&searches).Error
+	return searches, database.TranslateError(err, "failed to get saved searches")
+}
+
+// GetSearchByID retrieves a saved search by ID
+func (r *GormSavedSearchRepository) GetSearchByID(ctx context.Context, id uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.db.WithContext(ctx).First(&search, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Saved search not found")
+		}
+		return nil, database.TranslateError(err, "failed to get saved search")
+	}
+	return &search, nil
+}
+
+// UpdateSearch replaces a saved search's name and filter, keeping its creator, and reloads the search
+func (r *GormSavedSearchRepository) UpdateSearch(ctx context.Context, search *models.SavedSearch) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.SavedSearch{}).Where("id = ?", search.ID).Count(&count).Error; err != nil {
+		return database.TranslateError(err, "failed to update saved search")
+	}
+	if count == 0 {
+		return apperrors.NotFound("Saved search not found")
+	}
+
+	err := r.db.WithContext(ctx).Model(&models.SavedSearch{}).
+		Where("id = ?", search.ID).
+		Select("name", "filter", "updated_at").
+		Updates(search).Error
+	if err != nil {
+		return database.TranslateError(err, "failed to update saved search")
+	}
+	return database.TranslateError(r.db.WithContext(ctx).First(search, search.ID).Error, "failed to get saved search")
+}
+
+// DeleteSearch deletes a saved search
+func (r *GormSavedSearchRepository) DeleteSearch(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.SavedSearch{}, id)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to delete saved search")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Saved search not found")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchedulerRepository defines the interface for scheduled task lease and run history operations
+type SchedulerRepository interface {
+	// EnsureTask creates the task if it doesn't exist, and updates its interval if it does
+	EnsureTask(ctx context.Context, name string, interval time.Duration) error
+	// AcquireLease takes the task's lease until leaseUntil and records the start of a run, provided no other API
+	// server holds an unexpired lease and the last run started at least an interval ago. It returns nil when the
+	// lease wasn't acquired.
+	AcquireLease(ctx context.Context, name, owner string, interval time.Duration, now, leaseUntil time.Time) (*models.ScheduledTaskRun, error)
+	// RenewLease extends the lease held by owner, reporting whether it still held it
+	RenewLease(ctx context.Context, name, owner string, leaseUntil time.Time) (bool, error)
+	// FinishRun records the outcome of a run and releases the lease
+	FinishRun(ctx context.Context, run *models.ScheduledTaskRun) error
+	// GetTasks returns all scheduled tasks by name
+	GetTasks(ctx context.Context) ([]models.ScheduledTask, error)
+	// GetRuns returns the latest runs of a task, newest first
+	GetRuns(ctx context.Context, name string, limit int) ([]models.ScheduledTaskRun, error)
+	// PruneRuns deletes runs started before the cutoff
+	PruneRuns(ctx context.Context, before time.Time) (int64, error)
+}
+
+// GormSchedulerRepository implements SchedulerRepository using GORM
+type GormSchedulerRepository struct {
+	db *gorm.DB
+}
+
+// NewSchedulerRepository creates a new scheduler repository
+func NewSchedulerRepository(db *gorm.DB) SchedulerRepository {
+	return &GormSchedulerRepository{db: db}
+}
+
+// EnsureTask creates the task if it doesn't exist, and updates its interval if it does
+func (r *GormSchedulerRepository) EnsureTask(ctx context.Context, name string, interval time.Duration) error {
+	task := models.ScheduledTask{Name: name, IntervalSeconds: int64(interval / time.Second)}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"interval_seconds"}),
+	}).Create(&task).Error
+	return database.TranslateError(err, "failed to register scheduled task")
+}
+
+// AcquireLease takes the task's lease and records the start of a run when the task is due and not leased
+func (r *GormSchedulerRepository) AcquireLease(ctx context.Context, name, owner string, interval time.Duration, now, leaseUntil time.Time) (*models.ScheduledTaskRun, error) {
+	var run *models.ScheduledTaskRun
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ScheduledTask{}).
+			Where("name = ?", name).
+			Where("lease_expires_at IS NULL OR lease_expires_at < ?", now).
+			Where("last_started_at IS NULL OR last_started_at <= ?", now.Add(-interval)).
+			Updates(map[string]any{
+				"lease_owner":      owner,
+				"lease_expires_at": leaseUntil,
+				"last_started_at":  now,
+				"last_status":      constants.TaskRunRunning,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		run = &models.ScheduledTaskRun{Task: name, Owner: owner, Status: constants.TaskRunRunning, StartedAt: now}
+		return tx.Create(run).Error
+	})
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to acquire scheduled task lease")
+	}
+	return run, nil
+}
+
+// RenewLease extends the lease held by owner, reporting whether it still held it
+func (r *GormSchedulerRepository) RenewLease(ctx context.Context, name, owner string, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.ScheduledTask{}).
+		Where("name = ? AND lease_owner = ?", name, owner).
+		Update("lease_expires_at", leaseUntil)
+	if result.Error != nil {
+		return false, database.TranslateError(result.Error, "failed to renew scheduled task lease")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FinishRun records the outcome of a run on the run and its task, and releases the lease if owner still holds it
+func (r *GormSchedulerRepository) FinishRun(ctx context.Context, run *models.ScheduledTaskRun) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(run).Select("status", "error", "finished_at", "duration_ms").Updates(run).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ScheduledTask{}).
+			Where("name = ? AND lease_owner = ?", run.Task, run.Owner).
+			Updates(map[string]any{
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
+				"last_finished_at": run.FinishedAt,
+				"last_status":      run.Status,
+				"last_error":       run.Error,
+				"last_duration_ms": run.DurationMs,
+			}).Error
+	})
+	return database.TranslateError(err, "failed to record scheduled task run")
+}
+
+// GetTasks returns all scheduled tasks by name
+func (r *GormSchedulerRepository) GetTasks(ctx context.Context) ([]models.ScheduledTask, error) {
+	var tasks []models.ScheduledTask
+	err := r.db.WithContext(ctx).Order("name").Find(&tasks).Error
+	return tasks, database.TranslateError(err, "failed to get scheduled tasks")
+}
+
+// GetRuns returns the latest runs of a task, newest first
+func (r *GormSchedulerRepository) GetRuns(ctx context.Context, name string, limit int) ([]models.ScheduledTaskRun, error) {
+	var runs []models.ScheduledTaskRun
+	err := r.db.WithContext(ctx).Where("task = ?", name).Order("started_at DESC, id DESC").Limit(limit).Find(&runs).Error
+	return runs, database.TranslateError(err, "failed to get scheduled task runs")
+}
+
+// PruneRuns deletes runs started before the cutoff
+func (r *GormSchedulerRepository) PruneRuns(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("started_at < ?", before).Delete(&models.ScheduledTaskRun{})
+	return result.RowsAffected, database.TranslateError(result.Error, "failed to prune scheduled task runs")
+}
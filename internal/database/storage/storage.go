@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// StorageRepository defines the interface for storage statistics queries. Log growth is reported by the log store,
+// see logs.LogStorage.
+type StorageRepository interface {
+	GetTableStorage(ctx context.Context) ([]models.TableStorage, error)
+}
+
+// GormStorageRepository implements StorageRepository using GORM
+type GormStorageRepository struct {
+	db *gorm.DB
+}
+
+// NewStorageRepository creates a new storage repository
+func NewStorageRepository(db *gorm.DB) StorageRepository {
+	return &GormStorageRepository{db: db}
+}
+
+// mysqlTableStorage retrieves the table and index sizes of the current schema from information_schema
+const mysqlTableStorage = `
+	SELECT
+		TABLE_NAME AS table_name,
+		COALESCE(TABLE_ROWS, 0) AS row_count,
+		COALESCE(DATA_LENGTH, 0) AS data_bytes,
+		COALESCE(INDEX_LENGTH, 0) AS index_bytes,
+		COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0) AS total_bytes,
+		COALESCE(AVG_ROW_LENGTH, 0) AS avg_row_size
+	FROM information_schema.TABLES
+	WHERE TABLE_SCHEMA = DATABASE()
+	ORDER BY total_bytes DESC
+`
+
+// postgresTableStorage retrieves the table and index sizes of the current schema from the Postgres catalog. Row
+// counts are the planner's estimates, like those of information_schema.
+const postgresTableStorage = `
+	SELECT
+		c.relname AS table_name,
+		CAST(GREATEST(c.reltuples, 0) AS BIGINT) AS row_count,
+		pg_table_size(c.oid) AS data_bytes,
+		pg_indexes_size(c.oid) AS index_bytes,
+		pg_total_relation_size(c.oid) AS total_bytes,
+		CASE WHEN c.reltuples > 0 THEN CAST(pg_table_size(c.oid) / c.reltuples AS BIGINT) ELSE 0 END AS avg_row_size
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('r', 'p') AND n.nspname = current_schema()
+	ORDER BY total_bytes DESC
+`
+
+// GetTableStorage retrieves table and index sizes for the current schema
+func (r *GormStorageRepository) GetTableStorage(ctx context.Context) ([]models.TableStorage, error) {
+	query := mysqlTableStorage
+	if database.IsPostgres(r.db) {
+		query = postgresTableStorage
+	}
+	var tables []models.TableStorage
+	if err := r.db.WithContext(ctx).Raw(query).Scan(&tables).Error; err != nil {
+		return nil, database.TranslateError(err, "failed to get table storage")
+	}
+	return tables, nil
+}
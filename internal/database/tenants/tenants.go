@@ -0,0 +1,84 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TenantRepository defines the interface for tenant operations
+type TenantRepository interface {
+	CreateTenant(ctx context.Context, tenant *models.Tenant) error
+	GetTenants(ctx context.Context) ([]models.Tenant, error)
+	GetTenantByID(ctx context.Context, id uint) (*models.Tenant, error)
+	UpdateTenant(ctx context.Context, tenant *models.Tenant) error
+	DeleteTenant(ctx context.Context, id uint) error
+}
+
+// GormTenantRepository implements TenantRepository using GORM
+type GormTenantRepository struct {
+	db *gorm.DB
+}
+
+// NewTenantRepository creates a new tenant repository
+func NewTenantRepository(db *gorm.DB) TenantRepository {
+	return &GormTenantRepository{db: db}
+}
+
+// CreateTenant registers a new tenant
+func (r *GormTenantRepository) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	return database.TranslateError(r.db.WithContext(ctx).Create(tenant).Error, "failed to create tenant")
+}
+
+// GetTenants retrieves all tenants by name
+func (r *GormTenantRepository) GetTenants(ctx context.Context) ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	err := r.db.WithContext(ctx).Order("name").Find(&tenants).Error
+	return tenants, database.TranslateError(err, "failed to get tenants")
+}
+
+// GetTenantByID retrieves a tenant by ID
+func (r *GormTenantRepository) GetTenantByID(ctx context.Context, id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := r.db.WithContext(ctx).First(&tenant, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("Tenant not found")
+		}
+		return nil, database.TranslateError(err, "failed to get tenant")
+	}
+	return &tenant, nil
+}
+
+// UpdateTenant replaces a tenant's description and reloads the tenant. Its name is kept, since the tenant's
+// logs, alert rules and alerts are tagged with it.
+func (r *GormTenantRepository) UpdateTenant(ctx context.Context, tenant *models.Tenant) error {
+	result := r.db.WithContext(ctx).Model(&models.Tenant{}).
+		Where("id = ?", tenant.ID).
+		Select("description", "updated_at").
+		Updates(tenant)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to update tenant")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Tenant not found")
+	}
+	return database.TranslateError(r.db.WithContext(ctx).First(tenant, tenant.ID).Error, "failed to get tenant")
+}
+
+// DeleteTenant deletes a tenant. Its logs, alert rules and alerts are kept, but logs are no longer accepted with
+// its tenant header.
+func (r *GormTenantRepository) DeleteTenant(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Tenant{}, id)
+	if result.Error != nil {
+		return database.TranslateError(result.Error, "failed to delete tenant")
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Tenant not found")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueryTrace collects the SQL statements run through GORM on behalf of a request, for the debug output of
+// API responses. Statements are recorded with placeholders instead of their arguments, and string literals
+// written into the SQL itself are masked, so traces don't leak log contents or credentials.
+type QueryTrace struct {
+	mu        sync.Mutex
+	limit     int
+	queries   []TracedQuery
+	total     time.Duration
+	truncated bool
+}
+
+// TracedQuery is a statement recorded by a query trace
+type TracedQuery struct {
+	SQL        string  `json:"sql"`
+	DurationMs float64 `json:"duration_ms"`
+	Rows       int64   `json:"rows"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// QueryTraceSummary is the content of a query trace
+type QueryTraceSummary struct {
+	Queries    []TracedQuery `json:"queries"`
+	QueryCount int           `json:"query_count"`
+	TotalMs    float64       `json:"total_ms"`
+	Truncated  bool          `json:"truncated"` // whether statements beyond the limit were counted but not recorded
+}
+
+// queryTraceKey is the context key of the query trace
+type queryTraceKey struct{}
+
+// traceStartKey is the statement instance key of the time a traced statement started
+const traceStartKey = "trace:started_at"
+
+// stringLiteral matches single-quoted SQL string literals, including escaped and doubled quotes
+var stringLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+
+// WithQueryTrace returns a context recording the statements run with it, at most limit of them
+func WithQueryTrace(ctx context.Context, limit int) (context.Context, *QueryTrace) {
+	trace := &QueryTrace{limit: limit}
+	return context.WithValue(ctx, queryTraceKey{}, trace), trace
+}
+
+// Summary returns the statements recorded so far
+func (t *QueryTrace) Summary() QueryTraceSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := len(t.queries)
+	if t.truncated {
+		count = t.limit + 1
+	}
+	return QueryTraceSummary{
+		Queries:    append([]TracedQuery{}, t.queries...),
+		QueryCount: count,
+		TotalMs:    float64(t.total.Microseconds()) / 1000,
+		Truncated:  t.truncated,
+	}
+}
+
+// SanitizeSQL masks the string literals written into a statement or a database error, which may hold log
+// contents or credentials
+func SanitizeSQL(sql string) string {
+	return stringLiteral.ReplaceAllString(sql, "'?'")
+}
+
+// record adds a statement to the trace
+func (t *QueryTrace) record(query TracedQuery, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += duration
+	if len(t.queries) >= t.limit {
+		t.truncated = true
+		return
+	}
+	t.queries = append(t.queries, query)
+}
+
+// registerQueryTrace adds callbacks timing every statement run with a traced context
+func registerQueryTrace(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("trace:before_create", startTrace),
+		callbacks.Create().After("gorm:create").Register("trace:after_create", finishTrace),
+		callbacks.Query().Before("gorm:query").Register("trace:before_query", startTrace),
+		callbacks.Query().After("gorm:query").Register("trace:after_query", finishTrace),
+		callbacks.Update().Before("gorm:update").Register("trace:before_update", startTrace),
+		callbacks.Update().After("gorm:update").Register("trace:after_update", finishTrace),
+		callbacks.Delete().Before("gorm:delete").Register("trace:before_delete", startTrace),
+		callbacks.Delete().After("gorm:delete").Register("trace:after_delete", finishTrace),
+		callbacks.Row().Before("gorm:row").Register("trace:before_row", startTrace),
+		callbacks.Row().After("gorm:row").Register("trace:after_row", finishTrace),
+		callbacks.Raw().Before("gorm:raw").Register("trace:before_raw", startTrace),
+		callbacks.Raw().After("gorm:raw").Register("trace:after_raw", finishTrace),
+	)
+}
+
+// startTrace records when a traced statement starts
+func startTrace(db *gorm.DB) {
+	if db.Statement.Context.Value(queryTraceKey{}) != nil {
+		db.InstanceSet(traceStartKey, time.Now())
+	}
+}
+
+// finishTrace adds a traced statement to its trace
+func finishTrace(db *gorm.DB) {
+	trace, ok := db.Statement.Context.Value(queryTraceKey{}).(*QueryTrace)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(traceStartKey)
+	if !ok {
+		return
+	}
+	duration := time.Since(value.(time.Time))
+
+	query := TracedQuery{
+		SQL:        SanitizeSQL(db.Statement.SQL.String()),
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		Rows:       db.RowsAffected,
+	}
+	if db.Error != nil {
+		query.Error = SanitizeSQL(db.Error.Error())
+	}
+	trace.record(query, duration)
+}
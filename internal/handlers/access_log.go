@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/pkg/slogkafka"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs every request with attributes named after the log columns, replacing gin's text logger with the
+// server's structured logs. Access logs published to Kafka are stored with their method, path, status, response time, client IP, request ID as trace ID and
+// authenticated user. Server errors are logged at ERROR and client errors at WARN.
+//
+// Requests to the excluded paths, and the logs of their handlers that carry the request context, are only written
+// locally: serving logs must not itself produce logs, or every live tail poll would be answered by the access log
+// of the previous one.
+func AccessLog(logger *slog.Logger, excludePaths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx := c.Request.Context()
+		if slices.Contains(excludePaths, c.Request.URL.Path) {
+			ctx = slogkafka.Suppress(ctx)
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_method", c.Request.Method),
+			slog.String("request_path", c.Request.URL.Path),
+			slog.Int("response_status", status),
+			slog.Int64("response_time_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("response_bytes", max(c.Writer.Size(), 0)),
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+		}
+		if requestID := c.GetString(constants.RequestIDKey); requestID != "" {
+			attrs = append(attrs, slog.String("trace_id", requestID))
+		}
+		if principal, ok := c.Get(constants.AuthPrincipalKey); ok {
+			attrs = append(attrs, slog.String("user_id", principal.(*models.Principal).Username))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		logger.LogAttrs(ctx, level, constants.SelfIngestionAccessLogMessage, attrs...)
+	}
+}
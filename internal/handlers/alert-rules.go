@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database/alert_rules"
 	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,23 +20,35 @@ import (
 // AlertRuleHandler handles alert rule-related HTTP requests
 type AlertRuleHandler struct {
 	alertRuleRepo alert_rules.AlertRuleRepository
+	alertService  *services.AlertService
+	canaryPeriod  time.Duration // default canary period of edits; 0 applies them immediately
 	logger        *slog.Logger
 }
 
 // NewAlertRuleHandler creates a new alert rule handler
-func NewAlertRuleHandler(alertRuleRepo alert_rules.AlertRuleRepository, logger *slog.Logger) *AlertRuleHandler {
+func NewAlertRuleHandler(alertRuleRepo alert_rules.AlertRuleRepository, alertService *services.AlertService, canaryPeriod time.Duration, logger *slog.Logger) *AlertRuleHandler {
 	return &AlertRuleHandler{
 		alertRuleRepo: alertRuleRepo,
+		alertService:  alertService,
+		canaryPeriod:  canaryPeriod,
 		logger:        logger,
 	}
 }
 
-// CreateAlertRule creates a new alert rule
+// CreateAlertRule creates a new alert rule, belonging to the caller's tenant when the caller is limited to one
 func (h *AlertRuleHandler) CreateAlertRule(c *gin.Context) {
 	var rule models.AlertRule
 	if err := c.ShouldBindJSON(&rule); err != nil {
 		h.logger.Error("Failed to bind alert rule", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := ruleTenant(c, &rule); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if err := h.validateRule(c.Request.Context(), &rule, 0); err != nil {
+		respondError(c, err, "Failed to create alert rule")
 		return
 	}
 
@@ -40,23 +57,36 @@ func (h *AlertRuleHandler) CreateAlertRule(c *gin.Context) {
 
 	if err := h.alertRuleRepo.CreateAlertRule(c.Request.Context(), &rule); err != nil {
 		h.logger.Error("Failed to create alert rule", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		respondError(c, err, "Failed to create alert rule")
 		return
 	}
 
-	c.JSON(http.StatusCreated, rule)
+	respond(c, http.StatusCreated, rule, rule)
 }
 
-// GetAlertRules retrieves all alert rules
+// GetAlertRules retrieves all alert rules, optionally of a single tenant
 func (h *AlertRuleHandler) GetAlertRules(c *gin.Context) {
-	rules, err := h.alertRuleRepo.GetAlertRules(c.Request.Context())
+	limit, offset, err := pageParams(c, 0) // unversioned routes return all rules by default
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	rules, err := h.alertRuleRepo.GetAlertRules(c.Request.Context(), tenant)
 	if err != nil {
 		h.logger.Error("Failed to get alert rules", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alert rules"})
+		respondError(c, err, "Failed to get alert rules")
 		return
 	}
 
-	c.JSON(http.StatusOK, rules)
+	// Rules are few, so they are paged in memory
+	rules = rules[min(offset, len(rules)):]
+	respondPage(c, rules, limit, offset, func(page []models.AlertRule) any { return page })
 }
 
 // GetAlertRuleByID retrieves an alert rule by ID
@@ -64,46 +94,114 @@ func (h *AlertRuleHandler) GetAlertRuleByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule ID"})
+		respondValidationError(c, "Invalid alert rule ID")
 		return
 	}
 
 	rule, err := h.alertRuleRepo.GetAlertRuleByID(c.Request.Context(), uint(id))
+	if err == nil && !canAccessTenant(c, rule.Tenant) {
+		err = apperrors.NotFound("Alert rule not found")
+	}
 	if err != nil {
 		h.logger.Error("Failed to get alert rule", "error", err, "id", id)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		respondError(c, err, "Failed to get alert rule")
 		return
 	}
 
-	c.JSON(http.StatusOK, rule)
+	respond(c, http.StatusOK, rule, rule)
 }
 
-// UpdateAlertRule updates an alert rule
+// UpdateAlertRule updates an alert rule. Edits of the condition, threshold or time window are canaried
+// for the period given by the canary_period parameter, or the configured default: the previous version
+// stays in effect while the edited one is evaluated alongside it. Edits of the type or anomaly settings
+// take effect immediately.
 func (h *AlertRuleHandler) UpdateAlertRule(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule ID"})
+		respondValidationError(c, "Invalid alert rule ID")
 		return
 	}
 
+	canaryPeriod := h.canaryPeriod
+	if periodStr := c.Query("canary_period"); periodStr != "" {
+		parsed, err := time.ParseDuration(periodStr)
+		if err != nil || parsed < 0 || parsed > constants.MaxAlertCanaryPeriod {
+			respondValidationError(c, fmt.Sprintf("Canary period must be a duration between 0s and %s", constants.MaxAlertCanaryPeriod))
+			return
+		}
+		canaryPeriod = parsed
+	}
+
 	var rule models.AlertRule
 	if err := c.ShouldBindJSON(&rule); err != nil {
 		h.logger.Error("Failed to bind alert rule", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := ruleTenant(c, &rule); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	existing, err := h.alertRuleRepo.GetAlertRuleByID(c.Request.Context(), uint(id))
+	if err == nil && !canAccessTenant(c, existing.Tenant) {
+		err = apperrors.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		h.logger.Error("Failed to get alert rule", "error", err, "id", id)
+		respondError(c, err, "Failed to update alert rule")
+		return
+	}
+	if err := h.validateRule(c.Request.Context(), &rule, uint(id)); err != nil {
+		respondError(c, err, "Failed to update alert rule")
 		return
 	}
 
 	rule.ID = uint(id)
 	rule.UpdatedAt = time.Now()
 
+	now := time.Now()
+	switch {
+	case !rule.SameAnomaly(existing):
+		// Canaries only hold the previous condition, threshold and time window, so changes of the type or
+		// anomaly settings take effect immediately and end a canary in progress
+		if err := h.alertRuleRepo.ClearCanaryEvaluations(c.Request.Context(), rule.ID); err != nil {
+			h.logger.Error("Failed to clear canary evaluations", "error", err, "id", id)
+			respondError(c, err, "Failed to update alert rule")
+			return
+		}
+	case rule.SameEvaluation(existing):
+		// Other edits leave a canary in progress untouched
+		rule.PreviousCondition = existing.PreviousCondition
+		rule.PreviousThreshold = existing.PreviousThreshold
+		rule.PreviousTimeWindow = existing.PreviousTimeWindow
+		rule.CanaryUntil = existing.CanaryUntil
+	case canaryPeriod > 0:
+		// Re-editing during a canary keeps the version in effect, not the one being canaried
+		previous := existing
+		if existing.InCanary(now) {
+			previous = existing.Previous()
+		}
+		until := now.Add(canaryPeriod)
+		rule.PreviousCondition = &previous.Condition
+		rule.PreviousThreshold = &previous.Threshold
+		rule.PreviousTimeWindow = &previous.TimeWindow
+		rule.CanaryUntil = &until
+		if err := h.alertRuleRepo.ClearCanaryEvaluations(c.Request.Context(), rule.ID); err != nil {
+			h.logger.Error("Failed to clear canary evaluations", "error", err, "id", id)
+			respondError(c, err, "Failed to update alert rule")
+			return
+		}
+	}
+
 	if err := h.alertRuleRepo.UpdateAlertRule(c.Request.Context(), &rule); err != nil {
 		h.logger.Error("Failed to update alert rule", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert rule"})
+		respondError(c, err, "Failed to update alert rule")
 		return
 	}
 
-	c.JSON(http.StatusOK, rule)
+	respond(c, http.StatusOK, rule, rule)
 }
 
 // DeleteAlertRule deletes an alert rule
@@ -111,15 +209,200 @@ func (h *AlertRuleHandler) DeleteAlertRule(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule ID"})
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	if !h.checkRuleTenant(c, uint(id)) {
 		return
 	}
 
 	if err := h.alertRuleRepo.DeleteAlertRule(c.Request.Context(), uint(id)); err != nil {
 		h.logger.Error("Failed to delete alert rule", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert rule"})
+		respondError(c, err, "Failed to delete alert rule")
+		return
+	}
+
+	body := gin.H{"message": "Alert rule deleted successfully"}
+	respond(c, http.StatusOK, body, body)
+}
+
+// MuteAlertRule suppresses new alerts of a rule until the time given by the until parameter
+func (h *AlertRuleHandler) MuteAlertRule(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	until, err := untilParam(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	if !h.checkRuleTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertRuleRepo.MuteAlertRule(c.Request.Context(), uint(id), until); err != nil {
+		h.logger.Error("Failed to mute alert rule", "error", err)
+		respondError(c, err, "Failed to mute alert rule")
+		return
+	}
+
+	body := gin.H{"message": "Alert rule muted successfully", "muted_until": until}
+	respond(c, http.StatusOK, body, body)
+}
+
+// EvaluateAlertRule evaluates a rule immediately, reporting its value, the query run and whether an alert would
+// fire. Nothing is created, resolved or notified, so rules can be tuned during an incident.
+func (h *AlertRuleHandler) EvaluateAlertRule(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	rule, err := h.alertRuleRepo.GetAlertRuleByID(c.Request.Context(), uint(id))
+	if err == nil && !canAccessTenant(c, rule.Tenant) {
+		err = apperrors.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		h.logger.Error("Failed to get alert rule", "error", err, "id", id)
+		respondError(c, err, "Failed to evaluate alert rule")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted successfully"})
+	evaluation, err := h.alertService.EvaluateRuleNow(c.Request.Context(), rule)
+	if err != nil {
+		h.logger.Error("Failed to evaluate alert rule", "error", err, "id", id)
+		respondError(c, err, "Failed to evaluate alert rule")
+		return
+	}
+
+	respond(c, http.StatusOK, evaluation, evaluation)
+}
+
+// GetCanaryReport reports how the previous and edited versions of a canaried rule differ in firing behavior
+func (h *AlertRuleHandler) GetCanaryReport(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	if !h.checkRuleTenant(c, uint(id)) {
+		return
+	}
+
+	report, err := h.alertRuleRepo.GetCanaryReport(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to get canary report", "error", err, "id", id)
+		respondError(c, err, "Failed to get canary report")
+		return
+	}
+
+	respond(c, http.StatusOK, report, report)
+}
+
+// PromoteCanary ends a rule's canary early, putting the edited version into effect
+func (h *AlertRuleHandler) PromoteCanary(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	if !h.checkRuleTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertRuleRepo.PromoteCanary(c.Request.Context(), uint(id)); err != nil {
+		h.logger.Error("Failed to promote canary", "error", err, "id", id)
+		respondError(c, err, "Failed to promote canary")
+		return
+	}
+
+	body := gin.H{"message": "Alert rule canary promoted successfully"}
+	respond(c, http.StatusOK, body, body)
+}
+
+// RollbackCanary ends a rule's canary by restoring the previous version
+func (h *AlertRuleHandler) RollbackCanary(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	if !h.checkRuleTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertRuleRepo.RollbackCanary(c.Request.Context(), uint(id)); err != nil {
+		h.logger.Error("Failed to roll back canary", "error", err, "id", id)
+		respondError(c, err, "Failed to roll back canary")
+		return
+	}
+
+	body := gin.H{"message": "Alert rule canary rolled back successfully"}
+	respond(c, http.StatusOK, body, body)
+}
+
+// checkRuleTenant responds with not found when the caller is limited to a tenant other than the rule's,
+// reporting whether the request may go on
+func (h *AlertRuleHandler) checkRuleTenant(c *gin.Context, id uint) bool {
+	if principalOf(c).TenantScope() == nil {
+		return true
+	}
+	rule, err := h.alertRuleRepo.GetAlertRuleByID(c.Request.Context(), id)
+	if err == nil && !canAccessTenant(c, rule.Tenant) {
+		err = apperrors.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		respondError(c, err, "Failed to get alert rule")
+		return false
+	}
+	return true
+}
+
+// ruleTenant puts a rule created or updated by a caller limited to a tenant into that tenant; other callers may
+// set any tenant, or none for rules over every tenant's logs. The condition grammar has no subqueries, so the
+// caller's rules can't reach beyond the tenant's logs.
+func ruleTenant(c *gin.Context, rule *models.AlertRule) error {
+	scope := principalOf(c).TenantScope()
+	if scope == nil {
+		return nil
+	}
+	if rule.Tenant != nil && *rule.Tenant != *scope {
+		return apperrors.Forbidden("Access to tenant %s is not allowed", *rule.Tenant)
+	}
+	rule.Tenant = scope
+	return nil
+}
+
+// validateRule checks the fields of a created or updated rule, and that no other rule of its tenant has its name,
+// so that broken rules never reach the alert checker
+func (h *AlertRuleHandler) validateRule(ctx context.Context, rule *models.AlertRule, id uint) error {
+	fields := apperrors.FieldsOf(rule.Validate())
+	if rule.Name != "" {
+		taken, err := h.alertRuleRepo.AlertRuleNameTaken(ctx, rule.Name, rule.Tenant, id)
+		if err != nil {
+			h.logger.Error("Failed to check alert rule name", "error", err)
+			return err
+		}
+		if taken {
+			fields = append(fields, apperrors.FieldError{Field: "name", Message: "is already used by another alert rule"})
+		}
+	}
+	if len(fields) > 0 {
+		return apperrors.Invalid(fields...)
+	}
+	return nil
 }
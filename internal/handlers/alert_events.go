@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// AlertEventsHandler pushes alert events to dashboards over WebSocket
+type AlertEventsHandler struct {
+	events         *services.AlertEventBus
+	allowedOrigins []string // CORS origins whose pages may connect besides the server's own
+	logger         *slog.Logger
+}
+
+// NewAlertEventsHandler creates a new alert events handler
+func NewAlertEventsHandler(events *services.AlertEventBus, allowedOrigins []string, logger *slog.Logger) *AlertEventsHandler {
+	return &AlertEventsHandler{
+		events:         events,
+		allowedOrigins: allowedOrigins,
+		logger:         logger,
+	}
+}
+
+// StreamAlertEvents upgrades the request to a WebSocket and sends a JSON message for every alert that is
+// created, resolved or acknowledged, and a heartbeat while idle. The connection is closed when the client falls
+// behind, after which it should reload the active alerts and reconnect. Only the alerts of the tenant are sent
+// when the tenant parameter is given or the caller is limited to one.
+func (h *AlertEventsHandler) StreamAlertEvents(c *gin.Context) {
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	sub, err := h.events.Subscribe()
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	defer h.events.Unsubscribe(sub)
+
+	server := websocket.Server{
+		Handshake: h.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			h.stream(ws, sub, tenant)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// stream writes the subscription's events, of the tenant's alerts when one is given, to the connection until either
+// side goes away
+func (h *AlertEventsHandler) stream(ws *websocket.Conn, sub *services.AlertEventSubscription, tenant *string) {
+	// Clients don't send anything; reading only notices when they close the connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	// Deadlines set by the server for the upgrade request still apply to the hijacked connection
+	ws.SetReadDeadline(time.Time{})
+
+	heartbeat := time.NewTicker(constants.AlertEventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var event models.AlertEvent
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			event = models.AlertEvent{Type: constants.AlertEventHeartbeat, At: time.Now()}
+		case next, ok := <-sub.Events():
+			if !ok {
+				h.logger.Warn("Alert event client fell behind, disconnecting")
+				return
+			}
+			if tenant != nil && (next.Alert == nil || next.Alert.Tenant == nil || *next.Alert.Tenant != *tenant) {
+				continue
+			}
+			event = next
+		}
+
+		ws.SetWriteDeadline(time.Now().Add(constants.AlertEventWriteTimeout))
+		if err := websocket.JSON.Send(ws, event); err != nil {
+			h.logger.Debug("Alert event client disconnected", "error", err)
+			return
+		}
+	}
+}
+
+// checkOrigin rejects WebSocket handshakes from pages of other origins than the CORS allowed ones, which browsers
+// would otherwise let connect with the dashboard user's credentials. Clients that aren't browsers send no Origin
+// and are accepted.
+func (h *AlertEventsHandler) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin != nil && origin.Host != req.Host && !originAllowed(h.allowedOrigins, origin.Scheme+"://"+origin.Host) {
+		return fmt.Errorf("cross-origin request from %s", origin.Host)
+	}
+	return nil
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database/alerts"
 	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"log/slog"
 
@@ -13,26 +19,29 @@ import (
 
 // AlertHandler handles alert-related HTTP requests
 type AlertHandler struct {
-	alertRepo alerts.AlertRepository
-	logger    *slog.Logger
+	alertRepo    alerts.AlertRepository
+	alertService *services.AlertService   // resolves and acknowledges alerts, publishing their events
+	dashboard    *services.DashboardCache // nil when the active alerts aren't cached
+	logger       *slog.Logger
 }
 
-// NewAlertHandler creates a new alert handler
-func NewAlertHandler(alertRepo alerts.AlertRepository, logger *slog.Logger) *AlertHandler {
+// NewAlertHandler creates a new alert handler. The dashboard cache is optional.
+func NewAlertHandler(alertRepo alerts.AlertRepository, alertService *services.AlertService, dashboard *services.DashboardCache, logger *slog.Logger) *AlertHandler {
 	return &AlertHandler{
-		alertRepo: alertRepo,
-		logger:    logger,
+		alertRepo:    alertRepo,
+		alertService: alertService,
+		dashboard:    dashboard,
+		logger:       logger,
 	}
 }
 
-// GetAlerts retrieves alerts with filters
+// GetAlerts retrieves alerts with filters, newest first. Pages are selected with offset or, more efficiently
+// on large tables, with the cursor returned by the previous page. Rules are included with include=rule, which
+// unversioned routes default to, and view=summary lists lightweight summaries naming the rule instead.
 func (h *AlertHandler) GetAlerts(c *gin.Context) {
 	var filter models.AlertFilter
 
 	// Parse query parameters
-	if status := c.Query("status"); status != "" {
-		filter.Status = &status
-	}
 	if severity := c.Query("severity"); severity != "" {
 		filter.Severity = &severity
 	}
@@ -42,25 +51,88 @@ func (h *AlertHandler) GetAlerts(c *gin.Context) {
 			filter.RuleID = &ruleIDUint
 		}
 	}
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil {
-			filter.Limit = &limit
+	if snoozedStr := c.Query("snoozed"); snoozedStr != "" {
+		if snoozed, err := strconv.ParseBool(snoozedStr); err == nil {
+			filter.Snoozed = &snoozed
 		}
 	}
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil {
-			filter.Offset = &offset
+	if err := compoundAlertFilters(c, &filter); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.Tenant = tenant
+	limit, offset, err := pageParams(c, 0) // unversioned routes return all alerts by default
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if limit > 0 {
+		// Fetch one more alert than requested to tell whether another page follows
+		fetch := limit + 1
+		filter.Limit = &fetch
+	}
+	if offset > 0 {
+		filter.Offset = &offset
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if offset > 0 {
+			respondValidationError(c, "cursor and offset are mutually exclusive")
+			return
+		}
+		if filter.After, err = models.ParseAlertCursor(cursor); err != nil {
+			respondValidationError(c, "Invalid cursor")
+			return
 		}
 	}
 
+	view := c.Query("view")
+	if view != "" && view != constants.AlertViewSummary {
+		respondValidationError(c, "Invalid view, expected summary")
+		return
+	}
+	include, included := c.GetQuery("include")
+	switch {
+	case include == constants.AlertIncludeRule:
+		filter.IncludeRule = true
+	case include != "":
+		respondValidationError(c, "Invalid include, expected rule")
+		return
+	case !included:
+		filter.IncludeRule = !isV1(c)
+	}
+
+	if view == constants.AlertViewSummary {
+		if filter.IncludeRule && included {
+			respondValidationError(c, "include is not supported with view=summary")
+			return
+		}
+		summaries, err := h.alertRepo.GetAlertSummaries(c.Request.Context(), &filter)
+		if err != nil {
+			h.logger.Error("Failed to get alert summaries", "error", err)
+			respondError(c, err, "Failed to get alerts")
+			return
+		}
+		respondCursorPage(c, summaries, limit, offset, func(summary models.AlertSummary) string {
+			return models.AlertCursor{CreatedAt: summary.CreatedAt, ID: summary.ID}.String()
+		})
+		return
+	}
+
 	alerts, err := h.alertRepo.GetAlerts(c.Request.Context(), &filter)
 	if err != nil {
 		h.logger.Error("Failed to get alerts", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alerts"})
+		respondError(c, err, "Failed to get alerts")
 		return
 	}
 
-	c.JSON(http.StatusOK, alerts)
+	respondCursorPage(c, alerts, limit, offset, func(alert models.Alert) string {
+		return models.AlertCursor{CreatedAt: alert.CreatedAt, ID: alert.ID}.String()
+	})
 }
 
 // GetAlertByID retrieves an alert by ID
@@ -68,42 +140,91 @@ func (h *AlertHandler) GetAlertByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		respondValidationError(c, "Invalid alert ID")
 		return
 	}
 
 	alert, err := h.alertRepo.GetAlertByID(c.Request.Context(), uint(id))
+	if err == nil && !canAccessTenant(c, alert.Tenant) {
+		err = apperrors.NotFound("Alert not found")
+	}
 	if err != nil {
 		h.logger.Error("Failed to get alert", "error", err, "id", id)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		respondError(c, err, "Failed to get alert")
 		return
 	}
 
-	c.JSON(http.StatusOK, alert)
+	respond(c, http.StatusOK, alert, alert)
 }
 
-// GetAlertStats retrieves alert statistics
+// GetAlertStats retrieves statistics of the alerts created between the optional start_time and end_time,
+// optionally of a single tenant
 func (h *AlertHandler) GetAlertStats(c *gin.Context) {
-	stats, err := h.alertRepo.GetAlertStats(c.Request.Context())
+	var startTime, endTime *time.Time
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			respondValidationError(c, "Invalid start_time, expected RFC3339")
+			return
+		}
+		startTime = &t
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			respondValidationError(c, "Invalid end_time, expected RFC3339")
+			return
+		}
+		endTime = &t
+	}
+	if startTime != nil && endTime != nil && !startTime.Before(*endTime) {
+		respondValidationError(c, "start_time must be before end_time")
+		return
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	stats, err := h.alertRepo.GetAlertStats(c.Request.Context(), startTime, endTime, tenant)
 	if err != nil {
 		h.logger.Error("Failed to get alert stats", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alert stats"})
+		respondError(c, err, "Failed to get alert stats")
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	respond(c, http.StatusOK, stats, stats)
 }
 
-// GetActiveAlerts retrieves all active alerts
+// GetActiveAlerts retrieves all active alerts, from the dashboard cache when there is one, optionally of a
+// single tenant
 func (h *AlertHandler) GetActiveAlerts(c *gin.Context) {
-	alerts, err := h.alertRepo.GetActiveAlerts(c.Request.Context())
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	getActiveAlerts := h.alertRepo.GetActiveAlerts
+	if h.dashboard != nil {
+		getActiveAlerts = h.dashboard.ActiveAlerts
+	}
+	alerts, err := getActiveAlerts(c.Request.Context())
 	if err != nil {
 		h.logger.Error("Failed to get active alerts", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active alerts"})
+		respondError(c, err, "Failed to get active alerts")
 		return
 	}
+	// The cached alerts are shared by every tenant, so they are narrowed down here rather than in the query
+	if tenant != nil {
+		alerts = slices.DeleteFunc(slices.Clone(alerts), func(alert models.Alert) bool {
+			return alert.Tenant == nil || *alert.Tenant != *tenant
+		})
+	}
 
-	c.JSON(http.StatusOK, alerts)
+	respond(c, http.StatusOK, alerts, alerts)
 }
 
 // ResolveAlert resolves an alert
@@ -111,17 +232,23 @@ func (h *AlertHandler) ResolveAlert(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		respondValidationError(c, "Invalid alert ID")
 		return
 	}
 
-	if err := h.alertRepo.ResolveAlert(c.Request.Context(), uint(id)); err != nil {
+	if !h.checkAlertTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertService.ResolveAlert(c.Request.Context(), uint(id)); err != nil {
 		h.logger.Error("Failed to resolve alert", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve alert"})
+		respondError(c, err, "Failed to resolve alert")
 		return
 	}
+	h.alertsChanged()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Alert resolved successfully"})
+	body := gin.H{"message": "Alert resolved successfully"}
+	respond(c, http.StatusOK, body, body)
 }
 
 // AcknowledgeAlert acknowledges an alert
@@ -129,15 +256,190 @@ func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		respondValidationError(c, "Invalid alert ID")
 		return
 	}
 
-	if err := h.alertRepo.AcknowledgeAlert(c.Request.Context(), uint(id)); err != nil {
+	if !h.checkAlertTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertService.AcknowledgeAlert(c.Request.Context(), uint(id)); err != nil {
 		h.logger.Error("Failed to acknowledge alert", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert"})
+		respondError(c, err, "Failed to acknowledge alert")
+		return
+	}
+	h.alertsChanged()
+
+	body := gin.H{"message": "Alert acknowledged successfully"}
+	respond(c, http.StatusOK, body, body)
+}
+
+// SnoozeAlert suppresses re-firing of an alert's rule until the time given by the until parameter
+func (h *AlertHandler) SnoozeAlert(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert ID")
+		return
+	}
+
+	until, err := untilParam(c)
+	if err != nil {
+		respondError(c, err, "")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Alert acknowledged successfully"})
+	if !h.checkAlertTenant(c, uint(id)) {
+		return
+	}
+
+	if err := h.alertRepo.SnoozeAlert(c.Request.Context(), uint(id), until); err != nil {
+		h.logger.Error("Failed to snooze alert", "error", err)
+		respondError(c, err, "Failed to snooze alert")
+		return
+	}
+	h.alertsChanged()
+
+	body := gin.H{"message": "Alert snoozed successfully", "snoozed_until": until}
+	respond(c, http.StatusOK, body, body)
+}
+
+// BulkUpdateAlerts resolves or acknowledges many alerts in one call, given their IDs or a filter selecting them
+func (h *AlertHandler) BulkUpdateAlerts(c *gin.Context) {
+	var action models.AlertBulkAction
+	if err := c.ShouldBindJSON(&action); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := action.Validate(); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	result, err := h.alertService.BulkUpdateAlerts(c.Request.Context(), &action, tenant)
+	if err != nil {
+		h.logger.Error("Failed to update alerts in bulk", "error", err, "action", action.Action)
+		respondError(c, err, "Failed to update alerts")
+		return
+	}
+	if result.Updated > 0 {
+		h.alertsChanged()
+	}
+
+	respond(c, http.StatusOK, result, result)
+}
+
+// checkAlertTenant responds with not found when the caller is limited to a tenant other than the alert's,
+// reporting whether the request may go on
+func (h *AlertHandler) checkAlertTenant(c *gin.Context, id uint) bool {
+	if principalOf(c).TenantScope() == nil {
+		return true
+	}
+	alert, err := h.alertRepo.GetAlertByID(c.Request.Context(), id)
+	if err == nil && !canAccessTenant(c, alert.Tenant) {
+		err = apperrors.NotFound("Alert not found")
+	}
+	if err != nil {
+		respondError(c, err, "Failed to get alert")
+		return false
+	}
+	return true
+}
+
+// alertsChanged drops the cached active alerts, so the change shows on the next dashboard load
+func (h *AlertHandler) alertsChanged() {
+	if h.dashboard != nil {
+		h.dashboard.InvalidateAlerts()
+	}
+}
+
+// compoundAlertFilters parses the filters of alert listings taking several values: comma-separated status,
+// not_severity and not_rule_id lists, label.<name>=<value> and not_label.<name>=<value> rule label filters, and
+// search, a full-text search of the alerts' messages
+func compoundAlertFilters(c *gin.Context, filter *models.AlertFilter) error {
+	statuses := []string{constants.AlertStatusActive, constants.AlertStatusResolved, constants.AlertStatusAcknowledged}
+	for _, status := range listParam(c, "status") {
+		if !slices.Contains(statuses, status) {
+			return apperrors.Validation("invalid status %q, expected active, resolved or acknowledged", status)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	severities := []string{constants.AlertSeverityLow, constants.AlertSeverityMedium, constants.AlertSeverityHigh, constants.AlertSeverityCritical}
+	for _, severity := range listParam(c, "not_severity") {
+		if !slices.Contains(severities, severity) {
+			return apperrors.Validation("invalid not_severity %q, expected low, medium, high or critical", severity)
+		}
+		filter.ExcludeSeverities = append(filter.ExcludeSeverities, severity)
+	}
+
+	for _, ruleIDStr := range listParam(c, "not_rule_id") {
+		ruleID, err := strconv.ParseUint(ruleIDStr, 10, 32)
+		if err != nil {
+			return apperrors.Validation("invalid not_rule_id %q", ruleIDStr)
+		}
+		filter.ExcludeRuleIDs = append(filter.ExcludeRuleIDs, uint(ruleID))
+	}
+
+	// A label repeated in the query uses its first value, like attribute filters of logs
+	count := 0
+	for param, values := range c.Request.URL.Query() {
+		labels := &filter.Labels
+		name, ok := strings.CutPrefix(param, constants.AlertLabelFilterPrefix)
+		if !ok {
+			if name, ok = strings.CutPrefix(param, constants.AlertExcludeLabelFilterPrefix); !ok {
+				continue
+			}
+			labels = &filter.ExcludeLabels
+		}
+		if name == "" {
+			return apperrors.Validation("label filters need a name, e.g. %steam=payments", constants.AlertLabelFilterPrefix)
+		}
+		if *labels == nil {
+			*labels = make(map[string]string)
+		}
+		(*labels)[name] = values[0]
+		count++
+	}
+	if count > constants.MaxAlertLabelFilters {
+		return apperrors.Validation("at most %d label filters are allowed", constants.MaxAlertLabelFilters)
+	}
+
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+	return nil
+}
+
+// listParam splits a comma-separated query parameter, skipping empty values
+func listParam(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// untilParam parses the required until query parameter of snoozes and mutes, which must lie in the future
+func untilParam(c *gin.Context) (time.Time, error) {
+	untilStr := c.Query("until")
+	if untilStr == "" {
+		return time.Time{}, apperrors.Validation("until is required")
+	}
+	until, err := time.Parse(time.RFC3339, untilStr)
+	if err != nil {
+		return time.Time{}, apperrors.Validation("invalid until, expected RFC3339")
+	}
+	if !until.After(time.Now()) {
+		return time.Time{}, apperrors.Validation("until must be in the future")
+	}
+	return until, nil
 }
@@ -0,0 +1,589 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/openapi"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiDoc documents an endpoint for the OpenAPI document. Versioned routes are documented under their /api/v1 path,
+// which documents their deprecated unversioned copies as well.
+type apiDoc struct {
+	tag         string // defaults to the resource of the path
+	summary     string
+	description string
+	params      []openapi.Parameter // query parameters
+	body        any                 // value of the request body's type, nil without a body
+	response    any                 // value of the response data's type, nil for responses described in words
+	status      int                 // of successful responses, 200 by default
+	paginated   bool                // pages are selected with limit and offset
+	contentType string              // of successful responses that aren't JSON
+	public      bool                // served without authentication
+}
+
+// param documents a query parameter of the given type (string, integer, boolean or date-time)
+func param(name, typ, description string) openapi.Parameter {
+	schema := &openapi.Schema{Type: typ}
+	if typ == "date-time" {
+		schema = &openapi.Schema{Type: "string", Format: "date-time"}
+	}
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// required marks a query parameter as required
+func required(p openapi.Parameter) openapi.Parameter {
+	p.Required = true
+	return p
+}
+
+// Query parameters shared by several endpoints
+var (
+	pageParamDocs = []openapi.Parameter{
+		param("limit", "integer", "Page size, at most 1000 on /api/v1 routes"),
+		param("offset", "integer", "Items to skip"),
+	}
+	timeRangeParamDocs = []openapi.Parameter{
+		param("start_time", "date-time", "Start of the time range (RFC3339)"),
+		param("end_time", "date-time", "End of the time range (RFC3339)"),
+	}
+	dimensionParamDocs = []openapi.Parameter{
+		param("tenant", "string", "Tenant of the logs; callers limited to a tenant always get theirs"),
+		param("host", "string", ""),
+		param("environment", "string", ""),
+		param("region", "string", ""),
+		param("client_ip", "string", "IPv4 or IPv6 address"),
+	}
+	logFilterParamDocs = concat(
+		[]openapi.Parameter{
+			{Name: "level", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}}},
+			param("service", "string", ""),
+			param("trace_id", "string", ""),
+			param("span_id", "string", ""),
+			param("parent_span_id", "string", ""),
+			param("user_id", "string", ""),
+			param("search", "string", "Full-text search of the messages"),
+			param("fingerprint", "string", "Fingerprint grouping occurrences of the same error"),
+			param("has_request_body", "boolean", "Whether the log carries a request body excerpt"),
+			param("has_response_body", "boolean", "Whether the log carries a response body excerpt"),
+		},
+		dimensionParamDocs,
+		timeRangeParamDocs,
+	)
+	alignmentParamDocs = []openapi.Parameter{
+		param("week_start", "string", "Weekday weekly buckets start on, e.g. monday"),
+		param("bucket_offset", "string", "Offset of bucket boundaries from midnight UTC, e.g. -5h"),
+	}
+	projectionParamDocs = []openapi.Parameter{
+		param("fields", "string", "Comma-separated log fields returned, e.g. timestamp,level,service,message; all by default"),
+		param("compact", "boolean", "Return rows of field values, in the order of fields, instead of log objects"),
+	}
+	untilParamDocs = []openapi.Parameter{required(param("until", "date-time", "End of the suppression, in the future (RFC3339)"))}
+)
+
+// Descriptions shared by several endpoints
+const (
+	attributeFilterDoc = "Logs can also be filtered on up to 10 attributes with attr.<key>=<value>, e.g. attr.region=eu-west-1."
+	labelFilterDoc     = "Alerts can also be selected by the labels of their rules with label.<name>=<value> and " +
+		"excluded with not_label.<name>=<value>, up to 10 label filters."
+)
+
+// apiDocs documents the endpoints by method and path
+var apiDocs = map[string]apiDoc{
+	// Logs
+	"GET " + constants.APIV1Prefix + constants.APILogsPath: {
+		summary: "Search logs",
+		description: "Logs matching the filters, newest first. " + attributeFilterDoc + " With fields, only the " +
+			"selected fields are read and returned; compact responses list rows of values, their columns named in " +
+			"the X-Log-Columns header.",
+		params:    concat(logFilterParamDocs, projectionParamDocs),
+		response:  []models.Log{},
+		paginated: true,
+	},
+	"GET " + constants.APIV1Prefix + constants.APILogsPath + "/trace/:traceID": {
+		summary:  "Get the logs of a trace",
+		response: []models.Log{},
+	},
+	"GET " + constants.APIPrefix + constants.APILogsPath + "/poll": {
+		summary: "Long-poll for new logs",
+		description: "Blocks until logs matching the filters are stored after the cursor or the wait expires, and returns " +
+			"them with the cursor to pass to the next request. Without a cursor polling starts from the most recent log.",
+		params: concat([]openapi.Parameter{
+			param("cursor", "string", "Cursor returned by the previous poll"),
+			param("wait", "string", "How long to wait for logs, e.g. 30s, at most 60s"),
+			param("limit", "integer", "Logs returned at most"),
+			logFilterParamDocs[0],
+			param("service", "string", ""),
+			param("trace_id", "string", ""),
+			param("span_id", "string", ""),
+			param("parent_span_id", "string", ""),
+			param("user_id", "string", ""),
+			param("search", "string", "Full-text search of the messages"),
+			param("has_request_body", "boolean", ""),
+			param("has_response_body", "boolean", ""),
+		}, dimensionParamDocs),
+	},
+	"GET " + constants.APIPrefix + constants.APILogsPath + "/stream": {
+		summary:     "Live tail of new logs",
+		description: "Streams the logs matching the filters as Server-Sent Events as they are stored.",
+		params: []openapi.Parameter{
+			logFilterParamDocs[0],
+			param("service", "string", ""),
+			param("search", "string", ""),
+			param("tenant", "string", ""),
+		},
+		contentType: "text/event-stream",
+	},
+	"GET " + constants.APIPrefix + constants.APILogsPath + "/export": {
+		summary: "Export logs as CSV or NDJSON",
+		description: "Streams the logs matching the filters of GET /api/logs, oldest first. The X-Export-Truncated " +
+			"trailer tells whether the row cap cut the export short.",
+		params: concat([]openapi.Parameter{
+			{Name: "format", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{constants.LogExportFormatCSV, constants.LogExportFormatNDJSON}}},
+			param("limit", "integer", "Logs exported at most, capped at 100000"),
+		}, logFilterParamDocs),
+		contentType: "text/csv",
+	},
+	"GET " + constants.APIPrefix + constants.APILogsPath + "/aggregate": {
+		summary: "Aggregate logs",
+		description: "Groups the logs matching the filters of GET /api/logs, by default those of the last 24 hours, " +
+			"and returns the groups with the highest counts or response times, highest first. " + attributeFilterDoc,
+		params: concat([]openapi.Parameter{
+			required(param("group_by", "string", "Comma-separated fields among "+strings.Join(models.LogGroupByFields, ", "))),
+			{Name: "aggregation", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{
+				constants.LogAggregationCount, constants.LogAggregationAvgResponseTime, constants.LogAggregationMaxResponseTime}}},
+			param("limit", "integer", "Groups returned at most, 100 by default and at most 1000"),
+		}, logFilterParamDocs),
+		response: struct {
+			GroupBy     []string          `json:"group_by"`
+			Aggregation string            `json:"aggregation"`
+			Groups      []models.LogGroup `json:"groups"`
+			TimeRange   map[string]any    `json:"time_range"`
+			Timestamp   time.Time         `json:"timestamp"`
+		}{},
+	},
+
+	// Traces
+	"GET " + constants.APIPrefix + constants.APITracesPath: {
+		summary: "List recent traces",
+		description: "Traces with logs in the range, by default the last 24 hours, most recent last log first, with " +
+			"their time span, log, service and error counts and maximum response time. Logs at ERROR and FATAL level " +
+			"are errors.",
+		params: concat([]openapi.Parameter{
+			param("service", "string", "Lists the traces that went through the service; their rollups cover all their services"),
+			param("has_errors", "boolean", "Lists the traces with errors only"),
+			param("limit", "integer", "Traces returned at most, 50 by default and at most 500"),
+			param("offset", "integer", "Traces to skip"),
+		}, timeRangeParamDocs),
+		response: struct {
+			Traces    []models.TraceRollup `json:"traces"`
+			Limit     int                  `json:"limit"`
+			Offset    int                  `json:"offset"`
+			HasMore   bool                 `json:"has_more"`
+			TimeRange map[string]any       `json:"time_range"`
+			Timestamp time.Time            `json:"timestamp"`
+		}{},
+	},
+	"GET " + constants.APIPrefix + constants.APITracesPath + "/:traceID": {
+		summary: "Get the summary of a trace",
+		description: "The services the trace went through, in the order it reached them, the time span and duration " +
+			"of the trace and of each service, its error count, the tree of the spans its logs were emitted in, " +
+			"depth-first with the children of a span by start time, and its logs ordered by timestamp.",
+		response: models.TraceSummary{},
+	},
+
+	// Log statistics
+	"GET " + constants.APIPrefix + constants.APIMetricsPath: {
+		summary: "Get log statistics",
+		description: "Log counts by level, service and status class, error rates, response time percentiles and the " +
+			"time series of the range, by default the last 24 hours.",
+		params: concat(timeRangeParamDocs, dimensionParamDocs[1:], alignmentParamDocs),
+	},
+	"GET " + constants.APIPrefix + constants.APIMetricsPath + "/services/compare": {
+		summary: "Compare services",
+		params: concat([]openapi.Parameter{
+			required(param("services", "string", "Comma-separated services, at most 20")),
+		}, timeRangeParamDocs),
+		response: struct {
+			Services  []models.ServiceComparison `json:"services"`
+			TimeRange map[string]any             `json:"time_range"`
+			Timestamp time.Time                  `json:"timestamp"`
+		}{},
+	},
+	"GET " + constants.APIPrefix + constants.APIMetricsPath + "/timeseries": {
+		summary: "Get the log time series",
+		description: "Log counts per level and error rate per time bucket. Buckets are aligned to the interval, and a " +
+			"range may span at most 1440 of them.",
+		params: concat([]openapi.Parameter{
+			param("interval", "string", "One of 1m, 5m, 15m, 1h, 6h, 24h or 168h"),
+			param("service", "string", ""),
+		}, timeRangeParamDocs, alignmentParamDocs),
+	},
+	"GET " + constants.APIPrefix + constants.APIMetricsPath + "/slow-requests": {
+		summary: "List the slowest endpoints",
+		description: "Request methods and paths with the highest p95 or average response times in the range, by " +
+			"default the last 24 hours, with their request counts. Only logs carrying a request method, path and " +
+			"response time are counted.",
+		params: concat([]openapi.Parameter{
+			{Name: "sort", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{constants.SlowRequestsSortP95, constants.SlowRequestsSortAvg}}},
+			param("limit", "integer", "Endpoints returned at most, 10 by default and at most 100"),
+			param("min_count", "integer", "Requests an endpoint needs to be included, 1 by default"),
+			param("service", "string", ""),
+		}, timeRangeParamDocs),
+		response: struct {
+			Requests  []models.SlowRequest `json:"requests"`
+			Sort      string               `json:"sort"`
+			Service   string               `json:"service"`
+			TimeRange map[string]any       `json:"time_range"`
+			Timestamp time.Time            `json:"timestamp"`
+		}{},
+	},
+	"GET " + constants.APIPrefix + constants.APIMetricsPath + "/latency/heatmap": {
+		summary: "Get the latency heatmap of a service",
+		params: concat([]openapi.Parameter{
+			required(param("service", "string", "")),
+			param("interval", "string", "Width of the time buckets, 5m by default"),
+			param("buckets", "string", "Ascending upper bounds of the latency buckets in milliseconds"),
+		}, timeRangeParamDocs, alignmentParamDocs),
+	},
+
+	// Alerts
+	"GET " + constants.APIV1Prefix + "/alerts": {
+		summary: "List alerts",
+		description: "Alerts matching the filters, newest first. Pages are selected with offset or with the cursor " +
+			"of the previous page. " + labelFilterDoc,
+		params: []openapi.Parameter{
+			param("status", "string", "Comma-separated statuses: active, acknowledged, resolved"),
+			param("severity", "string", ""),
+			param("rule_id", "integer", ""),
+			param("snoozed", "boolean", ""),
+			param("not_severity", "string", "Comma-separated severities to exclude"),
+			param("not_rule_id", "string", "Comma-separated rule IDs to exclude"),
+			param("search", "string", "Full-text search of the messages"),
+			param("tenant", "string", ""),
+			param("cursor", "string", "next_cursor of the previous page"),
+			param("include", "string", "rule to include each alert's rule"),
+			param("view", "string", "summary to list lightweight summaries"),
+		},
+		response:  []models.Alert{},
+		paginated: true,
+	},
+	"GET " + constants.APIV1Prefix + "/alerts/stats": {
+		summary:  "Get alert statistics",
+		params:   timeRangeParamDocs,
+		response: models.AlertStats{},
+	},
+	"GET " + constants.APIV1Prefix + "/alerts/active": {
+		summary:  "List active alerts",
+		params:   []openapi.Parameter{param("tenant", "string", "")},
+		response: []models.Alert{},
+	},
+	"GET " + constants.APIV1Prefix + "/alerts/:id": {
+		summary:  "Get an alert",
+		response: models.Alert{},
+	},
+	"PUT " + constants.APIV1Prefix + "/alerts/:id/resolve": {
+		summary: "Resolve an alert",
+	},
+	"PUT " + constants.APIV1Prefix + "/alerts/:id/acknowledge": {
+		summary: "Acknowledge an alert",
+	},
+	"PUT " + constants.APIV1Prefix + "/alerts/:id/snooze": {
+		summary: "Snooze an alert, suppressing re-firing of its rule",
+		params:  untilParamDocs,
+	},
+	"POST " + constants.APIV1Prefix + "/alerts/bulk": {
+		summary: "Resolve or acknowledge many alerts at once",
+		description: "Selects the alerts by up to 1000 ids or by a filter of rule_id, severity, rule labels and a " +
+			"from/to creation time range, and updates them all in one transaction. Resolving applies to active and " +
+			"acknowledged alerts, acknowledging to active ones; other alerts are skipped.",
+		params:   []openapi.Parameter{param("tenant", "string", "")},
+		body:     models.AlertBulkAction{},
+		response: models.AlertBulkResult{},
+	},
+
+	// Alert rules
+	"POST " + constants.APIV1Prefix + "/alert-rules": {
+		summary: "Create an alert rule",
+		description: "Rules with invalid fields, e.g. a condition outside the condition grammar or a name already " +
+			"used in the tenant, are rejected with a 422 INVALID error listing the fields.",
+		body:     models.AlertRule{},
+		response: models.AlertRule{},
+		status:   http.StatusCreated,
+	},
+	"GET " + constants.APIV1Prefix + "/alert-rules": {
+		summary:   "List alert rules",
+		params:    []openapi.Parameter{param("tenant", "string", "")},
+		response:  []models.AlertRule{},
+		paginated: true,
+	},
+	"GET " + constants.APIV1Prefix + "/alert-rules/:id": {
+		summary:  "Get an alert rule",
+		response: models.AlertRule{},
+	},
+	"PUT " + constants.APIV1Prefix + "/alert-rules/:id": {
+		summary: "Update an alert rule",
+		description: "Edits of the condition, threshold or time window are canaried for canary_period, during which " +
+			"the previous version stays in effect. Rules are validated as they are on creation.",
+		params:   []openapi.Parameter{param("canary_period", "string", "e.g. 24h; 0 applies the edit at once")},
+		body:     models.AlertRule{},
+		response: models.AlertRule{},
+	},
+	"DELETE " + constants.APIV1Prefix + "/alert-rules/:id": {
+		summary: "Delete an alert rule",
+	},
+	"PUT " + constants.APIV1Prefix + "/alert-rules/:id/mute": {
+		summary: "Mute an alert rule, suppressing new alerts",
+		params:  untilParamDocs,
+	},
+	"POST " + constants.APIV1Prefix + "/alert-rules/:id/evaluate": {
+		summary:  "Evaluate an alert rule now, without creating or notifying alerts",
+		response: models.AlertRuleEvaluation{},
+	},
+	"GET " + constants.APIV1Prefix + "/alert-rules/:id/canary": {
+		summary:  "Compare the previous and edited versions of a canaried rule",
+		response: models.AlertRuleCanaryReport{},
+	},
+	"PUT " + constants.APIV1Prefix + "/alert-rules/:id/canary/promote": {
+		summary: "Put the edited version of a canaried rule into effect",
+	},
+	"PUT " + constants.APIV1Prefix + "/alert-rules/:id/canary/rollback": {
+		summary: "Restore the previous version of a canaried rule",
+	},
+
+	// Saved searches
+	"POST " + constants.APIPrefix + "/saved-searches": {
+		summary:  "Save a log search",
+		body:     models.SavedSearch{},
+		response: models.SavedSearch{},
+		status:   http.StatusCreated,
+	},
+	"GET " + constants.APIPrefix + "/saved-searches": {
+		summary:  "List saved searches",
+		response: []models.SavedSearch{},
+	},
+	"GET " + constants.APIPrefix + "/saved-searches/:id": {
+		summary:  "Get a saved search",
+		response: models.SavedSearch{},
+	},
+	"PUT " + constants.APIPrefix + "/saved-searches/:id": {
+		summary:  "Update a saved search",
+		body:     models.SavedSearch{},
+		response: models.SavedSearch{},
+	},
+	"DELETE " + constants.APIPrefix + "/saved-searches/:id": {
+		summary: "Delete a saved search",
+	},
+	"GET " + constants.APIPrefix + "/saved-searches/:id/execute": {
+		summary: "Run a saved search",
+		description: "Responds like GET /api/logs; start_time and end_time replace the saved time range, and fields " +
+			"the saved fields.",
+		params: concat(pageParamDocs, timeRangeParamDocs, projectionParamDocs),
+	},
+
+	// Auth
+	"GET " + constants.APIPrefix + constants.APIAuthPath + "/me": {
+		summary:  "Get the authenticated caller",
+		response: models.Principal{},
+	},
+
+	// Health
+	"GET " + constants.APIHealthPath: {
+		tag:         "health",
+		summary:     "Health check",
+		description: "Database connectivity and the health of the alert checker; 503 when the database is unreachable.",
+		public:      true,
+	},
+	"GET " + constants.LivenessPath: {
+		tag:         "health",
+		summary:     "Liveness check",
+		description: "200 as long as the server answers, regardless of its dependencies.",
+		response:    models.ProbeReport{},
+		public:      true,
+	},
+	"GET " + constants.ReadinessPath: {
+		tag:         "health",
+		summary:     "Readiness check",
+		description: "503 while the database is unreachable and until the dashboard cache is warmed up, with the outcome of each check.",
+		response:    models.ProbeReport{},
+		public:      true,
+	},
+	"GET " + constants.StatusPath: {
+		tag:      "health",
+		summary:  "Public status page",
+		response: models.StatusPage{},
+		public:   true,
+	},
+	"GET " + constants.MetricsPath: {
+		tag:         "health",
+		summary:     "Prometheus metrics",
+		contentType: "text/plain",
+		public:      true,
+	},
+	"GET " + constants.AlertEventsPath: {
+		tag:         "alerts",
+		summary:     "Alert events over WebSocket",
+		description: "Upgrades to a WebSocket pushing a JSON message for every alert created, resolved or acknowledged.",
+		params:      []openapi.Parameter{param("tenant", "string", "")},
+	},
+
+	// Admin
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/storage/stats": {
+		summary:  "Get storage statistics",
+		response: models.StorageStats{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/exports/compliance": {
+		summary:     "Download a signed compliance export",
+		params:      logFilterParamDocs,
+		contentType: "application/gzip",
+	},
+	"POST " + constants.APIPrefix + constants.APIAdminPath + "/exports/verify": {
+		summary:  "Verify a compliance export archive sent as the request body",
+		response: models.ExportVerification{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/audit": {
+		summary: "List recorded API mutations",
+		description: "Every successful POST, PUT, PATCH and DELETE request is recorded with the caller, the action, " +
+			"the resource and record ID it changed and JSON snapshots of the record before and after it, with " +
+			"secrets, passwords and tokens redacted.",
+		params: []openapi.Parameter{
+			param("caller", "string", ""),
+			param("resource", "string", "Collection of the records, e.g. alert-rules or admin/retention"),
+			param("resource_id", "string", ""),
+			param("action", "string", "create, update, delete or the operation of the route, e.g. resolve"),
+			param("since", "date-time", ""),
+			param("until", "date-time", ""),
+			param("limit", "integer", "At most 1000, 100 by default"),
+		},
+		response: []models.AuditRecord{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/audit/exports": {
+		summary: "List recorded export requests",
+		params: []openapi.Parameter{
+			param("caller", "string", ""),
+			param("since", "date-time", ""),
+			param("limit", "integer", "At most 1000, 100 by default"),
+		},
+		response: []models.ExportAudit{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/dlq/stats": {
+		summary:  "Get dead-letter topic statistics",
+		response: models.DeadLetterStats{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/kafka/lag": {
+		summary:  "Get the lag of the processor's consumer groups",
+		response: models.KafkaLag{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/maintenance": {
+		summary:  "Get the maintenance status",
+		response: models.MaintenanceStatus{},
+	},
+	"PUT " + constants.APIPrefix + constants.APIAdminPath + "/maintenance": {
+		summary:  "Set the read-only switch and maintenance banner",
+		body:     models.MaintenanceUpdate{},
+		response: models.MaintenanceStatus{},
+	},
+	"POST " + constants.APIPrefix + constants.APIAdminPath + "/notification-channels": {
+		summary:  "Create a notification channel",
+		body:     models.NotificationChannel{},
+		response: models.NotificationChannel{},
+		status:   http.StatusCreated,
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/notification-channels": {
+		summary:  "List notification channels",
+		response: []models.NotificationChannel{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/notification-channels/:id": {
+		summary:  "Get a notification channel",
+		response: models.NotificationChannel{},
+	},
+	"PUT " + constants.APIPrefix + constants.APIAdminPath + "/notification-channels/:id": {
+		summary:  "Replace a notification channel",
+		body:     models.NotificationChannel{},
+		response: models.NotificationChannel{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/alert-rules/:id/channels": {
+		summary:  "List the channels an alert rule notifies",
+		response: []models.NotificationChannel{},
+	},
+	"PUT " + constants.APIPrefix + constants.APIAdminPath + "/alert-rules/:id/channels": {
+		summary:  "Replace the channels an alert rule notifies",
+		body:     models.AlertRuleChannelsUpdate{},
+		response: []models.NotificationChannel{},
+	},
+	"POST " + constants.APIPrefix + constants.APIAdminPath + "/retention": {
+		summary:  "Create a log retention policy",
+		body:     models.LogRetentionPolicy{},
+		response: models.LogRetentionPolicy{},
+		status:   http.StatusCreated,
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/retention": {
+		summary:  "List log retention policies",
+		response: []models.LogRetentionPolicy{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/retention/:id": {
+		summary:  "Get a log retention policy",
+		response: models.LogRetentionPolicy{},
+	},
+	"PUT " + constants.APIPrefix + constants.APIAdminPath + "/retention/:id": {
+		summary:  "Replace a log retention policy",
+		body:     models.LogRetentionPolicy{},
+		response: models.LogRetentionPolicy{},
+	},
+	"POST " + constants.APIPrefix + constants.APIAdminPath + "/fingerprints/backfill": {
+		summary:  "Start recomputing the fingerprints of stored error logs",
+		response: models.FingerprintBackfill{},
+		status:   http.StatusAccepted,
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/fingerprints/backfill": {
+		summary:  "Get the fingerprint rules and backfill progress",
+		response: models.FingerprintStatus{},
+	},
+	"DELETE " + constants.APIPrefix + constants.APIAdminPath + "/fingerprints/backfill": {
+		summary:  "Cancel the fingerprint backfill",
+		response: models.FingerprintBackfill{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/migrations": {
+		summary:  "List applied schema migrations",
+		response: []models.Migration{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/scheduled-tasks": {
+		summary:  "List scheduled tasks",
+		response: []models.ScheduledTask{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/scheduled-tasks/:name/runs": {
+		summary:  "List the latest runs of a scheduled task",
+		params:   []openapi.Parameter{param("limit", "integer", "At most 500, 20 by default")},
+		response: []models.ScheduledTaskRun{},
+	},
+	"POST " + constants.APIPrefix + constants.APIAdminPath + "/tenants": {
+		summary:  "Register a tenant",
+		body:     models.Tenant{},
+		response: models.Tenant{},
+		status:   http.StatusCreated,
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/tenants": {
+		summary:  "List tenants",
+		response: []models.Tenant{},
+	},
+	"GET " + constants.APIPrefix + constants.APIAdminPath + "/tenants/:id": {
+		summary:  "Get a tenant",
+		response: models.Tenant{},
+	},
+	"PUT " + constants.APIPrefix + constants.APIAdminPath + "/tenants/:id": {
+		summary:  "Replace the description of a tenant",
+		body:     models.Tenant{},
+		response: models.Tenant{},
+	},
+}
+
+// concat joins parameter lists into a new list
+func concat(lists ...[]openapi.Parameter) []openapi.Parameter {
+	var params []openapi.Parameter
+	for _, list := range lists {
+		params = append(params, list...)
+	}
+	return params
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/adeesh/log-analytics/internal/arrow"
+	"github.com/adeesh/log-analytics/internal/models"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logArrowFields are the Arrow columns of logs, named like the log JSON fields; attributes are JSON encoded
+var logArrowFields = []arrow.Field{
+	{Name: "id", Type: arrow.Int64},
+	{Name: "timestamp", Type: arrow.Timestamp},
+	{Name: "level", Type: arrow.String},
+	{Name: "service", Type: arrow.String},
+	{Name: "tenant", Type: arrow.String},
+	{Name: "host", Type: arrow.String},
+	{Name: "environment", Type: arrow.String},
+	{Name: "region", Type: arrow.String},
+	{Name: "client_ip", Type: arrow.String},
+	{Name: "message", Type: arrow.String},
+	{Name: "fingerprint", Type: arrow.String},
+	{Name: "trace_id", Type: arrow.String},
+	{Name: "span_id", Type: arrow.String},
+	{Name: "parent_span_id", Type: arrow.String},
+	{Name: "user_id", Type: arrow.String},
+	{Name: "request_method", Type: arrow.String},
+	{Name: "request_path", Type: arrow.String},
+	{Name: "response_status", Type: arrow.Int64},
+	{Name: "response_time_ms", Type: arrow.Int64},
+	{Name: "request_body", Type: arrow.String},
+	{Name: "response_body", Type: arrow.String},
+	{Name: "attributes", Type: arrow.String},
+	{Name: "created_at", Type: arrow.Timestamp},
+}
+
+// timeSeriesArrowFields are the Arrow columns of time series buckets
+var timeSeriesArrowFields = []arrow.Field{
+	{Name: "timestamp", Type: arrow.Timestamp},
+	{Name: "count", Type: arrow.Int64},
+	{Name: "debug_count", Type: arrow.Int64},
+	{Name: "info_count", Type: arrow.Int64},
+	{Name: "warning_count", Type: arrow.Int64},
+	{Name: "error_count", Type: arrow.Int64},
+	{Name: "fatal_count", Type: arrow.Int64},
+	{Name: "error_rate", Type: arrow.Float64},
+}
+
+// wantsArrow reports whether the client accepts the Arrow IPC stream format, which analytical clients ask for
+// to skip JSON parsing of large results
+func wantsArrow(c *gin.Context) bool {
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == arrow.ContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// respondArrow writes the record as an Arrow IPC stream of a single record batch
+func respondArrow(c *gin.Context, fields []arrow.Field, record *arrow.Record) error {
+	c.Header("Content-Type", arrow.ContentType)
+	c.Status(http.StatusOK)
+	stream := arrow.NewStreamWriter(c.Writer, fields...)
+	if err := stream.Write(record); err != nil {
+		return err
+	}
+	return stream.Close()
+}
+
+// logArrowRecord renders logs as rows of the given log columns, all of them when none are given
+func logArrowRecord(logs []*models.Log, fields []string) ([]arrow.Field, *arrow.Record, error) {
+	columns := logArrowFields
+	if len(fields) > 0 {
+		columns = make([]arrow.Field, 0, len(fields))
+		for _, field := range fields {
+			i := slices.IndexFunc(logArrowFields, func(column arrow.Field) bool { return column.Name == field })
+			columns = append(columns, logArrowFields[i])
+		}
+	}
+
+	record := arrow.NewRecord(columns...)
+	values := make([]any, len(columns))
+	for _, log := range logs {
+		for i, column := range columns {
+			values[i] = logFieldValues[column.Name](log)
+			if column.Name == "attributes" {
+				var attributes *string
+				if len(log.Attributes) > 0 {
+					data, err := json.Marshal(log.Attributes)
+					if err != nil {
+						return nil, nil, err
+					}
+					encoded := string(data)
+					attributes = &encoded
+				}
+				values[i] = attributes
+			}
+		}
+		if err := record.Append(values...); err != nil {
+			return nil, nil, err
+		}
+	}
+	return columns, record, nil
+}
+
+// timeSeriesArrowRecord renders time series buckets as rows of the time series columns
+func timeSeriesArrowRecord(series []models.TimeSeriesData) (*arrow.Record, error) {
+	record := arrow.NewRecord(timeSeriesArrowFields...)
+	for _, point := range series {
+		if err := record.Append(
+			point.Timestamp, point.Count, point.DebugCount, point.InfoCount, point.WarningCount, point.ErrorCount,
+			point.FatalCount, point.ErrorRate,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/audit"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditSnapshotFunc loads the record of the given ID of a resource, for the snapshots of audit records
+type AuditSnapshotFunc func(ctx context.Context, id uint) (any, error)
+
+// AuditHandler records the API mutations, and lists them to admins
+type AuditHandler struct {
+	audits    audit.AuditRepository
+	snapshots map[string]AuditSnapshotFunc // by resource, e.g. alert-rules or admin/retention
+	logger    *slog.Logger
+}
+
+// NewAuditHandler creates a new audit handler, taking the snapshots of the records of the given resources
+func NewAuditHandler(audits audit.AuditRepository, snapshots map[string]AuditSnapshotFunc, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{
+		audits:    audits,
+		snapshots: snapshots,
+		logger:    logger,
+	}
+}
+
+// Record records every successful POST, PUT, PATCH and DELETE request but those of the exempt routes, which
+// don't change anything. Routes addressing a record by its :id get snapshots of it, loaded before and after the
+// request, when their resource has a snapshot function; other routes keep their response as the after snapshot.
+func (h *AuditHandler) Record(exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		if route == "" || slices.Contains(exempt, route) {
+			c.Next()
+			return
+		}
+
+		resource, operation := auditTarget(route)
+		record := &models.AuditRecord{
+			Action:     auditAction(c.Request.Method, operation),
+			Resource:   resource,
+			ResourceID: c.Param("id"),
+			Method:     c.Request.Method,
+			Route:      route,
+			ClientIP:   c.ClientIP(),
+		}
+		load := h.snapshots[resource]
+		if record.ResourceID == "" {
+			load = nil
+		}
+		if load != nil {
+			record.Before = h.snapshot(c.Request.Context(), load, record.ResourceID)
+		}
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		record.Status = c.Writer.Status()
+		if record.Status >= http.StatusBadRequest {
+			return
+		}
+		// The request context ends with the response, which must not keep the record from being written
+		ctx := context.WithoutCancel(c.Request.Context())
+		switch {
+		case load != nil && record.Action != constants.AuditActionDelete:
+			record.After = h.snapshot(ctx, load, record.ResourceID)
+		case record.ResourceID == "":
+			record.After = writer.response(isV1(c))
+			if record.Action == constants.AuditActionCreate {
+				record.ResourceID = createdID(record.After)
+			}
+		}
+		if principal := principalOf(c); principal != nil {
+			record.Caller = principal.Username
+			record.Provider = principal.Provider
+		}
+
+		if err := h.audits.CreateAuditRecord(ctx, record); err != nil {
+			h.logger.Error("Failed to record audit record", "error", err, "caller", record.Caller, "route", record.Route)
+		}
+	}
+}
+
+// snapshot loads a record and encodes it with its sensitive fields redacted. Records that can't be loaded,
+// e.g. because they don't exist yet or anymore, and oversized ones have no snapshot.
+func (h *AuditHandler) snapshot(ctx context.Context, load AuditSnapshotFunc, idStr string) models.AuditSnapshot {
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return nil
+	}
+	value, err := load(ctx, uint(id))
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		h.logger.Warn("Failed to encode audit snapshot", "error", err, "id", id)
+		return nil
+	}
+	return redactSnapshot(data)
+}
+
+// GetAuditRecords retrieves the recorded API mutations, newest first, optionally of a single caller (caller=name),
+// resource (resource=alert-rules), record (resource_id=12) and action (action=update), and between two times
+// (since=RFC3339, until=RFC3339)
+func (h *AuditHandler) GetAuditRecords(c *gin.Context) {
+	filter := &models.AuditRecordFilter{Limit: constants.DefaultAuditRecordLimit}
+	for name, value := range map[string]**string{
+		"caller":      &filter.Caller,
+		"resource":    &filter.Resource,
+		"resource_id": &filter.ResourceID,
+		"action":      &filter.Action,
+	} {
+		if query := c.Query(name); query != "" {
+			*value = &query
+		}
+	}
+	for name, value := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if query := c.Query(name); query != "" {
+			t, err := time.Parse(time.RFC3339, query)
+			if err != nil {
+				respondValidationError(c, fmt.Sprintf("Invalid %s, expected RFC3339", name))
+				return
+			}
+			*value = &t
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > constants.MaxAuditRecordLimit {
+			respondValidationError(c, fmt.Sprintf("Invalid limit, expected 1 to %d", constants.MaxAuditRecordLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	records, err := h.audits.GetAuditRecords(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to get audit records", "error", err)
+		respondError(c, err, "Failed to get audit records")
+		return
+	}
+	if records == nil {
+		records = []models.AuditRecord{}
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// auditTarget splits a route into the resource it changes, the collection of the record with admin/ prefixed to
+// admin collections, and the operation following the record's ID, e.g. PUT /api/v1/alerts/:id/resolve into alerts
+// and resolve
+func auditTarget(route string) (resource, operation string) {
+	path, ok := strings.CutPrefix(route, constants.APIV1Prefix+"/")
+	if !ok {
+		path = strings.TrimPrefix(route, constants.APIPrefix+"/")
+	}
+	segments := strings.Split(path, "/")
+	n := 1
+	if segments[0] == strings.TrimPrefix(constants.APIAdminPath, "/") && len(segments) > 1 {
+		n = 2
+	}
+	rest := segments[n:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], ":") {
+		rest = rest[1:]
+	}
+	return strings.Join(segments[:n], "/"), strings.Join(rest, "/")
+}
+
+// auditAction names what a request did: delete for DELETE requests, otherwise the operation of its route or,
+// without one, create for POST and update for PUT and PATCH requests
+func auditAction(method, operation string) string {
+	switch {
+	case method == http.MethodDelete:
+		return constants.AuditActionDelete
+	case operation != "":
+		return operation
+	case method == http.MethodPost:
+		return constants.AuditActionCreate
+	default:
+		return constants.AuditActionUpdate
+	}
+}
+
+// createdID returns the ID of the record a create request responded with, empty when it has none
+func createdID(snapshot models.AuditSnapshot) string {
+	var created struct {
+		ID json.Number `json:"id"`
+	}
+	if json.Unmarshal(snapshot, &created) != nil {
+		return ""
+	}
+	return created.ID.String()
+}
+
+// redactSnapshot replaces the values of the sensitive fields of a JSON document, at any depth. Documents larger
+// than the snapshot size limit are dropped.
+func redactSnapshot(data []byte) models.AuditSnapshot {
+	if len(data) > constants.MaxAuditSnapshotSize {
+		return nil
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil
+	}
+	if !redactFields(document) {
+		return data
+	}
+	redacted, err := json.Marshal(document)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// redactFields redacts the sensitive fields of a decoded JSON value in place, reporting whether it redacted any
+func redactFields(value any) bool {
+	redacted := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if slices.Contains(constants.AuditRedactedKeys, strings.ToLower(key)) && field != nil && field != "" {
+				v[key] = constants.RedactedValue
+				redacted = true
+				continue
+			}
+			redacted = redactFields(field) || redacted
+		}
+	case []any:
+		for _, item := range v {
+			redacted = redactFields(item) || redacted
+		}
+	}
+	return redacted
+}
+
+// auditWriter keeps a copy of the response written through it, up to the snapshot size limit
+type auditWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+// Write copies the response and passes it through
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) > constants.MaxAuditSnapshotSize {
+		w.truncated = true
+	} else if !w.truncated {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString copies the response and passes it through
+func (w *auditWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// response returns the redacted JSON response, taken out of the envelope of /api/v1 responses. Responses that
+// aren't JSON or were too large to keep have none.
+func (w *auditWriter) response(enveloped bool) models.AuditSnapshot {
+	body := bytes.TrimSpace(w.body.Bytes())
+	if w.truncated || !json.Valid(body) || len(body) == 0 {
+		return nil
+	}
+	if enveloped {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(body, &envelope) != nil || len(envelope.Data) == 0 {
+			return nil
+		}
+		body = envelope.Data
+	}
+	return redactSnapshot(body)
+}
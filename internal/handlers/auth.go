@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/auth"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/audit"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler authenticates API and dashboard requests, and meters and audits the requests of scoped endpoints
+type AuthHandler struct {
+	provider auth.Provider
+	tokens   *auth.ServiceTokens
+	limiter  *auth.ScopeLimiter
+	audits   audit.AuditRepository
+	logger   *slog.Logger
+}
+
+// NewAuthHandler creates a new auth handler. A nil provider disables the authentication of users.
+func NewAuthHandler(provider auth.Provider, tokens *auth.ServiceTokens, limiter *auth.ScopeLimiter, audits audit.AuditRepository, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		provider: provider,
+		tokens:   tokens,
+		limiter:  limiter,
+		audits:   audits,
+		logger:   logger,
+	}
+}
+
+// RequireAuth authenticates requests with HTTP basic credentials or a service token and enforces access:
+// admin endpoints and mutations require the admin role, everything else the viewer role, while service tokens
+// may only call the routes of their scopes. Requests to the routes listed in scopes (by their registered path)
+// are metered against the caps of the route's scope, and export requests are audited. Callers limited to a tenant
+// may not call admin endpoints. Routes listed in exempt are always allowed.
+func (h *AuthHandler) RequireAuth(scopes map[string]string, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(exempt, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		scope := scopes[c.FullPath()]
+		principal, ok := h.authenticate(c, scope)
+		if !ok {
+			return
+		}
+		if principal.TenantScope() != nil && strings.HasPrefix(c.FullPath(), constants.APIPrefix+constants.APIAdminPath) {
+			respondError(c, apperrors.Forbidden("Admin endpoints are not available to callers of tenant %s", principal.Tenant), "")
+			c.Abort()
+			return
+		}
+		if principal != nil {
+			c.Set(constants.AuthPrincipalKey, principal)
+		}
+		if scope == "" {
+			c.Next()
+			return
+		}
+		h.meter(c, scope, principal)
+	}
+}
+
+// authenticate identifies the caller and checks that it may call the route. It returns a nil principal when
+// authentication is disabled, and false once it has rejected the request.
+func (h *AuthHandler) authenticate(c *gin.Context, scope string) (*models.Principal, bool) {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), constants.BearerPrefix); ok {
+		principal, err := h.tokens.Authenticate(token)
+		if err != nil {
+			h.logger.Warn("Service token authentication failed", "client_ip", c.ClientIP())
+			h.challenge(c, err)
+			return nil, false
+		}
+		if scope == "" || !slices.Contains(principal.Scopes, scope) {
+			respondError(c, apperrors.Forbidden("Service token %s lacks the scope of this endpoint", principal.Username), "")
+			c.Abort()
+			return nil, false
+		}
+		return principal, true
+	}
+
+	if h.provider == nil {
+		return nil, true
+	}
+
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		h.challenge(c, apperrors.Unauthorized("Authentication required"))
+		return nil, false
+	}
+
+	principal, err := h.provider.Authenticate(c.Request.Context(), username, password)
+	if err != nil {
+		switch apperrors.CodeOf(err) {
+		case apperrors.CodeUnauthorized:
+			h.logger.Warn("Authentication failed", "username", username, "client_ip", c.ClientIP())
+			h.challenge(c, err)
+		default:
+			h.logger.Error("Failed to authenticate request", "error", err, "username", username)
+			respondError(c, err, "Failed to authenticate")
+			c.Abort()
+		}
+		return nil, false
+	}
+
+	required := constants.RoleViewer
+	if strings.HasPrefix(c.FullPath(), constants.APIPrefix+constants.APIAdminPath) || !isReadMethod(c.Request.Method) {
+		required = constants.RoleAdmin
+	}
+	if !auth.RoleAllows(principal.Role, required) {
+		respondError(c, apperrors.Forbidden("The %s role is required", required), "")
+		c.Abort()
+		return nil, false
+	}
+	return principal, true
+}
+
+// meter admits the request under the request rate cap of its scope, hands the volume quota to the handler and
+// records export requests once they are served. Callers are told apart by principal, or by address when
+// authentication is disabled.
+func (h *AuthHandler) meter(c *gin.Context, scope string, principal *models.Principal) {
+	caller := "ip:" + c.ClientIP()
+	if principal != nil {
+		caller = principal.Provider + ":" + principal.Username
+	}
+	quota, err := h.limiter.Admit(caller, scope)
+	if err != nil {
+		h.logger.Warn("Scoped request rate limited", "caller", caller, "scope", scope, "route", c.FullPath())
+		respondError(c, err, "")
+		c.Abort()
+		return
+	}
+	c.Set(constants.AuthQuotaKey, quota)
+
+	if scope != constants.ScopeLogsExport {
+		c.Next()
+		return
+	}
+	startedAt := time.Now()
+	c.Next()
+	h.audit(c, principal, quota, startedAt)
+}
+
+// audit records who made an export request, its filter and time range, and how many logs it got
+func (h *AuthHandler) audit(c *gin.Context, principal *models.Principal, quota *auth.Quota, startedAt time.Time) {
+	entry := &models.ExportAudit{
+		Route:      c.FullPath(),
+		Filter:     c.Request.URL.RawQuery,
+		Rows:       quota.Used(),
+		Status:     c.Writer.Status(),
+		ClientIP:   c.ClientIP(),
+		StartedAt:  startedAt,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	if principal != nil {
+		entry.Caller = principal.Username
+		entry.Provider = principal.Provider
+	}
+	if t, err := time.Parse(time.RFC3339, c.Query("start_time")); err == nil {
+		entry.StartTime = &t
+	}
+	if t, err := time.Parse(time.RFC3339, c.Query("end_time")); err == nil {
+		entry.EndTime = &t
+	}
+
+	// The request context ends with the response, which must not keep the entry from being written
+	if err := h.audits.CreateExportAudit(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+		h.logger.Error("Failed to record export audit", "error", err, "caller", entry.Caller, "route", entry.Route)
+	}
+}
+
+// GetExportAudits retrieves the recorded export requests, newest first, optionally of a single caller
+// (caller=name) and since a time (since=RFC3339)
+func (h *AuthHandler) GetExportAudits(c *gin.Context) {
+	filter := &models.ExportAuditFilter{Limit: constants.DefaultExportAuditLimit}
+	if caller := c.Query("caller"); caller != "" {
+		filter.Caller = &caller
+	}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			respondValidationError(c, "Invalid since, expected RFC3339")
+			return
+		}
+		filter.Since = &since
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > constants.MaxExportAuditLimit {
+			respondValidationError(c, fmt.Sprintf("Invalid limit, expected 1 to %d", constants.MaxExportAuditLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.audits.GetExportAudits(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to get export audits", "error", err)
+		respondError(c, err, "Failed to get export audits")
+		return
+	}
+	if entries == nil {
+		entries = []models.ExportAudit{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetCurrentUser returns the authenticated caller
+func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
+	principal, ok := c.Get(constants.AuthPrincipalKey)
+	if !ok {
+		respondError(c, apperrors.NotFound("Authentication is disabled"), "")
+		return
+	}
+	c.JSON(http.StatusOK, principal.(*models.Principal))
+}
+
+// principalOf returns the authenticated caller, nil when authentication is disabled
+func principalOf(c *gin.Context) *models.Principal {
+	if principal, ok := c.Get(constants.AuthPrincipalKey); ok {
+		return principal.(*models.Principal)
+	}
+	return nil
+}
+
+// tenantScope returns the tenant a request is limited to: the caller's own tenant, or the tenant query parameter
+// of callers that may see every tenant. Callers limited to a tenant asking for another one are refused.
+func tenantScope(c *gin.Context) (*string, error) {
+	requested := c.Query("tenant")
+	if scope := principalOf(c).TenantScope(); scope != nil {
+		if requested != "" && requested != *scope {
+			return nil, apperrors.Forbidden("Access to tenant %s is not allowed", requested)
+		}
+		return scope, nil
+	}
+	if requested == "" {
+		return nil, nil
+	}
+	return &requested, nil
+}
+
+// canAccessTenant reports whether the caller may access a record of the given tenant. Records of other tenants
+// are reported as not found, so their existence isn't revealed.
+func canAccessTenant(c *gin.Context, tenant *string) bool {
+	scope := principalOf(c).TenantScope()
+	return scope == nil || (tenant != nil && *tenant == *scope)
+}
+
+// challenge rejects the request and asks browsers to prompt for credentials
+func (h *AuthHandler) challenge(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", `Basic realm="`+constants.AuthRealm+`", charset="UTF-8"`)
+	respondError(c, err, "")
+	c.Abort()
+}
+
+// quotaOf returns the volume quota of a scoped request, nil (unlimited) for other requests
+func quotaOf(c *gin.Context) *auth.Quota {
+	if quota, ok := c.Get(constants.AuthQuotaKey); ok {
+		return quota.(*auth.Quota)
+	}
+	return nil
+}
+
+// errVolumeExhausted is returned to scoped requests made once the caller's hourly volume is used up
+func errVolumeExhausted() error {
+	return apperrors.RateLimited("The hourly log volume of this endpoint is used up, try again later")
+}
+
+// isReadMethod reports whether the HTTP method doesn't modify state
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compress gzips the responses of clients accepting it once they outgrow minBytes, so that large log pages and
+// exports take a fraction of the bandwidth. Smaller responses are sent as they are, since they aren't worth it.
+// Streamed responses are compressed from their first flush, except for event streams; WebSocket upgrades, HEAD
+// requests and responses encoded by their handler are left alone.
+func Compress(minBytes, level int) gin.HandlerFunc {
+	writers := sync.Pool{New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	}}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: c.Writer, minBytes: minBytes, writers: &writers}
+		c.Writer = writer
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		writer.finish()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header accepts gzip, by name or else as any encoding, with a
+// non-zero quality
+func acceptsGzip(header string) bool {
+	anyAccepted := false
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		if name == "gzip" {
+			return q > 0
+		}
+		anyAccepted = q > 0
+	}
+	return anyAccepted
+}
+
+// gzipWriter holds back the start of a response until it outgrows the minimum size, then compresses it
+type gzipWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	writers  *sync.Pool
+	buffered bytes.Buffer
+	decided  bool         // whether the response is compressed or passed through is known
+	gz       *gzip.Writer // set once the response is compressed
+}
+
+// Write buffers the start of a response, compresses the rest of it once it is large enough, and passes
+// responses that aren't compressed through
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	if !w.decided && !w.compressible() {
+		w.decided = true
+	}
+	if w.decided {
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buffered.Write(data)
+	if w.buffered.Len() >= w.minBytes {
+		if err := w.compress(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString buffers, compresses or passes a response through like Write
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether any of the response was written, including its buffered start
+func (w *gzipWriter) Written() bool {
+	return w.buffered.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush compresses streamed responses from their first flush, since they are expected to keep growing, and
+// flushes what was compressed so far
+func (w *gzipWriter) Flush() {
+	if !w.decided && w.buffered.Len() > 0 {
+		w.compress()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap returns the wrapped writer, so that http.ResponseController reaches the connection's deadlines
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed, judging by the headers set by its handler. Responses
+// whose headers were sent already, by a flush before anything was written, can't be.
+func (w *gzipWriter) compressible() bool {
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return !slices.Contains(constants.UncompressedContentTypes, mediaType)
+}
+
+// compress starts compressing the response with the buffered start of it
+func (w *gzipWriter) compress() error {
+	w.decided = true
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.gz = w.writers.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	_, err := w.gz.Write(w.buffered.Bytes())
+	w.buffered.Reset()
+	return err
+}
+
+// finish ends the compressed stream, or writes responses that stayed too small to compress as they are
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		w.writers.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	if w.buffered.Len() > 0 {
+		w.ResponseWriter.Write(w.buffered.Bytes())
+	}
+}
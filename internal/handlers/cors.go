@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets browser apps of the allowed origins call the API. Preflight requests are answered here, before
+// authentication, since browsers send them without credentials; requests of other origins get no CORS headers, so
+// browsers keep their pages from reading the responses, and their preflight requests are refused.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		// Answers depend on the origin, so caches must not serve them to other origins
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !originAllowed(cfg.AllowedOrigins, origin) {
+			if preflight {
+				respondError(c, apperrors.Forbidden("Origin %s is not allowed", origin), "")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if slices.Contains(cfg.AllowedOrigins, constants.CORSAllowAllOrigins) && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", constants.CORSAllowAllOrigins)
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				c.Header("Access-Control-Allow-Headers", headers)
+			}
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if exposed != "" {
+			c.Header("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
+
+// originAllowed reports whether the origin is one of the allowed origins, ignoring case as browsers lower-case
+// schemes and hosts
+func originAllowed(allowed []string, origin string) bool {
+	return slices.ContainsFunc(allowed, func(candidate string) bool {
+		return candidate == constants.CORSAllowAllOrigins || strings.EqualFold(candidate, origin)
+	})
+}
+
+// SecurityHeaders adds headers that keep browsers from sniffing, framing or leaking the referrer of API
+// responses, and asks them to only use HTTPS once the max age is set
+func SecurityHeaders(hstsMaxAge time.Duration) gin.HandlerFunc {
+	hsts := ""
+	if hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(hstsMaxAge/time.Second))
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", constants.ReferrerPolicy)
+		header.Set("Content-Security-Policy", constants.ContentSecurityPolicy)
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
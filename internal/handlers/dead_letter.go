@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterHandler handles dead-letter queue HTTP requests
+type DeadLetterHandler struct {
+	deadLetterService *services.DeadLetterService
+	logger            *slog.Logger
+}
+
+// NewDeadLetterHandler creates a new dead-letter handler
+func NewDeadLetterHandler(deadLetterService *services.DeadLetterService, logger *slog.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: deadLetterService,
+		logger:            logger,
+	}
+}
+
+// GetDeadLetterStats retrieves the message counts of the dead-letter topic
+func (h *DeadLetterHandler) GetDeadLetterStats(c *gin.Context) {
+	stats, err := h.deadLetterService.GetStats(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get dead-letter stats", "error", err)
+		respondError(c, err, "Failed to get dead-letter stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
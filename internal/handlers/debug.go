@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/models"
+	"mime"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugSQL attaches the SQL statements run for a request, with their durations, to its response when an admin asks
+// for them with debug=sql, so slow dashboards can be investigated without database access. JSON object responses
+// get the trace under the _debug key; other JSON responses, such as lists, only the number of statements and their
+// total time in headers. Streamed responses are left alone. Other callers' debug parameters are ignored.
+func DebugSQL(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled || c.Query(constants.DebugQueryParam) != constants.DebugSQL || !isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		ctx, trace := database.WithQueryTrace(c.Request.Context(), constants.MaxDebugQueries)
+		c.Request = c.Request.WithContext(ctx)
+		writer := &debugWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if writer.buffering {
+			writer.attach(trace.Summary())
+		}
+	}
+}
+
+// isAdmin reports whether the caller is an admin; without authentication everyone is
+func isAdmin(c *gin.Context) bool {
+	principal, ok := c.Get(constants.AuthPrincipalKey)
+	return !ok || principal.(*models.Principal).Role == constants.RoleAdmin
+}
+
+// debugWriter holds back JSON responses until the handler is done, so the trace can be added to them
+type debugWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// Write buffers JSON responses and passes other responses through
+func (w *debugWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		w.buffering = mediaType == "application/json"
+	}
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers JSON responses and passes other responses through
+func (w *debugWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush flushes responses that are passed through
+func (w *debugWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// attach writes the buffered response with the trace added
+func (w *debugWriter) attach(summary database.QueryTraceSummary) {
+	body := bytes.TrimSpace(w.body.Bytes())
+	debug, err := json.Marshal(summary)
+	if err == nil && len(body) >= 2 && body[0] == '{' && body[len(body)-1] == '}' {
+		// The key is spliced in rather than the body decoded and encoded again, which would reorder its fields
+		var extended bytes.Buffer
+		extended.Write(body[:len(body)-1])
+		if len(bytes.TrimSpace(body[1:len(body)-1])) > 0 {
+			extended.WriteByte(',')
+		}
+		extended.WriteString(strconv.Quote(constants.DebugResponseKey) + ":")
+		extended.Write(debug)
+		extended.WriteByte('}')
+		body = extended.Bytes()
+	} else {
+		w.Header().Set(constants.HeaderDebugSQLQueries, strconv.Itoa(summary.QueryCount))
+		w.Header().Set(constants.HeaderDebugSQLTime, strconv.FormatFloat(summary.TotalMs, 'f', 3, 64))
+	}
+	w.ResponseWriter.Write(body)
+}
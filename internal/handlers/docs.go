@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/openapi"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DocsHandler serves the OpenAPI document of the API and Swagger UI to browse it
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler creates a new docs handler serving the document
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{spec: spec}, nil
+}
+
+// GetSpec returns the OpenAPI document
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// swaggerUI loads Swagger UI from its CDN and points it at the OpenAPI document
+var swaggerUI = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.UI}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.UI}}/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: {{.Spec}}, dom_id: "#swagger-ui", deepLinking: true});
+</script>
+</body>
+</html>
+`))
+
+// GetUI serves Swagger UI. The page is the only document the API serves, so it relaxes the content security policy
+// of API responses to load the UI's scripts and styles from its CDN.
+func (h *DocsHandler) GetUI(c *gin.Context) {
+	c.Header("Content-Security-Policy", constants.APIDocsCSP)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	swaggerUI.Execute(c.Writer, map[string]string{
+		"Title": constants.APIDocsTitle,
+		"UI":    constants.SwaggerUIURL,
+		"Spec":  constants.APIPrefix + constants.APIDocsSpecPath,
+	})
+}
+
+// APISpec documents the routes registered on the router. Every route is included, so the document can't fall
+// behind the router: routes listed in apiDocs are described with their parameters and schemas, others only by the
+// name of their handler. Unversioned copies of the /api/v1 routes are marked deprecated, and their responses
+// described as their original shapes.
+func APISpec(routes gin.RoutesInfo) *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:   constants.APIDocsTitle,
+		Version: constants.APIDocsVersion,
+		Description: "Search logs, follow their statistics and manage alerts and alert rules. Responses of /api/v1 " +
+			"routes are wrapped in an envelope with the request ID and timing; errors carry the request ID too.",
+	})
+	b.SecurityScheme("basicAuth", &openapi.SecurityScheme{Type: "http", Scheme: "basic", Description: "Users, when authentication is enabled"})
+	b.SecurityScheme("bearerAuth", &openapi.SecurityScheme{Type: "http", Scheme: "bearer", Description: "Service tokens, on the endpoints of their scopes"})
+
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, constants.APIPrefix+constants.APIDocsPath) {
+			continue
+		}
+		doc, documented := apiDocs[route.Method+" "+route.Path]
+		v1 := strings.HasPrefix(route.Path, constants.APIV1Prefix+"/")
+		legacy := false
+		if rest, ok := strings.CutPrefix(route.Path, constants.APIPrefix+"/"); ok && !documented && !v1 {
+			doc, legacy = apiDocs[route.Method+" "+constants.APIV1Prefix+"/"+rest]
+			documented = legacy
+		}
+		if !documented {
+			doc = apiDoc{summary: handlerSummary(route.Handler)}
+		}
+		b.Add(route.Method, route.Path, operation(b, route, doc, v1, legacy))
+	}
+	return b.Document()
+}
+
+// operation builds the operation of a route from its documentation
+func operation(b *openapi.Builder, route gin.RouteInfo, doc apiDoc, v1, legacy bool) *openapi.Operation {
+	op := &openapi.Operation{
+		Tags:        []string{doc.tag},
+		Summary:     doc.summary,
+		Description: doc.description,
+		OperationID: operationID(route.Method, route.Path),
+		Parameters:  pathParams(route.Path),
+		Deprecated:  legacy,
+		Responses:   make(map[string]*openapi.Response),
+	}
+	if op.Tags[0] == "" {
+		op.Tags[0] = routeTag(route.Path)
+	}
+	if doc.paginated {
+		op.Parameters = append(op.Parameters, pageParamDocs...)
+	}
+	op.Parameters = append(op.Parameters, doc.params...)
+	if doc.public {
+		op.Security = &[]openapi.SecurityRequirement{}
+	}
+	if doc.body != nil {
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: b.Schema(doc.body)}},
+		}
+	}
+
+	status := doc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &openapi.Response{Description: http.StatusText(status)}
+	switch {
+	case doc.contentType != "":
+		success.Content = map[string]openapi.MediaType{doc.contentType: {Schema: &openapi.Schema{Type: "string"}}}
+	case legacy:
+		success.Description = "Original response shape of the unversioned route, see its /api/v1 successor"
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{}}}
+	case v1:
+		envelope := &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"data":       b.Schema(doc.response),
+				"request_id": {Type: "string"},
+				"timing":     b.Schema(Timing{}),
+			},
+			Required: []string{"data", "request_id", "timing"},
+		}
+		if doc.paginated {
+			envelope.Properties["pagination"] = b.Schema(Pagination{})
+		}
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: envelope}}
+	default:
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: b.Schema(doc.response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	failure := legacyErrorSchema
+	if v1 {
+		failure = b.Schema(ErrorEnvelope{})
+	}
+	op.Responses["default"] = &openapi.Response{
+		Description: "Error, with a code such as VALIDATION, INVALID, NOT_FOUND, UNAUTHORIZED or FORBIDDEN",
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: failure}},
+	}
+	return op
+}
+
+// legacyErrorSchema is the schema of the error responses of unversioned routes
+var legacyErrorSchema = &openapi.Schema{
+	Type: "object",
+	Properties: map[string]*openapi.Schema{
+		"error":      {Type: "string"},
+		"code":       {Type: "string"},
+		"request_id": {Type: "string"},
+		"fields": {Type: "array", Items: &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"field":   {Type: "string"},
+				"message": {Type: "string"},
+			},
+			Required: []string{"field", "message"},
+		}},
+	},
+	Required: []string{"error", "code", "request_id"},
+}
+
+// pathParams documents the parameters of a path; IDs are integers
+func pathParams(path string) []openapi.Parameter {
+	var params []openapi.Parameter
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		schema := &openapi.Schema{Type: "string"}
+		if name == "id" {
+			schema = &openapi.Schema{Type: "integer"}
+		}
+		params = append(params, openapi.Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
+
+// routeTag groups a route by its resource, the first segment of its path below the API prefix, e.g. logs or admin
+func routeTag(path string) string {
+	rest, ok := strings.CutPrefix(path, constants.APIV1Prefix+"/")
+	if !ok {
+		if rest, ok = strings.CutPrefix(path, constants.APIPrefix+"/"); !ok {
+			return "system"
+		}
+	}
+	tag, _, _ := strings.Cut(rest, "/")
+	return tag
+}
+
+// operationID names an operation after its method and path, e.g. getApiV1AlertsId for GET /api/v1/alerts/:id
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == ':' || r == '.' }) {
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
+
+// handlerSummary turns the name of a route's handler, e.g. handlers.(*TenantHandler).GetTenants-fm, into a summary
+// such as "Get tenants"
+func handlerSummary(handler string) string {
+	name := strings.TrimSuffix(handler[strings.LastIndex(handler, ".")+1:], "-fm")
+	var words []string
+	start := 0
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, strings.ToLower(name[start:i]))
+			start = i
+		}
+	}
+	words = append(words, strings.ToLower(name[start:]))
+	summary := strings.Join(words, " ")
+	return strings.ToUpper(summary[:1]) + summary[1:]
+}
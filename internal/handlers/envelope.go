@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Envelope is the response body of /api/v1 endpoints
+type Envelope[T any] struct {
+	Data       T           `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	RequestID  string      `json:"request_id"`
+	Timing     Timing      `json:"timing"`
+}
+
+// ErrorEnvelope is the error response body of /api/v1 endpoints
+type ErrorEnvelope struct {
+	Error     APIError `json:"error"`
+	RequestID string   `json:"request_id"`
+	Timing    Timing   `json:"timing"`
+}
+
+// APIError describes a failed request
+type APIError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Fields  []apperrors.FieldError `json:"fields,omitempty"` // invalid fields of the request body
+}
+
+// Pagination describes the page returned by a list endpoint.
+// Totals aren't counted; HasMore tells whether another page follows.
+type Pagination struct {
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	Count      int  `json:"count"`
+	HasMore    bool `json:"has_more"`
+	NextOffset *int `json:"next_offset,omitempty"`
+
+	NextCursor *string `json:"next_cursor,omitempty"` // set by endpoints with keyset pagination
+}
+
+// Timing reports when the server started handling the request and how long it took
+type Timing struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// RequestContext assigns every request an ID, echoed in the X-Request-ID header, and records its start time.
+// A well-formed ID sent by the client is kept so requests can be correlated across services.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(constants.RequestStartTimeKey, time.Now())
+
+		requestID := c.GetHeader(constants.HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set(constants.RequestIDKey, requestID)
+		c.Header(constants.HeaderRequestID, requestID)
+
+		c.Next()
+	}
+}
+
+// EndOnDrain cancels the context of requests when the server starts draining, for long-lived requests such as
+// live tails that would otherwise hold up the shutdown until the client goes away
+func EndOnDrain(draining context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		stop := context.AfterFunc(draining, cancel)
+		defer stop()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Deprecated marks responses of legacy unversioned routes as deprecated, linking their /api/v1 successor
+func Deprecated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		successor := constants.APIV1Prefix + strings.TrimPrefix(c.Request.URL.Path, constants.APIPrefix)
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client-supplied request ID is short and printable
+func validRequestID(id string) bool {
+	if id == "" || len(id) > constants.MaxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isV1 reports whether the request targets the versioned API
+func isV1(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, constants.APIV1Prefix+"/")
+}
+
+// timing returns the timing of the request so far
+func timing(c *gin.Context) Timing {
+	started, ok := c.Value(constants.RequestStartTimeKey).(time.Time)
+	if !ok {
+		started = time.Now()
+	}
+	return Timing{
+		StartedAt:  started,
+		DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+}
+
+// respond writes data in the v1 envelope, or the legacy body on unversioned routes
+func respond[T any](c *gin.Context, status int, data T, legacy any) {
+	if !isV1(c) {
+		c.JSON(status, legacy)
+		return
+	}
+	c.JSON(status, Envelope[T]{
+		Data:      data,
+		RequestID: c.GetString(constants.RequestIDKey),
+		Timing:    timing(c),
+	})
+}
+
+// respondPage writes a page of items in the v1 envelope. Handlers fetch one item beyond the limit,
+// which tells whether another page follows and is trimmed here. Unversioned routes get the legacy
+// body built from the trimmed items.
+func respondPage[T any](c *gin.Context, items []T, limit, offset int, legacy func([]T) any) {
+	writePage(c, items, limit, offset, nil, legacy)
+}
+
+// respondCursorPage writes a page of items like respondPage, adding the cursor of the last item, from which the
+// next page starts. Pages requested by cursor don't report a next offset. Unversioned routes get the items as
+// they are, with the cursor in the X-Next-Cursor header.
+func respondCursorPage[T any](c *gin.Context, items []T, limit, offset int, cursor func(T) string) {
+	writePage(c, items, limit, offset, cursor, func(page []T) any { return page })
+}
+
+// writePage writes a page of items, with the cursor of the next page when a cursor function is given
+func writePage[T any](c *gin.Context, items []T, limit, offset int, cursor func(T) string, legacy func([]T) any) {
+	hasMore := limit > 0 && len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+	var nextCursor *string
+	if hasMore && cursor != nil {
+		next := cursor(items[len(items)-1])
+		nextCursor = &next
+	}
+	if !isV1(c) {
+		if nextCursor != nil {
+			c.Header(constants.HeaderNextCursor, *nextCursor)
+		}
+		c.JSON(http.StatusOK, legacy(items))
+		return
+	}
+
+	pagination := &Pagination{Limit: limit, Offset: offset, Count: len(items), HasMore: hasMore, NextCursor: nextCursor}
+	if hasMore && (cursor == nil || c.Query("cursor") == "") {
+		next := offset + len(items)
+		pagination.NextOffset = &next
+	}
+	c.JSON(http.StatusOK, Envelope[[]T]{
+		Data:       items,
+		Pagination: pagination,
+		RequestID:  c.GetString(constants.RequestIDKey),
+		Timing:     timing(c),
+	})
+}
+
+// pageParams parses limit and offset. Versioned routes default and cap the limit; unversioned
+// routes keep their previous behavior of using the given default, with 0 meaning no limit.
+func pageParams(c *gin.Context, legacyDefault int) (limit, offset int, err error) {
+	limit = legacyDefault
+	if isV1(c) {
+		limit = constants.DefaultPageLimit
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, parseErr := strconv.Atoi(limitStr)
+		if parseErr != nil || parsed <= 0 {
+			if isV1(c) {
+				return 0, 0, errInvalidLimit
+			}
+		} else {
+			limit = parsed
+		}
+	}
+	if isV1(c) && limit > constants.MaxPageLimit {
+		return 0, 0, errInvalidLimit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsed, parseErr := strconv.Atoi(offsetStr)
+		if parseErr != nil || parsed < 0 {
+			if isV1(c) {
+				return 0, 0, errInvalidOffset
+			}
+		} else {
+			offset = parsed
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination parameter errors of versioned routes
+var (
+	errInvalidLimit  = apperrors.Validation("limit must be between 1 and %d", constants.MaxPageLimit)
+	errInvalidOffset = apperrors.Validation("offset must be a non-negative integer")
+)
+
+// respondError writes an error response whose status and code are derived from the error type.
+// The fallback message is used for internal errors so that details are not leaked to clients. Both shapes carry the
+// request ID, so that a failed call can be matched with the server's logs.
+func respondError(c *gin.Context, err error, fallback string) {
+	if isV1(c) {
+		c.JSON(apperrors.HTTPStatus(err), ErrorEnvelope{
+			Error: APIError{
+				Code:    string(apperrors.CodeOf(err)),
+				Message: apperrors.MessageOf(err, fallback),
+				Fields:  apperrors.FieldsOf(err),
+			},
+			RequestID: c.GetString(constants.RequestIDKey),
+			Timing:    timing(c),
+		})
+		return
+	}
+	body := gin.H{
+		"error":      apperrors.MessageOf(err, fallback),
+		"code":       apperrors.CodeOf(err),
+		"request_id": c.GetString(constants.RequestIDKey),
+	}
+	if fields := apperrors.FieldsOf(err); fields != nil {
+		body["fields"] = fields
+	}
+	c.JSON(apperrors.HTTPStatus(err), body)
+}
+
+// respondValidationError writes a 400 response for invalid client input
+func respondValidationError(c *gin.Context, message string) {
+	respondError(c, apperrors.Validation(message), message)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles compliance export HTTP requests
+type ExportHandler struct {
+	exportService *services.ComplianceExportService
+	logger        *slog.Logger
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ComplianceExportService, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		logger:        logger,
+	}
+}
+
+// ExportLogs streams a signed, hash-chained archive of the logs matching the query filters
+func (h *ExportHandler) ExportLogs(c *gin.Context) {
+	if !h.exportService.Enabled() {
+		respondError(c, apperrors.Unavailable("Compliance export is not configured"), "Failed to export logs")
+		return
+	}
+
+	filter := &models.LogFilter{}
+
+	if level := c.Query("level"); level != "" {
+		logLevel := models.LogLevel(level)
+		filter.Level = &logLevel
+	}
+
+	if service := c.Query("service"); service != "" {
+		filter.Service = &service
+	}
+
+	if traceID := c.Query("trace_id"); traceID != "" {
+		filter.TraceID = &traceID
+	}
+
+	if spanID := c.Query("span_id"); spanID != "" {
+		filter.SpanID = &spanID
+	}
+
+	if parentSpanID := c.Query("parent_span_id"); parentSpanID != "" {
+		filter.ParentSpanID = &parentSpanID
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+
+	if startTime := c.Query("start_time"); startTime != "" {
+		t, err := time.Parse(time.RFC3339, startTime)
+		if err != nil {
+			respondValidationError(c, "Invalid start_time, expected RFC3339")
+			return
+		}
+		filter.StartTime = &t
+	}
+
+	if endTime := c.Query("end_time"); endTime != "" {
+		t, err := time.Parse(time.RFC3339, endTime)
+		if err != nil {
+			respondValidationError(c, "Invalid end_time, expected RFC3339")
+			return
+		}
+		filter.EndTime = &t
+	}
+
+	dimensions, err := dimensionFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.LogDimensions = dimensions
+
+	attributes, err := attributeFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.Attributes = attributes
+
+	if filter.HasRequestBody, err = boolFilter(c, "has_request_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if filter.HasResponseBody, err = boolFilter(c, "has_response_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	// Archives are signed as a whole and can't be cut short, so exports matching more logs than the row cap or what is
+	// left of the caller's hourly volume fail, and their rows are charged once they are written
+	quota := quotaOf(c)
+	if quota.Remaining() == 0 {
+		respondError(c, errVolumeExhausted(), "")
+		return
+	}
+	maxRows := min(int64(constants.MaxExportRows), quota.Remaining())
+
+	filename := fmt.Sprintf("logs-export-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// The archive is streamed, so failures after its first file was sent can only abort the response
+	manifest, err := h.exportService.Export(c.Request.Context(), c.Writer, filter, maxRows)
+	if err != nil {
+		h.logger.Error("Failed to export logs", "error", err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			respondError(c, err, "Failed to export logs")
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+	quota.Charge(manifest.TotalRows)
+}
+
+// VerifyExport verifies the integrity of an uploaded export archive sent as the request body
+func (h *ExportHandler) VerifyExport(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxExportVerifySize)
+
+	result, err := h.exportService.Verify(body)
+	if err != nil {
+		h.logger.Error("Failed to verify export", "error", err)
+		respondError(c, err, "Failed to verify export")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
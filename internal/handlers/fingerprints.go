@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FingerprintHandler handles error fingerprint backfill HTTP requests
+type FingerprintHandler struct {
+	service *services.FingerprintBackfillService
+	logger  *slog.Logger
+}
+
+// NewFingerprintHandler creates a new fingerprint handler
+func NewFingerprintHandler(service *services.FingerprintBackfillService, logger *slog.Logger) *FingerprintHandler {
+	return &FingerprintHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// StartBackfill starts recomputing the fingerprints of the stored error logs in the background
+func (h *FingerprintHandler) StartBackfill(c *gin.Context) {
+	backfill, err := h.service.StartBackfill(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to start fingerprint backfill", "error", err)
+		respondError(c, err, "Failed to start fingerprint backfill")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, backfill)
+}
+
+// GetStatus reports the fingerprint rules in effect and the progress of the latest backfill
+func (h *FingerprintHandler) GetStatus(c *gin.Context) {
+	status, err := h.service.GetStatus(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get fingerprint backfill", "error", err)
+		respondError(c, err, "Failed to get fingerprint backfill")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CancelBackfill cancels the running backfill; fingerprints already recomputed are kept
+func (h *FingerprintHandler) CancelBackfill(c *gin.Context) {
+	backfill, err := h.service.CancelBackfill(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to cancel fingerprint backfill", "error", err)
+		respondError(c, err, "Failed to cancel fingerprint backfill")
+		return
+	}
+
+	c.JSON(http.StatusOK, backfill)
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/services"
 	"net/http"
 	"time"
 
@@ -12,15 +14,19 @@ import (
 
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db     *database.GormDB
-	logger *slog.Logger
+	db                 *database.GormDB
+	maintenanceService *services.MaintenanceService
+	alertService       *services.AlertService
+	logger             *slog.Logger
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(db *database.GormDB, logger *slog.Logger) *HealthHandler {
+func NewHealthHandler(db *database.GormDB, maintenanceService *services.MaintenanceService, alertService *services.AlertService, logger *slog.Logger) *HealthHandler {
 	return &HealthHandler{
-		db:     db,
-		logger: logger,
+		db:                 db,
+		maintenanceService: maintenanceService,
+		alertService:       alertService,
+		logger:             logger,
 	}
 }
 
@@ -40,13 +46,23 @@ func (h *HealthHandler) HealthCheck(c *gin.Context) {
 		return
 	}
 
+	// A failing alert checker degrades the service without taking the API out of rotation
+	alertChecker := h.alertService.CheckerHealth()
+	status := "healthy"
+	if alertChecker.Status == constants.AlertCheckerFailing {
+		status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
+		"status":    status,
 		"message":   "Service is running",
 		"timestamp": time.Now(),
 		"services": gin.H{
-			"database": "healthy",
-			"api":      "healthy",
+			"database":      "healthy",
+			"api":           "healthy",
+			"alert_checker": alertChecker.Status,
 		},
+		"maintenance":   h.maintenanceService.Status(),
+		"alert_checker": alertChecker,
 	})
 } 
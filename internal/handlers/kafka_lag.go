@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KafkaLagHandler handles Kafka consumer lag HTTP requests
+type KafkaLagHandler struct {
+	lagService *services.KafkaLagService
+	logger     *slog.Logger
+}
+
+// NewKafkaLagHandler creates a new Kafka lag handler
+func NewKafkaLagHandler(lagService *services.KafkaLagService, logger *slog.Logger) *KafkaLagHandler {
+	return &KafkaLagHandler{
+		lagService: lagService,
+		logger:     logger,
+	}
+}
+
+// GetLag retrieves the lag of the processor's consumer groups
+func (h *KafkaLagHandler) GetLag(c *gin.Context) {
+	lag, err := h.lagService.GetLag(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get Kafka consumer lag", "error", err)
+		respondError(c, err, "Failed to get Kafka consumer lag")
+		return
+	}
+
+	c.JSON(http.StatusOK, lag)
+}
@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/metrics"
 	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -15,15 +23,17 @@ import (
 
 // LogHandler handles log-related HTTP requests
 type LogHandler struct {
-	logRepo logs.LogRepository
-	logger  *slog.Logger
+	logRepo   logs.LogRepository
+	dashboard *services.DashboardCache // nil when the log statistics aren't cached
+	logger    *slog.Logger
 }
 
-// NewLogHandler creates a new log handler
-func NewLogHandler(logRepo logs.LogRepository, logger *slog.Logger) *LogHandler {
+// NewLogHandler creates a new log handler. The dashboard cache is optional.
+func NewLogHandler(logRepo logs.LogRepository, dashboard *services.DashboardCache, logger *slog.Logger) *LogHandler {
 	return &LogHandler{
-		logRepo: logRepo,
-		logger:  logger,
+		logRepo:   logRepo,
+		dashboard: dashboard,
+		logger:    logger,
 	}
 }
 
@@ -45,6 +55,14 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 		filter.TraceID = &traceID
 	}
 
+	if spanID := c.Query("span_id"); spanID != "" {
+		filter.SpanID = &spanID
+	}
+
+	if parentSpanID := c.Query("parent_span_id"); parentSpanID != "" {
+		filter.ParentSpanID = &parentSpanID
+	}
+
 	if userID := c.Query("user_id"); userID != "" {
 		filter.UserID = &userID
 	}
@@ -65,62 +83,172 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 		filter.Search = &search
 	}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
-			filter.Limit = limit
-		}
-	} else {
-		filter.Limit = 100 // default limit
+	if fingerprint := c.Query("fingerprint"); fingerprint != "" {
+		filter.Fingerprint = &fingerprint
 	}
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
-			filter.Offset = offset
-		}
+	dimensions, err := dimensionFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
 	}
+	filter.LogDimensions = dimensions
+
+	attributes, err := attributeFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.Attributes = attributes
+
+	if filter.HasRequestBody, err = boolFilter(c, "has_request_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if filter.HasResponseBody, err = boolFilter(c, "has_response_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	listLogs(c, h.logRepo, filter, h.logger)
+}
+
+// listLogs responds with the page of logs matching the filter requested by the limit and offset query parameters,
+// as JSON or, to clients accepting it, as an Arrow stream. The fields query parameter selects the columns returned,
+// and compact=true returns rows of values instead of objects.
+func listLogs(c *gin.Context, logRepo logs.LogRepository, filter *models.LogFilter, logger *slog.Logger) {
+	limit, offset, err := pageParams(c, 100) // default limit
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	// The fields requested replace those of a saved search
+	fields, err := logFields(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if fields != nil {
+		filter.Fields = fields
+	}
+	compact, err := boolFilter(c, "compact")
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	// Fetch one more log than requested to tell whether another page follows
+	filter.Limit = limit + 1
+	filter.Offset = offset
 
 	// Get logs from database
-	responseLogs, err := h.logRepo.GetLogs(c.Request.Context(), filter)
+	responseLogs, err := logRepo.GetLogs(c.Request.Context(), filter)
 	if err != nil {
-		h.logger.Error("Failed to get logs", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
+		logger.Error("Failed to get logs", "error", err)
+		respondError(c, err, "Failed to retrieve logs")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"logs":   responseLogs,
-		"count":  len(responseLogs),
-		"filter": filter,
-	})
+	filter.Limit = limit
+	switch {
+	case wantsArrow(c):
+		respondLogsArrow(c, responseLogs, filter.Fields, limit, offset, logger)
+	case compact != nil && *compact:
+		// Rows of values instead of objects, without repeating the names of the columns in every log
+		columns := filter.Fields
+		if len(columns) == 0 {
+			columns = models.LogColumns
+		}
+		c.Header(constants.HeaderLogColumns, strings.Join(columns, ","))
+		respondPage(c, logRows(responseLogs, columns), limit, offset, func(page [][]any) any {
+			return gin.H{
+				"columns": columns,
+				"rows":    page,
+				"count":   len(page),
+				"filter":  filter,
+			}
+		})
+	case len(filter.Fields) > 0:
+		respondPage(c, projectLogs(responseLogs, filter.Fields), limit, offset, func(page []map[string]any) any {
+			return gin.H{
+				"logs":   page,
+				"count":  len(page),
+				"filter": filter,
+			}
+		})
+	default:
+		respondPage(c, responseLogs, limit, offset, func(page []*models.Log) any {
+			return gin.H{
+				"logs":   page,
+				"count":  len(page),
+				"filter": filter,
+			}
+		})
+	}
+}
+
+// respondLogsArrow writes a page of logs as an Arrow stream. The stream has no room for pagination, so the offset
+// of the next page, if any, is sent in the X-Next-Offset header.
+func respondLogsArrow(c *gin.Context, page []*models.Log, fields []string, limit, offset int, logger *slog.Logger) {
+	if len(page) > limit {
+		page = page[:limit]
+		c.Header(constants.HeaderNextOffset, strconv.Itoa(offset+limit))
+	}
+	columns, record, err := logArrowRecord(page, fields)
+	if err != nil {
+		logger.Error("Failed to encode logs", "error", err)
+		respondError(c, err, "Failed to retrieve logs")
+		return
+	}
+	if err := respondArrow(c, columns, record); err != nil {
+		logger.Debug("Failed to write logs", "error", err)
+	}
 }
 
 // GetLogsByTraceID retrieves all logs for a specific trace ID
 func (h *LogHandler) GetLogsByTraceID(c *gin.Context) {
 	traceID := c.Param("traceID")
 	if traceID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Trace ID is required"})
+		respondValidationError(c, "Trace ID is required")
+		return
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
 		return
 	}
 
-	responseLogs, err := h.logRepo.GetLogsByTraceID(c.Request.Context(), traceID)
+	responseLogs, err := h.logRepo.GetLogsByTraceID(c.Request.Context(), traceID, tenant)
 	if err != nil {
 		h.logger.Error("Failed to get logs by trace ID", "error", err, "trace_id", traceID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
+		respondError(c, err, "Failed to retrieve logs")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, responseLogs, gin.H{
 		"trace_id": traceID,
 		"logs":     responseLogs,
 		"count":    len(responseLogs),
 	})
 }
 
-// GetMetrics retrieves system metrics and statistics
+// GetMetrics retrieves system metrics and statistics. The statistics of the default time range without filters,
+// which every dashboard load asks for, come from the dashboard cache, except for callers limited to a tenant.
 func (h *LogHandler) GetMetrics(c *gin.Context) {
+	if h.dashboard != nil && c.Request.URL.RawQuery == "" && principalOf(c).TenantScope() == nil {
+		cached, err := h.dashboard.LogStats(c.Request.Context())
+		if err != nil {
+			h.logger.Error("Failed to get metrics", "error", err)
+			respondError(c, err, "Failed to retrieve metrics")
+			return
+		}
+		h.respondMetrics(c, cached.Stats, cached.StartTime, cached.EndTime, models.LogDimensions{})
+		return
+	}
+
 	// Parse time range with defaults
 	endTime := time.Now()
-	startTime := endTime.Add(-24 * time.Hour) // Default to last 24 hours
+	startTime := endTime.Add(-constants.DefaultMetricsRange)
 
 	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
 		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
@@ -134,14 +262,29 @@ func (h *LogHandler) GetMetrics(c *gin.Context) {
 		}
 	}
 
+	dimensions, err := dimensionFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	alignment, err := h.bucketAlignment(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
 	// Get stats from database
-	stats, err := h.logRepo.GetLogStats(c.Request.Context(), startTime, endTime)
+	stats, err := h.logRepo.GetLogStats(c.Request.Context(), startTime, endTime, dimensions, alignment)
 	if err != nil {
 		h.logger.Error("Failed to get metrics", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve metrics"})
+		respondError(c, err, "Failed to retrieve metrics")
 		return
 	}
+	h.respondMetrics(c, stats, startTime, endTime, dimensions)
+}
 
+// respondMetrics writes the statistics of a time range with the metrics derived from them
+func (h *LogHandler) respondMetrics(c *gin.Context, stats *models.LogStats, startTime, endTime time.Time, dimensions models.LogDimensions) {
 	// Calculate additional metrics
 	totalRequests := stats.TotalLogs
 	errorRate := 0.0
@@ -149,6 +292,13 @@ func (h *LogHandler) GetMetrics(c *gin.Context) {
 		errorRate = float64(stats.ErrorCount+stats.FatalCount) / float64(totalRequests) * 100
 	}
 
+	// Client and server error rates are relative to logs carrying a response status
+	clientErrorRate, serverErrorRate := 0.0, 0.0
+	if statusTotal := stats.Status2xxCount + stats.Status3xxCount + stats.Status4xxCount + stats.Status5xxCount; statusTotal > 0 {
+		clientErrorRate = float64(stats.Status4xxCount) / float64(statusTotal) * 100
+		serverErrorRate = float64(stats.Status5xxCount) / float64(statusTotal) * 100
+	}
+
 	// Calculate time duration for requests per minute
 	duration := endTime.Sub(startTime)
 	minutes := duration.Minutes()
@@ -166,17 +316,29 @@ func (h *LogHandler) GetMetrics(c *gin.Context) {
 			"debug_count":       stats.DebugCount,
 			"fatal_count":       stats.FatalCount,
 			"avg_response_time": stats.AvgResponseTime,
+			"p50_response_time": stats.P50ResponseTime,
+			"p95_response_time": stats.P95ResponseTime,
+			"p99_response_time": stats.P99ResponseTime,
+			"status_2xx_count":  stats.Status2xxCount,
+			"status_3xx_count":  stats.Status3xxCount,
+			"status_4xx_count":  stats.Status4xxCount,
+			"status_5xx_count":  stats.Status5xxCount,
 			"top_services":      stats.TopServices,
 			"top_errors":        stats.TopErrors,
 			"time_series":       stats.TimeSeries,
+			"service_latency":   stats.ServiceLatency,
 		},
 		// Calculated metrics
 		"metrics": gin.H{
-			"total_requests":      totalRequests,
-			"error_count":         stats.ErrorCount + stats.FatalCount,
-			"error_rate_percent":  errorRate,
-			"avg_response_time":   stats.AvgResponseTime,
-			"requests_per_minute": float64(totalRequests) / minutes,
+			"total_requests":            totalRequests,
+			"error_count":               stats.ErrorCount + stats.FatalCount,
+			"error_rate_percent":        errorRate,
+			"client_error_rate_percent": clientErrorRate,
+			"server_error_rate_percent": serverErrorRate,
+			"avg_response_time":         stats.AvgResponseTime,
+			"p95_response_time":         stats.P95ResponseTime,
+			"p99_response_time":         stats.P99ResponseTime,
+			"requests_per_minute":       float64(totalRequests) / minutes,
 		},
 		// Time range information
 		"time_range": gin.H{
@@ -184,12 +346,561 @@ func (h *LogHandler) GetMetrics(c *gin.Context) {
 			"end_time":         endTime,
 			"duration_minutes": minutes,
 		},
+		"filter":    dimensions,
 		"timestamp": time.Now(),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// CompareServices returns error rates, latency percentiles and volumes of the selected services side by side
+func (h *LogHandler) CompareServices(c *gin.Context) {
+	var services []string
+	seen := make(map[string]bool)
+	for _, service := range strings.Split(c.Query("services"), ",") {
+		service = strings.TrimSpace(service)
+		if service != "" && !seen[service] {
+			seen[service] = true
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		respondValidationError(c, "At least one service is required")
+		return
+	}
+	if len(services) > constants.MaxCompareServices {
+		respondValidationError(c, fmt.Sprintf("At most %d services can be compared", constants.MaxCompareServices))
+		return
+	}
+
+	// Parse time range with defaults
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour) // Default to last 24 hours
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+			startTime = t
+		}
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			endTime = t
+		}
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	comparisons, err := h.logRepo.GetServiceComparison(c.Request.Context(), services, tenant, startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to compare services", "error", err, "services", services)
+		respondError(c, err, "Failed to compare services")
+		return
+	}
+
+	minutes := endTime.Sub(startTime).Minutes()
+	if minutes <= 0 {
+		minutes = 1 // Avoid division by zero
+	}
+
+	// Report services in the requested order, including those without logs in the range
+	byService := make(map[string]models.ServiceComparison, len(comparisons))
+	for _, comparison := range comparisons {
+		byService[comparison.Service] = comparison
+	}
+	result := make([]models.ServiceComparison, 0, len(services))
+	for _, service := range services {
+		comparison, ok := byService[service]
+		if !ok {
+			comparison = models.ServiceComparison{Service: service}
+		}
+		if comparison.TotalLogs > 0 {
+			comparison.ErrorRate = float64(comparison.ErrorCount) / float64(comparison.TotalLogs) * 100
+		}
+		comparison.RequestsPerMin = float64(comparison.TotalLogs) / minutes
+		result = append(result, comparison)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"services": result,
+		"time_range": gin.H{
+			"start_time":       startTime,
+			"end_time":         endTime,
+			"duration_minutes": minutes,
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// GetTimeSeries returns per-level log counts and the error rate per time bucket for charting
+func (h *LogHandler) GetTimeSeries(c *gin.Context) {
+	// Parse time range with defaults
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour) // Default to last 24 hours
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+			startTime = t
+		}
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			endTime = t
+		}
+	}
+	if !endTime.After(startTime) {
+		respondValidationError(c, "end_time must be after start_time")
+		return
+	}
+
+	interval := constants.DefaultTimeSeriesInterval
+	if intervalStr := c.Query("interval"); intervalStr != "" {
+		parsed, err := time.ParseDuration(intervalStr)
+		if err != nil || !slices.Contains(logs.TimeSeriesIntervals, parsed) {
+			respondValidationError(c, "Interval must be one of 1m, 5m, 15m, 1h, 6h, 24h or 168h")
+			return
+		}
+		interval = parsed
+	}
+	alignment, err := h.bucketAlignment(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	// Align buckets to the interval so consecutive requests chart consistently
+	startTime = alignment.Truncate(startTime, interval)
+	if buckets := int((endTime.Sub(startTime) + interval - 1) / interval); buckets > constants.MaxTimeSeriesBuckets {
+		respondValidationError(c, fmt.Sprintf("The time range spans more than %d intervals, use a larger interval", constants.MaxTimeSeriesBuckets))
+		return
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	service := strings.TrimSpace(c.Query("service"))
+	series, err := h.logRepo.GetLogTimeSeries(c.Request.Context(), service, tenant, startTime, endTime, interval)
+	if err != nil {
+		h.logger.Error("Failed to get log time series", "error", err, "service", service)
+		respondError(c, err, "Failed to retrieve time series")
+		return
+	}
+
+	if wantsArrow(c) {
+		record, err := timeSeriesArrowRecord(series)
+		if err != nil {
+			h.logger.Error("Failed to encode log time series", "error", err)
+			respondError(c, err, "Failed to retrieve time series")
+			return
+		}
+		c.Header(constants.HeaderIntervalSeconds, strconv.FormatInt(int64(interval/time.Second), 10))
+		if err := respondArrow(c, timeSeriesArrowFields, record); err != nil {
+			h.logger.Debug("Failed to write log time series", "error", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service":               service,
+		"interval_seconds":      int64(interval / time.Second),
+		"week_start":            strings.ToLower(alignment.WeekStart.String()),
+		"bucket_offset_seconds": int64(alignment.Offset / time.Second),
+		"series":                series,
+		"time_range": gin.H{
+			"start_time":       startTime,
+			"end_time":         endTime,
+			"duration_minutes": endTime.Sub(startTime).Minutes(),
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// GetSlowRequests returns the top endpoints, request methods and paths, by p95 or average response time, with their
+// request counts, so that slow endpoints can be found from the logs
+func (h *LogHandler) GetSlowRequests(c *gin.Context) {
+	// Parse time range with defaults
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour) // Default to last 24 hours
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+			startTime = t
+		}
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			endTime = t
+		}
+	}
+	if !endTime.After(startTime) {
+		respondValidationError(c, "end_time must be after start_time")
+		return
+	}
+
+	sort := c.DefaultQuery("sort", constants.SlowRequestsSortP95)
+	if sort != constants.SlowRequestsSortP95 && sort != constants.SlowRequestsSortAvg {
+		respondValidationError(c, "Sort must be p95 or avg")
+		return
+	}
+
+	limit := constants.DefaultSlowRequestsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > constants.MaxSlowRequestsLimit {
+			respondValidationError(c, fmt.Sprintf("Limit must be between 1 and %d", constants.MaxSlowRequestsLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	minCount := constants.DefaultSlowRequestsMinCount
+	if minCountStr := c.Query("min_count"); minCountStr != "" {
+		parsed, err := strconv.Atoi(minCountStr)
+		if err != nil || parsed < 1 {
+			respondValidationError(c, "min_count must be a positive integer")
+			return
+		}
+		minCount = parsed
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	service := strings.TrimSpace(c.Query("service"))
+	requests, err := h.logRepo.GetSlowRequests(c.Request.Context(), &models.SlowRequestQuery{
+		Service:   service,
+		Tenant:    tenant,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Sort:      sort,
+		MinCount:  minCount,
+		Limit:     limit,
+	})
+	if err != nil {
+		h.logger.Error("Failed to get slow requests", "error", err, "service", service)
+		respondError(c, err, "Failed to get slow requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"sort":     sort,
+		"service":  service,
+		"time_range": gin.H{
+			"start_time":       startTime,
+			"end_time":         endTime,
+			"duration_minutes": endTime.Sub(startTime).Minutes(),
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// AggregateLogs groups the logs matching the log filters by the group_by fields and returns the groups with the
+// highest value of the aggregation: their log count, or their average or maximum response time. Logs of the last
+// day are aggregated unless start_time is given.
+func (h *LogHandler) AggregateLogs(c *gin.Context) {
+	filter, err := logFilter(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if filter.EndTime == nil {
+		now := time.Now()
+		filter.EndTime = &now
+	}
+	if filter.StartTime == nil {
+		start := filter.EndTime.Add(-constants.DefaultLogAggregationRange)
+		filter.StartTime = &start
+	}
+	if !filter.EndTime.After(*filter.StartTime) {
+		respondValidationError(c, "end_time must be after start_time")
+		return
+	}
+
+	var groupBy []string
+	for _, field := range strings.Split(c.Query("group_by"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(groupBy, field) {
+			continue
+		}
+		if !slices.Contains(models.LogGroupByFields, field) {
+			respondValidationError(c, fmt.Sprintf("group_by fields must be among %s", strings.Join(models.LogGroupByFields, ", ")))
+			return
+		}
+		groupBy = append(groupBy, field)
+	}
+	if len(groupBy) == 0 {
+		respondValidationError(c, "At least one group_by field is required")
+		return
+	}
+
+	aggregation := c.DefaultQuery("aggregation", constants.LogAggregationCount)
+	switch aggregation {
+	case constants.LogAggregationCount, constants.LogAggregationAvgResponseTime, constants.LogAggregationMaxResponseTime:
+	default:
+		respondValidationError(c, "Aggregation must be one of count, avg_response_time or max_response_time")
+		return
+	}
+
+	limit := constants.DefaultLogAggregationGroups
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > constants.MaxLogAggregationGroups {
+			respondValidationError(c, fmt.Sprintf("Limit must be between 1 and %d", constants.MaxLogAggregationGroups))
+			return
+		}
+	}
+
+	groups, err := h.logRepo.AggregateLogs(c.Request.Context(), &models.LogAggregation{
+		Filter:      *filter,
+		GroupBy:     groupBy,
+		Aggregation: aggregation,
+		Limit:       limit,
+	})
+	if err != nil {
+		h.logger.Error("Failed to aggregate logs", "error", err, "group_by", groupBy, "aggregation", aggregation)
+		respondError(c, err, "Failed to aggregate logs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_by":    groupBy,
+		"aggregation": aggregation,
+		"groups":      groups,
+		"time_range": gin.H{
+			"start_time":       *filter.StartTime,
+			"end_time":         *filter.EndTime,
+			"duration_minutes": filter.EndTime.Sub(*filter.StartTime).Minutes(),
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// GetLatencyHeatmap returns a service's log counts per time bucket and latency bucket, exposing
+// distributions such as bimodal latencies that averages and percentiles hide
+func (h *LogHandler) GetLatencyHeatmap(c *gin.Context) {
+	service := strings.TrimSpace(c.Query("service"))
+	if service == "" {
+		respondValidationError(c, "Service is required")
+		return
+	}
+
+	// Parse time range with defaults
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour) // Default to last 24 hours
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+			startTime = t
+		}
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			endTime = t
+		}
+	}
+	if !endTime.After(startTime) {
+		respondValidationError(c, "end_time must be after start_time")
+		return
+	}
+
+	interval := constants.DefaultHeatmapInterval
+	if intervalStr := c.Query("interval"); intervalStr != "" {
+		parsed, err := time.ParseDuration(intervalStr)
+		if err != nil || parsed < constants.MinHeatmapInterval || parsed%time.Second != 0 {
+			respondValidationError(c, "Interval must be a whole number of seconds, e.g. 30s or 5m")
+			return
+		}
+		interval = parsed
+	}
+	timeBuckets := int((endTime.Sub(startTime) + interval - 1) / interval)
+	if timeBuckets > constants.MaxHeatmapTimeBuckets {
+		respondValidationError(c, fmt.Sprintf("The time range spans more than %d intervals, use a larger interval", constants.MaxHeatmapTimeBuckets))
+		return
+	}
+
+	bucketsStr := c.Query("buckets")
+	if bucketsStr == "" {
+		bucketsStr = constants.DefaultHeatmapLatencyBuckets
+	}
+	bounds, err := parseLatencyBounds(bucketsStr)
+	if err != nil {
+		respondError(c, err, "Invalid latency buckets")
+		return
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	cells, err := h.logRepo.GetLatencyHeatmap(c.Request.Context(), service, tenant, startTime, endTime, interval, bounds)
+	if err != nil {
+		h.logger.Error("Failed to get latency heatmap", "error", err, "service", service)
+		respondError(c, err, "Failed to retrieve latency heatmap")
+		return
+	}
+
+	// Expand the sparse cells into a dense matrix of time buckets by latency buckets
+	counts := make([][]int64, timeBuckets)
+	for i := range counts {
+		counts[i] = make([]int64, len(bounds)+1)
+	}
+	var total int64
+	for _, cell := range cells {
+		// A log exactly at end_time lands one bucket past the range when it divides evenly
+		row := min(max(cell.TimeBucket, 0), timeBuckets-1)
+		counts[row][cell.LatencyBucket] += cell.Count
+		total += cell.Count
+	}
+
+	bucketStarts := make([]time.Time, timeBuckets)
+	for i := range bucketStarts {
+		bucketStarts[i] = startTime.Add(time.Duration(i) * interval)
+	}
+	latencyBuckets := make([]models.LatencyBucket, len(bounds)+1)
+	for i := range latencyBuckets {
+		if i > 0 {
+			latencyBuckets[i].MinMs = bounds[i-1]
+		}
+		if i < len(bounds) {
+			latencyBuckets[i].MaxMs = &bounds[i]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service":          service,
+		"interval_seconds": int64(interval / time.Second),
+		"time_buckets":     bucketStarts,
+		"latency_buckets":  latencyBuckets,
+		"counts":           counts,
+		"total":            total,
+		"time_range": gin.H{
+			"start_time":       startTime,
+			"end_time":         endTime,
+			"duration_minutes": endTime.Sub(startTime).Minutes(),
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// attributeFilters collects attr.<key>=<value> query parameters into attribute equality filters.
+// A key repeated in the query uses its first value, like every other filter.
+func attributeFilters(c *gin.Context) (map[string]string, error) {
+	var attributes map[string]string
+	for param, values := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, constants.AttributeFilterPrefix)
+		if !ok {
+			continue
+		}
+		if key == "" {
+			return nil, apperrors.Validation("attribute filters need a key, e.g. %sregion=eu-west-1", constants.AttributeFilterPrefix)
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[key] = values[0]
+	}
+	if len(attributes) > constants.MaxAttributeFilters {
+		return nil, apperrors.Validation("at most %d attribute filters are allowed", constants.MaxAttributeFilters)
+	}
+	return attributes, nil
+}
+
+// dimensionFilters parses the tenant, host, environment, region and client_ip query parameters. The tenant of
+// callers limited to one is always set.
+func dimensionFilters(c *gin.Context) (models.LogDimensions, error) {
+	tenant, err := tenantScope(c)
+	if err != nil {
+		return models.LogDimensions{}, err
+	}
+	dimensions := models.LogDimensions{Tenant: tenant}
+	for name, target := range map[string]**string{
+		"host":        &dimensions.Host,
+		"environment": &dimensions.Environment,
+		"region":      &dimensions.Region,
+		"client_ip":   &dimensions.ClientIP,
+	} {
+		if value := c.Query(name); value != "" {
+			*target = &value
+		}
+	}
+	if dimensions.ClientIP != nil && net.ParseIP(*dimensions.ClientIP) == nil {
+		return models.LogDimensions{}, apperrors.Validation("client_ip must be an IPv4 or IPv6 address")
+	}
+	return dimensions, nil
+}
+
+// bucketAlignment returns the alignment of time series buckets: the dashboard's, unless the week_start or
+// bucket_offset query parameters override it
+func (h *LogHandler) bucketAlignment(c *gin.Context) (models.BucketAlignment, error) {
+	alignment := models.BucketAlignment{WeekStart: constants.DefaultDashboardWeekStart, Offset: constants.DefaultDashboardBucketOffset}
+	if h.dashboard != nil {
+		alignment = h.dashboard.Alignment()
+	}
+	if weekStart := c.Query("week_start"); weekStart != "" {
+		day, err := models.ParseWeekday(weekStart)
+		if err != nil {
+			return models.BucketAlignment{}, apperrors.Validation("%s", err.Error())
+		}
+		alignment.WeekStart = day
+	}
+	if offsetStr := c.Query("bucket_offset"); offsetStr != "" {
+		offset, err := time.ParseDuration(offsetStr)
+		if err != nil || offset <= -constants.MaxBucketOffset || offset >= constants.MaxBucketOffset || offset%time.Second != 0 {
+			return models.BucketAlignment{}, apperrors.Validation("bucket_offset must be a whole number of seconds between -%s and %s, e.g. 6h",
+				constants.MaxBucketOffset, constants.MaxBucketOffset)
+		}
+		alignment.Offset = offset
+	}
+	return alignment, nil
+}
+
+// boolFilter parses an optional boolean query parameter, such as has_request_body
+func boolFilter(c *gin.Context, name string) (*bool, error) {
+	valueStr := c.Query(name)
+	if valueStr == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return nil, apperrors.Validation("%s must be true or false", name)
+	}
+	return &value, nil
+}
+
+// parseLatencyBounds parses comma-separated, strictly increasing latency bucket upper bounds in milliseconds
+func parseLatencyBounds(value string) ([]int, error) {
+	var bounds []int
+	for _, part := range strings.Split(value, ",") {
+		bound, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || bound <= 0 {
+			return nil, apperrors.Validation("invalid latency bucket %q: bounds must be positive milliseconds", strings.TrimSpace(part))
+		}
+		if len(bounds) > 0 && bound <= bounds[len(bounds)-1] {
+			return nil, apperrors.Validation("latency buckets must be strictly increasing")
+		}
+		bounds = append(bounds, bound)
+	}
+	if len(bounds) > constants.MaxHeatmapLatencyBuckets {
+		return nil, apperrors.Validation("at most %d latency buckets are allowed", constants.MaxHeatmapLatencyBuckets)
+	}
+	return bounds, nil
+}
+
 // HandleLog processes a single log message from Kafka
 func (h *LogHandler) HandleLog(ctx context.Context, log *models.Log) error {
 	// Store log in database
@@ -213,12 +924,15 @@ func (h *LogHandler) HandleLog(ctx context.Context, log *models.Log) error {
 // HandleLogBatch processes a batch of log messages from Kafka
 func (h *LogHandler) HandleLogBatch(ctx context.Context, logs []*models.Log) error {
 	// Store logs in database
+	start := time.Now()
 	if err := h.logRepo.CreateLogBatch(ctx, logs); err != nil {
+		metrics.DBInsertDuration.WithLabelValues(metrics.StatusError).Observe(time.Since(start).Seconds())
 		h.logger.Error("Failed to store log batch",
 			"error", err,
 			"batch_size", len(logs))
 		return err
 	}
+	metrics.DBInsertDuration.WithLabelValues(metrics.StatusSuccess).Observe(time.Since(start).Seconds())
 
 	h.logger.Info("Log batch processed successfully",
 		"batch_size", len(logs),
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logExportColumns are the CSV columns of exported logs, in the order of the log fields
+var logExportColumns = models.LogColumns
+
+// ExportLogs streams the logs matching the query filters, oldest first, as CSV or NDJSON (format=csv|ndjson).
+// At most limit logs are exported, bounded by the hard row cap and the caller's hourly export volume; the
+// X-Export-Truncated trailer tells whether more logs matched.
+func (h *LogHandler) ExportLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", constants.LogExportFormatCSV)
+	if format != constants.LogExportFormatCSV && format != constants.LogExportFormatNDJSON {
+		respondValidationError(c, "Invalid format, expected csv or ndjson")
+		return
+	}
+
+	limit := constants.MaxLogExportRows
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > constants.MaxLogExportRows {
+			respondValidationError(c, fmt.Sprintf("Invalid limit, expected 1 to %d", constants.MaxLogExportRows))
+			return
+		}
+		limit = parsed
+	}
+
+	filter, err := logFilter(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	quota := quotaOf(c)
+	if quota.Remaining() == 0 {
+		respondError(c, errVolumeExhausted(), "")
+		return
+	}
+
+	// CSV rows are buffered by the CSV writer and flushed to the client after every page
+	var csvWriter *csv.Writer
+	var encoder *json.Encoder
+	if format == constants.LogExportFormatCSV {
+		csvWriter = csv.NewWriter(c.Writer)
+	} else {
+		encoder = json.NewEncoder(c.Writer)
+	}
+
+	// Large exports outlast the server write timeout, so the deadline is extended as long as pages keep coming
+	controller := http.NewResponseController(c.Writer)
+	ctx := c.Request.Context()
+	cursor := ""
+	written := 0
+	complete := false
+	capped := false
+	for written < limit {
+		filter.Limit = int(min(int64(min(constants.LogExportPageSize, limit-written)), quota.Remaining()))
+		if filter.Limit == 0 {
+			break
+		}
+		page, next, err := h.logRepo.GetLogsAfterCursor(ctx, filter, cursor)
+		if err != nil {
+			h.logger.Error("Failed to export logs", "error", err, "written", written)
+			if !c.Writer.Written() {
+				respondError(c, err, "Failed to export logs")
+			} else {
+				// The export is streamed, so failures after the first page can only abort the response
+				panic(http.ErrAbortHandler)
+			}
+			return
+		}
+
+		// Concurrent requests of the caller share the hourly volume, so the page may be cut to what is left of it
+		fetched := len(page)
+		page = page[:quota.Take(int64(fetched))]
+		capped = len(page) < fetched
+
+		controller.SetWriteDeadline(time.Now().Add(constants.LogExportPageWriteTimeout))
+
+		// Headers are sent with the first page, so failures of the first query can still be reported
+		if !c.Writer.Written() {
+			startLogExport(c, format)
+			if csvWriter != nil {
+				csvWriter.Write(logExportColumns)
+			}
+		}
+		for _, log := range page {
+			if csvWriter != nil {
+				err = csvWriter.Write(logExportRecord(log))
+			} else {
+				err = encoder.Encode(log)
+			}
+			if err != nil {
+				break
+			}
+		}
+		if csvWriter != nil && err == nil {
+			csvWriter.Flush()
+			err = csvWriter.Error()
+		}
+		if err != nil {
+			h.logger.Error("Failed to write log export", "error", err, "written", written)
+			panic(http.ErrAbortHandler)
+		}
+		c.Writer.Flush()
+
+		written += len(page)
+		cursor = next
+		if capped {
+			break
+		}
+		if len(page) < filter.Limit {
+			complete = true
+			break
+		}
+	}
+
+	truncated := capped
+	if !complete && !capped {
+		filter.Limit = 1
+		more, _, err := h.logRepo.GetLogsAfterCursor(ctx, filter, cursor)
+		if err != nil {
+			h.logger.Warn("Failed to check for logs beyond the export limit", "error", err)
+		}
+		truncated = len(more) > 0
+	}
+	c.Writer.Header().Set(constants.HeaderLogExportTruncated, strconv.FormatBool(truncated))
+}
+
+// startLogExport sends the headers of a log export, declaring the truncation trailer sent after the logs
+func startLogExport(c *gin.Context, format string) {
+	contentType := "text/csv; charset=utf-8"
+	if format == constants.LogExportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("logs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Trailer", constants.HeaderLogExportTruncated)
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+}
+
+// logExportRecord renders a log as a CSV record of the export columns; unset fields are empty
+func logExportRecord(log *models.Log) []string {
+	optional := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+	optionalInt := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.Itoa(*value)
+	}
+	attributes := ""
+	if len(log.Attributes) > 0 {
+		if data, err := json.Marshal(log.Attributes); err == nil {
+			attributes = string(data)
+		}
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(log.ID), 10),
+		log.Timestamp.Format(time.RFC3339Nano),
+		string(log.Level),
+		log.Service,
+		optional(log.Tenant),
+		optional(log.Host),
+		optional(log.Environment),
+		optional(log.Region),
+		optional(log.ClientIP),
+		log.Message,
+		optional(log.Fingerprint),
+		optional(log.TraceID),
+		optional(log.SpanID),
+		optional(log.ParentSpanID),
+		optional(log.UserID),
+		optional(log.RequestMethod),
+		optional(log.RequestPath),
+		optionalInt(log.ResponseStatus),
+		optionalInt(log.ResponseTimeMs),
+		optional(log.RequestBody),
+		optional(log.ResponseBody),
+		attributes,
+		log.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// logFilter parses the log filter query parameters shared by the log listing endpoints.
+// Unlike GET /api/logs, invalid times are rejected rather than ignored.
+func logFilter(c *gin.Context) (*models.LogFilter, error) {
+	filter := &models.LogFilter{}
+
+	if level := c.Query("level"); level != "" {
+		logLevel := models.LogLevel(level)
+		filter.Level = &logLevel
+	}
+	if service := c.Query("service"); service != "" {
+		filter.Service = &service
+	}
+	if traceID := c.Query("trace_id"); traceID != "" {
+		filter.TraceID = &traceID
+	}
+	if spanID := c.Query("span_id"); spanID != "" {
+		filter.SpanID = &spanID
+	}
+	if parentSpanID := c.Query("parent_span_id"); parentSpanID != "" {
+		filter.ParentSpanID = &parentSpanID
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+	if fingerprint := c.Query("fingerprint"); fingerprint != "" {
+		filter.Fingerprint = &fingerprint
+	}
+
+	if startTime := c.Query("start_time"); startTime != "" {
+		t, err := time.Parse(time.RFC3339, startTime)
+		if err != nil {
+			return nil, apperrors.Validation("Invalid start_time, expected RFC3339")
+		}
+		filter.StartTime = &t
+	}
+	if endTime := c.Query("end_time"); endTime != "" {
+		t, err := time.Parse(time.RFC3339, endTime)
+		if err != nil {
+			return nil, apperrors.Validation("Invalid end_time, expected RFC3339")
+		}
+		filter.EndTime = &t
+	}
+
+	var err error
+	if filter.LogDimensions, err = dimensionFilters(c); err != nil {
+		return nil, err
+	}
+	if filter.Attributes, err = attributeFilters(c); err != nil {
+		return nil, err
+	}
+	if filter.HasRequestBody, err = boolFilter(c, "has_request_body"); err != nil {
+		return nil, err
+	}
+	if filter.HasResponseBody, err = boolFilter(c, "has_response_body"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/models"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logFieldValues read the value of each log column, as rendered in JSON; unset fields are nil pointers
+var logFieldValues = map[string]func(*models.Log) any{
+	"id":               func(l *models.Log) any { return l.ID },
+	"timestamp":        func(l *models.Log) any { return l.Timestamp },
+	"level":            func(l *models.Log) any { return l.Level },
+	"service":          func(l *models.Log) any { return l.Service },
+	"tenant":           func(l *models.Log) any { return l.Tenant },
+	"host":             func(l *models.Log) any { return l.Host },
+	"environment":      func(l *models.Log) any { return l.Environment },
+	"region":           func(l *models.Log) any { return l.Region },
+	"client_ip":        func(l *models.Log) any { return l.ClientIP },
+	"message":          func(l *models.Log) any { return l.Message },
+	"fingerprint":      func(l *models.Log) any { return l.Fingerprint },
+	"trace_id":         func(l *models.Log) any { return l.TraceID },
+	"span_id":          func(l *models.Log) any { return l.SpanID },
+	"parent_span_id":   func(l *models.Log) any { return l.ParentSpanID },
+	"user_id":          func(l *models.Log) any { return l.UserID },
+	"request_method":   func(l *models.Log) any { return l.RequestMethod },
+	"request_path":     func(l *models.Log) any { return l.RequestPath },
+	"response_status":  func(l *models.Log) any { return l.ResponseStatus },
+	"response_time_ms": func(l *models.Log) any { return l.ResponseTimeMs },
+	"request_body":     func(l *models.Log) any { return l.RequestBody },
+	"response_body":    func(l *models.Log) any { return l.ResponseBody },
+	"attributes":       func(l *models.Log) any { return l.Attributes },
+	"created_at":       func(l *models.Log) any { return l.CreatedAt },
+}
+
+// logFields parses the fields query parameter, the comma-separated log columns a listing returns
+func logFields(c *gin.Context) ([]string, error) {
+	value := c.Query("fields")
+	if value == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(models.LogColumns, field) {
+			return nil, apperrors.Validation("unknown field %q, expected some of %s", field, strings.Join(models.LogColumns, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// projectLogs renders logs as objects of the given columns only
+func projectLogs(logs []*models.Log, fields []string) []map[string]any {
+	projected := make([]map[string]any, len(logs))
+	for i, log := range logs {
+		projected[i] = make(map[string]any, len(fields))
+		for _, field := range fields {
+			projected[i][field] = logFieldValues[field](log)
+		}
+	}
+	return projected
+}
+
+// logRows renders logs as rows of the values of the given columns, in their order
+func logRows(logs []*models.Log, fields []string) [][]any {
+	rows := make([][]any, len(logs))
+	for i, log := range logs {
+		rows[i] = make([]any, len(fields))
+		for j, field := range fields {
+			rows[i][j] = logFieldValues[field](log)
+		}
+	}
+	return rows
+}
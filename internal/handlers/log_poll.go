@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogPollHandler serves long-poll requests for newly stored logs
+type LogPollHandler struct {
+	logRepo logs.LogRepository
+	feed    *services.LogFeed
+	maxWait time.Duration
+	logger  *slog.Logger
+}
+
+// NewLogPollHandler creates a new log poll handler. maxWait bounds how long a request may block.
+func NewLogPollHandler(logRepo logs.LogRepository, feed *services.LogFeed, maxWait time.Duration, logger *slog.Logger) *LogPollHandler {
+	if maxWait > constants.MaxLogPollWait {
+		maxWait = constants.MaxLogPollWait
+	}
+	if maxWait < 0 {
+		maxWait = 0
+	}
+	return &LogPollHandler{
+		logRepo: logRepo,
+		feed:    feed,
+		maxWait: maxWait,
+		logger:  logger,
+	}
+}
+
+// PollLogs blocks until logs matching the filters are stored after the cursor or the wait expires.
+// Without a cursor, polling starts from the most recent log.
+func (h *LogPollHandler) PollLogs(c *gin.Context) {
+	filter := &models.LogFilter{Limit: constants.DefaultLogPollLimit}
+
+	if level := c.Query("level"); level != "" {
+		logLevel := models.LogLevel(level)
+		filter.Level = &logLevel
+	}
+
+	if service := c.Query("service"); service != "" {
+		filter.Service = &service
+	}
+
+	if traceID := c.Query("trace_id"); traceID != "" {
+		filter.TraceID = &traceID
+	}
+
+	if spanID := c.Query("span_id"); spanID != "" {
+		filter.SpanID = &spanID
+	}
+
+	if parentSpanID := c.Query("parent_span_id"); parentSpanID != "" {
+		filter.ParentSpanID = &parentSpanID
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+
+	dimensions, err := dimensionFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.LogDimensions = dimensions
+
+	attributes, err := attributeFilters(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.Attributes = attributes
+
+	if filter.HasRequestBody, err = boolFilter(c, "has_request_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if filter.HasResponseBody, err = boolFilter(c, "has_response_body"); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			respondValidationError(c, "Invalid limit")
+			return
+		}
+		filter.Limit = min(limit, constants.MaxLogPollLimit)
+	}
+
+	// Logs beyond what is left of the caller's hourly tail volume are left for later polls
+	quota := quotaOf(c)
+	remaining := quota.Remaining()
+	if remaining == 0 {
+		respondError(c, errVolumeExhausted(), "")
+		return
+	}
+	filter.Limit = int(min(int64(filter.Limit), remaining))
+
+	wait := constants.DefaultLogPollWait
+	if waitStr := c.Query("wait"); waitStr != "" {
+		parsed, err := time.ParseDuration(waitStr)
+		if err != nil || parsed < 0 {
+			respondValidationError(c, "Invalid wait duration")
+			return
+		}
+		wait = parsed
+	}
+	if wait > h.maxWait {
+		wait = h.maxWait
+	}
+
+	ctx := c.Request.Context()
+	cursor := c.Query("cursor")
+	if cursor == "" {
+		head, err := h.feed.Cursor(ctx)
+		if err != nil {
+			h.logger.Error("Failed to get log cursor", "error", err)
+			respondError(c, err, "Failed to poll logs")
+			return
+		}
+		cursor = head
+	}
+
+	// Subscribe before the first query so logs stored in between still wake us up
+	sub := h.feed.Subscribe(filter)
+	defer h.feed.Unsubscribe(sub)
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		newLogs, next, err := h.logRepo.GetLogsAfterCursor(ctx, filter, cursor)
+		if err != nil {
+			h.logger.Error("Failed to poll logs", "error", err)
+			respondError(c, err, "Failed to poll logs")
+			return
+		}
+		if len(newLogs) > 0 {
+			h.respond(c, newLogs, next)
+			quota.Charge(int64(len(newLogs)))
+			return
+		}
+
+		select {
+		case <-sub.Notify():
+		case <-waitCtx.Done():
+			h.respond(c, newLogs, next)
+			return
+		}
+	}
+}
+
+// respond writes the poll result with the cursor to continue from
+func (h *LogPollHandler) respond(c *gin.Context, newLogs []*models.Log, cursor string) {
+	c.JSON(http.StatusOK, gin.H{
+		"logs":   newLogs,
+		"count":  len(newLogs),
+		"cursor": cursor,
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogStreamHandler serves the live tail of newly stored logs as Server-Sent Events
+type LogStreamHandler struct {
+	stream *services.LogStream // nil when the live tail is disabled
+	logger *slog.Logger
+}
+
+// NewLogStreamHandler creates a new log stream handler
+func NewLogStreamHandler(stream *services.LogStream, logger *slog.Logger) *LogStreamHandler {
+	return &LogStreamHandler{
+		stream: stream,
+		logger: logger,
+	}
+}
+
+// StreamLogs streams the logs matching the tenant, service, level and search filters as they are stored, until the
+// client disconnects. Every log is sent as a "log" event; a "dropped" event reports how many logs were skipped
+// because the client fell behind, and a "limit" event ends the stream once the caller's hourly tail volume is
+// used up.
+func (h *LogStreamHandler) StreamLogs(c *gin.Context) {
+	if h.stream == nil {
+		respondError(c, apperrors.Unavailable("Live tail is disabled"), "")
+		return
+	}
+
+	filter := &models.LogFilter{}
+	if level := c.Query("level"); level != "" {
+		logLevel := models.LogLevel(level)
+		filter.Level = &logLevel
+	}
+	if service := c.Query("service"); service != "" {
+		filter.Service = &service
+	}
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	filter.Tenant = tenant
+
+	quota := quotaOf(c)
+	if quota.Remaining() == 0 {
+		respondError(c, errVolumeExhausted(), "")
+		return
+	}
+
+	sub, err := h.stream.Subscribe(filter)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	defer h.stream.Unsubscribe(sub)
+
+	startedAt := time.Now()
+	defer func() {
+		h.logger.InfoContext(c.Request.Context(), "Live tail ended", "client_ip", c.ClientIP(), "logs", quota.Used(),
+			"duration", time.Since(startedAt))
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	// The stream outlasts the server write timeout, so the deadline is extended for every event
+	controller := http.NewResponseController(c.Writer)
+	heartbeat := time.NewTicker(constants.LogStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			controller.SetWriteDeadline(time.Now().Add(constants.LogStreamWriteTimeout))
+			_, err = fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case log := <-sub.Logs():
+			controller.SetWriteDeadline(time.Now().Add(constants.LogStreamWriteTimeout))
+			if dropped := sub.Dropped(); dropped > 0 {
+				_, err = fmt.Fprintf(c.Writer, "event: dropped\ndata: {\"count\":%d}\n\n", dropped)
+			}
+			if err == nil && quota.Take(1) == 0 {
+				fmt.Fprint(c.Writer, "event: limit\ndata: {\"reason\":\"hourly tail volume used up\"}\n\n")
+				c.Writer.Flush()
+				return
+			}
+			if err == nil {
+				err = writeLogEvent(c, log)
+			}
+		}
+		if err != nil {
+			h.logger.Debug("Live tail client disconnected", "error", err)
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeLogEvent writes a log as a "log" event identified by the log ID
+func writeLogEvent(c *gin.Context, log *models.Log) error {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: log\nid: %d\ndata: %s\n\n", log.ID, data)
+	return err
+}
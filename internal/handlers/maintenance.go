@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"slices"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler handles maintenance mode HTTP requests
+type MaintenanceHandler struct {
+	maintenanceService *services.MaintenanceService
+	logger             *slog.Logger
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenanceService *services.MaintenanceService, logger *slog.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenanceService: maintenanceService,
+		logger:             logger,
+	}
+}
+
+// GetMaintenanceStatus retrieves the read-only switch and maintenance banner
+func (h *MaintenanceHandler) GetMaintenanceStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenanceService.Status())
+}
+
+// UpdateMaintenanceStatus toggles read-only mode and sets the maintenance banner
+func (h *MaintenanceHandler) UpdateMaintenanceStatus(c *gin.Context) {
+	var update models.MaintenanceUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if update.Banner != nil && len(*update.Banner) > constants.MaxMaintenanceBannerLength {
+		respondValidationError(c, "Banner is too long")
+		return
+	}
+
+	status, err := h.maintenanceService.Update(c.Request.Context(), &update)
+	if err != nil {
+		h.logger.Error("Failed to update maintenance status", "error", err)
+		respondError(c, err, "Failed to update maintenance status")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ReadOnlyGuard rejects mutating requests while read-only mode is active.
+// Routes listed in exempt (by their registered path) are always allowed.
+func (h *MaintenanceHandler) ReadOnlyGuard(exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if slices.Contains(exempt, c.FullPath()) || !h.maintenanceService.IsReadOnly() {
+			c.Next()
+			return
+		}
+
+		respondError(c, apperrors.Unavailable("The API is in read-only mode for maintenance"), "")
+		c.Abort()
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/database/migrations"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MigrationHandler handles schema migration history HTTP requests
+type MigrationHandler struct {
+	repo   migrations.MigrationRepository
+	logger *slog.Logger
+}
+
+// NewMigrationHandler creates a new migration handler
+func NewMigrationHandler(repo migrations.MigrationRepository, logger *slog.Logger) *MigrationHandler {
+	return &MigrationHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetMigrations retrieves the applied schema migrations, oldest first
+func (h *MigrationHandler) GetMigrations(c *gin.Context) {
+	applied, err := h.repo.GetMigrations(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get migrations", "error", err)
+		respondError(c, err, "Failed to get migrations")
+		return
+	}
+	if applied == nil {
+		applied = []models.Migration{}
+	}
+
+	c.JSON(http.StatusOK, applied)
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/database/notifications"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles notification channel HTTP requests
+type NotificationHandler struct {
+	repo    notifications.NotificationRepository
+	service *services.NotificationService
+	logger  *slog.Logger
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(repo notifications.NotificationRepository, service *services.NotificationService, logger *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		repo:    repo,
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateChannel creates a new notification channel
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	channel.ID = 0
+	if err := h.service.ValidateChannel(&channel); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	channel.CreatedAt = time.Now()
+	channel.UpdatedAt = time.Now()
+	if err := h.repo.CreateChannel(c.Request.Context(), &channel); err != nil {
+		h.logger.Error("Failed to create notification channel", "error", err)
+		respondError(c, err, "Failed to create notification channel")
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// GetChannels retrieves all notification channels
+func (h *NotificationHandler) GetChannels(c *gin.Context) {
+	channels, err := h.repo.GetChannels(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get notification channels", "error", err)
+		respondError(c, err, "Failed to get notification channels")
+		return
+	}
+	if channels == nil {
+		channels = []models.NotificationChannel{}
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetChannelByID retrieves a notification channel by ID
+func (h *NotificationHandler) GetChannelByID(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+
+	channel, err := h.repo.GetChannelByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get notification channel", "error", err, "id", id)
+		respondError(c, err, "Failed to get notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// UpdateChannel replaces a notification channel's name, type, settings and enabled flag
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := h.service.ValidateChannel(&channel); err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	channel.ID = id
+	channel.UpdatedAt = time.Now()
+	if err := h.repo.UpdateChannel(c.Request.Context(), &channel); err != nil {
+		h.logger.Error("Failed to update notification channel", "error", err)
+		respondError(c, err, "Failed to update notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel deletes a notification channel
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteChannel(c.Request.Context(), id); err != nil {
+		h.logger.Error("Failed to delete notification channel", "error", err)
+		respondError(c, err, "Failed to delete notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted successfully"})
+}
+
+// TestChannel sends a test notification to a channel, without retries, and reports the outcome
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+
+	channel, err := h.repo.GetChannelByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get notification channel", "error", err, "id", id)
+		respondError(c, err, "Failed to get notification channel")
+		return
+	}
+
+	if err := h.service.Test(c.Request.Context(), channel); err != nil {
+		h.logger.Warn("Test notification failed", "error", err, "channel", channel.Name)
+		if apperrors.CodeOf(err) == apperrors.CodeValidation {
+			respondError(c, err, "")
+			return
+		}
+		respondError(c, apperrors.Unavailable("Test notification failed: %s", err.Error()), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent successfully"})
+}
+
+// GetRuleChannels retrieves the channels an alert rule notifies
+func (h *NotificationHandler) GetRuleChannels(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	channels, err := h.repo.GetRuleChannels(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to get alert rule channels", "error", err, "rule_id", id)
+		respondError(c, err, "Failed to get alert rule channels")
+		return
+	}
+	if channels == nil {
+		channels = []models.NotificationChannel{}
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// SetRuleChannels replaces the channels an alert rule notifies
+func (h *NotificationHandler) SetRuleChannels(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid alert rule ID")
+		return
+	}
+
+	var update models.AlertRuleChannelsUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+
+	if err := h.repo.SetRuleChannels(c.Request.Context(), uint(id), update.ChannelIDs); err != nil {
+		h.logger.Error("Failed to set alert rule channels", "error", err, "rule_id", id)
+		respondError(c, err, "Failed to set alert rule channels")
+		return
+	}
+
+	h.GetRuleChannels(c)
+}
+
+// channelID parses the channel ID path parameter, responding with a validation error when it is invalid
+func channelID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid notification channel ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/health"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessHandler tells load balancers and orchestrators when the API server is alive, and when it may receive traffic
+type ReadinessHandler struct {
+	checks map[string]health.Check
+}
+
+// NewReadinessHandler creates a new readiness handler
+func NewReadinessHandler(db *database.GormDB, dashboard *services.DashboardCache) *ReadinessHandler {
+	return &ReadinessHandler{checks: map[string]health.Check{
+		constants.ProbeCheckDatabase: db.Ping,
+		constants.ProbeCheckDashboard: func(context.Context) error {
+			if !dashboard.Ready() {
+				return errors.New("warming up")
+			}
+			return nil
+		},
+	}}
+}
+
+// Liveness answers 200 as long as the server answers at all, so that orchestrators restart it only when it hangs
+// rather than whenever a dependency is down
+func (h *ReadinessHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Live())
+}
+
+// Readiness answers 503 while the database is unreachable and until the dashboard cache is warmed up, so that a
+// freshly deployed server only gets traffic once the first dashboard loads are served from the cache
+func (h *ReadinessHandler) Readiness(c *gin.Context) {
+	report, ready := health.Ready(c.Request.Context(), h.checks)
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery recovers from panics of handlers, logging them and responding 500 if nothing was sent yet. Panics with
+// http.ErrAbortHandler are passed on, so that the server aborts a streamed response rather than ending it as if it
+// were complete.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			logger.Error("Recovered from panic", "error", err, "request_method", c.Request.Method,
+				"request_path", c.Request.URL.Path, "stack", string(debug.Stack()))
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/database/retention"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler handles log retention policy HTTP requests
+type RetentionHandler struct {
+	repo   retention.RetentionRepository
+	logger *slog.Logger
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(repo retention.RetentionRepository, logger *slog.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreatePolicy creates a new retention policy; policies are enabled unless the request says otherwise
+func (h *RetentionHandler) CreatePolicy(c *gin.Context) {
+	policy := models.LogRetentionPolicy{Enabled: true}
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	policy.ID = 0
+	policy.LastRunAt = nil
+	policy.LastPurged = 0
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+	if err := h.repo.CreatePolicy(c.Request.Context(), &policy); err != nil {
+		h.logger.Error("Failed to create retention policy", "error", err)
+		respondError(c, err, "Failed to create retention policy")
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// GetPolicies retrieves all retention policies
+func (h *RetentionHandler) GetPolicies(c *gin.Context) {
+	policies, err := h.repo.GetPolicies(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get retention policies", "error", err)
+		respondError(c, err, "Failed to get retention policies")
+		return
+	}
+	if policies == nil {
+		policies = []models.LogRetentionPolicy{}
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetPolicyByID retrieves a retention policy by ID
+func (h *RetentionHandler) GetPolicyByID(c *gin.Context) {
+	id, ok := policyID(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.repo.GetPolicyByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get retention policy", "error", err, "id", id)
+		respondError(c, err, "Failed to get retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy replaces a retention policy's scope, maximum age, action and enabled flag
+func (h *RetentionHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := policyID(c)
+	if !ok {
+		return
+	}
+
+	policy := models.LogRetentionPolicy{Enabled: true}
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	policy.ID = id
+	policy.UpdatedAt = time.Now()
+	if err := h.repo.UpdatePolicy(c.Request.Context(), &policy); err != nil {
+		h.logger.Error("Failed to update retention policy", "error", err)
+		respondError(c, err, "Failed to update retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy deletes a retention policy
+func (h *RetentionHandler) DeletePolicy(c *gin.Context) {
+	id, ok := policyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePolicy(c.Request.Context(), id); err != nil {
+		h.logger.Error("Failed to delete retention policy", "error", err)
+		respondError(c, err, "Failed to delete retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted successfully"})
+}
+
+// policyID parses the retention policy ID path parameter, responding with a validation error when it is invalid
+func policyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid retention policy ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/saved_searches"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedSearchHandler handles saved search HTTP requests
+type SavedSearchHandler struct {
+	repo    saved_searches.SavedSearchRepository
+	logRepo logs.LogRepository
+	logger  *slog.Logger
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler(repo saved_searches.SavedSearchRepository, logRepo logs.LogRepository, logger *slog.Logger) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		repo:    repo,
+		logRepo: logRepo,
+		logger:  logger,
+	}
+}
+
+// CreateSearch saves a named log filter, recording the caller as its creator
+func (h *SavedSearchHandler) CreateSearch(c *gin.Context) {
+	var search models.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := search.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	search.ID = 0
+	search.CreatedBy = ""
+	if principal, ok := c.Get(constants.AuthPrincipalKey); ok {
+		search.CreatedBy = principal.(*models.Principal).Username
+	}
+	search.CreatedAt = time.Now()
+	search.UpdatedAt = time.Now()
+	if err := h.repo.CreateSearch(c.Request.Context(), &search); err != nil {
+		h.logger.Error("Failed to create saved search", "error", err)
+		respondError(c, err, "Failed to create saved search")
+		return
+	}
+
+	c.JSON(http.StatusCreated, search)
+}
+
+// GetSearches retrieves all saved searches by name
+func (h *SavedSearchHandler) GetSearches(c *gin.Context) {
+	searches, err := h.repo.GetSearches(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get saved searches", "error", err)
+		respondError(c, err, "Failed to get saved searches")
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// GetSearchByID retrieves a saved search by ID
+func (h *SavedSearchHandler) GetSearchByID(c *gin.Context) {
+	id, ok := savedSearchID(c)
+	if !ok {
+		return
+	}
+
+	search, err := h.repo.GetSearchByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get saved search", "error", err, "id", id)
+		respondError(c, err, "Failed to get saved search")
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+// UpdateSearch replaces a saved search's name and filter
+func (h *SavedSearchHandler) UpdateSearch(c *gin.Context) {
+	id, ok := savedSearchID(c)
+	if !ok {
+		return
+	}
+
+	var search models.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := search.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	search.ID = id
+	search.UpdatedAt = time.Now()
+	if err := h.repo.UpdateSearch(c.Request.Context(), &search); err != nil {
+		h.logger.Error("Failed to update saved search", "error", err)
+		respondError(c, err, "Failed to update saved search")
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+// DeleteSearch deletes a saved search
+func (h *SavedSearchHandler) DeleteSearch(c *gin.Context) {
+	id, ok := savedSearchID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteSearch(c.Request.Context(), id); err != nil {
+		h.logger.Error("Failed to delete saved search", "error", err)
+		respondError(c, err, "Failed to delete saved search")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// ExecuteSearch runs a saved search, responding like GET /api/logs. The start_time and end_time query
+// parameters (RFC3339) replace the saved time range, so a search can be run over a different window.
+func (h *SavedSearchHandler) ExecuteSearch(c *gin.Context) {
+	id, ok := savedSearchID(c)
+	if !ok {
+		return
+	}
+
+	search, err := h.repo.GetSearchByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get saved search", "error", err, "id", id)
+		respondError(c, err, "Failed to get saved search")
+		return
+	}
+
+	filter := models.LogFilter(search.Filter)
+	for name, target := range map[string]**time.Time{
+		"start_time": &filter.StartTime,
+		"end_time":   &filter.EndTime,
+	} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondValidationError(c, fmt.Sprintf("Invalid %s, expected RFC3339", name))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	// Searches are shared, so callers limited to a tenant only ever run them over the tenant's logs
+	if scope := principalOf(c).TenantScope(); scope != nil {
+		if filter.Tenant != nil && *filter.Tenant != *scope {
+			respondError(c, apperrors.Forbidden("Access to tenant %s is not allowed", *filter.Tenant), "")
+			return
+		}
+		filter.Tenant = scope
+	}
+
+	listLogs(c, h.logRepo, &filter, h.logger)
+}
+
+// savedSearchID parses the saved search ID path parameter, responding with a validation error when it is invalid
+func savedSearchID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid saved search ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/scheduler"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchedulerHandler handles scheduled task HTTP requests
+type SchedulerHandler struct {
+	repo   scheduler.SchedulerRepository
+	logger *slog.Logger
+}
+
+// NewSchedulerHandler creates a new scheduler handler
+func NewSchedulerHandler(repo scheduler.SchedulerRepository, logger *slog.Logger) *SchedulerHandler {
+	return &SchedulerHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetTasks lists the scheduled tasks, the API server holding each lease and the outcome of each last run
+func (h *SchedulerHandler) GetTasks(c *gin.Context) {
+	tasks, err := h.repo.GetTasks(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get scheduled tasks", "error", err)
+		respondError(c, err, "Failed to get scheduled tasks")
+		return
+	}
+	if tasks == nil {
+		tasks = []models.ScheduledTask{}
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetRuns retrieves the latest runs of a scheduled task, newest first
+func (h *SchedulerHandler) GetRuns(c *gin.Context) {
+	name := c.Param("name")
+	limit := constants.DefaultTaskRunsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > constants.MaxTaskRunsLimit {
+			respondValidationError(c, fmt.Sprintf("Invalid limit, expected 1 to %d", constants.MaxTaskRunsLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	tasks, err := h.repo.GetTasks(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get scheduled tasks", "error", err)
+		respondError(c, err, "Failed to get scheduled task runs")
+		return
+	}
+	if !hasTask(tasks, name) {
+		respondError(c, apperrors.NotFound("Scheduled task not found"), "")
+		return
+	}
+
+	runs, err := h.repo.GetRuns(c.Request.Context(), name, limit)
+	if err != nil {
+		h.logger.Error("Failed to get scheduled task runs", "task", name, "error", err)
+		respondError(c, err, "Failed to get scheduled task runs")
+		return
+	}
+	if runs == nil {
+		runs = []models.ScheduledTaskRun{}
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// hasTask reports whether a task of the given name is scheduled
+func hasTask(tasks []models.ScheduledTask, name string) bool {
+	for _, task := range tasks {
+		if task.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusHandler serves the public status page
+type StatusHandler struct {
+	statusService *services.StatusService
+}
+
+// NewStatusHandler creates a new status handler
+func NewStatusHandler(statusService *services.StatusService) *StatusHandler {
+	return &StatusHandler{statusService: statusService}
+}
+
+// GetStatus returns the health summary of the status page. It is served without authentication, so that teams can
+// show it to stakeholders, and may be cached by their pages and proxies for as long as the server caches it.
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.statusService.CacheTTL().Seconds())))
+	c.JSON(http.StatusOK, h.statusService.Status(c.Request.Context()))
+}
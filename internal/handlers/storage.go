@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/services"
+	"net/http"
+
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageHandler handles storage statistics HTTP requests
+type StorageHandler struct {
+	storageService *services.StorageService
+	logger         *slog.Logger
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(storageService *services.StorageService, logger *slog.Logger) *StorageHandler {
+	return &StorageHandler{
+		storageService: storageService,
+		logger:         logger,
+	}
+}
+
+// GetStorageStats retrieves storage usage statistics for capacity planning
+func (h *StorageHandler) GetStorageStats(c *gin.Context) {
+	stats, err := h.storageService.GetStorageStats(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get storage stats", "error", err)
+		respondError(c, err, "Failed to get storage stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
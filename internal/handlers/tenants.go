@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"github.com/adeesh/log-analytics/internal/database/tenants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHandler handles tenant administration HTTP requests
+type TenantHandler struct {
+	repo   tenants.TenantRepository
+	logger *slog.Logger
+}
+
+// NewTenantHandler creates a new tenant handler
+func NewTenantHandler(repo tenants.TenantRepository, logger *slog.Logger) *TenantHandler {
+	return &TenantHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateTenant registers a tenant, whose name producers may then send in the tenant header
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var tenant models.Tenant
+	if err := c.ShouldBindJSON(&tenant); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if err := tenant.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	tenant.ID = 0
+	tenant.CreatedAt = time.Now()
+	tenant.UpdatedAt = time.Now()
+	if err := h.repo.CreateTenant(c.Request.Context(), &tenant); err != nil {
+		h.logger.Error("Failed to create tenant", "error", err, "name", tenant.Name)
+		respondError(c, err, "Failed to create tenant")
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenant)
+}
+
+// GetTenants retrieves all tenants by name
+func (h *TenantHandler) GetTenants(c *gin.Context) {
+	tenants, err := h.repo.GetTenants(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get tenants", "error", err)
+		respondError(c, err, "Failed to get tenants")
+		return
+	}
+	if tenants == nil {
+		tenants = []models.Tenant{}
+	}
+
+	c.JSON(http.StatusOK, tenants)
+}
+
+// GetTenantByID retrieves a tenant by ID
+func (h *TenantHandler) GetTenantByID(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := h.repo.GetTenantByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get tenant", "error", err, "id", id)
+		respondError(c, err, "Failed to get tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenant replaces a tenant's description. Tenants can't be renamed, since their data is tagged with the name.
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	existing, err := h.repo.GetTenantByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to get tenant", "error", err, "id", id)
+		respondError(c, err, "Failed to update tenant")
+		return
+	}
+
+	var tenant models.Tenant
+	if err := c.ShouldBindJSON(&tenant); err != nil {
+		respondValidationError(c, "Invalid request body")
+		return
+	}
+	if tenant.Name != "" && tenant.Name != existing.Name {
+		respondValidationError(c, "Tenants can't be renamed")
+		return
+	}
+	tenant.Name = existing.Name
+	if err := tenant.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	tenant.ID = id
+	tenant.UpdatedAt = time.Now()
+	if err := h.repo.UpdateTenant(c.Request.Context(), &tenant); err != nil {
+		h.logger.Error("Failed to update tenant", "error", err, "id", id)
+		respondError(c, err, "Failed to update tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// DeleteTenant deletes a tenant, keeping its data
+func (h *TenantHandler) DeleteTenant(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteTenant(c.Request.Context(), id); err != nil {
+		h.logger.Error("Failed to delete tenant", "error", err, "id", id)
+		respondError(c, err, "Failed to delete tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tenant deleted successfully"})
+}
+
+// tenantID parses the tenant ID path parameter, responding with a validation error when it is invalid
+func tenantID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondValidationError(c, "Invalid tenant ID")
+		return 0, false
+	}
+	return uint(id), true
+}
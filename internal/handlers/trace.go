@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTraces lists the most recent traces, by their last log, with their error and latency rollups. Traces with logs
+// in the last day are listed unless start_time is given; service lists the traces that went through a service, and
+// has_errors=true those with ERROR or FATAL logs.
+func (h *LogHandler) GetTraces(c *gin.Context) {
+	endTime := time.Now()
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			respondValidationError(c, "Invalid end_time, expected RFC3339")
+			return
+		}
+		endTime = t
+	}
+	startTime := endTime.Add(-constants.DefaultTraceListRange)
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			respondValidationError(c, "Invalid start_time, expected RFC3339")
+			return
+		}
+		startTime = t
+	}
+	if !endTime.After(startTime) {
+		respondValidationError(c, "end_time must be after start_time")
+		return
+	}
+
+	limit := constants.DefaultTraceListLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > constants.MaxTraceListLimit {
+			respondValidationError(c, fmt.Sprintf("Limit must be between 1 and %d", constants.MaxTraceListLimit))
+			return
+		}
+		limit = parsed
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < 0 {
+			respondValidationError(c, "Offset must be a non-negative integer")
+			return
+		}
+		offset = parsed
+	}
+
+	errorsOnly, err := boolFilter(c, "has_errors")
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	service := strings.TrimSpace(c.Query("service"))
+	// Fetch one more trace than requested to tell whether another page follows
+	traces, err := h.logRepo.GetTraces(c.Request.Context(), &models.TraceQuery{
+		Service:    service,
+		Tenant:     tenant,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		ErrorsOnly: errorsOnly != nil && *errorsOnly,
+		Limit:      limit + 1,
+		Offset:     offset,
+	})
+	if err != nil {
+		h.logger.Error("Failed to get traces", "error", err, "service", service)
+		respondError(c, err, "Failed to get traces")
+		return
+	}
+
+	hasMore := len(traces) > limit
+	if hasMore {
+		traces = traces[:limit]
+	}
+	if traces == nil {
+		traces = []models.TraceRollup{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"traces":   traces,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": hasMore,
+		"time_range": gin.H{
+			"start_time":       startTime,
+			"end_time":         endTime,
+			"duration_minutes": endTime.Sub(startTime).Minutes(),
+		},
+		"timestamp": time.Now(),
+	})
+}
+
+// GetTrace returns the summary of a trace combining the logs of every service it went through: the services, the
+// time span and duration of the trace and of each service, whether it has errors, and its logs in order
+func (h *LogHandler) GetTrace(c *gin.Context) {
+	traceID := c.Param("traceID")
+	if traceID == "" {
+		respondValidationError(c, "Trace ID is required")
+		return
+	}
+
+	tenant, err := tenantScope(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	logs, err := h.logRepo.GetLogsByTraceID(c.Request.Context(), traceID, tenant)
+	if err != nil {
+		h.logger.Error("Failed to get logs by trace ID", "error", err, "trace_id", traceID)
+		respondError(c, err, "Failed to retrieve trace")
+		return
+	}
+	if len(logs) == 0 {
+		respondError(c, apperrors.NotFound("Trace not found"), "")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewTraceSummary(traceID, logs))
+}
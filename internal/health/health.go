@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Check reports why a dependency isn't healthy, nil when it is
+type Check func(ctx context.Context) error
+
+// Live answers a liveness probe. A process able to answer is alive; dependencies are left to readiness, so that an
+// outage of Kafka or the database takes instances out of rotation rather than restarting them all.
+func Live() *models.ProbeReport {
+	return &models.ProbeReport{Status: constants.ProbeStatusAlive, Timestamp: time.Now()}
+}
+
+// Ready runs the checks concurrently, each within the probe check timeout, and reports whether all of them passed
+func Ready(ctx context.Context, checks map[string]Check) (*models.ProbeReport, bool) {
+	ctx, cancel := context.WithTimeout(ctx, constants.ProbeCheckTimeout)
+	defer cancel()
+
+	report := &models.ProbeReport{
+		Status:    constants.ProbeStatusReady,
+		Checks:    make(map[string]string, len(checks)),
+		Timestamp: time.Now(),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome := constants.ProbeCheckOK
+			if err := run(ctx, check); err != nil {
+				outcome = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = outcome
+			if outcome != constants.ProbeCheckOK {
+				report.Status = constants.ProbeStatusNotReady
+			}
+		}()
+	}
+	wg.Wait()
+	return report, report.Status == constants.ProbeStatusReady
+}
+
+// run runs a check, giving up once the context is done for checks that don't honor it
+func run(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
+
+// Kafka returns a check refreshing the metadata of the given topics, which fails while no broker answers
+func Kafka(client sarama.Client, topics ...string) Check {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return errors.New("no kafka client")
+		}
+		if client.Closed() {
+			return errors.New("kafka client closed")
+		}
+		if err := client.RefreshMetadata(topics...); err != nil {
+			return fmt.Errorf("kafka unreachable: %w", err)
+		}
+		return nil
+	}
+}
+
+// Serve serves the liveness and readiness probes on the given port until the context is cancelled.
+// It is used by services that don't otherwise serve HTTP.
+func Serve(ctx context.Context, port string, checks map[string]Check, logger *slog.Logger) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(constants.LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Live())
+	})
+	mux.HandleFunc(constants.ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+		report, ready := Ready(r.Context(), checks)
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      mux,
+		ReadTimeout:  constants.DefaultServerReadTimeout,
+		WriteTimeout: constants.DefaultServerWriteTimeout,
+		IdleTimeout:  constants.DefaultServerIdleTimeout,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		logger.Info("Starting health server", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return fmt.Errorf("failed to start health server: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.HealthShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down health server: %w", err)
+	}
+	logger.Info("Health server stopped")
+	return nil
+}
+
+// writeReport writes a probe report as JSON
+func writeReport(w http.ResponseWriter, status int, report *models.ProbeReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
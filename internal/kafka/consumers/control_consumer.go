@@ -0,0 +1,222 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// controlConsumer follows the compacted control topic of dynamic pipeline settings. Every processor reads all of
+// its partitions from the oldest offset without a consumer group, so each one rebuilds the latest settings of
+// every service on start and applies changes as they are published.
+type controlConsumer struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	topic    string
+	kafka    *config.KafkaConfig
+	mu       sync.RWMutex
+	settings map[string]*models.PipelineSettings // by service, or by the default key
+	ready    chan struct{}                       // closed once the settings published before the start are applied
+	logger   *slog.Logger
+}
+
+// newControlConsumer creates a control consumer, creating the compacted control topic when it doesn't exist
+func newControlConsumer(cfg *config.KafkaConfig, logger *slog.Logger) (*controlConsumer, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V3_0_0_0
+	config.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control client: %w", err)
+	}
+	if err := ensureControlTopic(cfg, config, cfg.ControlTopic); err != nil {
+		client.Close()
+		return nil, err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create control consumer: %w", err)
+	}
+
+	return &controlConsumer{
+		client:   client,
+		consumer: consumer,
+		topic:    cfg.ControlTopic,
+		kafka:    cfg,
+		settings: make(map[string]*models.PipelineSettings),
+		ready:    make(chan struct{}),
+		logger:   logger,
+	}, nil
+}
+
+// ensureControlTopic creates the control topic with log compaction, so that the latest settings of every key
+// are kept forever, unless it already exists
+func ensureControlTopic(cfg *config.KafkaConfig, config *sarama.Config, topic string) error {
+	admin, err := sarama.NewClusterAdmin(cfg.Brokers, config)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster admin: %w", err)
+	}
+	defer admin.Close()
+
+	cleanupPolicy := "compact"
+	err = admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     1,
+		ReplicationFactor: -1, // the broker default
+		ConfigEntries:     map[string]*string{"cleanup.policy": &cleanupPolicy},
+	}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("failed to create control topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// forService returns the settings applying to a service: its own or else the default settings, nil when neither
+// was published
+func (c *controlConsumer) forService(service string) *models.PipelineSettings {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if settings, ok := c.settings[service]; ok {
+		return settings
+	}
+	return c.settings[constants.ControlSettingsDefaultKey]
+}
+
+// apply records the settings published under a key; a tombstone, a message without a value, removes them.
+// Settings forwarding to a topic the processor consumes are rejected, since forwarded messages would loop.
+func (c *controlConsumer) apply(key string, value []byte) error {
+	if key == "" {
+		return errors.New("settings without a key")
+	}
+	if value == nil {
+		c.mu.Lock()
+		delete(c.settings, key)
+		c.mu.Unlock()
+		c.logger.Info("Pipeline settings removed", "key", key)
+		return nil
+	}
+
+	var settings models.PipelineSettings
+	if err := json.Unmarshal(value, &settings); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if topic := settings.ForwardTopic; topic != "" && (c.kafka.Consumes(topic) || topic == c.kafka.DeadLetterTopic ||
+		topic == c.kafka.StreamTopic || topic == c.kafka.ControlTopic) {
+		return fmt.Errorf("forward topic %q must differ from the log, priority, dead-letter, stream, control and tenant topics", topic)
+	}
+
+	c.mu.Lock()
+	c.settings[key] = &settings
+	c.mu.Unlock()
+	c.logger.Info("Pipeline settings applied", "key", key, "settings", string(value))
+	return nil
+}
+
+// Start consumes the control topic until the context is cancelled
+func (c *controlConsumer) Start(ctx context.Context) {
+	var partitions []int32
+	for {
+		var err error
+		partitions, err = c.consumer.Partitions(c.topic)
+		if err == nil {
+			break
+		}
+		c.logger.Warn("Control topic not available yet", "topic", c.topic, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.ControlConsumerRetryInterval):
+		}
+	}
+
+	var wg, replayed sync.WaitGroup
+	for _, partition := range partitions {
+		// Settings up to the current end of the partition were published before the start
+		end, err := c.client.GetOffset(c.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			c.logger.Error("Failed to get control partition offset", "error", err, "partition", partition)
+			continue
+		}
+		pc, err := c.consumer.ConsumePartition(c.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			c.logger.Error("Failed to consume control partition", "error", err, "partition", partition)
+			continue
+		}
+		wg.Add(1)
+		replayed.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consumePartition(ctx, pc, end, replayed.Done)
+		}()
+	}
+	go func() {
+		replayed.Wait()
+		close(c.ready)
+	}()
+	c.logger.Info("Control consumer started", "topic", c.topic, "partitions", len(partitions))
+
+	wg.Wait()
+}
+
+// consumePartition applies the settings of a partition until the context is cancelled, calling replayed once
+// the settings before the given end offset are applied
+func (c *controlConsumer) consumePartition(ctx context.Context, pc sarama.PartitionConsumer, end int64, replayed func()) {
+	defer pc.AsyncClose()
+	var once sync.Once
+	defer once.Do(replayed)
+	if end <= 0 {
+		once.Do(replayed)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return
+			}
+			c.logger.Warn("Control consumer error", "error", err.Err, "partition", err.Partition)
+		case message, ok := <-pc.Messages():
+			if !ok {
+				return
+			}
+			if err := c.apply(string(message.Key), message.Value); err != nil {
+				c.logger.Error("Ignoring pipeline settings", "error", err, "key", string(message.Key), "offset", message.Offset)
+			}
+			if message.Offset >= end-1 {
+				once.Do(replayed)
+			}
+		}
+	}
+}
+
+// Ready returns a channel closed once the settings published before the start are applied
+func (c *controlConsumer) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Close closes the consumer
+func (c *controlConsumer) Close() error {
+	if err := c.consumer.Close(); err != nil {
+		c.logger.Error("Failed to close control consumer", "error", err)
+	}
+	return c.client.Close()
+}
@@ -0,0 +1,94 @@
+package consumers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// dedupCache remembers the message IDs of the most recently stored logs, so that redelivered logs are usually
+// skipped without asking the database. The oldest ID is forgotten once the cache is full.
+type dedupCache struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string // ring of the remembered IDs, oldest at next
+	next  int
+}
+
+func newDedupCache(size int) *dedupCache {
+	return &dedupCache{ids: make(map[string]struct{}, size), order: make([]string, 0, size)}
+}
+
+// filter returns the logs whose message ID wasn't stored recently, dropping repeats within the logs as well
+func (c *dedupCache) filter(logs []*models.Log) []*models.Log {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := make([]*models.Log, 0, len(logs))
+	seen := make(map[string]struct{}, len(logs))
+	for _, log := range logs {
+		if log.MessageID != nil {
+			if _, ok := c.ids[*log.MessageID]; ok {
+				continue
+			}
+			if _, ok := seen[*log.MessageID]; ok {
+				continue
+			}
+			seen[*log.MessageID] = struct{}{}
+		}
+		kept = append(kept, log)
+	}
+	return kept
+}
+
+// add remembers the message IDs of stored logs
+func (c *dedupCache) add(logs []*models.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, log := range logs {
+		if log.MessageID == nil {
+			continue
+		}
+		if _, ok := c.ids[*log.MessageID]; ok {
+			continue
+		}
+		c.ids[*log.MessageID] = struct{}{}
+		if len(c.order) < cap(c.order) {
+			c.order = append(c.order, *log.MessageID)
+			continue
+		}
+		delete(c.ids, c.order[c.next])
+		c.order[c.next] = *log.MessageID
+		c.next = (c.next + 1) % len(c.order)
+	}
+}
+
+// assignMessageID gives a log without a producer-supplied message ID one derived from its tenant, service,
+// timestamp and message, so that every delivery of the log gets the same ID. Logs without a timestamp are identified by their
+// position in Kafka instead, which is the same for redeliveries of the message but not for messages produced twice.
+func assignMessageID(message *sarama.ConsumerMessage, log *models.Log) {
+	if log.MessageID != nil && *log.MessageID != "" && len(*log.MessageID) <= constants.MaxMessageIDLength {
+		return
+	}
+
+	var key string
+	if log.Timestamp.IsZero() {
+		key = fmt.Sprintf("%s\x00%d\x00%d", message.Topic, message.Partition, message.Offset)
+	} else {
+		var tenant string
+		if log.Tenant != nil {
+			tenant = *log.Tenant
+		}
+		key = tenant + "\x00" + log.Service + "\x00" + log.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + log.Message
+	}
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:])[:constants.MessageIDHashLength]
+	log.MessageID = &id
+}
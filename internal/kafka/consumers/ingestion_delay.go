@@ -0,0 +1,133 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ingestionDelays tracks how late the logs of each service are stored, measured from their timestamp. Once per
+// window the services are checked: the ingestion delay alert fires for a service when most of its logs stored in
+// the window were later than the threshold, and resolves once most of them are on time again.
+type ingestionDelays struct {
+	threshold     time.Duration // 0 when the alert is disabled
+	window        time.Duration
+	notifications *services.NotificationService // nil when the alert is disabled
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	windows map[string]*delayWindow // service -> delays of its logs stored in the current window
+	firing  map[string]bool         // services whose alert is firing
+}
+
+// delayWindow sums up the delays of the logs of a service stored in the current window
+type delayWindow struct {
+	logs int
+	late int
+	max  time.Duration
+}
+
+// newIngestionDelays creates a tracker alerting on services whose logs are mostly later than the threshold
+func newIngestionDelays(threshold, window time.Duration, notifications *services.NotificationService, logger *slog.Logger) *ingestionDelays {
+	return &ingestionDelays{
+		threshold:     threshold,
+		window:        window,
+		notifications: notifications,
+		logger:        logger,
+		windows:       make(map[string]*delayWindow),
+		firing:        make(map[string]bool),
+	}
+}
+
+// record observes the delays of logs stored at the given time. Logs timestamped in the future count as on time.
+func (d *ingestionDelays) record(logs []*models.Log, storedAt time.Time) {
+	for _, log := range logs {
+		metrics.IngestionDelay.WithLabelValues(log.Service).Observe(max(storedAt.Sub(log.Timestamp), 0).Seconds())
+	}
+	if d.threshold == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, log := range logs {
+		window, ok := d.windows[log.Service]
+		if !ok {
+			window = &delayWindow{}
+			d.windows[log.Service] = window
+		}
+		delay := storedAt.Sub(log.Timestamp)
+		window.logs++
+		if delay > d.threshold {
+			window.late++
+		}
+		window.max = max(window.max, delay)
+	}
+}
+
+// Start checks the services once per window until the context is done
+func (d *ingestionDelays) Start(ctx context.Context) {
+	if d.threshold == 0 {
+		return
+	}
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.check(ctx, now)
+		}
+	}
+}
+
+// check fires or resolves the alert of each service that stored enough logs in the window, and starts the next
+// window. Services that stored too few logs, or none, keep their alert as it is.
+func (d *ingestionDelays) check(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	windows := d.windows
+	d.windows = make(map[string]*delayWindow)
+	var fired []*models.AlertNotification
+	for service, window := range windows {
+		if window.logs < constants.IngestionDelayMinLogs {
+			continue
+		}
+		lateShare := float64(window.late) / float64(window.logs) * 100
+		late := lateShare > constants.IngestionDelayLateShare
+		switch {
+		case late && !d.firing[service]:
+			d.firing[service] = true
+			message := fmt.Sprintf("Logs of service %s are arriving late: %d of %d logs stored in the last %s were more than %s late (up to %s)",
+				service, window.late, window.logs, d.window, d.threshold, window.max.Round(time.Second))
+			d.logger.Error("Ingestion delay alert fired", "service", service, "message", message)
+			metrics.IngestionDelayAlert.WithLabelValues(service).Set(1)
+			fired = append(fired, &models.AlertNotification{
+				RuleName:  constants.IngestionDelayAlertName,
+				Severity:  constants.IngestionDelayAlertSeverity,
+				Message:   message,
+				Value:     lateShare,
+				Threshold: constants.IngestionDelayLateShare,
+				CreatedAt: now,
+				Meta:      true,
+			})
+		case !late && d.firing[service]:
+			delete(d.firing, service)
+			d.logger.Info("Ingestion delay alert resolved", "service", service)
+			metrics.IngestionDelayAlert.WithLabelValues(service).Set(0)
+		}
+	}
+	d.mu.Unlock()
+
+	// Notifications are delivered outside the lock, since loading the channels queries the database
+	for _, notification := range fired {
+		d.notifications.NotifyAll(ctx, notification)
+	}
+}
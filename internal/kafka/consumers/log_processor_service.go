@@ -2,17 +2,32 @@ package consumers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/adeesh/log-analytics/internal/avro"
 	"github.com/adeesh/log-analytics/internal/config"
 	"github.com/adeesh/log-analytics/internal/constants"
 	"github.com/adeesh/log-analytics/internal/database"
 	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/maintenance"
+	"github.com/adeesh/log-analytics/internal/database/notifications"
+	"github.com/adeesh/log-analytics/internal/database/tenants"
 	"github.com/adeesh/log-analytics/internal/handlers"
+	"github.com/adeesh/log-analytics/internal/health"
+	"github.com/adeesh/log-analytics/internal/kafka/producers"
+	"github.com/adeesh/log-analytics/internal/kafka/serde"
+	"github.com/adeesh/log-analytics/internal/metrics"
 	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/parsers"
+	"github.com/adeesh/log-analytics/internal/services"
 	"log/slog"
+	"math/rand"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -21,12 +36,74 @@ import (
 
 // LogProcessorService represents the log processing service with integrated batch consumer
 type LogProcessorService struct {
-	consumer     sarama.ConsumerGroup
-	topic        string
-	handler      handlers.LogHandler
-	logger       *slog.Logger
-	batchSize    int
-	batchTimeout time.Duration
+	db              *database.GormDB
+	consumer        sarama.ConsumerGroup
+	topic           string
+	tenants         map[string]string    // tenant topic -> tenant, consumed alongside the log topic
+	registry        *tenantRegistry      // tenants accepted in the tenant header
+	priority        sarama.ConsumerGroup // consumes the priority topic; nil when the priority lane is disabled
+	priorityTopic   string
+	priorityTimeout time.Duration
+	handler         handlers.LogHandler
+	shards          *logs.ShardedLogRepository
+	clickHouse      *logs.ClickHouseLogRepository // nil unless logs are stored in ClickHouse
+	deadLetter      *producers.DeadLetterProducer
+	stream          *producers.LogStreamPublisher // publishes stored logs for the live tail; nil when disabled
+	control         *controlConsumer              // follows the dynamic pipeline settings; nil when disabled
+	forward         *producers.ForwardProducer    // republishes the messages of forwarded services
+	pipeline        config.PipelineConfig
+	deserializer    *serde.Deserializer
+	parsers         *parsers.Pipeline
+	fingerprinter   *parsers.Fingerprinter
+	redactor        *parsers.Redactor
+	maintenance     *services.MaintenanceService
+	enricher        *services.EnrichmentService
+	dedup           *dedupCache // nil when disabled
+	delays          *ingestionDelays
+	notifications   *services.NotificationService // delivers the ingestion delay alert; nil when disabled
+	writeQueue      chan *pendingBatch            // bulk batches awaiting the write workers
+	writers         sync.WaitGroup
+	writeWorkers    int
+	metrics         config.MetricsConfig
+	health          config.HealthConfig
+	kafka           sarama.Client // checked by the readiness probe; nil when the probes are disabled
+	inSession       atomic.Bool   // whether the consumer group has a session, between Setup and Cleanup
+	priorityActive  atomic.Bool   // likewise for the priority lane
+	logger          *slog.Logger
+	batchSize       atomic.Int64 // changed by configuration reloads, like the batch timeout
+	batchTimeout    atomic.Int64
+	storeRetries    int
+	retryBackoff    time.Duration
+	breaker         *storeBreaker
+
+	// Closed on shutdown, once drainCtx is set: claims then store their batches in flight within the drain timeout
+	draining     chan struct{}
+	drainCtx     context.Context
+	cancelDrain  context.CancelFunc
+	drainTimeout time.Duration
+}
+
+// priorityLane consumes the priority topic of ERROR and FATAL logs. Its batches are flushed after a
+// shorter timeout and stored directly rather than queued behind bulk batches awaiting the write workers.
+type priorityLane struct {
+	*LogProcessorService
+}
+
+// Setup implements sarama.ConsumerGroupHandler for the priority topic
+func (l priorityLane) Setup(sarama.ConsumerGroupSession) error {
+	l.priorityActive.Store(true)
+	return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler for the priority topic
+func (l priorityLane) Cleanup(sarama.ConsumerGroupSession) error {
+	l.priorityActive.Store(false)
+	return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler for the priority topic
+func (l priorityLane) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	return l.consumeClaim(session, claim, true)
 }
 
 // NewLogProcessorService creates a new log processor service
@@ -37,11 +114,166 @@ func NewLogProcessorService(cfg *config.Config, logger *slog.Logger) (*LogProces
 		return nil, err
 	}
 
-	// Create log repository
+	if err := cfg.Pipeline.Validate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid pipeline configuration: %w", err)
+	}
+	if err := cfg.Kafka.Validate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid kafka configuration: %w", err)
+	}
+
+	// Build the parsers decoding messages that aren't in the native log JSON shape
+	parserConfigs, err := cfg.Pipeline.LoadParsers()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	parserPipeline, err := parsers.NewPipeline(parserConfigs)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid parser configuration: %w", err)
+	}
+	if len(parserConfigs) > 0 {
+		logger.Info("Log parsers enabled", "parsers", len(parserConfigs), "file", cfg.Pipeline.ParsersFile)
+	}
+
+	// Build the fingerprinter grouping error logs
+	fingerprintMasks, err := cfg.Pipeline.LoadFingerprintMasks()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	fingerprinter, err := parsers.NewFingerprinter(fingerprintMasks)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid fingerprint rules: %w", err)
+	}
+	logger.Info("Error fingerprinting enabled", "masks", len(fingerprintMasks), "rules_version", fingerprinter.Version())
+
+	// Build the redactor masking sensitive data before logs are stored
+	redactionRules, err := cfg.Pipeline.LoadRedactionRules()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	redactor, err := parsers.NewRedactor(cfg.Pipeline.RedactBuiltins, redactionRules)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid redaction rules: %w", err)
+	}
+	if redactor.Enabled() {
+		logger.Info("Redaction enabled", "rules", redactor.Rules(), "builtins", cfg.Pipeline.RedactBuiltins, "file", cfg.Pipeline.RedactionFile)
+	}
+
+	// Create log repository in the configured store, routing configured services to their dedicated shards
+	if err := cfg.LogStore.Validate(&cfg.Routing); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid log store configuration: %w", err)
+	}
 	logRepo := logs.NewLogRepository(db)
+	var clickHouse *logs.ClickHouseLogRepository
+	if cfg.LogStore.Backend == constants.LogStoreClickHouse {
+		clickHouse, err = logs.NewClickHouseLogRepository(context.Background(), &cfg.LogStore.ClickHouse)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		logRepo = clickHouse
+		logger.Info("Storing logs in ClickHouse", "url", cfg.LogStore.ClickHouse.URL, "database", cfg.LogStore.ClickHouse.Database)
+	}
+	var shards *logs.ShardedLogRepository
+	if len(cfg.Routing.Rules) > 0 {
+		shards, err = logs.NewShardedLogRepository(context.Background(), db, &cfg.Database, &cfg.Routing)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		logRepo = shards
+		logger.Info("Log routing enabled", "rules", len(cfg.Routing.Rules))
+	}
+
+	// Writes pause while the shared maintenance switch is in read-only mode
+	maintenanceService := services.NewMaintenanceService(maintenance.NewMaintenanceRepository(db.GetDB()), cfg.Maintenance, logger)
+	if err := maintenanceService.Refresh(context.Background()); err != nil {
+		logger.Warn("Failed to load maintenance state", "error", err)
+	}
+
+	// Services whose logs arrive later than the threshold fire the built-in ingestion delay alert
+	var notificationService *services.NotificationService
+	if cfg.Pipeline.IngestionDelayThreshold > 0 {
+		notificationService, err = services.NewNotificationService(notifications.NewNotificationRepository(db.GetDB()), cfg.Notification, logger)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		logger.Info("Ingestion delay alert enabled", "threshold", cfg.Pipeline.IngestionDelayThreshold, "window", cfg.Pipeline.IngestionDelayWindow)
+	}
 
 	// Create log handlers using the handlers package
-	logHandler := handlers.NewLogHandler(logRepo, logger)
+	logHandler := handlers.NewLogHandler(logRepo, nil, logger)
+
+	// Create enrichment service if external lookups are configured
+	var enricher *services.EnrichmentService
+	if cfg.Enrichment.Enabled && len(cfg.Enrichment.Endpoints) > 0 {
+		if err := cfg.Enrichment.Validate(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("invalid enrichment configuration: %w", err)
+		}
+		enricher = services.NewEnrichmentService(&cfg.Enrichment, logger)
+		logger.Info("Log enrichment enabled", "endpoints", len(cfg.Enrichment.Endpoints))
+	}
+
+	// Each write worker has a batch waiting for it, unless enrichment sizes the queue to absorb slow lookups
+	queueSize := cfg.Pipeline.WriteWorkers
+	if enricher != nil {
+		queueSize = max(cfg.Enrichment.QueueSize, 1)
+	}
+
+	// Remember the message IDs of recently stored logs so that redeliveries rarely reach the database
+	var dedup *dedupCache
+	if cfg.Kafka.DedupCacheSize > 0 {
+		dedup = newDedupCache(cfg.Kafka.DedupCacheSize)
+	}
+
+	// Create dead-letter producer for messages that cannot be parsed
+	deadLetter, err := producers.NewDeadLetterProducer(&cfg.Kafka, logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Stored logs are published back to the stream topic followed by the API server's live tail
+	var stream *producers.LogStreamPublisher
+	if cfg.Kafka.StreamTopic != "" {
+		stream, err = producers.NewLogStreamPublisher(&cfg.Kafka, logger)
+		if err != nil {
+			deadLetter.Close()
+			db.Close()
+			return nil, err
+		}
+		logger.Info("Live tail publishing enabled", "topic", cfg.Kafka.StreamTopic)
+	}
+
+	// Dynamic pipeline settings are consumed from the control topic and applied without a restart
+	var control *controlConsumer
+	var forward *producers.ForwardProducer
+	if cfg.Kafka.ControlTopic != "" {
+		control, err = newControlConsumer(&cfg.Kafka, logger)
+		if err == nil {
+			forward, err = producers.NewForwardProducer(&cfg.Kafka, logger)
+			if err != nil {
+				control.Close()
+			}
+		}
+		if err != nil {
+			closeStream(stream)
+			deadLetter.Close()
+			db.Close()
+			return nil, err
+		}
+		logger.Info("Dynamic pipeline settings enabled", "topic", cfg.Kafka.ControlTopic)
+	}
 
 	// Create Kafka consumer configuration
 	config := sarama.NewConfig()
@@ -69,6 +301,9 @@ func NewLogProcessorService(cfg *config.Config, logger *slog.Logger) (*LogProces
 	logger.Info("Creating consumer group", "group_id", cfg.Kafka.GroupID, "brokers", cfg.Kafka.Brokers)
 	consumer, err := sarama.NewConsumerGroup(cfg.Kafka.Brokers, cfg.Kafka.GroupID, config)
 	if err != nil {
+		closeControl(control, forward)
+		closeStream(stream)
+		deadLetter.Close()
 		db.Close()
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
@@ -76,12 +311,50 @@ func NewLogProcessorService(cfg *config.Config, logger *slog.Logger) (*LogProces
 	// Test connection and get metadata
 	logger.Info("Consumer group created successfully", "group_id", cfg.Kafka.GroupID)
 
-	// Create a test client to verify topic exists
+	// The priority lane gets its own consumer group so it keeps up while the main topic is backlogged
+	var priority sarama.ConsumerGroup
+	if cfg.Kafka.PriorityTopic != "" {
+		priority, err = sarama.NewConsumerGroup(cfg.Kafka.Brokers, cfg.Kafka.PriorityGroupID(), config)
+		if err != nil {
+			consumer.Close()
+			closeControl(control, forward)
+			closeStream(stream)
+			deadLetter.Close()
+			db.Close()
+			return nil, fmt.Errorf("failed to create priority consumer: %w", err)
+		}
+		logger.Info("Priority lane enabled",
+			"topic", cfg.Kafka.PriorityTopic,
+			"group_id", cfg.Kafka.PriorityGroupID(),
+			"batch_timeout", cfg.Kafka.PriorityBatchTimeout)
+	}
+
+	// Tenant topics are consumed by the main consumer group alongside the log topic
+	topicTenants := make(map[string]string, len(cfg.Kafka.TenantTopics))
+	for _, mapping := range cfg.Kafka.TenantTopics {
+		topicTenants[mapping.Topic] = mapping.Tenant
+	}
+	if len(topicTenants) > 0 {
+		logger.Info("Tenant topics enabled", "tenants", len(topicTenants))
+	}
+
+	// Other tenants name themselves in the tenant header, which is accepted for registered tenants only
+	registry := newTenantRegistry(tenants.NewTenantRepository(db.GetDB()), logger)
+	if err := registry.refresh(context.Background()); err != nil {
+		logger.Warn("Failed to load tenants", "error", err)
+	}
+
+	// Create a test client to verify topic exists, kept for the readiness probe when enabled
+	var kafkaClient sarama.Client
 	testClient, err := sarama.NewClient(cfg.Kafka.Brokers, config)
 	if err != nil {
 		logger.Warn("Failed to create test client", "error", err)
 	} else {
-		defer testClient.Close()
+		if cfg.Health.Enabled {
+			kafkaClient = testClient
+		} else {
+			defer testClient.Close()
+		}
 
 		// Get topic metadata
 		topics, err := testClient.Topics()
@@ -100,21 +373,71 @@ func NewLogProcessorService(cfg *config.Config, logger *slog.Logger) (*LogProces
 		}
 	}
 
-	return &LogProcessorService{
-		consumer:     consumer,
-		topic:        cfg.Kafka.Topic,
-		handler:      *logHandler,
-		logger:       logger,
-		batchSize:    constants.DefaultBatchSize,
-		batchTimeout: constants.DefaultBatchTimeout,
-	}, nil
+	service := &LogProcessorService{
+		db:              db,
+		consumer:        consumer,
+		topic:           cfg.Kafka.Topic,
+		tenants:         topicTenants,
+		registry:        registry,
+		priority:        priority,
+		priorityTopic:   cfg.Kafka.PriorityTopic,
+		priorityTimeout: cfg.Kafka.PriorityBatchTimeout,
+		handler:         *logHandler,
+		shards:          shards,
+		clickHouse:      clickHouse,
+		deadLetter:      deadLetter,
+		stream:          stream,
+		control:         control,
+		forward:         forward,
+		pipeline:        cfg.Pipeline,
+		deserializer:    serde.NewDeserializer(&cfg.Kafka),
+		parsers:         parserPipeline,
+		fingerprinter:   fingerprinter,
+		redactor:        redactor,
+		maintenance:     maintenanceService,
+		enricher:        enricher,
+		dedup:           dedup,
+		delays:          newIngestionDelays(cfg.Pipeline.IngestionDelayThreshold, cfg.Pipeline.IngestionDelayWindow, notificationService, logger),
+		notifications:   notificationService,
+		writeQueue:      make(chan *pendingBatch, queueSize),
+		writeWorkers:    cfg.Pipeline.WriteWorkers,
+		metrics:         cfg.Metrics,
+		health:          cfg.Health,
+		kafka:           kafkaClient,
+		logger:          logger,
+		storeRetries:    cfg.Kafka.StoreRetries,
+		retryBackoff:    cfg.Kafka.StoreRetryBackoff,
+		draining:        make(chan struct{}),
+		drainTimeout:    cfg.Pipeline.DrainTimeout,
+	}
+	service.batchSize.Store(int64(cfg.Pipeline.BatchSize))
+	service.batchTimeout.Store(int64(cfg.Pipeline.BatchTimeout))
+	service.breaker = newStoreBreaker(service.pingStore, service.pauseConsumption, service.resumeConsumption,
+		cfg.Kafka.StoreBreaker, cfg.Kafka.StoreBreakerProbe, logger)
+	return service, nil
+}
+
+// Reload applies the batch size and timeout of a reloaded configuration to the claims being consumed, which
+// pick them up with their next batch
+func (s *LogProcessorService) Reload(cfg *config.Config) {
+	s.batchSize.Store(int64(cfg.Pipeline.BatchSize))
+	s.batchTimeout.Store(int64(cfg.Pipeline.BatchTimeout))
 }
 
-// Start starts the log processor service
+// batchTimeoutFor returns the timeout after which batches of the bulk or the priority lane are flushed
+func (s *LogProcessorService) batchTimeoutFor(priority bool) time.Duration {
+	if priority {
+		return s.priorityTimeout
+	}
+	return time.Duration(s.batchTimeout.Load())
+}
+
+// Start starts the log processor service. It returns nil once a shutdown signal drained the batches in flight.
 func (s *LogProcessorService) Start(ctx context.Context) error {
 	s.logger.Info("Log processor service started",
-		"batch_size", s.batchSize,
-		"batch_timeout", s.batchTimeout)
+		"batch_size", s.batchSize.Load(),
+		"batch_timeout", s.batchTimeoutFor(false),
+		"write_workers", s.writeWorkers)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(ctx)
@@ -123,15 +446,85 @@ func (s *LogProcessorService) Start(ctx context.Context) error {
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
+	// Ending the sessions stops intake; the claims then store the batches they hold and commit their offsets
+	// before the sessions are released, as long as the drain timeout allows
 	go func() {
-		<-sigChan
-		s.logger.Info("Shutdown signal received")
+		select {
+		case <-sigChan:
+		case <-ctx.Done():
+			return
+		}
+		s.logger.Info("Shutdown signal received, draining batches in flight", "timeout", s.drainTimeout)
+		s.drainCtx, s.cancelDrain = context.WithTimeout(context.Background(), s.drainTimeout)
+		close(s.draining)
 		cancel()
 	}()
 
-	// Start consuming messages
+	go s.maintenance.Start(ctx)
+	go s.registry.Start(ctx)
+	go s.delays.Start(ctx)
+
+	// Expose pipeline metrics for scraping
+	if s.metrics.Enabled {
+		go func() {
+			if err := metrics.Serve(ctx, s.metrics.ProcessorPort, s.logger); err != nil {
+				s.logger.Error("Metrics server error", "error", err)
+			}
+		}()
+	}
+
+	// Answer liveness and readiness probes, ready while the database and Kafka are reachable and the consumer
+	// groups have a session
+	if s.health.Enabled {
+		go func() {
+			if err := health.Serve(ctx, s.health.ProcessorPort, s.readinessChecks(), s.logger); err != nil {
+				s.logger.Error("Health server error", "error", err)
+			}
+		}()
+	}
+
+	// Enrich and store bulk batches in a pool of write workers, so that slow lookups and inserts don't block
+	// consumption. The workers outlive the consumer context so batches queued during shutdown are still stored.
+	s.writers.Add(s.writeWorkers)
+	for range s.writeWorkers {
+		go s.runWriteWorker(context.WithoutCancel(ctx))
+	}
+	defer s.stopWriteWorkers()
+
+	// Consume the priority topic alongside the main topic. It is stopped and waited for before the
+	// write workers shut down, since its batches use the enricher directly.
+	if s.priority != nil {
+		priorityDone := make(chan struct{})
+		go func() {
+			defer close(priorityDone)
+			s.consumePriorityLane(ctx)
+			cancel()
+		}()
+		defer func() {
+			cancel()
+			<-priorityDone
+		}()
+	}
+
+	// Settings published before the start are applied before any log is consumed, unless replaying them stalls
+	if s.control != nil {
+		go s.control.Start(ctx)
+		select {
+		case <-s.control.Ready():
+		case <-time.After(constants.ControlReplayTimeout):
+			s.logger.Warn("Timed out applying pipeline settings, consuming logs meanwhile", "timeout", constants.ControlReplayTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Start consuming messages, fanning in the tenant topics
 	topics := []string{s.topic}
+	for topic := range s.tenants {
+		topics = append(topics, topic)
+	}
 	for {
 		err := s.consumer.Consume(ctx, topics, s)
 		if err != nil {
@@ -140,28 +533,228 @@ func (s *LogProcessorService) Start(ctx context.Context) error {
 		}
 
 		if ctx.Err() != nil {
+			if _, draining := s.drainContext(); draining {
+				s.logger.Info("Log processor drained")
+				return nil
+			}
 			return ctx.Err()
 		}
 	}
 }
 
+// readinessChecks returns the checks of the readiness probe
+func (s *LogProcessorService) readinessChecks() map[string]health.Check {
+	topics := []string{s.topic}
+	if s.priority != nil {
+		topics = append(topics, s.priorityTopic)
+	}
+	return map[string]health.Check{
+		constants.ProbeCheckDatabase: s.pingStore,
+		constants.ProbeCheckKafka:    health.Kafka(s.kafka, topics...),
+		constants.ProbeCheckConsumer: func(context.Context) error {
+			if !s.inSession.Load() {
+				return errors.New("consumer group has no session")
+			}
+			if s.priority != nil && !s.priorityActive.Load() {
+				return errors.New("priority consumer group has no session")
+			}
+			return nil
+		},
+	}
+}
+
+// drainContext returns the context bounding the drain by its timeout, and whether the processor is shutting down
+func (s *LogProcessorService) drainContext() (context.Context, bool) {
+	select {
+	case <-s.draining:
+		return s.drainCtx, true
+	default:
+		return nil, false
+	}
+}
+
+// consumePriorityLane consumes the priority topic until the context is done or consumption fails
+func (s *LogProcessorService) consumePriorityLane(ctx context.Context) {
+	topics := []string{s.priorityTopic}
+	for ctx.Err() == nil {
+		if err := s.priority.Consume(ctx, topics, priorityLane{s}); err != nil {
+			s.logger.Error("Error from priority consumer", "error", err)
+			return
+		}
+	}
+}
+
 // ConsumeClaim implements sarama.ConsumerGroupHandler for batch processing
 func (s *LogProcessorService) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	batch := make([]*models.Log, 0, s.batchSize)
-	timer := time.NewTimer(s.batchTimeout)
+	return s.consumeClaim(session, claim, false)
+}
+
+// consumeClaim batches the claim's messages, flushing when a batch is full or the timeout elapses.
+// Priority batches bypass the write queue. A claim covers a single partition, so batches of a
+// tenant topic only ever hold that tenant's logs.
+func (s *LogProcessorService) consumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim, priority bool) error {
+	// The tenant is taken from the topic, or else from the tenant header naming a registered tenant; tenants
+	// claimed in message bodies are not trusted
+	var tenant *string
+	if name, ok := s.tenants[claim.Topic()]; ok {
+		tenant = &name
+	}
+
+	// Partitions claimed while the log store is unreachable start out paused
+	s.breaker.pauseIfOpen()
+
+	batch := newPendingBatch(session, priority)
+	var queued *pendingBatch // the last batch queued for the write workers
+	timer := time.NewTimer(s.batchTimeoutFor(priority))
 	defer timer.Stop()
 
+	// flush hands the batch over to be stored and starts a new one. A batch that can't be stored means the
+	// session ended, so consumption stops rather than marking the offsets of later messages.
+	flush := func(ctx context.Context) error {
+		if batch.last == nil {
+			return nil
+		}
+		if !priority {
+			batch.previous = queued
+		}
+		err := s.processBatch(ctx, batch)
+		if err != nil {
+			s.logger.Error("Failed to process batch, leaving its messages for redelivery", "error", err,
+				"batch_size", len(batch.logs), "topic", claim.Topic(), "partition", claim.Partition())
+		} else if !priority {
+			queued = batch
+		}
+		batch = newPendingBatch(session, priority)
+		return err
+	}
+
+	// end stores the remaining batch once the session ends. On shutdown it is stored within the drain timeout,
+	// after which the batches queued for the write workers are waited for and the offsets committed. Otherwise the
+	// session ended for a rebalance: offsets marked before the claim returns are still committed, so the batch is
+	// stored once more; it isn't retried, its messages are redelivered to the partition's next owner instead.
+	end := func() {
+		drainCtx, draining := s.drainContext()
+		if !draining {
+			flush(context.WithoutCancel(session.Context()))
+			return
+		}
+		pending := len(batch.logs)
+		batch.ctx = drainCtx
+		flush(drainCtx)
+		if queued != nil {
+			select {
+			case <-queued.done:
+			case <-drainCtx.Done():
+				s.logger.Warn("Drain timed out before the write workers stored the partition's batches, leaving them for redelivery",
+					"topic", claim.Topic(), "partition", claim.Partition())
+			}
+		}
+		session.Commit()
+		s.logger.Info("Drained partition", "topic", claim.Topic(), "partition", claim.Partition(), "batch_size", pending)
+	}
+
+	// deadLetter dead-letters a message that can't be stored. A message that could be neither stored nor
+	// dead-lettered ends the claim, leaving it and the messages after it for redelivery.
+	deadLetter := func(message, previous *sarama.ConsumerMessage, cause error) bool {
+		if err := s.deadLetterMessage(session.Context(), message, cause); err != nil {
+			s.logger.Error("Failed to dead-letter message, leaving it for redelivery", "error", err, "partition", message.Partition, "offset", message.Offset)
+			batch.last = previous
+			end()
+			return false
+		}
+		return true
+	}
+
 	for {
+		// Stop pulling messages while writes are paused so they stay in Kafka until maintenance ends
+		if err := s.maintenance.WaitWritable(session.Context()); err != nil {
+			s.logger.Info("Session ended while paused for maintenance", "partition", claim.Partition())
+		}
+		// Messages already fetched are left for redelivery once the session ended
+		if session.Context().Err() != nil {
+			end()
+			return nil
+		}
+
 		select {
 		case message := <-claim.Messages():
-			var log models.Log
-			if err := json.Unmarshal(message.Value, &log); err != nil {
-				s.logger.Error("Failed to unmarshal log", "error", err)
-				session.MarkMessage(message, "")
+			metrics.LogsConsumed.WithLabelValues(message.Topic).Inc()
+			metrics.KafkaLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).
+				Set(float64(max(claim.HighWaterMarkOffset()-message.Offset-1, 0)))
+			headers := messageHeaders(message)
+
+			// Messages that aren't stored are marked along with the batch, since marking a message
+			// commits the offsets of the batch's earlier messages as well
+			previous := batch.last
+			batch.last = message
+
+			// Skip messages excluded by the pipeline's header filters
+			if !s.matchesHeaderFilters(headers) {
+				continue
+			}
+
+			// Avro messages are decoded into the log JSON before parsing. The session ended while the schema
+			// registry was unavailable, so the message is left for redelivery rather than dead-lettered.
+			value, err := s.deserialize(session.Context(), message)
+			if errors.Is(err, avro.ErrRegistryUnavailable) {
+				s.logger.Warn("Session ended while the schema registry was unavailable, leaving the message for redelivery", "error", err, "partition", message.Partition, "offset", message.Offset)
+				batch.last = previous
+				end()
+				return nil
+			}
+			var log *models.Log
+			if err == nil {
+				log, err = s.parsers.Parse(value, headers)
+			}
+			if err != nil {
+				s.logger.Error("Failed to parse log", "error", err, "partition", message.Partition, "offset", message.Offset)
+				if !deadLetter(message, previous, err) {
+					return nil
+				}
+				continue
+			}
+
+			// Keep the trace context producers sent in headers only
+			serde.ApplyTraceHeaders(log, headers)
+
+			// Drop response statuses outside the HTTP range rather than storing bogus status classes
+			if log.ResponseStatus != nil && (*log.ResponseStatus < constants.MinHTTPStatus || *log.ResponseStatus > constants.MaxHTTPStatus) {
+				s.logger.Warn("Discarding invalid response status", "status", *log.ResponseStatus, "service", log.Service)
+				log.ResponseStatus = nil
+			}
+
+			log.Tenant = tenant
+			if name, ok := headers[constants.HeaderTenant]; ok && tenant == nil {
+				if !s.registry.has(name) {
+					s.logger.Warn("Dead-lettering log of unknown tenant", "tenant", name, "partition", message.Partition, "offset", message.Offset)
+					if !deadLetter(message, previous, fmt.Errorf("unknown tenant %q", name)) {
+						return nil
+					}
+					continue
+				}
+				log.Tenant = &name
+			}
+
+			// Drop, sample out or forward the log as the dynamic pipeline settings of its service say
+			if !s.applySettings(message, log) {
 				continue
 			}
 
+			// Propagate selected producer headers into the log's attributes
+			for _, name := range s.pipeline.HeaderAttributes {
+				if value, ok := headers[name]; ok {
+					log.Attributes.Set(constants.HeaderAttributePrefix+name, value)
+				}
+			}
+
+			// Identify the log before defaults that differ between deliveries are applied
+			assignMessageID(message, log)
+
+			// Mask sensitive data before anything derived from the log, such as its fingerprint, is computed
+			s.redactor.Redact(log)
+
 			// Add processing metadata
+			log.Fingerprint = s.fingerprinter.Fingerprint(log)
 			if log.Timestamp.IsZero() {
 				log.Timestamp = time.Now()
 			}
@@ -169,59 +762,422 @@ func (s *LogProcessorService) ConsumeClaim(session sarama.ConsumerGroupSession,
 				log.CreatedAt = time.Now()
 			}
 
-			batch = append(batch, &log)
-			session.MarkMessage(message, "")
+			batch.logs = append(batch.logs, log)
+			batch.messages = append(batch.messages, message)
 
 			// Process batch if it's full
-			if len(batch) >= s.batchSize {
-				if err := s.processBatch(session.Context(), batch); err != nil {
-					s.logger.Error("Failed to process batch", "error", err, "batch_size", len(batch))
+			if int64(len(batch.logs)) >= s.batchSize.Load() {
+				if err := flush(session.Context()); err != nil {
+					return nil
 				}
-				batch = batch[:0]
-				timer.Reset(s.batchTimeout)
+				timer.Reset(s.batchTimeoutFor(priority))
 			}
 
 		case <-timer.C:
 			// Process batch on timeout
-			if len(batch) > 0 {
-				if err := s.processBatch(session.Context(), batch); err != nil {
-					s.logger.Error("Failed to process batch on timeout", "error", err, "batch_size", len(batch))
-				}
-				batch = batch[:0]
+			if err := flush(session.Context()); err != nil {
+				return nil
 			}
-			timer.Reset(s.batchTimeout)
+			timer.Reset(s.batchTimeoutFor(priority))
 
 		case <-session.Context().Done():
-			// Process remaining batch
-			if len(batch) > 0 {
-				if err := s.processBatch(session.Context(), batch); err != nil {
-					s.logger.Error("Failed to process final batch", "error", err, "batch_size", len(batch))
-				}
-			}
+			end()
 			return nil
 		}
 	}
 }
 
+// applySettings applies the dynamic pipeline settings of the log's service, reporting whether the log is kept.
+// Messages that fail to be forwarded are stored instead, so that no log is lost.
+func (s *LogProcessorService) applySettings(message *sarama.ConsumerMessage, log *models.Log) bool {
+	settings := s.control.forService(log.Service)
+	if settings == nil {
+		return true
+	}
+	if settings.ForwardTopic != "" {
+		if err := s.forward.Forward(message, settings.ForwardTopic); err != nil {
+			s.logger.Error("Failed to forward message, storing it", "error", err, "service", log.Service, "offset", message.Offset)
+		} else {
+			metrics.LogsFiltered.WithLabelValues(metrics.FilterForwarded).Inc()
+			return false
+		}
+	}
+	if settings.Drops(log.Level) {
+		metrics.LogsFiltered.WithLabelValues(metrics.FilterLevel).Inc()
+		return false
+	}
+	if settings.SampleRate != nil && rand.Float64() >= *settings.SampleRate {
+		metrics.LogsFiltered.WithLabelValues(metrics.FilterSampled).Inc()
+		return false
+	}
+	return true
+}
+
+// matchesHeaderFilters reports whether the message headers satisfy every configured header filter
+func (s *LogProcessorService) matchesHeaderFilters(headers map[string]string) bool {
+	for _, filter := range s.pipeline.HeaderFilters {
+		value, ok := headers[filter.Key]
+		if !ok || !slices.Contains(filter.Values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// messageHeaders returns the message headers as a map, keeping the last value of repeated keys
+func messageHeaders(message *sarama.ConsumerMessage) map[string]string {
+	headers := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		if header != nil {
+			headers[string(header.Key)] = string(header.Value)
+		}
+	}
+	return headers
+}
+
 // Setup implements sarama.ConsumerGroupHandler
 func (s *LogProcessorService) Setup(sarama.ConsumerGroupSession) error {
+	s.inSession.Store(true)
 	s.logger.Info("Log processor setup completed")
 	return nil
 }
 
 // Cleanup implements sarama.ConsumerGroupHandler
 func (s *LogProcessorService) Cleanup(sarama.ConsumerGroupSession) error {
+	s.inSession.Store(false)
 	s.logger.Info("Log processor cleanup completed")
 	return nil
 }
 
-// Close closes the service and its resources
+// Close closes the service and its resources: the consumer groups first, leaving them so that their partitions are
+// reassigned right away, then the producers, flushing the messages they buffer, and the database last
 func (s *LogProcessorService) Close() error {
-	return s.consumer.Close()
+	if s.cancelDrain != nil {
+		s.cancelDrain()
+	}
+	s.breaker.close()
+	if s.kafka != nil {
+		s.kafka.Close()
+	}
+	consumerErr := s.consumer.Close()
+	if consumerErr != nil {
+		s.logger.Error("Failed to close consumer", "error", consumerErr)
+	}
+	if s.priority != nil {
+		if err := s.priority.Close(); err != nil {
+			s.logger.Error("Failed to close priority consumer", "error", err)
+		}
+	}
+	if err := s.deadLetter.Close(); err != nil {
+		s.logger.Error("Failed to close dead-letter producer", "error", err)
+	}
+	if s.stream != nil {
+		if err := s.stream.Close(); err != nil {
+			s.logger.Error("Failed to close live tail producer", "error", err)
+		}
+	}
+	if s.control != nil {
+		if err := s.control.Close(); err != nil {
+			s.logger.Error("Failed to close control consumer", "error", err)
+		}
+		if err := s.forward.Close(); err != nil {
+			s.logger.Error("Failed to close forward producer", "error", err)
+		}
+	}
+	if s.shards != nil {
+		if err := s.shards.Close(); err != nil {
+			s.logger.Error("Failed to close log shards", "error", err)
+		}
+	}
+	if s.clickHouse != nil {
+		if err := s.clickHouse.Close(); err != nil {
+			s.logger.Error("Failed to close ClickHouse log store", "error", err)
+		}
+	}
+	if s.notifications != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.ShutdownNotificationTimeout)
+		defer cancel()
+		if err := s.notifications.Shutdown(ctx); err != nil {
+			s.logger.Error("Failed to deliver pending notifications", "error", err)
+		}
+	}
+	if err := s.db.Close(); err != nil {
+		s.logger.Error("Failed to close database", "error", err)
+	}
+	return consumerErr
+}
+
+// pendingBatch is a batch of logs together with the messages they were parsed from. The offsets of its messages,
+// and of the messages skipped while it was collected, are marked once the batch is stored or dead-lettered.
+type pendingBatch struct {
+	session  sarama.ConsumerGroupSession
+	ctx      context.Context // ends the batch's waits: the session's context, or the drain's on shutdown
+	done     chan struct{}   // closed once a write worker handled the batch
+	previous *pendingBatch   // the claim's batch queued before this one, whose offsets are marked first
+	err      error           // why the batch wasn't stored, set before done is closed
+	logs     []*models.Log
+	messages []*sarama.ConsumerMessage // the messages of the logs, dead-lettered when the batch can't be stored
+	last     *sarama.ConsumerMessage   // the last message consumed, marked once the batch is stored
+	priority bool
+}
+
+// newPendingBatch starts an empty batch
+func newPendingBatch(session sarama.ConsumerGroupSession, priority bool) *pendingBatch {
+	return &pendingBatch{session: session, ctx: session.Context(), done: make(chan struct{}), priority: priority}
 }
 
-// processBatch processes a batch of logs
-func (s *LogProcessorService) processBatch(ctx context.Context, logs []*models.Log) error {
-	s.logger.Debug("Processing batch", "batch_size", len(logs))
-	return s.handler.HandleLogBatch(ctx, logs)
+// mark marks the offsets of a stored batch once the claim's previous batch is stored too. Write workers store the
+// batches of a claim concurrently, and marking a message commits the offsets of all earlier messages of its partition.
+// The previous batch is always handled, as storing gives up once the session ends.
+func (batch *pendingBatch) mark() error {
+	if previous := batch.previous; previous != nil {
+		<-previous.done
+		if previous.err != nil {
+			return fmt.Errorf("previous batch of the partition was not stored: %w", previous.err)
+		}
+		// Drop the reference so that the claim's handled batches can be collected
+		batch.previous = nil
+	}
+	batch.session.MarkMessage(batch.last, "")
+	return nil
+}
+
+// processBatch processes a batch of logs. Priority batches are enriched and stored in the calling goroutine
+// instead of waiting in the write queue.
+func (s *LogProcessorService) processBatch(ctx context.Context, batch *pendingBatch) error {
+	if len(batch.logs) > 0 {
+		s.logger.Debug("Processing batch", "batch_size", len(batch.logs))
+		lane := metrics.LaneBulk
+		if batch.priority {
+			lane = metrics.LanePriority
+		}
+		metrics.BatchSize.WithLabelValues(lane).Observe(float64(len(batch.logs)))
+	}
+
+	// Batches already collected wait for maintenance to end; batches queued for the write workers are still written
+	if err := s.maintenance.WaitWritable(batch.ctx); err != nil {
+		return fmt.Errorf("writes paused for maintenance: %w", err)
+	}
+	if batch.priority {
+		if s.enricher != nil {
+			s.enricher.EnrichBatch(ctx, batch.logs)
+		}
+		return s.persist(ctx, batch)
+	}
+
+	// Batches without logs are queued too, so that offsets are marked in order. The send blocks when
+	// the workers are saturated, applying backpressure to consumption.
+	select {
+	case s.writeQueue <- batch:
+		return nil
+	case <-batch.ctx.Done():
+		return fmt.Errorf("session ended before the batch was queued for the write workers")
+	}
+}
+
+// persist stores a batch and marks the offsets of its messages. Failed writes are retried with a growing, jittered
+// backoff; once the retries are exhausted the batch's messages are dead-lettered instead, and as long as that fails
+// too, storing and dead-lettering are retried until the session ends, leaving the messages for redelivery. Writes
+// failing because the log store is unreachable aren't counted as retries, and wait for the store to be back once
+// they opened the store breaker.
+func (s *LogProcessorService) persist(ctx context.Context, batch *pendingBatch) error {
+	if len(batch.logs) == 0 {
+		return batch.mark()
+	}
+
+	backoff := s.retryBackoff
+	retries := 0
+	for {
+		if err := s.breaker.wait(batch.ctx); err != nil {
+			return fmt.Errorf("session ended while the log store was unreachable: %w", err)
+		}
+		err := s.store(ctx, batch.logs)
+		if err == nil {
+			s.breaker.succeeded()
+			return batch.mark()
+		}
+		switch {
+		case s.breaker.failed(ctx):
+			s.logger.Warn("Failed to store batch, log store unreachable", "error", err, "batch_size", len(batch.logs), "backoff", backoff)
+		case retries >= s.storeRetries:
+			dlqErr := s.deadLetterBatch(batch, err)
+			if dlqErr == nil {
+				return batch.mark()
+			}
+			s.logger.Error("Failed to dead-letter batch, retrying", "error", dlqErr, "batch_size", len(batch.logs), "backoff", backoff)
+		default:
+			retries++
+			s.logger.Warn("Failed to store batch, retrying", "error", err, "attempt", retries, "batch_size", len(batch.logs), "backoff", backoff)
+		}
+		metrics.BatchStoreRetries.Inc()
+
+		select {
+		case <-batch.ctx.Done():
+			return fmt.Errorf("session ended before the batch was stored: %w", err)
+		case <-time.After(jittered(backoff)):
+		}
+		backoff = min(2*backoff, constants.MaxStoreRetryBackoff)
+
+		// IDs assigned by the failed insert were rolled back
+		for _, log := range batch.logs {
+			log.ID = 0
+		}
+	}
+}
+
+// pingStore checks that the database and the log store are reachable
+func (s *LogProcessorService) pingStore(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		return err
+	}
+	if s.shards != nil {
+		if err := s.shards.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	if s.clickHouse != nil {
+		return s.clickHouse.Ping(ctx)
+	}
+	return nil
+}
+
+// pauseConsumption stops fetching from the claimed partitions, keeping the consumer groups' membership
+func (s *LogProcessorService) pauseConsumption() {
+	s.consumer.PauseAll()
+	if s.priority != nil {
+		s.priority.PauseAll()
+	}
+}
+
+// resumeConsumption resumes fetching from the claimed partitions
+func (s *LogProcessorService) resumeConsumption() {
+	s.consumer.ResumeAll()
+	if s.priority != nil {
+		s.priority.ResumeAll()
+	}
+}
+
+// deserialize decodes a message value. Failures to reach the schema registry aren't the message's fault, so they are
+// retried with a growing, jittered backoff until the session ends, holding up the partition, rather than
+// dead-lettering the message.
+func (s *LogProcessorService) deserialize(ctx context.Context, message *sarama.ConsumerMessage) ([]byte, error) {
+	backoff := s.retryBackoff
+	for {
+		value, err := s.deserializer.Deserialize(ctx, message.Value)
+		if !errors.Is(err, avro.ErrRegistryUnavailable) {
+			return value, err
+		}
+		s.logger.Warn("Failed to decode message, schema registry unavailable, retrying", "error", err, "partition", message.Partition, "offset", message.Offset, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(jittered(backoff)):
+		}
+		backoff = min(2*backoff, constants.MaxStoreRetryBackoff)
+	}
+}
+
+// deadLetterMessage republishes a message that can't be stored to the dead-letter topic. Failed publishes are retried
+// with a growing, jittered backoff until the session ends, since marking the message without it being dead-lettered
+// would lose it.
+func (s *LogProcessorService) deadLetterMessage(ctx context.Context, message *sarama.ConsumerMessage, cause error) error {
+	backoff := s.retryBackoff
+	for {
+		err := s.deadLetter.Publish(message, cause)
+		if err == nil {
+			metrics.LogsDeadLettered.WithLabelValues(message.Topic).Inc()
+			return nil
+		}
+		s.logger.Warn("Failed to dead-letter message, retrying", "error", err, "partition", message.Partition, "offset", message.Offset, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("session ended before the message was dead-lettered: %w", err)
+		case <-time.After(jittered(backoff)):
+		}
+		backoff = min(2*backoff, constants.MaxStoreRetryBackoff)
+	}
+}
+
+// deadLetterBatch republishes the messages of a batch that couldn't be stored to the dead-letter topic
+func (s *LogProcessorService) deadLetterBatch(batch *pendingBatch, cause error) error {
+	cause = fmt.Errorf("failed to store log: %w", cause)
+	for _, message := range batch.messages {
+		if err := s.deadLetter.Publish(message, cause); err != nil {
+			return err
+		}
+		metrics.LogsDeadLettered.WithLabelValues(message.Topic).Inc()
+	}
+	s.logger.Error("Dead-lettered batch that could not be stored", "error", cause, "batch_size", len(batch.logs))
+	return nil
+}
+
+// store stores a batch of logs and publishes the stored logs to the live tail. Logs stored before, by an earlier
+// delivery of their messages, are skipped.
+func (s *LogProcessorService) store(ctx context.Context, logs []*models.Log) error {
+	if s.dedup != nil {
+		kept := s.dedup.filter(logs)
+		metrics.LogsDeduplicated.WithLabelValues(metrics.DedupCache).Add(float64(len(logs) - len(kept)))
+		logs = kept
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := s.handler.HandleLogBatch(ctx, logs); err != nil {
+		return err
+	}
+
+	// The repository leaves the IDs of the logs it found stored at zero
+	stored := slices.DeleteFunc(slices.Clone(logs), func(log *models.Log) bool { return log.ID == 0 })
+	metrics.LogsDeduplicated.WithLabelValues(metrics.DedupDatabase).Add(float64(len(logs) - len(stored)))
+	if s.dedup != nil {
+		s.dedup.add(logs)
+	}
+	s.delays.record(stored, time.Now())
+	if s.stream != nil {
+		s.stream.Publish(stored)
+	}
+	return nil
+}
+
+// closeStream closes the live tail producer, if any, of a processor that failed to start
+func closeStream(stream *producers.LogStreamPublisher) {
+	if stream != nil {
+		stream.Close()
+	}
+}
+
+// closeControl closes the control consumer and forward producer, if any, of a processor that failed to start
+func closeControl(control *controlConsumer, forward *producers.ForwardProducer) {
+	if control != nil {
+		control.Close()
+		forward.Close()
+	}
+}
+
+// runWriteWorker enriches queued batches and stores them until the queue is closed
+func (s *LogProcessorService) runWriteWorker(ctx context.Context) {
+	defer s.writers.Done()
+
+	for batch := range s.writeQueue {
+		if s.enricher != nil {
+			s.enricher.EnrichBatch(ctx, batch.logs)
+		}
+		if err := s.persist(ctx, batch); err != nil {
+			s.logger.Error("Failed to store batch, leaving its messages for redelivery", "error", err, "batch_size", len(batch.logs))
+			batch.err = err
+		}
+		close(batch.done)
+	}
+}
+
+// stopWriteWorkers drains the write workers once consumption has stopped
+func (s *LogProcessorService) stopWriteWorkers() {
+	close(s.writeQueue)
+	s.writers.Wait()
+	if s.enricher != nil {
+		s.enricher.Close()
+	}
+	s.logger.Info("Write workers drained")
 }
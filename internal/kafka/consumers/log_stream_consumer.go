@@ -0,0 +1,118 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// LogStreamConsumer follows the stream topic of stored logs and feeds them to the live tail.
+// Every API server reads all partitions from the newest offset without a consumer group, since
+// each one serves its own live tail clients and logs published while it was down are of no interest.
+type LogStreamConsumer struct {
+	consumer sarama.Consumer
+	topic    string
+	stream   *services.LogStream
+	logger   *slog.Logger
+}
+
+// NewLogStreamConsumer creates a new log stream consumer
+func NewLogStreamConsumer(cfg *config.KafkaConfig, stream *services.LogStream, logger *slog.Logger) (*LogStreamConsumer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka configuration: %w", err)
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V3_0_0_0
+	config.Consumer.Return.Errors = true
+
+	consumer, err := sarama.NewConsumer(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream consumer: %w", err)
+	}
+
+	return &LogStreamConsumer{
+		consumer: consumer,
+		topic:    cfg.StreamTopic,
+		stream:   stream,
+		logger:   logger,
+	}, nil
+}
+
+// Start consumes the stream topic until the context is cancelled. The topic is created by the first
+// published log, so partitions are looked up again until it exists.
+func (c *LogStreamConsumer) Start(ctx context.Context) {
+	var partitions []int32
+	for {
+		var err error
+		partitions, err = c.consumer.Partitions(c.topic)
+		if err == nil && len(partitions) > 0 {
+			break
+		}
+		c.logger.Debug("Live tail topic not available yet", "topic", c.topic, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.StreamConsumerRetryInterval):
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, partition := range partitions {
+		pc, err := c.consumer.ConsumePartition(c.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			c.logger.Error("Failed to consume live tail partition", "error", err, "topic", c.topic, "partition", partition)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consumePartition(ctx, pc)
+		}()
+	}
+	c.logger.Info("Live tail consumer started", "topic", c.topic, "partitions", len(partitions))
+
+	wg.Wait()
+	c.logger.Info("Live tail consumer stopped")
+}
+
+// consumePartition publishes the logs of a partition to the live tail until the context is cancelled
+func (c *LogStreamConsumer) consumePartition(ctx context.Context, pc sarama.PartitionConsumer) {
+	defer pc.AsyncClose()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return
+			}
+			c.logger.Warn("Live tail consumer error", "error", err.Err, "partition", err.Partition)
+		case message, ok := <-pc.Messages():
+			if !ok {
+				return
+			}
+			var log models.Log
+			if err := json.Unmarshal(message.Value, &log); err != nil {
+				c.logger.Warn("Failed to unmarshal live tail log", "error", err, "partition", message.Partition, "offset", message.Offset)
+				continue
+			}
+			c.stream.Publish(&log)
+		}
+	}
+}
+
+// Close closes the consumer
+func (c *LogStreamConsumer) Close() error {
+	return c.consumer.Close()
+}
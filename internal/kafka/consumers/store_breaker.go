@@ -0,0 +1,153 @@
+package consumers
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// storeBreaker pauses consumption while the log store is unreachable. Failed writes are told apart from outages by
+// pinging the store: writes failing while the store answers are the batch's own problem, retried and then
+// dead-lettered, while consecutive failures with the store unreachable open the breaker. An open breaker pauses the
+// partitions of the consumer groups, which keep their membership, holds writes back and probes the store until it
+// answers again, then resumes consumption.
+type storeBreaker struct {
+	ping      func(ctx context.Context) error
+	pause     func()
+	resume    func()
+	threshold int // 0 disables the breaker
+	interval  time.Duration
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	failures int
+	closed   chan struct{} // closed while the breaker is closed; writes wait on it while open
+	stop     chan struct{}
+}
+
+// newStoreBreaker creates a closed breaker
+func newStoreBreaker(ping func(ctx context.Context) error, pause, resume func(), threshold int, interval time.Duration, logger *slog.Logger) *storeBreaker {
+	closed := make(chan struct{})
+	close(closed)
+	return &storeBreaker{
+		ping:      ping,
+		pause:     pause,
+		resume:    resume,
+		threshold: threshold,
+		interval:  interval,
+		logger:    logger,
+		closed:    closed,
+		stop:      make(chan struct{}),
+	}
+}
+
+// wait blocks while the breaker is open, until the store is back or the context is done
+func (b *storeBreaker) wait(ctx context.Context) error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// succeeded records a successful write
+func (b *storeBreaker) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// failed records a failed write, reporting whether the store is unreachable. Such failures don't count toward
+// dead-lettering the batch, since the batch isn't at fault.
+func (b *storeBreaker) failed(ctx context.Context) bool {
+	if b.threshold == 0 {
+		return false
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, constants.StoreBreakerPingTimeout)
+	defer cancel()
+	err := b.ping(pingCtx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.failures >= b.threshold && b.isClosed() {
+		b.closed = make(chan struct{})
+		b.pause()
+		metrics.StoreBreakerOpen.Set(1)
+		b.logger.Error("Log store unreachable, pausing consumption", "error", err, "failures", b.failures, "probe_interval", b.interval)
+		go b.probe()
+	}
+	return true
+}
+
+// pauseIfOpen pauses consumption again while the breaker is open, for partitions claimed after it opened
+func (b *storeBreaker) pauseIfOpen() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.isClosed() {
+		b.pause()
+	}
+}
+
+// probe pings the store until it answers, then closes the breaker and resumes consumption
+func (b *storeBreaker) probe() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), constants.StoreBreakerPingTimeout)
+		err := b.ping(ctx)
+		cancel()
+		if err != nil {
+			b.logger.Warn("Log store still unreachable", "error", err)
+			continue
+		}
+
+		b.mu.Lock()
+		b.failures = 0
+		close(b.closed)
+		b.resume()
+		b.mu.Unlock()
+		metrics.StoreBreakerOpen.Set(0)
+		b.logger.Info("Log store reachable again, resuming consumption")
+		return
+	}
+}
+
+// close stops probing the store
+func (b *storeBreaker) close() {
+	close(b.stop)
+}
+
+// isClosed reports whether the breaker is closed; the caller holds the lock
+func (b *storeBreaker) isClosed() bool {
+	select {
+	case <-b.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// jittered returns a backoff randomized to between half and all of it, so that processors retrying after the same
+// outage don't all hit the store at once
+func jittered(backoff time.Duration) time.Duration {
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
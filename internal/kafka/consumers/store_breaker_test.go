@@ -0,0 +1,105 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoreBreaker(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		writes    string // per write: f failed with the store down, F failed with the store answering, s succeeded
+		want      []bool // per failed write, whether the store was reported unreachable
+		wantOpen  bool
+	}{
+		{name: "disabled", threshold: 0, writes: "fff", want: []bool{false, false, false}},
+		{name: "batch at fault", threshold: 2, writes: "FFF", want: []bool{false, false, false}},
+		{name: "below threshold", threshold: 3, writes: "ff", want: []bool{true, true}},
+		{name: "success resets the failures", threshold: 2, writes: "fsf", want: []bool{true, true}},
+		{name: "answering store resets the failures", threshold: 2, writes: "fFf", want: []bool{true, false, true}},
+		{name: "opens at threshold", threshold: 2, writes: "ff", want: []bool{true, true}, wantOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var down atomic.Bool
+			var pauses, resumes atomic.Int32
+			ping := func(ctx context.Context) error {
+				if down.Load() {
+					return errors.New("connection refused")
+				}
+				return nil
+			}
+			b := newStoreBreaker(ping, func() { pauses.Add(1) }, func() { resumes.Add(1) }, tt.threshold,
+				time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			defer b.close()
+
+			var got []bool
+			for _, write := range tt.writes {
+				if write == 's' {
+					b.succeeded()
+					continue
+				}
+				down.Store(write == 'f')
+				got = append(got, b.failed(context.Background()))
+			}
+			for i := range tt.want {
+				if i >= len(got) || got[i] != tt.want[i] {
+					t.Fatalf("failed() reported unreachable %v, want %v", got, tt.want)
+				}
+			}
+
+			if !tt.wantOpen {
+				if pauses.Load() != 0 {
+					t.Errorf("paused %d times, want the breaker closed", pauses.Load())
+				}
+				if err := b.wait(context.Background()); err != nil {
+					t.Errorf("wait() error %v on a closed breaker", err)
+				}
+				return
+			}
+
+			if pauses.Load() != 1 {
+				t.Fatalf("paused %d times, want once", pauses.Load())
+			}
+			b.pauseIfOpen()
+			if pauses.Load() != 2 {
+				t.Errorf("pauseIfOpen() didn't pause partitions claimed while open")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("wait() = %v while the store is down, want it to block", err)
+			}
+
+			down.Store(false)
+			ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := b.wait(ctx); err != nil {
+				t.Fatalf("wait() = %v, want the breaker closed once the store answers", err)
+			}
+			if resumes.Load() != 1 {
+				t.Errorf("resumed %d times, want once", resumes.Load())
+			}
+			b.pauseIfOpen()
+			if pauses.Load() != 2 {
+				t.Errorf("pauseIfOpen() paused a closed breaker")
+			}
+		})
+	}
+}
+
+func TestJittered(t *testing.T) {
+	for _, backoff := range []time.Duration{0, time.Nanosecond, time.Second} {
+		for range 100 {
+			if got := jittered(backoff); got < backoff/2 || got > backoff {
+				t.Fatalf("jittered(%v) = %v, want between half and all of it", backoff, got)
+			}
+		}
+	}
+}
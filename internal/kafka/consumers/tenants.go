@@ -0,0 +1,66 @@
+package consumers
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/tenants"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// tenantRegistry holds the names of the registered tenants, which producers may name in the tenant header of logs
+// sent to the log and priority topics. It is reloaded periodically, so tenants registered through the API are
+// accepted without restarting the processor.
+type tenantRegistry struct {
+	repo   tenants.TenantRepository
+	logger *slog.Logger
+	mu     sync.RWMutex
+	names  map[string]bool
+}
+
+func newTenantRegistry(repo tenants.TenantRepository, logger *slog.Logger) *tenantRegistry {
+	return &tenantRegistry{repo: repo, logger: logger, names: make(map[string]bool)}
+}
+
+// Start reloads the tenants every refresh interval until the context is done
+func (r *tenantRegistry) Start(ctx context.Context) {
+	ticker := time.NewTicker(constants.TenantRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.refresh(ctx); err != nil {
+			r.logger.Error("Failed to refresh tenants", "error", err)
+		}
+	}
+}
+
+// refresh reloads the registered tenants. The last known tenants are kept when loading fails.
+func (r *tenantRegistry) refresh(ctx context.Context) error {
+	registered, err := r.repo.GetTenants(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(registered))
+	for _, tenant := range registered {
+		names[tenant.Name] = true
+	}
+
+	r.mu.Lock()
+	r.names = names
+	r.mu.Unlock()
+	return nil
+}
+
+// has reports whether the tenant is registered
+func (r *tenantRegistry) has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names[name]
+}
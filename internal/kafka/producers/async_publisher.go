@@ -0,0 +1,101 @@
+package producers
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+)
+
+// asyncPublisher sends messages through a sarama.AsyncProducer, which batches them per partition.
+// The number of messages not yet acknowledged is bounded by the buffer size; publishing blocks
+// once the buffer is full so producers slow down instead of exhausting memory.
+type asyncPublisher struct {
+	producer sarama.AsyncProducer
+	slots    chan struct{}
+	sent     atomic.Int64
+	failed   atomic.Int64
+	fallback func(message *sarama.ProducerMessage) bool // keeps failed messages, reporting whether it did; nil drops them
+	wg       sync.WaitGroup
+	logger   *slog.Logger
+}
+
+// newAsyncPublisher creates an async publisher and starts handling delivery results.
+// The config must have Return.Successes and Return.Errors enabled.
+func newAsyncPublisher(brokers []string, config *sarama.Config, bufferSize int, logger *slog.Logger) (*asyncPublisher, error) {
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create async producer: %w", err)
+	}
+
+	p := &asyncPublisher{
+		producer: producer,
+		slots:    make(chan struct{}, bufferSize),
+		logger:   logger,
+	}
+	p.wg.Add(2)
+	go p.handleSuccesses()
+	go p.handleErrors()
+	return p, nil
+}
+
+// publish queues the messages, waiting for buffer space until the context is done
+func (p *asyncPublisher) publish(ctx context.Context, messages ...*sarama.ProducerMessage) error {
+	for i, message := range messages {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("producer buffer full, %d of %d messages queued: %w", i, len(messages), ctx.Err())
+		}
+		p.producer.Input() <- message
+	}
+	return nil
+}
+
+// tryPublish queues a message unless the buffer is full, reporting whether it was queued
+func (p *asyncPublisher) tryPublish(message *sarama.ProducerMessage) bool {
+	select {
+	case p.slots <- struct{}{}:
+		p.producer.Input() <- message
+		return true
+	default:
+		return false
+	}
+}
+
+// handleSuccesses releases buffer space for delivered messages
+func (p *asyncPublisher) handleSuccesses() {
+	defer p.wg.Done()
+	for message := range p.producer.Successes() {
+		<-p.slots
+		p.sent.Add(1)
+		metrics.LogsProduced.WithLabelValues(message.Topic).Inc()
+	}
+}
+
+// handleErrors releases buffer space for failed messages and logs the failure. Failed messages are handed to
+// the fallback, if any, or else dropped after the producer's own retries are exhausted.
+func (p *asyncPublisher) handleErrors() {
+	defer p.wg.Done()
+	for err := range p.producer.Errors() {
+		<-p.slots
+		p.failed.Add(1)
+		metrics.ProduceErrors.WithLabelValues(err.Msg.Topic).Inc()
+		if p.fallback != nil && p.fallback(err.Msg) {
+			continue
+		}
+		p.logger.Error("Failed to deliver log", "error", err.Err, "topic", err.Msg.Topic)
+	}
+}
+
+// close flushes buffered messages and waits for their delivery results
+func (p *asyncPublisher) close() error {
+	p.producer.AsyncClose()
+	p.wg.Wait()
+	p.logger.Info("Async producer stopped", "sent", p.sent.Load(), "failed", p.failed.Load())
+	return nil
+}
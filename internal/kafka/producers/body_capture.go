@@ -0,0 +1,84 @@
+package producers
+
+import (
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bodyCapture decides which logs keep their request and response bodies, redacting sensitive
+// fields and truncating what is kept. Bodies of other services are dropped before publishing.
+type bodyCapture struct {
+	services map[string]bool
+	all      bool
+	maxBytes int
+	// redactions replace the values of sensitive fields in JSON, form, query string and header bodies
+	redactions []redaction
+}
+
+// redaction replaces the matches of a pattern
+type redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// newBodyCapture creates the body capture of the configured services
+func newBodyCapture(cfg *config.CollectorConfig) *bodyCapture {
+	b := &bodyCapture{
+		services: make(map[string]bool, len(cfg.BodyServices)),
+		all:      slices.Contains(cfg.BodyServices, constants.BodyCaptureAllServices),
+		maxBytes: cfg.BodyMaxBytes,
+	}
+	for _, service := range cfg.BodyServices {
+		b.services[service] = true
+	}
+
+	if len(cfg.BodyRedactKeys) > 0 {
+		keys := make([]string, len(cfg.BodyRedactKeys))
+		for i, key := range cfg.BodyRedactKeys {
+			keys[i] = regexp.QuoteMeta(key)
+		}
+		alternation := strings.Join(keys, "|")
+		b.redactions = []redaction{
+			// "key": "value" or "key": 123
+			{regexp.MustCompile(`(?i)("(?:` + alternation + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*"|[^,}\]\s]+)`), `${1}"` + constants.BodyRedactedValue + `"`},
+			// key=value in forms and query strings, key: value in headers
+			{regexp.MustCompile(`(?i)(\b(?:` + alternation + `)\s*[=:]\s*)[^&\r\n"]+`), `${1}` + constants.BodyRedactedValue},
+		}
+	}
+	return b
+}
+
+// Enabled reports whether any service keeps its bodies
+func (b *bodyCapture) Enabled() bool {
+	return len(b.services) > 0
+}
+
+// apply drops the bodies of a log whose service doesn't keep them, and redacts and truncates them otherwise
+func (b *bodyCapture) apply(log *models.Log) {
+	if !b.all && !b.services[log.Service] {
+		log.RequestBody, log.ResponseBody = nil, nil
+		return
+	}
+	log.RequestBody = b.excerpt(log.RequestBody)
+	log.ResponseBody = b.excerpt(log.ResponseBody)
+}
+
+// excerpt redacts a body and truncates it to the size cap; empty bodies are dropped
+func (b *bodyCapture) excerpt(body *string) *string {
+	if body == nil || *body == "" {
+		return nil
+	}
+	// Redacting first keeps secrets from leaking through a truncated field
+	excerpt := *body
+	for _, r := range b.redactions {
+		excerpt = r.pattern.ReplaceAllString(excerpt, r.replacement)
+	}
+	if len(excerpt) > b.maxBytes {
+		excerpt = strings.ToValidUTF8(excerpt[:b.maxBytes-len(constants.BodyTruncatedSuffix)], "") + constants.BodyTruncatedSuffix
+	}
+	return &excerpt
+}
@@ -0,0 +1,78 @@
+package producers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// DeadLetterProducer republishes messages that could not be processed to the dead-letter topic
+type DeadLetterProducer struct {
+	producer sarama.SyncProducer
+	topic    string
+	logger   *slog.Logger
+}
+
+// NewDeadLetterProducer creates a new dead-letter producer
+func NewDeadLetterProducer(cfg *config.KafkaConfig, logger *slog.Logger) (*DeadLetterProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = constants.DefaultProducerRetryMax
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter producer: %w", err)
+	}
+
+	return &DeadLetterProducer{
+		producer: producer,
+		topic:    cfg.DeadLetterTopic,
+		logger:   logger,
+	}, nil
+}
+
+// Publish republishes the original message unchanged, keeping its key and headers
+// and adding headers describing where it came from and why it failed
+func (p *DeadLetterProducer) Publish(message *sarama.ConsumerMessage, cause error) error {
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers)+5)
+	for _, header := range message.Headers {
+		if header != nil {
+			headers = append(headers, *header)
+		}
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(constants.HeaderDeadLetterError), Value: []byte(cause.Error())},
+		sarama.RecordHeader{Key: []byte(constants.HeaderDeadLetterTopic), Value: []byte(message.Topic)},
+		sarama.RecordHeader{Key: []byte(constants.HeaderDeadLetterPartition), Value: []byte(strconv.FormatInt(int64(message.Partition), 10))},
+		sarama.RecordHeader{Key: []byte(constants.HeaderDeadLetterOffset), Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		sarama.RecordHeader{Key: []byte(constants.HeaderDeadLetterFailedAt), Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	dlqMessage := &sarama.ProducerMessage{
+		Topic:   p.topic,
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	}
+	if message.Key != nil {
+		dlqMessage.Key = sarama.ByteEncoder(message.Key)
+	}
+
+	partition, offset, err := p.producer.SendMessage(dlqMessage)
+	if err != nil {
+		return fmt.Errorf("failed to publish to dead-letter topic: %w", err)
+	}
+
+	p.logger.Debug("Message dead-lettered", "topic", p.topic, "partition", partition, "offset", offset)
+	return nil
+}
+
+// Close closes the producer
+func (p *DeadLetterProducer) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package producers
+
+import "os"
+
+// fileInode returns 0 where inodes are unavailable, so rotation is only detected by truncation
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,369 @@
+package producers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileTailer follows log files matching glob patterns and publishes their lines to Kafka.
+// Files are identified by inode so rotation is detected, and offsets are checkpointed
+// after every successful publish so a restarted collector resumes where it stopped.
+type FileTailer struct {
+	collector  *LogCollectorService
+	patterns   []string
+	checkpoint string
+	files      map[string]*tailedFile // path -> followed file
+	logger     *slog.Logger
+}
+
+// tailedFile is an open file being followed
+type tailedFile struct {
+	path   string
+	inode  uint64
+	file   *os.File
+	offset int64 // position after the last published line
+}
+
+// tailCheckpoint is the persisted position of a followed file
+type tailCheckpoint struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// NewFileTailer creates a new file tailer publishing through the collector
+func NewFileTailer(collector *LogCollectorService, cfg *config.CollectorConfig, logger *slog.Logger) *FileTailer {
+	return &FileTailer{
+		collector:  collector,
+		patterns:   cfg.TailPaths,
+		checkpoint: cfg.TailCheckpoint,
+		files:      make(map[string]*tailedFile),
+		logger:     logger,
+	}
+}
+
+// Enabled reports whether any paths are configured
+func (t *FileTailer) Enabled() bool {
+	return len(t.patterns) > 0
+}
+
+// Start follows the configured files until the context is cancelled
+func (t *FileTailer) Start(ctx context.Context) error {
+	checkpoints, err := t.loadCheckpoints()
+	if err != nil {
+		return err
+	}
+	defer t.closeAll()
+
+	t.logger.Info("File tailer started", "paths", t.patterns, "checkpoint", t.checkpoint)
+
+	ticker := time.NewTicker(constants.TailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.poll(ctx, checkpoints); err != nil {
+			t.logger.Error("Failed to tail files", "error", err)
+		}
+		// Later polls resume from the in-memory positions
+		checkpoints = nil
+
+		select {
+		case <-ctx.Done():
+			t.logger.Info("File tailer stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll discovers files, handles rotation and truncation, and publishes newly written lines
+func (t *FileTailer) poll(ctx context.Context, checkpoints map[string]tailCheckpoint) error {
+	matched := make(map[string]os.FileInfo)
+	for _, pattern := range t.patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid tail path %q: %w", pattern, err)
+		}
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				matched[path] = info
+			}
+		}
+	}
+
+	// A followed file whose path now holds another file was rotated. If it was renamed to a
+	// path that still matches, keep following it there; otherwise finish it and let it go.
+	changed := false
+	for path, tailed := range t.files {
+		if info, ok := matched[path]; ok && fileInode(info) == tailed.inode {
+			continue
+		}
+		delete(t.files, path)
+		changed = true
+		if renamed := t.findRenamed(matched, tailed.inode); renamed != "" {
+			t.logger.Info("Log file rotated", "path", path, "renamed_to", renamed)
+			tailed.path = renamed
+			t.files[renamed] = tailed
+			continue
+		}
+		t.logger.Info("Log file rotated or removed", "path", path)
+		err := t.drain(ctx, tailed, true)
+		tailed.file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for path, info := range matched {
+		tailed, ok := t.files[path]
+		if !ok {
+			var err error
+			if tailed, err = t.open(path, fileInode(info), checkpoints); err != nil {
+				t.logger.Warn("Failed to open log file", "error", err, "path", path)
+				continue
+			}
+			changed = true
+		}
+
+		// A shrinking file was truncated in place (copytruncate)
+		if info.Size() < tailed.offset {
+			t.logger.Info("Log file truncated", "path", path)
+			tailed.offset = 0
+			changed = true
+		}
+
+		if info.Size() > tailed.offset {
+			if err := t.drain(ctx, tailed, false); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+
+	if changed {
+		return t.saveCheckpoints()
+	}
+	return nil
+}
+
+// findRenamed returns the matched path that isn't followed yet and holds the given inode
+func (t *FileTailer) findRenamed(matched map[string]os.FileInfo, inode uint64) string {
+	for path, info := range matched {
+		if _, followed := t.files[path]; !followed && fileInode(info) == inode {
+			return path
+		}
+	}
+	return ""
+}
+
+// open starts following a file, resuming from the checkpoint recorded for its inode.
+// The inode is looked up across all paths so a file rotated while the collector was down isn't re-read.
+func (t *FileTailer) open(path string, inode uint64, checkpoints map[string]tailCheckpoint) (*tailedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	tailed := &tailedFile{path: path, inode: inode, file: file}
+	if checkpoint, ok := checkpoints[path]; ok && checkpoint.Inode == inode {
+		tailed.offset = checkpoint.Offset
+	} else {
+		for _, checkpoint := range checkpoints {
+			if checkpoint.Inode == inode {
+				tailed.offset = checkpoint.Offset
+				break
+			}
+		}
+	}
+	t.files[path] = tailed
+	t.logger.Info("Following log file", "path", path, "offset", tailed.offset)
+	return tailed, nil
+}
+
+// close stops following a file
+func (t *FileTailer) close(path string) {
+	if tailed, ok := t.files[path]; ok {
+		tailed.file.Close()
+		delete(t.files, path)
+	}
+}
+
+// closeAll closes every followed file
+func (t *FileTailer) closeAll() {
+	for path := range t.files {
+		t.close(path)
+	}
+}
+
+// drain publishes every complete line after the file's offset in batches, advancing the offset
+// only after each batch is published. When final is set, a trailing line without newline is
+// published too since the file won't be appended to anymore.
+func (t *FileTailer) drain(ctx context.Context, tailed *tailedFile, final bool) error {
+	buf := make([]byte, constants.TailReadChunkSize)
+	batch := make([]*models.Log, 0, constants.TailBatchSize)
+	batchEnd := tailed.offset
+
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := t.collector.SendLogs(ctx, batch); err != nil {
+				return fmt.Errorf("failed to publish lines of %s: %w", tailed.path, err)
+			}
+			batch = batch[:0]
+		}
+		tailed.offset = batchEnd
+		return nil
+	}
+
+	for {
+		n, err := tailed.file.ReadAt(buf, batchEnd)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read %s: %w", tailed.path, err)
+		}
+		if n == 0 {
+			break
+		}
+		data := buf[:n]
+
+		consumed := 0
+		for consumed < len(data) {
+			rest := data[consumed:]
+			var line []byte
+			switch end := bytes.IndexByte(rest, '\n'); {
+			case end >= 0 && end < constants.TailMaxLineSize:
+				line = rest[:end+1]
+			case len(rest) >= constants.TailMaxLineSize:
+				// Overlong lines are split
+				line = rest[:constants.TailMaxLineSize]
+			case final && errors.Is(err, io.EOF):
+				// The last line of a finished file has no newline
+				line = rest
+			}
+			if line == nil {
+				// Incomplete line, wait for the rest to be written
+				break
+			}
+			consumed += len(line)
+
+			if log := parseTailLine(string(line), tailed.path, time.Now()); log != nil {
+				batch = append(batch, log)
+			}
+			batchEnd += int64(len(line))
+			if len(batch) >= constants.TailBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if consumed == 0 || errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return flush()
+}
+
+// parseTailLine converts a line to a log. JSON lines holding a valid log are used as-is;
+// anything else becomes a message of the service named after the file, with the level
+// detected from the line. Blank lines yield nil.
+func parseTailLine(line string, path string, now time.Time) *models.Log {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(line, "{") {
+		var log models.Log
+		if err := json.Unmarshal([]byte(line), &log); err == nil && log.Validate() == nil {
+			log.ID = 0
+			log.CreatedAt = time.Time{}
+			if log.Timestamp.IsZero() {
+				log.Timestamp = now
+			}
+			log.Attributes.Set(constants.TailAttributePath, path)
+			return &log
+		}
+	}
+
+	service := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if len(service) > constants.MaxServiceLength {
+		service = service[:constants.MaxServiceLength]
+	}
+	log := &models.Log{
+		Timestamp: now,
+		Level:     detectLogLevel(line),
+		Service:   service,
+		Message:   line,
+	}
+	log.Attributes.Set(constants.TailAttributePath, path)
+	return log
+}
+
+// detectLogLevel looks for a level keyword among the first words of a line, defaulting to INFO
+func detectLogLevel(line string) models.LogLevel {
+	const maxWords = 5
+	for i, word := range strings.Fields(line) {
+		if i >= maxWords {
+			break
+		}
+		switch strings.ToUpper(strings.Trim(word, "[]():|=")) {
+		case "DEBUG", "TRACE":
+			return models.LogLevelDebug
+		case "INFO":
+			return models.LogLevelInfo
+		case "WARN", "WARNING":
+			return models.LogLevelWarn
+		case "ERROR", "ERR":
+			return models.LogLevelError
+		case "FATAL", "CRITICAL", "PANIC":
+			return models.LogLevelFatal
+		}
+	}
+	return models.LogLevelInfo
+}
+
+// loadCheckpoints reads the persisted file positions; a missing checkpoint file starts fresh
+func (t *FileTailer) loadCheckpoints() (map[string]tailCheckpoint, error) {
+	checkpoints := make(map[string]tailCheckpoint)
+	data, err := os.ReadFile(t.checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tail checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode tail checkpoint: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// saveCheckpoints atomically persists the positions of the followed files
+func (t *FileTailer) saveCheckpoints() error {
+	checkpoints := make(map[string]tailCheckpoint, len(t.files))
+	for path, tailed := range t.files {
+		checkpoints[path] = tailCheckpoint{Inode: tailed.inode, Offset: tailed.offset}
+	}
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return fmt.Errorf("failed to encode tail checkpoint: %w", err)
+	}
+
+	tmp := t.checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write tail checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, t.checkpoint); err != nil {
+		return fmt.Errorf("failed to write tail checkpoint: %w", err)
+	}
+	return nil
+}
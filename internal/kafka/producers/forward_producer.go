@@ -0,0 +1,68 @@
+package producers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+)
+
+// ForwardProducer republishes messages to the topic their service is forwarded to by the dynamic pipeline settings
+type ForwardProducer struct {
+	producer sarama.SyncProducer
+	logger   *slog.Logger
+}
+
+// NewForwardProducer creates a new forward producer
+func NewForwardProducer(cfg *config.KafkaConfig, logger *slog.Logger) (*ForwardProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = constants.DefaultProducerRetryMax
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create forward producer: %w", err)
+	}
+
+	return &ForwardProducer{
+		producer: producer,
+		logger:   logger,
+	}, nil
+}
+
+// Forward republishes the original message unchanged to the topic, keeping its key and headers
+// and adding a header naming the topic it was consumed from
+func (p *ForwardProducer) Forward(message *sarama.ConsumerMessage, topic string) error {
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers)+1)
+	for _, header := range message.Headers {
+		if header != nil {
+			headers = append(headers, *header)
+		}
+	}
+	headers = append(headers, sarama.RecordHeader{Key: []byte(constants.HeaderForwardedFrom), Value: []byte(message.Topic)})
+
+	forwarded := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	}
+	if message.Key != nil {
+		forwarded.Key = sarama.ByteEncoder(message.Key)
+	}
+
+	partition, offset, err := p.producer.SendMessage(forwarded)
+	if err != nil {
+		return fmt.Errorf("failed to forward message to %s: %w", topic, err)
+	}
+
+	p.logger.Debug("Message forwarded", "topic", topic, "partition", partition, "offset", offset)
+	return nil
+}
+
+// Close closes the producer
+func (p *ForwardProducer) Close() error {
+	return p.producer.Close()
+}
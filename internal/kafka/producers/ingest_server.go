@@ -0,0 +1,168 @@
+package producers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngestServer accepts logs over HTTP and publishes them to Kafka
+type IngestServer struct {
+	collector    *LogCollectorService
+	server       *http.Server
+	maxBodyBytes int64
+	maxBatchSize int
+	logger       *slog.Logger
+}
+
+// NewIngestServer creates a new HTTP ingestion server publishing through the collector
+func NewIngestServer(collector *LogCollectorService, cfg *config.CollectorConfig, logger *slog.Logger) *IngestServer {
+	s := &IngestServer{
+		collector:    collector,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		maxBatchSize: cfg.MaxBatchSize,
+		logger:       logger,
+	}
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = constants.DefaultIngestMaxBodyBytes
+	}
+	if s.maxBatchSize <= 0 {
+		s.maxBatchSize = constants.DefaultIngestMaxBatchSize
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST(constants.APIIngestPath, s.Ingest)
+
+	s.server = &http.Server{
+		Addr:         ":" + cfg.IngestPort,
+		Handler:      router,
+		ReadTimeout:  constants.DefaultServerReadTimeout,
+		WriteTimeout: constants.DefaultServerWriteTimeout,
+		IdleTimeout:  constants.DefaultServerIdleTimeout,
+	}
+	return s
+}
+
+// Start serves ingestion requests until the context is cancelled
+func (s *IngestServer) Start(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	go func() {
+		s.logger.Info("Starting log ingestion server", "addr", s.server.Addr, "path", constants.APIIngestPath)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return fmt.Errorf("failed to start ingestion server: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.IngestShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down ingestion server: %w", err)
+	}
+	s.logger.Info("Log ingestion server stopped")
+	return nil
+}
+
+// Ingest accepts a single log object or an array of logs, validates them and publishes them to Kafka.
+// A batch is rejected as a whole if any log is invalid.
+func (s *IngestServer) Ingest(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, s.maxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ingestError(c, apperrors.Validation("Request body exceeds %d bytes", s.maxBodyBytes))
+			return
+		}
+		ingestError(c, apperrors.Validation("Failed to read request body"))
+		return
+	}
+
+	var logs []*models.Log
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &logs); err != nil {
+			ingestError(c, apperrors.Validation("Invalid JSON array of logs"))
+			return
+		}
+	} else {
+		var log models.Log
+		if err := json.Unmarshal(trimmed, &log); err != nil {
+			ingestError(c, apperrors.Validation("Invalid JSON log"))
+			return
+		}
+		logs = append(logs, &log)
+	}
+
+	if len(logs) == 0 {
+		ingestError(c, apperrors.Validation("No logs provided"))
+		return
+	}
+	if len(logs) > s.maxBatchSize {
+		ingestError(c, apperrors.Validation("Batch exceeds %d logs", s.maxBatchSize))
+		return
+	}
+
+	now := time.Now()
+	for i, log := range logs {
+		if log == nil {
+			ingestError(c, apperrors.Validation("Log %d: must be an object", i))
+			return
+		}
+		s.collector.CaptureBodies(log)
+		if err := log.Validate(); err != nil {
+			ingestError(c, apperrors.Validation("Log %d: %v", i, err))
+			return
+		}
+		if log.Tenant != nil && !s.collector.HasTenant(*log.Tenant) {
+			ingestError(c, apperrors.Validation("Log %d: unknown tenant %q", i, *log.Tenant))
+			return
+		}
+		// Server-assigned fields are not accepted from clients
+		log.ID = 0
+		log.CreatedAt = time.Time{}
+		if log.Timestamp.IsZero() {
+			log.Timestamp = now
+		}
+	}
+
+	if len(logs) == 1 {
+		err = s.collector.SendLog(c.Request.Context(), logs[0])
+	} else {
+		err = s.collector.SendLogs(c.Request.Context(), logs)
+	}
+	if err != nil {
+		s.logger.Error("Failed to publish ingested logs", "error", err, "count", len(logs))
+		ingestError(c, apperrors.Unavailable("Failed to publish logs"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"accepted": len(logs)})
+}
+
+// ingestError writes an error response in the same shape as the API server
+func ingestError(c *gin.Context, err *apperrors.Error) {
+	c.JSON(apperrors.HTTPStatus(err), gin.H{"error": err.Message, "code": err.Code})
+}
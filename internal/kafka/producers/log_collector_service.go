@@ -2,15 +2,19 @@ package producers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/adeesh/log-analytics/internal/config"
 	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/health"
+	"github.com/adeesh/log-analytics/internal/kafka/serde"
+	"github.com/adeesh/log-analytics/internal/metrics"
 	"github.com/adeesh/log-analytics/internal/models"
 	"log/slog"
 	"math/rand"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -20,31 +24,125 @@ import (
 
 // LogCollectorService represents the log collection service with integrated producer
 type LogCollectorService struct {
-	producer sarama.SyncProducer
-	topic    string
-	logger   *slog.Logger
+	producer      sarama.SyncProducer
+	async         *asyncPublisher // set in async mode instead of producer
+	topic         string
+	priorityTopic string            // ERROR and FATAL logs are sent here when set
+	tenantTopics  map[string]string // tenant -> dedicated topic
+	serializer    *serde.Serializer
+	bodies        *bodyCapture
+	sampler       *sampler
+	statuses      *statusDistribution
+	cfg           config.CollectorConfig
+	metrics       config.MetricsConfig
+	health        config.HealthConfig
+	kafka         sarama.Client // checked by the readiness probe; nil when the probes are disabled
+	spool         *spool        // keeps the logs that can't be delivered; nil when spooling is disabled
+	logger        *slog.Logger
 }
 
 // NewLogCollectorService creates a new log collector service
 func NewLogCollectorService(cfg *config.Config, logger *slog.Logger) (*LogCollectorService, error) {
+	if err := cfg.Generator.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid generator configuration: %w", err)
+	}
+	if err := cfg.Collector.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid collector configuration: %w", err)
+	}
+	if err := cfg.Kafka.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka configuration: %w", err)
+	}
+
 	// Create Kafka producer configuration
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = constants.DefaultProducerRetryMax
 	config.Producer.Return.Successes = true
-	config.Producer.Compression = sarama.CompressionSnappy
+	config.Producer.Compression = compressionFor(cfg.Collector.Compression)
+	config.Producer.Partitioner = partitionerFor(&cfg.Collector)
 
-	// Create producer
-	producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create producer: %w", err)
+	s := &LogCollectorService{
+		topic:         cfg.Kafka.Topic,
+		priorityTopic: cfg.Kafka.PriorityTopic,
+		tenantTopics:  make(map[string]string, len(cfg.Kafka.TenantTopics)),
+		serializer:    serde.NewSerializer(&cfg.Kafka),
+		bodies:        newBodyCapture(&cfg.Collector),
+		sampler:       newSampler(&cfg.Collector),
+		statuses:      newStatusDistribution(cfg.Generator.StatusWeights),
+		cfg:           cfg.Collector,
+		metrics:       cfg.Metrics,
+		health:        cfg.Health,
+		logger:        logger,
+	}
+	if s.priorityTopic != "" {
+		logger.Info("Priority lane enabled", "topic", s.priorityTopic)
+	}
+	for _, mapping := range cfg.Kafka.TenantTopics {
+		s.tenantTopics[mapping.Tenant] = mapping.Topic
+	}
+	if len(s.tenantTopics) > 0 {
+		logger.Info("Tenant topics enabled", "tenants", len(s.tenantTopics))
+	}
+	if cfg.Kafka.MessageFormat == constants.MessageFormatAvro {
+		logger.Info("Producing Avro messages", "schema_registry", cfg.Kafka.SchemaRegistry.URL)
+	}
+	if s.bodies.Enabled() {
+		logger.Info("Body capture enabled", "services", cfg.Collector.BodyServices, "max_bytes", cfg.Collector.BodyMaxBytes)
+	}
+	if cfg.Collector.PartitionKey != constants.PartitionKeyTraceID || cfg.Collector.Partitioner != constants.PartitionerHash {
+		logger.Info("Partitioning configured", "partition_key", cfg.Collector.PartitionKey, "partitioner", cfg.Collector.Partitioner)
+	}
+	if s.sampler.Enabled() {
+		logger.Info("Sampling enabled", "sample_rates", cfg.Collector.SampleRates, "rate_limit", cfg.Collector.RateLimit,
+			"service_rate_limits", cfg.Collector.ServiceRateLimits)
 	}
 
-	return &LogCollectorService{
-		producer: producer,
-		topic:    cfg.Kafka.Topic,
-		logger:   logger,
-	}, nil
+	// Logs that can't be delivered while Kafka is unreachable are spooled to disk, and delivered once it is back
+	if cfg.Collector.SpoolDir != "" {
+		spool, err := newSpool(cfg.Collector.SpoolDir, cfg.Collector.SpoolMaxBytes, logger)
+		if err != nil {
+			return nil, err
+		}
+		s.spool = spool
+		logger.Info("Spooling enabled", "dir", cfg.Collector.SpoolDir, "max_bytes", cfg.Collector.SpoolMaxBytes)
+	}
+
+	// Async mode batches sends in the background instead of blocking on every message
+	if cfg.Collector.AsyncProducer {
+		config.Producer.Return.Errors = true
+		config.Producer.Flush.Messages = cfg.Collector.FlushMessages
+		config.Producer.Flush.Frequency = cfg.Collector.FlushFrequency
+		config.ChannelBufferSize = cfg.Collector.BufferSize
+
+		async, err := newAsyncPublisher(cfg.Kafka.Brokers, config, cfg.Collector.BufferSize, logger)
+		if err != nil {
+			return nil, err
+		}
+		if s.spool != nil {
+			async.fallback = func(message *sarama.ProducerMessage) bool {
+				return s.spool.write([]*sarama.ProducerMessage{message}) == nil
+			}
+		}
+		s.async = async
+	} else {
+		// Create producer
+		producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create producer: %w", err)
+		}
+		s.producer = producer
+	}
+
+	// The readiness probe checks Kafka through a client of its own, since the producers don't expose theirs
+	if s.health.Enabled {
+		client, err := sarama.NewClient(cfg.Kafka.Brokers, config)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to create kafka client: %w", err)
+		}
+		s.kafka = client
+	}
+	return s, nil
 }
 
 // Start starts the log collector service
@@ -65,24 +163,98 @@ func (s *LogCollectorService) Start(ctx context.Context) error {
 		cancel()
 	}()
 
+	// Sources are waited for on shutdown so none sends after the producer is closed
+	var wg sync.WaitGroup
+	run := func(name string, start func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := start(ctx); err != nil {
+				s.logger.Error(name+" error", "error", err)
+				cancel()
+			}
+		}()
+	}
+
+	// Expose pipeline metrics for scraping
+	if s.metrics.Enabled {
+		run("Metrics server", func(ctx context.Context) error {
+			return metrics.Serve(ctx, s.metrics.CollectorPort, s.logger)
+		})
+	}
+
+	// Answer liveness and readiness probes, ready while Kafka accepts connections
+	if s.health.Enabled {
+		run("Health server", func(ctx context.Context) error {
+			return health.Serve(ctx, s.health.CollectorPort, map[string]health.Check{
+				constants.ProbeCheckKafka: health.Kafka(s.kafka, s.topic),
+			}, s.logger)
+		})
+	}
+
+	// Deliver the spooled logs once Kafka is back
+	if s.spool != nil {
+		run("Spool drainer", s.drainSpool)
+	}
+
+	// Accept logs from real applications over HTTP
+	if s.cfg.IngestEnabled {
+		run("Log ingestion server", NewIngestServer(s, &s.cfg, s.logger).Start)
+	}
+
+	// Accept logs from syslog-only devices
+	if syslogServer := NewSyslogServer(s, &s.cfg, s.logger); syslogServer.Enabled() {
+		run("Syslog listener", syslogServer.Start)
+	}
+
+	// Accept logs from applications instrumented with OpenTelemetry SDKs
+	if otlpServer := NewOTLPServer(s, &s.cfg, s.logger); otlpServer.Enabled() {
+		run("OTLP receiver", otlpServer.Start)
+	}
+
+	// Follow application log files on disk
+	if fileTailer := NewFileTailer(s, &s.cfg, s.logger); fileTailer.Enabled() {
+		run("File tailer", fileTailer.Start)
+	}
+
 	// Start generating sample logs
-	go s.generateSampleLogs(ctx)
+	if s.cfg.GenerateSamples {
+		run("Sample generator", func(ctx context.Context) error {
+			s.generateSampleLogs(ctx)
+			return nil
+		})
+	}
 
 	// Wait for context cancellation
 	<-ctx.Done()
+	wg.Wait()
 	s.logger.Info("Log collector service stopped")
 	return nil
 }
 
-// Close closes the service and its resources
+// Reload applies the sample rates and rate limits of a reloaded configuration to the logs collected from then on
+func (s *LogCollectorService) Reload(cfg *config.Config) {
+	s.sampler.update(&cfg.Collector)
+}
+
+// Close closes the service and its resources, flushing buffered logs in async mode
 func (s *LogCollectorService) Close() error {
+	if s.kafka != nil {
+		s.kafka.Close()
+	}
+	// The spool is closed last, since the async producer spools the messages it fails to flush
+	if s.spool != nil {
+		defer s.spool.close()
+	}
+	if s.async != nil {
+		return s.async.close()
+	}
 	return s.producer.Close()
 }
 
 // generateSampleLogs generates and sends sample logs to Kafka
 func (s *LogCollectorService) generateSampleLogs(ctx context.Context) {
 	services := []string{constants.ServiceAPIGateway, constants.ServiceUserService, constants.ServicePaymentService, constants.ServiceOrderService, constants.ServiceNotificationService}
-	levels := []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal}
 	methods := []string{constants.MethodGET, constants.MethodPOST, constants.MethodPUT, constants.MethodDELETE}
 	paths := []string{constants.PathAPIUsers, constants.PathAPIOrders, constants.PathAPIPayments, constants.PathAPIProducts, constants.PathAPIAuth}
 
@@ -98,7 +270,8 @@ func (s *LogCollectorService) generateSampleLogs(ctx context.Context) {
 			count := rand.Intn(constants.MaxLogsPerSecond)
 
 			for i := 0; i < count; i++ {
-				log := s.generateRandomLog(services, levels, methods, paths)
+				log := s.generateRandomLog(services, methods, paths)
+				sampleDeployments.assign(log)
 
 				// Send individual log
 				if err := s.SendLog(ctx, log); err != nil {
@@ -110,17 +283,20 @@ func (s *LogCollectorService) generateSampleLogs(ctx context.Context) {
 }
 
 // generateRandomLog creates a random log entry for testing
-func (s *LogCollectorService) generateRandomLog(services []string, levels []models.LogLevel, methods []string, paths []string) *models.Log {
+func (s *LogCollectorService) generateRandomLog(services []string, methods []string, paths []string) *models.Log {
 	service := services[rand.Intn(len(services))]
-	level := levels[rand.Intn(len(levels))]
+	// The status is drawn from the configured distribution and the level follows from its class, so that the
+	// weights set the share of every status among all sample logs
+	responseStatus := s.statuses.pick()
+	level := sampleLevel(responseStatus)
 	method := methods[rand.Intn(len(methods))]
 	path := paths[rand.Intn(len(paths))]
 	traceID := uuid.New().String()
-	userID := fmt.Sprintf(constants.UserIDFormat, rand.Intn(constants.MaxUserID)+1)
+	spanID := fmt.Sprintf(constants.SpanIDFormat, rand.Uint64()) // every sample log is the root span of its trace
+	userNumber := rand.Intn(constants.MaxUserID) + 1
+	userID := fmt.Sprintf(constants.UserIDFormat, userNumber)
+	clientIP := sampleClientIP(userNumber)
 	responseTime := rand.Intn(constants.MaxResponseTime-constants.MinResponseTime+1) + constants.MinResponseTime
-	responseStatus := constants.StatusOK
-
-	// Generate appropriate message based on level
 	var message string
 	switch level {
 	case models.LogLevelDebug:
@@ -132,11 +308,9 @@ func (s *LogCollectorService) generateRandomLog(services []string, levels []mode
 		responseTime = rand.Intn(constants.WarningMaxResponseTime-constants.WarningMinResponseTime+1) + constants.WarningMinResponseTime
 	case models.LogLevelError:
 		message = fmt.Sprintf(constants.ErrorMessageTemplate, method, path)
-		responseStatus = constants.StatusError
 		responseTime = rand.Intn(constants.ErrorMaxResponseTime-constants.ErrorMinResponseTime+1) + constants.ErrorMinResponseTime
 	case models.LogLevelFatal:
 		message = fmt.Sprintf(constants.FatalMessageTemplate, service)
-		responseStatus = constants.StatusError
 		responseTime = rand.Intn(constants.FatalMaxResponseTime-constants.FatalMinResponseTime+1) + constants.FatalMinResponseTime
 	}
 
@@ -160,7 +334,9 @@ func (s *LogCollectorService) generateRandomLog(services []string, levels []mode
 		Service:        service,
 		Message:        message,
 		TraceID:        &traceID,
+		SpanID:         &spanID,
 		UserID:         &userID,
+		ClientIP:       &clientIP,
 		RequestMethod:  &method,
 		RequestPath:    &path,
 		ResponseStatus: &responseStatus,
@@ -169,39 +345,327 @@ func (s *LogCollectorService) generateRandomLog(services []string, levels []mode
 	}
 }
 
-// SendLog sends a log message to Kafka
-func (s *LogCollectorService) SendLog(_ context.Context, log *models.Log) error {
+// SendLog sends a log message to Kafka, unless it is sampled out
+func (s *LogCollectorService) SendLog(ctx context.Context, log *models.Log) error {
+	if !s.sampler.keep(log) {
+		return nil
+	}
+	message, err := s.BuildMessage(log)
+	if err != nil {
+		return err
+	}
+	return s.deliver(ctx, []*sarama.ProducerMessage{message})
+}
 
-	// Generate message ID if not present
+// SendLogs sends multiple log messages to Kafka in a single request, except those sampled out
+func (s *LogCollectorService) SendLogs(ctx context.Context, logs []*models.Log) error {
+	logs = s.sampler.filter(logs)
+	if len(logs) == 0 {
+		return nil
+	}
+	messages := make([]*sarama.ProducerMessage, 0, len(logs))
+	for _, log := range logs {
+		message, err := s.BuildMessage(log)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+	return s.deliver(ctx, messages)
+}
+
+// deliver publishes messages to Kafka. With spooling enabled, messages that fail to be delivered are spooled
+// instead, and while any are spooled, new ones queue up behind them so that logs reach Kafka in order.
+func (s *LogCollectorService) deliver(ctx context.Context, messages []*sarama.ProducerMessage) error {
+	if s.spool != nil && s.spool.pending() {
+		return s.spool.write(messages)
+	}
+	err := s.publish(ctx, messages)
+	if err == nil || s.spool == nil || s.async != nil {
+		return err
+	}
+
+	var producerErrs sarama.ProducerErrors
+	failed := messages
+	if errors.As(err, &producerErrs) {
+		failed = make([]*sarama.ProducerMessage, 0, len(producerErrs))
+		for _, producerErr := range producerErrs {
+			failed = append(failed, producerErr.Msg)
+		}
+	}
+	if spoolErr := s.spool.write(failed); spoolErr != nil {
+		return fmt.Errorf("%w; %w", err, spoolErr)
+	}
+	s.logger.Warn("Failed to deliver logs, spooled them to disk", "error", err, "count", len(failed))
+	return nil
+}
+
+// publish sends messages to Kafka, queueing them in async mode
+func (s *LogCollectorService) publish(ctx context.Context, messages []*sarama.ProducerMessage) error {
+	if s.async != nil {
+		return s.async.publish(ctx, messages...)
+	}
+
+	err := s.producer.SendMessages(messages)
+	recordDeliveries(messages, err)
+	if err != nil {
+		return fmt.Errorf("failed to send messages: %w", err)
+	}
+
+	s.logger.Debug("Logs sent", "count", len(messages))
+	return nil
+}
+
+// drainSpool delivers the spooled logs, checking for them every drain interval, until the context is done
+func (s *LogCollectorService) drainSpool(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.SpoolDrainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if !s.spool.pending() {
+			continue
+		}
+		if err := s.spool.drain(func(messages []*sarama.ProducerMessage) error {
+			return s.publish(ctx, messages)
+		}); err != nil {
+			s.logger.Warn("Failed to deliver spooled logs, retrying", "error", err, "interval", s.cfg.SpoolDrainInterval)
+			continue
+		}
+		s.logger.Info("Delivered spooled logs")
+	}
+}
+
+// recordDeliveries counts the delivered and failed messages of a sync batch send. Sarama reports
+// the failed messages of a partially delivered batch; any other error means none were delivered.
+func recordDeliveries(messages []*sarama.ProducerMessage, err error) {
+	failed := make(map[*sarama.ProducerMessage]bool)
+	var producerErrs sarama.ProducerErrors
+	switch {
+	case err == nil:
+	case errors.As(err, &producerErrs):
+		for _, producerErr := range producerErrs {
+			failed[producerErr.Msg] = true
+		}
+	default:
+		for _, message := range messages {
+			failed[message] = true
+		}
+	}
+
+	for _, message := range messages {
+		if failed[message] {
+			metrics.ProduceErrors.WithLabelValues(message.Topic).Inc()
+		} else {
+			metrics.LogsProduced.WithLabelValues(message.Topic).Inc()
+		}
+	}
+}
+
+// CaptureBodies drops, or redacts and truncates, the bodies of a log according to the body capture settings.
+// Logs are captured again when published, so sources only call it to validate logs as they will be published.
+func (s *LogCollectorService) CaptureBodies(log *models.Log) {
+	s.bodies.apply(log)
+}
+
+// HasTenant reports whether the tenant has a dedicated topic
+func (s *LogCollectorService) HasTenant(tenant string) bool {
+	_, ok := s.tenantTopics[tenant]
+	return ok
+}
+
+// topicFor returns the topic of a log: its tenant's topic, the priority topic for ERROR and FATAL logs
+// when configured, or the log topic. Tenant logs always go to the tenant's topic so tenants stay isolated.
+func (s *LogCollectorService) topicFor(log *models.Log) string {
+	if log.Tenant != nil {
+		if topic, ok := s.tenantTopics[*log.Tenant]; ok {
+			return topic
+		}
+	}
+	if s.priorityTopic != "" && (log.Level == models.LogLevelError || log.Level == models.LogLevelFatal) {
+		return s.priorityTopic
+	}
+	return s.topic
+}
+
+// keyFor returns the key of a log's message according to the partition key strategy, nil for unkeyed messages
+func (s *LogCollectorService) keyFor(log *models.Log) sarama.Encoder {
+	switch s.cfg.PartitionKey {
+	case constants.PartitionKeyService:
+		return sarama.StringEncoder(log.Service)
+	case constants.PartitionKeyTenant:
+		if log.Tenant != nil {
+			return sarama.StringEncoder(*log.Tenant)
+		}
+		return sarama.StringEncoder(log.Service)
+	case constants.PartitionKeyRoundRobin:
+		return nil
+	default:
+		return sarama.StringEncoder(*log.TraceID)
+	}
+}
+
+// compressionFor returns the codec of a producer compression setting
+func compressionFor(compression string) sarama.CompressionCodec {
+	switch compression {
+	case constants.ProducerCompressionNone:
+		return sarama.CompressionNone
+	case constants.ProducerCompressionGzip:
+		return sarama.CompressionGZIP
+	case constants.ProducerCompressionLZ4:
+		return sarama.CompressionLZ4
+	case constants.ProducerCompressionZstd:
+		return sarama.CompressionZSTD
+	default:
+		return sarama.CompressionSnappy
+	}
+}
+
+// partitionerFor returns the partitioner mapping messages to partitions. Unkeyed messages are spread round-robin
+// rather than at random, as hash partitioners do.
+func partitionerFor(cfg *config.CollectorConfig) sarama.PartitionerConstructor {
+	if cfg.PartitionKey == constants.PartitionKeyRoundRobin {
+		return sarama.NewRoundRobinPartitioner
+	}
+	switch cfg.Partitioner {
+	case constants.PartitionerMurmur2:
+		return sarama.NewReferenceHashPartitioner
+	case constants.PartitionerCRC32:
+		return sarama.NewConsistentCRCHashPartitioner
+	default:
+		return sarama.NewHashPartitioner
+	}
+}
+
+// BuildMessage serializes a log into the Kafka message SendLog publishes, keyed according to the partition key
+// strategy. Bodies are dropped, or redacted and truncated, according to the body capture settings.
+func (s *LogCollectorService) BuildMessage(log *models.Log) (*sarama.ProducerMessage, error) {
+	s.bodies.apply(log)
+
+	// Generate trace ID if not present
 	if log.TraceID == nil {
 		traceID := uuid.New().String()
 		log.TraceID = &traceID
 	}
 
-	// Serialize log to JSON
-	value, err := json.Marshal(log)
+	// Identify the log so that the processor stores it once even if the producer sends it twice
+	if log.MessageID == nil {
+		messageID := uuid.New().String()
+		log.MessageID = &messageID
+	}
+
+	// Serialize the log in the configured format; Avro registers the log schema for the topic on first use
+	topic := s.topicFor(log)
+	value, err := s.serializer.Serialize(context.Background(), topic, log)
 	if err != nil {
-		return fmt.Errorf("failed to marshal log: %w", err)
+		return nil, fmt.Errorf("failed to marshal log: %w", err)
 	}
 
 	// Create Kafka message
-	message := &sarama.ProducerMessage{
-		Topic: s.topic,
-		Key:   sarama.StringEncoder(*log.TraceID),
+	return &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   s.keyFor(log),
 		Value: sarama.ByteEncoder(value),
-		Headers: []sarama.RecordHeader{
+		Headers: append([]sarama.RecordHeader{
 			{Key: []byte(constants.HeaderService), Value: []byte(log.Service)},
 			{Key: []byte(constants.HeaderLevel), Value: []byte(string(log.Level))},
 			{Key: []byte(constants.HeaderTimestamp), Value: []byte(log.Timestamp.Format(time.RFC3339))},
-		},
+		}, serde.TraceHeaders(log)...),
+	}, nil
+}
+
+// statusDistribution picks HTTP status codes according to configured weights, each weight being the status's share
+// of all generated logs
+type statusDistribution struct {
+	weights []config.StatusWeight
+	total   int
+}
+
+func newStatusDistribution(weights []config.StatusWeight) *statusDistribution {
+	d := &statusDistribution{weights: weights}
+	for _, weight := range weights {
+		d.total += weight.Weight
 	}
+	return d
+}
 
-	// Send message
-	partition, offset, err := s.producer.SendMessage(message)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+// pick returns a weighted random status, or 200 when no weights are configured
+func (d *statusDistribution) pick() int {
+	if d.total <= 0 {
+		return constants.StatusOK
 	}
+	n := rand.Intn(d.total)
+	for _, weight := range d.weights {
+		if n < weight.Weight {
+			return weight.Status
+		}
+		n -= weight.Weight
+	}
+	return constants.StatusOK
+}
 
-	s.logger.Debug("Log sent", "topic", s.topic, "partition", partition, "offset", offset)
-	return nil
+// sampleLevel returns a level fitting the class of a status: DEBUG or INFO for 1xx-3xx, WARN for 4xx, and ERROR or
+// FATAL for 5xx
+func sampleLevel(status int) models.LogLevel {
+	var levels []models.LogLevel
+	switch status / 100 {
+	case 4:
+		levels = []models.LogLevel{models.LogLevelWarn}
+	case 5:
+		levels = []models.LogLevel{models.LogLevelError, models.LogLevelFatal}
+	default:
+		levels = []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo}
+	}
+	return levels[rand.Intn(len(levels))]
+}
+
+// sampleDeployment is an environment and region the sample services run in, with the number of
+// instances of each service there and the share of traffic it receives
+type sampleDeployment struct {
+	environment string
+	region      string
+	instances   int
+	weight      int
+}
+
+// sampleTopology spreads sample traffic over deployments by weight
+type sampleTopology []sampleDeployment
+
+// sampleDeployments puts most traffic on production in three regions, with a little staging and
+// development traffic in a single region
+var sampleDeployments = sampleTopology{
+	{constants.EnvironmentProduction, constants.RegionUSEast1, 4, 40},
+	{constants.EnvironmentProduction, constants.RegionEUWest1, 3, 25},
+	{constants.EnvironmentProduction, constants.RegionAPSouth1, 2, 15},
+	{constants.EnvironmentStaging, constants.RegionUSEast1, 2, 12},
+	{constants.EnvironmentDevelopment, constants.RegionUSEast1, 1, 8},
+}
+
+// assign sets the host, environment and region of a log from a weighted random deployment
+func (t sampleTopology) assign(log *models.Log) {
+	total := 0
+	for _, deployment := range t {
+		total += deployment.weight
+	}
+	n := rand.Intn(total)
+	for _, deployment := range t {
+		if n >= deployment.weight {
+			n -= deployment.weight
+			continue
+		}
+		host := fmt.Sprintf(constants.SampleHostFormat, log.Service, deployment.environment, deployment.region, rand.Intn(deployment.instances)+1)
+		log.Host = &host
+		log.Environment = &deployment.environment
+		log.Region = &deployment.region
+		return
+	}
+}
+
+// sampleClientIP returns the client address of a sample user; a user keeps the same address
+func sampleClientIP(userNumber int) string {
+	networks := []string{constants.SampleClientNetworkA, constants.SampleClientNetworkB, constants.SampleClientNetworkC}
+	return fmt.Sprintf(networks[userNumber%len(networks)], userNumber%254+1)
 }
@@ -0,0 +1,75 @@
+package producers
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+func TestStatusDistributionPick(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []config.StatusWeight
+		want    map[int]float64 // share of each status
+	}{
+		{name: "no weights", want: map[int]float64{200: 1}},
+		{name: "single status", weights: []config.StatusWeight{{Status: 503, Weight: 4}}, want: map[int]float64{503: 1}},
+		{
+			name:    "shares across classes",
+			weights: []config.StatusWeight{{Status: 200, Weight: 6}, {Status: 404, Weight: 3}, {Status: 500, Weight: 1}},
+			want:    map[int]float64{200: 0.6, 404: 0.3, 500: 0.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const draws = 20000
+			d := newStatusDistribution(tt.weights)
+			counts := make(map[int]int)
+			for range draws {
+				counts[d.pick()]++
+			}
+			for status, count := range counts {
+				if _, ok := tt.want[status]; !ok {
+					t.Errorf("picked status %d %d times, want never", status, count)
+				}
+			}
+			for status, share := range tt.want {
+				if got := float64(counts[status]) / draws; math.Abs(got-share) > 0.02 {
+					t.Errorf("status %d picked for %.3f of logs, want %.3f", status, got, share)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleLevel(t *testing.T) {
+	tests := []struct {
+		status int
+		want   []models.LogLevel
+	}{
+		{status: 101, want: []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo}},
+		{status: 200, want: []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo}},
+		{status: 304, want: []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo}},
+		{status: 429, want: []models.LogLevel{models.LogLevelWarn}},
+		{status: 503, want: []models.LogLevel{models.LogLevelError, models.LogLevelFatal}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			seen := make(map[models.LogLevel]bool)
+			for range 200 {
+				level := sampleLevel(tt.status)
+				if !slices.Contains(tt.want, level) {
+					t.Fatalf("sampleLevel(%d) = %s, want one of %v", tt.status, level, tt.want)
+				}
+				seen[level] = true
+			}
+			if len(seen) != len(tt.want) {
+				t.Errorf("sampleLevel(%d) only returned %v, want all of %v", tt.status, seen, tt.want)
+			}
+		})
+	}
+}
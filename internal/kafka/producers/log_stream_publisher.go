@@ -0,0 +1,70 @@
+package producers
+
+import (
+	"encoding/json"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+)
+
+// LogStreamPublisher publishes stored logs to the stream topic followed by the API server's live tail.
+// The live tail is best effort: logs are dropped rather than delaying storage when the topic falls behind.
+type LogStreamPublisher struct {
+	publisher *asyncPublisher
+	topic     string
+	dropped   atomic.Int64
+	logger    *slog.Logger
+}
+
+// NewLogStreamPublisher creates a new log stream publisher
+func NewLogStreamPublisher(cfg *config.KafkaConfig, logger *slog.Logger) (*LogStreamPublisher, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Retry.Max = constants.DefaultProducerRetryMax
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Compression = sarama.CompressionSnappy
+	config.ChannelBufferSize = constants.DefaultStreamProducerBufferSize
+
+	publisher, err := newAsyncPublisher(cfg.Brokers, config, constants.DefaultStreamProducerBufferSize, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogStreamPublisher{
+		publisher: publisher,
+		topic:     cfg.StreamTopic,
+		logger:    logger,
+	}, nil
+}
+
+// Publish queues stored logs for the stream topic without blocking, dropping them when the buffer is full
+func (p *LogStreamPublisher) Publish(logs []*models.Log) {
+	for _, log := range logs {
+		value, err := json.Marshal(log)
+		if err != nil {
+			p.logger.Warn("Failed to marshal log for the live tail", "error", err)
+			continue
+		}
+		message := &sarama.ProducerMessage{
+			Topic: p.topic,
+			Key:   sarama.StringEncoder(log.Service),
+			Value: sarama.ByteEncoder(value),
+		}
+		if !p.publisher.tryPublish(message) {
+			p.dropped.Add(1)
+		}
+	}
+}
+
+// Close flushes queued logs and closes the producer
+func (p *LogStreamPublisher) Close() error {
+	if dropped := p.dropped.Load(); dropped > 0 {
+		p.logger.Warn("Logs dropped from the live tail", "count", dropped)
+	}
+	return p.publisher.close()
+}
@@ -0,0 +1,215 @@
+package producers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// otlpLevel maps an OpenTelemetry severity number, or the severity text when the number is unset, to a log level
+func otlpLevel(number logspb.SeverityNumber, text string) models.LogLevel {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return models.LogLevelFatal
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return models.LogLevelError
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return models.LogLevelWarn
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return models.LogLevelInfo
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return models.LogLevelDebug
+	}
+
+	text = strings.ToUpper(text)
+	switch {
+	case strings.HasPrefix(text, "FATAL"), strings.HasPrefix(text, "CRIT"), strings.HasPrefix(text, "EMERG"):
+		return models.LogLevelFatal
+	case strings.HasPrefix(text, "ERR"):
+		return models.LogLevelError
+	case strings.HasPrefix(text, "WARN"):
+		return models.LogLevelWarn
+	case strings.HasPrefix(text, "DEBUG"), strings.HasPrefix(text, "TRACE"):
+		return models.LogLevelDebug
+	default:
+		return models.LogLevelInfo
+	}
+}
+
+// parseOTLPRecord maps a log record and the attributes of its resource to a log. service.name and the
+// semantic convention attributes matching log fields are mapped to those fields, and the remaining
+// attributes are stored as attributes, the record's taking precedence over the resource's.
+func parseOTLPRecord(resource []*commonpb.KeyValue, record *logspb.LogRecord, now time.Time) *models.Log {
+	log := &models.Log{
+		Timestamp: now,
+		Level:     otlpLevel(record.GetSeverityNumber(), record.GetSeverityText()),
+		Service:   constants.OTLPDefaultService,
+		Message:   otlpValueString(record.GetBody()),
+	}
+	if nanos := record.GetTimeUnixNano(); nanos > 0 {
+		log.Timestamp = time.Unix(0, int64(nanos))
+	} else if nanos := record.GetObservedTimeUnixNano(); nanos > 0 {
+		log.Timestamp = time.Unix(0, int64(nanos))
+	}
+	if traceID := record.GetTraceId(); len(traceID) > 0 && !allZero(traceID) {
+		id := hex.EncodeToString(traceID)
+		log.TraceID = &id
+	}
+	if spanID := record.GetSpanId(); len(spanID) > 0 && !allZero(spanID) {
+		id := hex.EncodeToString(spanID)
+		log.SpanID = &id
+	}
+
+	for _, attributes := range [][]*commonpb.KeyValue{resource, record.GetAttributes()} {
+		for _, attribute := range attributes {
+			value := otlpValueString(attribute.GetValue())
+			switch attribute.GetKey() {
+			case constants.OTLPAttributeServiceName:
+				log.Service = value
+			case constants.OTLPAttributeTenant:
+				log.Tenant = &value
+			case constants.OTLPAttributeParentSpanID:
+				// Log records don't carry the parent of their span, which instrumentations may add as an attribute
+				log.ParentSpanID = &value
+			case constants.OTLPAttributeUserID, constants.OTLPAttributeEndUserID:
+				log.UserID = &value
+			case constants.OTLPAttributeHTTPMethod:
+				log.RequestMethod = &value
+			case constants.OTLPAttributeURLPath:
+				log.RequestPath = &value
+			case constants.OTLPAttributeHostName:
+				log.Host = &value
+			case constants.OTLPAttributeEnvironment, constants.OTLPAttributeEnvironmentOld:
+				log.Environment = &value
+			case constants.OTLPAttributeCloudRegion:
+				log.Region = &value
+			case constants.OTLPAttributeClientAddr:
+				// client.address may also be a host name, which is kept as an attribute
+				if net.ParseIP(value) != nil {
+					log.ClientIP = &value
+				} else {
+					log.Attributes.Set(attribute.GetKey(), value)
+				}
+			case constants.OTLPAttributeRequestBody:
+				log.RequestBody = &value
+			case constants.OTLPAttributeResponseBody:
+				log.ResponseBody = &value
+			case constants.OTLPAttributeHTTPStatus:
+				if status, err := strconv.Atoi(value); err == nil {
+					log.ResponseStatus = &status
+				} else {
+					log.Attributes.Set(attribute.GetKey(), value)
+				}
+			default:
+				log.Attributes.Set(attribute.GetKey(), value)
+			}
+		}
+	}
+	if len(log.Service) > constants.MaxServiceLength {
+		log.Service = log.Service[:constants.MaxServiceLength]
+	}
+	return log
+}
+
+// otlpValueString renders an attribute or body value as a string; arrays and maps are rendered as JSON
+func otlpValueString(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case nil:
+		return ""
+	default:
+		data, err := json.Marshal(otlpValue(value))
+		if err != nil {
+			return fmt.Sprint(otlpValue(value))
+		}
+		return string(data)
+	}
+}
+
+// otlpValue converts an attribute or body value to the equivalent Go value
+func otlpValue(value *commonpb.AnyValue) any {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, element := range v.ArrayValue.GetValues() {
+			values[i] = otlpValue(element)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = otlpValue(kv.GetValue())
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// allZero reports whether an ID is all zero bytes, which OpenTelemetry uses for an unset trace or span ID
+func allZero(id []byte) bool {
+	for _, b := range id {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeOTLPJSON rewrites the hex-encoded trace and span IDs of an OTLP/JSON request as base64,
+// the encoding protojson expects for bytes fields
+func normalizeOTLPJSON(body []byte) ([]byte, error) {
+	// Numbers are kept as written so nanosecond timestamps survive the round trip
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var request map[string]any
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+	resourceLogs, _ := request["resourceLogs"].([]any)
+	for _, resourceLog := range resourceLogs {
+		resourceLog, _ := resourceLog.(map[string]any)
+		scopeLogs, _ := resourceLog["scopeLogs"].([]any)
+		for _, scopeLog := range scopeLogs {
+			scopeLog, _ := scopeLog.(map[string]any)
+			records, _ := scopeLog["logRecords"].([]any)
+			for _, record := range records {
+				record, _ := record.(map[string]any)
+				for _, key := range []string{"traceId", "spanId"} {
+					id, ok := record[key].(string)
+					if !ok || id == "" {
+						continue
+					}
+					decoded, err := hex.DecodeString(id)
+					if err != nil {
+						return nil, fmt.Errorf("invalid %s %q: %w", key, id, err)
+					}
+					record[key] = base64.StdEncoding.EncodeToString(decoded)
+				}
+			}
+		}
+	}
+	return json.Marshal(request)
+}
@@ -0,0 +1,269 @@
+package producers
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // accept gzip-compressed gRPC exports
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPServer receives OpenTelemetry log records over OTLP/HTTP and OTLP/gRPC and publishes them to Kafka
+type OTLPServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	collector    *LogCollectorService
+	httpAddr     string
+	grpcAddr     string
+	maxBodyBytes int64
+	logger       *slog.Logger
+}
+
+// NewOTLPServer creates a new OTLP receiver publishing through the collector.
+// An empty address disables the corresponding transport.
+func NewOTLPServer(collector *LogCollectorService, cfg *config.CollectorConfig, logger *slog.Logger) *OTLPServer {
+	s := &OTLPServer{
+		collector:    collector,
+		httpAddr:     cfg.OTLPHTTPAddr,
+		grpcAddr:     cfg.OTLPGRPCAddr,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		logger:       logger,
+	}
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = constants.DefaultIngestMaxBodyBytes
+	}
+	return s
+}
+
+// Enabled reports whether any OTLP transport is configured
+func (s *OTLPServer) Enabled() bool {
+	return s.httpAddr != "" || s.grpcAddr != ""
+}
+
+// Start serves export requests on the configured transports until the context is cancelled
+func (s *OTLPServer) Start(ctx context.Context) error {
+	var httpServer *http.Server
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	var err error
+
+	if s.grpcAddr != "" {
+		if grpcListener, err = net.Listen("tcp", s.grpcAddr); err != nil {
+			return fmt.Errorf("failed to listen for OTLP on grpc %s: %w", s.grpcAddr, err)
+		}
+		grpcServer = grpc.NewServer(grpc.MaxRecvMsgSize(int(s.maxBodyBytes)))
+		collogspb.RegisterLogsServiceServer(grpcServer, s)
+	}
+	if s.httpAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST "+constants.OTLPLogsPath, s.handleHTTP)
+		httpServer = &http.Server{
+			Addr:         s.httpAddr,
+			Handler:      mux,
+			ReadTimeout:  constants.DefaultServerReadTimeout,
+			WriteTimeout: constants.DefaultServerWriteTimeout,
+			IdleTimeout:  constants.DefaultServerIdleTimeout,
+		}
+	}
+
+	errChan := make(chan error, 2)
+	var wg sync.WaitGroup
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.logger.Info("Starting OTLP receiver", "transport", "grpc", "addr", s.grpcAddr)
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				errChan <- fmt.Errorf("failed to serve OTLP over grpc: %w", err)
+			}
+		}()
+	}
+	if httpServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.logger.Info("Starting OTLP receiver", "transport", "http", "addr", s.httpAddr, "path", constants.OTLPLogsPath)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errChan <- fmt.Errorf("failed to serve OTLP over http: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+	}
+
+	if httpServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.OTLPShutdownTimeout)
+		if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+			err = fmt.Errorf("failed to shut down OTLP http receiver: %w", shutdownErr)
+		}
+		cancel()
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	wg.Wait()
+	s.logger.Info("OTLP receiver stopped")
+	return err
+}
+
+// Export implements the OTLP/gRPC logs service
+func (s *OTLPServer) Export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	response, err := s.export(ctx, request)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return response, nil
+}
+
+// handleHTTP implements OTLP/HTTP, accepting binary protobuf and JSON requests, optionally gzip-compressed.
+// Responses use the encoding of the request.
+func (s *OTLPServer) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != constants.OTLPContentTypeProtobuf && contentType != constants.OTLPContentTypeJSON {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	var reader io.Reader = body
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			s.writeStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, "invalid gzip body")
+			return
+		}
+		defer gz.Close()
+		// Bound the decompressed size too, so small compressed bodies can't expand without limit
+		reader = io.LimitReader(gz, s.maxBodyBytes+1)
+	default:
+		s.writeStatus(w, contentType, http.StatusUnsupportedMediaType, codes.InvalidArgument, "unsupported content encoding")
+		return
+	}
+
+	data, err := io.ReadAll(reader)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || int64(len(data)) > s.maxBodyBytes {
+		s.writeStatus(w, contentType, http.StatusRequestEntityTooLarge, codes.InvalidArgument,
+			fmt.Sprintf("request body exceeds %d bytes", s.maxBodyBytes))
+		return
+	}
+	if err != nil {
+		s.writeStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, "failed to read request body")
+		return
+	}
+
+	request := &collogspb.ExportLogsServiceRequest{}
+	if contentType == constants.OTLPContentTypeJSON {
+		if data, err = normalizeOTLPJSON(data); err == nil {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, request)
+		}
+	} else {
+		err = proto.Unmarshal(data, request)
+	}
+	if err != nil {
+		s.writeStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, fmt.Sprintf("invalid export request: %v", err))
+		return
+	}
+
+	response, err := s.export(r.Context(), request)
+	if err != nil {
+		// 503 tells exporters to retry
+		s.writeStatus(w, contentType, http.StatusServiceUnavailable, codes.Unavailable, err.Error())
+		return
+	}
+	s.write(w, contentType, http.StatusOK, response)
+}
+
+// export publishes the valid log records of a request. Invalid records are rejected individually
+// and reported as a partial success, as the OTLP specification prescribes.
+func (s *OTLPServer) export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	now := time.Now()
+	var logs []*models.Log
+	var rejected int64
+	var firstErr error
+	for _, resourceLogs := range request.GetResourceLogs() {
+		resource := resourceLogs.GetResource().GetAttributes()
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				log := parseOTLPRecord(resource, record, now)
+				s.collector.CaptureBodies(log)
+				err := log.Validate()
+				if err == nil && log.Tenant != nil && !s.collector.HasTenant(*log.Tenant) {
+					err = fmt.Errorf("unknown tenant %q", *log.Tenant)
+				}
+				if err != nil {
+					rejected++
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				logs = append(logs, log)
+			}
+		}
+	}
+
+	if len(logs) > 0 {
+		if err := s.collector.SendLogs(ctx, logs); err != nil {
+			s.logger.Error("Failed to publish OTLP logs", "error", err, "count", len(logs))
+			return nil, fmt.Errorf("failed to publish logs")
+		}
+	}
+
+	response := &collogspb.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		s.logger.Warn("Rejected invalid OTLP log records", "rejected", rejected, "error", firstErr)
+		response.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: rejected,
+			ErrorMessage:       firstErr.Error(),
+		}
+	}
+	return response, nil
+}
+
+// writeStatus writes an error response as a google.rpc.Status message
+func (s *OTLPServer) writeStatus(w http.ResponseWriter, contentType string, httpStatus int, code codes.Code, message string) {
+	s.write(w, contentType, httpStatus, &statuspb.Status{Code: int32(code), Message: message})
+}
+
+// write encodes a response message in the given content type
+func (s *OTLPServer) write(w http.ResponseWriter, contentType string, httpStatus int, message proto.Message) {
+	var data []byte
+	var err error
+	if contentType == constants.OTLPContentTypeJSON {
+		data, err = protojson.Marshal(message)
+	} else {
+		data, err = proto.Marshal(message)
+	}
+	if err != nil {
+		s.logger.Error("Failed to encode OTLP response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(httpStatus)
+	w.Write(data)
+}
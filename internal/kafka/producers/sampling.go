@@ -0,0 +1,123 @@
+package producers
+
+import (
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampler decides which logs the collector publishes. Logs are first kept at the sample rate of their level, then
+// the logs of each service are capped to its rate limit. ERROR and FATAL logs are never rate limited, so a noisy
+// service can't crowd out its own errors.
+type sampler struct {
+	policy atomic.Pointer[samplingPolicy] // replaced as a whole by configuration reloads
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // service -> its rate limit's bucket
+}
+
+// samplingPolicy is the sample rates and rate limits the sampler applies
+type samplingPolicy struct {
+	rates  map[models.LogLevel]float64
+	limit  int
+	limits map[string]int // service -> logs per second, overriding limit
+}
+
+// tokenBucket allows up to its limit of logs per second, refilling continuously, with bursts of up to a second's worth
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newSampler creates the sampler of the configured sample rates and rate limits
+func newSampler(cfg *config.CollectorConfig) *sampler {
+	s := &sampler{buckets: make(map[string]*tokenBucket)}
+	s.update(cfg)
+	return s
+}
+
+// update switches to the sample rates and rate limits of the given configuration. The buckets of the services are
+// kept, and capped to the new limits as they refill.
+func (s *sampler) update(cfg *config.CollectorConfig) {
+	policy := &samplingPolicy{
+		rates:  make(map[models.LogLevel]float64, len(cfg.SampleRates)),
+		limit:  cfg.RateLimit,
+		limits: make(map[string]int, len(cfg.ServiceRateLimits)),
+	}
+	for _, rate := range cfg.SampleRates {
+		policy.rates[models.LogLevel(rate.Level)] = rate.Rate
+	}
+	for _, limit := range cfg.ServiceRateLimits {
+		policy.limits[limit.Service] = limit.Limit
+	}
+	s.policy.Store(policy)
+}
+
+// Enabled reports whether any log may be sampled out
+func (s *sampler) Enabled() bool {
+	policy := s.policy.Load()
+	if len(policy.rates) > 0 || policy.limit > 0 {
+		return true
+	}
+	for _, limit := range policy.limits {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether a log is published, counting those that aren't
+func (s *sampler) keep(log *models.Log) bool {
+	policy := s.policy.Load()
+	if rate, ok := policy.rates[log.Level]; ok && rand.Float64() >= rate {
+		metrics.LogsSampledOut.WithLabelValues(metrics.SampleLevel).Inc()
+		return false
+	}
+	if log.Level == models.LogLevelError || log.Level == models.LogLevelFatal {
+		return true
+	}
+	limit, ok := policy.limits[log.Service]
+	if !ok {
+		limit = policy.limit
+	}
+	if limit <= 0 || s.take(log.Service, limit, time.Now()) {
+		return true
+	}
+	metrics.LogsSampledOut.WithLabelValues(metrics.SampleRateLimit).Inc()
+	return false
+}
+
+// filter returns the logs that are published
+func (s *sampler) filter(logs []*models.Log) []*models.Log {
+	kept := make([]*models.Log, 0, len(logs))
+	for _, log := range logs {
+		if s.keep(log) {
+			kept = append(kept, log)
+		}
+	}
+	return kept
+}
+
+// take takes a token from the bucket of a service, reporting whether one was left
+func (s *sampler) take(service string, limit int, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[service]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit), last: now}
+		s.buckets[service] = bucket
+	}
+	bucket.tokens = min(float64(limit), bucket.tokens+now.Sub(bucket.last).Seconds()*float64(limit))
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
@@ -0,0 +1,305 @@
+package producers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// errSpoolFull is returned for messages that didn't fit in the spool
+var errSpoolFull = errors.New("spool full")
+
+// spool keeps the messages the collector couldn't deliver in segment files on disk, oldest first, until they are
+// delivered. Segments are appended to until they outgrow the segment size, and removed once all of their messages
+// are delivered; segments left by an earlier run are delivered first. The messages of a segment that was being
+// delivered when the collector stopped are delivered again from its start, which the processor deduplicates.
+type spool struct {
+	dir      string
+	maxBytes int64
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	segments []*spoolSegment // oldest first; the last one is written to
+	writer   *os.File        // the last segment, nil once it is being delivered
+	size     int64           // bytes not yet delivered
+	nextSeq  uint64
+}
+
+// spoolSegment is a segment file of the spool
+type spoolSegment struct {
+	path   string
+	size   int64
+	offset int64 // bytes of its messages already delivered
+}
+
+// spooledMessage is a spooled Kafka message, stored as JSON behind its length
+type spooledMessage struct {
+	Topic   string          `json:"topic"`
+	Key     []byte          `json:"key,omitempty"`
+	Value   []byte          `json:"value"`
+	Headers []spooledHeader `json:"headers,omitempty"`
+}
+
+// spooledHeader is a header of a spooled message
+type spooledHeader struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// newSpool opens the spool in the given directory, creating it if needed, and picks up the segments left there
+func newSpool(dir string, maxBytes int64, logger *slog.Logger) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	s := &spool{dir: dir, maxBytes: maxBytes, logger: logger}
+	// Entries are sorted by name, which is the zero-padded sequence number of the segment
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), constants.SpoolSegmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spool segment: %w", err)
+		}
+		s.segments = append(s.segments, &spoolSegment{path: filepath.Join(dir, entry.Name()), size: info.Size()})
+		s.size += info.Size()
+		s.nextSeq = seq + 1
+	}
+	metrics.SpoolBytes.Set(float64(s.size))
+	if len(s.segments) > 0 {
+		logger.Info("Found spooled logs to deliver", "dir", dir, "segments", len(s.segments), "bytes", s.size)
+	}
+	return s, nil
+}
+
+// pending reports whether any spooled message is yet to be delivered
+func (s *spool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) > 0
+}
+
+// write spools messages, all of them or, when they don't fit in the spool, none
+func (s *spool) write(messages []*sarama.ProducerMessage) error {
+	var buf bytes.Buffer
+	for _, message := range messages {
+		record, err := encodeSpooled(message)
+		if err != nil {
+			return err
+		}
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(record))))
+		buf.Write(record)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+int64(buf.Len()) > s.maxBytes {
+		metrics.LogsSpooled.WithLabelValues(metrics.SpoolDropped).Add(float64(len(messages)))
+		return fmt.Errorf("%w, %d logs dropped", errSpoolFull, len(messages))
+	}
+	if s.writer == nil || s.segments[len(s.segments)-1].size >= constants.SpoolSegmentBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	segment := s.segments[len(s.segments)-1]
+	n, err := s.writer.Write(buf.Bytes())
+	segment.size += int64(n)
+	s.size += int64(n)
+	metrics.SpoolBytes.Set(float64(s.size))
+	if err == nil {
+		err = s.writer.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	metrics.LogsSpooled.WithLabelValues(metrics.SpoolSpooled).Add(float64(len(messages)))
+	return nil
+}
+
+// rotate closes the segment written to and starts a new one; the caller holds the lock
+func (s *spool) rotate() error {
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.nextSeq, constants.SpoolSegmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	s.nextSeq++
+	s.writer = file
+	s.segments = append(s.segments, &spoolSegment{path: path})
+	return nil
+}
+
+// drain delivers the spooled messages oldest first, in batches, until the spool is empty or a batch fails to be
+// delivered, which is retried on the next drain
+func (s *spool) drain(send func(messages []*sarama.ProducerMessage) error) error {
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		segment := s.segments[0]
+		// The segment written to is closed, so that messages spooled meanwhile go to a new one
+		if len(s.segments) == 1 && s.writer != nil {
+			s.writer.Close()
+			s.writer = nil
+		}
+		s.mu.Unlock()
+
+		if err := s.drainSegment(segment, send); err != nil {
+			return err
+		}
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool segment: %w", err)
+		}
+		s.mu.Lock()
+		s.segments = s.segments[1:]
+		s.size -= segment.size - segment.offset
+		metrics.SpoolBytes.Set(float64(s.size))
+		s.mu.Unlock()
+	}
+}
+
+// drainSegment delivers the messages of a segment from where its delivery stopped. A message cut short, by a crash
+// while it was written, ends the segment.
+func (s *spool) drainSegment(segment *spoolSegment, send func(messages []*sarama.ProducerMessage) error) error {
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(segment.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read spool segment: %w", err)
+	}
+	reader := bufio.NewReader(file)
+
+	for {
+		batch := make([]*sarama.ProducerMessage, 0, constants.SpoolDrainBatchSize)
+		var read int64
+		end := false
+		for !end && len(batch) < constants.SpoolDrainBatchSize {
+			message, n, err := readSpooled(reader)
+			switch {
+			case err == io.EOF:
+				end = true
+			case err != nil:
+				s.logger.Warn("Skipping the rest of a damaged spool segment", "segment", segment.path, "error", err)
+				end = true
+			default:
+				read += n
+				if message != nil {
+					batch = append(batch, message)
+				}
+			}
+		}
+		if len(batch) > 0 {
+			if err := send(batch); err != nil {
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		segment.offset += read
+		s.size -= read
+		metrics.SpoolBytes.Set(float64(s.size))
+		s.mu.Unlock()
+		metrics.LogsSpooled.WithLabelValues(metrics.SpoolDrained).Add(float64(len(batch)))
+		if end {
+			return nil
+		}
+	}
+}
+
+// close closes the segment written to; spooled messages stay on disk for the next run
+func (s *spool) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+}
+
+// encodeSpooled encodes a message as a spool record
+func encodeSpooled(message *sarama.ProducerMessage) ([]byte, error) {
+	record := spooledMessage{Topic: message.Topic}
+	var err error
+	if message.Key != nil {
+		if record.Key, err = message.Key.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode spooled message key: %w", err)
+		}
+	}
+	if message.Value != nil {
+		if record.Value, err = message.Value.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode spooled message: %w", err)
+		}
+	}
+	for _, header := range message.Headers {
+		record.Headers = append(record.Headers, spooledHeader{Key: header.Key, Value: header.Value})
+	}
+	return json.Marshal(record)
+}
+
+// readSpooled reads the next spool record and the bytes it took. Records that aren't valid JSON are skipped,
+// returning no message.
+func readSpooled(reader *bufio.Reader) (*sarama.ProducerMessage, int64, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, fmt.Errorf("truncated record length: %w", err)
+		}
+		return nil, 0, err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > constants.SpoolSegmentBytes {
+		return nil, 0, fmt.Errorf("invalid record length %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, 0, fmt.Errorf("truncated record: %w", err)
+	}
+	n := int64(len(length) + len(data))
+
+	var record spooledMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, n, nil
+	}
+	message := &sarama.ProducerMessage{Topic: record.Topic, Value: sarama.ByteEncoder(record.Value)}
+	if record.Key != nil {
+		message.Key = sarama.ByteEncoder(record.Key)
+	}
+	for _, header := range record.Headers {
+		message.Headers = append(message.Headers, sarama.RecordHeader{Key: header.Key, Value: header.Value})
+	}
+	return message, n, nil
+}
@@ -0,0 +1,162 @@
+package producers
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+// spoolRecord frames a record as the spool stores it
+func spoolRecord(t *testing.T, record []byte) []byte {
+	t.Helper()
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(record))), record...)
+}
+
+// spooledValue encodes a message with the value as a spool record
+func spooledValue(t *testing.T, value string) []byte {
+	t.Helper()
+	record, err := encodeSpooled(&sarama.ProducerMessage{Topic: "logs", Value: sarama.StringEncoder(value)})
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	return spoolRecord(t, record)
+}
+
+func TestSpool(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		writes    [][]string
+		wantFull  []bool                    // per write, whether it doesn't fit
+		damage    func(t *testing.T) []byte // bytes appended to the last segment before the spool is reopened
+		reopen    bool
+		failSends int
+		want      []string
+	}{
+		{
+			name:     "delivered oldest first",
+			maxBytes: 1 << 20,
+			writes:   [][]string{{"a", "b"}, {"c"}},
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "writes that don't fit are dropped whole",
+			maxBytes: 100,
+			writes:   [][]string{{"a"}, {"b", "c"}, {"d"}},
+			wantFull: []bool{false, true, false},
+			want:     []string{"a", "d"},
+		},
+		{
+			name:      "failed delivery is retried",
+			maxBytes:  1 << 20,
+			writes:    [][]string{{"a"}, {"b"}},
+			failSends: 2,
+			want:      []string{"a", "b"},
+		},
+		{
+			name:     "segments left by an earlier run",
+			maxBytes: 1 << 20,
+			writes:   [][]string{{"a"}, {"b"}},
+			reopen:   true,
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "invalid record skipped",
+			maxBytes: 1 << 20,
+			writes:   [][]string{{"a"}},
+			damage: func(t *testing.T) []byte {
+				return append(spoolRecord(t, []byte("not json")), spooledValue(t, "b")...)
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name:     "record cut short ends the segment",
+			maxBytes: 1 << 20,
+			writes:   [][]string{{"a"}},
+			damage: func(t *testing.T) []byte {
+				return spooledValue(t, "b")[:8]
+			},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			s, err := newSpool(dir, tt.maxBytes, logger)
+			if err != nil {
+				t.Fatalf("failed to open spool: %v", err)
+			}
+
+			for i, values := range tt.writes {
+				var messages []*sarama.ProducerMessage
+				for _, value := range values {
+					messages = append(messages, &sarama.ProducerMessage{Topic: "logs", Value: sarama.StringEncoder(value)})
+				}
+				err := s.write(messages)
+				wantFull := tt.wantFull != nil && tt.wantFull[i]
+				if wantFull != errors.Is(err, errSpoolFull) || (!wantFull && err != nil) {
+					t.Fatalf("write %d: error %v, want spool full %v", i, err, wantFull)
+				}
+			}
+			if !s.pending() {
+				t.Fatal("spool has nothing pending after writes")
+			}
+
+			if tt.damage != nil || tt.reopen {
+				s.close()
+				if tt.damage != nil {
+					path := s.segments[len(s.segments)-1].path
+					file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+					if err != nil {
+						t.Fatalf("failed to open segment: %v", err)
+					}
+					file.Write(tt.damage(t))
+					file.Close()
+				}
+				if s, err = newSpool(dir, tt.maxBytes, logger); err != nil {
+					t.Fatalf("failed to reopen spool: %v", err)
+				}
+			}
+
+			var delivered []string
+			failures := tt.failSends
+			send := func(messages []*sarama.ProducerMessage) error {
+				if failures > 0 {
+					failures--
+					return errors.New("broker unreachable")
+				}
+				for _, message := range messages {
+					value, _ := message.Value.Encode()
+					delivered = append(delivered, string(value))
+				}
+				return nil
+			}
+			for range tt.failSends {
+				if err := s.drain(send); err == nil {
+					t.Fatal("drain succeeded although delivery failed")
+				}
+			}
+			if err := s.drain(send); err != nil {
+				t.Fatalf("drain failed: %v", err)
+			}
+
+			if !slices.Equal(delivered, tt.want) {
+				t.Errorf("delivered %q, want %q", delivered, tt.want)
+			}
+			if s.pending() || s.size != 0 {
+				t.Errorf("spool still holds %d bytes after draining", s.size)
+			}
+			if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) > 0 {
+				t.Errorf("segments %q left after draining", left)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package producers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogFacilities maps facility codes to their conventional names
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogSeverities maps severity codes to their conventional names
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// syslogLevel maps a syslog severity to a log level
+func syslogLevel(severity int) models.LogLevel {
+	switch {
+	case severity <= 2: // emergency, alert, critical
+		return models.LogLevelFatal
+	case severity == 3:
+		return models.LogLevelError
+	case severity == 4:
+		return models.LogLevelWarn
+	case severity == 7:
+		return models.LogLevelDebug
+	default: // notice, informational
+		return models.LogLevelInfo
+	}
+}
+
+// parseSyslog parses an RFC 5424 or RFC 3164 frame into a log.
+// The sender's address is used as hostname when the frame doesn't carry one.
+func parseSyslog(frame string, remoteHost string, now time.Time) (*models.Log, error) {
+	frame = strings.TrimRight(frame, "\r\n\x00")
+
+	// Frames without a priority are treated as user.notice, as RFC 3164 prescribes for relays
+	priority, rest, err := parseSyslogPriority(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &models.Log{}
+	var hostname string
+	if strings.HasPrefix(rest, constants.SyslogRFC5424Version+" ") {
+		hostname, err = parseRFC5424(log, rest[len(constants.SyslogRFC5424Version)+1:], now)
+	} else {
+		hostname = parseRFC3164(log, rest, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hostname == "" {
+		hostname = remoteHost
+	}
+	if log.Service == "" {
+		log.Service = hostname
+	}
+	if log.Service == "" {
+		log.Service = constants.SyslogDefaultService
+	}
+	if len(log.Service) > constants.MaxServiceLength {
+		log.Service = log.Service[:constants.MaxServiceLength]
+	}
+	if log.Message == "" {
+		return nil, fmt.Errorf("syslog message is empty")
+	}
+
+	facility, severity := priority/8, priority%8
+	log.Level = syslogLevel(severity)
+	log.Attributes.Set(constants.SyslogAttributePrefix+"facility", syslogFacilities[facility])
+	log.Attributes.Set(constants.SyslogAttributePrefix+"severity", syslogSeverities[severity])
+	if hostname != "" {
+		log.Attributes.Set(constants.SyslogAttributePrefix+"hostname", hostname)
+		host := hostname[:min(len(hostname), constants.MaxHostLength)]
+		log.Host = &host
+	}
+	if remoteHost != "" {
+		log.Attributes.Set(constants.SyslogAttributePrefix+"remote_addr", remoteHost)
+	}
+	return log, nil
+}
+
+// parseSyslogPriority parses the leading <PRI> part, defaulting to user.notice when absent
+func parseSyslogPriority(frame string) (int, string, error) {
+	const defaultPriority = 1*8 + 5
+	if !strings.HasPrefix(frame, "<") {
+		return defaultPriority, frame, nil
+	}
+	end := strings.IndexByte(frame, '>')
+	if end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("invalid syslog priority")
+	}
+	priority, err := strconv.Atoi(frame[1:end])
+	if err != nil || priority < 0 || priority >= len(syslogFacilities)*8 {
+		return 0, "", fmt.Errorf("invalid syslog priority %q", frame[1:end])
+	}
+	return priority, frame[end+1:], nil
+}
+
+// parseRFC5424 fills the log from the fields following the version:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(log *models.Log, rest string, now time.Time) (string, error) {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], rest, ok = strings.Cut(rest, " ")
+		if !ok && i < len(fields)-1 {
+			return "", fmt.Errorf("truncated RFC 5424 header")
+		}
+	}
+	timestamp, hostname, appName, procID, msgID := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	log.Timestamp = now
+	if timestamp != constants.SyslogNilValue {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return "", fmt.Errorf("invalid RFC 5424 timestamp %q", timestamp)
+		}
+		log.Timestamp = t
+	}
+
+	if appName != constants.SyslogNilValue {
+		log.Service = appName
+	}
+	if procID != constants.SyslogNilValue {
+		log.Attributes.Set(constants.SyslogAttributePrefix+"procid", procID)
+	}
+	if msgID != constants.SyslogNilValue {
+		log.Attributes.Set(constants.SyslogAttributePrefix+"msgid", msgID)
+	}
+
+	message, err := parseStructuredData(log, rest)
+	if err != nil {
+		return "", err
+	}
+	message = strings.TrimPrefix(message, "\xEF\xBB\xBF") // UTF-8 BOM
+	if message == "" && msgID != constants.SyslogNilValue {
+		message = msgID
+	}
+	log.Message = message
+
+	if hostname == constants.SyslogNilValue {
+		hostname = ""
+	}
+	return hostname, nil
+}
+
+// parseStructuredData stores SD-PARAMs as syslog.<SD-ID>.<PARAM-NAME> attributes and returns the remaining message
+func parseStructuredData(log *models.Log, rest string) (string, error) {
+	if rest == constants.SyslogNilValue || strings.HasPrefix(rest, constants.SyslogNilValue+" ") {
+		return strings.TrimPrefix(strings.TrimPrefix(rest, constants.SyslogNilValue), " "), nil
+	}
+	if !strings.HasPrefix(rest, "[") {
+		return "", fmt.Errorf("invalid RFC 5424 structured data")
+	}
+
+	for strings.HasPrefix(rest, "[") {
+		end := strings.IndexAny(rest, " ]")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated RFC 5424 structured data")
+		}
+		id := rest[1:end]
+		rest = rest[end:]
+
+		for strings.HasPrefix(rest, " ") {
+			name, value, ok := strings.Cut(rest[1:], "=\"")
+			if !ok {
+				return "", fmt.Errorf("invalid RFC 5424 structured data parameter")
+			}
+			rest = value
+
+			var unescaped strings.Builder
+			closed := false
+			for i := 0; i < len(rest); i++ {
+				switch {
+				case rest[i] == '\\' && i+1 < len(rest) && strings.IndexByte(`"\]`, rest[i+1]) >= 0:
+					i++
+					unescaped.WriteByte(rest[i])
+				case rest[i] == '"':
+					rest = rest[i+1:]
+					closed = true
+				default:
+					unescaped.WriteByte(rest[i])
+				}
+				if closed {
+					break
+				}
+			}
+			if !closed {
+				return "", fmt.Errorf("unterminated RFC 5424 structured data parameter")
+			}
+			log.Attributes.Set(constants.SyslogAttributePrefix+id+"."+name, unescaped.String())
+		}
+
+		if !strings.HasPrefix(rest, "]") {
+			return "", fmt.Errorf("unterminated RFC 5424 structured data")
+		}
+		rest = rest[1:]
+	}
+	return strings.TrimPrefix(rest, " "), nil
+}
+
+// parseRFC3164 fills the log from a BSD syslog frame: TIMESTAMP HOSTNAME TAG[PID]: MSG.
+// Frames without a valid timestamp are kept whole as the message, as RFC 3164 prescribes for relays.
+func parseRFC3164(log *models.Log, rest string, now time.Time) string {
+	log.Timestamp = now
+	layout := constants.SyslogRFC3164TimeLayout
+	if len(rest) <= len(layout) || rest[len(layout)] != ' ' {
+		log.Message = rest
+		return ""
+	}
+	t, err := time.ParseInLocation(layout, rest[:len(layout)], time.Local)
+	if err != nil {
+		log.Message = rest
+		return ""
+	}
+
+	// The timestamp carries no year; pick the one placing it closest to now
+	t = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	log.Timestamp = t
+
+	hostname, message, _ := strings.Cut(rest[len(layout)+1:], " ")
+
+	// TAG is up to 32 alphanumeric characters, optionally followed by [PID], and ends with a colon
+	if end := strings.IndexByte(message, ':'); end > 0 {
+		tag := message[:end]
+		var procID string
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			procID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		if isSyslogTag(tag) {
+			log.Service = tag
+			if procID != "" {
+				log.Attributes.Set(constants.SyslogAttributePrefix+"procid", procID)
+			}
+			message = strings.TrimPrefix(message[end+1:], " ")
+		}
+	}
+	log.Message = message
+	return hostname
+}
+
+// isSyslogTag reports whether s is a valid RFC 3164 TAG
+func isSyslogTag(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == '/') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package producers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogServer receives syslog messages over UDP and TCP and publishes them to Kafka in batches
+type SyslogServer struct {
+	collector *LogCollectorService
+	udpAddr   string
+	tcpAddr   string
+	queue     chan *models.Log
+	logger    *slog.Logger
+}
+
+// NewSyslogServer creates a new syslog server publishing through the collector.
+// An empty address disables the corresponding transport.
+func NewSyslogServer(collector *LogCollectorService, cfg *config.CollectorConfig, logger *slog.Logger) *SyslogServer {
+	return &SyslogServer{
+		collector: collector,
+		udpAddr:   cfg.SyslogUDPAddr,
+		tcpAddr:   cfg.SyslogTCPAddr,
+		queue:     make(chan *models.Log, constants.SyslogQueueSize),
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether any syslog transport is configured
+func (s *SyslogServer) Enabled() bool {
+	return s.udpAddr != "" || s.tcpAddr != ""
+}
+
+// Start listens on the configured transports until the context is cancelled.
+// Queued messages are published before Start returns.
+func (s *SyslogServer) Start(ctx context.Context) error {
+	var udpConn net.PacketConn
+	var tcpListener net.Listener
+	var err error
+
+	if s.udpAddr != "" {
+		if udpConn, err = net.ListenPacket("udp", s.udpAddr); err != nil {
+			return fmt.Errorf("failed to listen for syslog on udp %s: %w", s.udpAddr, err)
+		}
+		s.logger.Info("Starting syslog listener", "transport", "udp", "addr", s.udpAddr)
+	}
+	if s.tcpAddr != "" {
+		if tcpListener, err = net.Listen("tcp", s.tcpAddr); err != nil {
+			if udpConn != nil {
+				udpConn.Close()
+			}
+			return fmt.Errorf("failed to listen for syslog on tcp %s: %w", s.tcpAddr, err)
+		}
+		s.logger.Info("Starting syslog listener", "transport", "tcp", "addr", s.tcpAddr)
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		s.publishBatches()
+		close(flushed)
+	}()
+
+	var wg sync.WaitGroup
+	if udpConn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveUDP(ctx, udpConn)
+		}()
+	}
+	if tcpListener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveTCP(ctx, tcpListener)
+		}()
+	}
+
+	<-ctx.Done()
+	if udpConn != nil {
+		udpConn.Close()
+	}
+	if tcpListener != nil {
+		tcpListener.Close()
+	}
+	wg.Wait()
+
+	close(s.queue)
+	<-flushed
+	s.logger.Info("Syslog listener stopped")
+	return nil
+}
+
+// serveUDP handles one message per datagram; oversized datagrams are truncated
+func (s *SyslogServer) serveUDP(ctx context.Context, conn net.PacketConn) {
+	buf := make([]byte, constants.SyslogMaxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("Failed to read syslog datagram", "error", err)
+			}
+			return
+		}
+		var remoteHost string
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			remoteHost = udpAddr.IP.String()
+		}
+		s.handleFrame(string(buf[:n]), remoteHost)
+	}
+}
+
+// serveTCP accepts connections until the listener is closed
+func (s *SyslogServer) serveTCP(ctx context.Context, listener net.Listener) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("Failed to accept syslog connection", "error", err)
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleConn(ctx, conn)
+		}()
+	}
+}
+
+// handleConn reads frames from a TCP connection until it is closed, idle or the context is cancelled
+func (s *SyslogServer) handleConn(ctx context.Context, conn net.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		conn.Close()
+	}()
+
+	remoteHost, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	reader := bufio.NewReaderSize(conn, constants.SyslogMaxMessageSize)
+	for {
+		conn.SetReadDeadline(time.Now().Add(constants.SyslogTCPIdleTimeout))
+		frame, err := readSyslogFrame(reader)
+		if frame != "" {
+			s.handleFrame(frame, remoteHost)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				s.logger.Warn("Closing syslog connection", "error", err, "remote_addr", remoteHost)
+			}
+			return
+		}
+	}
+}
+
+// readSyslogFrame reads one frame using RFC 6587 octet counting ("LEN SP MSG")
+// or, when the frame doesn't start with a digit, newline-delimited framing
+func readSyslogFrame(reader *bufio.Reader) (string, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if first[0] >= '0' && first[0] <= '9' {
+		prefix, err := reader.ReadString(' ')
+		if err != nil {
+			return "", fmt.Errorf("invalid syslog frame length: %w", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil || length <= 0 || length > constants.SyslogMaxMessageSize {
+			return "", fmt.Errorf("invalid syslog frame length %q", strings.TrimSuffix(prefix, " "))
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return "", fmt.Errorf("truncated syslog frame: %w", err)
+		}
+		return string(frame), nil
+	}
+
+	line, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("syslog frame exceeds %d bytes", constants.SyslogMaxMessageSize)
+	}
+	return strings.TrimRight(string(line), "\r\n"), err
+}
+
+// handleFrame parses a frame and queues the resulting log for publishing
+func (s *SyslogServer) handleFrame(frame string, remoteHost string) {
+	if strings.TrimSpace(frame) == "" {
+		return
+	}
+	log, err := parseSyslog(frame, remoteHost, time.Now())
+	if err == nil {
+		err = log.Validate()
+	}
+	if err != nil {
+		s.logger.Warn("Discarding invalid syslog message", "error", err, "remote_addr", remoteHost)
+		return
+	}
+	// Blocking applies backpressure to TCP senders; UDP datagrams are dropped by the kernel meanwhile
+	s.queue <- log
+}
+
+// publishBatches publishes queued logs when a batch fills up or the flush interval elapses,
+// until the queue is closed
+func (s *SyslogServer) publishBatches() {
+	ticker := time.NewTicker(constants.SyslogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.Log, 0, constants.SyslogBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.collector.SendLogs(context.Background(), batch); err != nil {
+			s.logger.Error("Failed to publish syslog messages", "error", err, "count", len(batch))
+		}
+		batch = make([]*models.Log, 0, constants.SyslogBatchSize)
+	}
+
+	for {
+		select {
+		case log, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, log)
+			if len(batch) >= constants.SyslogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
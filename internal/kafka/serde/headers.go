@@ -0,0 +1,51 @@
+package serde
+
+import (
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// TraceHeaders returns the headers carrying the trace context of a log: its trace, span and parent span IDs, those
+// it has. Consumers can route on them without decoding the value.
+func TraceHeaders(log *models.Log) []sarama.RecordHeader {
+	var headers []sarama.RecordHeader
+	for _, field := range []struct {
+		key   string
+		value *string
+	}{
+		{constants.HeaderTraceID, log.TraceID},
+		{constants.HeaderSpanID, log.SpanID},
+		{constants.HeaderParentSpanID, log.ParentSpanID},
+	} {
+		if field.value != nil && *field.value != "" {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(field.key), Value: []byte(*field.value)})
+		}
+	}
+	return headers
+}
+
+// ApplyTraceHeaders fills in the trace context a log lacks from the message headers, so that logs parsed from
+// formats without one, such as raw lines, keep the context their producer knew. The span and its parent are only
+// taken together, for logs without a span, and IDs too long to store are ignored.
+func ApplyTraceHeaders(log *models.Log, headers map[string]string) {
+	if log.TraceID == nil {
+		log.TraceID = headerValue(headers, constants.HeaderTraceID, constants.MaxTraceIDLength)
+	}
+	if log.SpanID == nil {
+		log.SpanID = headerValue(headers, constants.HeaderSpanID, constants.MaxSpanIDLength)
+		if log.SpanID != nil {
+			log.ParentSpanID = headerValue(headers, constants.HeaderParentSpanID, constants.MaxSpanIDLength)
+		}
+	}
+}
+
+// headerValue returns the value of a header, or nil when it is missing, empty or longer than maxLength
+func headerValue(headers map[string]string, key string, maxLength int) *string {
+	value, ok := headers[key]
+	if !ok || value == "" || len(value) > maxLength {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,171 @@
+// Package serde converts logs to and from the values of Kafka messages, either as JSON or as Avro framed with the
+// ID of its schema in a Confluent Schema Registry.
+package serde
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/avro"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// LogSchema is the Avro schema of the logs the collector produces. Fields are named like the log JSON, so that
+// decoded values read as native logs; the processor sets the ID, fingerprint and creation time.
+const LogSchema = `{
+  "type": "record",
+  "name": "Log",
+  "namespace": "io.loganalytics",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "level", "type": {"type": "enum", "name": "LogLevel", "symbols": ["DEBUG", "INFO", "WARN", "ERROR", "FATAL"]}},
+    {"name": "service", "type": "string"},
+    {"name": "tenant", "type": ["null", "string"], "default": null},
+    {"name": "host", "type": ["null", "string"], "default": null},
+    {"name": "environment", "type": ["null", "string"], "default": null},
+    {"name": "region", "type": ["null", "string"], "default": null},
+    {"name": "client_ip", "type": ["null", "string"], "default": null},
+    {"name": "message", "type": "string"},
+    {"name": "trace_id", "type": ["null", "string"], "default": null},
+    {"name": "span_id", "type": ["null", "string"], "default": null},
+    {"name": "parent_span_id", "type": ["null", "string"], "default": null},
+    {"name": "message_id", "type": ["null", "string"], "default": null},
+    {"name": "user_id", "type": ["null", "string"], "default": null},
+    {"name": "request_method", "type": ["null", "string"], "default": null},
+    {"name": "request_path", "type": ["null", "string"], "default": null},
+    {"name": "response_status", "type": ["null", "int"], "default": null},
+    {"name": "response_time_ms", "type": ["null", "int"], "default": null},
+    {"name": "request_body", "type": ["null", "string"], "default": null},
+    {"name": "response_body", "type": ["null", "string"], "default": null},
+    {"name": "attributes", "type": {"type": "map", "values": "string"}, "default": {}}
+  ]
+}`
+
+// logSchema is LogSchema parsed
+var logSchema = mustParse(LogSchema)
+
+// Serializer encodes logs in the configured message format
+type Serializer struct {
+	registry *avro.Registry // nil for JSON
+}
+
+// NewSerializer creates a serializer for the configured message format
+func NewSerializer(cfg *config.KafkaConfig) *Serializer {
+	s := &Serializer{}
+	if cfg.MessageFormat == constants.MessageFormatAvro {
+		s.registry = newRegistry(&cfg.SchemaRegistry)
+	}
+	return s
+}
+
+// Serialize encodes a log for the topic. Avro logs are framed with the ID of LogSchema, registered under the
+// topic's value subject the first time a log is sent to the topic.
+func (s *Serializer) Serialize(ctx context.Context, topic string, log *models.Log) ([]byte, error) {
+	if s.registry == nil {
+		return json.Marshal(log)
+	}
+
+	id, err := s.registry.Register(ctx, topic+constants.SchemaRegistrySubjectSuffix, logSchema)
+	if err != nil {
+		return nil, err
+	}
+	value, err := logSchema.Encode(logRecord(log))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log: %w", err)
+	}
+	return avro.Frame(id, value), nil
+}
+
+// Deserializer turns message values into the log JSON the processor parses
+type Deserializer struct {
+	registry *avro.Registry // nil when no schema registry is configured
+}
+
+// NewDeserializer creates a deserializer, reading Avro messages when a schema registry is configured
+func NewDeserializer(cfg *config.KafkaConfig) *Deserializer {
+	d := &Deserializer{}
+	if cfg.SchemaRegistry.URL != "" {
+		d.registry = newRegistry(&cfg.SchemaRegistry)
+	}
+	return d
+}
+
+// Deserialize returns the JSON of a message value. Avro values are decoded with the schema they were written with,
+// fetched from the registry by the ID they are framed with, and re-encoded as JSON; any schema whose fields are
+// named like those of the log JSON is read. Other values, such as JSON, are returned as they are. Failures to reach
+// the registry wrap avro.ErrRegistryUnavailable: the message isn't at fault and decodes once the registry is back,
+// unlike messages of unknown schemas or that don't decode.
+func (d *Deserializer) Deserialize(ctx context.Context, value []byte) ([]byte, error) {
+	if d.registry == nil {
+		return value, nil
+	}
+	id, encoded, ok := avro.Unframe(value)
+	if !ok {
+		return value, nil
+	}
+
+	schema, err := d.registry.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := schema.Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode avro message of schema %d: %w", id, err)
+	}
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert avro message of schema %d to JSON: %w", id, err)
+	}
+	return data, nil
+}
+
+// logRecord converts a log into a record of LogSchema
+func logRecord(log *models.Log) map[string]any {
+	return map[string]any{
+		"timestamp":        log.Timestamp,
+		"level":            string(log.Level),
+		"service":          log.Service,
+		"tenant":           optional(log.Tenant),
+		"host":             optional(log.Host),
+		"environment":      optional(log.Environment),
+		"region":           optional(log.Region),
+		"client_ip":        optional(log.ClientIP),
+		"message":          log.Message,
+		"trace_id":         optional(log.TraceID),
+		"span_id":          optional(log.SpanID),
+		"parent_span_id":   optional(log.ParentSpanID),
+		"message_id":       optional(log.MessageID),
+		"user_id":          optional(log.UserID),
+		"request_method":   optional(log.RequestMethod),
+		"request_path":     optional(log.RequestPath),
+		"response_status":  optional(log.ResponseStatus),
+		"response_time_ms": optional(log.ResponseTimeMs),
+		"request_body":     optional(log.RequestBody),
+		"response_body":    optional(log.ResponseBody),
+		"attributes":       map[string]string(log.Attributes),
+	}
+}
+
+// optional returns the value of a pointer, or nil
+func optional[T any](value *T) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// newRegistry creates a client of the configured schema registry
+func newRegistry(cfg *config.SchemaRegistryConfig) *avro.Registry {
+	return avro.NewRegistry(cfg.URL, cfg.Username, cfg.Password, cfg.Timeout)
+}
+
+// mustParse parses a schema defined in the code
+func mustParse(text string) *avro.Schema {
+	schema, err := avro.Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
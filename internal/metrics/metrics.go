@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"github.com/adeesh/log-analytics/internal/constants"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Pipeline metrics, registered with the default Prometheus registry next to the Go runtime and process collectors.
+// Each service only updates the metrics of its own stage; the others stay at zero.
+var (
+	// LogsProduced counts logs delivered to Kafka by the collector, by topic
+	LogsProduced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_produced_total",
+		Help:      "Logs delivered to Kafka by the collector.",
+	}, []string{"topic"})
+
+	// ProduceErrors counts logs the collector failed to deliver to Kafka, by topic
+	ProduceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "produce_errors_total",
+		Help:      "Logs the collector failed to deliver to Kafka.",
+	}, []string{"topic"})
+
+	// SpoolBytes is the size of the logs the collector spooled to disk and has yet to deliver
+	SpoolBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "spool_bytes",
+		Help:      "Size of the logs the collector spooled to disk while Kafka was unreachable and has yet to deliver.",
+	})
+
+	// LogsSpooled counts logs the collector spooled to disk, delivered from the spool, or dropped with the spool full,
+	// by outcome
+	LogsSpooled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_spooled_total",
+		Help:      "Logs the collector spooled to disk while Kafka was unreachable, by outcome (spooled, drained, dropped).",
+	}, []string{"outcome"})
+
+	// LogsSampledOut counts logs the collector didn't publish because of its sampling settings, by reason
+	LogsSampledOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_sampled_out_total",
+		Help:      "Logs the collector didn't publish because of their level's sample rate or their service's rate limit.",
+	}, []string{"reason"})
+
+	// LogsConsumed counts messages consumed from Kafka by the processor, by topic
+	LogsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_consumed_total",
+		Help:      "Messages consumed from Kafka by the processor.",
+	}, []string{"topic"})
+
+	// LogsDeadLettered counts messages the processor could not parse or store, by topic
+	LogsDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_dead_lettered_total",
+		Help:      "Messages the processor could not parse or store and sent to the dead-letter topic.",
+	}, []string{"topic"})
+
+	// BatchStoreRetries counts failed attempts to store or dead-letter a batch that were retried
+	BatchStoreRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "batch_store_retries_total",
+		Help:      "Failed attempts to store or dead-letter a batch of logs that the processor retried.",
+	})
+
+	// StoreBreakerOpen is 1 while the processor paused consumption because the log store is unreachable
+	StoreBreakerOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "store_breaker_open",
+		Help:      "Whether the processor paused consumption because the log store is unreachable (1) or not (0).",
+	})
+
+	// LogsFiltered counts logs the processor didn't store because of the dynamic pipeline settings, by reason
+	LogsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_filtered_total",
+		Help:      "Logs dropped, sampled out or forwarded by the processor's dynamic pipeline settings.",
+	}, []string{"reason"})
+
+	// Redactions counts the sensitive values masked in logs before they were stored, by redaction rule
+	Redactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "redactions_total",
+		Help:      "Sensitive values masked in logs before they were stored.",
+	}, []string{"rule"})
+
+	// LogsRedacted counts the logs that had at least one value masked before they were stored
+	LogsRedacted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_redacted_total",
+		Help:      "Logs that had sensitive values masked before they were stored.",
+	})
+
+	// LogsDeduplicated counts logs the processor didn't store because they were stored before, by where the
+	// duplicate was detected (cache or database)
+	LogsDeduplicated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_deduplicated_total",
+		Help:      "Redelivered logs the processor skipped because their message ID was already stored.",
+	}, []string{"source"})
+
+	// BatchSize observes the number of logs in each processed batch, by lane (bulk or priority)
+	BatchSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "batch_size",
+		Help:      "Logs per batch processed by the processor.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	}, []string{"lane"})
+
+	// DBInsertDuration observes how long storing a batch of logs takes, by outcome
+	DBInsertDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "db_insert_duration_seconds",
+		Help:      "Time taken to store a batch of logs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+
+	// KafkaLag reports how many messages of a partition the processor has yet to consume
+	KafkaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Messages between the processor's position and the partition's high water mark.",
+	}, []string{"topic", "partition"})
+
+	// ConsumerGroupLag reports how many messages of a partition the processor's consumer groups have yet to commit,
+	// as checked by the API server's lag monitor
+	ConsumerGroupLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "consumer_group_lag",
+		Help:      "Messages between a consumer group's committed offset and the partition's newest offset.",
+	}, []string{"group", "topic", "partition"})
+
+	// KafkaLagAlert is 1 while the lag alert of a consumer group is firing
+	KafkaLagAlert = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "kafka_lag_alert_firing",
+		Help:      "Whether a consumer group lags behind by more than the lag alert threshold.",
+	}, []string{"group"})
+
+	// IngestionDelay observes the time from a log's timestamp until the processor stored it, by service
+	IngestionDelay = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "ingestion_delay_seconds",
+		Help:      "Time from the timestamp of a log until the processor stored it.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 21600},
+	}, []string{"service"})
+
+	// IngestionDelayAlert is 1 while the ingestion delay alert of a service is firing
+	IngestionDelayAlert = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "ingestion_delay_alert_firing",
+		Help:      "Whether most logs of a service stored in the last window were later than the ingestion delay threshold.",
+	}, []string{"service"})
+
+	// AlertEvaluations counts alert rule evaluations, by outcome (ok, fired, suppressed, resolved or error)
+	AlertEvaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "alert_evaluations_total",
+		Help:      "Alert rule evaluations by outcome.",
+	}, []string{"result"})
+
+	// AlertRuleFailures counts failed evaluations of each alert rule, by rule ID
+	AlertRuleFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "alert_rule_failures_total",
+		Help:      "Failed alert rule evaluations by rule.",
+	}, []string{"rule_id"})
+
+	// AlertCheckerLastSuccess reports when the alert checker last evaluated every enabled rule successfully
+	AlertCheckerLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "alert_checker_last_success_timestamp_seconds",
+		Help:      "Unix time of the last alert check in which every enabled rule was evaluated.",
+	})
+
+	// AlertCheckerRulesEvaluated reports the number of enabled rules the last alert check evaluated
+	AlertCheckerRulesEvaluated = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "alert_checker_rules_evaluated",
+		Help:      "Enabled alert rules evaluated by the last alert check.",
+	})
+
+	// AlertCheckerMetaAlert is 1 while the alert checker's meta-alert is firing
+	AlertCheckerMetaAlert = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "alert_checker_meta_alert_firing",
+		Help:      "Whether alert rules have gone unevaluated long enough to fire the alert checker's meta-alert.",
+	})
+
+	// LogsPurged counts logs removed by retention policies, by action
+	LogsPurged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "logs_purged_total",
+		Help:      "Logs deleted or archived by log retention policies.",
+	}, []string{"action"})
+
+	// HTTPRequestDuration observes HTTP request durations, by method, route and status code
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: constants.MetricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request durations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Batch lanes of the processor
+const (
+	LaneBulk     = "bulk"
+	LanePriority = "priority"
+)
+
+// Where the processor detected a redelivered log
+const (
+	DedupCache    = "cache"
+	DedupDatabase = "database"
+)
+
+// Reasons for the processor not to store a log
+const (
+	FilterLevel     = "level"
+	FilterSampled   = "sampled"
+	FilterForwarded = "forwarded"
+)
+
+// Reasons for the collector not to publish a log
+const (
+	SampleLevel     = "level"
+	SampleRateLimit = "rate_limit"
+)
+
+// Outcomes of the logs of the collector's spool
+const (
+	SpoolSpooled = "spooled"
+	SpoolDrained = "drained"
+	SpoolDropped = "dropped"
+)
+
+// Alert evaluation results
+const (
+	EvaluationOK         = "ok"
+	EvaluationFired      = "fired"
+	EvaluationSuppressed = "suppressed"
+	EvaluationResolved   = "resolved"
+	EvaluationError      = "error"
+)
+
+// Outcomes of storing a batch
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler returns the scrape handler exposing all registered metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware observes the duration of every request. Requests are labeled with their route
+// pattern rather than their path so IDs in paths don't create a series per request.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = constants.MetricsUnmatchedRoute
+		}
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Serve exposes the metrics on the given port until the context is cancelled.
+// It is used by services that don't otherwise serve HTTP.
+func Serve(ctx context.Context, port string, logger *slog.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle(constants.MetricsPath, Handler())
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      mux,
+		ReadTimeout:  constants.DefaultServerReadTimeout,
+		WriteTimeout: constants.DefaultServerWriteTimeout,
+		IdleTimeout:  constants.DefaultServerIdleTimeout,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		logger.Info("Starting metrics server", "addr", server.Addr, "path", constants.MetricsPath)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.MetricsShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	logger.Info("Metrics server stopped")
+	return nil
+}
@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"hash/fnv"
+	"time"
+)
+
+// errLockTimeout is returned when another run holds the migration lock for longer than the lock timeout
+var errLockTimeout = errors.New("timed out waiting for the migration lock, held by another migration run")
+
+// migrationLock is the advisory lock serializing migration runs across instances, such as the replicas of a rolling
+// deployment migrating on startup. It is held by a connection of its own, to the database server rather than the
+// migrated database, so that it outlives the runner reconnecting once the database is created. The database server
+// releases it when that connection ends, even if the run crashes.
+type migrationLock struct {
+	db   *sql.DB
+	conn *sql.Conn
+	name string
+	key  int64 // Postgres identifies advisory locks by number
+	pg   bool
+}
+
+// acquireLock waits for the migration lock of the configured database for up to the lock timeout
+func (m *MigrationRunner) acquireLock(ctx context.Context) (*migrationLock, error) {
+	timeout := m.config.Migration.LockTimeout
+	name := constants.MigrationLockPrefix + m.config.Database.Database
+	name = name[:min(len(name), constants.MaxMigrationLockNameLength)]
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+
+	db, err := sql.Open(sqlDriverName(m.config.Database.Driver), m.config.Database.DSN(""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database server: %w", err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database server: %w", err)
+	}
+	lock := &migrationLock{db: db, conn: conn, name: name, key: int64(hash.Sum64()), pg: m.postgres()}
+
+	m.logger.Info("Acquiring migration lock", "lock", name, "timeout", timeout)
+	if lock.pg {
+		err = lock.acquirePostgres(ctx, timeout)
+	} else {
+		err = lock.acquireMySQL(ctx, timeout)
+	}
+	if err != nil {
+		conn.Close()
+		db.Close()
+		return nil, err
+	}
+	m.logger.Info("Acquired migration lock", "lock", name)
+	return lock, nil
+}
+
+// acquireMySQL takes the named lock with GET_LOCK, which waits for up to the timeout itself
+func (l *migrationLock) acquireMySQL(ctx context.Context, timeout time.Duration) error {
+	var acquired sql.NullInt64
+	seconds := max(int64(timeout/time.Second), 1)
+	if err := l.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.name, seconds).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !acquired.Valid {
+		return fmt.Errorf("failed to acquire migration lock %s", l.name)
+	}
+	if acquired.Int64 != 1 {
+		return errLockTimeout
+	}
+	return nil
+}
+
+// acquirePostgres polls pg_try_advisory_lock until it succeeds or the timeout passes, since a blocking
+// pg_advisory_lock can only be bounded by changing the session's lock_timeout
+func (l *migrationLock) acquirePostgres(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(constants.MigrationLockPollInterval):
+		}
+	}
+}
+
+// releaseLock releases the migration lock at the end of a run. Closing the lock's connection releases it as well,
+// so a failure is only logged.
+func (m *MigrationRunner) releaseLock(lock *migrationLock) {
+	if err := lock.Release(context.Background()); err != nil {
+		m.logger.Warn("Failed to release migration lock", "error", err)
+		return
+	}
+	m.logger.Info("Released migration lock", "lock", lock.name)
+}
+
+// Release releases the lock and closes its connection
+func (l *migrationLock) Release(ctx context.Context) error {
+	query, arg := "SELECT RELEASE_LOCK(?)", any(l.name)
+	if l.pg {
+		query, arg = "SELECT pg_advisory_unlock($1)", l.key
+	}
+	_, err := l.conn.ExecContext(ctx, query, arg)
+	l.conn.Close()
+	l.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyBlockSizePattern extracts the key block size from the CREATE_OPTIONS of information_schema.TABLES
+var keyBlockSizePattern = regexp.MustCompile(`(?i)key_block_size=(\d+)`)
+
+// pageCompressionPattern extracts the page compression algorithm from the CREATE_OPTIONS of information_schema.TABLES
+var pageCompressionPattern = regexp.MustCompile(`(?i)compression="?(\w+)"?`)
+
+// logsLayout is the compression and clustering of the logs table
+type logsLayout struct {
+	compression  string // one of the LogsCompression constants
+	keyBlockSize int    // with row compression
+	primaryKey   string // the primary key columns, comma separated
+	idIndex      bool   // whether the unique index on id exists
+}
+
+// applyLogsLayout alters the logs table into the configured compression and primary key, when it isn't already.
+// Both rebuild the table, so they are made in one ALTER, run through the online DDL tool when the table is large
+// enough. The tables logs are routed to are created like the logs table, taking the layout it has at the time.
+func (m *MigrationRunner) applyLogsLayout(ctx context.Context) error {
+	cfg := m.config.Migration
+	if cfg.LogsCompression == "" && cfg.LogsPrimaryKey == "" {
+		return nil
+	}
+
+	current, err := m.currentLogsLayout(ctx)
+	if err != nil {
+		return err
+	}
+
+	var clauses []string
+	switch cfg.LogsCompression {
+	case constants.LogsCompressionNone:
+		if current.compression == constants.LogsCompressionRow {
+			clauses = append(clauses, "ROW_FORMAT=DYNAMIC", "KEY_BLOCK_SIZE=0")
+		} else if current.compression != constants.LogsCompressionNone {
+			// Pages keep the compression they were written with until the table is rebuilt
+			clauses = append(clauses, "COMPRESSION='None'", "FORCE")
+		}
+	case constants.LogsCompressionRow:
+		if current.compression != constants.LogsCompressionRow || current.keyBlockSize != cfg.LogsKeyBlockSize {
+			if current.compression != constants.LogsCompressionNone && current.compression != constants.LogsCompressionRow {
+				clauses = append(clauses, "COMPRESSION='None'")
+			}
+			clauses = append(clauses, "ROW_FORMAT=COMPRESSED", fmt.Sprintf("KEY_BLOCK_SIZE=%d", cfg.LogsKeyBlockSize))
+		}
+	case constants.LogsCompressionZlib, constants.LogsCompressionLZ4:
+		if current.compression != cfg.LogsCompression {
+			if current.compression == constants.LogsCompressionRow {
+				clauses = append(clauses, "ROW_FORMAT=DYNAMIC", "KEY_BLOCK_SIZE=0")
+			}
+			clauses = append(clauses, fmt.Sprintf("COMPRESSION='%s'", cfg.LogsCompression), "FORCE")
+		}
+	}
+
+	switch cfg.LogsPrimaryKey {
+	case constants.LogsPrimaryKeyTimestamp:
+		if current.primaryKey == "id" {
+			// The auto incremented id must lead an index once it no longer leads the primary key
+			if !current.idIndex {
+				clauses = append(clauses, fmt.Sprintf("ADD UNIQUE INDEX %s (id)", constants.LogsIDIndex))
+			}
+			clauses = append(clauses, "DROP PRIMARY KEY", "ADD PRIMARY KEY (timestamp, id)")
+		} else if current.primaryKey != "timestamp,id" {
+			return fmt.Errorf("logs table has an unexpected primary key (%s)", current.primaryKey)
+		}
+	case constants.LogsPrimaryKeyID:
+		if current.primaryKey == "timestamp,id" {
+			clauses = append(clauses, "DROP PRIMARY KEY", "ADD PRIMARY KEY (id)")
+			if current.idIndex {
+				clauses = append(clauses, fmt.Sprintf("DROP INDEX %s", constants.LogsIDIndex))
+			}
+		} else if current.primaryKey != "id" {
+			return fmt.Errorf("logs table has an unexpected primary key (%s)", current.primaryKey)
+		}
+	}
+
+	if len(clauses) == 0 {
+		m.logger.Debug("Logs table already has the configured layout",
+			"compression", current.compression, "primary_key", current.primaryKey)
+		return nil
+	}
+
+	statement := fmt.Sprintf("ALTER TABLE %s %s", constants.DefaultLogsTable, strings.Join(clauses, ", "))
+	m.logger.Info("Changing logs table layout", "statement", statement,
+		"compression", current.compression, "primary_key", current.primaryKey)
+	start := time.Now()
+	online, err := m.executeOnline(ctx, statement)
+	if err != nil {
+		return fmt.Errorf("failed to change logs table layout: %w", err)
+	}
+	if !online {
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to change logs table layout: %w", err)
+		}
+	}
+	m.logger.Info("Changed logs table layout", "duration", time.Since(start), "online", online)
+	return nil
+}
+
+// currentLogsLayout reads the compression and primary key of the logs table
+func (m *MigrationRunner) currentLogsLayout(ctx context.Context) (*logsLayout, error) {
+	database := m.config.Database.Database
+	table := constants.DefaultLogsTable
+
+	var rowFormat, options string
+	query := `SELECT COALESCE(ROW_FORMAT, ''), COALESCE(CREATE_OPTIONS, '') FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`
+	if err := m.db.QueryRowContext(ctx, query, database, table).Scan(&rowFormat, &options); err != nil {
+		return nil, fmt.Errorf("failed to read logs table options: %w", err)
+	}
+
+	layout := &logsLayout{compression: constants.LogsCompressionNone}
+	if strings.EqualFold(rowFormat, "Compressed") {
+		layout.compression = constants.LogsCompressionRow
+		// Compressed tables created without a key block size use half the page size
+		layout.keyBlockSize = constants.DefaultLogsKeyBlockSize
+		if match := keyBlockSizePattern.FindStringSubmatch(options); match != nil {
+			layout.keyBlockSize, _ = strconv.Atoi(match[1])
+		}
+	} else if match := pageCompressionPattern.FindStringSubmatch(options); match != nil && !strings.EqualFold(match[1], "None") {
+		layout.compression = strings.ToLower(match[1])
+	}
+
+	query = `SELECT INDEX_NAME, COLUMN_NAME FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME IN ('PRIMARY', ?) ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+	rows, err := m.db.QueryContext(ctx, query, database, table, constants.LogsIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logs table primary key: %w", err)
+	}
+	defer rows.Close()
+
+	var primaryKey []string
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, fmt.Errorf("failed to read logs table primary key: %w", err)
+		}
+		if index == "PRIMARY" {
+			primaryKey = append(primaryKey, strings.ToLower(column))
+		} else {
+			layout.idIndex = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read logs table primary key: %w", err)
+	}
+	layout.primaryKey = strings.Join(primaryKey, ",")
+	return layout, nil
+}
@@ -0,0 +1,215 @@
+package migrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The migration scripts are written for MySQL. On Postgres each statement is translated before it runs; only the
+// MySQL syntax the scripts use is covered, so new scripts should stick to it.
+var (
+	pgUsePattern            = regexp.MustCompile(`(?i)^USE\s`)
+	pgCreateDatabasePattern = regexp.MustCompile(`(?i)^CREATE\s+DATABASE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w"]+)`)
+	pgCreateTablePattern    = regexp.MustCompile(`(?is)^(CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w"]+)\s*)\((.*)\)$`)
+	pgCreateIndexPattern    = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+|FULLTEXT\s+)?INDEX\s+([\w"]+)\s+ON\s+([\w"]+)\s*\((.*)\)$`)
+	pgDropIndexPattern      = regexp.MustCompile(`(?i)^DROP\s+INDEX\s+([\w"]+)\s+ON\s+([\w"]+)$`)
+	pgModifyColumnPattern   = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+([\w"]+)\s+MODIFY\s+(?:COLUMN\s+)?([\w"]+)\s+(\w+(?:\s*\([^)]*\))?)(?:\s+CHECK\s*\((.*)\))?(.*)$`)
+	pgDefaultPattern        = regexp.MustCompile(`(?i)\bDEFAULT\s+('(?:[^']|'')*'|\S+)`)
+	pgTableOptionsPattern   = regexp.MustCompile(`(?i)\)\s*ENGINE\s*=.*$`)
+	pgCommentPattern        = regexp.MustCompile(`(?i)\s+COMMENT\s+'(?:[^']|'')*'`)
+	pgAfterPattern          = regexp.MustCompile(`(?i)\s+AFTER\s+[\w"]+$`)
+	pgOnUpdatePattern       = regexp.MustCompile(`(?i)\s+ON\s+UPDATE\s+CURRENT_TIMESTAMP(?:\(\d\))?`)
+	pgBigSerialPattern      = regexp.MustCompile(`(?i)\bBIGINT\s+(?:UNSIGNED\s+)?AUTO_INCREMENT\b`)
+	pgSerialPattern         = regexp.MustCompile(`(?i)\bINT(?:EGER)?\s+(?:UNSIGNED\s+)?AUTO_INCREMENT\b`)
+	pgUnsignedPattern       = regexp.MustCompile(`(?i)\s+UNSIGNED\b`)
+	pgDatetimePattern       = regexp.MustCompile(`(?i)\bDATETIME\b`)
+	pgDoublePattern         = regexp.MustCompile(`(?i)\bDOUBLE\b(\s+PRECISION\b)?`)
+	pgTextPattern           = regexp.MustCompile(`(?i)\b(?:TINY|MEDIUM|LONG)TEXT\b`)
+	pgEnumPattern           = regexp.MustCompile(`(?i)([\w"]+)\s+ENUM\s*\(([^)]*)\)`)
+	pgIntervalPattern       = regexp.MustCompile(`(?i)\bINTERVAL\s+(\d+)\s+(SECOND|MINUTE|HOUR|DAY|WEEK|MONTH|YEAR)\b`)
+	pgInlineIndexPattern    = regexp.MustCompile(`(?is)^(UNIQUE\s+|FULLTEXT\s+)?(?:INDEX|KEY)\s+([\w"]+)\s*\((.*)\)$`)
+	pgStringPattern         = regexp.MustCompile(`'(?:[^']|'')*'`)
+)
+
+// pgMySQLPatterns match MySQL syntax left in translated statements, which would fail or behave differently on
+// Postgres
+var pgMySQLPatterns = []*regexp.Regexp{
+	regexp.MustCompile("`"),
+	regexp.MustCompile(`(?i)\bENGINE\s*=`),
+	regexp.MustCompile(`(?i)\b(?:CHARSET|CHARACTER\s+SET)\b`),
+	regexp.MustCompile(`(?i)\bAUTO_INCREMENT\b`),
+	regexp.MustCompile(`(?i)\bUNSIGNED\b`),
+	regexp.MustCompile(`(?i)\bENUM\s*\(`),
+	regexp.MustCompile(`(?i)\b(?:DATETIME|TINYINT|MEDIUMINT|TINYTEXT|MEDIUMTEXT|LONGTEXT|TINYBLOB|MEDIUMBLOB|LONGBLOB)\b`),
+	regexp.MustCompile(`(?i)\bFULLTEXT\b`),
+	regexp.MustCompile(`(?i)\bMODIFY\b`),
+	regexp.MustCompile(`(?i)\bAFTER\s+[\w"]+`),
+	regexp.MustCompile(`(?i)\bCOMMENT\s*=?\s*'`),
+	regexp.MustCompile(`(?i)\bON\s+UPDATE\s+CURRENT_TIMESTAMP\b`),
+	regexp.MustCompile(`(?i)\bON\s+DUPLICATE\s+KEY\b`),
+	regexp.MustCompile(`(?i)\bINSERT\s+IGNORE\b`),
+	regexp.MustCompile(`(?i)\bREPLACE\s+INTO\b`),
+	regexp.MustCompile(`(?i)\bDROP\s+INDEX\s+[\w"]+\s+ON\b`),
+	regexp.MustCompile(`(?i)^\s*(?:USE|OPTIMIZE|LOCK\s+TABLES|SET\s+(?:NAMES|FOREIGN_KEY_CHECKS))\b`),
+	regexp.MustCompile(`(?i)\bINTERVAL\s+-?\d+\s+\w+`),
+	regexp.MustCompile(`(?i)\b(?:IFNULL|IF|DATE_ADD|DATE_SUB|DATE_FORMAT|TIMESTAMPDIFF|UNIX_TIMESTAMP|FROM_UNIXTIME|GROUP_CONCAT|JSON_\w+)\s*\(`),
+}
+
+// untranslatedMySQL returns the MySQL syntax left in a translated statement, outside its string literals, or
+// nothing if there is none
+func untranslatedMySQL(statement string) string {
+	statement = pgStringPattern.ReplaceAllString(statement, "''")
+	for _, pattern := range pgMySQLPatterns {
+		if syntax := pattern.FindString(statement); syntax != "" {
+			return strings.TrimSpace(syntax)
+		}
+	}
+	return ""
+}
+
+// postgresStatements translates a statement of the migration scripts into the Postgres statements doing the same.
+// USE statements translate to none, since Postgres connections are bound to a database, and index definitions
+// inside CREATE TABLE become CREATE INDEX statements following it. Index names are prefixed with their table,
+// as Postgres requires them to be unique in the schema rather than in the table.
+func postgresStatements(statement string) []string {
+	if pgUsePattern.MatchString(statement) {
+		return nil
+	}
+	if match := pgCreateDatabasePattern.FindStringSubmatch(statement); match != nil {
+		// Character set and collation are those of the server's template database
+		return []string{"CREATE DATABASE " + match[1]}
+	}
+
+	statement = strings.ReplaceAll(statement, "`", `"`)
+	statement = pgTableOptionsPattern.ReplaceAllString(statement, ")")
+	statement = pgCommentPattern.ReplaceAllString(statement, "")
+	statement = pgAfterPattern.ReplaceAllString(statement, "")
+	statement = pgOnUpdatePattern.ReplaceAllString(statement, "")
+	statement = pgBigSerialPattern.ReplaceAllString(statement, "BIGSERIAL")
+	statement = pgSerialPattern.ReplaceAllString(statement, "SERIAL")
+	statement = pgUnsignedPattern.ReplaceAllString(statement, "")
+	statement = pgDatetimePattern.ReplaceAllString(statement, "TIMESTAMP")
+	statement = pgDoublePattern.ReplaceAllString(statement, "DOUBLE PRECISION")
+	statement = pgTextPattern.ReplaceAllString(statement, "TEXT")
+	statement = pgIntervalPattern.ReplaceAllString(statement, "INTERVAL '$1 $2'")
+	statement = pgEnumPattern.ReplaceAllStringFunc(statement, postgresEnum)
+
+	if match := pgCreateIndexPattern.FindStringSubmatch(statement); match != nil {
+		return []string{postgresIndex(match[1], match[3], match[2], match[4])}
+	}
+	if match := pgCreateTablePattern.FindStringSubmatch(statement); match != nil {
+		return postgresCreateTable(match[1], match[2], match[3])
+	}
+	if match := pgModifyColumnPattern.FindStringSubmatch(statement); match != nil {
+		return postgresModifyColumn(match[1], match[2], match[3], match[4], match[5])
+	}
+	if match := pgDropIndexPattern.FindStringSubmatch(statement); match != nil {
+		return []string{"DROP INDEX IF EXISTS " + postgresIndexName(match[2], match[1])}
+	}
+	return []string{statement}
+}
+
+// postgresEnum translates an ENUM column type into a VARCHAR fitting the longest value, checked to be one of the
+// values. MySQL compares enum values case-insensitively, so the check does too.
+func postgresEnum(definition string) string {
+	match := pgEnumPattern.FindStringSubmatch(definition)
+	column := match[1]
+
+	size := 0
+	var values []string
+	for _, value := range strings.Split(match[2], ",") {
+		value = strings.Trim(strings.TrimSpace(value), "'")
+		size = max(size, len(value))
+		values = append(values, "'"+strings.ToLower(value)+"'")
+	}
+	return fmt.Sprintf("%s VARCHAR(%d) CHECK (LOWER(%s) IN (%s))", column, size, column, strings.Join(values, ", "))
+}
+
+// postgresModifyColumn translates a MODIFY COLUMN, which redefines the whole column, into the ALTER COLUMN statements
+// changing its type, nullability and default. The check constraint of a translated ENUM is replaced, under the name
+// Postgres gives inline column checks.
+func postgresModifyColumn(table, column, dataType, check, options string) []string {
+	alter := "ALTER TABLE " + table + " "
+	constraint := strings.Trim(table, `"`) + "_" + strings.Trim(column, `"`) + "_check"
+	statements := []string{
+		alter + "DROP CONSTRAINT IF EXISTS " + constraint,
+		alter + "ALTER COLUMN " + column + " TYPE " + dataType,
+	}
+	if strings.Contains(strings.ToUpper(options), "NOT NULL") {
+		statements = append(statements, alter+"ALTER COLUMN "+column+" SET NOT NULL")
+	} else {
+		statements = append(statements, alter+"ALTER COLUMN "+column+" DROP NOT NULL")
+	}
+	if match := pgDefaultPattern.FindStringSubmatch(options); match != nil {
+		statements = append(statements, alter+"ALTER COLUMN "+column+" SET DEFAULT "+match[1])
+	} else {
+		statements = append(statements, alter+"ALTER COLUMN "+column+" DROP DEFAULT")
+	}
+	if check != "" {
+		statements = append(statements, alter+"ADD CONSTRAINT "+constraint+" CHECK ("+check+")")
+	}
+	return statements
+}
+
+// postgresCreateTable moves the index definitions of a CREATE TABLE into CREATE INDEX statements, and turns unique
+// keys into unique constraints
+func postgresCreateTable(prefix, table, body string) []string {
+	var definitions, indexes []string
+	for _, definition := range splitDefinitions(body) {
+		match := pgInlineIndexPattern.FindStringSubmatch(definition)
+		switch {
+		case match == nil:
+			definitions = append(definitions, definition)
+		case strings.EqualFold(strings.TrimSpace(match[1]), "UNIQUE"):
+			definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", postgresIndexName(table, match[2]), match[3]))
+		default:
+			indexes = append(indexes, postgresIndex(match[1], table, match[2], match[3]))
+		}
+	}
+	return append([]string{prefix + "(" + strings.Join(definitions, ", ") + ")"}, indexes...)
+}
+
+// postgresIndex builds a CREATE INDEX statement. Full-text indexes become GIN indexes of the columns' text search
+// vectors.
+func postgresIndex(kind, table, name, columns string) string {
+	kind = strings.ToUpper(strings.TrimSpace(kind))
+	name = postgresIndexName(table, name)
+	switch kind {
+	case "FULLTEXT":
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (to_tsvector('simple', %s))",
+			name, table, strings.Join(strings.Split(columns, ","), " || ' ' || "))
+	case "UNIQUE":
+		return fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)", name, table, columns)
+	default:
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", name, table, columns)
+	}
+}
+
+// postgresIndexName prefixes an index name with its table
+func postgresIndexName(table, name string) string {
+	return strings.Trim(table, `"`) + "_" + strings.Trim(name, `"`)
+}
+
+// splitDefinitions splits the body of a CREATE TABLE at the commas separating its definitions, leaving commas
+// inside parentheses and string literals alone
+func splitDefinitions(body string) []string {
+	var definitions []string
+	depth, start := 0, 0
+	quoted := false
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			definitions = append(definitions, strings.TrimSpace(body[start:i]))
+			start = i + 1
+		}
+	}
+	return append(definitions, strings.TrimSpace(body[start:]))
+}
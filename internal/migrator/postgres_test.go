@@ -0,0 +1,217 @@
+package migrator
+
+import (
+	"io"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/scripts/migrations"
+)
+
+// newTestRunner returns a runner for the driver without a database connection
+func newTestRunner(driver string) *MigrationRunner {
+	return &MigrationRunner{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config: &config.Config{Database: config.DatabaseConfig{Driver: driver}},
+	}
+}
+
+func TestPostgresStatements(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		want      []string
+	}{
+		{
+			name:      "use",
+			statement: "USE log_analytics",
+			want:      nil,
+		},
+		{
+			name:      "create database",
+			statement: "CREATE DATABASE IF NOT EXISTS log_analytics CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci",
+			want:      []string{"CREATE DATABASE log_analytics"},
+		},
+		{
+			name:      "create table with inline indexes",
+			statement: "CREATE TABLE IF NOT EXISTS `spans` (id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY, kind ENUM('a','bc') NOT NULL, started DATETIME(3) NOT NULL COMMENT 'start', body LONGTEXT, UNIQUE KEY uk_kind (kind), INDEX idx_started (started)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			want: []string{
+				`CREATE TABLE IF NOT EXISTS "spans" (id BIGSERIAL PRIMARY KEY, kind VARCHAR(2) CHECK (LOWER(kind) IN ('a', 'bc')) NOT NULL, started TIMESTAMP(3) NOT NULL, body TEXT, CONSTRAINT spans_uk_kind UNIQUE (kind))`,
+				`CREATE INDEX IF NOT EXISTS spans_idx_started ON "spans" (started)`,
+			},
+		},
+		{
+			name:      "full-text index",
+			statement: "CREATE FULLTEXT INDEX ft_message ON alerts (message)",
+			want:      []string{"CREATE INDEX IF NOT EXISTS alerts_ft_message ON alerts USING GIN (to_tsvector('simple', message))"},
+		},
+		{
+			name:      "drop index",
+			statement: "DROP INDEX idx_host ON logs",
+			want:      []string{"DROP INDEX IF EXISTS logs_idx_host"},
+		},
+		{
+			name:      "add column after",
+			statement: "ALTER TABLE logs ADD COLUMN span_id VARCHAR(32) NULL AFTER trace_id",
+			want:      []string{"ALTER TABLE logs ADD COLUMN span_id VARCHAR(32) NULL"},
+		},
+		{
+			name:      "modify enum column",
+			statement: "ALTER TABLE channels MODIFY COLUMN type ENUM('email','slack') NOT NULL DEFAULT 'email'",
+			want: []string{
+				"ALTER TABLE channels DROP CONSTRAINT IF EXISTS channels_type_check",
+				"ALTER TABLE channels ALTER COLUMN type TYPE VARCHAR(5)",
+				"ALTER TABLE channels ALTER COLUMN type SET NOT NULL",
+				"ALTER TABLE channels ALTER COLUMN type SET DEFAULT 'email'",
+				"ALTER TABLE channels ADD CONSTRAINT channels_type_check CHECK (LOWER(type) IN ('email', 'slack'))",
+			},
+		},
+		{
+			name:      "interval",
+			statement: "INSERT INTO logs (timestamp) VALUES (NOW() - INTERVAL 30 MINUTE)",
+			want:      []string{"INSERT INTO logs (timestamp) VALUES (NOW() - INTERVAL '30 MINUTE')"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := postgresStatements(tt.statement)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("postgresStatements(%q)\n got %q\nwant %q", tt.statement, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUntranslatedMySQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{name: "portable", statement: "ALTER TABLE logs ADD COLUMN tenant VARCHAR(64) NULL", want: ""},
+		{name: "syntax inside string literal", statement: "INSERT INTO rules (note) VALUES ('uses `ticks` and ON DUPLICATE KEY')", want: ""},
+		{name: "backtick", statement: "SELECT `id` FROM logs", want: "`"},
+		{name: "upsert", statement: "INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2", want: "ON DUPLICATE KEY"},
+		{name: "insert ignore", statement: "INSERT IGNORE INTO t (a) VALUES (1)", want: "INSERT IGNORE"},
+		{name: "function", statement: "UPDATE t SET a = IFNULL(b, 0)", want: "IFNULL("},
+		{name: "json function", statement: "SELECT JSON_EXTRACT(attributes, '$.a') FROM logs", want: "JSON_EXTRACT("},
+		{name: "tinyint", statement: "ALTER TABLE t ADD COLUMN flag TINYINT(1) NOT NULL", want: "TINYINT"},
+		{name: "unquoted interval", statement: "DELETE FROM t WHERE at < NOW() - INTERVAL 1 DAY", want: "INTERVAL 1 DAY"},
+		{name: "optimize", statement: "OPTIMIZE TABLE alerts", want: "OPTIMIZE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := untranslatedMySQL(tt.statement); got != tt.want {
+				t.Errorf("untranslatedMySQL(%q) = %q, want %q", tt.statement, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMigrationScriptsTranslate translates every migration script for Postgres, so that scripts using MySQL syntax
+// the translation doesn't cover fail here rather than on a Postgres deployment
+func TestMigrationScriptsTranslate(t *testing.T) {
+	runner := newTestRunner(constants.DBDriverPostgres)
+	loaded, err := runner.LoadMigrations(migrations.Files)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations loaded")
+	}
+	for _, migration := range loaded {
+		t.Run(migration.Filename, func(t *testing.T) {
+			if _, err := runner.statements(migration.Content, migration.Postgres); err != nil {
+				t.Errorf("failed to translate %s: %v", migration.Filename, err)
+			}
+			if migration.Down == "" {
+				return
+			}
+			if _, err := runner.statements(migration.Down, migration.PostgresDown); err != nil {
+				t.Errorf("failed to translate %s: %v", migration.DownFilename, err)
+			}
+		})
+	}
+}
+
+func TestStatementsPostgresScripts(t *testing.T) {
+	source := fstest.MapFS{
+		"005_tags.sql":               {Data: []byte("ALTER TABLE logs ADD COLUMN tags JSON NULL;")},
+		"005_tags.postgres.sql":      {Data: []byte("ALTER TABLE logs ADD COLUMN tags JSONB NULL;")},
+		"005_tags.down.sql":          {Data: []byte("ALTER TABLE logs DROP COLUMN tags;")},
+		"005_tags.postgres.down.sql": {Data: []byte("ALTER TABLE logs DROP COLUMN IF EXISTS tags;")},
+		"006_upsert.sql":             {Data: []byte("INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2;")},
+	}
+
+	tests := []struct {
+		name     string
+		driver   string
+		id       string
+		down     bool
+		want     []string
+		wantFail bool
+	}{
+		{name: "mysql runs the script", driver: constants.DBDriverMySQL, id: "005", want: []string{"ALTER TABLE logs ADD COLUMN tags JSON NULL"}},
+		{name: "postgres runs its script", driver: constants.DBDriverPostgres, id: "005", want: []string{"ALTER TABLE logs ADD COLUMN tags JSONB NULL"}},
+		{name: "postgres runs its down script", driver: constants.DBDriverPostgres, id: "005", down: true, want: []string{"ALTER TABLE logs DROP COLUMN IF EXISTS tags"}},
+		{name: "mysql runs untranslatable syntax", driver: constants.DBDriverMySQL, id: "006", want: []string{"INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2"}},
+		{name: "postgres refuses untranslatable syntax", driver: constants.DBDriverPostgres, id: "006", wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := newTestRunner(tt.driver)
+			loaded, err := runner.LoadMigrations(source)
+			if err != nil {
+				t.Fatalf("failed to load migrations: %v", err)
+			}
+			i := slices.IndexFunc(loaded, func(migration Migration) bool { return migration.ID == tt.id })
+			if i < 0 {
+				t.Fatalf("migration %s not loaded", tt.id)
+			}
+			migration := loaded[i]
+			content, postgres := migration.Content, migration.Postgres
+			if tt.down {
+				content, postgres = migration.Down, migration.PostgresDown
+			}
+
+			got, err := runner.statements(content, postgres)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("got statements %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got statements %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsRejectsUnpairedPostgresScripts(t *testing.T) {
+	tests := []struct {
+		name   string
+		source fs.FS
+	}{
+		{name: "script", source: fstest.MapFS{"007_orphan.postgres.sql": {Data: []byte("SELECT 1;")}}},
+		{name: "down script", source: fstest.MapFS{
+			"007_orphan.sql":               {Data: []byte("SELECT 1;")},
+			"008_orphan.postgres.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestRunner(constants.DBDriverPostgres).LoadMigrations(tt.source); err == nil {
+				t.Error("got no error for a Postgres script without its migration")
+			}
+		})
+	}
+}
@@ -0,0 +1,861 @@
+// Package migrator applies and rolls back the SQL migrations of the database, either with the migration command
+// or on the startup of the services.
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/onlineddl"
+	"github.com/adeesh/log-analytics/scripts/migrations"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Migration represents a database migration
+type Migration struct {
+	ID           string
+	Filename     string
+	Content      string
+	DownFilename string // paired NNN_name.down.sql script reverting the migration, if any
+	Down         string
+	Postgres     string // NNN_name.postgres.sql script run instead of translating the migration on Postgres, if any
+	PostgresDown string // NNN_name.postgres.down.sql script run instead of translating the down script, if any
+}
+
+// MigrationRunner handles database migrations
+type MigrationRunner struct {
+	db        *sql.DB
+	logger    *slog.Logger
+	config    *config.Config
+	onlineDDL *onlineddl.Runner
+	appliedBy string // recorded as who applied or rolled back the migrations
+	batch     int    // batch of this run, assigned when it records its first migration
+	history   bool   // whether the migrations table has the applied by, duration and batch columns
+}
+
+// NewMigrationRunner creates a new migration runner
+func NewMigrationRunner(cfg *config.Config, logger *slog.Logger) (*MigrationRunner, error) {
+	if err := cfg.Database.Validate(); err != nil {
+		return nil, err
+	}
+	onlineDDL, err := onlineddl.NewRunner(cfg.Migration, cfg.Database, logger)
+	if err != nil {
+		return nil, err
+	}
+	if onlineDDL.Enabled() && cfg.Database.Driver != constants.DBDriverMySQL {
+		return nil, fmt.Errorf("online DDL tools only support MySQL, not %s", cfg.Database.Driver)
+	}
+	if (cfg.Migration.LogsCompression != "" || cfg.Migration.LogsPrimaryKey != "") && cfg.Database.Driver != constants.DBDriverMySQL {
+		return nil, fmt.Errorf("the logs table layout options only support MySQL, not %s", cfg.Database.Driver)
+	}
+
+	// First, try to connect to the database server without specifying a database
+	db, err := sql.Open(sqlDriverName(cfg.Database.Driver), cfg.Database.DSN(""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database server: %w", err)
+	}
+
+	// Test connection to the database server
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database server: %w", err)
+	}
+
+	logger.Info("Connected to database server successfully", "driver", cfg.Database.Driver)
+
+	// Configure connection pool
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
+	appliedBy := cfg.Migration.AppliedBy
+	if appliedBy == "" {
+		appliedBy = currentOperator()
+	}
+
+	return &MigrationRunner{
+		db:        db,
+		logger:    logger,
+		config:    cfg,
+		onlineDDL: onlineDDL,
+		appliedBy: appliedBy,
+	}, nil
+}
+
+// sqlDriverName returns the database/sql driver connecting with the configured database driver
+func sqlDriverName(driver string) string {
+	if driver == constants.DBDriverPostgres {
+		return "pgx"
+	}
+	return "mysql"
+}
+
+// postgres reports whether migrations run against Postgres
+func (m *MigrationRunner) postgres() bool {
+	return m.config.Database.Driver == constants.DBDriverPostgres
+}
+
+// bind rewrites the ? placeholders of a query into the $n placeholders Postgres expects
+func (m *MigrationRunner) bind(query string) string {
+	if !m.postgres() {
+		return query
+	}
+	var bound strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&bound, "$%d", n)
+			continue
+		}
+		bound.WriteRune(c)
+	}
+	return bound.String()
+}
+
+// currentOperator identifies who runs the migrations as user@host
+func currentOperator() string {
+	username := "unknown"
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return username + "@" + host
+}
+
+// Close closes the database connection
+func (m *MigrationRunner) Close() error {
+	return m.db.Close()
+}
+
+// LoadMigrations loads all migration files of the source. A migration is either a single
+// NNN_name.sql file or an NNN_name.up.sql file, paired with the NNN_name.down.sql file reverting it, if any.
+// NNN_name.postgres.sql and NNN_name.postgres.down.sql files replace the translation of either script on Postgres.
+func (m *MigrationRunner) LoadMigrations(source fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []Migration
+	downs := make(map[string]Migration)
+	postgresUps := make(map[string]string)
+	postgresDowns := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".sql") {
+			continue
+		}
+
+		// Extract migration ID from filename (e.g., "001_initial_schema.sql" -> "001")
+		parts := strings.Split(file.Name(), "_")
+		if len(parts) < 2 {
+			m.logger.Warn("Skipping migration file with invalid name", "filename", file.Name())
+			continue
+		}
+
+		migrationID := parts[0]
+
+		// Read migration content
+		content, err := fs.ReadFile(source, file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", file.Name(), err)
+		}
+
+		switch {
+		case strings.HasSuffix(file.Name(), ".postgres.down.sql"):
+			postgresDowns[migrationID] = string(content)
+			continue
+		case strings.HasSuffix(file.Name(), ".postgres.sql"), strings.HasSuffix(file.Name(), ".postgres.up.sql"):
+			postgresUps[migrationID] = string(content)
+			continue
+		case strings.HasSuffix(file.Name(), ".down.sql"):
+			downs[migrationID] = Migration{ID: migrationID, DownFilename: file.Name(), Down: string(content)}
+			continue
+		}
+		migrations = append(migrations, Migration{
+			ID:       migrationID,
+			Filename: file.Name(),
+			Content:  string(content),
+		})
+	}
+
+	// Pair the down scripts with their migrations
+	for i := range migrations {
+		if down, ok := downs[migrations[i].ID]; ok {
+			migrations[i].DownFilename = down.DownFilename
+			migrations[i].Down = down.Down
+			delete(downs, migrations[i].ID)
+		}
+	}
+	for _, down := range downs {
+		return nil, fmt.Errorf("down migration %s has no up migration", down.DownFilename)
+	}
+
+	// Pair the Postgres scripts with their migrations
+	for i := range migrations {
+		migrations[i].Postgres = postgresUps[migrations[i].ID]
+		migrations[i].PostgresDown = postgresDowns[migrations[i].ID]
+		delete(postgresUps, migrations[i].ID)
+		delete(postgresDowns, migrations[i].ID)
+	}
+	for id := range postgresUps {
+		return nil, fmt.Errorf("Postgres migration %s has no migration", id)
+	}
+	for id := range postgresDowns {
+		return nil, fmt.Errorf("Postgres down migration %s has no migration", id)
+	}
+
+	// Sort migrations by ID
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].ID < migrations[j].ID
+	})
+
+	return migrations, nil
+}
+
+// GetAppliedMigrations gets the list of already applied migrations, leaving out those rolled back since
+func (m *MigrationRunner) GetAppliedMigrations(ctx context.Context) (map[string]bool, error) {
+	query := `SELECT id FROM migrations`
+	rollbacks, err := m.hasMigrationsColumn(ctx, m.db, "rolled_back_at")
+	if err != nil {
+		return nil, err
+	}
+	if rollbacks {
+		query += ` WHERE rolled_back_at IS NULL`
+	}
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		applied[id] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// ApplyMigration applies a single migration
+func (m *MigrationRunner) ApplyMigration(ctx context.Context, migration Migration) error {
+	m.logger.Info("Applying migration", "id", migration.ID, "filename", migration.Filename)
+	start := time.Now()
+
+	// Start transaction
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements, err := m.statements(migration.Content, migration.Postgres)
+	if err != nil {
+		return fmt.Errorf("failed to translate migration %s: %w", migration.ID, err)
+	}
+
+	// Execute each statement
+	for i, statement := range statements {
+		m.logger.Debug("Executing SQL statement", "migration", migration.ID, "statement", i+1)
+		if err := m.executeStatement(ctx, tx, statement); err != nil {
+			return fmt.Errorf("failed to execute migration %s statement %d: %w", migration.ID, i+1, err)
+		}
+	}
+
+	// For the first migration (database creation), don't record it in migrations table
+	if migration.ID == "000" {
+		m.logger.Info("Database creation migration completed, skipping migration record")
+		// Commit transaction without recording migration
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", migration.ID, err)
+		}
+		return nil
+	}
+
+	// For the migrations table creation migration, don't record it either
+	if migration.ID == "001" {
+		m.logger.Info("Migrations table creation migration completed, skipping migration record")
+		// Commit transaction without recording migration
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", migration.ID, err)
+		}
+		return nil
+	}
+
+	// Record migration as applied
+	if err := m.recordMigration(ctx, tx, migration, time.Since(start)); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.ID, err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.ID, err)
+	}
+
+	m.logger.Info("Migration applied successfully", "id", migration.ID, "filename", migration.Filename, "duration", time.Since(start))
+	return nil
+}
+
+// RollbackMigration reverts a single migration with its down script, in a transaction along with the record of
+// the rollback
+func (m *MigrationRunner) RollbackMigration(ctx context.Context, migration Migration) error {
+	m.logger.Info("Rolling back migration", "id", migration.ID, "filename", migration.DownFilename)
+	start := time.Now()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements, err := m.statements(migration.Down, migration.PostgresDown)
+	if err != nil {
+		return fmt.Errorf("failed to translate rollback of migration %s: %w", migration.ID, err)
+	}
+	for i, statement := range statements {
+		m.logger.Debug("Executing SQL statement", "migration", migration.ID, "statement", i+1)
+		if err := m.executeStatement(ctx, tx, statement); err != nil {
+			return fmt.Errorf("failed to execute rollback of migration %s statement %d: %w", migration.ID, i+1, err)
+		}
+	}
+
+	if err := m.recordRollback(ctx, tx, migration); err != nil {
+		return fmt.Errorf("failed to record rollback of migration %s: %w", migration.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %s: %w", migration.ID, err)
+	}
+
+	m.logger.Info("Migration rolled back successfully", "id", migration.ID, "filename", migration.DownFilename, "duration", time.Since(start))
+	return nil
+}
+
+// statements splits SQL content into the statements to execute. Against Postgres, the Postgres script of the content
+// runs as it is if there is one; otherwise the statements are translated, failing on MySQL syntax the translation
+// doesn't cover rather than running it.
+func (m *MigrationRunner) statements(content, postgres string) ([]string, error) {
+	if !m.postgres() {
+		return m.splitSQLStatements(content), nil
+	}
+	if postgres != "" {
+		return m.splitSQLStatements(postgres), nil
+	}
+	return m.translateStatements(content)
+}
+
+// translateStatements splits SQL content into statements translated for Postgres, failing on any of them left with
+// MySQL syntax
+func (m *MigrationRunner) translateStatements(content string) ([]string, error) {
+	var translated []string
+	for i, statement := range m.splitSQLStatements(content) {
+		for _, postgres := range postgresStatements(statement) {
+			if syntax := untranslatedMySQL(postgres); syntax != "" {
+				return nil, fmt.Errorf("statement %d uses MySQL syntax %q that isn't translated for Postgres, add a .postgres.sql script", i+1, syntax)
+			}
+			translated = append(translated, postgres)
+		}
+	}
+	return translated, nil
+}
+
+// queryer is implemented by both the connection pool and transactions
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// hasMigrationsColumn reports whether the migrations table has the column, which depends on the migrations applied
+func (m *MigrationRunner) hasMigrationsColumn(ctx context.Context, q queryer, column string) (bool, error) {
+	query := `SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'migrations' AND COLUMN_NAME = ?`
+	if m.postgres() {
+		query = `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'migrations' AND column_name = $1`
+	}
+	var columns int
+	if err := q.QueryRowContext(ctx, query, column).Scan(&columns); err != nil {
+		return false, fmt.Errorf("failed to inspect migrations table: %w", err)
+	}
+	return columns > 0, nil
+}
+
+// recordMigration records a migration as applied. Who applied it, how long it took and the batch of the run are
+// recorded once the migrations table has the columns for them, which migration 017 adds. A migration applied again
+// after a rollback reuses the row that recorded the rollback.
+func (m *MigrationRunner) recordMigration(ctx context.Context, tx *sql.Tx, migration Migration, duration time.Duration) error {
+	checksum := m.generateChecksum(migration.Content)
+	if !m.history {
+		history, err := m.hasMigrationsColumn(ctx, tx, "batch")
+		if err != nil {
+			return err
+		}
+		m.history = history
+	}
+	if !m.history {
+		_, err := tx.ExecContext(ctx, m.bind(`INSERT INTO migrations (id, filename, checksum) VALUES (?, ?, ?)`),
+			migration.ID, migration.Filename, checksum)
+		return err
+	}
+
+	if m.batch == 0 {
+		if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(batch), 0) + 1 FROM migrations`).Scan(&m.batch); err != nil {
+			return fmt.Errorf("failed to get migration batch: %w", err)
+		}
+	}
+
+	rollbacks, err := m.hasMigrationsColumn(ctx, tx, "rolled_back_at")
+	if err != nil {
+		return err
+	}
+	if rollbacks {
+		result, err := tx.ExecContext(ctx, m.bind(`UPDATE migrations SET filename = ?, checksum = ?, applied_at = CURRENT_TIMESTAMP, applied_by = ?, duration_ms = ?, batch = ?, rolled_back_at = NULL, rolled_back_by = NULL WHERE id = ? AND rolled_back_at IS NOT NULL`),
+			migration.Filename, checksum, m.appliedBy, duration.Milliseconds(), m.batch, migration.ID)
+		if err != nil {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, m.bind(`INSERT INTO migrations (id, filename, checksum, applied_by, duration_ms, batch) VALUES (?, ?, ?, ?, ?, ?)`),
+		migration.ID, migration.Filename, checksum, m.appliedBy, duration.Milliseconds(), m.batch)
+	return err
+}
+
+// recordRollback marks a migration as rolled back, along with who rolled it back, once the migrations table has the
+// columns for it, which migration 027 adds. Until then, and once 027 itself is rolled back, the row is deleted instead.
+func (m *MigrationRunner) recordRollback(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	rollbacks, err := m.hasMigrationsColumn(ctx, tx, "rolled_back_at")
+	if err != nil {
+		return err
+	}
+	if !rollbacks {
+		_, err := tx.ExecContext(ctx, m.bind(`DELETE FROM migrations WHERE id = ?`), migration.ID)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, m.bind(`UPDATE migrations SET rolled_back_at = CURRENT_TIMESTAMP, rolled_back_by = ? WHERE id = ?`),
+		m.appliedBy, migration.ID)
+	return err
+}
+
+// splitSQLStatements splits SQL content into individual statements
+func (m *MigrationRunner) splitSQLStatements(content string) []string {
+	// Remove comments
+	lines := strings.Split(content, "\n")
+	var cleanLines []string
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		cleanLines = append(cleanLines, line)
+	}
+
+	// Join lines and split by semicolon, leaving semicolons inside string literals alone
+	cleanContent := strings.Join(cleanLines, " ")
+	var statements []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(cleanContent); i++ {
+		switch cleanContent[i] {
+		case '\'':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				statements = append(statements, cleanContent[start:i])
+				start = i + 1
+			}
+		}
+	}
+	statements = append(statements, cleanContent[start:])
+
+	var result []string
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if stmt != "" {
+			result = append(result, stmt)
+		}
+	}
+
+	return result
+}
+
+// executeStatement executes a single SQL statement, handling USE statements specially
+func (m *MigrationRunner) executeStatement(ctx context.Context, tx *sql.Tx, statement string) error {
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return nil
+	}
+
+	// Handle USE statements by executing them directly on the connection
+	if strings.HasPrefix(strings.ToUpper(statement), "USE ") {
+		// Execute USE statement on the connection, not in transaction
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to execute USE statement: %w", err)
+		}
+		return nil
+	}
+
+	// Postgres can't create databases inside a transaction, nor skip existing ones
+	if m.postgres() {
+		if match := pgCreateDatabasePattern.FindStringSubmatch(statement); match != nil {
+			return m.createPostgresDatabase(ctx, statement, strings.Trim(match[1], `"`))
+		}
+	}
+
+	// Hand ALTERs of large tables to the online DDL tool
+	if online, err := m.executeOnline(ctx, statement); online || err != nil {
+		return err
+	}
+
+	// Execute other statements in the transaction
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("failed to execute statement: %w", err)
+	}
+
+	return nil
+}
+
+// createPostgresDatabase creates a Postgres database unless it exists
+func (m *MigrationRunner) createPostgresDatabase(ctx context.Context, statement, name string) error {
+	var exists int
+	err := m.db.QueryRowContext(ctx, `SELECT 1 FROM pg_database WHERE datname = $1`, name).Scan(&exists)
+	if err == nil {
+		m.logger.Info("Database already exists", "database", name)
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check database %s: %w", name, err)
+	}
+	if _, err := m.db.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", name, err)
+	}
+	return nil
+}
+
+// executeOnline runs a schema change through the online DDL tool when one is configured and the table has
+// at least the configured number of rows, reporting whether it did. MySQL commits DDL implicitly, so running
+// it outside the migration's transaction doesn't change what a failed migration leaves behind.
+func (m *MigrationRunner) executeOnline(ctx context.Context, statement string) (bool, error) {
+	if !m.onlineDDL.Enabled() {
+		return false, nil
+	}
+	table, alter, ok := onlineddl.ParseAlter(statement)
+	if !ok {
+		return false, nil
+	}
+
+	// TABLE_ROWS is an estimate, which is all the threshold needs
+	var rows int64
+	query := `SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`
+	err := m.db.QueryRowContext(ctx, query, m.config.Database.Database, table).Scan(&rows)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to estimate rows of table %s: %w", table, err)
+	}
+	if rows < m.onlineDDL.MinRows() {
+		m.logger.Debug("Table below online DDL threshold, altering directly", "table", table, "rows", rows)
+		return false, nil
+	}
+
+	if err := m.onlineDDL.Run(ctx, table, alter); err != nil {
+		return true, fmt.Errorf("failed to execute online schema change: %w", err)
+	}
+	return true, nil
+}
+
+// generateChecksum generates a SHA256 hash of the content
+func (m *MigrationRunner) generateChecksum(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%x", hash)
+}
+
+// RunMigrations runs all pending migrations
+func (m *MigrationRunner) RunMigrations(source fs.FS) error {
+	ctx := context.Background()
+
+	// Load migrations
+	migrations, err := m.LoadMigrations(source)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		m.logger.Info("No migration files found")
+		return nil
+	}
+
+	m.logger.Info("Found migrations", "count", len(migrations))
+	for _, migration := range migrations {
+		m.logger.Debug("Migration file", "id", migration.ID, "filename", migration.Filename)
+	}
+
+	// Runs started concurrently, e.g. by instances migrating on startup, would apply the same migrations twice.
+	// The applied migrations are read once the lock is held, so a run that waited skips what the other applied.
+	lock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.releaseLock(lock)
+
+	// Get applied migrations (only after migrations table exists)
+	applied := make(map[string]bool)
+	if len(migrations) > 0 && (migrations[0].ID == "000" || migrations[0].ID == "001") {
+		// First migrations create database and migrations table, so we can't check applied migrations yet
+		m.logger.Info("First migrations will create database and migrations table, skipping applied migrations check")
+	} else {
+		// Get applied migrations
+		applied, err = m.GetAppliedMigrations(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+	}
+
+	// Apply pending migrations
+	appliedCount := 0
+	for i, migration := range migrations {
+		// For the first two migrations (database creation and migrations table), always apply them
+		if i <= 1 && (migration.ID == "000" || migration.ID == "001") {
+			if err := m.ApplyMigration(ctx, migration); err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration.ID, err)
+			}
+			appliedCount++
+
+			// After first migration, reconnect to the specific database
+			if i == 0 && migration.ID == "000" {
+				m.logger.Info("Reconnecting to specific database after creation")
+				if err := m.reconnectToDatabase(); err != nil {
+					return fmt.Errorf("failed to reconnect to database: %w", err)
+				}
+			}
+			continue
+		}
+
+		// Before handling subsequent migrations, ensure we have the applied set
+		// This covers the case where we just created the DB and migrations table
+		if len(applied) == 0 {
+			var err error
+			applied, err = m.GetAppliedMigrations(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get applied migrations: %w", err)
+			}
+		}
+
+		// For subsequent migrations, check if already applied
+		if applied[migration.ID] {
+			m.logger.Debug("Migration already applied", "id", migration.ID, "filename", migration.Filename)
+			continue
+		}
+
+		if err := m.ApplyMigration(ctx, migration); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", migration.ID, err)
+		}
+
+		appliedCount++
+	}
+
+	m.logger.Info("Migrations completed", "applied", appliedCount, "total", len(migrations))
+
+	// The layout is applied once the logs table exists, and on every run, so that changing it takes effect
+	return m.applyLogsLayout(ctx)
+}
+
+// RollbackMigrations reverts the last count applied migrations, newest first. Every migration to revert must have a
+// down script, which is checked before any is run; a failing rollback stops the run, leaving the migrations reverted
+// before it rolled back. MySQL commits DDL statements implicitly, so there a failing down script can leave part of
+// its changes behind, as a failing migration does.
+func (m *MigrationRunner) RollbackMigrations(source fs.FS, count int) error {
+	ctx := context.Background()
+
+	migrations, err := m.LoadMigrations(source)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	byID := make(map[string]Migration, len(migrations))
+	for _, migration := range migrations {
+		byID[migration.ID] = migration
+	}
+
+	lock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.releaseLock(lock)
+
+	if err := m.reconnectToDatabase(); err != nil {
+		return fmt.Errorf("failed to reconnect to database: %w", err)
+	}
+	applied, err := m.GetAppliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	// Newest first
+	var ids []string
+	for id := range applied {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	if len(ids) > count {
+		ids = ids[:count]
+	}
+	if len(ids) == 0 {
+		m.logger.Info("No applied migrations to roll back")
+		return nil
+	}
+
+	for _, id := range ids {
+		migration, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("applied migration %s has no migration file", id)
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("migration %s has no down migration", migration.Filename)
+		}
+	}
+
+	for _, id := range ids {
+		if err := m.RollbackMigration(ctx, byID[id]); err != nil {
+			return fmt.Errorf("failed to roll back migration %s: %w", id, err)
+		}
+	}
+
+	m.logger.Info("Rollback completed", "rolled_back", len(ids))
+	return nil
+}
+
+// reconnectToDatabase reconnects to the specific database after it's created
+func (m *MigrationRunner) reconnectToDatabase() error {
+	// Close current connection
+	m.db.Close()
+
+	// Connect to the specific database
+	db, err := sql.Open(sqlDriverName(m.config.Database.Driver), m.config.Database.DSN(m.config.Database.Database))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Test connection to the specific database
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Configure connection pool
+	db.SetMaxOpenConns(m.config.Database.MaxOpenConns)
+	db.SetMaxIdleConns(m.config.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(m.config.Database.ConnMaxLifetime)
+
+	// Update the connection
+	m.db = db
+
+	m.logger.Info("Reconnected to database successfully", "database", m.config.Database.Database)
+	return nil
+}
+
+// ShowStatus shows the current migration status
+func (m *MigrationRunner) ShowStatus(source fs.FS) error {
+	ctx := context.Background()
+
+	// Load migrations
+	migrations, err := m.LoadMigrations(source)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	// Try to connect to the specific database first
+	originalDB := m.db
+	if err := m.reconnectToDatabase(); err != nil {
+		// Database doesn't exist yet, all migrations are pending
+		m.logger.Info("Database does not exist yet, all migrations are pending")
+		for _, migration := range migrations {
+			m.logger.Info("Migration", "id", migration.ID, "filename", migration.Filename, "status", "PENDING")
+		}
+		// Restore original connection
+		m.db = originalDB
+		return nil
+	}
+
+	// Get applied migrations
+	applied, err := m.GetAppliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	m.logger.Info("Migration Status", "total", len(migrations))
+	for _, migration := range migrations {
+		status := "PENDING"
+		if applied[migration.ID] {
+			status = "APPLIED"
+		}
+		m.logger.Info("Migration", "id", migration.ID, "filename", migration.Filename, "status", status)
+	}
+
+	return nil
+}
+
+// SetupDatabase performs complete database setup
+func (m *MigrationRunner) SetupDatabase(source fs.FS) error {
+	m.logger.Info("Starting complete database setup")
+
+	// Run migrations (this will create the database and migrations table)
+	if err := m.RunMigrations(source); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	m.logger.Info("Database setup completed successfully")
+	return nil
+}
+
+// Source returns the migrations in the directory, or those embedded in the binary when no directory is given
+func Source(dir string) fs.FS {
+	if dir == "" {
+		return migrations.Files
+	}
+	return os.DirFS(dir)
+}
+
+// RunOnStartup applies the pending migrations before a service starts, when it is configured to. Instances starting
+// together take turns through the migration lock, and the later ones find nothing left to apply.
+func RunOnStartup(cfg *config.Config, logger *slog.Logger) error {
+	if !cfg.Migration.OnStartup {
+		return nil
+	}
+	logger.Info("Running database migrations on startup")
+	runner, err := NewMigrationRunner(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create migration runner: %w", err)
+	}
+	defer runner.Close()
+
+	if err := runner.RunMigrations(Source(cfg.Migration.Dir)); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
@@ -1,24 +1,148 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// AlertSeverity is the severity of an alert rule and its alerts: low, medium, high or critical
+type AlertSeverity string
+
+// GormDBDataType stores severities as an enum of the four severities
+func (AlertSeverity) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return enumDataType(db, constants.AlertSeverityLow, constants.AlertSeverityMedium, constants.AlertSeverityHigh, constants.AlertSeverityCritical)
+}
+
+// AlertStatus is the status of an alert: active, resolved or acknowledged
+type AlertStatus string
+
+// GormDBDataType stores statuses as an enum of the three statuses
+func (AlertStatus) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return enumDataType(db, constants.AlertStatusActive, constants.AlertStatusResolved, constants.AlertStatusAcknowledged)
+}
+
 // Alert represents a triggered alert
 type Alert struct {
-	ID             uint      `json:"id" gorm:"primaryKey"`
-	RuleID         uint      `json:"rule_id" gorm:"not null"`
-	Rule           AlertRule `json:"rule" gorm:"foreignKey:RuleID"`
-	Message        string    `json:"message" gorm:"not null"`
-	Severity       string    `json:"severity" gorm:"type:enum('low','medium','high','critical');not null"`
-	Value          float64   `json:"value" gorm:"not null"` // actual value that triggered the alert
-	Status         string    `json:"status" gorm:"type:enum('active','resolved','acknowledged');default:'active'"` // active, resolved, acknowledged
-	CreatedAt      time.Time `json:"created_at"`
-	ResolvedAt     *time.Time `json:"resolved_at"`
-	AcknowledgedAt *time.Time `json:"acknowledged_at"`
-}
-
-// AlertStats represents alert statistics
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	RuleID         uint          `json:"rule_id" gorm:"not null"`
+	Rule           *AlertRule    `json:"rule,omitempty" gorm:"foreignKey:RuleID"` // loaded only when requested
+	Message        string        `json:"message" gorm:"not null"`
+	Severity       AlertSeverity `json:"severity" gorm:"not null"`
+	Value          float64       `json:"value" gorm:"not null"`              // actual value that triggered the alert
+	Status         AlertStatus   `json:"status" gorm:"default:'active'"`     // active, resolved, acknowledged
+	LateDetected   bool          `json:"late_detected" gorm:"default:false"` // created by catch-up evaluation of a missed window
+	CreatedAt      time.Time     `json:"created_at"`
+	ResolvedAt     *time.Time    `json:"resolved_at"`
+	AcknowledgedAt *time.Time    `json:"acknowledged_at"`
+	SnoozedUntil   *time.Time    `json:"snoozed_until"`    // the rule doesn't fire again before this time
+	Snoozed        bool          `json:"snoozed" gorm:"-"` // whether the snooze is still in effect
+	// Notification state, so re-notification survives restarts and moves between servers
+	LastNotifiedAt    *time.Time `json:"last_notified_at"`
+	NotificationCount int        `json:"notification_count" gorm:"not null;default:0"` // notifications sent, including re-notifications
+	// Tenant of the rule, copied so alerts are scoped without a join
+	Tenant *string `json:"tenant,omitempty" gorm:"size:64;index"`
+}
+
+// IsSnoozed reports whether the alert is snoozed at the given time
+func (a *Alert) IsSnoozed(at time.Time) bool {
+	return a.SnoozedUntil != nil && at.Before(*a.SnoozedUntil)
+}
+
+// AfterFind reports whether the snooze is in effect, so expired snoozes revert without a write
+func (a *Alert) AfterFind(tx *gorm.DB) error {
+	a.Snoozed = a.IsSnoozed(time.Now())
+	return nil
+}
+
+// AlertSummary is the projection of an alert listed in list views, without the rule's definition
+type AlertSummary struct {
+	ID           uint          `json:"id"`
+	RuleID       uint          `json:"rule_id"`
+	RuleName     string        `json:"rule_name"`
+	Tenant       *string       `json:"tenant,omitempty"`
+	Message      string        `json:"message"`
+	Severity     AlertSeverity `json:"severity"`
+	Value        float64       `json:"value"`
+	Status       AlertStatus   `json:"status"`
+	LateDetected bool          `json:"late_detected"`
+	CreatedAt    time.Time     `json:"created_at"`
+	SnoozedUntil *time.Time    `json:"snoozed_until"`
+	Snoozed      bool          `json:"snoozed" gorm:"-"`
+}
+
+// AfterFind reports whether the snooze is in effect
+func (a *AlertSummary) AfterFind(tx *gorm.DB) error {
+	a.Snoozed = a.SnoozedUntil != nil && time.Now().Before(*a.SnoozedUntil)
+	return nil
+}
+
+// AlertEvent reports an alert that was created, resolved or acknowledged
+type AlertEvent struct {
+	Type  string        `json:"type"` // created, resolved, acknowledged or heartbeat
+	Alert *AlertSummary `json:"alert,omitempty"`
+	At    time.Time     `json:"at"`
+}
+
+// NewAlertSummary returns the summary of an alert whose rule has the given name
+func NewAlertSummary(alert *Alert, ruleName string) AlertSummary {
+	return AlertSummary{
+		ID:           alert.ID,
+		RuleID:       alert.RuleID,
+		RuleName:     ruleName,
+		Tenant:       alert.Tenant,
+		Message:      alert.Message,
+		Severity:     alert.Severity,
+		Value:        alert.Value,
+		Status:       alert.Status,
+		LateDetected: alert.LateDetected,
+		CreatedAt:    alert.CreatedAt,
+		SnoozedUntil: alert.SnoozedUntil,
+		Snoozed:      alert.IsSnoozed(time.Now()),
+	}
+}
+
+// AlertCursor is the position of an alert in the listing order, newest first, from which the next page starts
+type AlertCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// String encodes the cursor as an opaque token
+func (c AlertCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d_%d", c.CreatedAt.UnixNano(), c.ID)))
+}
+
+// ParseAlertCursor decodes a cursor token
+func ParseAlertCursor(token string) (*AlertCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	createdAt, id, ok := strings.Cut(string(data), "_")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	nanos, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	parsedID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &AlertCursor{CreatedAt: time.Unix(0, nanos), ID: uint(parsedID)}, nil
+}
+
+// AlertStats represents alert statistics. Counts include resolved alerts pruned by retention.
 type AlertStats struct {
 	TotalAlerts    int64 `json:"total_alerts"`
 	ActiveAlerts   int64 `json:"active_alerts"`
@@ -27,15 +151,193 @@ type AlertStats struct {
 	HighAlerts     int64 `json:"high_alerts"`
 	MediumAlerts   int64 `json:"medium_alerts"`
 	LowAlerts      int64 `json:"low_alerts"`
+	PrunedAlerts   int64 `json:"pruned_alerts"` // resolved alerts deleted by retention, kept only as counts
+
+	// Average seconds from creation to acknowledgement and resolution, null when no alert was acknowledged or resolved
+	AvgTimeToAcknowledgeSeconds *float64 `json:"avg_time_to_acknowledge_seconds"`
+	AvgTimeToResolveSeconds     *float64 `json:"avg_time_to_resolve_seconds"`
+}
+
+// AlertStatsRollup counts resolved alerts of a rule and severity created on a day that were pruned by retention
+type AlertStatsRollup struct {
+	Day        time.Time     `json:"day" gorm:"type:date;primaryKey"`
+	RuleID     uint          `json:"rule_id" gorm:"primaryKey"`
+	Severity   AlertSeverity `json:"severity" gorm:"primaryKey"`
+	AlertCount int64         `json:"alert_count"`
+	UpdatedAt  time.Time     `json:"updated_at"`
 }
 
 // AlertFilter represents filters for querying alerts
 type AlertFilter struct {
-	Status   *string    `json:"status"`
+	Statuses []string   `json:"statuses"` // alerts in any of the statuses
 	Severity *string    `json:"severity"`
 	RuleID   *uint      `json:"rule_id"`
+	Tenant   *string    `json:"tenant"`
+	Snoozed  *bool      `json:"snoozed"`
 	From     *time.Time `json:"from"`
 	To       *time.Time `json:"to"`
 	Limit    *int       `json:"limit"`
 	Offset   *int       `json:"offset"`
-} 
+	// Exclusions, labels of the alerts' rules, and a full-text search of the alerts' messages
+	ExcludeSeverities []string          `json:"exclude_severities"`
+	ExcludeRuleIDs    []uint            `json:"exclude_rule_ids"`
+	Labels            map[string]string `json:"labels"`         // rules having all of the labels
+	ExcludeLabels     map[string]string `json:"exclude_labels"` // rules having none of the labels
+	Search            *string           `json:"search"`
+
+	After         *AlertCursor `json:"-"` // only alerts listed after the cursor
+	ResolvedAfter *time.Time   `json:"-"` // only alerts resolved after this time
+	IDs           []uint       `json:"-"` // only the alerts of these IDs
+	IncludeRule   bool         `json:"-"` // load each alert's rule
+}
+
+// AlertBulkAction resolves or acknowledges many alerts at once: either the alerts of the IDs or every alert
+// matching the filter
+type AlertBulkAction struct {
+	Action string           `json:"action"` // resolve or acknowledge
+	IDs    []uint           `json:"ids,omitempty"`
+	Filter *AlertBulkFilter `json:"filter,omitempty"`
+}
+
+// AlertBulkFilter selects the alerts of a bulk action by their rule, severity, rule labels and creation time
+type AlertBulkFilter struct {
+	RuleID   *uint             `json:"rule_id,omitempty"`
+	Severity *string           `json:"severity,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"` // rules having all of the labels
+	From     *time.Time        `json:"from,omitempty"`
+	To       *time.Time        `json:"to,omitempty"`
+}
+
+// Validate checks the action, and that it selects its alerts by either IDs or a filter of at least one criterion
+func (a *AlertBulkAction) Validate() error {
+	var fields []apperrors.FieldError
+	invalid := func(field, format string, args ...any) {
+		fields = append(fields, apperrors.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch a.Action {
+	case constants.AlertActionResolve, constants.AlertActionAcknowledge:
+	default:
+		invalid("action", "must be one of %s, %s", constants.AlertActionResolve, constants.AlertActionAcknowledge)
+	}
+	switch {
+	case len(a.IDs) > 0 && a.Filter != nil:
+		invalid("ids", "can't be combined with filter")
+	case len(a.IDs) == 0 && a.Filter == nil:
+		invalid("ids", "either ids or filter is required")
+	case len(a.IDs) > constants.MaxBulkAlertIDs:
+		invalid("ids", "at most %d alert IDs are allowed", constants.MaxBulkAlertIDs)
+	}
+	for _, id := range a.IDs {
+		if id == 0 {
+			invalid("ids", "must be positive alert IDs")
+			break
+		}
+	}
+
+	if f := a.Filter; f != nil {
+		if f.RuleID == nil && f.Severity == nil && len(f.Labels) == 0 && f.From == nil && f.To == nil {
+			invalid("filter", "must have at least one of rule_id, severity, labels, from, to")
+		}
+		if f.RuleID != nil && *f.RuleID == 0 {
+			invalid("filter.rule_id", "must be a positive rule ID")
+		}
+		if f.Severity != nil {
+			switch *f.Severity {
+			case constants.AlertSeverityLow, constants.AlertSeverityMedium, constants.AlertSeverityHigh, constants.AlertSeverityCritical:
+			default:
+				invalid("filter.severity", "must be one of low, medium, high, critical")
+			}
+		}
+		if len(f.Labels) > constants.MaxAlertLabelFilters {
+			invalid("filter.labels", "at most %d labels are allowed", constants.MaxAlertLabelFilters)
+		}
+		if f.From != nil && f.To != nil && f.From.After(*f.To) {
+			invalid("filter.from", "must not be after to")
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperrors.Invalid(fields...)
+	}
+	return nil
+}
+
+// AlertFilter returns the filter of the alerts the action applies to. Resolving applies to active and acknowledged
+// alerts, acknowledging to active ones only, so alerts already in the target status are left untouched.
+func (a *AlertBulkAction) AlertFilter() *AlertFilter {
+	filter := &AlertFilter{IDs: a.IDs, Statuses: []string{constants.AlertStatusActive}}
+	if a.Action == constants.AlertActionResolve {
+		filter.Statuses = append(filter.Statuses, constants.AlertStatusAcknowledged)
+	}
+	if f := a.Filter; f != nil {
+		filter.RuleID, filter.Severity, filter.Labels, filter.From, filter.To = f.RuleID, f.Severity, f.Labels, f.From, f.To
+	}
+	return filter
+}
+
+// Status returns the status the action puts alerts in
+func (a *AlertBulkAction) Status() string {
+	if a.Action == constants.AlertActionAcknowledge {
+		return constants.AlertStatusAcknowledged
+	}
+	return constants.AlertStatusResolved
+}
+
+// AlertBulkResult reports the alerts a bulk action updated
+type AlertBulkResult struct {
+	Action   string `json:"action"`
+	Updated  int    `json:"updated"`
+	AlertIDs []uint `json:"alert_ids"`
+}
+
+// AlertCheckerHealth reports whether the alert checker is evaluating rules successfully
+type AlertCheckerHealth struct {
+	Status              string                 `json:"status"` // starting, healthy, degraded or failing
+	LastCheckAt         *time.Time             `json:"last_check_at"`
+	LastSuccessAt       *time.Time             `json:"last_success_at"`      // last check in which every enabled rule was evaluated
+	RulesEvaluated      int                    `json:"rules_evaluated"`      // enabled rules evaluated by the last check
+	RulesFailed         int                    `json:"rules_failed"`         // rules of the last check whose evaluation failed
+	ConsecutiveFailures int                    `json:"consecutive_failures"` // failed checks since the last successful one
+	LastError           string                 `json:"last_error,omitempty"`
+	MetaAlert           *AlertCheckerMetaAlert `json:"meta_alert,omitempty"`
+	RuleFailures        []AlertRuleFailure     `json:"rule_failures"`
+}
+
+// AlertCheckerMetaAlert is the built-in alert fired when rules haven't been evaluated successfully for too long
+type AlertCheckerMetaAlert struct {
+	Message      string    `json:"message"`
+	FailedChecks int       `json:"failed_checks"`
+	FiredAt      time.Time `json:"fired_at"`
+}
+
+// AlertRuleEvaluation is the outcome of evaluating a rule on demand, which neither creates, resolves nor notifies
+// alerts. WouldFire tells whether the alert checker would create an alert from it: the rule is enabled, its
+// condition is met, it has no active alert and isn't muted, snoozed or cooling down.
+type AlertRuleEvaluation struct {
+	RuleID       uint      `json:"rule_id"`
+	RuleName     string    `json:"rule_name"`
+	Canary       bool      `json:"canary"` // whether the rule is canaried, in which case its previous version was evaluated
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
+	Query        string    `json:"query"` // with placeholders for its arguments and string literals masked
+	DurationMs   float64   `json:"duration_ms"`
+	Value        *float64  `json:"value"` // nil when the window holds no data, or too few baseline windows do
+	Threshold    float64   `json:"threshold"`
+	Description  string    `json:"description,omitempty"`
+	ConditionMet bool      `json:"condition_met"`
+	ActiveAlerts int       `json:"active_alerts"` // which the checker renotifies instead of creating another alert
+	Suppressed   bool      `json:"suppressed"`
+	WouldFire    bool      `json:"would_fire"`
+	Error        string    `json:"error,omitempty"` // why the rule's query failed
+}
+
+// AlertRuleFailure counts the failed evaluations of a rule since the alert checker started
+type AlertRuleFailure struct {
+	RuleID              uint      `json:"rule_id"`
+	RuleName            string    `json:"rule_name"`
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 0 once the rule evaluates successfully again
+	TotalFailures       int64     `json:"total_failures"`
+	LastError           string    `json:"last_error"`
+	LastFailureAt       time.Time `json:"last_failure_at"`
+}
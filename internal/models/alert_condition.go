@@ -0,0 +1,466 @@
+package models
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"strings"
+)
+
+// Alert rule conditions are SQL expressions computed over the logs of a window, so they are checked against a
+// grammar of the expressions both log stores evaluate alike, rather than passed to the database as they are:
+//
+//	condition := or
+//	or        := and { OR and }
+//	and       := not { AND not }
+//	not       := NOT not | predicate
+//	predicate := sum [ compare sum | [NOT] IN ( condition { , condition } ) | [NOT] LIKE sum |
+//	             [NOT] BETWEEN sum AND sum | IS [NOT] NULL ]
+//	sum       := product { ( + | - ) product }
+//	product   := unary { ( * | / | % ) unary }
+//	unary     := - unary | primary
+//	primary   := number | string | NULL | TRUE | FALSE | column | function | ( condition ) |
+//	             CASE WHEN condition THEN condition { WHEN condition THEN condition } [ ELSE condition ] END
+//	function  := COUNT ( * ) | aggregate ( [DISTINCT] condition ) | scalar ( condition { , condition } )
+//
+// Columns are those of the logs table and must be aggregated, so that the condition has one value per window.
+// Subqueries, comments, quoted identifiers and further statements can't be expressed.
+
+// conditionColumns are the columns of the logs table conditions may refer to
+var conditionColumns = map[string]bool{
+	"id": true, "timestamp": true, "level": true, "service": true, "tenant": true, "host": true, "environment": true,
+	"region": true, "client_ip": true, "message": true, "fingerprint": true, "trace_id": true, "span_id": true,
+	"parent_span_id": true, "message_id": true, "user_id": true, "request_method": true, "request_path": true,
+	"response_status": true, "response_time_ms": true, "request_body": true, "response_body": true, "created_at": true,
+}
+
+// conditionAggregates are the aggregate functions of conditions
+var conditionAggregates = map[string]bool{"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+// conditionFunctions are the scalar functions of conditions, with their minimum and maximum number of arguments
+var conditionFunctions = map[string][2]int{
+	"ABS": {1, 1}, "ROUND": {1, 2}, "COALESCE": {1, 16}, "GREATEST": {2, 16}, "LEAST": {2, 16},
+	"LOWER": {1, 1}, "UPPER": {1, 1}, "LENGTH": {1, 1},
+}
+
+// conditionKeywords can't be used as column names
+var conditionKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "LIKE": true, "BETWEEN": true, "IS": true, "NULL": true,
+	"TRUE": true, "FALSE": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true, "DISTINCT": true,
+}
+
+// conditionToken kinds
+const (
+	tokenEnd = iota
+	tokenWord
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+// conditionToken is a word, number, string literal or symbol of a condition, at its byte offset
+type conditionToken struct {
+	kind  int
+	text  string
+	start int
+}
+
+// CheckCondition checks that an alert rule condition is an expression of the condition grammar which aggregates
+// the logs of a window
+func CheckCondition(condition string) error {
+	if strings.TrimSpace(condition) == "" {
+		return fmt.Errorf("is required")
+	}
+	if len(condition) > constants.MaxAlertConditionLength {
+		return fmt.Errorf("must be at most %d characters", constants.MaxAlertConditionLength)
+	}
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return err
+	}
+	p := &conditionParser{tokens: tokens}
+	if err := p.condition(); err != nil {
+		return err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return p.unexpected(next)
+	}
+	if !p.aggregated {
+		return fmt.Errorf("must aggregate the logs of the window, e.g. with COUNT(*)")
+	}
+	return nil
+}
+
+// tokenizeCondition splits a condition into tokens, ending with a tokenEnd token
+func tokenizeCondition(condition string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(condition); {
+		c := condition[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isWordStart(c):
+			for i < len(condition) && (isWordStart(condition[i]) || isDigit(condition[i])) {
+				i++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenWord, text: condition[start:i], start: start})
+		case isDigit(c) || c == '.' && i+1 < len(condition) && isDigit(condition[i+1]):
+			for i < len(condition) && isDigit(condition[i]) {
+				i++
+			}
+			if i < len(condition) && condition[i] == '.' {
+				for i++; i < len(condition) && isDigit(condition[i]); i++ {
+				}
+			}
+			tokens = append(tokens, conditionToken{kind: tokenNumber, text: condition[start:i], start: start})
+		case c == '\'':
+			// Quotes are escaped by doubling them. Backslashes escape quotes in MySQL but not in other databases,
+			// so they aren't allowed, or where a literal ends would depend on the database.
+			for i++; ; i++ {
+				if i >= len(condition) {
+					return nil, fmt.Errorf("string literal at position %d is not terminated", start+1)
+				}
+				if condition[i] == '\\' {
+					return nil, fmt.Errorf("string literal at position %d may not contain backslashes", start+1)
+				}
+				if condition[i] == '\'' {
+					if i+1 < len(condition) && condition[i+1] == '\'' {
+						i++
+						continue
+					}
+					i++
+					break
+				}
+			}
+			tokens = append(tokens, conditionToken{kind: tokenString, text: condition[start:i], start: start})
+		case strings.HasPrefix(condition[i:], "--"):
+			// Comments in other databases, but only when followed by a space in MySQL
+			return nil, fmt.Errorf("comments are not allowed, at position %d", start+1)
+		default:
+			symbol := ""
+			for _, candidate := range []string{"<=", ">=", "<>", "!=", "(", ")", ",", "*", "+", "-", "/", "%", "=", "<", ">"} {
+				if strings.HasPrefix(condition[i:], candidate) {
+					symbol = candidate
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", condition[i:i+1], start+1)
+			}
+			i += len(symbol)
+			tokens = append(tokens, conditionToken{kind: tokenSymbol, text: symbol, start: start})
+		}
+	}
+	return append(tokens, conditionToken{kind: tokenEnd, start: len(condition)}), nil
+}
+
+// isWordStart reports whether a byte starts a keyword, column or function name
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isDigit reports whether a byte is a decimal digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// conditionParser is a recursive descent parser of the condition grammar
+type conditionParser struct {
+	tokens     []conditionToken
+	next       int
+	aggregates int  // depth of aggregate function calls around the current token
+	aggregated bool // whether the condition calls an aggregate function
+}
+
+// peek returns the next token without consuming it
+func (p *conditionParser) peek() conditionToken {
+	return p.tokens[p.next]
+}
+
+// keyword consumes the next token if it is one of the keywords, ignoring case, and returns it upper-cased
+func (p *conditionParser) keyword(keywords ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenWord {
+		return "", false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(token.text, keyword) {
+			p.next++
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+// symbol consumes the next token if it is one of the symbols, and returns it
+func (p *conditionParser) symbol(symbols ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenSymbol {
+		return "", false
+	}
+	for _, symbol := range symbols {
+		if token.text == symbol {
+			p.next++
+			return symbol, true
+		}
+	}
+	return "", false
+}
+
+// expectKeyword consumes the keyword or fails
+func (p *conditionParser) expectKeyword(keyword string) error {
+	if _, ok := p.keyword(keyword); !ok {
+		return fmt.Errorf("expected %s at position %d", keyword, p.peek().start+1)
+	}
+	return nil
+}
+
+// expectSymbol consumes the symbol or fails
+func (p *conditionParser) expectSymbol(symbol string) error {
+	if _, ok := p.symbol(symbol); !ok {
+		return fmt.Errorf("expected %q at position %d", symbol, p.peek().start+1)
+	}
+	return nil
+}
+
+// unexpected reports a token that the grammar doesn't allow where it is
+func (p *conditionParser) unexpected(token conditionToken) error {
+	if token.kind == tokenEnd {
+		return fmt.Errorf("ends unexpectedly")
+	}
+	return fmt.Errorf("unexpected %q at position %d", token.text, token.start+1)
+}
+
+// condition parses conditions joined by OR
+func (p *conditionParser) condition() error {
+	for {
+		if err := p.and(); err != nil {
+			return err
+		}
+		if _, ok := p.keyword("OR"); !ok {
+			return nil
+		}
+	}
+}
+
+// and parses conditions joined by AND
+func (p *conditionParser) and() error {
+	for {
+		if err := p.not(); err != nil {
+			return err
+		}
+		if _, ok := p.keyword("AND"); !ok {
+			return nil
+		}
+	}
+}
+
+// not parses a predicate, possibly negated
+func (p *conditionParser) not() error {
+	if _, ok := p.keyword("NOT"); ok {
+		return p.not()
+	}
+	return p.predicate()
+}
+
+// predicate parses a value, possibly compared or tested
+func (p *conditionParser) predicate() error {
+	if err := p.sum(); err != nil {
+		return err
+	}
+	if _, ok := p.symbol("=", "!=", "<>", "<", "<=", ">", ">="); ok {
+		return p.sum()
+	}
+	if _, ok := p.keyword("IS"); ok {
+		p.keyword("NOT")
+		return p.expectKeyword("NULL")
+	}
+	negated := false
+	if _, ok := p.keyword("NOT"); ok {
+		negated = true
+	}
+	keyword, ok := p.keyword("IN", "LIKE", "BETWEEN")
+	switch {
+	case !ok && negated:
+		return fmt.Errorf("expected IN, LIKE or BETWEEN at position %d", p.peek().start+1)
+	case !ok:
+		return nil
+	case keyword == "IN":
+		if err := p.expectSymbol("("); err != nil {
+			return err
+		}
+		if _, err := p.arguments(); err != nil {
+			return err
+		}
+		return p.expectSymbol(")")
+	case keyword == "LIKE":
+		return p.sum()
+	default:
+		if err := p.sum(); err != nil {
+			return err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return err
+		}
+		return p.sum()
+	}
+}
+
+// arguments parses conditions separated by commas, returning how many there are
+func (p *conditionParser) arguments() (int, error) {
+	for count := 1; ; count++ {
+		if err := p.condition(); err != nil {
+			return 0, err
+		}
+		if _, ok := p.symbol(","); !ok {
+			return count, nil
+		}
+	}
+}
+
+// sum parses products joined by + and -
+func (p *conditionParser) sum() error {
+	for {
+		if err := p.product(); err != nil {
+			return err
+		}
+		if _, ok := p.symbol("+", "-"); !ok {
+			return nil
+		}
+	}
+}
+
+// product parses unary values joined by *, / and %
+func (p *conditionParser) product() error {
+	for {
+		if err := p.unary(); err != nil {
+			return err
+		}
+		if _, ok := p.symbol("*", "/", "%"); !ok {
+			return nil
+		}
+	}
+}
+
+// unary parses a value, possibly negated
+func (p *conditionParser) unary() error {
+	if _, ok := p.symbol("-"); ok {
+		return p.unary()
+	}
+	return p.primary()
+}
+
+// primary parses a literal, column, function call, CASE expression or parenthesized condition
+func (p *conditionParser) primary() error {
+	token := p.peek()
+	switch token.kind {
+	case tokenNumber, tokenString:
+		p.next++
+		return nil
+	case tokenSymbol:
+		if token.text != "(" {
+			return p.unexpected(token)
+		}
+		p.next++
+		if err := p.condition(); err != nil {
+			return err
+		}
+		return p.expectSymbol(")")
+	case tokenWord:
+		word := strings.ToUpper(token.text)
+		if word == "NULL" || word == "TRUE" || word == "FALSE" {
+			p.next++
+			return nil
+		}
+		if word == "CASE" {
+			p.next++
+			return p.caseExpression()
+		}
+		if p.tokens[p.next+1].kind == tokenSymbol && p.tokens[p.next+1].text == "(" {
+			p.next += 2
+			return p.function(token, word)
+		}
+		if word == "SELECT" {
+			return fmt.Errorf("subqueries are not allowed, at position %d", token.start+1)
+		}
+		if conditionKeywords[word] {
+			return p.unexpected(token)
+		}
+		if !conditionColumns[strings.ToLower(token.text)] {
+			return fmt.Errorf("unknown column %s at position %d", token.text, token.start+1)
+		}
+		if p.aggregates == 0 {
+			return fmt.Errorf("column %s at position %d must be aggregated, e.g. with COUNT or AVG", token.text, token.start+1)
+		}
+		p.next++
+		return nil
+	default:
+		return p.unexpected(token)
+	}
+}
+
+// caseExpression parses a CASE expression after its CASE keyword
+func (p *conditionParser) caseExpression() error {
+	if err := p.expectKeyword("WHEN"); err != nil {
+		return err
+	}
+	for {
+		if err := p.condition(); err != nil {
+			return err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return err
+		}
+		if err := p.condition(); err != nil {
+			return err
+		}
+		if _, ok := p.keyword("WHEN"); !ok {
+			break
+		}
+	}
+	if _, ok := p.keyword("ELSE"); ok {
+		if err := p.condition(); err != nil {
+			return err
+		}
+	}
+	return p.expectKeyword("END")
+}
+
+// function parses the arguments of a function call after its opening parenthesis
+func (p *conditionParser) function(name conditionToken, word string) error {
+	if conditionAggregates[word] {
+		if p.aggregates > 0 {
+			return fmt.Errorf("aggregate %s at position %d may not be nested in another aggregate", name.text, name.start+1)
+		}
+		p.aggregated = true
+		if word == "COUNT" {
+			if _, ok := p.symbol("*"); ok {
+				return p.expectSymbol(")")
+			}
+		}
+		p.keyword("DISTINCT")
+		p.aggregates++
+		defer func() { p.aggregates-- }()
+		if err := p.condition(); err != nil {
+			return err
+		}
+		return p.expectSymbol(")")
+	}
+
+	arity, ok := conditionFunctions[word]
+	if !ok {
+		return fmt.Errorf("unknown function %s at position %d", name.text, name.start+1)
+	}
+	count, err := p.arguments()
+	if err != nil {
+		return err
+	}
+	if count < arity[0] || count > arity[1] {
+		if arity[0] == arity[1] {
+			return fmt.Errorf("function %s at position %d takes %d argument(s)", name.text, name.start+1, arity[0])
+		}
+		return fmt.Errorf("function %s at position %d takes %d to %d arguments", name.text, name.start+1, arity[0], arity[1])
+	}
+	return p.expectSymbol(")")
+}
@@ -1,19 +1,271 @@
 package models
 
 import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// labelNamePattern restricts rule label names to characters that are safe in query parameter names
+var labelNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// AlertRuleType is how an alert rule decides to fire: threshold or anomaly
+type AlertRuleType string
+
+// GormDBDataType stores rule types as an enum of the two types
+func (AlertRuleType) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return enumDataType(db, constants.AlertRuleThreshold, constants.AlertRuleAnomaly)
+}
+
 // AlertRule represents an alert rule configuration
 type AlertRule struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"not null"`
-	Description string    `json:"description"`
-	Condition   string    `json:"condition" gorm:"not null"` // SQL condition for the alert
-	Threshold   float64   `json:"threshold" gorm:"not null"`
-	TimeWindow  int       `json:"time_window" gorm:"not null"` // in minutes
-	Severity    string    `json:"severity" gorm:"type:enum('low','medium','high','critical');not null"`    // low, medium, high, critical
-	Enabled     bool      `json:"enabled" gorm:"default:true"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-} 
+	ID              uint          `json:"id" gorm:"primaryKey"`
+	Name            string        `json:"name" gorm:"not null"`
+	Description     string        `json:"description"`
+	Condition       string        `json:"condition" gorm:"not null"` // SQL condition for the alert
+	Threshold       float64       `json:"threshold" gorm:"not null"`
+	TimeWindow      int           `json:"time_window" gorm:"not null"` // in minutes
+	Severity        AlertSeverity `json:"severity" gorm:"not null"`    // low, medium, high, critical
+	Enabled         bool          `json:"enabled" gorm:"default:true"`
+	LastEvaluatedAt *time.Time    `json:"last_evaluated_at"`
+	MutedUntil      *time.Time    `json:"muted_until"`    // no alerts are created before this time
+	Muted           bool          `json:"muted" gorm:"-"` // whether the mute is still in effect
+	// Alerts of the rule aren't created again within the cooldown after one resolves, and active ones are
+	// notified again every renotify interval. Zero disables either.
+	CooldownMinutes int `json:"cooldown_minutes" gorm:"not null;default:0"`
+	RenotifyMinutes int `json:"renotify_minutes" gorm:"not null;default:0"`
+	// Rules of a tenant are evaluated against the tenant's logs only, and their alerts belong to the tenant
+	Tenant *string `json:"tenant,omitempty" gorm:"size:64;index"`
+	// Labels classify the rule, e.g. by team or service, and select its alerts in alert listings
+	Labels Attributes `json:"labels,omitempty" gorm:"type:json"`
+	// Anomaly rules compare a metric with the same window of previous days or weeks, and their threshold is a deviation
+	Type               AlertRuleType `json:"type" gorm:"not null;default:threshold"`                          // threshold, anomaly
+	AnomalyMetric      string        `json:"anomaly_metric,omitempty" gorm:"size:16;not null;default:''"`     // log_volume, error_rate
+	AnomalySeasonality string        `json:"anomaly_seasonality,omitempty" gorm:"size:8;not null;default:''"` // day, week
+	AnomalyPeriods     int           `json:"anomaly_periods,omitempty" gorm:"not null;default:0"`             // baseline windows compared with
+	AnomalyDeviation   string        `json:"anomaly_deviation,omitempty" gorm:"size:8;not null;default:''"`   // stddev, percent
+	AnomalyDirection   string        `json:"anomaly_direction,omitempty" gorm:"size:8;not null;default:''"`   // up, down, both
+	// Version in effect while an edit of the condition, threshold or time window is canaried
+	PreviousCondition  *string    `json:"previous_condition,omitempty"`
+	PreviousThreshold  *float64   `json:"previous_threshold,omitempty"`
+	PreviousTimeWindow *int       `json:"previous_time_window,omitempty"`
+	CanaryUntil        *time.Time `json:"canary_until,omitempty"` // the edit takes effect at this time
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// IsMuted reports whether the rule is muted at the given time
+func (r *AlertRule) IsMuted(at time.Time) bool {
+	return r.MutedUntil != nil && at.Before(*r.MutedUntil)
+}
+
+// HasCanary reports whether the rule holds a previous version from a canaried edit, in effect or expired
+func (r *AlertRule) HasCanary() bool {
+	return r.PreviousCondition != nil && r.PreviousThreshold != nil && r.PreviousTimeWindow != nil && r.CanaryUntil != nil
+}
+
+// InCanary reports whether the previous version is still in effect at the given time
+func (r *AlertRule) InCanary(at time.Time) bool {
+	return r.HasCanary() && at.Before(*r.CanaryUntil)
+}
+
+// Previous returns a copy of the rule with the previous condition, threshold and time window
+func (r *AlertRule) Previous() *AlertRule {
+	previous := *r
+	previous.Condition = *r.PreviousCondition
+	previous.Threshold = *r.PreviousThreshold
+	previous.TimeWindow = *r.PreviousTimeWindow
+	return &previous
+}
+
+// SameEvaluation reports whether two rules evaluate the same condition, threshold and time window
+func (r *AlertRule) SameEvaluation(other *AlertRule) bool {
+	return r.Condition == other.Condition && r.Threshold == other.Threshold && r.TimeWindow == other.TimeWindow
+}
+
+// IsAnomaly reports whether the rule fires on deviations from a baseline rather than on a fixed threshold
+func (r *AlertRule) IsAnomaly() bool {
+	return r.Type == constants.AlertRuleAnomaly
+}
+
+// SameAnomaly reports whether two rules have the same type and anomaly settings
+func (r *AlertRule) SameAnomaly(other *AlertRule) bool {
+	return r.Type == other.Type && r.AnomalyMetric == other.AnomalyMetric && r.AnomalySeasonality == other.AnomalySeasonality &&
+		r.AnomalyPeriods == other.AnomalyPeriods && r.AnomalyDeviation == other.AnomalyDeviation &&
+		r.AnomalyDirection == other.AnomalyDirection
+}
+
+// Seasonality returns the period between an anomaly rule's window and its baseline windows
+func (r *AlertRule) Seasonality() time.Duration {
+	if r.AnomalySeasonality == constants.AnomalySeasonalityWeek {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Validate checks the rule's fields, reporting every invalid one in an apperrors.CodeInvalid error. For anomaly
+// rules it fills in the defaults of the anomaly settings and sets the condition to the SQL expression of the metric;
+// threshold rules have their anomaly settings cleared. Whether the name is taken is up to the caller.
+func (r *AlertRule) Validate() error {
+	var fields []apperrors.FieldError
+	invalid := func(field, format string, args ...any) {
+		fields = append(fields, apperrors.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(r.Name) == "" || len(r.Name) > constants.MaxAlertRuleNameLength {
+		invalid("name", "must be between 1 and %d characters", constants.MaxAlertRuleNameLength)
+	}
+	switch r.Severity {
+	case constants.AlertSeverityLow, constants.AlertSeverityMedium, constants.AlertSeverityHigh, constants.AlertSeverityCritical:
+	default:
+		invalid("severity", "must be one of low, medium, high, critical")
+	}
+	if r.Tenant != nil && (*r.Tenant == "" || len(*r.Tenant) > constants.MaxTenantLength) {
+		invalid("tenant", "must be between 1 and %d characters", constants.MaxTenantLength)
+	}
+	if len(r.Labels) > constants.MaxAlertRuleLabels {
+		invalid("labels", "at most %d labels are allowed", constants.MaxAlertRuleLabels)
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Labels)) {
+		if !labelNamePattern.MatchString(name) || len(name) > constants.MaxAlertRuleLabelLength {
+			invalid("labels", "names must be 1 to %d letters, digits, dots, underscores and hyphens", constants.MaxAlertRuleLabelLength)
+			break
+		}
+		if value := r.Labels[name]; value == "" || len(value) > constants.MaxAlertRuleLabelLength {
+			invalid("labels."+name, "must be between 1 and %d characters", constants.MaxAlertRuleLabelLength)
+		}
+	}
+	if r.CooldownMinutes < 0 || r.CooldownMinutes > constants.MaxAlertCooldownMinutes {
+		invalid("cooldown_minutes", "must be between 0 and %d", constants.MaxAlertCooldownMinutes)
+	}
+	if r.RenotifyMinutes < 0 || r.RenotifyMinutes > constants.MaxAlertRenotifyMinutes {
+		invalid("renotify_minutes", "must be between 0 and %d", constants.MaxAlertRenotifyMinutes)
+	}
+	if r.TimeWindow < constants.MinAlertTimeWindow || r.TimeWindow > constants.MaxAlertTimeWindow {
+		invalid("time_window", "must be between %d and %d minutes", constants.MinAlertTimeWindow, constants.MaxAlertTimeWindow)
+	}
+
+	switch r.Type {
+	case "", constants.AlertRuleThreshold:
+		r.Type = constants.AlertRuleThreshold
+		r.AnomalyMetric, r.AnomalySeasonality, r.AnomalyPeriods, r.AnomalyDeviation, r.AnomalyDirection = "", "", 0, "", ""
+		if r.Threshold <= 0 {
+			invalid("threshold", "must be greater than 0")
+		}
+		if err := CheckCondition(r.Condition); err != nil {
+			invalid("condition", "%s", err)
+		}
+	case constants.AlertRuleAnomaly:
+		r.validateAnomaly(invalid)
+	default:
+		invalid("type", "must be one of threshold, anomaly")
+	}
+
+	if len(fields) > 0 {
+		return apperrors.Invalid(fields...)
+	}
+	return nil
+}
+
+// validateAnomaly fills in the defaults of an anomaly rule's settings and checks them
+func (r *AlertRule) validateAnomaly(invalid func(field, format string, args ...any)) {
+	switch r.AnomalyMetric {
+	case constants.AnomalyMetricLogVolume:
+		r.Condition = "COUNT(*)"
+	case constants.AnomalyMetricErrorRate:
+		r.Condition = "COUNT(CASE WHEN level IN ('ERROR', 'FATAL') THEN 1 END) * 100.0 / COUNT(*)"
+	default:
+		invalid("anomaly_metric", "must be one of log_volume, error_rate")
+	}
+
+	if r.AnomalySeasonality == "" {
+		r.AnomalySeasonality = constants.AnomalySeasonalityDay
+	}
+	if r.AnomalySeasonality != constants.AnomalySeasonalityDay && r.AnomalySeasonality != constants.AnomalySeasonalityWeek {
+		invalid("anomaly_seasonality", "must be one of day, week")
+	} else if time.Duration(r.TimeWindow)*time.Minute > r.Seasonality() {
+		invalid("time_window", "may not exceed the anomaly_seasonality of anomaly rules")
+	}
+	if r.AnomalyPeriods == 0 {
+		r.AnomalyPeriods = constants.DefaultAnomalyBaselinePeriods
+	}
+	if r.AnomalyPeriods < constants.MinAnomalyBaselinePeriods || r.AnomalyPeriods > constants.MaxAnomalyBaselinePeriods {
+		invalid("anomaly_periods", "must be between %d and %d", constants.MinAnomalyBaselinePeriods, constants.MaxAnomalyBaselinePeriods)
+	}
+	if r.AnomalyDeviation == "" {
+		r.AnomalyDeviation = constants.AnomalyDeviationStdDev
+	}
+	if r.AnomalyDeviation != constants.AnomalyDeviationStdDev && r.AnomalyDeviation != constants.AnomalyDeviationPercent {
+		invalid("anomaly_deviation", "must be one of stddev, percent")
+	}
+	switch r.AnomalyDirection {
+	case "":
+		r.AnomalyDirection = constants.AnomalyDirectionUp
+	case constants.AnomalyDirectionUp, constants.AnomalyDirectionDown, constants.AnomalyDirectionBoth:
+	default:
+		invalid("anomaly_direction", "must be one of up, down, both")
+	}
+
+	if r.Threshold <= 0 {
+		invalid("threshold", "must be a positive deviation")
+	}
+}
+
+// AfterFind reports whether the mute is in effect, so expired mutes revert without a write
+func (r *AlertRule) AfterFind(tx *gorm.DB) error {
+	r.Muted = r.IsMuted(time.Now())
+	return nil
+}
+
+// AlertRuleCanaryEvaluation records one evaluation of a canaried rule's previous and edited versions.
+// A version fires when its value reaches its threshold; values are nil when no data was found.
+type AlertRuleCanaryEvaluation struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	RuleID        uint      `json:"rule_id" gorm:"not null"`
+	EvaluatedAt   time.Time `json:"evaluated_at" gorm:"not null"`
+	PreviousValue *float64  `json:"previous_value"`
+	PreviousFired bool      `json:"previous_fired"`
+	CanaryValue   *float64  `json:"canary_value"`
+	CanaryFired   bool      `json:"canary_fired"`
+}
+
+// RuleVersion is the evaluated part of an alert rule
+type RuleVersion struct {
+	Condition  string  `json:"condition"`
+	Threshold  float64 `json:"threshold"`
+	TimeWindow int     `json:"time_window"`
+}
+
+// AlertRuleCanaryReport compares the firing behavior of a canaried rule's previous and edited versions
+type AlertRuleCanaryReport struct {
+	RuleID              uint                        `json:"rule_id"`
+	Active              bool                        `json:"active"` // whether the previous version is still in effect
+	CanaryUntil         time.Time                   `json:"canary_until"`
+	Previous            RuleVersion                 `json:"previous"`
+	Canary              RuleVersion                 `json:"canary"`
+	Evaluations         int64                       `json:"evaluations"`
+	PreviousFired       int64                       `json:"previous_fired"`
+	CanaryFired         int64                       `json:"canary_fired"`
+	OnlyPreviousFired   int64                       `json:"only_previous_fired"`
+	OnlyCanaryFired     int64                       `json:"only_canary_fired"`
+	RecentDisagreements []AlertRuleCanaryEvaluation `json:"recent_disagreements"`
+}
+
+// AlertRuleState is the persisted state of one of a rule's stateful evaluators, e.g. since when its condition has
+// held. Version is incremented by every save, so replicas evaluating the same rule can't overwrite each other's
+// state; it is 0 for states that were never saved.
+type AlertRuleState struct {
+	RuleID    uint      `json:"rule_id" gorm:"primaryKey;autoIncrement:false"`
+	Kind      string    `json:"kind" gorm:"primaryKey;size:32"`
+	State     string    `json:"state" gorm:"type:json;not null"` // JSON document owned by the evaluator
+	Version   uint64    `json:"version" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
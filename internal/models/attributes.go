@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Attributes holds arbitrary structured key/value context attached to a log entry
+type Attributes map[string]string
+
+// Value implements driver.Valuer so attributes are stored as a JSON column
+func (a Attributes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so attributes can be read back from a JSON column
+func (a *Attributes) Scan(value interface{}) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported attributes type: %T", value)
+	}
+
+	if len(data) == 0 {
+		*a = nil
+		return nil
+	}
+	return json.Unmarshal(data, a)
+}
+
+// Set sets an attribute, allocating the map if necessary
+func (a *Attributes) Set(key, value string) {
+	if *a == nil {
+		*a = make(Attributes)
+	}
+	(*a)[key] = value
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// ExportAudit records a request to a log export endpoint: who made it, the filter and time range it asked for and
+// how many logs it got
+type ExportAudit struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Caller     string     `json:"caller" gorm:"size:255"`  // username or service token name; empty when authentication is disabled
+	Provider   string     `json:"provider" gorm:"size:32"` // ldap or token
+	Route      string     `json:"route" gorm:"size:255"`
+	Filter     string     `json:"filter" gorm:"type:text"` // the request's query string
+	StartTime  *time.Time `json:"start_time,omitempty"`
+	EndTime    *time.Time `json:"end_time,omitempty"`
+	Rows       int64      `json:"rows"`
+	Status     int        `json:"status"`
+	ClientIP   string     `json:"client_ip" gorm:"size:45"`
+	StartedAt  time.Time  `json:"started_at"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+// ExportAuditFilter selects audit entries, newest first
+type ExportAuditFilter struct {
+	Caller *string
+	Since  *time.Time
+	Limit  int
+}
+
+// AuditRecord records a successful API mutation: who made it, what it did to which record, and the record's
+// snapshots before and after it
+type AuditRecord struct {
+	ID         uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	Caller     string        `json:"caller" gorm:"size:255"`  // username; empty when authentication is disabled
+	Provider   string        `json:"provider" gorm:"size:32"` // ldap or token
+	Action     string        `json:"action" gorm:"size:64"`   // create, update, delete, or the operation of the route, e.g. resolve
+	Resource   string        `json:"resource" gorm:"size:64"` // collection of the record, e.g. alert-rules or admin/retention
+	ResourceID string        `json:"resource_id,omitempty" gorm:"size:64"`
+	Method     string        `json:"method" gorm:"size:8"`
+	Route      string        `json:"route" gorm:"size:255"`
+	Status     int           `json:"status"`
+	Before     AuditSnapshot `json:"before,omitempty" gorm:"type:text"` // the record before the mutation, as JSON
+	After      AuditSnapshot `json:"after,omitempty" gorm:"type:text"`  // the record after it, or the response of routes without a record ID
+	ClientIP   string        `json:"client_ip" gorm:"size:45"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// AuditRecordFilter selects audit records, newest first
+type AuditRecordFilter struct {
+	Caller     *string
+	Resource   *string
+	ResourceID *string
+	Action     *string
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int
+}
+
+// AuditSnapshot is a JSON document of a record, stored as text and served as it is
+type AuditSnapshot []byte
+
+// MarshalJSON writes the snapshot as it is
+func (s AuditSnapshot) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte("null"), nil
+	}
+	return s, nil
+}
+
+// Value implements driver.Valuer so snapshots are stored as text
+func (s AuditSnapshot) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return string(s), nil
+}
+
+// Scan implements sql.Scanner so snapshots can be read back from text
+func (s *AuditSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+	case []byte:
+		*s = append(AuditSnapshot(nil), v...)
+	case string:
+		*s = AuditSnapshot(v)
+	default:
+		return fmt.Errorf("unsupported audit snapshot type: %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package models
+
+// Principal represents an authenticated caller
+type Principal struct {
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Groups   []string `json:"groups,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"` // granted to service tokens, which have no role
+	Provider string   `json:"provider"`
+	Tenant   string   `json:"tenant,omitempty"` // limits the caller to the data of a single tenant
+}
+
+// TenantScope returns the tenant the caller is limited to, nil when it may see every tenant
+func (p *Principal) TenantScope() *string {
+	if p == nil || p.Tenant == "" {
+		return nil
+	}
+	return &p.Tenant
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// DeadLetterStats represents the messages currently retained in the dead-letter topic
+type DeadLetterStats struct {
+	Topic         string                `json:"topic"`
+	TotalMessages int64                 `json:"total_messages"`
+	Partitions    []DeadLetterPartition `json:"partitions"`
+	CheckedAt     time.Time             `json:"checked_at"`
+}
+
+// DeadLetterPartition represents the retained offset range of a dead-letter topic partition
+type DeadLetterPartition struct {
+	Partition    int32 `json:"partition"`
+	OldestOffset int64 `json:"oldest_offset"`
+	NewestOffset int64 `json:"newest_offset"`
+	Messages     int64 `json:"messages"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// enumDataType returns the column type of a field taking one of the given values. MySQL stores it as an ENUM;
+// other databases have no inline enum type, so there it is a VARCHAR fitting the longest value, whose values the
+// migrations restrict with a check constraint.
+func enumDataType(db *gorm.DB, values ...string) string {
+	if db.Dialector.Name() == "mysql" {
+		return "enum('" + strings.Join(values, "','") + "')"
+	}
+	size := 0
+	for _, value := range values {
+		size = max(size, len(value))
+	}
+	return fmt.Sprintf("varchar(%d)", size)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// ExportManifest describes the contents of a compliance export archive.
+// Each data file's hash is chained with the previous chain hash so that removing,
+// reordering or altering any file breaks the chain.
+type ExportManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Filter    LogFilter    `json:"filter"`
+	TotalRows int64        `json:"total_rows"`
+	Files     []ExportFile `json:"files"`
+	ChainHash string       `json:"chain_hash"`
+	PublicKey string       `json:"public_key"`
+}
+
+// ExportFile describes a single data file in a compliance export archive
+type ExportFile struct {
+	Name      string `json:"name"`
+	Rows      int64  `json:"rows"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	ChainHash string `json:"chain_hash"`
+}
+
+// ExportVerification represents the result of verifying a compliance export archive
+type ExportVerification struct {
+	Valid     bool      `json:"valid"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	TotalRows int64     `json:"total_rows"`
+	Files     int       `json:"files"`
+	ChainHash string    `json:"chain_hash,omitempty"`
+	Errors    []string  `json:"errors,omitempty"`
+}
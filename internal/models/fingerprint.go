@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// FingerprintBackfill is a job recomputing the fingerprints of the stored error logs under the current
+// fingerprint rules, batch by batch, recording its progress as it goes
+type FingerprintBackfill struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	RulesVersion string     `json:"rules_version" gorm:"size:16;not null"`
+	Status       string     `json:"status" gorm:"type:enum('running','completed','failed','cancelled');not null"` // running, completed, failed, cancelled
+	Cursor       string     `json:"-" gorm:"size:500;not null;default:''"`                                        // position reached in the logs
+	Total        int64      `json:"total" gorm:"not null;default:0"`                                              // error logs stored when the job started
+	Processed    int64      `json:"processed" gorm:"not null;default:0"`                                          // error logs fingerprinted so far
+	Updated      int64      `json:"updated" gorm:"not null;default:0"`                                            // error logs whose fingerprint changed
+	Error        string     `json:"error,omitempty" gorm:"size:1000;not null;default:''"`
+	StartedAt    time.Time  `json:"started_at"`
+	UpdatedAt    time.Time  `json:"updated_at"` // when progress was last recorded
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// FingerprintStatus reports the version of the fingerprint rules in effect and the most recently started backfill,
+// which is nil when none was started. Error groups are consistent once a backfill under the current rules completed.
+type FingerprintStatus struct {
+	RulesVersion string               `json:"rules_version"`
+	Backfill     *FingerprintBackfill `json:"backfill"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// KafkaLag represents how far the processor's consumer groups are behind the topics they consume
+type KafkaLag struct {
+	Groups    []ConsumerGroupLag `json:"groups"`
+	TotalLag  int64              `json:"total_lag"`
+	CheckedAt time.Time          `json:"checked_at"`
+}
+
+// ConsumerGroupLag represents the lag of a consumer group over the partitions of its topics
+type ConsumerGroupLag struct {
+	Group      string         `json:"group"`
+	Topics     []string       `json:"topics"`
+	Lag        int64          `json:"lag"`
+	Partitions []PartitionLag `json:"partitions"`
+}
+
+// PartitionLag represents the committed and newest offsets of a partition consumed by a group. The committed
+// offset is -1 when the group hasn't committed one.
+type PartitionLag struct {
+	Topic           string `json:"topic"`
+	Partition       int32  `json:"partition"`
+	CommittedOffset int64  `json:"committed_offset"`
+	NewestOffset    int64  `json:"newest_offset"`
+	Lag             int64  `json:"lag"`
+}
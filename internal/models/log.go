@@ -1,7 +1,14 @@
 package models
 
 import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"net"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // LogLevel represents the severity level of a log entry
@@ -15,33 +22,141 @@ const (
 	LogLevelFatal LogLevel = "FATAL"
 )
 
+// GormDBDataType stores levels as an enum of the five levels
+func (LogLevel) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return enumDataType(db, string(LogLevelDebug), string(LogLevelInfo), string(LogLevelWarn), string(LogLevelError), string(LogLevelFatal))
+}
+
 // Log represents a log entry in the system
 type Log struct {
-	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Timestamp      time.Time `json:"timestamp" gorm:"index;not null"`
-	Level          LogLevel  `json:"level" gorm:"type:enum('DEBUG','INFO','WARN','ERROR','FATAL');index;not null" validate:"required,oneof=DEBUG INFO WARN ERROR FATAL"`
-	Service        string    `json:"service" gorm:"index;not null;size:100" validate:"required"`
-	Message        string    `json:"message" gorm:"type:text;not null" validate:"required"`
-	TraceID        *string   `json:"trace_id,omitempty" gorm:"index;size:50"`
-	UserID         *string   `json:"user_id,omitempty" gorm:"index;size:50"`
-	RequestMethod  *string   `json:"request_method,omitempty" gorm:"size:10"`
-	RequestPath    *string   `json:"request_path,omitempty" gorm:"size:500"`
-	ResponseStatus *int      `json:"response_status,omitempty"`
-	ResponseTimeMs *int      `json:"response_time_ms,omitempty"`
-	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Timestamp      time.Time  `json:"timestamp" gorm:"index;not null"`
+	Level          LogLevel   `json:"level" gorm:"index;not null" validate:"required,oneof=DEBUG INFO WARN ERROR FATAL"`
+	Service        string     `json:"service" gorm:"index;not null;size:100" validate:"required"`
+	Tenant         *string    `json:"tenant,omitempty" gorm:"size:64"` // set from the tenant topic or header the log was consumed with
+	Host           *string    `json:"host,omitempty" gorm:"index;size:255"`
+	Environment    *string    `json:"environment,omitempty" gorm:"index;size:32"`
+	Region         *string    `json:"region,omitempty" gorm:"index;size:32"`
+	ClientIP       *string    `json:"client_ip,omitempty" gorm:"index;size:45"`
+	Message        string     `json:"message" gorm:"type:text;not null" validate:"required"`
+	Fingerprint    *string    `json:"fingerprint,omitempty" gorm:"index;size:16"` // groups occurrences of the same error, set for ERROR and FATAL logs
+	TraceID        *string    `json:"trace_id,omitempty" gorm:"index;size:50"`
+	SpanID         *string    `json:"span_id,omitempty" gorm:"index;size:32"`          // the span of the trace the log was emitted in
+	ParentSpanID   *string    `json:"parent_span_id,omitempty" gorm:"size:32"`         // the parent of the span, unset in the root span
+	MessageID      *string    `json:"message_id,omitempty" gorm:"uniqueIndex;size:64"` // identifies deliveries of the same log, so redeliveries aren't stored twice
+	UserID         *string    `json:"user_id,omitempty" gorm:"index;size:50"`
+	RequestMethod  *string    `json:"request_method,omitempty" gorm:"size:10"`
+	RequestPath    *string    `json:"request_path,omitempty" gorm:"size:500"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	ResponseTimeMs *int       `json:"response_time_ms,omitempty"`
+	RequestBody    *string    `json:"request_body,omitempty" gorm:"type:text"`  // excerpt, captured for selected services only
+	ResponseBody   *string    `json:"response_body,omitempty" gorm:"type:text"` // excerpt, captured for selected services only
+	Attributes     Attributes `json:"attributes,omitempty" gorm:"type:json"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Validate checks that the log has the required fields and fits the storage schema
+func (l *Log) Validate() error {
+	switch l.Level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal:
+	default:
+		return fmt.Errorf("level must be one of DEBUG, INFO, WARN, ERROR, FATAL")
+	}
+	if l.Service == "" {
+		return fmt.Errorf("service is required")
+	}
+	if len(l.Service) > constants.MaxServiceLength {
+		return fmt.Errorf("service must be at most %d characters", constants.MaxServiceLength)
+	}
+	if l.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	if l.Tenant != nil && len(*l.Tenant) > constants.MaxTenantLength {
+		return fmt.Errorf("tenant must be at most %d characters", constants.MaxTenantLength)
+	}
+	if l.Host != nil && len(*l.Host) > constants.MaxHostLength {
+		return fmt.Errorf("host must be at most %d characters", constants.MaxHostLength)
+	}
+	if l.Environment != nil && len(*l.Environment) > constants.MaxEnvironmentLength {
+		return fmt.Errorf("environment must be at most %d characters", constants.MaxEnvironmentLength)
+	}
+	if l.Region != nil && len(*l.Region) > constants.MaxRegionLength {
+		return fmt.Errorf("region must be at most %d characters", constants.MaxRegionLength)
+	}
+	if l.ClientIP != nil && net.ParseIP(*l.ClientIP) == nil {
+		return fmt.Errorf("client_ip must be an IPv4 or IPv6 address")
+	}
+	if l.TraceID != nil && len(*l.TraceID) > constants.MaxTraceIDLength {
+		return fmt.Errorf("trace_id must be at most %d characters", constants.MaxTraceIDLength)
+	}
+	if l.SpanID != nil && len(*l.SpanID) > constants.MaxSpanIDLength {
+		return fmt.Errorf("span_id must be at most %d characters", constants.MaxSpanIDLength)
+	}
+	if l.ParentSpanID != nil && len(*l.ParentSpanID) > constants.MaxSpanIDLength {
+		return fmt.Errorf("parent_span_id must be at most %d characters", constants.MaxSpanIDLength)
+	}
+	if l.MessageID != nil && len(*l.MessageID) > constants.MaxMessageIDLength {
+		return fmt.Errorf("message_id must be at most %d characters", constants.MaxMessageIDLength)
+	}
+	if l.UserID != nil && len(*l.UserID) > constants.MaxUserIDLength {
+		return fmt.Errorf("user_id must be at most %d characters", constants.MaxUserIDLength)
+	}
+	if l.RequestMethod != nil && len(*l.RequestMethod) > constants.MaxRequestMethodLength {
+		return fmt.Errorf("request_method must be at most %d characters", constants.MaxRequestMethodLength)
+	}
+	if l.RequestPath != nil && len(*l.RequestPath) > constants.MaxRequestPathLength {
+		return fmt.Errorf("request_path must be at most %d characters", constants.MaxRequestPathLength)
+	}
+	if l.ResponseStatus != nil && (*l.ResponseStatus < constants.MinHTTPStatus || *l.ResponseStatus > constants.MaxHTTPStatus) {
+		return fmt.Errorf("response_status must be between %d and %d", constants.MinHTTPStatus, constants.MaxHTTPStatus)
+	}
+	if l.ResponseTimeMs != nil && *l.ResponseTimeMs < 0 {
+		return fmt.Errorf("response_time_ms must not be negative")
+	}
+	if l.RequestBody != nil && len(*l.RequestBody) > constants.MaxBodyExcerptLength {
+		return fmt.Errorf("request_body must be at most %d bytes", constants.MaxBodyExcerptLength)
+	}
+	if l.ResponseBody != nil && len(*l.ResponseBody) > constants.MaxBodyExcerptLength {
+		return fmt.Errorf("response_body must be at most %d bytes", constants.MaxBodyExcerptLength)
+	}
+	return nil
+}
+
+// LogColumns are the columns of logs, named like their JSON fields, in the order of the log fields
+var LogColumns = []string{
+	"id", "timestamp", "level", "service", "tenant", "host", "environment", "region", "client_ip", "message",
+	"fingerprint", "trace_id", "span_id", "parent_span_id", "user_id", "request_method", "request_path",
+	"response_status", "response_time_ms", "request_body", "response_body", "attributes", "created_at",
+}
+
+// LogDimensions selects logs by tenant, where they were emitted and who made the request
+type LogDimensions struct {
+	Tenant      *string `json:"tenant,omitempty"`
+	Host        *string `json:"host,omitempty"`
+	Environment *string `json:"environment,omitempty"`
+	Region      *string `json:"region,omitempty"`
+	ClientIP    *string `json:"client_ip,omitempty"`
 }
 
 // LogFilter represents filters for querying logs
 type LogFilter struct {
-	Level     *LogLevel  `json:"level,omitempty"`
-	Service   *string    `json:"service,omitempty"`
-	TraceID   *string    `json:"trace_id,omitempty"`
-	UserID    *string    `json:"user_id,omitempty"`
-	StartTime *time.Time `json:"start_time,omitempty"`
-	EndTime   *time.Time `json:"end_time,omitempty"`
-	Search    *string    `json:"search,omitempty"`
-	Limit     int        `json:"limit,omitempty"`
-	Offset    int        `json:"offset,omitempty"`
+	LogDimensions
+	Level           *LogLevel         `json:"level,omitempty"`
+	Service         *string           `json:"service,omitempty"`
+	TraceID         *string           `json:"trace_id,omitempty"`
+	SpanID          *string           `json:"span_id,omitempty"`
+	ParentSpanID    *string           `json:"parent_span_id,omitempty"`
+	UserID          *string           `json:"user_id,omitempty"`
+	StartTime       *time.Time        `json:"start_time,omitempty"`
+	EndTime         *time.Time        `json:"end_time,omitempty"`
+	Search          *string           `json:"search,omitempty"`
+	Fingerprint     *string           `json:"fingerprint,omitempty"`
+	HasRequestBody  *bool             `json:"has_request_body,omitempty"`
+	HasResponseBody *bool             `json:"has_response_body,omitempty"`
+	Attributes      map[string]string `json:"attributes,omitempty"` // attribute key -> required value
+	Fields          []string          `json:"fields,omitempty"`     // columns read, among LogColumns; all when empty
+	Limit           int               `json:"limit,omitempty"`
+	Offset          int               `json:"offset,omitempty"`
 }
 
 // LogStats represents aggregated statistics for logs
@@ -53,9 +168,125 @@ type LogStats struct {
 	DebugCount      int64            `json:"debug_count"`
 	FatalCount      int64            `json:"fatal_count"`
 	AvgResponseTime float64          `json:"avg_response_time"`
+	Status2xxCount  int64            `json:"status_2xx_count"`
+	Status3xxCount  int64            `json:"status_3xx_count"`
+	Status4xxCount  int64            `json:"status_4xx_count"`
+	Status5xxCount  int64            `json:"status_5xx_count"`
 	TopServices     []ServiceCount   `json:"top_services"`
 	TopErrors       []ErrorCount     `json:"top_errors"`
 	TimeSeries      []TimeSeriesData `json:"time_series"`
+	ServiceLatency  []ServiceLatency `json:"service_latency"`
+
+	ResponseTimePercentiles
+}
+
+// ResponseTimePercentiles are nearest-rank percentiles of the response times of logs, nil when none carries one
+type ResponseTimePercentiles struct {
+	P50ResponseTime *int `json:"p50_response_time" gorm:"column:p50_response_time"`
+	P95ResponseTime *int `json:"p95_response_time" gorm:"column:p95_response_time"`
+	P99ResponseTime *int `json:"p99_response_time" gorm:"column:p99_response_time"`
+}
+
+// ServiceLatency represents the response time percentiles of a single service
+type ServiceLatency struct {
+	Service string `json:"service"`
+	Count   int64  `json:"count"` // logs carrying a response time
+	ResponseTimePercentiles
+}
+
+// ServiceComparison represents volume, error and latency figures of a single service over a time range.
+// Percentiles are nil when none of the service's logs carry a response time.
+type ServiceComparison struct {
+	Service         string   `json:"service"`
+	TotalLogs       int64    `json:"total_logs"`
+	ErrorCount      int64    `json:"error_count"`
+	ErrorRate       float64  `json:"error_rate_percent" gorm:"-"`
+	Status4xxCount  int64    `json:"status_4xx_count" gorm:"column:status4xx_count"`
+	Status5xxCount  int64    `json:"status_5xx_count" gorm:"column:status5xx_count"`
+	RequestsPerMin  float64  `json:"requests_per_minute" gorm:"-"`
+	AvgResponseTime *float64 `json:"avg_response_time"`
+	P50ResponseTime *int     `json:"p50_response_time" gorm:"column:p50_response_time"`
+	P95ResponseTime *int     `json:"p95_response_time" gorm:"column:p95_response_time"`
+	P99ResponseTime *int     `json:"p99_response_time" gorm:"column:p99_response_time"`
+}
+
+// SlowRequestQuery selects the endpoints with the highest p95 or average response times over a time range,
+// among those with at least MinCount requests, optionally of a single service and tenant
+type SlowRequestQuery struct {
+	Service   string
+	Tenant    *string
+	StartTime time.Time
+	EndTime   time.Time
+	Sort      string // p95 or avg
+	MinCount  int
+	Limit     int
+}
+
+// SlowRequest represents the request count and response times of an endpoint, a request method and path, over a
+// time range. Only logs carrying a request method, path and response time are counted.
+type SlowRequest struct {
+	RequestMethod   string  `json:"request_method"`
+	RequestPath     string  `json:"request_path"`
+	Count           int64   `json:"count"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+	P95ResponseTime int     `json:"p95_response_time" gorm:"column:p95_response_time"`
+	MaxResponseTime int     `json:"max_response_time"`
+}
+
+// LogGroupByFields are the fields logs can be grouped by in aggregations
+var LogGroupByFields = []string{"service", "level", "request_path", "response_status", "user_id"}
+
+// LogAggregation groups the logs matching a filter by some of their fields and aggregates each group, counting its
+// logs or averaging or maximizing their response times. The Limit groups with the highest values are returned.
+type LogAggregation struct {
+	Filter      LogFilter
+	GroupBy     []string // fields of LogGroupByFields
+	Aggregation string   // count, avg_response_time or max_response_time
+	Limit       int
+}
+
+// LogGroup is a group of logs of an aggregation. Its key holds the values of the group_by fields, nil for logs
+// without the field.
+type LogGroup struct {
+	Key   map[string]any `json:"key"`
+	Count int64          `json:"count"`
+	// Value of the aggregation: the count, or the average or maximum response time in milliseconds, nil when no
+	// log of the group carries one
+	Value *float64 `json:"value"`
+	// Response time figures the value is computed from, so that groups of several shards can be merged
+	ResponseTimeSum   float64  `json:"-"`
+	ResponseTimeCount int64    `json:"-"`
+	MaxResponseTime   *float64 `json:"-"`
+}
+
+// Aggregate sets the group's value of the aggregation from its count and response time figures
+func (g *LogGroup) Aggregate(aggregation string) {
+	switch aggregation {
+	case constants.LogAggregationAvgResponseTime:
+		g.Value = nil
+		if g.ResponseTimeCount > 0 {
+			avg := g.ResponseTimeSum / float64(g.ResponseTimeCount)
+			g.Value = &avg
+		}
+	case constants.LogAggregationMaxResponseTime:
+		g.Value = g.MaxResponseTime
+	default:
+		count := float64(g.Count)
+		g.Value = &count
+	}
+}
+
+// LatencyHeatmapCell counts the logs of one time bucket whose response time falls into one latency bucket
+type LatencyHeatmapCell struct {
+	TimeBucket    int   `json:"time_bucket"`
+	LatencyBucket int   `json:"latency_bucket"`
+	Count         int64 `json:"count"`
+}
+
+// LatencyBucket is a response time range in milliseconds; the last bucket is open-ended
+type LatencyBucket struct {
+	MinMs int  `json:"min_ms"`
+	MaxMs *int `json:"max_ms"`
 }
 
 // ServiceCount represents service log count
@@ -72,7 +303,58 @@ type ErrorCount struct {
 
 // TimeSeriesData represents time series data point
 type TimeSeriesData struct {
-	Timestamp time.Time `json:"timestamp"`
-	Count     int64     `json:"count"`
-	ErrorRate float64   `json:"error_rate"`
+	Timestamp    time.Time `json:"timestamp"`
+	Count        int64     `json:"count"`
+	DebugCount   int64     `json:"debug_count"`
+	InfoCount    int64     `json:"info_count"`
+	WarningCount int64     `json:"warning_count"`
+	ErrorCount   int64     `json:"error_count"`
+	FatalCount   int64     `json:"fatal_count"`
+	ErrorRate    float64   `json:"error_rate"` // percentage of ERROR and FATAL logs
+}
+
+// Add accumulates the counts of another data point for the same bucket and updates the error rate
+func (d *TimeSeriesData) Add(other TimeSeriesData) {
+	d.Count += other.Count
+	d.DebugCount += other.DebugCount
+	d.InfoCount += other.InfoCount
+	d.WarningCount += other.WarningCount
+	d.ErrorCount += other.ErrorCount
+	d.FatalCount += other.FatalCount
+	d.ErrorRate = 0
+	if d.Count > 0 {
+		d.ErrorRate = float64(d.ErrorCount+d.FatalCount) / float64(d.Count) * 100
+	}
+}
+
+// BucketAlignment places the boundaries of time series buckets. Buckets of up to a day start at midnight UTC
+// shifted by the offset, e.g. 6h for business days starting at 06:00, and weekly buckets on the week start day.
+type BucketAlignment struct {
+	WeekStart time.Weekday
+	Offset    time.Duration
+}
+
+// Truncate returns the start of the bucket of the given interval holding t
+func (a BucketAlignment) Truncate(t time.Time, interval time.Duration) time.Time {
+	origin := time.Unix(0, 0).UTC().Add(a.Offset)
+	if interval%(7*24*time.Hour) == 0 {
+		// The Unix epoch fell on a Thursday
+		origin = origin.AddDate(0, 0, (int(a.WeekStart)-int(time.Thursday)+7)%7)
+	}
+	elapsed := t.Sub(origin)
+	buckets := elapsed / interval
+	if elapsed < 0 && elapsed%interval != 0 {
+		buckets--
+	}
+	return origin.Add(buckets * interval).In(t.Location())
+}
+
+// ParseWeekday parses an English weekday name such as monday, ignoring case
+func ParseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("week start must be a weekday name such as monday or sunday")
 }
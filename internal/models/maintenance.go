@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// MaintenanceState is the persisted maintenance switch shared by the API server and the log processor
+type MaintenanceState struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	ReadOnly  bool      `json:"read_only" gorm:"not null;default:false"`
+	Banner    string    `json:"banner" gorm:"size:500;not null;default:''"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// MaintenanceStatus represents the effective maintenance status
+type MaintenanceStatus struct {
+	ReadOnly  bool      `json:"read_only"`
+	Forced    bool      `json:"forced"` // read-only is enforced by configuration and cannot be lifted via the API
+	Banner    string    `json:"banner,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// MaintenanceUpdate represents a request to change the maintenance state
+type MaintenanceUpdate struct {
+	ReadOnly *bool   `json:"read_only" binding:"required"`
+	Banner   *string `json:"banner"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Migration is a schema migration recorded as applied by the migration runner. Who applied it, how long it took
+// and the batch of the run that applied it are null for migrations applied before migration 017 recorded them.
+// Migrations rolled back since keep their row, with when and by whom they were rolled back, from migration 027 on.
+type Migration struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	Filename     string     `json:"filename"`
+	Checksum     string     `json:"checksum"`
+	AppliedAt    time.Time  `json:"applied_at"`
+	AppliedBy    *string    `json:"applied_by"`
+	DurationMs   *int64     `json:"duration_ms"`
+	Batch        *int       `json:"batch"`
+	RolledBackAt *time.Time `json:"rolled_back_at"`
+	RolledBackBy *string    `json:"rolled_back_by"`
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// NotificationChannel is a destination alerts are delivered to when they fire
+type NotificationChannel struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	Name      string          `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Type      string          `json:"type" gorm:"type:enum('email','slack','webhook','teams','discord');not null"` // email, slack, webhook, teams, discord
+	Settings  ChannelSettings `json:"settings" gorm:"type:json;not null"`
+	Enabled   bool            `json:"enabled" gorm:"default:true"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ChannelSettings holds the type-specific settings of a notification channel
+type ChannelSettings struct {
+	To            []string          `json:"to,omitempty"`             // email recipients
+	URL           string            `json:"url,omitempty"`            // Slack, Teams or Discord incoming webhook, or webhook URL
+	Method        string            `json:"method,omitempty"`         // webhook HTTP method, POST by default
+	Headers       map[string]string `json:"headers,omitempty"`        // extra webhook request headers
+	BodyTemplate  string            `json:"body_template,omitempty"`  // webhook payload template; the notification as JSON by default
+	Secret        string            `json:"secret,omitempty"`         // signs webhook payloads with HMAC-SHA256 when set
+	TitleTemplate string            `json:"title_template,omitempty"` // Teams or Discord message title template; the summary by default
+	TextTemplate  string            `json:"text_template,omitempty"`  // Teams or Discord message text template; the alert message by default
+}
+
+// Value implements driver.Valuer so settings are stored as a JSON column
+func (s ChannelSettings) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal channel settings: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so settings can be read back from a JSON column
+func (s *ChannelSettings) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*s = ChannelSettings{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported channel settings type: %T", value)
+	}
+	return json.Unmarshal(data, s)
+}
+
+// Validate checks that the channel has a known type and the settings it requires
+func (c *NotificationChannel) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(c.Name) > constants.MaxNotificationChannelNameLength {
+		return fmt.Errorf("name must be at most %d characters", constants.MaxNotificationChannelNameLength)
+	}
+
+	switch c.Type {
+	case constants.NotificationChannelEmail:
+		if len(c.Settings.To) == 0 {
+			return fmt.Errorf("email channels need at least one recipient in settings.to")
+		}
+	case constants.NotificationChannelSlack, constants.NotificationChannelTeams, constants.NotificationChannelDiscord:
+		if err := validateChannelURL(c.Settings.URL); err != nil {
+			return err
+		}
+	case constants.NotificationChannelWebhook:
+		if err := validateChannelURL(c.Settings.URL); err != nil {
+			return err
+		}
+		if c.Settings.Method != "" && !slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch}, c.Settings.Method) {
+			return fmt.Errorf("settings.method must be one of POST, PUT, PATCH")
+		}
+		if c.Settings.Secret != "" && len(c.Settings.Secret) < constants.MinWebhookSecretLength {
+			return fmt.Errorf("settings.secret must be at least %d characters", constants.MinWebhookSecretLength)
+		}
+	default:
+		return fmt.Errorf("type must be one of email, slack, webhook, teams, discord")
+	}
+	return nil
+}
+
+// validateChannelURL checks that a channel URL is an absolute HTTP(S) URL
+func validateChannelURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("settings.url must be an http or https URL")
+	}
+	return nil
+}
+
+// AlertRuleChannel binds an alert rule to a notification channel
+type AlertRuleChannel struct {
+	RuleID    uint      `json:"rule_id" gorm:"primaryKey"`
+	ChannelID uint      `json:"channel_id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlertRuleChannelsUpdate replaces the channels an alert rule notifies; an empty list removes them all
+type AlertRuleChannelsUpdate struct {
+	ChannelIDs []uint `json:"channel_ids" binding:"required"`
+}
+
+// AlertNotification is the content delivered to notification channels when an alert fires.
+// Webhook body templates are executed against it.
+type AlertNotification struct {
+	AlertID      uint         `json:"alert_id"`
+	RuleID       uint         `json:"rule_id"`
+	RuleName     string       `json:"rule_name"`
+	Severity     string       `json:"severity"`
+	Message      string       `json:"message"`
+	Value        float64      `json:"value"`
+	Threshold    float64      `json:"threshold"`
+	LateDetected bool         `json:"late_detected"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Reminder     int          `json:"reminder,omitempty"` // number of the re-notification of a still active alert
+	Report       *AlertReport `json:"report,omitempty"`   // nil when it could not be computed
+	Test         bool         `json:"test,omitempty"`     // sent by the channel test endpoint
+	Meta         bool         `json:"meta,omitempty"`     // fired by the alert checker about its own health rather than by a rule
+}
+
+// AlertReport summarizes the logs of the window an alert fired on, computed at firing time
+type AlertReport struct {
+	WindowStart       time.Time    `json:"window_start"`
+	WindowEnd         time.Time    `json:"window_end"`
+	TotalLogs         int64        `json:"total_logs"`
+	ErrorRate         float64      `json:"error_rate_percent"`   // share of ERROR and FATAL logs
+	P95ResponseTimeMs *float64     `json:"p95_response_time_ms"` // nil when no log carries a response time
+	TopErrors         []ErrorCount `json:"top_errors"`           // most frequent ERROR and FATAL messages
+	AffectedUsers     int64        `json:"affected_users"`       // distinct users with ERROR or FATAL logs
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+)
+
+// PipelineSettings are the dynamic processing settings of a service, published on the control topic keyed by the
+// service name, or by * for the settings of services without their own. They take effect on every processor as
+// soon as they are consumed.
+type PipelineSettings struct {
+	SampleRate   *float64   `json:"sample_rate,omitempty"`   // fraction of logs stored, between 0 and 1; all when unset
+	DropLevels   []LogLevel `json:"drop_levels,omitempty"`   // levels whose logs are discarded
+	ForwardTopic string     `json:"forward_topic,omitempty"` // topic the messages are republished to instead of being stored
+}
+
+// Validate checks the settings
+func (s *PipelineSettings) Validate() error {
+	if s.SampleRate != nil && (*s.SampleRate < 0 || *s.SampleRate > 1) {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	for _, level := range s.DropLevels {
+		switch level {
+		case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal:
+		default:
+			return fmt.Errorf("drop_levels must be among DEBUG, INFO, WARN, ERROR, FATAL")
+		}
+	}
+	return nil
+}
+
+// Drops reports whether logs of the level are discarded
+func (s *PipelineSettings) Drops(level LogLevel) bool {
+	return slices.Contains(s.DropLevels, level)
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"time"
+)
+
+// LogRetentionScope selects the logs a retention policy applies to. An empty service or level matches every service or level.
+type LogRetentionScope struct {
+	Service string   `json:"service" gorm:"size:100;not null;default:''"`
+	Level   LogLevel `json:"level" gorm:"size:10;not null;default:''"`
+}
+
+// specificity ranks scopes for precedence: service and level, then service, then level, then everything
+func (s LogRetentionScope) specificity() int {
+	rank := 0
+	if s.Service != "" {
+		rank += 2
+	}
+	if s.Level != "" {
+		rank++
+	}
+	return rank
+}
+
+// overlaps reports whether some log can match both scopes
+func (s LogRetentionScope) overlaps(other LogRetentionScope) bool {
+	return (s.Service == "" || other.Service == "" || s.Service == other.Service) &&
+		(s.Level == "" || other.Level == "" || s.Level == other.Level)
+}
+
+// LogRetentionPolicy deletes or archives the logs of its scope once they are older than its maximum age.
+// Of the policies matching a log, the most specific one applies, whether or not it is enabled, so disabling
+// a policy keeps its logs rather than handing them to a broader policy.
+type LogRetentionPolicy struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	LogRetentionScope
+	MaxAgeDays int        `json:"max_age_days" gorm:"not null"`
+	Action     string     `json:"action" gorm:"type:enum('delete','archive');not null"` // delete, archive
+	Enabled    bool       `json:"enabled" gorm:"not null"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastPurged int64      `json:"last_purged" gorm:"not null;default:0"` // logs deleted or archived by the last run
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Validate checks the policy's scope, maximum age and action
+func (p *LogRetentionPolicy) Validate() error {
+	switch p.Level {
+	case "", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal:
+	default:
+		return fmt.Errorf("level must be empty or one of DEBUG, INFO, WARN, ERROR, FATAL")
+	}
+	if len(p.Service) > constants.MaxServiceLength {
+		return fmt.Errorf("service must be at most %d characters", constants.MaxServiceLength)
+	}
+	if p.MaxAgeDays <= 0 || p.MaxAgeDays > constants.MaxRetentionDays {
+		return fmt.Errorf("max_age_days must be between 1 and %d", constants.MaxRetentionDays)
+	}
+	if p.Action != constants.RetentionActionDelete && p.Action != constants.RetentionActionArchive {
+		return fmt.Errorf("action must be %s or %s", constants.RetentionActionDelete, constants.RetentionActionArchive)
+	}
+	return nil
+}
+
+// Purge returns the purge removing the logs the policy applies to that are older than its maximum age at the given time.
+// Logs matched by a more specific policy of the given ones are left to that policy.
+func (p *LogRetentionPolicy) Purge(policies []LogRetentionPolicy, now time.Time) *LogPurge {
+	purge := &LogPurge{
+		LogRetentionScope: p.LogRetentionScope,
+		Before:            now.AddDate(0, 0, -p.MaxAgeDays),
+		Archive:           p.Action == constants.RetentionActionArchive,
+	}
+	for _, other := range policies {
+		if other.specificity() > p.specificity() && other.overlaps(p.LogRetentionScope) {
+			purge.Exclude = append(purge.Exclude, other.LogRetentionScope)
+		}
+	}
+	return purge
+}
+
+// LogPurge selects the logs removed by a retention run
+type LogPurge struct {
+	LogRetentionScope
+	Exclude []LogRetentionScope // scopes of more specific policies, whose logs are kept
+	Before  time.Time
+	Archive bool // move the logs to the archive table instead of deleting them
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SavedSearch is a named log filter that dashboard users run again instead of re-entering its filters
+type SavedSearch struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Filter    SavedFilter `json:"filter" gorm:"type:json;not null"`
+	CreatedBy string      `json:"created_by" gorm:"size:255;not null;default:''"` // username of the caller who created it, empty without authentication
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// SavedFilter is the log filter of a saved search, stored as a JSON column. Its limit and offset are ignored;
+// pages are requested when the search is run.
+type SavedFilter LogFilter
+
+// Value implements driver.Valuer so the filter is stored as a JSON column
+func (f SavedFilter) Value() (driver.Value, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal saved filter: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so the filter can be read back from a JSON column
+func (f *SavedFilter) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*f = SavedFilter{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported saved filter type: %T", value)
+	}
+	return json.Unmarshal(data, f)
+}
+
+// Validate checks the search's name and filter, and clears the filter's limit and offset
+func (s *SavedSearch) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(s.Name) > constants.MaxSavedSearchNameLength {
+		return fmt.Errorf("name must be at most %d characters", constants.MaxSavedSearchNameLength)
+	}
+
+	filter := &s.Filter
+	if filter.Level != nil {
+		switch *filter.Level {
+		case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal:
+		default:
+			return fmt.Errorf("filter.level must be one of DEBUG, INFO, WARN, ERROR, FATAL")
+		}
+	}
+	if filter.StartTime != nil && filter.EndTime != nil && filter.EndTime.Before(*filter.StartTime) {
+		return fmt.Errorf("filter.end_time must not be before filter.start_time")
+	}
+	if filter.ClientIP != nil && net.ParseIP(*filter.ClientIP) == nil {
+		return fmt.Errorf("filter.client_ip must be an IPv4 or IPv6 address")
+	}
+	if len(filter.Attributes) > constants.MaxAttributeFilters {
+		return fmt.Errorf("filter.attributes may hold at most %d attribute filters", constants.MaxAttributeFilters)
+	}
+	for key := range filter.Attributes {
+		if key == "" {
+			return fmt.Errorf("filter.attributes keys must not be empty")
+		}
+	}
+	for i, field := range filter.Fields {
+		if !slices.Contains(LogColumns, field) {
+			return fmt.Errorf("filter.fields must be among %s", strings.Join(LogColumns, ", "))
+		}
+		if slices.Contains(filter.Fields[:i], field) {
+			return fmt.Errorf("filter.fields must not repeat %s", field)
+		}
+	}
+	filter.Limit = 0
+	filter.Offset = 0
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ScheduledTask is a periodic task run by a single API server at a time, the one holding its lease, along with
+// the outcome of its last run
+type ScheduledTask struct {
+	Name            string     `json:"name" gorm:"primaryKey;size:100"`
+	IntervalSeconds int64      `json:"interval_seconds" gorm:"not null"`
+	LeaseOwner      *string    `json:"lease_owner,omitempty" gorm:"size:255"` // API server running the task
+	LeaseExpiresAt  *time.Time `json:"lease_expires_at,omitempty"`
+	LastStartedAt   *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt  *time.Time `json:"last_finished_at,omitempty"`
+	LastStatus      *string    `json:"last_status,omitempty" gorm:"size:16"` // running, succeeded, failed
+	LastError       string     `json:"last_error,omitempty" gorm:"size:1000;not null;default:''"`
+	LastDurationMs  *int64     `json:"last_duration_ms,omitempty"`
+}
+
+// ScheduledTaskRun records a run of a scheduled task
+type ScheduledTaskRun struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Task       string     `json:"task" gorm:"size:100;not null"`
+	Owner      string     `json:"owner" gorm:"size:255;not null"`
+	Status     string     `json:"status" gorm:"size:16;not null"` // running, succeeded, failed
+	Error      string     `json:"error,omitempty" gorm:"size:1000;not null;default:''"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs *int64     `json:"duration_ms,omitempty"`
+}
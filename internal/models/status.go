@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// StatusPage is the public health summary of the system. It names components and counts incidents, without any
+// detail of logs or alerts, since it is served without authentication.
+type StatusPage struct {
+	Status        string            `json:"status"` // the worst status of the components
+	Components    []ComponentStatus `json:"components"`
+	Incidents     *int              `json:"incidents"` // active alerts; null when they couldn't be counted
+	StartedAt     time.Time         `json:"started_at"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ProbeReport is the answer of a liveness or readiness probe, with the outcome of each check readiness depends on
+type ProbeReport struct {
+	Status    string            `json:"status"`           // alive, ready or not_ready
+	Checks    map[string]string `json:"checks,omitempty"` // check -> ok, or why it failed
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// ComponentStatus is the status of a component of the status page: operational, degraded, maintenance or outage
+type ComponentStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// StorageStats represents storage usage statistics used for capacity planning
+type StorageStats struct {
+	Tables              []TableStorage        `json:"tables"`
+	TotalDataBytes      int64                 `json:"total_data_bytes"`
+	TotalIndexBytes     int64                 `json:"total_index_bytes"`
+	TotalBytes          int64                 `json:"total_bytes"`
+	DailyGrowth         []DailyGrowth         `json:"daily_growth"`
+	AvgDailyGrowthBytes float64               `json:"avg_daily_growth_bytes"`
+	ServiceShare        []ServiceStorageShare `json:"service_share"`
+	DiskCapacityBytes   int64                 `json:"disk_capacity_bytes,omitempty"`
+	ProjectedFullAt     *time.Time            `json:"projected_full_at,omitempty"`
+	GeneratedAt         time.Time             `json:"generated_at"`
+}
+
+// TableStorage represents the size and row count of a single table
+type TableStorage struct {
+	Table      string `json:"table" gorm:"column:table_name"`
+	Store      string `json:"store,omitempty" gorm:"-"` // clickhouse or the database of a log shard; empty for the primary database
+	Rows       int64  `json:"rows" gorm:"column:row_count"`
+	DataBytes  int64  `json:"data_bytes"`
+	IndexBytes int64  `json:"index_bytes"`
+	TotalBytes int64  `json:"total_bytes"`
+	AvgRowSize int64  `json:"avg_row_size"`
+}
+
+// DailyGrowth represents the number of logs ingested on a day and their estimated size
+type DailyGrowth struct {
+	Day            time.Time `json:"day"`
+	Rows           int64     `json:"rows" gorm:"column:row_count"`
+	EstimatedBytes int64     `json:"estimated_bytes"`
+}
+
+// ServiceStorageShare represents a service's share of log storage, estimated from its share of
+// ingestion over the growth window
+type ServiceStorageShare struct {
+	Service        string  `json:"service"`
+	Rows           int64   `json:"rows" gorm:"column:row_count"`
+	EstimatedBytes int64   `json:"estimated_bytes"`
+	SharePercent   float64 `json:"share_percent"`
+}
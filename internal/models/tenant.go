@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"regexp"
+	"time"
+)
+
+// tenantNamePattern restricts tenant names to characters that are safe in topics, headers and query parameters
+var tenantNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Tenant is a team or environment sharing the deployment, whose logs, alert rules and alerts are kept apart from
+// those of other tenants. Logs are tagged with the tenant's name.
+type Tenant struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:64;not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"size:255;not null;default:''"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks the tenant's name and description
+func (t *Tenant) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.Name) > constants.MaxTenantLength {
+		return fmt.Errorf("name must be at most %d characters", constants.MaxTenantLength)
+	}
+	if !tenantNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("name may only contain letters, digits, dots, underscores and hyphens")
+	}
+	if len(t.Description) > constants.MaxTenantDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", constants.MaxTenantDescriptionLength)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// TraceQuery selects the most recent traces, by their last log, among those with logs in a time range, optionally
+// of a single service and tenant, or with errors only
+type TraceQuery struct {
+	Service    string
+	Tenant     *string
+	StartTime  time.Time
+	EndTime    time.Time
+	ErrorsOnly bool
+	Limit      int
+	Offset     int
+}
+
+// TraceRollup represents a trace in trace listings: the time span of its logs, the number of logs and services and
+// its error and latency figures. Logs at ERROR and FATAL level are errors.
+type TraceRollup struct {
+	TraceID         string    `json:"trace_id"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationMs      int64     `json:"duration_ms" gorm:"-"`
+	LogCount        int64     `json:"log_count"`
+	ServiceCount    int64     `json:"service_count"`
+	ErrorCount      int64     `json:"error_count"`
+	MaxResponseTime *int      `json:"max_response_time"`
+}
+
+// SetDuration sets the duration of the trace from its time span
+func (t *TraceRollup) SetDuration() {
+	t.DurationMs = t.EndTime.Sub(t.StartTime).Milliseconds()
+}
+
+// TraceSummary represents a trace combining the logs of every service it went through: the services in the order
+// the trace reached them, the time span of their logs, whether any of them is an error, the tree of the spans its
+// logs were emitted in, and the logs in order
+type TraceSummary struct {
+	TraceID          string         `json:"trace_id"`
+	Services         []string       `json:"services"`
+	StartTime        time.Time      `json:"start_time"`
+	EndTime          time.Time      `json:"end_time"`
+	DurationMs       int64          `json:"duration_ms"`
+	LogCount         int            `json:"log_count"`
+	ErrorCount       int            `json:"error_count"`
+	HasErrors        bool           `json:"has_errors"`
+	ServiceDurations []TraceService `json:"service_durations"`
+	Spans            []TraceSpan    `json:"spans"`
+	Logs             []*Log         `json:"logs"`
+}
+
+// TraceService represents the part of a trace spent in a service, from its first to its last log of the trace
+type TraceService struct {
+	Service    string    `json:"service"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	DurationMs int64     `json:"duration_ms"`
+	LogCount   int       `json:"log_count"`
+	ErrorCount int       `json:"error_count"`
+}
+
+// TraceSpan represents a span of a trace, from the first to the last log emitted in it, and its place in the tree of
+// spans: its parent and its depth below the root. Spans whose parent has no logs in the trace are roots.
+type TraceSpan struct {
+	SpanID       string    `json:"span_id"`
+	ParentSpanID *string   `json:"parent_span_id"`
+	Depth        int       `json:"depth"`
+	Service      string    `json:"service"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	DurationMs   int64     `json:"duration_ms"`
+	LogCount     int       `json:"log_count"`
+	ErrorCount   int       `json:"error_count"`
+}
+
+// NewTraceSummary summarizes the logs of a trace, ordered by timestamp
+func NewTraceSummary(traceID string, logs []*Log) *TraceSummary {
+	summary := &TraceSummary{
+		TraceID:          traceID,
+		Services:         []string{},
+		ServiceDurations: []TraceService{},
+		Spans:            []TraceSpan{},
+		Logs:             logs,
+		LogCount:         len(logs),
+	}
+	if summary.Logs == nil {
+		summary.Logs = []*Log{}
+	}
+
+	services := make(map[string]int)
+	spans := make(map[string]int)
+	for _, log := range logs {
+		isError := log.Level == LogLevelError || log.Level == LogLevelFatal
+		if isError {
+			summary.ErrorCount++
+		}
+
+		i, ok := services[log.Service]
+		if !ok {
+			i = len(summary.ServiceDurations)
+			services[log.Service] = i
+			summary.Services = append(summary.Services, log.Service)
+			summary.ServiceDurations = append(summary.ServiceDurations, TraceService{Service: log.Service, StartTime: log.Timestamp, EndTime: log.Timestamp})
+		}
+		service := &summary.ServiceDurations[i]
+		service.StartTime = earliest(service.StartTime, log.Timestamp)
+		service.EndTime = latest(service.EndTime, log.Timestamp)
+		service.LogCount++
+		if isError {
+			service.ErrorCount++
+		}
+
+		if log.SpanID == nil {
+			continue
+		}
+		j, ok := spans[*log.SpanID]
+		if !ok {
+			j = len(summary.Spans)
+			spans[*log.SpanID] = j
+			summary.Spans = append(summary.Spans, TraceSpan{SpanID: *log.SpanID, Service: log.Service, StartTime: log.Timestamp, EndTime: log.Timestamp})
+		}
+		span := &summary.Spans[j]
+		if span.ParentSpanID == nil {
+			span.ParentSpanID = log.ParentSpanID
+		}
+		span.StartTime = earliest(span.StartTime, log.Timestamp)
+		span.EndTime = latest(span.EndTime, log.Timestamp)
+		span.LogCount++
+		if isError {
+			span.ErrorCount++
+		}
+	}
+	for i := range summary.Spans {
+		summary.Spans[i].DurationMs = summary.Spans[i].EndTime.Sub(summary.Spans[i].StartTime).Milliseconds()
+	}
+	summary.Spans = spanTree(summary.Spans)
+
+	for i := range summary.ServiceDurations {
+		service := &summary.ServiceDurations[i]
+		service.DurationMs = service.EndTime.Sub(service.StartTime).Milliseconds()
+		if i == 0 {
+			summary.StartTime, summary.EndTime = service.StartTime, service.EndTime
+		}
+		summary.StartTime = earliest(summary.StartTime, service.StartTime)
+		summary.EndTime = latest(summary.EndTime, service.EndTime)
+	}
+	summary.DurationMs = summary.EndTime.Sub(summary.StartTime).Milliseconds()
+	summary.HasErrors = summary.ErrorCount > 0
+	return summary
+}
+
+// spanTree orders spans depth-first from their roots, the children of a span following it by start time, and sets
+// their depth. Spans whose parent has no logs in the trace are roots, as are spans caught in a cycle of parents.
+func spanTree(spans []TraceSpan) []TraceSpan {
+	index := make(map[string]int, len(spans))
+	for i, span := range spans {
+		index[span.SpanID] = i
+	}
+	children := make(map[string][]int)
+	var roots []int
+	for i, span := range spans {
+		if span.ParentSpanID != nil && *span.ParentSpanID != span.SpanID {
+			if _, ok := index[*span.ParentSpanID]; ok {
+				children[*span.ParentSpanID] = append(children[*span.ParentSpanID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	byStart := func(a, b int) int { return spans[a].StartTime.Compare(spans[b].StartTime) }
+	ordered := make([]TraceSpan, 0, len(spans))
+	visited := make([]bool, len(spans))
+	var visit func(i, depth int)
+	visit = func(i, depth int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		span := spans[i]
+		span.Depth = depth
+		ordered = append(ordered, span)
+		next := children[span.SpanID]
+		slices.SortStableFunc(next, byStart)
+		for _, child := range next {
+			visit(child, depth+1)
+		}
+	}
+	slices.SortStableFunc(roots, byStart)
+	for _, root := range roots {
+		visit(root, 0)
+	}
+	// Spans in a cycle of parents can't be reached from a root
+	for i := range spans {
+		visit(i, 0)
+	}
+	return ordered
+}
+
+// earliest returns the earlier of two times
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// latest returns the later of two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
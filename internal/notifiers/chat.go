@@ -0,0 +1,53 @@
+package notifiers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/models"
+	"text/template"
+)
+
+// chatTemplates render the title and text of Teams and Discord messages from the channel's templates. Without
+// templates the title is the summary and the text the alert's message.
+type chatTemplates struct {
+	title *template.Template
+	text  *template.Template
+}
+
+// newChatTemplates compiles the title and text templates of a channel
+func newChatTemplates(settings *models.ChannelSettings) (*chatTemplates, error) {
+	title, err := parseTemplate("title_template", settings.TitleTemplate)
+	if err != nil {
+		return nil, err
+	}
+	text, err := parseTemplate("text_template", settings.TextTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &chatTemplates{title: title, text: text}, nil
+}
+
+// render returns the title and text of the message of a notification
+func (t *chatTemplates) render(notification *models.AlertNotification) (string, string, error) {
+	title := summary(notification)
+	if t.title != nil {
+		var buf bytes.Buffer
+		if err := t.title.Execute(&buf, notification); err != nil {
+			return "", "", fmt.Errorf("failed to render title: %w", err)
+		}
+		title = buf.String()
+	}
+
+	text := notification.Message
+	if notification.LateDetected {
+		text += "\n\n_Detected late, by evaluating a missed window_"
+	}
+	if t.text != nil {
+		var buf bytes.Buffer
+		if err := t.text.Execute(&buf, notification); err != nil {
+			return "", "", fmt.Errorf("failed to render text: %w", err)
+		}
+		text = buf.String()
+	}
+	return title, text, nil
+}
@@ -0,0 +1,67 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discordNotifier posts notifications as embeds to a Discord webhook
+type discordNotifier struct {
+	url       string
+	templates *chatTemplates
+	client    *http.Client
+}
+
+// discordColors maps severities to the colors of the embed's side bar
+var discordColors = map[string]int{
+	constants.AlertSeverityLow:      0x2EB67D,
+	constants.AlertSeverityMedium:   0xECB22E,
+	constants.AlertSeverityHigh:     0xE8912D,
+	constants.AlertSeverityCritical: 0xE01E5A,
+}
+
+// Send posts the notification as a Discord embed, cut to Discord's limits
+func (n *discordNotifier) Send(ctx context.Context, notification *models.AlertNotification) error {
+	title, text, err := n.templates.render(notification)
+	if err != nil {
+		return err
+	}
+
+	field := func(name, value string, inline bool) map[string]any {
+		return map[string]any{"name": name, "value": truncate(value, constants.DiscordMaxFieldLength), "inline": inline}
+	}
+	fields := []map[string]any{
+		field("Rule", fmt.Sprintf("%s (ID %d)", notification.RuleName, notification.RuleID), false),
+		field("Severity", notification.Severity, true),
+		field("Value", fmt.Sprintf("%.2f (threshold: %.2f)", notification.Value, notification.Threshold), true),
+		field("Alert ID", strconv.FormatUint(uint64(notification.AlertID), 10), true),
+	}
+	if notification.Report != nil {
+		report := truncate(strings.Join(reportLines(notification.Report), "\n"), constants.DiscordMaxFieldLength-len("``````"))
+		fields = append(fields, field("Report", "```"+report+"```", false))
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"embeds": []map[string]any{{
+			"title":       truncate(title, constants.DiscordMaxTitleLength),
+			"description": truncate(text, constants.DiscordMaxDescriptionLength),
+			"color":       discordColors[notification.Severity],
+			"timestamp":   notification.CreatedAt.Format(time.RFC3339),
+			"fields":      fields,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Discord message: %w", err)
+	}
+	if err := post(ctx, n.client, http.MethodPost, n.url, payload, nil); err != nil {
+		return fmt.Errorf("failed to post to Discord: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// emailNotifier sends notifications through the configured SMTP server
+type emailNotifier struct {
+	to  []string
+	cfg *config.NotificationConfig
+}
+
+// Send sends the notification as a plain text email to all recipients
+func (n *emailNotifier) Send(ctx context.Context, notification *models.AlertNotification) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", n.cfg.SMTPFrom)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", summary(notification))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&body, "%s\r\n\r\n", notification.Message)
+	fmt.Fprintf(&body, "Rule: %s (ID %d)\r\n", notification.RuleName, notification.RuleID)
+	fmt.Fprintf(&body, "Severity: %s\r\n", notification.Severity)
+	fmt.Fprintf(&body, "Value: %.2f (threshold: %.2f)\r\n", notification.Value, notification.Threshold)
+	fmt.Fprintf(&body, "Alert ID: %d\r\n", notification.AlertID)
+	fmt.Fprintf(&body, "Fired at: %s\r\n", notification.CreatedAt.Format(time.RFC3339))
+	if notification.LateDetected {
+		body.WriteString("Detected late, by evaluating a window missed while the alert checker was down.\r\n")
+	}
+	if notification.Report != nil {
+		fmt.Fprintf(&body, "\r\n%s\r\n", strings.Join(reportLines(notification.Report), "\r\n"))
+	}
+
+	var auth smtp.Auth
+	if n.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", n.cfg.SMTPUsername, n.cfg.SMTPPassword, n.cfg.SMTPHost)
+	}
+
+	// net/smtp doesn't take a context, so the send is abandoned rather than interrupted when it is done
+	addr := net.JoinHostPort(n.cfg.SMTPHost, n.cfg.SMTPPort)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- smtp.SendMail(addr, auth, n.cfg.SMTPFrom, n.to, []byte(body.String()))
+	}()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Notifier delivers alert notifications to a channel
+type Notifier interface {
+	// Send makes a single delivery attempt; retrying is left to the caller
+	Send(ctx context.Context, notification *models.AlertNotification) error
+}
+
+// New creates the notifier of a channel. The channel must have passed validation.
+func New(channel *models.NotificationChannel, cfg *config.NotificationConfig, client *http.Client) (Notifier, error) {
+	switch channel.Type {
+	case constants.NotificationChannelEmail:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("email channels require SMTP to be configured")
+		}
+		return &emailNotifier{to: channel.Settings.To, cfg: cfg}, nil
+	case constants.NotificationChannelSlack:
+		return &slackNotifier{url: channel.Settings.URL, client: client}, nil
+	case constants.NotificationChannelWebhook:
+		return newWebhookNotifier(&channel.Settings, client)
+	case constants.NotificationChannelTeams:
+		templates, err := newChatTemplates(&channel.Settings)
+		if err != nil {
+			return nil, err
+		}
+		return &teamsNotifier{url: channel.Settings.URL, templates: templates, client: client}, nil
+	case constants.NotificationChannelDiscord:
+		templates, err := newChatTemplates(&channel.Settings)
+		if err != nil {
+			return nil, err
+		}
+		return &discordNotifier{url: channel.Settings.URL, templates: templates, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel type %q", channel.Type)
+	}
+}
+
+// summary is the one-line description of a notification used in email subjects and chat messages
+func summary(n *models.AlertNotification) string {
+	prefix := ""
+	if n.Test {
+		prefix = "[TEST] "
+	}
+	if n.Meta {
+		return fmt.Sprintf("%s[%s] %s meta-alert fired", prefix, strings.ToUpper(n.Severity), n.RuleName)
+	}
+	if n.Reminder > 0 {
+		return fmt.Sprintf("%s[%s] Alert rule '%s' still firing (reminder %d)", prefix, strings.ToUpper(n.Severity), n.RuleName, n.Reminder)
+	}
+	return fmt.Sprintf("%s[%s] Alert rule '%s' fired", prefix, strings.ToUpper(n.Severity), n.RuleName)
+}
+
+// reportLines renders the report of the window an alert fired on as plain text lines
+func reportLines(r *models.AlertReport) []string {
+	latency := "n/a"
+	if r.P95ResponseTimeMs != nil {
+		latency = fmt.Sprintf("%.0f ms", *r.P95ResponseTimeMs)
+	}
+	lines := []string{
+		fmt.Sprintf("Window: %s - %s", r.WindowStart.Format(time.RFC3339), r.WindowEnd.Format(time.RFC3339)),
+		fmt.Sprintf("Error rate: %.2f%% of %d logs", r.ErrorRate, r.TotalLogs),
+		fmt.Sprintf("p95 latency: %s", latency),
+		fmt.Sprintf("Affected users: %d", r.AffectedUsers),
+	}
+	if len(r.TopErrors) > 0 {
+		lines = append(lines, "Top errors:")
+		for _, e := range r.TopErrors {
+			message := e.Message
+			if len(message) > constants.AlertReportMaxMessageLength {
+				message = strings.ToValidUTF8(message[:constants.AlertReportMaxMessageLength], "") + "..."
+			}
+			lines = append(lines, fmt.Sprintf("  %dx %s", e.Count, message))
+		}
+	}
+	return lines
+}
+
+// truncate shortens text to at most limit bytes, ending it with an ellipsis when cut
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	return strings.ToValidUTF8(text[:limit-3], "") + "..."
+}
+
+// post sends a request and treats any non-2xx response as a failure
+func post(ctx context.Context, client *http.Client, method, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
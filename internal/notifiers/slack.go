@@ -0,0 +1,37 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strings"
+)
+
+// slackNotifier posts notifications to a Slack incoming webhook
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Send posts the notification as a Slack message
+func (n *slackNotifier) Send(ctx context.Context, notification *models.AlertNotification) error {
+	text := fmt.Sprintf("*%s*\n%s\nValue: %.2f (threshold: %.2f)",
+		summary(notification), notification.Message, notification.Value, notification.Threshold)
+	if notification.LateDetected {
+		text += "\n_Detected late, by evaluating a missed window_"
+	}
+	if notification.Report != nil {
+		text += "\n```" + strings.Join(reportLines(notification.Report), "\n") + "```"
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+	if err := post(ctx, n.client, http.MethodPost, n.url, body, nil); err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// teamsNotifier posts notifications as adaptive cards to a Microsoft Teams incoming webhook or workflow
+type teamsNotifier struct {
+	url       string
+	templates *chatTemplates
+	client    *http.Client
+}
+
+// teamsColors maps severities to the adaptive card colors of the card title
+var teamsColors = map[string]string{
+	constants.AlertSeverityLow:      "Accent",
+	constants.AlertSeverityMedium:   "Warning",
+	constants.AlertSeverityHigh:     "Attention",
+	constants.AlertSeverityCritical: "Attention",
+}
+
+// Send posts the notification as an adaptive card
+func (n *teamsNotifier) Send(ctx context.Context, notification *models.AlertNotification) error {
+	title, text, err := n.templates.render(notification)
+	if err != nil {
+		return err
+	}
+
+	color, ok := teamsColors[notification.Severity]
+	if !ok {
+		color = "Default"
+	}
+	body := []map[string]any{
+		{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+		{"type": "TextBlock", "text": text, "wrap": true},
+		{"type": "FactSet", "facts": []map[string]string{
+			{"title": "Rule", "value": fmt.Sprintf("%s (ID %d)", notification.RuleName, notification.RuleID)},
+			{"title": "Severity", "value": notification.Severity},
+			{"title": "Value", "value": fmt.Sprintf("%.2f (threshold: %.2f)", notification.Value, notification.Threshold)},
+			{"title": "Alert ID", "value": strconv.FormatUint(uint64(notification.AlertID), 10)},
+			{"title": "Fired at", "value": notification.CreatedAt.Format(time.RFC3339)},
+		}},
+	}
+	if notification.Report != nil {
+		// Text blocks only break lines at blank lines
+		body = append(body, map[string]any{
+			"type": "TextBlock", "text": strings.Join(reportLines(notification.Report), "\n\n"),
+			"fontType": "Monospace", "isSubtle": true, "wrap": true,
+		})
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+				"msteams": map[string]string{"width": "Full"},
+			},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Teams message: %w", err)
+	}
+	if err := post(ctx, n.client, http.MethodPost, n.url, payload, nil); err != nil {
+		return fmt.Errorf("failed to post to Teams: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/pkg/client"
+	"maps"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// webhookNotifier sends notifications to an HTTP endpoint, rendering the payload from a template when one is set
+// and signing it when the channel has a secret
+type webhookNotifier struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template // nil sends the notification as JSON
+	secret  string
+	client  *http.Client
+}
+
+// templateFuncs are available in webhook body templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. to embed the message as a quoted string
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// newWebhookNotifier creates a webhook notifier, compiling its body template
+func newWebhookNotifier(settings *models.ChannelSettings, client *http.Client) (*webhookNotifier, error) {
+	n := &webhookNotifier{
+		url:     settings.URL,
+		method:  settings.Method,
+		headers: settings.Headers,
+		secret:  settings.Secret,
+		client:  client,
+	}
+	if n.method == "" {
+		n.method = http.MethodPost
+	}
+	body, err := parseTemplate("body_template", settings.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	return n, nil
+}
+
+// parseTemplate compiles the template of a channel setting, returning nil when the setting is empty
+func parseTemplate(setting, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(setting).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings.%s: %w", setting, err)
+	}
+	return tmpl, nil
+}
+
+// Send renders the payload and sends it to the webhook
+func (n *webhookNotifier) Send(ctx context.Context, notification *models.AlertNotification) error {
+	var body []byte
+	if n.body != nil {
+		var buf bytes.Buffer
+		if err := n.body.Execute(&buf, notification); err != nil {
+			return fmt.Errorf("failed to render webhook body: %w", err)
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		if body, err = json.Marshal(notification); err != nil {
+			return fmt.Errorf("failed to marshal notification: %w", err)
+		}
+	}
+
+	headers := n.headers
+	if n.secret != "" {
+		// Every attempt is signed anew, so retries carry a current timestamp
+		timestamp := time.Now().Unix()
+		headers = maps.Clone(n.headers)
+		if headers == nil {
+			headers = make(map[string]string, 2)
+		}
+		headers[constants.WebhookTimestampHeader] = strconv.FormatInt(timestamp, 10)
+		headers[constants.WebhookSignatureHeader] = client.Sign(n.secret, timestamp, body)
+	}
+
+	if err := post(ctx, n.client, n.method, n.url, body, headers); err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	return nil
+}
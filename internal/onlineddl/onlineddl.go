@@ -0,0 +1,174 @@
+package onlineddl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// alterTablePattern matches ALTER TABLE statements, capturing the table and the alter clauses
+	alterTablePattern = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+(.+)$")
+	// createIndexPattern matches CREATE INDEX statements, capturing the index kind, name, table and columns
+	createIndexPattern = regexp.MustCompile("(?is)^CREATE\\s+(UNIQUE\\s+|FULLTEXT\\s+)?INDEX\\s+`?(\\w+)`?\\s+ON\\s+`?(\\w+)`?\\s*(\\(.+\\))$")
+	// progressPattern matches the completion percentage in gh-ost status and pt-online-schema-change progress lines
+	progressPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)%`)
+)
+
+// Runner runs schema changes of large tables through gh-ost or pt-online-schema-change, which copy the
+// table in the background and swap it in, instead of executing the DDL directly and locking the table
+type Runner struct {
+	cfg    config.MigrationConfig
+	db     config.DatabaseConfig
+	logger *slog.Logger
+}
+
+// NewRunner creates a new online DDL runner
+func NewRunner(cfg config.MigrationConfig, db config.DatabaseConfig, logger *slog.Logger) (*Runner, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid migration configuration: %w", err)
+	}
+	if cfg.OnlineDDLBinary == "" {
+		cfg.OnlineDDLBinary = cfg.OnlineDDLTool
+	}
+	return &Runner{cfg: cfg, db: db, logger: logger}, nil
+}
+
+// Enabled reports whether an online DDL tool is configured
+func (r *Runner) Enabled() bool {
+	return r.cfg.OnlineDDLTool != ""
+}
+
+// MinRows returns the estimated row count from which a table is altered online
+func (r *Runner) MinRows() int64 {
+	return r.cfg.OnlineDDLMinRows
+}
+
+// ParseAlter extracts the table and alter clauses of a statement the tools can run: an ALTER TABLE, or a
+// CREATE INDEX rewritten as ADD INDEX. ok is false for any other statement.
+func ParseAlter(statement string) (table, alter string, ok bool) {
+	statement = strings.TrimSpace(statement)
+	if match := alterTablePattern.FindStringSubmatch(statement); match != nil {
+		return match[1], strings.TrimSpace(match[2]), true
+	}
+	if match := createIndexPattern.FindStringSubmatch(statement); match != nil {
+		kind := strings.ToUpper(strings.TrimSpace(match[1]))
+		if kind != "" {
+			kind += " "
+		}
+		return match[3], fmt.Sprintf("ADD %sINDEX `%s` %s", kind, match[2], match[4]), true
+	}
+	return "", "", false
+}
+
+// Run alters a table with the configured tool, logging its progress until it completes
+func (r *Runner) Run(ctx context.Context, table, alter string) error {
+	// Credentials go in an option file rather than on the command line, where other users could read them
+	credentials, err := r.writeCredentials()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(credentials)
+
+	cmd := exec.CommandContext(ctx, r.cfg.OnlineDDLBinary, r.args(table, alter, credentials)...)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to capture %s output: %w", r.cfg.OnlineDDLTool, err)
+	}
+	// Both tools report progress on stderr
+	cmd.Stderr = cmd.Stdout
+
+	r.logger.Info("Starting online schema change", "tool", r.cfg.OnlineDDLTool, "table", table, "alter", alter)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", r.cfg.OnlineDDLBinary, err)
+	}
+	lastLine := r.follow(table, output)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed on table %s: %w (last output: %s)", r.cfg.OnlineDDLTool, table, err, lastLine)
+	}
+
+	r.logger.Info("Online schema change completed", "tool", r.cfg.OnlineDDLTool, "table", table)
+	return nil
+}
+
+// args builds the command line of the configured tool. Configured options come last so they can
+// override the defaults.
+func (r *Runner) args(table, alter, credentials string) []string {
+	if r.cfg.OnlineDDLTool == constants.OnlineDDLToolPTOSC {
+		args := []string{
+			"--defaults-file=" + credentials,
+			"--alter=" + alter,
+			"--execute",
+		}
+		args = append(args, r.cfg.OnlineDDLOptions...)
+		return append(args, fmt.Sprintf("h=%s,P=%s,D=%s,t=%s", r.db.Host, r.db.Port, r.db.Database, table))
+	}
+
+	args := []string{
+		"--conf=" + credentials,
+		"--host=" + r.db.Host,
+		"--port=" + r.db.Port,
+		"--database=" + r.db.Database,
+		"--table=" + table,
+		"--alter=" + alter,
+		"--execute",
+	}
+	return append(args, r.cfg.OnlineDDLOptions...)
+}
+
+// writeCredentials writes the database user and password to a MySQL option file readable only by the
+// current user, returning its path
+func (r *Runner) writeCredentials() (string, error) {
+	file, err := os.CreateTemp("", "online-ddl-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	_, err = fmt.Fprintf(file, "[client]\nuser=%s\npassword=%s\n", r.db.Username, r.db.Password)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// follow logs the tool's output, reporting progress lines at info level and the rest at debug level,
+// and returns the last line written
+func (r *Runner) follow(table string, output io.Reader) string {
+	var lastLine string
+	lastProgress := -1.0
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lastLine = line
+
+		match := progressPattern.FindStringSubmatch(line)
+		if match == nil {
+			r.logger.Debug("Online schema change output", "table", table, "output", line)
+			continue
+		}
+		progress, _ := strconv.ParseFloat(match[1], 64)
+		// gh-ost repeats its status line while waiting, only log when the copy moves forward
+		if progress > lastProgress {
+			lastProgress = progress
+			r.logger.Info("Online schema change progress", "table", table, "percent", progress, "status", line)
+		}
+	}
+	// Keep draining after an overlong line so the tool never blocks on a full pipe
+	io.Copy(io.Discard, output)
+	return lastLine
+}
@@ -0,0 +1,181 @@
+// Package openapi builds OpenAPI 3 documents of the API from the routes registered on the router, so that the
+// documentation lists every route the server actually serves, with schemas reflected from the Go types handlers
+// respond with.
+package openapi
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Version is the OpenAPI version of the documents built
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Tag groups operations, e.g. by resource
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem holds the operations of a path by lower-case HTTP method
+type PathItem map[string]*Operation
+
+// Operation documents a route
+type Operation struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	OperationID string                 `json:"operationId,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]*Response   `json:"responses"`
+	Deprecated  bool                   `json:"deprecated,omitempty"`
+	Security    *[]SecurityRequirement `json:"security,omitempty"` // an empty list makes the operation public
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path or query
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas and security schemes referenced by operations
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityRequirement names the security schemes a request may authenticate with
+type SecurityRequirement map[string][]string
+
+// Schema describes a JSON value
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Builder builds a document, reflecting the schemas of the Go types it is given into its components
+type Builder struct {
+	doc   *Document
+	types map[reflect.Type]string // component name of each reflected struct type
+}
+
+// NewBuilder creates a builder of a document without operations
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas:         make(map[string]*Schema),
+				SecuritySchemes: make(map[string]*SecurityScheme),
+			},
+		},
+		types: make(map[reflect.Type]string),
+	}
+}
+
+// SecurityScheme adds a security scheme, which requests of operations that aren't public may authenticate with
+func (b *Builder) SecurityScheme(name string, scheme *SecurityScheme) {
+	b.doc.Components.SecuritySchemes[name] = scheme
+	b.doc.Security = append(b.doc.Security, SecurityRequirement{name: {}})
+}
+
+// Add adds an operation of a route given with gin's path syntax, e.g. /api/logs/:id, which is converted to
+// /api/logs/{id}. Path parameters that the operation doesn't describe are added as strings.
+func (b *Builder) Add(method, path string, op *Operation) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			name, ok = strings.CutPrefix(segment, "*")
+		}
+		if !ok {
+			continue
+		}
+		segments[i] = "{" + name + "}"
+		if !slices.ContainsFunc(op.Parameters, func(p Parameter) bool { return p.Name == name && p.In == "path" }) {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	path = strings.Join(segments, "/")
+
+	item := b.doc.Paths[path]
+	if item == nil {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// Document returns the document, with a tag for every tag used by its operations
+func (b *Builder) Document() *Document {
+	tags := make(map[string]bool)
+	for _, item := range b.doc.Paths {
+		for _, op := range item {
+			for _, tag := range op.Tags {
+				tags[tag] = true
+			}
+		}
+	}
+	b.doc.Tags = nil
+	for _, tag := range slices.Sorted(maps.Keys(tags)) {
+		b.doc.Tags = append(b.doc.Tags, Tag{Name: tag})
+	}
+	return b.doc
+}
@@ -0,0 +1,159 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema returns the schema of the value's type as encoding/json encodes it. Named structs are added to the
+// components and referenced, so recursive types are supported; nil returns an empty schema, which allows any value.
+func (b *Builder) Schema(value any) *Schema {
+	if value == nil {
+		return &Schema{}
+	}
+	return b.schemaOf(reflect.TypeOf(value))
+}
+
+// schemaOf returns the schema of a type
+func (b *Builder) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := b.schemaOf(t.Elem())
+		if schema.Ref == "" && schema.Type != "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.0
+		return &Schema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return b.reference(t)
+	default:
+		// Interfaces, such as gin.H values, may hold anything
+		return &Schema{}
+	}
+}
+
+// reference adds a named struct to the components, once, and returns a reference to it
+func (b *Builder) reference(t reflect.Type) *Schema {
+	name, ok := b.types[t]
+	if !ok {
+		name = componentName(t)
+		for taken := b.doc.Components.Schemas[name] != nil; taken; taken = b.doc.Components.Schemas[name] != nil {
+			name += "_"
+		}
+		b.types[t] = name
+		// Registered before the fields are reflected, so that fields of the same type reference it
+		b.doc.Components.Schemas[name] = &Schema{}
+		*b.doc.Components.Schemas[name] = *b.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema returns the object schema of a struct's JSON fields. Embedded structs without a JSON name have
+// their fields promoted, and fields without omitempty are required.
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for field := range fields(t) {
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if schema.Properties[name] != nil {
+			continue // shadowed by a field of the outer struct
+		}
+		property := b.schemaOf(field.Type)
+		if strings.Contains(options, "string") && property.Type != "" {
+			property = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = property
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// fields yields the JSON fields of a struct, those of embedded structs included
+func fields(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+				for promoted := range fields(embedded) {
+					if !yield(promoted) {
+						return
+					}
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// componentName names the component of a struct after its type, e.g. Log, or Envelope_Log for Envelope[models.Log]
+func componentName(t reflect.Type) string {
+	name, params, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	params = strings.TrimSuffix(params, "]")
+	for _, param := range strings.Split(params, ",") {
+		param = strings.TrimLeft(param, "[]*")
+		if i := strings.LastIndex(param, "."); i >= 0 {
+			param = param[i+1:]
+		}
+		name += "_" + param
+	}
+	return name
+}
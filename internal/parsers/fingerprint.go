@@ -0,0 +1,71 @@
+package parsers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// builtinMasks replace the variable parts commonly found in error messages. They apply after the configured
+// masks, in order, so that e.g. the digits of a UUID aren't masked as numbers first.
+var builtinMasks = []config.FingerprintMask{
+	{Name: "uuid", Pattern: `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`, Replacement: "<uuid>"},
+	{Name: "ip", Pattern: `\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`, Replacement: "<ip>"},
+	{Name: "hex", Pattern: `\b(?:0[xX][0-9A-Fa-f]+|[0-9A-Fa-f]{8,})\b`, Replacement: "<hex>"},
+	{Name: "quoted", Pattern: `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`, Replacement: "<str>"},
+	{Name: "number", Pattern: `[0-9]+(?:\.[0-9]+)?`, Replacement: "<num>"},
+}
+
+// Fingerprinter groups errors by a hash of their service and message, with the variable parts of the message
+// masked, so that occurrences of the same error share a fingerprint
+type Fingerprinter struct {
+	masks   []fingerprintMask
+	version string
+}
+
+// fingerprintMask is a compiled mask
+type fingerprintMask struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewFingerprinter compiles the configured masks followed by the built-in ones
+func NewFingerprinter(masks []config.FingerprintMask) (*Fingerprinter, error) {
+	f := &Fingerprinter{}
+	version := sha256.New()
+	for _, mask := range slices.Concat(masks, builtinMasks) {
+		pattern, err := regexp.Compile(mask.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("mask %s: invalid pattern: %w", mask.Name, err)
+		}
+		f.masks = append(f.masks, fingerprintMask{pattern: pattern, replacement: mask.Replacement})
+		fmt.Fprintf(version, "%s\x00%s\x00", mask.Pattern, mask.Replacement)
+	}
+	f.version = hex.EncodeToString(version.Sum(nil))[:constants.FingerprintLength]
+	return f, nil
+}
+
+// Version identifies the masks, changing whenever they do, so fingerprints computed under other rules can be told apart
+func (f *Fingerprinter) Version() string {
+	return f.version
+}
+
+// Fingerprint returns the fingerprint of an ERROR or FATAL log, nil for logs of other levels
+func (f *Fingerprinter) Fingerprint(log *models.Log) *string {
+	if log.Level != models.LogLevelError && log.Level != models.LogLevelFatal {
+		return nil
+	}
+	message := strings.TrimSpace(log.Message)
+	for _, mask := range f.masks {
+		message = mask.pattern.ReplaceAllLiteralString(message, mask.replacement)
+	}
+	sum := sha256.Sum256([]byte(log.Service + "\x00" + message))
+	fingerprint := hex.EncodeToString(sum[:])[:constants.FingerprintLength]
+	return &fingerprint
+}
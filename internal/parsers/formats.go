@@ -0,0 +1,163 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// extractJSON flattens a JSON object into fields, joining nested keys with dots
+// so nested values can be mapped as e.g. request.path
+func extractJSON(data []byte) (map[string]string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil || object == nil {
+		return nil, false
+	}
+	fields := make(map[string]string)
+	flattenJSON(fields, "", object)
+	return fields, true
+}
+
+// flattenJSON stores the scalar values of an object under dotted keys; arrays are kept as JSON
+func flattenJSON(fields map[string]string, prefix string, object map[string]any) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case nil:
+		case map[string]any:
+			flattenJSON(fields, key, v)
+		case string:
+			fields[key] = v
+		case json.Number:
+			fields[key] = v.String()
+		case bool:
+			fields[key] = fmt.Sprint(v)
+		default:
+			if encoded, err := json.Marshal(v); err == nil {
+				fields[key] = string(encoded)
+			}
+		}
+	}
+}
+
+// extractLogfmt parses key=value pairs separated by spaces. Values may be double-quoted with
+// backslash escapes, and a bare key means true. Lines without any key=value pair aren't logfmt.
+func extractLogfmt(data []byte) (map[string]string, bool) {
+	line := strings.TrimRight(string(data), "\r\n")
+	fields := make(map[string]string)
+	pairs := 0
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, false
+		}
+		if i >= len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, false
+			}
+			fields[key] = "true"
+			continue
+		}
+		i++ // '='
+		pairs++
+
+		if i < len(line) && line[i] == '"' {
+			var value strings.Builder
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					default:
+						value.WriteByte(line[i])
+					}
+					continue
+				}
+				if line[i] == '"' {
+					i++
+					closed = true
+					break
+				}
+				value.WriteByte(line[i])
+			}
+			if !closed {
+				return nil, false
+			}
+			fields[key] = value.String()
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		fields[key] = line[start:i]
+	}
+	return fields, pairs > 0
+}
+
+// regexExtractor extracts the named groups of a regular expression
+type regexExtractor struct {
+	re    *regexp.Regexp
+	names []string // field name per group; empty for unnamed groups
+}
+
+// compileRegex compiles a pattern whose named groups become fields
+func compileRegex(pattern string) (*regexExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	names := re.SubexpNames()
+	if !hasNamedGroup(names) {
+		return nil, fmt.Errorf("pattern has no named groups")
+	}
+	return &regexExtractor{re: re, names: names}, nil
+}
+
+// extract matches the message against the expression; groups that didn't participate are omitted
+func (r *regexExtractor) extract(data []byte) (map[string]string, bool) {
+	line := strings.TrimRight(string(data), "\r\n")
+	match := r.re.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, false
+	}
+	fields := make(map[string]string)
+	for group, name := range r.names {
+		if name == "" || match[2*group] < 0 {
+			continue
+		}
+		fields[name] = line[match[2*group]:match[2*group+1]]
+	}
+	return fields, true
+}
+
+// hasNamedGroup reports whether any group of an expression is named
+func hasNamedGroup(names []string) bool {
+	for _, name := range names {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package parsers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// grokReference matches %{PATTERN}, %{PATTERN:field} and %{PATTERN:field:type}; the type is
+// accepted for compatibility but ignored since all fields are converted per log column
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w.@\-\[\]]+))?(?::\w+)?\}`)
+
+// grokPatterns is the built-in pattern library, a subset of the common grok patterns rewritten for RE2
+var grokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"EMAILADDRESS":      `[a-zA-Z0-9!#$%&'*+/=?^_{|}~.-]+@%{HOSTNAME}`,
+	"INT":               `[+-]?[0-9]+`,
+	"POSINT":            `\b[1-9][0-9]*\b`,
+	"NONNEGINT":         `\b[0-9]+\b`,
+	"NUMBER":            `[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s?#]*)+`,
+	"URIPROTO":          `[A-Za-z][A-Za-z0-9+.-]*`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":               `%{URIPROTO}://\S+`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert|panic)`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHDAY":          `0[1-9]|[12][0-9]|3[01]|[1-9]`,
+	"DAY":               `Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?`,
+	"YEAR":              `[0-9]{4}`,
+	"HOUR":              `2[0-3]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-][0-9]{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+}
+
+// compileGrok expands a grok pattern into a regular expression. Named references become capture
+// groups; their field names may contain characters RE2 disallows in group names, so groups are
+// numbered and mapped back to the field names.
+func compileGrok(pattern string, custom map[string]string) (*regexExtractor, error) {
+	names := []string{""} // group 0 is the whole match
+	expanded, err := expandGrok(pattern, custom, &names, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grok pattern: %w", err)
+	}
+
+	// Groups written as plain regex in the pattern are numbered too; map fields by group name
+	fields := make([]string, len(re.SubexpNames()))
+	for group, name := range re.SubexpNames() {
+		if index, ok := strings.CutPrefix(name, "grok"); ok {
+			if i, err := strconv.Atoi(index); err == nil && i < len(names) {
+				fields[group] = names[i]
+			}
+		} else {
+			fields[group] = name
+		}
+	}
+	if !hasNamedGroup(fields) {
+		return nil, fmt.Errorf("grok pattern has no named fields")
+	}
+	return &regexExtractor{re: re, names: fields}, nil
+}
+
+// expandGrok replaces pattern references recursively, preferring custom patterns over built-in ones
+func expandGrok(pattern string, custom map[string]string, names *[]string, depth int) (string, error) {
+	if depth > constants.MaxGrokPatternDepth {
+		return "", fmt.Errorf("grok patterns nest deeper than %d levels, check for cyclic references", constants.MaxGrokPatternDepth)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range grokReference.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(pattern[last:match[0]])
+		last = match[1]
+
+		name := pattern[match[2]:match[3]]
+		definition, ok := custom[name]
+		if !ok {
+			definition, ok = grokPatterns[name]
+		}
+		if !ok {
+			return "", fmt.Errorf("unknown grok pattern %q", name)
+		}
+		expanded, err := expandGrok(definition, custom, names, depth+1)
+		if err != nil {
+			return "", err
+		}
+
+		if match[4] >= 0 {
+			fmt.Fprintf(&b, "(?P<grok%d>%s)", len(*names), expanded)
+			*names = append(*names, pattern[match[4]:match[5]])
+		} else {
+			fmt.Fprintf(&b, "(?:%s)", expanded)
+		}
+	}
+	b.WriteString(pattern[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,53 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// FieldMapper maps fields extracted outside the pipeline, such as the columns of imported CSV files and database
+// tables, to logs with the column mapping, defaults and timestamp formats of parsers. Historical records keep their
+// own time, so records without a timestamp are rejected.
+type FieldMapper struct {
+	parser *parser
+}
+
+// NewFieldMapper creates a field mapper from a parser configuration, whose type and patterns are not used
+func NewFieldMapper(cfg config.ParserConfig) (*FieldMapper, error) {
+	if err := checkColumns(cfg); err != nil {
+		return nil, err
+	}
+	return &FieldMapper{parser: &parser{
+		name:            cfg.Name,
+		fields:          cfg.Fields,
+		defaults:        cfg.Defaults,
+		timestampFormat: cfg.TimestampFormat,
+		requireTime:     true,
+	}}, nil
+}
+
+// Map builds a log from fields; raw is the record they were read from, used as the message when none is mapped.
+// Fields not mapped to a column are kept as attributes.
+func (m *FieldMapper) Map(fields map[string]string, raw []byte) (*models.Log, error) {
+	return m.parser.build(fields, raw)
+}
+
+// MapJSON builds a log from a JSON object. Objects in the native log shape are used as they are; others are
+// flattened, joining nested keys with dots, and mapped.
+func (m *FieldMapper) MapJSON(data []byte) (*models.Log, error) {
+	var native models.Log
+	if err := json.Unmarshal(data, &native); err == nil && native.Validate() == nil {
+		if native.Timestamp.IsZero() {
+			return nil, fmt.Errorf("timestamp is required")
+		}
+		return &native, nil
+	}
+
+	fields, ok := extractJSON(data)
+	if !ok {
+		return nil, fmt.Errorf("record is not a JSON object")
+	}
+	return m.Map(fields, data)
+}
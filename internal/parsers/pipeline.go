@@ -0,0 +1,310 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log columns that extracted fields can be mapped to
+const (
+	columnTimestamp      = "timestamp"
+	columnLevel          = "level"
+	columnService        = "service"
+	columnMessage        = "message"
+	columnTraceID        = "trace_id"
+	columnSpanID         = "span_id"
+	columnParentSpanID   = "parent_span_id"
+	columnUserID         = "user_id"
+	columnHost           = "host"
+	columnEnvironment    = "environment"
+	columnRegion         = "region"
+	columnClientIP       = "client_ip"
+	columnRequestMethod  = "request_method"
+	columnRequestPath    = "request_path"
+	columnResponseStatus = "response_status"
+	columnResponseTimeMs = "response_time_ms"
+)
+
+var logColumns = []string{
+	columnTimestamp, columnLevel, columnService, columnMessage, columnTraceID, columnSpanID, columnParentSpanID,
+	columnUserID, columnHost, columnEnvironment, columnRegion, columnClientIP,
+	columnRequestMethod, columnRequestPath, columnResponseStatus, columnResponseTimeMs,
+}
+
+// Pipeline decodes Kafka message values into logs. Messages in the native log JSON shape are used
+// as-is; anything else is tried against the configured parsers in order and the first match wins.
+type Pipeline struct {
+	parsers []*parser
+}
+
+// parser extracts fields in one format and maps them to log columns
+type parser struct {
+	name            string
+	extract         extractor
+	headers         map[string][]string
+	fields          map[string]string // log column -> extracted field
+	defaults        map[string]string // log column -> fallback value
+	timestampFormat string
+	requireTime     bool // logs without a timestamp are rejected instead of stamped with the current time
+}
+
+// extractor returns the fields of a message, or false when the message isn't in its format
+type extractor func(data []byte) (map[string]string, bool)
+
+// NewPipeline builds the parsers, compiling their patterns
+func NewPipeline(configs []config.ParserConfig) (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	for _, cfg := range configs {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		if err := checkColumns(cfg); err != nil {
+			return nil, err
+		}
+
+		var extract extractor
+		switch cfg.Type {
+		case constants.ParserTypeJSON:
+			extract = extractJSON
+		case constants.ParserTypeLogfmt:
+			extract = extractLogfmt
+		case constants.ParserTypeRegex:
+			re, err := compileRegex(cfg.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("parser %s: %w", cfg.Name, err)
+			}
+			extract = re.extract
+		case constants.ParserTypeGrok:
+			re, err := compileGrok(cfg.Pattern, cfg.Patterns)
+			if err != nil {
+				return nil, fmt.Errorf("parser %s: %w", cfg.Name, err)
+			}
+			extract = re.extract
+		}
+
+		pipeline.parsers = append(pipeline.parsers, &parser{
+			name:            cfg.Name,
+			extract:         extract,
+			headers:         cfg.Headers,
+			fields:          cfg.Fields,
+			defaults:        cfg.Defaults,
+			timestampFormat: cfg.TimestampFormat,
+		})
+	}
+	return pipeline, nil
+}
+
+// checkColumns checks that the fields and defaults of a parser map to log columns
+func checkColumns(cfg config.ParserConfig) error {
+	for column := range cfg.Fields {
+		if !slices.Contains(logColumns, column) {
+			return fmt.Errorf("parser %s: unknown log column %q in fields", cfg.Name, column)
+		}
+	}
+	for column := range cfg.Defaults {
+		if !slices.Contains(logColumns, column) {
+			return fmt.Errorf("parser %s: unknown log column %q in defaults", cfg.Name, column)
+		}
+	}
+	return nil
+}
+
+// Parse decodes a message. Native logs that fail validation are still tried against the parsers,
+// and kept as they are when no parser recognizes them, as they were before parsers existed.
+func (p *Pipeline) Parse(data []byte, headers map[string]string) (*models.Log, error) {
+	var native models.Log
+	nativeErr := json.Unmarshal(data, &native)
+	if nativeErr == nil && (len(p.parsers) == 0 || native.Validate() == nil) {
+		return &native, nil
+	}
+	if len(p.parsers) == 0 {
+		return nil, nativeErr
+	}
+
+	var parseErr error
+	for _, parser := range p.parsers {
+		if !parser.matches(headers) {
+			continue
+		}
+		fields, ok := parser.extract(data)
+		if !ok {
+			continue
+		}
+		log, err := parser.build(fields, data)
+		if err != nil {
+			// Remember the failure but give later parsers a chance
+			if parseErr == nil {
+				parseErr = fmt.Errorf("parser %s: %w", parser.name, err)
+			}
+			continue
+		}
+		return log, nil
+	}
+
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if nativeErr == nil {
+		return &native, nil
+	}
+	return nil, fmt.Errorf("message is not a log and matched no parser: %w", nativeErr)
+}
+
+// matches reports whether the message headers satisfy the parser's header conditions
+func (p *parser) matches(headers map[string]string) bool {
+	for key, values := range p.headers {
+		value, ok := headers[key]
+		if !ok || !slices.Contains(values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// build maps extracted fields to a log. Fields not mapped to a column are kept as attributes.
+func (p *parser) build(fields map[string]string, raw []byte) (*models.Log, error) {
+	mapped := make(map[string]bool)
+	value := func(column string) string {
+		field := column
+		if name, ok := p.fields[column]; ok {
+			field = name
+		}
+		if v := fields[field]; v != "" {
+			mapped[field] = true
+			return v
+		}
+		return p.defaults[column]
+	}
+
+	log := &models.Log{
+		Service: value(columnService),
+		Message: value(columnMessage),
+	}
+	if log.Message == "" {
+		log.Message = strings.TrimRight(string(raw), "\r\n")
+	}
+
+	log.Timestamp = time.Now()
+	if timestamp := value(columnTimestamp); timestamp != "" {
+		t, err := parseTimestamp(timestamp, p.timestampFormat)
+		if err != nil {
+			return nil, err
+		}
+		log.Timestamp = t
+	} else if p.requireTime {
+		return nil, fmt.Errorf("timestamp is required")
+	}
+
+	log.Level = models.LogLevelInfo
+	if level := value(columnLevel); level != "" {
+		normalized, ok := normalizeLevel(level)
+		if !ok {
+			return nil, fmt.Errorf("unknown level %q", level)
+		}
+		log.Level = normalized
+	}
+
+	for column, target := range map[string]**string{
+		columnTraceID:       &log.TraceID,
+		columnSpanID:        &log.SpanID,
+		columnParentSpanID:  &log.ParentSpanID,
+		columnUserID:        &log.UserID,
+		columnHost:          &log.Host,
+		columnEnvironment:   &log.Environment,
+		columnRegion:        &log.Region,
+		columnClientIP:      &log.ClientIP,
+		columnRequestMethod: &log.RequestMethod,
+		columnRequestPath:   &log.RequestPath,
+	} {
+		if v := value(column); v != "" {
+			*target = &v
+		}
+	}
+	if log.RequestMethod != nil {
+		method := strings.ToUpper(*log.RequestMethod)
+		log.RequestMethod = &method
+	}
+
+	if status := value(columnResponseStatus); status != "" {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response status %q", status)
+		}
+		log.ResponseStatus = &code
+	}
+	if responseTime := value(columnResponseTimeMs); responseTime != "" {
+		ms, err := strconv.ParseFloat(responseTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response time %q", responseTime)
+		}
+		rounded := int(math.Round(ms))
+		log.ResponseTimeMs = &rounded
+	}
+
+	for field, v := range fields {
+		if !mapped[field] {
+			log.Attributes.Set(field, v)
+		}
+	}
+	log.Attributes.Set(constants.ParserAttributeName, p.name)
+
+	if err := log.Validate(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// parseTimestamp parses a timestamp with a Go layout, as unix seconds or milliseconds, or as RFC 3339 by default
+func parseTimestamp(value, format string) (time.Time, error) {
+	switch format {
+	case "":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339", value)
+		}
+		return t, nil
+	case constants.ParserTimestampUnix:
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp %q", value)
+		}
+		return time.UnixMilli(int64(math.Round(seconds * 1000))), nil
+	case constants.ParserTimestampUnixMs:
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix millisecond timestamp %q", value)
+		}
+		return time.UnixMilli(ms), nil
+	default:
+		t, err := time.ParseInLocation(format, value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: expected layout %q", value, format)
+		}
+		return t, nil
+	}
+}
+
+// normalizeLevel maps common level spellings, including syslog severities, to a log level
+func normalizeLevel(level string) (models.LogLevel, bool) {
+	switch strings.ToUpper(strings.TrimSpace(level)) {
+	case "TRACE", "DEBUG", "DBG":
+		return models.LogLevelDebug, true
+	case "INFO", "INFORMATION", "INFORMATIONAL", "NOTICE":
+		return models.LogLevelInfo, true
+	case "WARN", "WARNING":
+		return models.LogLevelWarn, true
+	case "ERROR", "ERR":
+		return models.LogLevelError, true
+	case "FATAL", "CRITICAL", "CRIT", "ALERT", "EMERG", "EMERGENCY", "PANIC":
+		return models.LogLevelFatal, true
+	}
+	return "", false
+}
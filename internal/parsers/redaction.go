@@ -0,0 +1,205 @@
+package parsers
+
+import (
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// builtinRedactions are the rules that can be enabled by name. They apply to the message and request path, after the
+// configured rules.
+var builtinRedactions = map[string]config.RedactionRule{
+	constants.RedactionBuiltinEmail: {
+		Pattern:     `[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`,
+		Replacement: "[EMAIL]",
+	},
+	constants.RedactionBuiltinCreditCard: {
+		Pattern:     `\b[0-9](?:[ -]?[0-9]){12,18}\b`,
+		Replacement: "[CARD]",
+	},
+	constants.RedactionBuiltinBearerToken: {
+		Pattern:     `(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]+=*`,
+		Replacement: "${1}" + constants.RedactedValue,
+	},
+}
+
+// builtinChecks confirm the matches of built-in rules, for patterns that would match too much on their own
+var builtinChecks = map[string]func(string) bool{
+	// Long numbers such as Unix timestamps in milliseconds are only card numbers if their check digit is valid
+	constants.RedactionBuiltinCreditCard: luhnValid,
+}
+
+// Redactor masks sensitive data, such as email addresses, card numbers and tokens, in logs before they are stored
+type Redactor struct {
+	rules []redactionRule
+}
+
+// redactionRule is a compiled rule; rules without pattern mask the whole value of their fields
+type redactionRule struct {
+	name        string
+	pattern     *regexp.Regexp
+	check       func(string) bool
+	replacement string
+	fields      []string
+}
+
+// NewRedactor compiles the configured rules followed by the selected built-in ones, * selecting them all
+func NewRedactor(builtins []string, rules []config.RedactionRule) (*Redactor, error) {
+	if slices.Contains(builtins, constants.RedactionAllBuiltins) {
+		builtins = constants.RedactionBuiltins
+	}
+	for _, name := range builtins {
+		rule, ok := builtinRedactions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown built-in redaction rule %q", name)
+		}
+		rule.Name = name
+		rules = append(slices.Clip(rules), rule)
+	}
+
+	r := &Redactor{}
+	for _, rule := range rules {
+		compiled := redactionRule{
+			name:        rule.Name,
+			check:       builtinChecks[rule.Name],
+			replacement: rule.Replacement,
+			fields:      rule.Fields,
+		}
+		if compiled.replacement == "" {
+			compiled.replacement = constants.RedactedValue
+		}
+		if len(compiled.fields) == 0 {
+			compiled.fields = constants.DefaultRedactionFields
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule %s: invalid pattern: %w", rule.Name, err)
+			}
+			compiled.pattern = pattern
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// Enabled reports whether any rule applies
+func (r *Redactor) Enabled() bool {
+	return len(r.rules) > 0
+}
+
+// Rules returns the number of rules applied
+func (r *Redactor) Rules() int {
+	return len(r.rules)
+}
+
+// Redact masks the sensitive data of a log in place, counting the values masked by each rule
+func (r *Redactor) Redact(log *models.Log) {
+	redacted := false
+	for i := range r.rules {
+		rule := &r.rules[i]
+		count := 0
+		for _, field := range rule.fields {
+			count += rule.apply(log, field)
+		}
+		if count > 0 {
+			metrics.Redactions.WithLabelValues(rule.name).Add(float64(count))
+			redacted = true
+		}
+	}
+	if redacted {
+		metrics.LogsRedacted.Inc()
+	}
+}
+
+// apply masks a field of a log, returning the number of values masked
+func (r *redactionRule) apply(log *models.Log, field string) int {
+	switch field {
+	case "message":
+		return r.redact(&log.Message)
+	case "request_path":
+		return r.redact(log.RequestPath)
+	case "user_id":
+		return r.redact(log.UserID)
+	case "client_ip":
+		return r.redact(log.ClientIP)
+	case "host":
+		return r.redact(log.Host)
+	case "request_body":
+		return r.redact(log.RequestBody)
+	case "response_body":
+		return r.redact(log.ResponseBody)
+	}
+
+	count := 0
+	for key, value := range log.Attributes {
+		if field != constants.RedactionFieldAttributes && field != constants.RedactionAttributePrefix+key {
+			continue
+		}
+		n := r.redact(&value)
+		if n > 0 {
+			log.Attributes[key] = value
+			count += n
+		}
+	}
+	return count
+}
+
+// redact masks a value, returning the number of matches masked, or 1 when a rule without pattern masks a value
+func (r *redactionRule) redact(value *string) int {
+	if value == nil || *value == "" {
+		return 0
+	}
+	if r.pattern == nil {
+		if *value == r.replacement {
+			return 0
+		}
+		*value = r.replacement
+		return 1
+	}
+
+	matches := r.pattern.FindAllStringSubmatchIndex(*value, -1)
+	var b strings.Builder
+	var expanded []byte
+	last, count := 0, 0
+	for _, match := range matches {
+		if r.check != nil && !r.check((*value)[match[0]:match[1]]) {
+			continue
+		}
+		b.WriteString((*value)[last:match[0]])
+		expanded = r.pattern.ExpandString(expanded[:0], r.replacement, *value, match)
+		b.Write(expanded)
+		last = match[1]
+		count++
+	}
+	if count > 0 {
+		b.WriteString((*value)[last:])
+		*value = b.String()
+	}
+	return count
+}
+
+// luhnValid reports whether the digits of a number, ignoring separators, have a valid Luhn check digit
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
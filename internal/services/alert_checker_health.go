@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checkerHealth tracks the outcome of alert checks and of each rule's evaluations
+type checkerHealth struct {
+	mu                  sync.Mutex
+	disabled            bool
+	running             bool // whether this server runs the checker; another one may be running it
+	startedAt           time.Time
+	lastCheckAt         *time.Time
+	lastSuccessAt       *time.Time
+	rulesEvaluated      int
+	rulesFailed         int
+	consecutiveFailures int
+	lastError           string
+	metaAlert           *models.AlertCheckerMetaAlert
+	rules               map[uint]*models.AlertRuleFailure
+}
+
+// start records when the checker started, from which time without a successful check is measured. The outcome
+// of checks from an earlier run on this server is forgotten, as other servers may have run the checker since.
+func (h *checkerHealth) start(at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running = true
+	h.startedAt = at
+	h.lastCheckAt, h.lastSuccessAt = nil, nil
+	h.rulesEvaluated, h.rulesFailed, h.consecutiveFailures = 0, 0, 0
+	h.lastError = ""
+	h.metaAlert = nil
+	h.rules = nil
+}
+
+// stop records that the checker stopped running on this server, reporting whether its meta-alert was firing
+func (h *checkerHealth) stop() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running = false
+	firing := h.metaAlert != nil
+	h.metaAlert = nil
+	return firing
+}
+
+// disable records that the checker doesn't run
+func (h *checkerHealth) disable() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.disabled = true
+}
+
+// ruleFailed records a failed evaluation of a rule
+func (h *checkerHealth) ruleFailed(rule *models.AlertRule, err error, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rules == nil {
+		h.rules = make(map[uint]*models.AlertRuleFailure)
+	}
+	failure, ok := h.rules[rule.ID]
+	if !ok {
+		failure = &models.AlertRuleFailure{RuleID: rule.ID}
+		h.rules[rule.ID] = failure
+	}
+	failure.RuleName = rule.Name
+	failure.ConsecutiveFailures++
+	failure.TotalFailures++
+	failure.LastError = err.Error()
+	failure.LastFailureAt = at
+	metrics.AlertRuleFailures.WithLabelValues(strconv.FormatUint(uint64(rule.ID), 10)).Inc()
+}
+
+// ruleSucceeded records a successful evaluation of a rule
+func (h *checkerHealth) ruleSucceeded(ruleID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if failure, ok := h.rules[ruleID]; ok {
+		failure.ConsecutiveFailures = 0
+	}
+}
+
+// finishCheck records the outcome of a check. A check succeeds when the rules could be loaded and every
+// enabled rule was evaluated. Failures of rules that no longer exist are forgotten.
+func (h *checkerHealth) finishCheck(at time.Time, ruleIDs map[uint]bool, evaluated, failed int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCheckAt = &at
+	h.rulesEvaluated = evaluated
+	h.rulesFailed = failed
+	metrics.AlertCheckerRulesEvaluated.Set(float64(evaluated))
+	if err != nil {
+		h.consecutiveFailures++
+		h.lastError = err.Error()
+		return
+	}
+
+	h.lastSuccessAt = &at
+	h.consecutiveFailures = 0
+	h.lastError = ""
+	for id := range h.rules {
+		if !ruleIDs[id] {
+			delete(h.rules, id)
+		}
+	}
+	metrics.AlertCheckerLastSuccess.Set(float64(at.Unix()))
+}
+
+// updateMetaAlert fires the meta-alert when no check has succeeded for the threshold, measured from the last
+// success or from the start of the checker, and resolves it once a check succeeds again. It returns the alert
+// when it fires and whether it was resolved.
+func (h *checkerHealth) updateMetaAlert(now time.Time, threshold time.Duration) (fired *models.AlertCheckerMetaAlert, resolved bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.metaAlert != nil {
+		if h.lastSuccessAt != nil && h.lastSuccessAt.After(h.metaAlert.FiredAt) {
+			h.metaAlert = nil
+			return nil, true
+		}
+		return nil, false
+	}
+
+	since := h.startedAt
+	if h.lastSuccessAt != nil {
+		since = *h.lastSuccessAt
+	}
+	if since.IsZero() || now.Sub(since) < threshold {
+		return nil, false
+	}
+
+	message := fmt.Sprintf("Alert rules have not been evaluated successfully since %s (%d failed checks)",
+		since.Format(time.RFC3339), h.consecutiveFailures)
+	if h.lastCheckAt == nil || !h.lastCheckAt.After(since) {
+		// Checks that hang never report back, so a missing check is as telling as a failed one
+		message = fmt.Sprintf("Alert rules have not been evaluated successfully since %s (no check completed)", since.Format(time.RFC3339))
+	}
+	if h.lastError != "" {
+		message += ": " + h.lastError
+	}
+	h.metaAlert = &models.AlertCheckerMetaAlert{Message: message, FailedChecks: h.consecutiveFailures, FiredAt: now}
+	return h.metaAlert, false
+}
+
+// snapshot returns the current health
+func (h *checkerHealth) snapshot() *models.AlertCheckerHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := &models.AlertCheckerHealth{
+		LastCheckAt:         h.lastCheckAt,
+		LastSuccessAt:       h.lastSuccessAt,
+		RulesEvaluated:      h.rulesEvaluated,
+		RulesFailed:         h.rulesFailed,
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastError:           h.lastError,
+		RuleFailures:        []models.AlertRuleFailure{},
+	}
+	if h.metaAlert != nil {
+		metaAlert := *h.metaAlert
+		health.MetaAlert = &metaAlert
+	}
+	for _, id := range slices.Sorted(maps.Keys(h.rules)) {
+		health.RuleFailures = append(health.RuleFailures, *h.rules[id])
+	}
+
+	switch {
+	case h.disabled:
+		health.Status = constants.AlertCheckerDisabled
+	case !h.running:
+		health.Status = constants.AlertCheckerStandby
+	case h.metaAlert != nil:
+		health.Status = constants.AlertCheckerFailing
+	case h.lastCheckAt == nil:
+		health.Status = constants.AlertCheckerStarting
+	case h.consecutiveFailures > 0:
+		health.Status = constants.AlertCheckerDegraded
+	default:
+		health.Status = constants.AlertCheckerHealthy
+	}
+	return health
+}
+
+// CheckerHealth reports how the alert checker is doing
+func (s *AlertService) CheckerHealth() *models.AlertCheckerHealth {
+	return s.health.snapshot()
+}
+
+// checkInterval is the interval of the alert checker, which may change while it runs
+type checkInterval struct {
+	mu      sync.Mutex
+	value   time.Duration
+	changed chan struct{} // closed and replaced when the interval changes
+}
+
+// get returns the interval and a channel closed when it changes
+func (i *checkInterval) get() (time.Duration, <-chan struct{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.changed == nil {
+		i.changed = make(chan struct{})
+	}
+	return i.value, i.changed
+}
+
+// init sets the interval unless it was set before, e.g. by a reload before the checker started on this server
+func (i *checkInterval) init(value time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.value == 0 {
+		i.value = value
+	}
+}
+
+// set changes the interval, waking up the loops waiting on the previous one
+func (i *checkInterval) set(value time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if value == i.value {
+		return
+	}
+	i.value = value
+	if i.changed != nil {
+		close(i.changed)
+	}
+	i.changed = make(chan struct{})
+}
+
+// watchChecker fires the meta-alert when rules go unevaluated for the given number of check intervals. It runs
+// independently of the check loop so that checks that hang are noticed as well as checks that fail.
+func (s *AlertService) watchChecker(ctx context.Context, intervals int) {
+	interval, changed := s.interval.get()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	threshold := time.Duration(intervals) * interval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			interval, changed = s.interval.get()
+			ticker.Reset(interval)
+			threshold = time.Duration(intervals) * interval
+		case now := <-ticker.C:
+			fired, resolved := s.health.updateMetaAlert(now, threshold)
+			if fired != nil {
+				s.logger.Error("Alert checker meta-alert fired", "message", fired.Message)
+				metrics.AlertCheckerMetaAlert.Set(1)
+				if s.notifications != nil {
+					s.notifications.NotifyAll(ctx, &models.AlertNotification{
+						RuleName:  constants.AlertMetaAlertRuleName,
+						Severity:  constants.AlertMetaAlertSeverity,
+						Message:   fired.Message,
+						Value:     float64(fired.FailedChecks),
+						Threshold: float64(intervals),
+						CreatedAt: fired.FiredAt,
+						Meta:      true,
+					})
+				}
+			}
+			if resolved {
+				s.logger.Info("Alert checker meta-alert resolved")
+				metrics.AlertCheckerMetaAlert.Set(0)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"sync"
+	"time"
+)
+
+// AlertEventBus fans the alert events of this API server out to in-process subscribers
+type AlertEventBus struct {
+	mu          sync.Mutex
+	subscribers map[*AlertEventSubscription]struct{}
+}
+
+// AlertEventSubscription receives alert events. A subscriber that falls behind is dropped, its channel closed,
+// since a client missing events would show stale alerts; it can subscribe again and reload the active alerts.
+type AlertEventSubscription struct {
+	events chan models.AlertEvent
+}
+
+// NewAlertEventBus creates a new alert event bus
+func NewAlertEventBus() *AlertEventBus {
+	return &AlertEventBus{subscribers: make(map[*AlertEventSubscription]struct{})}
+}
+
+// Subscribe registers a subscription for alert events
+func (b *AlertEventBus) Subscribe() (*AlertEventSubscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subscribers) >= constants.MaxAlertEventSubscribers {
+		return nil, apperrors.Unavailable("Too many alert event clients, try again later")
+	}
+	sub := &AlertEventSubscription{events: make(chan models.AlertEvent, constants.AlertEventSubscriberBuffer)}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe removes a subscription
+func (b *AlertEventBus) Unsubscribe(sub *AlertEventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Publish delivers an event about an alert whose rule has the given name to every subscriber
+func (b *AlertEventBus) Publish(eventType string, alert *models.Alert, ruleName string) {
+	summary := models.NewAlertSummary(alert, ruleName)
+	event := models.AlertEvent{Type: eventType, Alert: &summary, At: time.Now()}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Events returns the channel events are delivered on, closed when the subscriber is dropped
+func (s *AlertEventSubscription) Events() <-chan models.AlertEvent {
+	return s.events
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"log/slog"
+	"time"
+)
+
+// AlertRetentionService periodically deletes resolved alerts past the retention age.
+// Their counts are kept in the alert stats rollups, and the alerts table is optimized after large deletes.
+type AlertRetentionService struct {
+	alertRepo   alerts.AlertRepository
+	maintenance *MaintenanceService
+	cfg         config.AlertConfig
+	logger      *slog.Logger
+}
+
+// NewAlertRetentionService creates a new alert retention service
+func NewAlertRetentionService(alertRepo alerts.AlertRepository, maintenance *MaintenanceService, cfg config.AlertConfig, logger *slog.Logger) (*AlertRetentionService, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &AlertRetentionService{
+		alertRepo:   alertRepo,
+		maintenance: maintenance,
+		cfg:         cfg,
+		logger:      logger,
+	}, nil
+}
+
+// Enabled reports whether resolved alerts are pruned
+func (s *AlertRetentionService) Enabled() bool {
+	return s.cfg.RetentionAge > 0
+}
+
+// Prune deletes resolved alerts older than the retention age in batches. Nothing is deleted while read-only mode is active.
+func (s *AlertRetentionService) Prune(ctx context.Context) error {
+	if s.maintenance.IsReadOnly() {
+		s.logger.Debug("Skipping alert pruning in read-only mode")
+		return nil
+	}
+
+	before := time.Now().Add(-s.cfg.RetentionAge)
+	var total int64
+	for {
+		deleted, err := s.alertRepo.PruneResolvedAlerts(ctx, before, s.cfg.RetentionBatchSize)
+		if err != nil {
+			return err
+		}
+		total += deleted
+		if deleted < int64(s.cfg.RetentionBatchSize) || ctx.Err() != nil {
+			break
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	s.logger.Info("Pruned resolved alerts", "count", total, "resolved_before", before)
+
+	if s.cfg.RetentionOptimizeThreshold > 0 && total >= int64(s.cfg.RetentionOptimizeThreshold) {
+		if err := s.alertRepo.OptimizeAlertsTable(ctx); err != nil {
+			return err
+		}
+		s.logger.Info("Optimized alerts table", "pruned", total)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// Stateful evaluators, e.g. of "for" durations or flap suppression, keep their state per rule in the database
+// rather than in memory, so it survives restarts and is shared by the replicas evaluating the rule. Each evaluator
+// stores its state under its own kind as a JSON document of a type of its choosing.
+
+// loadRuleState returns a rule's evaluator state of the given kind, or the zero state if it was never saved
+func loadRuleState[T any](ctx context.Context, s *AlertService, ruleID uint, kind string) (T, error) {
+	var value T
+	state, err := s.alertRuleRepo.GetRuleState(ctx, ruleID, kind)
+	if err != nil {
+		return value, err
+	}
+	err = decodeRuleState(state, &value)
+	return value, err
+}
+
+// updateRuleState applies update to a rule's evaluator state of the given kind and saves the result, which it
+// returns. When another replica saved the state first, update is applied again to the state that replica saved,
+// so update must not have side effects beyond the state.
+func updateRuleState[T any](ctx context.Context, s *AlertService, ruleID uint, kind string, update func(state *T) error) (T, error) {
+	for attempt := 1; ; attempt++ {
+		var value T
+		state, err := s.alertRuleRepo.GetRuleState(ctx, ruleID, kind)
+		if err != nil {
+			return value, err
+		}
+		if err := decodeRuleState(state, &value); err != nil {
+			return value, err
+		}
+		if err := update(&value); err != nil {
+			return value, err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value, fmt.Errorf("failed to encode %s state of alert rule %d: %w", kind, ruleID, err)
+		}
+		state.State = string(data)
+		err = s.alertRuleRepo.SaveRuleState(ctx, state)
+		if err == nil {
+			return value, nil
+		}
+		if !apperrors.Is(err, apperrors.CodeConflict) || attempt == constants.AlertRuleStateSaveAttempts {
+			return value, err
+		}
+		s.logger.Debug("Alert rule state was saved concurrently, retrying", "rule_id", ruleID, "kind", kind,
+			"attempt", attempt)
+	}
+}
+
+// decodeRuleState decodes a stored evaluator state into value, leaving it unchanged for states never saved
+func decodeRuleState(state *models.AlertRuleState, value any) error {
+	if state.Version == 0 {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(state.State), value); err != nil {
+		return fmt.Errorf("failed to decode %s state of alert rule %d: %w", state.Kind, state.RuleID, err)
+	}
+	return nil
+}
@@ -2,13 +2,19 @@ package services
 
 import (
 	"context"
-	"database/sql"
-	"errors"
 	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
 	"github.com/adeesh/log-analytics/internal/database/alert_rules"
 	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/metrics"
 	"github.com/adeesh/log-analytics/internal/models"
 	"log/slog"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -16,32 +22,61 @@ import (
 type AlertService struct {
 	alertRuleRepo alert_rules.AlertRuleRepository
 	alertRepo     alerts.AlertRepository
-	db            *sql.DB
+	logs          logs.LogQuerier      // runs rule conditions and reports in the log store
+	notifications *NotificationService // nil disables notifications
+	events        *AlertEventBus       // nil disables alert events
+	health        checkerHealth
+	interval      checkInterval // changed by configuration reloads
 	logger        *slog.Logger
 }
 
 // NewAlertService creates a new alert service
-func NewAlertService(alertRuleRepo alert_rules.AlertRuleRepository, alertRepo alerts.AlertRepository, db *sql.DB, logger *slog.Logger) *AlertService {
+func NewAlertService(alertRuleRepo alert_rules.AlertRuleRepository, alertRepo alerts.AlertRepository, logQuerier logs.LogQuerier, notifications *NotificationService, events *AlertEventBus, logger *slog.Logger) *AlertService {
 	return &AlertService{
 		alertRuleRepo: alertRuleRepo,
 		alertRepo:     alertRepo,
-		db:            db,
+		logs:          logQuerier,
+		notifications: notifications,
+		events:        events,
 		logger:        logger,
 	}
 }
 
-// StartAlertChecker starts the background alert checker
-func (s *AlertService) StartAlertChecker(ctx context.Context, interval time.Duration) {
+// StartAlertChecker runs the alert checker until the context is cancelled. It must run on a single API server at a
+// time, so that alerts are created and notified once, and is run as a scheduler worker.
+func (s *AlertService) StartAlertChecker(ctx context.Context, cfg *config.AlertConfig) {
+	s.interval.init(cfg.CheckInterval)
+	interval, changed := s.interval.get()
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	s.logger.Info("Alert checker started", "interval", interval)
+	s.health.start(time.Now())
+	defer func() {
+		if s.health.stop() {
+			metrics.AlertCheckerMetaAlert.Set(0)
+		}
+	}()
+	if cfg.MetaAlertIntervals > 0 {
+		go s.watchChecker(ctx, cfg.MetaAlertIntervals)
+	}
+
+	// Evaluate windows missed while the checker was not running
+	if cfg.CatchUpEnabled {
+		if err := s.CatchUpMissedEvaluations(ctx, cfg.CheckInterval, cfg.CatchUpMaxLookback); err != nil {
+			s.logger.Error("Failed to catch up missed alert evaluations", "error", err)
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.Info("Alert checker stopped")
 			return
+		case <-changed:
+			interval, changed = s.interval.get()
+			ticker.Reset(interval)
+			s.logger.Info("Alert check interval changed", "interval", interval)
 		case <-ticker.C:
 			if err := s.CheckAlertRules(ctx); err != nil {
 				s.logger.Error("Failed to check alert rules", "error", err)
@@ -50,63 +85,234 @@ func (s *AlertService) StartAlertChecker(ctx context.Context, interval time.Dura
 	}
 }
 
+// DisableChecker records that alert rules aren't evaluated, so that the checker's health says so
+func (s *AlertService) DisableChecker() {
+	s.health.disable()
+	s.logger.Info("Alert checker disabled")
+}
+
+// SetCheckInterval changes how often the running alert checker evaluates the rules, e.g. on a configuration reload
+func (s *AlertService) SetCheckInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
 // CheckAlertRules evaluates all enabled alert rules and creates alerts if conditions are met
 func (s *AlertService) CheckAlertRules(ctx context.Context) error {
 	// Get all enabled alert rules
-	rules, err := s.alertRuleRepo.GetAlertRules(ctx)
+	rules, err := s.alertRuleRepo.GetAlertRules(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get alert rules: %w", err)
+		err = fmt.Errorf("failed to get alert rules: %w", err)
+		if ctx.Err() == nil {
+			s.health.finishCheck(time.Now(), nil, 0, 0, err)
+		}
+		return err
 	}
 
+	ruleIDs := make(map[uint]bool, len(rules))
+	evaluated, failed := 0, 0
 	for _, rule := range rules {
+		ruleIDs[rule.ID] = true
 		if !rule.Enabled {
 			continue
 		}
 
-		if err := s.evaluateRule(ctx, &rule); err != nil {
+		evaluatedAt := time.Now()
+		if rule.HasCanary() && !rule.InCanary(evaluatedAt) {
+			s.promoteCanary(ctx, &rule)
+		}
+
+		// During a canary the previous version stays in effect and the edited one is only shadowed
+		live := &rule
+		if rule.InCanary(evaluatedAt) {
+			live = rule.Previous()
+			s.evaluateCanary(ctx, &rule, evaluatedAt)
+		}
+
+		if err := s.evaluateRule(ctx, live); err != nil {
+			// Evaluations interrupted by shutdown aren't failures of the rule
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			evaluated++
+			failed++
+			metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationError).Inc()
+			s.health.ruleFailed(&rule, err, time.Now())
 			s.logger.Error("Failed to evaluate alert rule", "error", err, "rule_id", rule.ID, "rule_name", rule.Name)
+			continue
+		}
+		evaluated++
+		s.health.ruleSucceeded(rule.ID)
+
+		if err := s.alertRuleRepo.UpdateLastEvaluatedAt(ctx, rule.ID, evaluatedAt); err != nil {
+			s.logger.Error("Failed to record rule evaluation", "error", err, "rule_id", rule.ID)
 		}
 	}
 
+	// Individual rule failures are reported through the checker's health rather than returned
+	var checkErr error
+	if failed > 0 {
+		checkErr = fmt.Errorf("%d of %d alert rules failed to evaluate", failed, evaluated)
+	}
+	s.health.finishCheck(time.Now(), ruleIDs, evaluated, failed, checkErr)
 	return nil
 }
 
-// evaluateRule evaluates a single alert rule
-func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule) error {
-	// Build the SQL query based on the rule condition
-	query := s.buildQuery(rule)
+// CatchUpMissedEvaluations evaluates the windows each rule missed while the alert checker was down.
+// Missed time is split into consecutive windows of the rule's time window, bounded by maxLookback,
+// and the first window whose value crosses the threshold creates an alert marked as late-detected.
+func (s *AlertService) CatchUpMissedEvaluations(ctx context.Context, interval, maxLookback time.Duration) error {
+	rules, err := s.alertRuleRepo.GetAlertRules(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get alert rules: %w", err)
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		// Rules that were never evaluated have no known gap to catch up on
+		if !rule.Enabled || rule.LastEvaluatedAt == nil {
+			continue
+		}
 
-	// Execute the query
-	var result float64
-	err := s.db.QueryRowContext(ctx, query).Scan(&result)
+		// Allow one missed tick of slack before treating the gap as downtime
+		if now.Sub(*rule.LastEvaluatedAt) <= 2*interval {
+			continue
+		}
+
+		live := &rule
+		if rule.InCanary(now) {
+			live = rule.Previous()
+		}
+		if err := s.catchUpRule(ctx, live, now, maxLookback); err != nil {
+			s.logger.Error("Failed to catch up alert rule", "error", err, "rule_id", rule.ID, "rule_name", rule.Name)
+			continue
+		}
+
+		// Record the catch-up so a quick restart doesn't rescan the same windows
+		if err := s.alertRuleRepo.UpdateLastEvaluatedAt(ctx, rule.ID, now); err != nil {
+			s.logger.Error("Failed to record rule evaluation", "error", err, "rule_id", rule.ID)
+		}
+	}
+
+	return nil
+}
+
+// catchUpRule evaluates the missed windows of a single rule
+func (s *AlertService) catchUpRule(ctx context.Context, rule *models.AlertRule, now time.Time, maxLookback time.Duration) error {
+	window := time.Duration(rule.TimeWindow) * time.Minute
+	if window <= 0 {
+		return nil
+	}
+
+	start := *rule.LastEvaluatedAt
+	if earliest := now.Add(-maxLookback); start.Before(earliest) {
+		start = earliest
+	}
+
+	activeAlerts, err := s.getActiveAlerts(ctx, rule.ID, true)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// No data found, which means no alert should be triggered
-			return nil
+		return err
+	}
+	if len(activeAlerts) > 0 {
+		return nil
+	}
+
+	s.logger.Info("Catching up missed alert evaluations", "rule_id", rule.ID, "rule_name", rule.Name, "from", start, "to", now)
+
+	// The trailing partial window is covered by the regular checker
+	for windowStart := start; !windowStart.Add(window).After(now); windowStart = windowStart.Add(window) {
+		windowEnd := windowStart.Add(window)
+
+		result, ok, err := s.evaluateWindow(ctx, rule, windowStart, windowEnd)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf("failed to execute alert query: %w", err)
+		if !ok || !result.fired {
+			continue
+		}
+
+		suppressed, err := s.isSuppressed(ctx, rule, windowEnd)
+		if err != nil {
+			return err
+		}
+		if suppressed {
+			s.logger.Info("Late-detected alert suppressed by mute, snooze or cooldown", "rule_id", rule.ID, "rule_name", rule.Name,
+				"window_start", windowStart, "window_end", windowEnd)
+			continue
+		}
+
+		alert := &models.Alert{
+			RuleID: rule.ID,
+			Message: fmt.Sprintf("Alert rule '%s' triggered during missed window %s - %s: %s",
+				rule.Name, windowStart.Format(time.RFC3339), windowEnd.Format(time.RFC3339), result.describe(rule)),
+			Severity:     rule.Severity,
+			Value:        result.value,
+			Status:       constants.AlertStatusActive,
+			LateDetected: true,
+			CreatedAt:    time.Now(),
+			Tenant:       rule.Tenant,
+		}
+		if err := s.alertRepo.CreateAlert(ctx, alert); err != nil {
+			return fmt.Errorf("failed to create late-detected alert: %w", err)
+		}
+
+		s.logger.Warn("Late-detected alert created",
+			"rule_id", rule.ID,
+			"rule_name", rule.Name,
+			"window_start", windowStart,
+			"window_end", windowEnd,
+			"value", result.value,
+			"threshold", rule.Threshold)
+		s.publish(constants.AlertEventCreated, alert, rule.Name)
+		s.notify(ctx, rule, alert, windowStart, windowEnd)
+		return nil
+	}
+
+	return nil
+}
+
+// evaluateRule evaluates a single alert rule
+func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule) error {
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(rule.TimeWindow) * time.Minute)
+	result, ok, err := s.evaluateWindow(ctx, rule, windowStart, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// No data found, which means no alert should be triggered
+		metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationOK).Inc()
+		return nil
 	}
 
-	// Check if the result exceeds the threshold
-	if result >= rule.Threshold {
+	// Check if the result exceeds the threshold, or deviates from the baseline by the threshold
+	if result.fired {
 		// Check if there's already an active alert for this rule
-		activeAlerts, err := s.alertRepo.GetAlerts(ctx, &models.AlertFilter{
-			RuleID: &rule.ID,
-			Status: func() *string { s := "active"; return &s }(),
-		})
+		activeAlerts, err := s.getActiveAlerts(ctx, rule.ID, false)
 		if err != nil {
-			return fmt.Errorf("failed to check existing alerts: %w", err)
+			return err
 		}
 
-		// If no active alert exists, create a new one
+		// If no active alert exists, create a new one unless the rule is muted, snoozed or cooling down.
+		// Otherwise the active alerts are notified again once their rule's renotify interval has passed.
 		if len(activeAlerts) == 0 {
+			suppressed, err := s.isSuppressed(ctx, rule, now)
+			if err != nil {
+				return err
+			}
+			if suppressed {
+				s.logger.Debug("Alert suppressed by mute, snooze or cooldown", "rule_id", rule.ID, "rule_name", rule.Name, "value", result.value)
+				metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationSuppressed).Inc()
+				return nil
+			}
+
 			alert := &models.Alert{
 				RuleID:    rule.ID,
-				Message:   fmt.Sprintf("Alert rule '%s' triggered: %s = %.2f (threshold: %.2f)", rule.Name, rule.Condition, result, rule.Threshold),
+				Message:   fmt.Sprintf("Alert rule '%s' triggered: %s", rule.Name, result.describe(rule)),
 				Severity:  rule.Severity,
-				Value:     result,
-				Status:    "active",
+				Value:     result.value,
+				Status:    constants.AlertStatusActive,
 				CreatedAt: time.Now(),
+				Tenant:    rule.Tenant,
 			}
 
 			if err := s.alertRepo.CreateAlert(ctx, alert); err != nil {
@@ -117,17 +323,20 @@ func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule)
 				"rule_id", rule.ID,
 				"rule_name", rule.Name,
 				"severity", rule.Severity,
-				"value", result,
+				"value", result.value,
 				"threshold", rule.Threshold)
+			metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationFired).Inc()
+			s.publish(constants.AlertEventCreated, alert, rule.Name)
+			s.notify(ctx, rule, alert, windowStart, now)
+			return nil
 		}
+		s.renotify(ctx, rule, activeAlerts, windowStart, now)
 	} else {
-		// If the condition is no longer met, resolve any active alerts for this rule
-		activeAlerts, err := s.alertRepo.GetAlerts(ctx, &models.AlertFilter{
-			RuleID: &rule.ID,
-			Status: func() *string { s := "active"; return &s }(),
-		})
+		// If the condition is no longer met, resolve any active alerts for this rule.
+		// Late-detected alerts describe a past window and are left for operators to resolve.
+		activeAlerts, err := s.getActiveAlerts(ctx, rule.ID, false)
 		if err != nil {
-			return fmt.Errorf("failed to check existing alerts: %w", err)
+			return err
 		}
 
 		for _, alert := range activeAlerts {
@@ -135,24 +344,498 @@ func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule)
 				s.logger.Error("Failed to resolve alert", "error", err, "alert_id", alert.ID)
 			} else {
 				s.logger.Info("Alert resolved", "alert_id", alert.ID, "rule_name", rule.Name)
+				alert.Status = constants.AlertStatusResolved
+				s.publish(constants.AlertEventResolved, &alert, rule.Name)
 			}
 		}
+		if len(activeAlerts) > 0 {
+			metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationResolved).Inc()
+			return nil
+		}
+	}
+
+	metrics.AlertEvaluations.WithLabelValues(metrics.EvaluationOK).Inc()
+	return nil
+}
+
+// EvaluateRuleNow evaluates a rule over the window ending now, outside the alert checker, and reports what the
+// checker would do without doing it. Failures of the rule's query are reported in the evaluation rather than
+// returned, so that rules can be tuned against them.
+func (s *AlertService) EvaluateRuleNow(ctx context.Context, rule *models.AlertRule) (*models.AlertRuleEvaluation, error) {
+	now := time.Now()
+	live := rule
+	if rule.InCanary(now) {
+		live = rule.Previous()
+	}
+	windowStart := now.Add(-time.Duration(live.TimeWindow) * time.Minute)
+	query, _ := s.buildQuery(live, windowStart, now)
+	evaluation := &models.AlertRuleEvaluation{
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		Canary:      live != rule,
+		WindowStart: windowStart,
+		WindowEnd:   now,
+		Query:       database.SanitizeSQL(strings.Join(strings.Fields(query), " ")),
+		Threshold:   live.Threshold,
+	}
+
+	result, ok, err := s.evaluateWindow(ctx, live, windowStart, now)
+	evaluation.DurationMs = float64(time.Since(now).Microseconds()) / 1000
+	if err != nil {
+		if ctx.Err() != nil || apperrors.Is(err, apperrors.CodeUnavailable) {
+			return nil, err
+		}
+		evaluation.Error = database.SanitizeSQL(err.Error())
+		return evaluation, nil
+	}
+	if !ok {
+		return evaluation, nil
+	}
+	evaluation.Value = &result.value
+	evaluation.Description = database.SanitizeSQL(result.describe(live))
+	evaluation.ConditionMet = result.fired
+	if !result.fired {
+		return evaluation, nil
+	}
+
+	activeAlerts, err := s.getActiveAlerts(ctx, rule.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	evaluation.ActiveAlerts = len(activeAlerts)
+	if len(activeAlerts) == 0 {
+		suppressed, err := s.isSuppressed(ctx, live, now)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.Suppressed = suppressed
+		evaluation.WouldFire = rule.Enabled && !suppressed
 	}
+	return evaluation, nil
+}
 
+// ResolveAlert resolves an alert on behalf of an operator
+func (s *AlertService) ResolveAlert(ctx context.Context, id uint) error {
+	if err := s.alertRepo.ResolveAlert(ctx, id); err != nil {
+		return err
+	}
+	s.publishByID(ctx, constants.AlertEventResolved, id)
 	return nil
 }
 
-// buildQuery builds the SQL query for evaluating an alert rule
-func (s *AlertService) buildQuery(rule *models.AlertRule) string {
-	// Add time window filter to the condition
-	timeWindow := time.Now().Add(-time.Duration(rule.TimeWindow) * time.Minute)
+// AcknowledgeAlert acknowledges an alert on behalf of an operator
+func (s *AlertService) AcknowledgeAlert(ctx context.Context, id uint) error {
+	if err := s.alertRepo.AcknowledgeAlert(ctx, id); err != nil {
+		return err
+	}
+	s.publishByID(ctx, constants.AlertEventAcknowledged, id)
+	return nil
+}
+
+// BulkUpdateAlerts resolves or acknowledges every alert the action selects in one transaction and publishes an
+// event for each updated alert
+func (s *AlertService) BulkUpdateAlerts(ctx context.Context, action *models.AlertBulkAction, tenant *string) (*models.AlertBulkResult, error) {
+	filter := action.AlertFilter()
+	filter.Tenant = tenant
+	alerts, err := s.alertRepo.UpdateAlertsStatus(ctx, filter, action.Status())
+	if err != nil {
+		return nil, err
+	}
+
+	eventType := constants.AlertEventResolved
+	if action.Action == constants.AlertActionAcknowledge {
+		eventType = constants.AlertEventAcknowledged
+	}
+	result := &models.AlertBulkResult{Action: action.Action, Updated: len(alerts), AlertIDs: make([]uint, 0, len(alerts))}
+	for i := range alerts {
+		alert := &alerts[i]
+		result.AlertIDs = append(result.AlertIDs, alert.ID)
+		ruleName := ""
+		if alert.Rule != nil {
+			ruleName = alert.Rule.Name
+		}
+		s.publish(eventType, alert, ruleName)
+	}
+	s.logger.Info("Alerts updated in bulk", "action", action.Action, "updated", result.Updated)
+	return result, nil
+}
 
+// publish reports an alert event to the subscribers of the event bus
+func (s *AlertService) publish(eventType string, alert *models.Alert, ruleName string) {
+	if s.events != nil {
+		s.events.Publish(eventType, alert, ruleName)
+	}
+}
+
+// publishByID reports an event about an alert that was just updated, reloading it with its rule
+func (s *AlertService) publishByID(ctx context.Context, eventType string, id uint) {
+	if s.events == nil {
+		return
+	}
+	alert, err := s.alertRepo.GetAlertByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to load alert for its event", "error", err, "alert_id", id, "type", eventType)
+		return
+	}
+	ruleName := ""
+	if alert.Rule != nil {
+		ruleName = alert.Rule.Name
+	}
+	s.publish(eventType, alert, ruleName)
+}
+
+// promoteCanary puts the edited version of a rule whose canary period has passed into effect
+func (s *AlertService) promoteCanary(ctx context.Context, rule *models.AlertRule) {
+	if err := s.alertRuleRepo.PromoteCanary(ctx, rule.ID); err != nil {
+		s.logger.Error("Failed to promote alert rule canary", "error", err, "rule_id", rule.ID)
+		return
+	}
+	s.logger.Info("Alert rule canary ended, edit in effect", "rule_id", rule.ID, "rule_name", rule.Name,
+		"condition", rule.Condition, "threshold", rule.Threshold, "time_window", rule.TimeWindow)
+	rule.PreviousCondition, rule.PreviousThreshold, rule.PreviousTimeWindow, rule.CanaryUntil = nil, nil, nil, nil
+}
+
+// evaluateCanary evaluates the previous and edited versions of a canaried rule over their own windows
+// and records whether each would fire. Failures are logged so they never hold up the live evaluation.
+func (s *AlertService) evaluateCanary(ctx context.Context, rule *models.AlertRule, now time.Time) {
+	evaluation := &models.AlertRuleCanaryEvaluation{RuleID: rule.ID, EvaluatedAt: now}
+	var err error
+	if evaluation.PreviousValue, evaluation.PreviousFired, err = s.evaluateVersion(ctx, rule.Previous(), now); err != nil {
+		s.logger.Error("Failed to evaluate alert rule canary", "error", err, "rule_id", rule.ID)
+		return
+	}
+	if evaluation.CanaryValue, evaluation.CanaryFired, err = s.evaluateVersion(ctx, rule, now); err != nil {
+		s.logger.Error("Failed to evaluate alert rule canary", "error", err, "rule_id", rule.ID)
+		return
+	}
+
+	if evaluation.PreviousFired != evaluation.CanaryFired {
+		s.logger.Info("Alert rule canary disagrees with the version in effect", "rule_id", rule.ID, "rule_name", rule.Name,
+			"previous_fired", evaluation.PreviousFired, "canary_fired", evaluation.CanaryFired)
+	}
+	if err := s.alertRuleRepo.RecordCanaryEvaluation(ctx, evaluation); err != nil {
+		s.logger.Error("Failed to record canary evaluation", "error", err, "rule_id", rule.ID)
+	}
+}
+
+// evaluateVersion computes a rule version's value over the window ending now, nil when no data was found,
+// and whether it fires
+func (s *AlertService) evaluateVersion(ctx context.Context, rule *models.AlertRule, now time.Time) (*float64, bool, error) {
+	result, ok, err := s.evaluateWindow(ctx, rule, now.Add(-time.Duration(rule.TimeWindow)*time.Minute), now)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &result.value, result.fired, nil
+}
+
+// notify delivers an alert to the notification channels of its rule, along with a report of the window
+// it fired on, and records the notification on the alert. Alerts are still delivered when the report
+// cannot be computed.
+func (s *AlertService) notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert, start, end time.Time) {
+	if s.notifications == nil {
+		return
+	}
+	report, err := s.buildReport(ctx, start, end, rule.Tenant)
+	if err != nil {
+		s.logger.Warn("Failed to build alert report", "error", err, "rule_id", rule.ID, "alert_id", alert.ID)
+	}
+	s.notifications.Notify(ctx, rule, alert, report)
+
+	now := time.Now()
+	if err := s.alertRepo.RecordNotification(ctx, alert.ID, now); err != nil {
+		s.logger.Error("Failed to record alert notification", "error", err, "alert_id", alert.ID)
+		return
+	}
+	alert.LastNotifiedAt = &now
+	alert.NotificationCount++
+}
+
+// renotify notifies the rule's still active alerts again once its renotify interval has passed since their
+// last notification. Snoozed alerts and alerts of muted rules are left alone, and acknowledged alerts are
+// no longer active, so acknowledging an alert stops its re-notifications.
+func (s *AlertService) renotify(ctx context.Context, rule *models.AlertRule, activeAlerts []models.Alert, start, end time.Time) {
+	if rule.RenotifyMinutes <= 0 || s.notifications == nil || rule.IsMuted(end) {
+		return
+	}
+	interval := time.Duration(rule.RenotifyMinutes) * time.Minute
+	for i := range activeAlerts {
+		alert := &activeAlerts[i]
+		last := alert.CreatedAt
+		if alert.LastNotifiedAt != nil {
+			last = *alert.LastNotifiedAt
+		}
+		if alert.IsSnoozed(end) || end.Sub(last) < interval {
+			continue
+		}
+
+		s.logger.Info("Re-notifying active alert", "alert_id", alert.ID, "rule_name", rule.Name, "notifications", alert.NotificationCount)
+		s.notify(ctx, rule, alert, start, end)
+	}
+}
+
+// buildReport summarizes the logs created in [start, end), only those of the tenant when one is given: the error
+// rate, p95 response time, most frequent errors and number of users who hit errors
+func (s *AlertService) buildReport(ctx context.Context, start, end time.Time, tenant *string) (*models.AlertReport, error) {
+	report := &models.AlertReport{WindowStart: start, WindowEnd: end, TopErrors: []models.ErrorCount{}}
+
+	window, args := "created_at >= ? AND created_at < ?", []any{start, end}
+	if tenant != nil {
+		window += " AND tenant = ?"
+		args = append(args, *tenant)
+	}
+
+	// Column aliases match the snake-cased field names, as every log store backend decodes rows by them
+	var counts []struct {
+		TotalLogs     int64 `json:"total_logs"`
+		ErrorCount    int64 `json:"error_count"`
+		AffectedUsers int64 `json:"affected_users"`
+	}
+	err := s.logs.QueryLogs(ctx, &counts, `
+		SELECT COUNT(*) as total_logs,
+			COUNT(CASE WHEN level IN ('ERROR', 'FATAL') THEN 1 END) as error_count,
+			COUNT(DISTINCT CASE WHEN level IN ('ERROR', 'FATAL') THEN user_id END) as affected_users
+		FROM logs
+		WHERE `+window, args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get alert report counts")
+	}
+	if len(counts) > 0 {
+		report.TotalLogs = counts[0].TotalLogs
+		report.AffectedUsers = counts[0].AffectedUsers
+		if report.TotalLogs > 0 {
+			report.ErrorRate = float64(counts[0].ErrorCount) / float64(report.TotalLogs) * 100
+		}
+	}
+
+	// Nearest-rank percentile, as in the service comparison
+	var p95 []struct {
+		Value *float64 `json:"value"`
+	}
+	err = s.logs.QueryLogs(ctx, &p95, `
+		SELECT MIN(response_time_ms) as value
+		FROM (
+			SELECT response_time_ms,
+				ROW_NUMBER() OVER (ORDER BY response_time_ms) as latency_rank,
+				COUNT(*) OVER () as latency_count
+			FROM logs
+			WHERE `+window+` AND response_time_ms IS NOT NULL
+		) ranked
+		WHERE latency_rank >= CEIL(0.95 * latency_count)
+	`, args...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get alert report latency")
+	}
+	if len(p95) > 0 {
+		report.P95ResponseTimeMs = p95[0].Value
+	}
+
+	err = s.logs.QueryLogs(ctx, &report.TopErrors, `
+		SELECT message, COUNT(*) as count
+		FROM logs
+		WHERE `+window+` AND level IN ('ERROR', 'FATAL')
+		GROUP BY message
+		ORDER BY count DESC
+		LIMIT ?
+	`, append(args, constants.AlertReportTopErrors)...)
+	if err != nil {
+		return nil, database.TranslateError(err, "failed to get alert report errors")
+	}
+	return report, nil
+}
+
+// isSuppressed reports whether a rule may not fire, because it is muted at the given time, one of its
+// alerts is currently snoozed or one was resolved within the rule's cooldown. All revert on their own
+// once their time passes.
+func (s *AlertService) isSuppressed(ctx context.Context, rule *models.AlertRule, at time.Time) (bool, error) {
+	if rule.IsMuted(at) {
+		return true, nil
+	}
+
+	if rule.CooldownMinutes > 0 {
+		since, limit := at.Add(-time.Duration(rule.CooldownMinutes)*time.Minute), 1
+		cooling, err := s.alertRepo.GetAlerts(ctx, &models.AlertFilter{
+			RuleID:        &rule.ID,
+			ResolvedAfter: &since,
+			Limit:         &limit,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to check recently resolved alerts: %w", err)
+		}
+		if len(cooling) > 0 {
+			return true, nil
+		}
+	}
+
+	snoozed, limit := true, 1
+	snoozedAlerts, err := s.alertRepo.GetAlerts(ctx, &models.AlertFilter{
+		RuleID:  &rule.ID,
+		Snoozed: &snoozed,
+		Limit:   &limit,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check snoozed alerts: %w", err)
+	}
+	return len(snoozedAlerts) > 0, nil
+}
+
+// getActiveAlerts returns the active alerts for a rule, optionally including late-detected ones
+func (s *AlertService) getActiveAlerts(ctx context.Context, ruleID uint, includeLate bool) ([]models.Alert, error) {
+	activeAlerts, err := s.alertRepo.GetAlerts(ctx, &models.AlertFilter{
+		RuleID:   &ruleID,
+		Statuses: []string{constants.AlertStatusActive},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing alerts: %w", err)
+	}
+	if includeLate {
+		return activeAlerts, nil
+	}
+
+	live := activeAlerts[:0]
+	for _, alert := range activeAlerts {
+		if !alert.LateDetected {
+			live = append(live, alert)
+		}
+	}
+	return live, nil
+}
+
+// ruleEvaluation is the outcome of evaluating a rule over a window
+type ruleEvaluation struct {
+	value    float64
+	fired    bool
+	baseline *anomalyBaseline // of anomaly rules
+}
+
+// anomalyBaseline is the value of an anomaly rule's metric in the same window of previous days or weeks
+type anomalyBaseline struct {
+	mean      float64
+	stddev    float64
+	periods   int     // baseline windows that had data
+	deviation float64 // of the evaluated value, in standard deviations or percent of the mean
+}
+
+// evaluateWindow computes the rule's value over logs created in [start, end) and whether the rule fires: threshold
+// rules when the value reaches the threshold, anomaly rules when the value deviates from the baseline by the
+// threshold in the rule's direction. The boolean result is false when there is no data to evaluate, including
+// anomaly rules with too few baseline windows holding data.
+func (s *AlertService) evaluateWindow(ctx context.Context, rule *models.AlertRule, start, end time.Time) (ruleEvaluation, bool, error) {
+	value, ok, err := s.queryRuleValue(ctx, rule, start, end)
+	if err != nil || !ok {
+		return ruleEvaluation{}, false, err
+	}
+	if !rule.IsAnomaly() {
+		return ruleEvaluation{value: value, fired: value >= rule.Threshold}, true, nil
+	}
+
+	var samples []float64
+	for period := 1; period <= rule.AnomalyPeriods; period++ {
+		shift := time.Duration(period) * rule.Seasonality()
+		sample, ok, err := s.queryRuleValue(ctx, rule, start.Add(-shift), end.Add(-shift))
+		if err != nil {
+			return ruleEvaluation{}, false, err
+		}
+		if ok {
+			samples = append(samples, sample)
+		}
+	}
+	if len(samples) < constants.MinAnomalyBaselinePeriods {
+		s.logger.Debug("Not enough baseline to evaluate anomaly rule", "rule_id", rule.ID, "rule_name", rule.Name,
+			"baseline_periods", len(samples))
+		return ruleEvaluation{}, false, nil
+	}
+
+	baseline := newAnomalyBaseline(samples)
+	if rule.AnomalyDeviation == constants.AnomalyDeviationPercent {
+		baseline.deviation = deviation(value, baseline.mean, baseline.mean) * 100
+	} else {
+		baseline.deviation = deviation(value, baseline.mean, baseline.stddev)
+	}
+
+	var fired bool
+	switch rule.AnomalyDirection {
+	case constants.AnomalyDirectionDown:
+		fired = -baseline.deviation >= rule.Threshold
+	case constants.AnomalyDirectionBoth:
+		fired = math.Abs(baseline.deviation) >= rule.Threshold
+	default:
+		fired = baseline.deviation >= rule.Threshold
+	}
+	return ruleEvaluation{value: value, fired: fired, baseline: baseline}, true, nil
+}
+
+// newAnomalyBaseline computes the mean and sample standard deviation of the baseline values
+func newAnomalyBaseline(samples []float64) *anomalyBaseline {
+	baseline := &anomalyBaseline{periods: len(samples)}
+	for _, sample := range samples {
+		baseline.mean += sample
+	}
+	baseline.mean /= float64(len(samples))
+
+	var squares float64
+	for _, sample := range samples {
+		squares += (sample - baseline.mean) * (sample - baseline.mean)
+	}
+	baseline.stddev = math.Sqrt(squares / float64(len(samples)-1))
+	return baseline
+}
+
+// deviation returns how many units the value is away from the mean. Against a flat baseline, where the unit is
+// zero, any change is an infinite deviation.
+func deviation(value, mean, unit float64) float64 {
+	switch {
+	case value == mean:
+		return 0
+	case unit == 0:
+		return math.Inf(int(math.Copysign(1, value-mean)))
+	default:
+		return (value - mean) / math.Abs(unit)
+	}
+}
+
+// describe explains the evaluation in alert messages
+func (e ruleEvaluation) describe(rule *models.AlertRule) string {
+	if e.baseline == nil {
+		return fmt.Sprintf("%s = %.2f (threshold: %.2f)", rule.Condition, e.value, rule.Threshold)
+	}
+	deviation := fmt.Sprintf("%+.1f standard deviations", e.baseline.deviation)
+	if rule.AnomalyDeviation == constants.AnomalyDeviationPercent {
+		deviation = fmt.Sprintf("%+.1f%%", e.baseline.deviation)
+	}
+	return fmt.Sprintf("%s = %.2f, %s from its baseline of %.2f over the same window on %d previous %ss (threshold: %.2f)",
+		rule.AnomalyMetric, e.value, deviation, e.baseline.mean, e.baseline.periods, rule.AnomalySeasonality, rule.Threshold)
+}
+
+// queryRuleValue computes the rule's condition over logs created in [start, end), only those of the rule's tenant
+// for tenant rules. The boolean result is false when there is no data to evaluate.
+func (s *AlertService) queryRuleValue(ctx context.Context, rule *models.AlertRule, start, end time.Time) (float64, bool, error) {
+	query, args := s.buildQuery(rule, start, end)
+
+	var rows []struct {
+		Value *float64 `json:"value"`
+	}
+	if err := s.logs.QueryLogs(ctx, &rows, query, args...); err != nil {
+		return 0, false, database.TranslateError(err, "failed to execute alert query")
+	}
+	if len(rows) == 0 || rows[0].Value == nil {
+		return 0, false, nil
+	}
+	return *rows[0].Value, true, nil
+}
+
+// buildQuery builds the SQL query for evaluating an alert rule over a time window, with its arguments
+func (s *AlertService) buildQuery(rule *models.AlertRule, start, end time.Time) (string, []any) {
 	// Build the query with time window filter
 	query := fmt.Sprintf(`
-		SELECT %s 
+		SELECT (%s) as value
 		FROM logs 
-		WHERE created_at >= '%s'
-	`, rule.Condition, timeWindow.Format("2006-01-02 15:04:05"))
+		WHERE created_at >= ? AND created_at < ?
+	`, rule.Condition)
+	args := []any{start, end}
 
-	return query
+	// Rules of a tenant only see the tenant's logs
+	if rule.Tenant != nil {
+		return query + "AND tenant = ?", append(args, *rule.Tenant)
+	}
+	return query, args
 }
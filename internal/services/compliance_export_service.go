@@ -0,0 +1,281 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// ComplianceExportService produces signed, hash-chained log archives and verifies them
+type ComplianceExportService struct {
+	logRepo     logs.LogRepository
+	privateKey  ed25519.PrivateKey
+	rowsPerFile int
+	logger      *slog.Logger
+}
+
+// NewComplianceExportService creates a new compliance export service.
+// Exports are disabled when no signing key is configured.
+func NewComplianceExportService(logRepo logs.LogRepository, cfg *config.ExportConfig, logger *slog.Logger) (*ComplianceExportService, error) {
+	s := &ComplianceExportService{
+		logRepo:     logRepo,
+		rowsPerFile: cfg.RowsPerFile,
+		logger:      logger,
+	}
+	if s.rowsPerFile <= 0 {
+		s.rowsPerFile = constants.DefaultExportRowsPerFile
+	}
+
+	if cfg.SigningKey != "" {
+		seed, err := hex.DecodeString(cfg.SigningKey)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid export signing key: expected %d hex-encoded bytes", ed25519.SeedSize)
+		}
+		s.privateKey = ed25519.NewKeyFromSeed(seed)
+	}
+	return s, nil
+}
+
+// Enabled reports whether a signing key is configured
+func (s *ComplianceExportService) Enabled() bool {
+	return s.privateKey != nil
+}
+
+// Export writes a gzip-compressed tar archive of the logs matching the filter to w.
+// Logs are split into NDJSON files followed by the manifest and its signature. Archives are signed as a whole and
+// can't be truncated, so the export fails once more than maxRows logs match, possibly after part of it was written.
+func (s *ComplianceExportService) Export(ctx context.Context, w io.Writer, filter *models.LogFilter, maxRows int64) (*models.ExportManifest, error) {
+	if !s.Enabled() {
+		return nil, apperrors.Unavailable("Compliance export is not configured")
+	}
+
+	createdAt := time.Now().UTC()
+	manifest := &models.ExportManifest{
+		Version:   constants.ExportManifestVersion,
+		CreatedAt: createdAt,
+		Filter:    *filter,
+		PublicKey: hex.EncodeToString(s.privateKey.Public().(ed25519.PublicKey)),
+	}
+	manifest.Filter.Limit = 0
+	manifest.Filter.Offset = 0
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	// An empty cursor starts from the oldest stored log
+	cursor := ""
+	chainHash := sha256.Sum256([]byte(constants.ExportChainGenesis))
+	var file bytes.Buffer
+	var fileRows int64
+	encoder := json.NewEncoder(&file)
+
+	flush := func() error {
+		if fileRows == 0 {
+			return nil
+		}
+		fileHash := sha256.Sum256(file.Bytes())
+		chainHash = sha256.Sum256(append(chainHash[:], fileHash[:]...))
+		name := fmt.Sprintf(constants.ExportDataFileFormat, len(manifest.Files)+1)
+		if err := writeTarFile(archive, name, file.Bytes(), createdAt); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, models.ExportFile{
+			Name:      name,
+			Rows:      fileRows,
+			Size:      int64(file.Len()),
+			SHA256:    hex.EncodeToString(fileHash[:]),
+			ChainHash: hex.EncodeToString(chainHash[:]),
+		})
+		manifest.TotalRows += fileRows
+		file.Reset()
+		fileRows = 0
+		return nil
+	}
+
+	pageFilter := *filter
+	pageFilter.Limit = constants.ExportPageSize
+	pageFilter.Offset = 0
+	for {
+		page, next, err := s.logRepo.GetLogsAfterCursor(ctx, &pageFilter, cursor)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.TotalRows+fileRows+int64(len(page)) > maxRows {
+			return nil, apperrors.Validation("More than %d logs match the export, narrow its filters or time range", maxRows)
+		}
+		for _, log := range page {
+			if err := encoder.Encode(log); err != nil {
+				return nil, fmt.Errorf("failed to encode log: %w", err)
+			}
+			fileRows++
+			if fileRows >= int64(s.rowsPerFile) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		cursor = next
+		if len(page) < constants.ExportPageSize {
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	manifest.ChainHash = hex.EncodeToString(chainHash[:])
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	signature := ed25519.Sign(s.privateKey, manifestBytes)
+
+	if err := writeTarFile(archive, constants.ExportManifestFile, manifestBytes, createdAt); err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(archive, constants.ExportSignatureFile, []byte(hex.EncodeToString(signature)), createdAt); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	s.logger.Info("Compliance export created", "rows", manifest.TotalRows, "files", len(manifest.Files), "chain_hash", manifest.ChainHash)
+	return manifest, nil
+}
+
+// Verify checks an archive's manifest signature, every file hash and the hash chain.
+// Integrity problems are reported in the result; only unreadable archives return an error.
+func (s *ComplianceExportService) Verify(r io.Reader) (*models.ExportVerification, error) {
+	if !s.Enabled() {
+		return nil, apperrors.Unavailable("Compliance export is not configured")
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, apperrors.Validation("Archive is not a gzip-compressed tar file")
+	}
+	defer gz.Close()
+
+	var manifestBytes, signatureHex []byte
+	fileHashes := make(map[string]string)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperrors.Validation("Archive is corrupted")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		hash := sha256.New()
+		var content bytes.Buffer
+		dest := io.Writer(hash)
+		if header.Name == constants.ExportManifestFile || header.Name == constants.ExportSignatureFile {
+			dest = &content
+		}
+		if _, err := io.Copy(dest, io.LimitReader(archive, constants.MaxExportEntrySize)); err != nil {
+			return nil, apperrors.Validation("Archive is corrupted")
+		}
+
+		switch header.Name {
+		case constants.ExportManifestFile:
+			manifestBytes = content.Bytes()
+		case constants.ExportSignatureFile:
+			signatureHex = bytes.TrimSpace(content.Bytes())
+		default:
+			fileHashes[header.Name] = hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+
+	result := &models.ExportVerification{}
+	if manifestBytes == nil || signatureHex == nil {
+		result.Errors = append(result.Errors, "archive is missing its manifest or signature")
+		return result, nil
+	}
+
+	signature, err := hex.DecodeString(string(signatureHex))
+	publicKey := s.privateKey.Public().(ed25519.PublicKey)
+	if err != nil || !ed25519.Verify(publicKey, manifestBytes, signature) {
+		result.Errors = append(result.Errors, "manifest signature is invalid")
+	}
+
+	var manifest models.ExportManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		result.Errors = append(result.Errors, "manifest is not valid JSON")
+		return result, nil
+	}
+	result.CreatedAt = manifest.CreatedAt
+	result.TotalRows = manifest.TotalRows
+	result.Files = len(manifest.Files)
+	result.ChainHash = manifest.ChainHash
+
+	chainHash := sha256.Sum256([]byte(constants.ExportChainGenesis))
+	for _, file := range manifest.Files {
+		actual, ok := fileHashes[file.Name]
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("file %s is missing", file.Name))
+		} else if actual != file.SHA256 {
+			result.Errors = append(result.Errors, fmt.Sprintf("file %s does not match its hash", file.Name))
+		}
+		delete(fileHashes, file.Name)
+
+		fileHash, err := hex.DecodeString(file.SHA256)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("file %s has an invalid hash", file.Name))
+			continue
+		}
+		chainHash = sha256.Sum256(append(chainHash[:], fileHash...))
+		if hex.EncodeToString(chainHash[:]) != file.ChainHash {
+			result.Errors = append(result.Errors, fmt.Sprintf("hash chain is broken at file %s", file.Name))
+		}
+	}
+	if hex.EncodeToString(chainHash[:]) != manifest.ChainHash {
+		result.Errors = append(result.Errors, "final chain hash does not match the manifest")
+	}
+	for name := range fileHashes {
+		result.Errors = append(result.Errors, fmt.Sprintf("file %s is not listed in the manifest", name))
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
+// writeTarFile adds a regular file to the archive
+func writeTarFile(archive *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o444,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := archive.Write(content); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	return nil
+}
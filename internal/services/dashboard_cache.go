@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DashboardCache keeps the results of the queries every dashboard load runs — the log statistics of the default
+// time range, which include the top services, and the active alerts — so that concurrent loads share a single
+// query per TTL. The queries are run once at startup, before the server reports ready, so the first loads after
+// a deploy don't all hit cold tables.
+type DashboardCache struct {
+	logRepo      logs.LogRepository
+	alertRepo    alerts.AlertRepository
+	cfg          config.DashboardConfig
+	stats        cachedQuery[DashboardStats]
+	activeAlerts cachedQuery[[]models.Alert]
+	ready        atomic.Bool
+	logger       *slog.Logger
+}
+
+// DashboardStats are the log statistics of the default time range, ending when they were computed
+type DashboardStats struct {
+	Stats     *models.LogStats
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// cachedQuery holds the result of a query until it expires. The lock is held while the query runs, so callers
+// missing the cache at the same time wait for a single query instead of each running their own.
+type cachedQuery[T any] struct {
+	mu       sync.Mutex
+	value    T
+	loadedAt time.Time // zero when nothing is cached
+}
+
+// NewDashboardCache creates a new dashboard cache
+func NewDashboardCache(logRepo logs.LogRepository, alertRepo alerts.AlertRepository, cfg config.DashboardConfig, logger *slog.Logger) *DashboardCache {
+	return &DashboardCache{
+		logRepo:   logRepo,
+		alertRepo: alertRepo,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Warm runs the cached queries and marks the server ready, even when they fail or outlast the warm-up timeout:
+// a slow warm-up must not keep the server out of rotation for good.
+func (d *DashboardCache) Warm(ctx context.Context) {
+	defer d.ready.Store(true)
+	if d.cfg.CacheTTL <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.WarmupTimeout)
+	defer cancel()
+	started := time.Now()
+	if _, err := d.LogStats(ctx); err != nil {
+		d.logger.Warn("Failed to warm up the dashboard log statistics", "error", err)
+	}
+	if _, err := d.ActiveAlerts(ctx); err != nil {
+		d.logger.Warn("Failed to warm up the dashboard active alerts", "error", err)
+	}
+	d.logger.Info("Dashboard cache warmed up", "duration", time.Since(started))
+}
+
+// Ready reports whether the warm-up has finished
+func (d *DashboardCache) Ready() bool {
+	return d.ready.Load()
+}
+
+// LogStats returns the log statistics of the default time range, at most the cache TTL old
+func (d *DashboardCache) LogStats(ctx context.Context) (DashboardStats, error) {
+	return d.stats.get(ctx, d.cfg.CacheTTL, func(ctx context.Context) (DashboardStats, error) {
+		endTime := time.Now()
+		startTime := endTime.Add(-constants.DefaultMetricsRange)
+		stats, err := d.logRepo.GetLogStats(ctx, startTime, endTime, models.LogDimensions{}, d.Alignment())
+		return DashboardStats{Stats: stats, StartTime: startTime, EndTime: endTime}, err
+	})
+}
+
+// Alignment returns the dashboard's alignment of time series buckets
+func (d *DashboardCache) Alignment() models.BucketAlignment {
+	return models.BucketAlignment{WeekStart: d.cfg.WeekStart, Offset: d.cfg.BucketOffset}
+}
+
+// ActiveAlerts returns the active alerts, at most the cache TTL old unless alerts changed on this server since
+func (d *DashboardCache) ActiveAlerts(ctx context.Context) ([]models.Alert, error) {
+	return d.activeAlerts.get(ctx, d.cfg.CacheTTL, d.alertRepo.GetActiveAlerts)
+}
+
+// InvalidateAlerts drops the cached active alerts after an alert was resolved, acknowledged or snoozed
+func (d *DashboardCache) InvalidateAlerts() {
+	d.activeAlerts.invalidate()
+}
+
+// get returns the cached value, running the query when it has expired. Failures aren't cached.
+func (q *cachedQuery[T]) get(ctx context.Context, ttl time.Duration, query func(context.Context) (T, error)) (T, error) {
+	if ttl <= 0 {
+		return query(ctx)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loadedAt.IsZero() && time.Since(q.loadedAt) < ttl {
+		return q.value, nil
+	}
+	value, err := query(ctx)
+	if err != nil {
+		return value, err
+	}
+	q.value = value
+	q.loadedAt = time.Now()
+	return value, nil
+}
+
+// invalidate drops the cached value
+func (q *cachedQuery[T]) invalidate() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.loadedAt = time.Time{}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// DeadLetterService inspects the dead-letter topic
+type DeadLetterService struct {
+	brokers []string
+	topic   string
+	logger  *slog.Logger
+	mu      sync.Mutex
+	client  sarama.Client
+}
+
+// NewDeadLetterService creates a new dead-letter service.
+// The Kafka client is created on first use so the API starts even when Kafka is unavailable.
+func NewDeadLetterService(cfg *config.KafkaConfig, logger *slog.Logger) *DeadLetterService {
+	return &DeadLetterService{
+		brokers: cfg.Brokers,
+		topic:   cfg.DeadLetterTopic,
+		logger:  logger,
+	}
+}
+
+// GetStats returns the number of messages retained in each partition of the dead-letter topic
+func (s *DeadLetterService) GetStats(_ context.Context) (*models.DeadLetterStats, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.DeadLetterStats{
+		Topic:      s.topic,
+		Partitions: []models.DeadLetterPartition{},
+		CheckedAt:  time.Now(),
+	}
+
+	// Refresh so partitions created since the last request are included
+	if err := client.RefreshMetadata(s.topic); err != nil {
+		if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return stats, nil
+		}
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+	}
+
+	partitions, err := client.Partitions(s.topic)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+	}
+
+	for _, partition := range partitions {
+		oldest, err := client.GetOffset(s.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+		}
+		newest, err := client.GetOffset(s.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+		}
+		messages := newest - oldest
+		stats.Partitions = append(stats.Partitions, models.DeadLetterPartition{
+			Partition:    partition,
+			OldestOffset: oldest,
+			NewestOffset: newest,
+			Messages:     messages,
+		})
+		stats.TotalMessages += messages
+	}
+
+	return stats, nil
+}
+
+// Close closes the Kafka client if one was created
+func (s *DeadLetterService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// getClient returns the shared Kafka client, creating it if needed
+func (s *DeadLetterService) getClient() (sarama.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+	client, err := sarama.NewClient(s.brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+	}
+	s.client = client
+	return client, nil
+}
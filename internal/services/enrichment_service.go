@@ -0,0 +1,418 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxEnrichmentResponseSize caps the size of an enrichment endpoint response body
+const maxEnrichmentResponseSize = 1 << 20
+
+// EnrichmentService enriches logs with attributes fetched from external HTTP endpoints
+type EnrichmentService struct {
+	endpoints   []config.EnrichmentEndpoint
+	client      *http.Client
+	cache       *enrichmentCache
+	breakers    map[string]*circuitBreaker
+	concurrency int
+	logger      *slog.Logger
+}
+
+// enrichmentLookup identifies a single external lookup for an endpoint and key value
+type enrichmentLookup struct {
+	endpoint int
+	value    string
+}
+
+// NewEnrichmentService creates a new enrichment service
+func NewEnrichmentService(cfg *config.EnrichmentConfig, logger *slog.Logger) *EnrichmentService {
+	breakers := make(map[string]*circuitBreaker, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		breakers[endpoint.Name] = newCircuitBreaker(cfg.FailureThreshold, cfg.BreakerCooldown)
+	}
+
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = constants.DefaultEnrichmentConcurrency
+	}
+
+	return &EnrichmentService{
+		endpoints:   cfg.Endpoints,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		cache:       newEnrichmentCache(cfg.CacheTTL, cfg.CacheMaxEntries),
+		breakers:    breakers,
+		concurrency: concurrency,
+		logger:      logger,
+	}
+}
+
+// Close stops background cache maintenance
+func (s *EnrichmentService) Close() {
+	s.cache.close()
+}
+
+// EnrichBatch enriches a batch of logs in two phases: unique lookups are first collected
+// across the batch, then resolved concurrently and merged into each log's attributes.
+// Lookup failures never fail the batch; affected logs are simply stored without enrichment.
+func (s *EnrichmentService) EnrichBatch(ctx context.Context, logs []*models.Log) {
+	// Phase 1: collect unique lookups
+	lookups := make(map[enrichmentLookup]struct{})
+	for _, log := range logs {
+		for i, endpoint := range s.endpoints {
+			if value := lookupValue(log, endpoint.Key); value != "" {
+				lookups[enrichmentLookup{endpoint: i, value: value}] = struct{}{}
+			}
+		}
+	}
+	if len(lookups) == 0 {
+		return
+	}
+
+	// Phase 2: resolve lookups concurrently with bounded parallelism
+	results := make(map[enrichmentLookup]map[string]string, len(lookups))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.concurrency)
+
+	for lookup := range lookups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(lookup enrichmentLookup) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, ok := s.resolve(ctx, lookup)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			results[lookup] = data
+			mu.Unlock()
+		}(lookup)
+	}
+	wg.Wait()
+
+	// Merge results into log attributes without overriding existing values
+	for _, log := range logs {
+		for i, endpoint := range s.endpoints {
+			value := lookupValue(log, endpoint.Key)
+			if value == "" {
+				continue
+			}
+			for field, fieldValue := range results[enrichmentLookup{endpoint: i, value: value}] {
+				key := endpoint.Name + "." + field
+				if _, exists := log.Attributes[key]; !exists {
+					log.Attributes.Set(key, fieldValue)
+				}
+			}
+		}
+	}
+}
+
+// resolve returns the enrichment data for a lookup, using the cache and circuit breaker
+func (s *EnrichmentService) resolve(ctx context.Context, lookup enrichmentLookup) (map[string]string, bool) {
+	endpoint := s.endpoints[lookup.endpoint]
+	cacheKey := endpoint.Name + ":" + lookup.value
+
+	if data, ok := s.cache.get(cacheKey); ok {
+		return data, true
+	}
+
+	breaker := s.breakers[endpoint.Name]
+	if !breaker.Allow() {
+		return nil, false
+	}
+
+	data, err := s.fetch(ctx, endpoint, lookup.value)
+	if err != nil {
+		breaker.RecordFailure()
+		s.logger.Warn("Enrichment lookup failed", "error", err, "endpoint", endpoint.Name, "key", endpoint.Key)
+		return nil, false
+	}
+	breaker.RecordSuccess()
+
+	// Cache misses too (empty data) so unknown keys don't hammer the endpoint
+	s.cache.set(cacheKey, data)
+	return data, true
+}
+
+// fetch performs the HTTP lookup and flattens the JSON object response into string values
+func (s *EnrichmentService) fetch(ctx context.Context, endpoint config.EnrichmentEndpoint, value string) (map[string]string, error) {
+	target := expandEnrichmentURL(endpoint.URL, value)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var payload map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxEnrichmentResponseSize)).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	data := make(map[string]string, len(payload))
+	for field, fieldValue := range payload {
+		switch v := fieldValue.(type) {
+		case nil:
+			continue
+		case string:
+			data[field] = v
+		case map[string]interface{}, []interface{}:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			data[field] = string(encoded)
+		default:
+			data[field] = fmt.Sprint(v)
+		}
+	}
+	return data, nil
+}
+
+// expandEnrichmentURL substitutes the placeholder, escaping the value for the URL part it appears in
+func expandEnrichmentURL(rawURL, value string) string {
+	queryStart := strings.Index(rawURL, "?")
+	var out strings.Builder
+	rest := rawURL
+	offset := 0
+	for {
+		idx := strings.Index(rest, constants.EnrichmentURLPlaceholder)
+		if idx < 0 {
+			out.WriteString(rest)
+			return out.String()
+		}
+		out.WriteString(rest[:idx])
+		if queryStart >= 0 && offset+idx > queryStart {
+			out.WriteString(url.QueryEscape(value))
+		} else {
+			out.WriteString(url.PathEscape(value))
+		}
+		advance := idx + len(constants.EnrichmentURLPlaceholder)
+		rest = rest[advance:]
+		offset += advance
+	}
+}
+
+// lookupValue returns the value of the log field used as the lookup key
+func lookupValue(log *models.Log, key string) string {
+	switch key {
+	case constants.EnrichmentKeyUserID:
+		if log.UserID != nil {
+			return *log.UserID
+		}
+	case constants.EnrichmentKeyTraceID:
+		if log.TraceID != nil {
+			return *log.TraceID
+		}
+	}
+	return ""
+}
+
+// enrichmentCache is a TTL cache for enrichment results bounded by a maximum number of entries.
+// The least recently used entry is evicted when the cache is full and expired entries are
+// swept periodically by a background janitor.
+type enrichmentCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+type enrichmentCacheEntry struct {
+	key       string
+	data      map[string]string
+	expiresAt time.Time
+}
+
+func newEnrichmentCache(ttl time.Duration, maxEntries int) *enrichmentCache {
+	if maxEntries <= 0 {
+		maxEntries = constants.DefaultEnrichmentCacheMaxEntries
+	}
+	c := &enrichmentCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		stop:       make(chan struct{}),
+	}
+	if ttl > 0 {
+		go c.janitor()
+	}
+	return c
+}
+
+func (c *enrichmentCache) get(key string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*enrichmentCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.data, true
+}
+
+func (c *enrichmentCache) set(key string, data map[string]string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*enrichmentCacheEntry)
+		entry.data = data
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&enrichmentCacheEntry{key: key, data: data, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// janitor periodically removes expired entries until the cache is closed
+func (c *enrichmentCache) janitor() {
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.sweep()
+		}
+	}
+}
+
+// sweep removes all expired entries
+func (c *enrichmentCache) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for element := c.order.Back(); element != nil; {
+		prev := element.Prev()
+		if now.After(element.Value.(*enrichmentCacheEntry).expiresAt) {
+			c.removeElement(element)
+		}
+		element = prev
+	}
+}
+
+func (c *enrichmentCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*enrichmentCacheEntry).key)
+}
+
+func (c *enrichmentCache) close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// circuitBreakerState represents the state of a circuit breaker
+type circuitBreakerState int
+
+const (
+	breakerClosed circuitBreakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops calling an endpoint after consecutive failures until a cooldown passes,
+// then lets a single probe call through to decide whether to close again
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     circuitBreakerState
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = constants.DefaultEnrichmentFailureThreshold
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be attempted. While half-open only the probe call is allowed.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// RecordFailure counts a failure and opens the breaker once the threshold is reached.
+// A failed probe while half-open reopens it immediately.
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
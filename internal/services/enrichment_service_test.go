@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps string // a allowed, d denied, f failure, s success, c cooldown elapsed
+	}{
+		{name: "closed below threshold", steps: "afafa"},
+		{name: "opens at threshold", steps: "fffd"},
+		{name: "success resets the failures", steps: "ffsffa"},
+		{name: "single probe after cooldown", steps: "fffdcad"},
+		{name: "successful probe closes", steps: "fffcasaa"},
+		{name: "failed probe reopens", steps: "fffcafd"},
+		{name: "reopened breaker probes again", steps: "fffcafcad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(3, time.Hour)
+			for i, step := range tt.steps {
+				switch step {
+				case 'a', 'd':
+					if got := b.Allow(); got != (step == 'a') {
+						t.Fatalf("step %d: Allow() = %v in %q", i, got, tt.steps)
+					}
+				case 'f':
+					b.RecordFailure()
+				case 's':
+					b.RecordSuccess()
+				case 'c':
+					b.openUntil = time.Now()
+				}
+			}
+		})
+	}
+
+	if b := newCircuitBreaker(0, time.Hour); b.threshold != constants.DefaultEnrichmentFailureThreshold {
+		t.Errorf("threshold %d without configuration, want the default", b.threshold)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/fingerprints"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/parsers"
+	"log/slog"
+	"time"
+)
+
+// FingerprintBackfillService recomputes the fingerprints of the stored error logs under the current fingerprint
+// rules, so that error groups stay consistent after the rules change. Logs are visited in batches with a pause in
+// between, and the progress of the job is recorded after every batch.
+type FingerprintBackfillService struct {
+	logRepo       logs.LogRepository
+	repo          fingerprints.FingerprintRepository
+	fingerprinter *parsers.Fingerprinter
+	maintenance   *MaintenanceService
+	jobs          chan *models.FingerprintBackfill
+	logger        *slog.Logger
+}
+
+// NewFingerprintBackfillService creates a new fingerprint backfill service
+func NewFingerprintBackfillService(logRepo logs.LogRepository, repo fingerprints.FingerprintRepository, fingerprinter *parsers.Fingerprinter, maintenance *MaintenanceService, logger *slog.Logger) *FingerprintBackfillService {
+	return &FingerprintBackfillService{
+		logRepo:       logRepo,
+		repo:          repo,
+		fingerprinter: fingerprinter,
+		maintenance:   maintenance,
+		jobs:          make(chan *models.FingerprintBackfill, 1),
+		logger:        logger,
+	}
+}
+
+// Start runs the jobs started on this API server until the context is cancelled. A job interrupted by the
+// cancellation stays running and is resumed by the next job started under the same rules.
+func (s *FingerprintBackfillService) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case backfill := <-s.jobs:
+			s.run(ctx, backfill)
+		}
+	}
+}
+
+// StartBackfill starts a job on this API server. A job left running by an API server that stopped is taken over,
+// continuing from where it stopped when it ran under the same rules.
+func (s *FingerprintBackfillService) StartBackfill(ctx context.Context) (*models.FingerprintBackfill, error) {
+	staleBefore := time.Now().Add(-constants.FingerprintBackfillStaleAfter)
+	backfill := &models.FingerprintBackfill{
+		RulesVersion: s.fingerprinter.Version(),
+		Status:       constants.FingerprintBackfillRunning,
+		StartedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	latest, err := s.repo.GetLatestBackfill(ctx)
+	if err != nil && !apperrors.Is(err, apperrors.CodeNotFound) {
+		return nil, err
+	}
+	if latest != nil && latest.Status == constants.FingerprintBackfillRunning && latest.RulesVersion == backfill.RulesVersion &&
+		!latest.UpdatedAt.After(staleBefore) {
+		backfill.Cursor = latest.Cursor
+		backfill.Processed = latest.Processed
+		backfill.Updated = latest.Updated
+	}
+
+	total, err := s.logRepo.CountErrorLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backfill.Total = total
+
+	if err := s.repo.CreateBackfill(ctx, backfill, staleBefore); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fingerprint backfill started", "id", backfill.ID, "rules_version", backfill.RulesVersion,
+		"total", backfill.Total, "resumed_at", backfill.Processed)
+
+	select {
+	case s.jobs <- backfill:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return backfill, nil
+}
+
+// GetStatus returns the version of the fingerprint rules in effect and the most recently started job
+func (s *FingerprintBackfillService) GetStatus(ctx context.Context) (*models.FingerprintStatus, error) {
+	status := &models.FingerprintStatus{RulesVersion: s.fingerprinter.Version()}
+	backfill, err := s.repo.GetLatestBackfill(ctx)
+	if err != nil && !apperrors.Is(err, apperrors.CodeNotFound) {
+		return nil, err
+	}
+	status.Backfill = backfill
+	return status, nil
+}
+
+// CancelBackfill cancels the running job, wherever it runs
+func (s *FingerprintBackfillService) CancelBackfill(ctx context.Context) (*models.FingerprintBackfill, error) {
+	backfill, err := s.repo.CancelBackfill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fingerprint backfill cancelled", "id", backfill.ID, "processed", backfill.Processed)
+	return backfill, nil
+}
+
+// run fingerprints the error logs batch by batch until all are visited, the job stops running or the context is
+// cancelled. Nothing is written while read-only mode is active.
+func (s *FingerprintBackfillService) run(ctx context.Context, backfill *models.FingerprintBackfill) {
+	for {
+		if !s.maintenance.IsReadOnly() {
+			done, err := s.fingerprintBatch(ctx, backfill)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				backfill.Status = constants.FingerprintBackfillFailed
+				backfill.Error = err.Error()
+				s.finish(backfill)
+				return
+			}
+			if done {
+				backfill.Status = constants.FingerprintBackfillCompleted
+				s.finish(backfill)
+				return
+			}
+		}
+
+		if err := s.repo.RecordBackfillProgress(ctx, backfill); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if apperrors.Is(err, apperrors.CodeConflict) {
+				s.logger.Info("Fingerprint backfill stopped", "id", backfill.ID, "reason", err)
+				return
+			}
+			s.logger.Error("Failed to record fingerprint backfill progress", "error", err, "id", backfill.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.FingerprintBackfillBatchPause):
+		}
+	}
+}
+
+// fingerprintBatch fingerprints the next batch of error logs, reporting whether none were left
+func (s *FingerprintBackfillService) fingerprintBatch(ctx context.Context, backfill *models.FingerprintBackfill) (bool, error) {
+	batch, cursor, err := s.logRepo.GetErrorLogsAfterCursor(ctx, backfill.Cursor, constants.FingerprintBackfillBatchSize)
+	if err != nil || len(batch) == 0 {
+		return err == nil, err
+	}
+
+	for _, log := range batch {
+		log.Fingerprint = s.fingerprinter.Fingerprint(log)
+	}
+	updated, err := s.logRepo.SetLogFingerprints(ctx, batch)
+	backfill.Updated += updated
+	if err != nil {
+		return false, err
+	}
+	backfill.Cursor = cursor
+	backfill.Processed += int64(len(batch))
+	return false, nil
+}
+
+// finish records the final status of a job
+func (s *FingerprintBackfillService) finish(backfill *models.FingerprintBackfill) {
+	if err := s.repo.FinishBackfill(context.Background(), backfill); err != nil {
+		if apperrors.Is(err, apperrors.CodeConflict) {
+			s.logger.Info("Fingerprint backfill stopped", "id", backfill.ID, "reason", err)
+			return
+		}
+		s.logger.Error("Failed to finish fingerprint backfill", "error", err, "id", backfill.ID, "status", backfill.Status)
+		return
+	}
+	s.logger.Info("Fingerprint backfill finished", "id", backfill.ID, "status", backfill.Status,
+		"processed", backfill.Processed, "updated", backfill.Updated, "error", backfill.Error)
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaLagService compares the offsets committed by the processor's consumer groups with the newest offsets of the
+// topics they consume. Its monitor reports the lag as metrics and fires the lag alert for a group that falls more
+// than the threshold behind, resolving it once the group catches up.
+type KafkaLagService struct {
+	brokers       []string
+	groups        []consumerGroup
+	interval      time.Duration
+	threshold     int64                // 0 when the alert is disabled
+	notifications *NotificationService // nil when the alert is disabled
+	firing        map[string]bool      // groups whose alert is firing, only touched by the monitor
+	logger        *slog.Logger
+	mu            sync.Mutex
+	client        sarama.Client
+	admin         sarama.ClusterAdmin // shares the client
+}
+
+// consumerGroup is a consumer group of the processor and the topics it consumes
+type consumerGroup struct {
+	id     string
+	topics []string
+}
+
+// NewKafkaLagService creates a new lag service for the processor's consumer groups: the log group over the log and
+// tenant topics, and the priority group over the priority topic when the priority lane is enabled.
+// The Kafka client is created on first use so the API starts even when Kafka is unavailable.
+func NewKafkaLagService(cfg *config.KafkaConfig, notifications *NotificationService, logger *slog.Logger) *KafkaLagService {
+	topics := []string{cfg.Topic}
+	for _, mapping := range cfg.TenantTopics {
+		topics = append(topics, mapping.Topic)
+	}
+	groups := []consumerGroup{{id: cfg.GroupID, topics: topics}}
+	if cfg.PriorityTopic != "" {
+		groups = append(groups, consumerGroup{id: cfg.GroupID + constants.PriorityGroupIDSuffix, topics: []string{cfg.PriorityTopic}})
+	}
+
+	service := &KafkaLagService{
+		brokers:   cfg.Brokers,
+		groups:    groups,
+		interval:  cfg.LagCheckInterval,
+		threshold: max(cfg.LagAlertThreshold, 0),
+		firing:    make(map[string]bool),
+		logger:    logger,
+	}
+	if cfg.LagAlertThreshold > 0 {
+		service.notifications = notifications
+	}
+	return service
+}
+
+// GetLag returns the lag of each consumer group, per partition of its topics
+func (s *KafkaLagService) GetLag(_ context.Context) (*models.KafkaLag, error) {
+	client, admin, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	lag := &models.KafkaLag{Groups: []models.ConsumerGroupLag{}, CheckedAt: time.Now()}
+	for _, group := range s.groups {
+		groupLag, err := s.groupLag(client, admin, group)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+		}
+		lag.Groups = append(lag.Groups, *groupLag)
+		lag.TotalLag += groupLag.Lag
+	}
+	return lag, nil
+}
+
+// groupLag compares the offsets committed by a group with the newest offsets of its topics' partitions. Topics that
+// don't exist yet are left out. Partitions without a committed offset have no lag, since the processor starts
+// consuming them at the newest offset.
+func (s *KafkaLagService) groupLag(client sarama.Client, admin sarama.ClusterAdmin, group consumerGroup) (*models.ConsumerGroupLag, error) {
+	groupLag := &models.ConsumerGroupLag{Group: group.id, Topics: group.topics, Partitions: []models.PartitionLag{}}
+
+	topicPartitions := make(map[string][]int32, len(group.topics))
+	for _, topic := range group.topics {
+		// Refresh so partitions created since the last check are included
+		if err := client.RefreshMetadata(topic); err != nil {
+			if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to refresh metadata of topic %s: %w", topic, err)
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitions of topic %s: %w", topic, err)
+		}
+		topicPartitions[topic] = partitions
+	}
+	if len(topicPartitions) == 0 {
+		return groupLag, nil
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(group.id, topicPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offsets of consumer group %s: %w", group.id, err)
+	}
+	for _, topic := range group.topics {
+		for _, partition := range topicPartitions[topic] {
+			newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, partition, err)
+			}
+			partitionLag := models.PartitionLag{Topic: topic, Partition: partition, CommittedOffset: -1, NewestOffset: newest}
+			if block := committed.GetBlock(topic, partition); block != nil {
+				if block.Err != sarama.ErrNoError {
+					return nil, fmt.Errorf("failed to get offset of consumer group %s for %s/%d: %w", group.id, topic, partition, block.Err)
+				}
+				partitionLag.CommittedOffset = block.Offset
+			}
+			if partitionLag.CommittedOffset >= 0 {
+				partitionLag.Lag = max(newest-partitionLag.CommittedOffset, 0)
+			}
+			groupLag.Partitions = append(groupLag.Partitions, partitionLag)
+			groupLag.Lag += partitionLag.Lag
+		}
+	}
+	return groupLag, nil
+}
+
+// Start checks the lag once per check interval until the context is done
+func (s *KafkaLagService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Kafka lag monitor started", "interval", s.interval, "threshold", s.threshold)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.check(ctx, now)
+		}
+	}
+}
+
+// check reports the lag of every partition as metrics, and fires or resolves the alert of each group. Groups keep
+// their alert as it is while the lag can't be checked.
+func (s *KafkaLagService) check(ctx context.Context, now time.Time) {
+	lag, err := s.GetLag(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to check Kafka consumer lag", "error", err)
+		return
+	}
+
+	for _, group := range lag.Groups {
+		for _, partition := range group.Partitions {
+			metrics.ConsumerGroupLag.WithLabelValues(group.Group, partition.Topic, strconv.Itoa(int(partition.Partition))).Set(float64(partition.Lag))
+		}
+
+		if s.threshold == 0 {
+			continue
+		}
+		late := group.Lag > s.threshold
+		switch {
+		case late && !s.firing[group.Group]:
+			s.firing[group.Group] = true
+			message := fmt.Sprintf("Consumer group %s is %d messages behind its topics, more than the threshold of %d",
+				group.Group, group.Lag, s.threshold)
+			s.logger.Error("Kafka lag alert fired", "group", group.Group, "message", message)
+			metrics.KafkaLagAlert.WithLabelValues(group.Group).Set(1)
+			s.notifications.NotifyAll(ctx, &models.AlertNotification{
+				RuleName:  constants.KafkaLagAlertName,
+				Severity:  constants.KafkaLagAlertSeverity,
+				Message:   message,
+				Value:     float64(group.Lag),
+				Threshold: float64(s.threshold),
+				CreatedAt: now,
+				Meta:      true,
+			})
+		case !late && s.firing[group.Group]:
+			delete(s.firing, group.Group)
+			s.logger.Info("Kafka lag alert resolved", "group", group.Group, "lag", group.Lag)
+			metrics.KafkaLagAlert.WithLabelValues(group.Group).Set(0)
+		}
+	}
+}
+
+// Close closes the Kafka client, through its cluster admin, if one was created
+func (s *KafkaLagService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.admin == nil {
+		return nil
+	}
+	return s.admin.Close()
+}
+
+// getClient returns the shared Kafka client and the cluster admin using it, creating them if needed
+func (s *KafkaLagService) getClient() (sarama.Client, sarama.ClusterAdmin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.admin != nil {
+		return s.client, s.admin, nil
+	}
+	client, err := sarama.NewClient(s.brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, apperrors.Wrap(apperrors.CodeUnavailable, err, "Kafka unavailable")
+	}
+	s.client, s.admin = client, admin
+	return client, admin, nil
+}
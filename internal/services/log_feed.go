@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogFeed publishes newly stored logs to in-process subscribers.
+// A single background poller follows the log store so waiting clients don't each query the database.
+type LogFeed struct {
+	logRepo     logs.LogRepository
+	interval    time.Duration
+	logger      *slog.Logger
+	mu          sync.Mutex
+	cursor      string
+	subscribers map[*LogSubscription]struct{}
+}
+
+// LogSubscription receives a signal whenever a log matching its filter is published
+type LogSubscription struct {
+	filter *models.LogFilter
+	notify chan struct{}
+}
+
+// NewLogFeed creates a new log feed
+func NewLogFeed(logRepo logs.LogRepository, logger *slog.Logger) *LogFeed {
+	return &LogFeed{
+		logRepo:     logRepo,
+		interval:    constants.LogFeedPollInterval,
+		logger:      logger,
+		subscribers: make(map[*LogSubscription]struct{}),
+	}
+}
+
+// Start follows the log store until the context is cancelled
+func (f *LogFeed) Start(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	f.logger.Info("Log feed started", "interval", f.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			f.logger.Info("Log feed stopped")
+			return
+		case <-ticker.C:
+			if err := f.poll(ctx); err != nil {
+				f.logger.Error("Failed to poll new logs", "error", err)
+			}
+		}
+	}
+}
+
+// Cursor returns the position of the most recently published log
+func (f *LogFeed) Cursor(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	cursor := f.cursor
+	f.mu.Unlock()
+	if cursor != "" {
+		return cursor, nil
+	}
+	return f.logRepo.GetLogCursor(ctx)
+}
+
+// Subscribe registers a subscription for logs matching the filter
+func (f *LogFeed) Subscribe(filter *models.LogFilter) *LogSubscription {
+	sub := &LogSubscription{filter: filter, notify: make(chan struct{}, 1)}
+	f.mu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscription
+func (f *LogFeed) Unsubscribe(sub *LogSubscription) {
+	f.mu.Lock()
+	delete(f.subscribers, sub)
+	f.mu.Unlock()
+}
+
+// Notify returns the channel signalled when matching logs are published
+func (s *LogSubscription) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// poll fetches logs stored since the last poll and signals matching subscribers
+func (f *LogFeed) poll(ctx context.Context) error {
+	f.mu.Lock()
+	cursor := f.cursor
+	f.mu.Unlock()
+
+	if cursor == "" {
+		head, err := f.logRepo.GetLogCursor(ctx)
+		if err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.cursor = head
+		f.mu.Unlock()
+		return nil
+	}
+
+	for {
+		newLogs, next, err := f.logRepo.GetLogsAfterCursor(ctx, &models.LogFilter{Limit: constants.LogFeedBatchSize}, cursor)
+		if err != nil {
+			return err
+		}
+		cursor = next
+		f.publish(newLogs, cursor)
+
+		if len(newLogs) < constants.LogFeedBatchSize {
+			return nil
+		}
+	}
+}
+
+// publish advances the feed cursor and signals subscribers with a matching log
+func (f *LogFeed) publish(newLogs []*models.Log, cursor string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cursor = cursor
+	for sub := range f.subscribers {
+		for _, log := range newLogs {
+			if matchesLogFilter(sub.filter, log) {
+				select {
+				case sub.notify <- struct{}{}:
+				default:
+				}
+				break
+			}
+		}
+	}
+}
+
+// matchesLogFilter reports whether a log may match the filter.
+// Full-text search is not evaluated here; the subscriber re-queries the store to apply it.
+func matchesLogFilter(filter *models.LogFilter, log *models.Log) bool {
+	if filter.Level != nil && *filter.Level != log.Level {
+		return false
+	}
+	if filter.Service != nil && *filter.Service != log.Service {
+		return false
+	}
+	if filter.Tenant != nil && (log.Tenant == nil || *filter.Tenant != *log.Tenant) {
+		return false
+	}
+	if !matchesDimension(filter.Host, log.Host) || !matchesDimension(filter.Environment, log.Environment) ||
+		!matchesDimension(filter.Region, log.Region) || !matchesDimension(filter.ClientIP, log.ClientIP) {
+		return false
+	}
+	if filter.TraceID != nil && (log.TraceID == nil || *filter.TraceID != *log.TraceID) {
+		return false
+	}
+	if filter.SpanID != nil && (log.SpanID == nil || *filter.SpanID != *log.SpanID) {
+		return false
+	}
+	if filter.ParentSpanID != nil && (log.ParentSpanID == nil || *filter.ParentSpanID != *log.ParentSpanID) {
+		return false
+	}
+	if filter.UserID != nil && (log.UserID == nil || *filter.UserID != *log.UserID) {
+		return false
+	}
+	if filter.HasRequestBody != nil && *filter.HasRequestBody != (log.RequestBody != nil) {
+		return false
+	}
+	if filter.HasResponseBody != nil && *filter.HasResponseBody != (log.ResponseBody != nil) {
+		return false
+	}
+	for key, value := range filter.Attributes {
+		if actual, ok := log.Attributes[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesDimension reports whether a log's dimension value satisfies an optional filter value
+func matchesDimension(filter, value *string) bool {
+	return filter == nil || (value != nil && *filter == *value)
+}
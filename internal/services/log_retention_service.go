@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/retention"
+	"github.com/adeesh/log-analytics/internal/metrics"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"time"
+)
+
+// LogRetentionService periodically deletes or archives logs past the maximum age of their retention policy.
+// Logs are removed in batches with a pause in between, so that no purge holds locks on the log tables for long.
+type LogRetentionService struct {
+	logRepo     logs.LogRepository
+	repo        retention.RetentionRepository
+	maintenance *MaintenanceService
+	cfg         config.RetentionConfig
+	logger      *slog.Logger
+}
+
+// NewLogRetentionService creates a new log retention service
+func NewLogRetentionService(logRepo logs.LogRepository, repo retention.RetentionRepository, maintenance *MaintenanceService, cfg config.RetentionConfig, logger *slog.Logger) (*LogRetentionService, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &LogRetentionService{
+		logRepo:     logRepo,
+		repo:        repo,
+		maintenance: maintenance,
+		cfg:         cfg,
+		logger:      logger,
+	}, nil
+}
+
+// Purge applies every enabled retention policy. A policy that fails doesn't stop the others.
+// Nothing is removed while read-only mode is active.
+func (s *LogRetentionService) Purge(ctx context.Context) error {
+	if s.maintenance.IsReadOnly() {
+		s.logger.Debug("Skipping log retention in read-only mode")
+		return nil
+	}
+
+	policies, err := s.repo.GetPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, policy := range policies {
+		if !policy.Enabled {
+			continue
+		}
+		purge := policy.Purge(policies, now)
+		purged, err := s.purge(ctx, purge, policy.Action)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error("Failed to apply log retention policy", "error", err, "policy_id", policy.ID, "purged", purged)
+			continue
+		}
+		if err := s.repo.RecordPolicyRun(ctx, policy.ID, now, purged); err != nil {
+			s.logger.Error("Failed to record log retention policy run", "error", err, "policy_id", policy.ID)
+		}
+		if purged > 0 {
+			s.logger.Info("Applied log retention policy", "policy_id", policy.ID, "service", policy.Service,
+				"level", policy.Level, "action", policy.Action, "count", purged, "before", purge.Before)
+		}
+	}
+	return nil
+}
+
+// purge removes the logs of a purge in batches, pausing between them, and returns the number of removed logs
+func (s *LogRetentionService) purge(ctx context.Context, purge *models.LogPurge, action string) (int64, error) {
+	var total int64
+	for {
+		purged, err := s.logRepo.PurgeLogs(ctx, purge, s.cfg.BatchSize)
+		total += purged
+		metrics.LogsPurged.WithLabelValues(action).Add(float64(purged))
+		if err != nil {
+			return total, err
+		}
+		if purged < int64(s.cfg.BatchSize) {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(s.cfg.BatchPause):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LogStream fans stored logs out to live tail subscribers.
+// It is fed by the consumer of the stream topic the log processor publishes stored logs to.
+type LogStream struct {
+	mu          sync.RWMutex
+	subscribers map[*LogStreamSubscription]struct{}
+}
+
+// LogStreamSubscription receives the logs matching its filter. Logs are dropped, and counted,
+// while the subscriber's buffer is full so that a slow client never holds up the others.
+type LogStreamSubscription struct {
+	filter  *models.LogFilter
+	logs    chan *models.Log
+	dropped atomic.Int64
+}
+
+// NewLogStream creates a new log stream
+func NewLogStream() *LogStream {
+	return &LogStream{subscribers: make(map[*LogStreamSubscription]struct{})}
+}
+
+// Subscribe registers a subscription for logs matching the filter
+func (s *LogStream) Subscribe(filter *models.LogFilter) (*LogStreamSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.subscribers) >= constants.MaxLogStreamSubscribers {
+		return nil, apperrors.Unavailable("Too many live tail clients, try again later")
+	}
+	sub := &LogStreamSubscription{filter: filter, logs: make(chan *models.Log, constants.LogStreamSubscriberBuffer)}
+	s.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe removes a subscription
+func (s *LogStream) Unsubscribe(sub *LogStreamSubscription) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+}
+
+// Publish delivers a stored log to the subscribers whose filter it matches
+func (s *LogStream) Publish(log *models.Log) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for sub := range s.subscribers {
+		if !matchesLogFilter(sub.filter, log) || !matchesSearch(sub.filter.Search, log.Message) {
+			continue
+		}
+		select {
+		case sub.logs <- log:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Logs returns the channel the matching logs are delivered on
+func (s *LogStreamSubscription) Logs() <-chan *models.Log {
+	return s.logs
+}
+
+// Dropped returns the number of logs dropped since the last call
+func (s *LogStreamSubscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// matchesSearch evaluates a full-text search against a message in memory, following the boolean mode
+// search of the log store: words prefixed with + are required, words prefixed with - are excluded and,
+// without required words, at least one of the other words must appear. Words match case-insensitively
+// anywhere in the message, which is more lenient than the store's whole-word matching.
+func matchesSearch(search *string, message string) bool {
+	if search == nil {
+		return true
+	}
+	message = strings.ToLower(message)
+
+	required, matched, optional := false, false, false
+	for _, word := range strings.Fields(strings.ToLower(*search)) {
+		operator := word[0]
+		word = strings.Trim(word, `+-~<>()"*`)
+		if word == "" {
+			continue
+		}
+		found := strings.Contains(message, word)
+		switch operator {
+		case '+':
+			if !found {
+				return false
+			}
+			required = true
+		case '-':
+			if found {
+				return false
+			}
+		default:
+			optional = true
+			matched = matched || found
+		}
+	}
+	return required || matched || !optional
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/maintenance"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// MaintenanceService tracks the read-only switch and maintenance banner.
+// The state is persisted so the API server and the log processor share it; each process reloads it periodically.
+type MaintenanceService struct {
+	repo   maintenance.MaintenanceRepository
+	cfg    config.MaintenanceConfig
+	logger *slog.Logger
+	mu     sync.RWMutex
+	state  models.MaintenanceState
+}
+
+// NewMaintenanceService creates a new maintenance service
+func NewMaintenanceService(repo maintenance.MaintenanceRepository, cfg config.MaintenanceConfig, logger *slog.Logger) *MaintenanceService {
+	return &MaintenanceService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Start loads the maintenance state and keeps it up to date until the context is cancelled
+func (s *MaintenanceService) Start(ctx context.Context) {
+	ticker := time.NewTicker(constants.MaintenanceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			s.logger.Error("Failed to refresh maintenance state", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh reloads the persisted state. The last known state is kept when loading fails.
+func (s *MaintenanceService) Refresh(ctx context.Context) error {
+	state, err := s.repo.GetState(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	previous := s.state.ReadOnly
+	s.state = *state
+	s.mu.Unlock()
+
+	if previous != state.ReadOnly {
+		s.logger.Info("Maintenance state changed", "read_only", state.ReadOnly, "banner", state.Banner)
+	}
+	return nil
+}
+
+// Status returns the effective maintenance status
+func (s *MaintenanceService) Status() models.MaintenanceStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := models.MaintenanceStatus{
+		ReadOnly:  s.cfg.ReadOnly || s.state.ReadOnly,
+		Forced:    s.cfg.ReadOnly,
+		Banner:    s.state.Banner,
+		UpdatedAt: s.state.UpdatedAt,
+	}
+	if status.Banner == "" {
+		status.Banner = s.cfg.Banner
+	}
+	return status
+}
+
+// IsReadOnly reports whether writes are currently rejected
+func (s *MaintenanceService) IsReadOnly() bool {
+	return s.Status().ReadOnly
+}
+
+// Update changes the persisted read-only switch and banner
+func (s *MaintenanceService) Update(ctx context.Context, update *models.MaintenanceUpdate) (*models.MaintenanceStatus, error) {
+	if s.cfg.ReadOnly && !*update.ReadOnly {
+		return nil, apperrors.Conflict("Read-only mode is enforced by configuration")
+	}
+
+	s.mu.RLock()
+	state := s.state
+	s.mu.RUnlock()
+
+	state.ReadOnly = *update.ReadOnly
+	if update.Banner != nil {
+		state.Banner = *update.Banner
+	}
+	if err := s.repo.SaveState(ctx, &state); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+
+	s.logger.Info("Maintenance state updated", "read_only", state.ReadOnly, "banner", state.Banner)
+	status := s.Status()
+	return &status, nil
+}
+
+// WaitWritable blocks while read-only mode is active, returning early if the context is cancelled
+func (s *MaintenanceService) WaitWritable(ctx context.Context) error {
+	if !s.IsReadOnly() {
+		return nil
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for s.IsReadOnly() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
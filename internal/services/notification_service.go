@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/apperrors"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/database/notifications"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/notifiers"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NotificationService delivers fired alerts to the notification channels bound to their rule.
+// Deliveries run in the background and are retried with exponential backoff. They outlive the evaluation that
+// fired the alert, and are only cancelled when the service is shut down.
+type NotificationService struct {
+	repo   notifications.NotificationRepository
+	cfg    config.NotificationConfig
+	client *http.Client
+	logger *slog.Logger
+	wg     sync.WaitGroup
+
+	// Deliveries in progress
+	deliveries context.Context
+	abandon    context.CancelFunc
+	inFlight   atomic.Int64
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(repo notifications.NotificationRepository, cfg config.NotificationConfig, logger *slog.Logger) (*NotificationService, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid notification configuration: %w", err)
+	}
+	deliveries, abandon := context.WithCancel(context.Background())
+	return &NotificationService{
+		repo:       repo,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+		deliveries: deliveries,
+		abandon:    abandon,
+	}, nil
+}
+
+// ValidateChannel checks a channel's settings and that it can be delivered to with the current configuration
+func (s *NotificationService) ValidateChannel(channel *models.NotificationChannel) error {
+	if err := channel.Validate(); err != nil {
+		return apperrors.Validation("%s", err.Error())
+	}
+	if _, err := notifiers.New(channel, &s.cfg, s.client); err != nil {
+		return apperrors.Validation("%s", err.Error())
+	}
+	return nil
+}
+
+// Notify starts delivering a fired alert, with the report of its window when available, to the enabled
+// channels of its rule. Delivery continues in the background until it succeeds, attempts run out or the
+// service is shut down.
+func (s *NotificationService) Notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert, report *models.AlertReport) {
+	channels, err := s.repo.GetRuleChannels(ctx, rule.ID)
+	if err != nil {
+		s.logger.Error("Failed to get alert rule channels", "error", err, "rule_id", rule.ID, "alert_id", alert.ID)
+		return
+	}
+
+	s.send(channels, &models.AlertNotification{
+		AlertID:      alert.ID,
+		RuleID:       rule.ID,
+		RuleName:     rule.Name,
+		Severity:     string(alert.Severity),
+		Message:      alert.Message,
+		Value:        alert.Value,
+		Threshold:    rule.Threshold,
+		LateDetected: alert.LateDetected,
+		CreatedAt:    alert.CreatedAt,
+		Reminder:     alert.NotificationCount,
+		Report:       report,
+	})
+}
+
+// NotifyAll starts delivering a notification that isn't tied to a rule, such as the alert checker's
+// meta-alert, to every enabled channel
+func (s *NotificationService) NotifyAll(ctx context.Context, notification *models.AlertNotification) {
+	channels, err := s.repo.GetChannels(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get notification channels", "error", err, "rule_name", notification.RuleName)
+		return
+	}
+	s.send(channels, notification)
+}
+
+// send delivers a notification to the enabled channels in the background
+func (s *NotificationService) send(channels []models.NotificationChannel, notification *models.AlertNotification) {
+	for _, channel := range channels {
+		if !channel.Enabled {
+			continue
+		}
+		notifier, err := notifiers.New(&channel, &s.cfg, s.client)
+		if err != nil {
+			s.logger.Error("Failed to create notifier", "error", err, "channel", channel.Name, "alert_id", notification.AlertID)
+			continue
+		}
+
+		s.wg.Add(1)
+		s.inFlight.Add(1)
+		go func(channel models.NotificationChannel) {
+			defer s.wg.Done()
+			defer s.inFlight.Add(-1)
+			s.deliver(s.deliveries, &channel, notifier, notification)
+		}(channel)
+	}
+}
+
+// Test sends a single test notification to a channel, returning the delivery error if any
+func (s *NotificationService) Test(ctx context.Context, channel *models.NotificationChannel) error {
+	notifier, err := notifiers.New(channel, &s.cfg, s.client)
+	if err != nil {
+		return apperrors.Validation("%s", err.Error())
+	}
+	return notifier.Send(ctx, &models.AlertNotification{
+		RuleName:  "Test",
+		Severity:  "low",
+		Message:   fmt.Sprintf("Test notification for channel '%s'", channel.Name),
+		CreatedAt: time.Now(),
+		Test:      true,
+	})
+}
+
+// Shutdown waits for deliveries in progress, including their retries, to finish. Deliveries still in progress when
+// the context is done are abandoned, and reported in the returned error.
+func (s *NotificationService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	pending := s.inFlight.Load()
+	s.abandon()
+	<-done
+	return fmt.Errorf("%d notification deliveries abandoned: %w", pending, ctx.Err())
+}
+
+// deliver sends a notification, retrying failed attempts with exponential backoff
+func (s *NotificationService) deliver(ctx context.Context, channel *models.NotificationChannel, notifier notifiers.Notifier, notification *models.AlertNotification) {
+	backoff := s.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := notifier.Send(ctx, notification)
+		if err == nil {
+			s.logger.Info("Alert notification sent", "channel", channel.Name, "type", channel.Type, "alert_id", notification.AlertID, "attempt", attempt)
+			return
+		}
+		if attempt >= s.cfg.MaxAttempts {
+			s.logger.Error("Failed to send alert notification, giving up",
+				"error", err, "channel", channel.Name, "alert_id", notification.AlertID, "attempts", attempt)
+			return
+		}
+		s.logger.Warn("Failed to send alert notification, retrying",
+			"error", err, "channel", channel.Name, "alert_id", notification.AlertID, "attempt", attempt, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Warn("Alert notification abandoned on shutdown", "channel", channel.Name, "alert_id", notification.AlertID)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, s.cfg.MaxBackoff)
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/scheduler"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scheduler runs periodic tasks on a single API server at a time. Every server polls each task; the first to
+// find it due takes its lease in the database and runs it, renewing the lease until the run ends. A server that
+// dies mid-run stops renewing, and another takes over once the lease expires. The outcome of every run is kept
+// as run history.
+type Scheduler struct {
+	repo   scheduler.SchedulerRepository
+	cfg    config.SchedulerConfig
+	owner  string
+	tasks  []scheduledTask
+	logger *slog.Logger
+}
+
+// scheduledTask is a registered task
+type scheduledTask struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// NewScheduler creates a new scheduler, identifying this server by its hostname and a random suffix
+func NewScheduler(repo scheduler.SchedulerRepository, cfg config.SchedulerConfig, logger *slog.Logger) *Scheduler {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &Scheduler{
+		repo:   repo,
+		cfg:    cfg,
+		owner:  fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8]),
+		logger: logger,
+	}
+}
+
+// Register adds a task run every interval. Tasks must be registered before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, scheduledTask{name: name, interval: interval, run: run})
+}
+
+// RegisterWorker adds a long-running worker, run by a single server at a time: the first server to find it unleased
+// runs it until shutdown, renewing its lease meanwhile, and another takes over once the lease expires or is released.
+// Workers must be registered before Start.
+func (s *Scheduler) RegisterWorker(name string, run func(ctx context.Context)) {
+	s.Register(name, s.cfg.PollInterval, func(ctx context.Context) error {
+		run(ctx)
+		return nil
+	})
+}
+
+// Start runs the registered tasks as they come due until the context is cancelled, and prunes the run history
+func (s *Scheduler) Start(ctx context.Context) {
+	s.Register(constants.TaskSchedulerHistory, constants.SchedulerHistoryInterval, s.pruneRuns)
+	s.logger.Info("Scheduler started", "owner", s.owner, "tasks", len(s.tasks))
+
+	var wg sync.WaitGroup
+	for _, task := range s.tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.poll(ctx, task)
+		}()
+	}
+	wg.Wait()
+}
+
+// poll checks whether the task is due at most every poll interval, with jitter so that servers started together
+// don't all race for the lease at the same moment
+func (s *Scheduler) poll(ctx context.Context, task scheduledTask) {
+	interval := min(task.interval, s.cfg.PollInterval)
+	registered := false
+	for {
+		// The task is registered on the first poll the database is reachable
+		if !registered {
+			if err := s.repo.EnsureTask(ctx, task.name, task.interval); err != nil {
+				if ctx.Err() == nil {
+					s.logger.Error("Failed to register scheduled task", "task", task.name, "error", err)
+				}
+			} else {
+				registered = true
+			}
+		}
+		if registered {
+			s.runIfDue(ctx, task)
+		}
+
+		jitter := time.Duration(rand.Float64() * constants.SchedulerJitter * float64(interval))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval + jitter):
+		}
+	}
+}
+
+// runIfDue runs the task if this server acquires its lease
+func (s *Scheduler) runIfDue(ctx context.Context, task scheduledTask) {
+	now := time.Now()
+	run, err := s.repo.AcquireLease(ctx, task.name, s.owner, task.interval, now, now.Add(s.cfg.LeaseDuration))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to acquire scheduled task lease", "task", task.name, "error", err)
+		}
+		return
+	}
+	if run == nil {
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renew(runCtx, task, cancel)
+	}()
+
+	s.logger.Debug("Running scheduled task", "task", task.name)
+	runErr := task.run(runCtx)
+	cancel()
+	<-renewed
+
+	finished := time.Now()
+	duration := finished.Sub(run.StartedAt).Milliseconds()
+	run.FinishedAt = &finished
+	run.DurationMs = &duration
+	run.Status = constants.TaskRunSucceeded
+	if runErr != nil {
+		run.Status = constants.TaskRunFailed
+		run.Error = runErr.Error()
+		if len(run.Error) > constants.MaxTaskRunErrorLength {
+			run.Error = run.Error[:constants.MaxTaskRunErrorLength]
+		}
+		s.logger.Error("Scheduled task failed", "task", task.name, "duration", finished.Sub(run.StartedAt), "error", runErr)
+	} else {
+		s.logger.Debug("Scheduled task finished", "task", task.name, "duration", finished.Sub(run.StartedAt))
+	}
+
+	// The outcome is recorded even when shutdown cancelled the run
+	if err := s.repo.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.Error("Failed to record scheduled task run", "task", task.name, "error", err)
+	}
+}
+
+// renew extends the lease every third of its duration while the task runs. If the lease is lost, another server
+// may have taken over, so the run is cancelled.
+func (s *Scheduler) renew(ctx context.Context, task scheduledTask, cancel context.CancelFunc) {
+	ticker := time.NewTicker(s.cfg.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := s.repo.RenewLease(ctx, task.name, s.owner, time.Now().Add(s.cfg.LeaseDuration))
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("Failed to renew scheduled task lease", "task", task.name, "error", err)
+			continue
+		}
+		if !held {
+			s.logger.Warn("Lost scheduled task lease, cancelling the run", "task", task.name)
+			cancel()
+			return
+		}
+	}
+}
+
+// pruneRuns deletes run history past the retention
+func (s *Scheduler) pruneRuns(ctx context.Context) error {
+	deleted, err := s.repo.PruneRuns(ctx, time.Now().Add(-constants.SchedulerRunRetention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Info("Pruned scheduled task runs", "deleted", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ShutdownCoordinator stops the components of a server in ordered stages, e.g. draining HTTP requests before the
+// background workers whose state they read, and closing the database last. The components of a stage stop
+// concurrently, each within its own deadline, and the next stage starts once all of them are done or out of time.
+// The whole shutdown is bounded by an overall timeout, after which the remaining components get no time at all.
+type ShutdownCoordinator struct {
+	stages     []string
+	timeout    time.Duration
+	logger     *slog.Logger
+	mu         sync.Mutex
+	components map[string][]shutdownComponent
+}
+
+// shutdownComponent is a registered component
+type shutdownComponent struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// ShutdownResult is the outcome of stopping a component, as reported once the shutdown is over
+type ShutdownResult struct {
+	Stage      string `json:"stage"`
+	Component  string `json:"component"`
+	DurationMs int64  `json:"duration_ms"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewShutdownCoordinator creates a coordinator running the given stages in order within the overall timeout
+func NewShutdownCoordinator(stages []string, timeout time.Duration, logger *slog.Logger) *ShutdownCoordinator {
+	return &ShutdownCoordinator{
+		stages:     stages,
+		timeout:    timeout,
+		logger:     logger,
+		components: make(map[string][]shutdownComponent),
+	}
+}
+
+// Register adds a component stopped in the given stage. Its stop function should return once the context is done;
+// if it doesn't, the shutdown moves on without it and reports it as timed out.
+func (s *ShutdownCoordinator) Register(stage, name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	if !slices.Contains(s.stages, stage) {
+		panic(fmt.Sprintf("unknown shutdown stage %q", stage))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components[stage] = append(s.components[stage], shutdownComponent{name: name, timeout: timeout, stop: stop})
+}
+
+// Go runs a background worker until the given stage, which cancels the worker's context and waits for it to return
+func (s *ShutdownCoordinator) Go(stage, name string, timeout time.Duration, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+
+	s.Register(stage, name, timeout, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Shutdown runs the stages in order and logs a report of every component's outcome. It returns the results,
+// with an error when any component failed or ran out of time.
+func (s *ShutdownCoordinator) Shutdown() ([]ShutdownResult, error) {
+	started := time.Now()
+	overall, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var results []ShutdownResult
+	for _, stage := range s.stages {
+		s.mu.Lock()
+		components := s.components[stage]
+		s.mu.Unlock()
+		if len(components) == 0 {
+			continue
+		}
+
+		s.logger.Info("Shutdown stage started", "stage", stage, "components", len(components))
+		stageResults := make([]ShutdownResult, len(components))
+		var wg sync.WaitGroup
+		for i, component := range components {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stageResults[i] = s.stop(overall, stage, component)
+			}()
+		}
+		wg.Wait()
+		results = append(results, stageResults...)
+	}
+
+	var failed []string
+	for _, result := range results {
+		if result.TimedOut || result.Error != "" {
+			failed = append(failed, result.Stage+"/"+result.Component)
+		}
+	}
+	level := slog.LevelInfo
+	if len(failed) > 0 {
+		level = slog.LevelWarn
+	}
+	s.logger.Log(context.Background(), level, "Shutdown report",
+		"duration", time.Since(started), "components", len(results), "failed", failed, "results", results)
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d of %d components didn't stop cleanly", len(failed), len(results))
+	}
+	return results, nil
+}
+
+// stop stops a component within its deadline, bounded by the overall one. A stop function ignoring its context
+// is left running.
+func (s *ShutdownCoordinator) stop(overall context.Context, stage string, component shutdownComponent) ShutdownResult {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(overall, component.timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- component.stop(ctx)
+	}()
+
+	// Components that give up at the deadline get a moment to report what they left undone
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		select {
+		case err = <-errCh:
+		case <-time.After(constants.ShutdownGracePeriod):
+			err = ctx.Err()
+		}
+	}
+
+	result := ShutdownResult{Stage: stage, Component: component.name, DurationMs: time.Since(started).Milliseconds()}
+	if err == nil {
+		return result
+	}
+	result.TimedOut = errors.Is(err, context.DeadlineExceeded)
+	if err != ctx.Err() {
+		result.Error = err.Error()
+	}
+	if result.TimedOut {
+		s.logger.Warn("Component didn't stop in time", "stage", stage, "component", component.name, "timeout", component.timeout, "error", err)
+	} else {
+		s.logger.Error("Failed to stop component", "stage", stage, "component", component.name, "error", err)
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"time"
+)
+
+// statusSeverity ranks component statuses, the overall status being the most severe
+var statusSeverity = map[string]int{
+	constants.StatusOperational: 0,
+	constants.StatusMaintenance: 1,
+	constants.StatusDegraded:    2,
+	constants.StatusOutage:      3,
+}
+
+// StatusService summarizes the health of the system for the public status page. Summaries are cached for the
+// cache TTL, so that anonymous callers can't load the database however often they ask.
+type StatusService struct {
+	db          *database.GormDB
+	logRepo     logs.LogRepository
+	dashboard   *DashboardCache
+	maintenance *MaintenanceService
+	alerts      *AlertService
+	cfg         config.StatusPageConfig
+	startedAt   time.Time
+	page        cachedQuery[models.StatusPage]
+	logger      *slog.Logger
+}
+
+// NewStatusService creates a new status service, counting the uptime from now
+func NewStatusService(db *database.GormDB, logRepo logs.LogRepository, dashboard *DashboardCache, maintenance *MaintenanceService, alerts *AlertService, cfg config.StatusPageConfig, logger *slog.Logger) *StatusService {
+	return &StatusService{
+		db:          db,
+		logRepo:     logRepo,
+		dashboard:   dashboard,
+		maintenance: maintenance,
+		alerts:      alerts,
+		cfg:         cfg,
+		startedAt:   time.Now(),
+		logger:      logger,
+	}
+}
+
+// CacheTTL is how long a summary is served before it is computed again
+func (s *StatusService) CacheTTL() time.Duration {
+	return s.cfg.CacheTTL
+}
+
+// Status returns the health summary, at most the cache TTL old apart from the uptime
+func (s *StatusService) Status(ctx context.Context) models.StatusPage {
+	page, _ := s.page.get(ctx, s.cfg.CacheTTL, func(ctx context.Context) (models.StatusPage, error) {
+		return s.summarize(ctx), nil
+	})
+	page.UptimeSeconds = int64(time.Since(s.startedAt).Seconds())
+	return page
+}
+
+// summarize checks every component. Failures only show as statuses; their errors are logged, not published.
+func (s *StatusService) summarize(ctx context.Context) models.StatusPage {
+	ctx, cancel := context.WithTimeout(ctx, constants.StatusPageTimeout)
+	defer cancel()
+
+	databaseStatus := constants.StatusOperational
+	if err := s.db.Ping(ctx); err != nil {
+		s.logger.Warn("Status page: database unreachable", "error", err)
+		databaseStatus = constants.StatusOutage
+	}
+
+	alertingStatus := constants.StatusOperational
+	if s.alerts.CheckerHealth().Status == constants.AlertCheckerFailing {
+		alertingStatus = constants.StatusDegraded
+	}
+
+	page := models.StatusPage{
+		Status: constants.StatusOperational,
+		Components: []models.ComponentStatus{
+			{Name: constants.StatusComponentAPI, Status: constants.StatusOperational},
+			{Name: constants.StatusComponentDatabase, Status: databaseStatus},
+			{Name: constants.StatusComponentIngestion, Status: s.ingestionStatus(ctx)},
+			{Name: constants.StatusComponentAlerting, Status: alertingStatus},
+		},
+		StartedAt: s.startedAt,
+		UpdatedAt: time.Now(),
+	}
+	for _, component := range page.Components {
+		if statusSeverity[component.Status] > statusSeverity[page.Status] {
+			page.Status = component.Status
+		}
+	}
+
+	if active, err := s.dashboard.ActiveAlerts(ctx); err != nil {
+		s.logger.Warn("Status page: failed to count active alerts", "error", err)
+	} else {
+		incidents := len(active)
+		page.Incidents = &incidents
+	}
+	return page
+}
+
+// ingestionStatus reports ingestion as paused during maintenance, and as degraded once no log was stored for
+// longer than the stale threshold
+func (s *StatusService) ingestionStatus(ctx context.Context) string {
+	if s.maintenance.Status().ReadOnly {
+		return constants.StatusMaintenance
+	}
+	if s.cfg.StaleAfter <= 0 {
+		return constants.StatusOperational
+	}
+	newest, err := s.logRepo.GetLogs(ctx, &models.LogFilter{Limit: 1})
+	if err != nil {
+		s.logger.Warn("Status page: failed to read the newest log", "error", err)
+		return constants.StatusOutage
+	}
+	if len(newest) == 0 || time.Since(newest[0].CreatedAt) > s.cfg.StaleAfter {
+		return constants.StatusDegraded
+	}
+	return constants.StatusOperational
+}
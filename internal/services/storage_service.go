@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/storage"
+	"github.com/adeesh/log-analytics/internal/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// StorageService computes storage usage statistics and capacity projections
+type StorageService struct {
+	storageRepo storage.StorageRepository
+	logStorage  logs.LogStorage
+	cfg         config.StorageConfig
+}
+
+// NewStorageService creates a new storage service
+func NewStorageService(storageRepo storage.StorageRepository, logStorage logs.LogStorage, cfg config.StorageConfig) *StorageService {
+	return &StorageService{
+		storageRepo: storageRepo,
+		logStorage:  logStorage,
+		cfg:         cfg,
+	}
+}
+
+// GetStorageStats computes table sizes, daily growth, per-service share and the projected disk exhaustion date
+func (s *StorageService) GetStorageStats(ctx context.Context) (*models.StorageStats, error) {
+	now := time.Now()
+	stats := &models.StorageStats{GeneratedAt: now}
+
+	tables, err := s.storageRepo.GetTableStorage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table storage: %w", err)
+	}
+	logTables, err := s.logStorage.GetLogTableStorage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log table storage: %w", err)
+	}
+	// Log tables of the primary database are already listed; those of shards and ClickHouse are added
+	var logBytes, logRows int64
+	for _, table := range logTables {
+		logBytes += table.TotalBytes
+		logRows += table.Rows
+		if table.Store != "" {
+			tables = append(tables, table)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].TotalBytes > tables[j].TotalBytes })
+	stats.Tables = tables
+
+	// Average row size across the log tables is used to estimate bytes from row counts
+	var logRowSize int64
+	if logRows > 0 {
+		logRowSize = logBytes / logRows
+	}
+	for _, table := range tables {
+		stats.TotalDataBytes += table.DataBytes
+		stats.TotalIndexBytes += table.IndexBytes
+	}
+	stats.TotalBytes = stats.TotalDataBytes + stats.TotalIndexBytes
+
+	windowDays := s.cfg.GrowthWindowDays
+	if windowDays <= 0 {
+		windowDays = constants.DefaultStorageGrowthWindowDays
+	}
+
+	// Growth is measured over complete days only, using local day boundaries to match the
+	// connection's loc=Local setting and therefore DATE(created_at) on the database side
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	since := todayStart.AddDate(0, 0, -windowDays)
+
+	growth, err := s.logStorage.GetDailyLogGrowth(ctx, since, todayStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily growth: %w", err)
+	}
+	var growthBytes int64
+	for i := range growth {
+		growth[i].EstimatedBytes = growth[i].Rows * logRowSize
+		growthBytes += growth[i].EstimatedBytes
+	}
+	stats.DailyGrowth = growth
+
+	// Average over the days actually observed so young datasets are not understated
+	if len(growth) > 0 {
+		firstDay := growth[0].Day
+		firstDay = time.Date(firstDay.Year(), firstDay.Month(), firstDay.Day(), 0, 0, 0, 0, time.Local)
+		observedDays := int(math.Round(todayStart.Sub(firstDay).Hours() / 24))
+		if observedDays < 1 {
+			observedDays = 1
+		}
+		if observedDays > windowDays {
+			observedDays = windowDays
+		}
+		stats.AvgDailyGrowthBytes = float64(growthBytes) / float64(observedDays)
+	}
+
+	shares, err := s.logStorage.GetServiceLogCounts(ctx, since, todayStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service share: %w", err)
+	}
+	var totalRows int64
+	for _, share := range shares {
+		totalRows += share.Rows
+	}
+	for i := range shares {
+		shares[i].EstimatedBytes = shares[i].Rows * logRowSize
+		if totalRows > 0 {
+			shares[i].SharePercent = float64(shares[i].Rows) / float64(totalRows) * 100
+		}
+	}
+	stats.ServiceShare = shares
+
+	// Project when the configured disk capacity will be exhausted at the current growth rate
+	if s.cfg.DiskCapacityGB > 0 {
+		stats.DiskCapacityBytes = int64(s.cfg.DiskCapacityGB) * constants.BytesPerGB
+		if stats.AvgDailyGrowthBytes > 0 {
+			remaining := float64(stats.DiskCapacityBytes - stats.TotalBytes)
+			days := remaining / stats.AvgDailyGrowthBytes
+			if days < 0 {
+				days = 0
+			}
+			// Projections beyond the horizon are meaningless and would overflow date arithmetic
+			if days <= constants.MaxStorageProjectionDays {
+				projected := now.AddDate(0, 0, int(math.Ceil(days)))
+				stats.ProjectedFullAt = &projected
+			}
+		}
+	}
+
+	return stats, nil
+}
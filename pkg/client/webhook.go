@@ -0,0 +1,92 @@
+// Package client holds helpers for applications receiving from log analytics, such as the verification of the
+// signatures of webhook notifications.
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of webhook notifications sent by channels with a secret
+const (
+	TimestampHeader = constants.WebhookTimestampHeader
+	SignatureHeader = constants.WebhookSignatureHeader
+)
+
+// Errors returned by Verify
+var (
+	ErrMissingSignature = errors.New("webhook signature or timestamp missing")
+	ErrInvalidSignature = errors.New("webhook signature invalid")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature of a webhook payload signed at the timestamp: "sha256=" followed by the hex encoded
+// HMAC-SHA256, keyed with the channel's secret, of the Unix timestamp in seconds, a dot and the payload
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return constants.WebhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier authenticates the webhook notifications of a channel. Signing the timestamp along with the payload
+// stops a captured notification from being replayed once the timestamp is outside the tolerance.
+type Verifier struct {
+	secret    string
+	tolerance time.Duration
+}
+
+// NewVerifier creates a verifier for the secret of a channel, accepting timestamps up to the tolerance away from
+// the receiver's clock; zero uses the default of 5 minutes
+func NewVerifier(secret string, tolerance time.Duration) *Verifier {
+	if tolerance <= 0 {
+		tolerance = constants.DefaultWebhookSignatureTolerance
+	}
+	return &Verifier{secret: secret, tolerance: tolerance}
+}
+
+// Verify checks the timestamp and signature headers of a notification against its payload
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	signature := header.Get(SignatureHeader)
+	rawTimestamp := header.Get(TimestampHeader)
+	if signature == "" || rawTimestamp == "" {
+		return ErrMissingSignature
+	}
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, rawTimestamp)
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)).Abs(); skew > v.tolerance {
+		return fmt.Errorf("%w: signed %s away", ErrStaleTimestamp, skew.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(v.secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequest reads the payload of a notification request and verifies it. The payload is returned, and the
+// request body replaced so that it can be read again.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook payload: %w", err)
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := v.Verify(r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
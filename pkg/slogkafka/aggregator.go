@@ -0,0 +1,76 @@
+package slogkafka
+
+import (
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// aggregator counts repeats of identical messages so that a tight loop logging the same error produces a summary
+// per window instead of a log per iteration
+type aggregator struct {
+	mu      sync.Mutex
+	entries map[string]*aggregate
+	maxKeys int
+	repeats atomic.Int64
+}
+
+// aggregate tracks the repeats of a message since the last summary
+type aggregate struct {
+	log       *models.Log // the last repeat, sent as the summary
+	count     int64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+func newAggregator(maxKeys int) *aggregator {
+	return &aggregator{entries: make(map[string]*aggregate), maxKeys: maxKeys}
+}
+
+// observe records a log, reporting whether it should be sent. The first occurrence of a message is sent, and
+// so are messages beyond the tracked maximum; repeats are counted for the summary instead.
+func (a *aggregator) observe(key string, log *models.Log) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.entries[key]
+	if !ok {
+		if len(a.entries) < a.maxKeys {
+			a.entries[key] = &aggregate{}
+		}
+		return true
+	}
+	if entry.count == 0 {
+		entry.firstSeen = log.Timestamp
+	}
+	entry.log = log
+	entry.count++
+	entry.lastSeen = log.Timestamp
+	a.repeats.Add(1)
+	return false
+}
+
+// flush returns the summaries of the messages repeated since the last flush. Messages that weren't repeated stop
+// being tracked, so their next occurrence is sent right away; repeated ones stay tracked for another window.
+func (a *aggregator) flush() []*models.Log {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var summaries []*models.Log
+	for key, entry := range a.entries {
+		if entry.count == 0 {
+			delete(a.entries, key)
+			continue
+		}
+		summary := entry.log
+		summary.Attributes.Set(constants.SlogKafkaAttributeCount, strconv.FormatInt(entry.count, 10))
+		summary.Attributes.Set(constants.SlogKafkaAttributeFirstSeen, entry.firstSeen.Format(time.RFC3339Nano))
+		summary.Attributes.Set(constants.SlogKafkaAttributeLastSeen, entry.lastSeen.Format(time.RFC3339Nano))
+		summaries = append(summaries, summary)
+		a.entries[key] = &aggregate{}
+	}
+	return summaries
+}
@@ -0,0 +1,59 @@
+package slogkafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Fanout is a slog.Handler passing every record to several handlers, e.g. to keep writing logs to stdout while
+// publishing them to Kafka
+type Fanout struct {
+	handlers []slog.Handler
+}
+
+// NewFanout creates a handler passing records to the given handlers, each of which filters them by its own level
+func NewFanout(handlers ...slog.Handler) *Fanout {
+	return &Fanout{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers handles logs of the level
+func (f *Fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range f.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to the handlers enabled for its level, returning their errors joined
+func (f *Fanout) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range f.handlers {
+		if handler.Enabled(ctx, record.Level) {
+			if err := handler.Handle(ctx, record.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a fanout of the handlers with the attributes added
+func (f *Fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := make([]slog.Handler, len(f.handlers))
+	for i, handler := range f.handlers {
+		derived[i] = handler.WithAttrs(attrs)
+	}
+	return &Fanout{handlers: derived}
+}
+
+// WithGroup returns a fanout of the handlers with the group opened
+func (f *Fanout) WithGroup(name string) slog.Handler {
+	derived := make([]slog.Handler, len(f.handlers))
+	for i, handler := range f.handlers {
+		derived[i] = handler.WithGroup(name)
+	}
+	return &Fanout{handlers: derived}
+}
@@ -0,0 +1,411 @@
+// Package slogkafka is a log/slog handler that publishes application logs to the log analytics Kafka topic in the
+// native log JSON shape, for services logging straight to Kafka instead of through the log collector.
+package slogkafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/kafka/serde"
+	"github.com/adeesh/log-analytics/internal/models"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+)
+
+// LevelFatal is the slog level of FATAL logs; levels from LevelFatal up are sent as FATAL
+const LevelFatal = slog.LevelError + 4
+
+// Options configure a Handler
+type Options struct {
+	Service       string       // service the logs are stored under; required
+	Topic         string       // defaults to the logs topic
+	PriorityTopic string       // ERROR and FATAL logs are sent here when set
+	Level         slog.Leveler // minimum level sent; defaults to INFO
+	Host          string
+	Environment   string
+	Tenant        string // registered tenant the logs are stored under, sent in the tenant header
+
+	// AggregateWindow is how long identical messages (same level, message and handler attributes) are summarized
+	// after the first one is sent: repeats are counted instead of sent, and a summary with their count and first
+	// and last timestamps is sent at the end of every window in which the message was repeated. Defaults to 1s;
+	// a negative window disables aggregation.
+	AggregateWindow  time.Duration
+	MaxAggregateKeys int // distinct messages tracked at once; defaults to 1000
+	BufferSize       int // messages awaiting delivery before more are dropped; defaults to 1000
+}
+
+// Handler is a slog.Handler publishing logs to Kafka. Logging never blocks on Kafka: messages that don't fit the
+// producer's buffer are dropped and counted. Handlers derived with WithAttrs and WithGroup share the producer.
+type Handler struct {
+	core   *core
+	attrs  []attr
+	prefix string // qualifies the keys of attributes added under groups
+	key    string // identifies the handler's attributes in aggregation keys
+}
+
+// attr is an attribute flattened to a qualified key and its string value
+type attr struct {
+	key   string
+	value string
+}
+
+// core is the state shared by a handler and the handlers derived from it
+type core struct {
+	producer  sarama.AsyncProducer
+	opts      Options
+	level     slog.Leveler
+	aggregate *aggregator // nil when aggregation is disabled
+	stop      chan struct{}
+	flusher   sync.WaitGroup
+	errors    sync.WaitGroup
+	mu        sync.RWMutex // held for writing while the producer is closed
+	closed    bool
+	closeOnce sync.Once
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// Stats counts the messages the handler didn't deliver or summarized
+type Stats struct {
+	Dropped    int64 // dropped because the producer's buffer was full
+	Failed     int64 // rejected by Kafka
+	Aggregated int64 // repeats of identical messages sent as summaries instead
+}
+
+// New creates a handler publishing to the given brokers. Close it before exiting so pending summaries and
+// buffered messages are sent.
+func New(brokers []string, opts Options) (*Handler, error) {
+	if opts.Service == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+	if opts.Topic == "" {
+		opts.Topic = constants.DefaultKafkaTopic
+	}
+	if opts.AggregateWindow == 0 {
+		opts.AggregateWindow = constants.SlogKafkaDefaultAggregateWindow
+	}
+	if opts.MaxAggregateKeys <= 0 {
+		opts.MaxAggregateKeys = constants.SlogKafkaDefaultMaxAggregateKeys
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = constants.SlogKafkaDefaultBufferSize
+	}
+
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Retry.Max = constants.DefaultProducerRetryMax
+	config.Producer.Compression = sarama.CompressionSnappy
+	config.Producer.Flush.Frequency = constants.DefaultProducerFlushFrequency
+	config.Producer.Return.Errors = true
+	config.ChannelBufferSize = opts.BufferSize
+
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+
+	c := &core{
+		producer: producer,
+		opts:     opts,
+		level:    opts.Level,
+		stop:     make(chan struct{}),
+	}
+	if c.level == nil {
+		c.level = slog.LevelInfo
+	}
+	c.errors.Add(1)
+	go c.handleErrors()
+	if opts.AggregateWindow > 0 {
+		c.aggregate = newAggregator(opts.MaxAggregateKeys)
+		c.flusher.Add(1)
+		go c.flushSummaries()
+	}
+	return &Handler{core: c}, nil
+}
+
+// suppressKey marks contexts whose logs aren't sent
+type suppressKey struct{}
+
+// Suppress returns a context whose logs no handler sends to Kafka, while other handlers still write them. Services
+// publishing their own logs use it where logging would feed back into itself, e.g. in requests serving the logs.
+func Suppress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, suppressKey{}, true)
+}
+
+// suppressed reports whether the context's logs aren't sent
+func suppressed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	suppress, _ := ctx.Value(suppressKey{}).(bool)
+	return suppress
+}
+
+// Enabled reports whether logs of the level are sent; logs of suppressed contexts never are
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.core.level.Level() && !suppressed(ctx)
+}
+
+// Handle sends the record, or counts it when it repeats a message sent in the current window
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if suppressed(ctx) {
+		return nil
+	}
+	log := h.core.newLog(record.Level, record.Message, record.Time)
+	for _, a := range h.attrs {
+		setAttr(log, a)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		for _, flat := range flatten(h.prefix, a) {
+			setAttr(log, flat)
+		}
+		return true
+	})
+
+	if h.core.aggregate != nil && !h.core.aggregate.observe(h.key+"\x00"+string(log.Level)+"\x00"+log.Message, log) {
+		return nil
+	}
+	h.core.send(log)
+	return nil
+}
+
+// WithAttrs returns a handler adding the attributes to every log
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	derived := *h
+	derived.attrs = append([]attr(nil), h.attrs...)
+	var key strings.Builder
+	key.WriteString(h.key)
+	for _, a := range attrs {
+		for _, flat := range flatten(h.prefix, a) {
+			derived.attrs = append(derived.attrs, flat)
+			fmt.Fprintf(&key, "%s=%s\x00", flat.key, flat.value)
+		}
+	}
+	derived.key = key.String()
+	return &derived
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with the group name
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.prefix = h.prefix + name + "."
+	return &derived
+}
+
+// Stats returns the handler's counters
+func (h *Handler) Stats() Stats {
+	stats := Stats{Dropped: h.core.dropped.Load(), Failed: h.core.failed.Load()}
+	if h.core.aggregate != nil {
+		stats.Aggregated = h.core.aggregate.repeats.Load()
+	}
+	return stats
+}
+
+// Close sends the pending summaries, then flushes the buffered messages and stops the producer. Logs handled
+// afterwards are dropped.
+func (h *Handler) Close() error {
+	c := h.core
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.flusher.Wait()
+		if c.aggregate != nil {
+			c.sendSummaries()
+		}
+
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		c.producer.AsyncClose()
+		c.errors.Wait()
+	})
+	return nil
+}
+
+// newLog creates a log of the handler's service
+func (c *core) newLog(level slog.Level, message string, t time.Time) *models.Log {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	log := &models.Log{
+		Timestamp: t,
+		Level:     logLevel(level),
+		Service:   c.opts.Service,
+		Message:   message,
+	}
+	if c.opts.Host != "" {
+		log.Host = &c.opts.Host
+	}
+	if c.opts.Environment != "" {
+		log.Environment = &c.opts.Environment
+	}
+	if c.opts.Tenant != "" {
+		log.Tenant = &c.opts.Tenant
+	}
+	return log
+}
+
+// send queues a log for delivery, dropping it when the producer's buffer is full
+func (c *core) send(log *models.Log) {
+	messageID := uuid.New().String()
+	log.MessageID = &messageID
+	value, err := json.Marshal(log)
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic: c.opts.Topic,
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(constants.HeaderService), Value: []byte(log.Service)},
+			{Key: []byte(constants.HeaderLevel), Value: []byte(string(log.Level))},
+			{Key: []byte(constants.HeaderTimestamp), Value: []byte(log.Timestamp.Format(time.RFC3339))},
+		},
+	}
+	if c.opts.Tenant != "" {
+		message.Headers = append(message.Headers, sarama.RecordHeader{Key: []byte(constants.HeaderTenant), Value: []byte(c.opts.Tenant)})
+	}
+	message.Headers = append(message.Headers, serde.TraceHeaders(log)...)
+	if log.TraceID != nil {
+		message.Key = sarama.StringEncoder(*log.TraceID)
+	}
+	if c.opts.PriorityTopic != "" && (log.Level == models.LogLevelError || log.Level == models.LogLevelFatal) {
+		message.Topic = c.opts.PriorityTopic
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		c.dropped.Add(1)
+		return
+	}
+	select {
+	case c.producer.Input() <- message:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// handleErrors counts the messages Kafka rejected
+func (c *core) handleErrors() {
+	defer c.errors.Done()
+	for range c.producer.Errors() {
+		c.failed.Add(1)
+	}
+}
+
+// flushSummaries sends the summaries of repeated messages at the end of every window until the handler is closed
+func (c *core) flushSummaries() {
+	defer c.flusher.Done()
+	ticker := time.NewTicker(c.opts.AggregateWindow)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.sendSummaries()
+		}
+	}
+}
+
+// sendSummaries sends a summary of every message repeated in the window
+func (c *core) sendSummaries() {
+	for _, summary := range c.aggregate.flush() {
+		c.send(summary)
+	}
+}
+
+// setAttr sets a log attribute. Attributes named after a log column (trace_id, user_id, client_ip, request_path,
+// response_status, ...) set the log's own field instead, so that e.g. access logs feed the dashboard's request
+// metrics; numeric columns stay attributes when the value isn't a number.
+func setAttr(log *models.Log, a attr) {
+	switch a.key {
+	case "trace_id":
+		log.TraceID = &a.value
+	case "span_id":
+		log.SpanID = &a.value
+	case "parent_span_id":
+		log.ParentSpanID = &a.value
+	case "user_id":
+		log.UserID = &a.value
+	case "host":
+		log.Host = &a.value
+	case "environment":
+		log.Environment = &a.value
+	case "region":
+		log.Region = &a.value
+	case "client_ip":
+		log.ClientIP = &a.value
+	case "request_method":
+		log.RequestMethod = &a.value
+	case "request_path":
+		log.RequestPath = &a.value
+	case "response_status", "response_time_ms":
+		value, err := strconv.Atoi(a.value)
+		if err != nil {
+			log.Attributes.Set(a.key, a.value)
+		} else if a.key == "response_status" {
+			log.ResponseStatus = &value
+		} else {
+			log.ResponseTimeMs = &value
+		}
+	default:
+		log.Attributes.Set(a.key, a.value)
+	}
+}
+
+// flatten resolves an attribute, expanding groups into attributes with keys qualified by the group names
+func flatten(prefix string, a slog.Attr) []attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return nil
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		var attrs []attr
+		for _, member := range group {
+			attrs = append(attrs, flatten(prefix, member)...)
+		}
+		return attrs
+	case slog.KindTime:
+		return []attr{{key: prefix + a.Key, value: a.Value.Time().Format(time.RFC3339Nano)}}
+	default:
+		return []attr{{key: prefix + a.Key, value: a.Value.String()}}
+	}
+}
+
+// logLevel maps a slog level to a log level
+func logLevel(level slog.Level) models.LogLevel {
+	switch {
+	case level >= LevelFatal:
+		return models.LogLevelFatal
+	case level >= slog.LevelError:
+		return models.LogLevelError
+	case level >= slog.LevelWarn:
+		return models.LogLevelWarn
+	case level >= slog.LevelInfo:
+		return models.LogLevelInfo
+	default:
+		return models.LogLevelDebug
+	}
+}
@@ -0,0 +1,6 @@
+-- Log Attributes Rollback
+-- This script removes the JSON attributes column of logs
+
+ALTER TABLE logs DROP COLUMN attributes;
+
+-- Log attributes rollback completed successfully
@@ -0,0 +1,6 @@
+-- Log Attributes Migration
+-- This script adds a JSON attributes column for structured log context (e.g. enrichment data)
+
+ALTER TABLE logs ADD COLUMN attributes JSON NULL AFTER response_time_ms;
+
+-- Log attributes migration completed successfully
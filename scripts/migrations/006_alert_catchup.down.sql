@@ -0,0 +1,7 @@
+-- Alert Catch-up Rollback
+-- This script removes the evaluation bookkeeping of alert rules and the late flag of alerts
+
+ALTER TABLE alerts DROP COLUMN late_detected;
+ALTER TABLE alert_rules DROP COLUMN last_evaluated_at;
+
+-- Alert catch-up rollback completed successfully
@@ -0,0 +1,10 @@
+-- Alert Catch-up Migration
+-- This script adds evaluation bookkeeping used to catch up on windows missed during downtime
+
+-- Track when each rule was last evaluated successfully
+ALTER TABLE alert_rules ADD COLUMN last_evaluated_at DATETIME NULL AFTER enabled;
+
+-- Flag alerts created by catch-up evaluation of a missed window
+ALTER TABLE alerts ADD COLUMN late_detected BOOLEAN NOT NULL DEFAULT FALSE AFTER status;
+
+-- Alert catch-up migration completed successfully
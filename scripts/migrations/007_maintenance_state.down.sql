@@ -0,0 +1,6 @@
+-- Maintenance State Rollback
+-- This script drops the table holding the read-only switch and maintenance banner
+
+DROP TABLE IF EXISTS maintenance_states;
+
+-- Maintenance state rollback completed successfully
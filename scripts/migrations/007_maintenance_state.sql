@@ -0,0 +1,11 @@
+-- Maintenance State Migration
+-- This script creates the table holding the shared read-only switch and maintenance banner
+
+CREATE TABLE IF NOT EXISTS maintenance_states (
+    id BIGINT UNSIGNED PRIMARY KEY,
+    read_only BOOLEAN NOT NULL DEFAULT FALSE,
+    banner VARCHAR(500) NOT NULL DEFAULT '',
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Maintenance state migration completed successfully
@@ -0,0 +1,7 @@
+-- Alert Snooze Rollback
+-- This script removes the snooze and mute times of alerts and alert rules
+
+ALTER TABLE alert_rules DROP COLUMN muted_until;
+ALTER TABLE alerts DROP COLUMN snoozed_until;
+
+-- Alert snooze rollback completed successfully
@@ -0,0 +1,10 @@
+-- Alert Snooze Migration
+-- This script adds the times until which alerts are snoozed and alert rules are muted
+
+-- Suppress re-firing of an alert's rule until the given time
+ALTER TABLE alerts ADD COLUMN snoozed_until DATETIME NULL AFTER acknowledged_at;
+
+-- Suppress new alerts of a rule until the given time
+ALTER TABLE alert_rules ADD COLUMN muted_until DATETIME NULL AFTER last_evaluated_at;
+
+-- Alert snooze migration completed successfully
@@ -0,0 +1,8 @@
+-- Alert Stats Rollups Rollback
+-- This script drops the counts of pruned alerts and the index the retention job prunes by
+
+DROP INDEX idx_status_resolved ON alerts;
+
+DROP TABLE IF EXISTS alert_stats_rollups;
+
+-- Alert stats rollups rollback completed successfully
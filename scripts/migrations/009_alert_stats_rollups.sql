@@ -0,0 +1,18 @@
+-- Alert Stats Rollups Migration
+-- This script creates the table keeping counts of resolved alerts pruned by the retention job
+
+CREATE TABLE IF NOT EXISTS alert_stats_rollups (
+    day DATE NOT NULL COMMENT 'Day the pruned alerts were created',
+    rule_id BIGINT UNSIGNED NOT NULL COMMENT 'Rule of the pruned alerts; kept when the rule is deleted',
+    severity ENUM('low', 'medium', 'high', 'critical') NOT NULL,
+    alert_count BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (day, rule_id, severity),
+    INDEX idx_severity (severity)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Speed up finding resolved alerts past the retention age
+CREATE INDEX idx_status_resolved ON alerts (status, resolved_at);
+
+-- Alert stats rollups migration completed successfully
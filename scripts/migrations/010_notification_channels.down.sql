@@ -0,0 +1,7 @@
+-- Notification Channels Rollback
+-- This script drops the notification channels and the channels of alert rules
+
+DROP TABLE IF EXISTS alert_rule_channels;
+DROP TABLE IF EXISTS notification_channels;
+
+-- Notification channels rollback completed successfully
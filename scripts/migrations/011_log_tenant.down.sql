@@ -0,0 +1,8 @@
+-- Log Tenant Rollback
+-- This script removes the tenant of logs
+
+DROP INDEX idx_tenant_timestamp ON logs;
+
+ALTER TABLE logs DROP COLUMN tenant;
+
+-- Log tenant rollback completed successfully
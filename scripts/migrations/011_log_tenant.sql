@@ -0,0 +1,9 @@
+-- Log Tenant Migration
+-- This script adds the tenant of logs consumed from a tenant's dedicated Kafka topic
+
+ALTER TABLE logs ADD COLUMN tenant VARCHAR(64) NULL AFTER service;
+
+-- Speed up filtering logs by tenant
+CREATE INDEX idx_tenant_timestamp ON logs (tenant, timestamp);
+
+-- Log tenant migration completed successfully
@@ -0,0 +1,11 @@
+-- Alert Rule Canary Rollback
+-- This script drops the shadow evaluations and the previous version of rules under canary evaluation
+
+DROP TABLE IF EXISTS alert_rule_canary_evaluations;
+
+ALTER TABLE alert_rules DROP COLUMN canary_until;
+ALTER TABLE alert_rules DROP COLUMN previous_time_window;
+ALTER TABLE alert_rules DROP COLUMN previous_threshold;
+ALTER TABLE alert_rules DROP COLUMN previous_condition;
+
+-- Alert rule canary rollback completed successfully
@@ -0,0 +1,23 @@
+-- Alert Rule Canary Migration
+-- This script adds the previous version of rules under canary evaluation and the table recording shadow evaluations
+
+-- Previous condition, threshold and time window, still in effect until canary_until
+ALTER TABLE alert_rules ADD COLUMN previous_condition TEXT NULL AFTER muted_until;
+ALTER TABLE alert_rules ADD COLUMN previous_threshold DOUBLE NULL AFTER previous_condition;
+ALTER TABLE alert_rules ADD COLUMN previous_time_window INT NULL AFTER previous_threshold;
+ALTER TABLE alert_rules ADD COLUMN canary_until DATETIME NULL AFTER previous_time_window;
+
+CREATE TABLE IF NOT EXISTS alert_rule_canary_evaluations (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    rule_id BIGINT UNSIGNED NOT NULL,
+    evaluated_at DATETIME NOT NULL,
+    previous_value DOUBLE NULL COMMENT 'Value of the previous version; NULL when no data was found',
+    previous_fired BOOLEAN NOT NULL,
+    canary_value DOUBLE NULL COMMENT 'Value of the edited version; NULL when no data was found',
+    canary_fired BOOLEAN NOT NULL,
+
+    FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
+    INDEX idx_rule_evaluated (rule_id, evaluated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Alert rule canary migration completed successfully
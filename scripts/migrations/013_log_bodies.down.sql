@@ -0,0 +1,7 @@
+-- Log Bodies Rollback
+-- This script removes the request and response body excerpts of logs
+
+ALTER TABLE logs DROP COLUMN response_body;
+ALTER TABLE logs DROP COLUMN request_body;
+
+-- Log bodies rollback completed successfully
@@ -0,0 +1,7 @@
+-- Log Bodies Migration
+-- This script adds the request and response body excerpts captured for selected services
+
+ALTER TABLE logs ADD COLUMN request_body TEXT NULL AFTER response_time_ms;
+ALTER TABLE logs ADD COLUMN response_body TEXT NULL AFTER request_body;
+
+-- Log bodies migration completed successfully
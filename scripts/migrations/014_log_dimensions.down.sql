@@ -0,0 +1,14 @@
+-- Log Dimensions Rollback
+-- This script removes the host, environment, region and client IP of logs along with their indexes
+
+DROP INDEX idx_client_ip ON logs;
+DROP INDEX idx_region ON logs;
+DROP INDEX idx_environment ON logs;
+DROP INDEX idx_host ON logs;
+
+ALTER TABLE logs DROP COLUMN client_ip;
+ALTER TABLE logs DROP COLUMN region;
+ALTER TABLE logs DROP COLUMN environment;
+ALTER TABLE logs DROP COLUMN host;
+
+-- Log dimensions rollback completed successfully
@@ -0,0 +1,14 @@
+-- Log Dimensions Migration
+-- This script adds the host, environment, region and client IP of logs, indexed for dashboard filters
+
+ALTER TABLE logs ADD COLUMN host VARCHAR(255) NULL AFTER tenant;
+ALTER TABLE logs ADD COLUMN environment VARCHAR(32) NULL AFTER host;
+ALTER TABLE logs ADD COLUMN region VARCHAR(32) NULL AFTER environment;
+ALTER TABLE logs ADD COLUMN client_ip VARCHAR(45) NULL AFTER region;
+
+CREATE INDEX idx_host ON logs (host);
+CREATE INDEX idx_environment ON logs (environment);
+CREATE INDEX idx_region ON logs (region);
+CREATE INDEX idx_client_ip ON logs (client_ip);
+
+-- Log dimensions migration completed successfully
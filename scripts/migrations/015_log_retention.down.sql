@@ -0,0 +1,6 @@
+-- Log Retention Rollback
+-- This script drops the table holding the log retention policies
+
+DROP TABLE IF EXISTS log_retention_policies;
+
+-- Log retention rollback completed successfully
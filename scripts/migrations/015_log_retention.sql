@@ -0,0 +1,19 @@
+-- Log Retention Migration
+-- This script creates the table holding the log retention policies applied by the API server
+
+CREATE TABLE IF NOT EXISTS log_retention_policies (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    service VARCHAR(100) NOT NULL DEFAULT '' COMMENT 'Empty matches every service',
+    level VARCHAR(10) NOT NULL DEFAULT '' COMMENT 'Empty matches every level',
+    max_age_days INT NOT NULL,
+    action ENUM('delete', 'archive') NOT NULL DEFAULT 'delete',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at DATETIME NULL,
+    last_purged BIGINT NOT NULL DEFAULT 0 COMMENT 'Logs deleted or archived by the last run',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_scope (service, level)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Log retention migration completed successfully
@@ -0,0 +1,10 @@
+-- Log Fingerprints Rollback
+-- This script drops the fingerprint backfills and removes the fingerprint of logs
+
+DROP TABLE IF EXISTS fingerprint_backfills;
+
+DROP INDEX idx_fingerprint ON logs;
+
+ALTER TABLE logs DROP COLUMN fingerprint;
+
+-- Log fingerprints rollback completed successfully
@@ -0,0 +1,24 @@
+-- Log Fingerprints Migration
+-- This script adds the fingerprint grouping occurrences of the same error and the table tracking fingerprint backfills
+
+ALTER TABLE logs ADD COLUMN fingerprint CHAR(16) NULL AFTER message;
+
+CREATE INDEX idx_fingerprint ON logs (fingerprint);
+
+CREATE TABLE IF NOT EXISTS fingerprint_backfills (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    rules_version CHAR(16) NOT NULL,
+    status ENUM('running', 'completed', 'failed', 'cancelled') NOT NULL,
+    `cursor` VARCHAR(500) NOT NULL DEFAULT '' COMMENT 'Position reached in the logs',
+    total BIGINT NOT NULL DEFAULT 0 COMMENT 'Error logs stored when the backfill started',
+    processed BIGINT NOT NULL DEFAULT 0,
+    updated BIGINT NOT NULL DEFAULT 0 COMMENT 'Error logs whose fingerprint changed',
+    error VARCHAR(1000) NOT NULL DEFAULT '',
+    started_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'When progress was last recorded',
+    finished_at DATETIME NULL,
+
+    INDEX idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Log fingerprints migration completed successfully
@@ -0,0 +1,10 @@
+-- Migration History Rollback
+-- This script removes who applied each migration, how long it took and its batch
+
+DROP INDEX idx_batch ON migrations;
+
+ALTER TABLE migrations DROP COLUMN batch;
+ALTER TABLE migrations DROP COLUMN duration_ms;
+ALTER TABLE migrations DROP COLUMN applied_by;
+
+-- Migration history rollback completed successfully
@@ -0,0 +1,10 @@
+-- Migration History Migration
+-- This script adds who applied each migration, how long it took and the batch of the run that applied it
+
+ALTER TABLE migrations ADD COLUMN applied_by VARCHAR(255) NULL COMMENT 'user@host running the migration' AFTER applied_at;
+ALTER TABLE migrations ADD COLUMN duration_ms BIGINT NULL AFTER applied_by;
+ALTER TABLE migrations ADD COLUMN batch INT UNSIGNED NULL COMMENT 'Incremented by every run applying migrations' AFTER duration_ms;
+
+CREATE INDEX idx_batch ON migrations (batch);
+
+-- Migration history migration completed successfully
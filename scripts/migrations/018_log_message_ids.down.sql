@@ -0,0 +1,8 @@
+-- Log Message IDs Rollback
+-- This script removes the message ID of logs
+
+DROP INDEX idx_message_id ON logs;
+
+ALTER TABLE logs DROP COLUMN message_id;
+
+-- Log message IDs rollback completed successfully
@@ -0,0 +1,8 @@
+-- Log Message IDs Migration
+-- This script adds the message ID identifying deliveries of the same log, unique so that redelivered logs are stored once
+
+ALTER TABLE logs ADD COLUMN message_id VARCHAR(64) NULL AFTER trace_id;
+
+CREATE UNIQUE INDEX idx_message_id ON logs (message_id);
+
+-- Log message IDs migration completed successfully
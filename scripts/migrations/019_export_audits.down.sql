@@ -0,0 +1,6 @@
+-- Export Audits Rollback
+-- This script drops the table recording log exports
+
+DROP TABLE IF EXISTS export_audits;
+
+-- Export audits rollback completed successfully
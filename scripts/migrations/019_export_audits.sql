@@ -0,0 +1,22 @@
+-- Export Audits Migration
+-- This script creates the table recording who exported logs, with which filter and time range
+
+CREATE TABLE IF NOT EXISTS export_audits (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    caller VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Username or service token name',
+    provider VARCHAR(32) NOT NULL DEFAULT '',
+    route VARCHAR(255) NOT NULL,
+    filter TEXT NULL COMMENT 'Query string of the request',
+    start_time DATETIME NULL,
+    end_time DATETIME NULL,
+    `rows` BIGINT NOT NULL DEFAULT 0 COMMENT 'Logs exported',
+    status INT NOT NULL,
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    started_at DATETIME NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+
+    INDEX idx_caller_started_at (caller, started_at),
+    INDEX idx_started_at (started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Export audits migration completed successfully
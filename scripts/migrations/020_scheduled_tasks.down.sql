@@ -0,0 +1,7 @@
+-- Scheduled Tasks Rollback
+-- This script drops the leases of periodic tasks and their run history
+
+DROP TABLE IF EXISTS scheduled_task_runs;
+DROP TABLE IF EXISTS scheduled_tasks;
+
+-- Scheduled tasks rollback completed successfully
@@ -0,0 +1,30 @@
+-- Scheduled Tasks Migration
+-- This script creates the leases of periodic tasks run by a single API server at a time, and their run history
+
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+    name VARCHAR(100) PRIMARY KEY,
+    interval_seconds BIGINT NOT NULL,
+    lease_owner VARCHAR(255) NULL COMMENT 'API server running the task',
+    lease_expires_at DATETIME NULL,
+    last_started_at DATETIME NULL,
+    last_finished_at DATETIME NULL,
+    last_status VARCHAR(16) NULL,
+    last_error VARCHAR(1000) NOT NULL DEFAULT '',
+    last_duration_ms BIGINT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS scheduled_task_runs (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    task VARCHAR(100) NOT NULL,
+    owner VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error VARCHAR(1000) NOT NULL DEFAULT '',
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NULL,
+    duration_ms BIGINT NULL,
+
+    INDEX idx_task_started_at (task, started_at),
+    INDEX idx_started_at (started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Scheduled tasks migration completed successfully
@@ -0,0 +1,6 @@
+-- Saved Searches Rollback
+-- This script drops the table of saved log filters
+
+DROP TABLE IF EXISTS saved_searches;
+
+-- Saved searches rollback completed successfully
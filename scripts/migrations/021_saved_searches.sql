@@ -0,0 +1,15 @@
+-- Saved Searches Migration
+-- This script creates the table of named log filters that dashboard users run again
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    filter JSON NOT NULL COMMENT 'Log filter: level, service, time range, search, dimensions and attributes',
+    created_by VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Username of the caller who created the search',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Saved searches migration completed successfully
@@ -0,0 +1,14 @@
+-- Anomaly Alert Rules Rollback
+-- This script removes the type of alert rules and the settings of anomaly rules
+
+-- Anomaly rules would be evaluated as threshold rules without their settings
+DELETE FROM alert_rules WHERE type = 'anomaly';
+
+ALTER TABLE alert_rules DROP COLUMN anomaly_direction;
+ALTER TABLE alert_rules DROP COLUMN anomaly_deviation;
+ALTER TABLE alert_rules DROP COLUMN anomaly_periods;
+ALTER TABLE alert_rules DROP COLUMN anomaly_seasonality;
+ALTER TABLE alert_rules DROP COLUMN anomaly_metric;
+ALTER TABLE alert_rules DROP COLUMN type;
+
+-- Anomaly alert rules rollback completed successfully
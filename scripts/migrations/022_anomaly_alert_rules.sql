@@ -0,0 +1,16 @@
+-- Anomaly Alert Rules Migration
+-- This script adds the type of alert rules and the settings of anomaly rules, which compare a metric with the same
+-- window of previous days or weeks instead of a fixed threshold
+
+-- Existing rules keep firing on their threshold
+ALTER TABLE alert_rules ADD COLUMN type ENUM('threshold', 'anomaly') NOT NULL DEFAULT 'threshold' AFTER muted_until;
+
+-- Metric (log_volume, error_rate), period between a window and its baseline windows (day, week), number of baseline
+-- windows, measure of the deviation the threshold is expressed in (stddev, percent) and direction (up, down, both)
+ALTER TABLE alert_rules ADD COLUMN anomaly_metric VARCHAR(16) NOT NULL DEFAULT '' AFTER type;
+ALTER TABLE alert_rules ADD COLUMN anomaly_seasonality VARCHAR(8) NOT NULL DEFAULT '' AFTER anomaly_metric;
+ALTER TABLE alert_rules ADD COLUMN anomaly_periods INT NOT NULL DEFAULT 0 AFTER anomaly_seasonality;
+ALTER TABLE alert_rules ADD COLUMN anomaly_deviation VARCHAR(8) NOT NULL DEFAULT '' AFTER anomaly_periods;
+ALTER TABLE alert_rules ADD COLUMN anomaly_direction VARCHAR(8) NOT NULL DEFAULT '' AFTER anomaly_deviation;
+
+-- Anomaly alert rules migration completed successfully
@@ -0,0 +1,9 @@
+-- Alert Cooldown and Re-notification Rollback
+-- This script removes the cooldown and renotify intervals of alert rules and the notification state of alerts
+
+ALTER TABLE alerts DROP COLUMN notification_count;
+ALTER TABLE alerts DROP COLUMN last_notified_at;
+ALTER TABLE alert_rules DROP COLUMN renotify_minutes;
+ALTER TABLE alert_rules DROP COLUMN cooldown_minutes;
+
+-- Alert cooldown and re-notification rollback completed successfully
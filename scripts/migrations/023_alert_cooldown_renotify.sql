@@ -0,0 +1,14 @@
+-- Alert Cooldown and Re-notification Migration
+-- This script adds the cooldown and renotify intervals of alert rules, and the notification state of alerts that
+-- re-notification is scheduled from
+
+-- Minutes after an alert resolves during which the rule doesn't fire again, and between notifications of an alert
+-- that stays active; zero disables either
+ALTER TABLE alert_rules ADD COLUMN cooldown_minutes INT NOT NULL DEFAULT 0 AFTER muted_until;
+ALTER TABLE alert_rules ADD COLUMN renotify_minutes INT NOT NULL DEFAULT 0 AFTER cooldown_minutes;
+
+-- When the alert's notification was last sent and how many were sent, re-notifications included
+ALTER TABLE alerts ADD COLUMN last_notified_at DATETIME NULL AFTER snoozed_until;
+ALTER TABLE alerts ADD COLUMN notification_count INT NOT NULL DEFAULT 0 AFTER last_notified_at;
+
+-- Alert cooldown and re-notification migration completed successfully
@@ -0,0 +1,12 @@
+-- Tenants Rollback
+-- This script removes the tenant of alert rules and alerts, and drops the registry of tenants
+
+DROP INDEX idx_alerts_tenant ON alerts;
+ALTER TABLE alerts DROP COLUMN tenant;
+
+DROP INDEX idx_alert_rules_tenant ON alert_rules;
+ALTER TABLE alert_rules DROP COLUMN tenant;
+
+DROP TABLE IF EXISTS tenants;
+
+-- Tenants rollback completed successfully
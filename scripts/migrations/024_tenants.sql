@@ -0,0 +1,20 @@
+-- Tenants Migration
+-- This script adds the registry of tenants, and the tenant of alert rules and the alerts they create
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL COMMENT 'Name logs are tagged with, sent by producers in the tenant header',
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Rules of a tenant only evaluate the tenant's logs; their alerts inherit the tenant
+ALTER TABLE alert_rules ADD COLUMN tenant VARCHAR(64) NULL AFTER renotify_minutes;
+CREATE INDEX idx_alert_rules_tenant ON alert_rules (tenant);
+ALTER TABLE alerts ADD COLUMN tenant VARCHAR(64) NULL AFTER notification_count;
+CREATE INDEX idx_alerts_tenant ON alerts (tenant);
+
+-- Tenants migration completed successfully
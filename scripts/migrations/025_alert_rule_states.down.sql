@@ -0,0 +1,6 @@
+-- Alert Rule States Rollback
+-- This script drops the persisted state of stateful alert rule evaluators
+
+DROP TABLE IF EXISTS alert_rule_states;
+
+-- Alert rule states rollback completed successfully
@@ -0,0 +1,15 @@
+-- Alert Rule States Migration
+-- This script adds the table persisting the state of stateful alert rule evaluators across restarts and replicas
+
+CREATE TABLE IF NOT EXISTS alert_rule_states (
+    rule_id BIGINT UNSIGNED NOT NULL,
+    kind VARCHAR(32) NOT NULL COMMENT 'Evaluator owning the state; a rule has at most one state per evaluator',
+    state JSON NOT NULL,
+    version BIGINT UNSIGNED NOT NULL COMMENT 'Incremented by every save; saves of an outdated version are rejected',
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (rule_id, kind),
+    FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Alert rule states migration completed successfully
@@ -0,0 +1,8 @@
+-- Alert Filters Rollback
+-- This script removes the labels of alert rules and the full-text index of alert messages
+
+DROP INDEX ft_alerts_message ON alerts;
+
+ALTER TABLE alert_rules DROP COLUMN labels;
+
+-- Alert filters rollback completed successfully
@@ -0,0 +1,9 @@
+-- Alert Filters Migration
+-- This script adds the labels of alert rules and the full-text index searching alert messages
+
+-- Labels such as {"team": "payments"} select the alerts of matching rules in alert listings
+ALTER TABLE alert_rules ADD COLUMN labels JSON NULL AFTER tenant;
+
+CREATE FULLTEXT INDEX ft_alerts_message ON alerts (message);
+
+-- Alert filters migration completed successfully
@@ -0,0 +1,9 @@
+-- Migration Rollbacks Rollback
+-- This script removes when and by whom migrations were rolled back. Rolled back migrations lose their row instead.
+
+DELETE FROM migrations WHERE rolled_back_at IS NOT NULL;
+
+ALTER TABLE migrations DROP COLUMN rolled_back_by;
+ALTER TABLE migrations DROP COLUMN rolled_back_at;
+
+-- Migration rollbacks rollback completed successfully
@@ -0,0 +1,8 @@
+-- Migration Rollbacks Migration
+-- This script adds when and by whom a migration was rolled back. Rolled back migrations keep their row, so the
+-- history survives until the migration is applied again.
+
+ALTER TABLE migrations ADD COLUMN rolled_back_at TIMESTAMP NULL AFTER batch;
+ALTER TABLE migrations ADD COLUMN rolled_back_by VARCHAR(255) NULL COMMENT 'user@host running the rollback' AFTER rolled_back_at;
+
+-- Migration rollbacks migration completed successfully
@@ -0,0 +1,8 @@
+-- Chat Notification Channels Rollback
+-- This script deletes the Microsoft Teams and Discord channels and removes their types
+
+DELETE FROM notification_channels WHERE type IN ('teams', 'discord');
+
+ALTER TABLE notification_channels MODIFY COLUMN type ENUM('email', 'slack', 'webhook') NOT NULL;
+
+-- Chat notification channels rollback completed successfully
@@ -0,0 +1,6 @@
+-- Chat Notification Channels Migration
+-- This script adds the Microsoft Teams and Discord notification channel types
+
+ALTER TABLE notification_channels MODIFY COLUMN type ENUM('email', 'slack', 'webhook', 'teams', 'discord') NOT NULL;
+
+-- Chat notification channels migration completed successfully
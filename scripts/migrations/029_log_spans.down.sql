@@ -0,0 +1,9 @@
+-- Log Spans Rollback
+-- This script removes the span and parent span of logs
+
+DROP INDEX idx_span_id ON logs;
+
+ALTER TABLE logs DROP COLUMN parent_span_id;
+ALTER TABLE logs DROP COLUMN span_id;
+
+-- Log spans rollback completed successfully
@@ -0,0 +1,9 @@
+-- Log Spans Migration
+-- This script adds the span of the trace logs were emitted in and its parent span, so traces can be shown as a tree
+
+ALTER TABLE logs ADD COLUMN span_id VARCHAR(32) NULL AFTER trace_id;
+ALTER TABLE logs ADD COLUMN parent_span_id VARCHAR(32) NULL AFTER span_id;
+
+CREATE INDEX idx_span_id ON logs (span_id);
+
+-- Log spans migration completed successfully
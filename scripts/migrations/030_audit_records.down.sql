@@ -0,0 +1,6 @@
+-- Audit Records Rollback
+-- This script drops the table recording API mutations
+
+DROP TABLE IF EXISTS audit_records;
+
+-- Audit records rollback completed successfully
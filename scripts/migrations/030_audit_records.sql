@@ -0,0 +1,24 @@
+-- Audit Records Migration
+-- This script creates the table recording who changed what through the API, with snapshots before and after
+
+CREATE TABLE IF NOT EXISTS audit_records (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    caller VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Username',
+    provider VARCHAR(32) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL COMMENT 'create, update, delete or the operation of the route',
+    resource VARCHAR(64) NOT NULL COMMENT 'Collection of the record, e.g. alert-rules',
+    resource_id VARCHAR(64) NOT NULL DEFAULT '',
+    method VARCHAR(8) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    status INT NOT NULL,
+    `before` TEXT NULL COMMENT 'JSON snapshot of the record before the mutation',
+    `after` TEXT NULL COMMENT 'JSON snapshot of the record after the mutation',
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+
+    INDEX idx_resource (resource, resource_id, created_at),
+    INDEX idx_caller_created_at (caller, created_at),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Audit records migration completed successfully
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migration scripts, so that binaries can migrate the database without shipping
+// this directory.
+package migrations
+
+import "embed"
+
+// Files holds the NNN_name.sql, NNN_name.up.sql and NNN_name.down.sql scripts of this directory
+//
+//go:embed *.sql
+var Files embed.FS
@@ -0,0 +1,116 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// TestAlertRuleFiresAndResolves creates a rule through the API, pushes matching logs through the
+// pipeline and checks the alert service raises an alert, then resolves it once the rule no longer matches
+func TestAlertRuleFiresAndResolves(t *testing.T) {
+	h := newHarness(t)
+	ctx := withTimeout(t, pipelineTimeout)
+
+	service := uniqueService("it-alerting")
+	rule := models.AlertRule{
+		Name:       "Integration error count " + service,
+		Condition:  fmt.Sprintf("COUNT(CASE WHEN service = '%s' AND level = 'ERROR' THEN 1 END)", service),
+		Threshold:  3,
+		TimeWindow: 5,
+		Severity:   "high",
+		Enabled:    true,
+	}
+	h.do(t, http.MethodPost, "/api/alert-rules", rule, http.StatusCreated, &rule)
+
+	activeFor := func() []models.Alert {
+		var active []models.Alert
+		h.get(t, "/api/alerts/active", &active)
+		var matching []models.Alert
+		for _, alert := range active {
+			if alert.RuleID == rule.ID {
+				matching = append(matching, alert)
+			}
+		}
+		return matching
+	}
+
+	// Below the threshold nothing fires
+	if err := h.collector.SendLog(ctx, newLog(service, models.LogLevelError, "first failure")); err != nil {
+		t.Fatalf("failed to send log: %v", err)
+	}
+	h.waitForLogs(t, service, 1)
+	if err := h.alerts.CheckAlertRules(ctx); err != nil {
+		t.Fatalf("failed to check alert rules: %v", err)
+	}
+	if active := activeFor(); len(active) != 0 {
+		t.Fatalf("got %d active alerts below the threshold, want 0", len(active))
+	}
+
+	batch := []*models.Log{
+		newLog(service, models.LogLevelError, "second failure"),
+		newLog(service, models.LogLevelError, "third failure"),
+		newLog(service, models.LogLevelInfo, "recovered request"),
+	}
+	if err := h.collector.SendLogs(ctx, batch); err != nil {
+		t.Fatalf("failed to send logs: %v", err)
+	}
+	h.waitForLogs(t, service, 4)
+
+	// Evaluating on demand reports the alert the checker would create, without creating it
+	evaluate := func() models.AlertRuleEvaluation {
+		var evaluation models.AlertRuleEvaluation
+		h.do(t, http.MethodPost, fmt.Sprintf("/api/alert-rules/%d/evaluate", rule.ID), nil, http.StatusOK, &evaluation)
+		return evaluation
+	}
+	evaluation := evaluate()
+	if evaluation.Value == nil || *evaluation.Value != 3 || !evaluation.ConditionMet || !evaluation.WouldFire {
+		t.Fatalf("unexpected evaluation at the threshold: %+v", evaluation)
+	}
+	if strings.Contains(evaluation.Query, service) {
+		t.Errorf("evaluation query leaks the condition's literals: %s", evaluation.Query)
+	}
+	if active := activeFor(); len(active) != 0 {
+		t.Fatalf("got %d active alerts after evaluating on demand, want 0", len(active))
+	}
+
+	if err := h.alerts.CheckAlertRules(ctx); err != nil {
+		t.Fatalf("failed to check alert rules: %v", err)
+	}
+
+	active := activeFor()
+	if len(active) != 1 {
+		t.Fatalf("got %d active alerts at the threshold, want 1", len(active))
+	}
+	if active[0].Value != 3 || active[0].Severity != rule.Severity {
+		t.Errorf("unexpected alert: value=%.2f severity=%s", active[0].Value, active[0].Severity)
+	}
+
+	// Re-evaluating doesn't duplicate the alert
+	if err := h.alerts.CheckAlertRules(ctx); err != nil {
+		t.Fatalf("failed to check alert rules: %v", err)
+	}
+	if active := activeFor(); len(active) != 1 {
+		t.Fatalf("got %d active alerts after re-evaluation, want 1", len(active))
+	}
+	if evaluation := evaluate(); evaluation.WouldFire || evaluation.ActiveAlerts != 1 {
+		t.Errorf("unexpected evaluation with an active alert: %+v", evaluation)
+	}
+
+	// Raising the threshold above the observed value resolves the alert
+	rule.Threshold = 10
+	if err := h.rules.UpdateAlertRule(ctx, &rule); err != nil {
+		t.Fatalf("failed to update alert rule: %v", err)
+	}
+	if err := h.alerts.CheckAlertRules(ctx); err != nil {
+		t.Fatalf("failed to check alert rules: %v", err)
+	}
+	if active := activeFor(); len(active) != 0 {
+		t.Fatalf("got %d active alerts after the rule stopped matching, want 0", len(active))
+	}
+}
@@ -0,0 +1,201 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/alert_rules"
+	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/handlers"
+	"github.com/adeesh/log-analytics/internal/kafka/consumers"
+	"github.com/adeesh/log-analytics/internal/kafka/producers"
+	"github.com/adeesh/log-analytics/internal/models"
+	"github.com/adeesh/log-analytics/internal/services"
+)
+
+// pipelineTimeout bounds how long a log may take to travel from the collector to the API
+const pipelineTimeout = 60 * time.Second
+
+// harness wires the collector, a running processor and the API routes against the test infrastructure
+type harness struct {
+	collector *producers.LogCollectorService
+	alerts    *services.AlertService
+	rules     alert_rules.AlertRuleRepository
+	router    *gin.Engine
+}
+
+// newHarness starts a processor and builds the API router; everything is stopped when the test ends
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	logger := testLogger()
+
+	db, err := database.NewGormDB(&cfg.Database)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	processor, err := consumers.NewLogProcessorService(cfg, logger)
+	if err != nil {
+		t.Fatalf("failed to create log processor: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		processor.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+		processor.Close()
+	})
+
+	collector, err := producers.NewLogCollectorService(cfg, logger)
+	if err != nil {
+		t.Fatalf("failed to create log collector: %v", err)
+	}
+	t.Cleanup(func() { collector.Close() })
+
+	logRepo := logs.NewLogRepository(db)
+	alertRepo := alerts.NewAlertRepository(db.GetDB())
+	alertRuleRepo := alert_rules.NewAlertRuleRepository(db.GetDB())
+
+	alertService := services.NewAlertService(alertRuleRepo, alertRepo, logs.NewLogQuerier(db), nil, nil, logger)
+	logHandler := handlers.NewLogHandler(logRepo, nil, logger)
+	alertHandler := handlers.NewAlertHandler(alertRepo, alertService, nil, logger)
+	alertRuleHandler := handlers.NewAlertRuleHandler(alertRuleRepo, alertService, 0, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group(constants.APIPrefix)
+	api.GET(constants.APILogsPath, logHandler.GetLogs)
+	api.GET(constants.APILogsPath+"/trace/:traceID", logHandler.GetLogsByTraceID)
+	api.GET(constants.APIMetricsPath+"/services/compare", logHandler.CompareServices)
+	api.GET("/alerts/active", alertHandler.GetActiveAlerts)
+	api.POST("/alert-rules", alertRuleHandler.CreateAlertRule)
+	api.POST("/alert-rules/:id/evaluate", alertRuleHandler.EvaluateAlertRule)
+
+	h := &harness{
+		collector: collector,
+		alerts:    alertService,
+		rules:     alertRuleRepo,
+		router:    router,
+	}
+	h.waitForProcessor(t)
+	return h
+}
+
+// waitForProcessor sends probe logs until one is stored. The processor starts from the newest
+// offset, so logs sent before its consumer group is assigned the partition would be skipped.
+func (h *harness) waitForProcessor(t *testing.T) {
+	t.Helper()
+	service := uniqueService("it-probe")
+	ctx := withTimeout(t, pipelineTimeout)
+
+	for {
+		if err := h.collector.SendLog(ctx, newLog(service, models.LogLevelInfo, "probe")); err != nil {
+			t.Fatalf("failed to send probe log: %v", err)
+		}
+		var response struct {
+			Count int `json:"count"`
+		}
+		h.get(t, constants.APIPrefix+constants.APILogsPath+"?service="+service, &response)
+		if response.Count > 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("processor did not store logs within %s", pipelineTimeout)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// get performs a GET request against the router and decodes the JSON response
+func (h *harness) get(t *testing.T, path string, out any) {
+	t.Helper()
+	h.do(t, http.MethodGet, path, nil, http.StatusOK, out)
+}
+
+// do performs a request against the router, checks the status and decodes the JSON response
+func (h *harness) do(t *testing.T, method, path string, body any, status int, out any) {
+	t.Helper()
+	var payload io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, payload)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+
+	if rec.Code != status {
+		t.Fatalf("%s %s: got status %d, want %d: %s", method, path, rec.Code, status, rec.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+}
+
+// waitForLogs polls the logs endpoint until the service has at least count stored logs
+func (h *harness) waitForLogs(t *testing.T, service string, count int) []models.Log {
+	t.Helper()
+	deadline := time.Now().Add(pipelineTimeout)
+	for {
+		var response struct {
+			Logs  []models.Log `json:"logs"`
+			Count int          `json:"count"`
+		}
+		h.get(t, fmt.Sprintf("%s%s?service=%s&limit=1000", constants.APIPrefix, constants.APILogsPath, service), &response)
+		if response.Count >= count {
+			return response.Logs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("service %s has %d stored logs after %s, want %d", service, response.Count, pipelineTimeout, count)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// uniqueService returns a service name not used by earlier runs against the same database
+func uniqueService(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+// newLog builds a log for the service timestamped now
+func newLog(service string, level models.LogLevel, message string) *models.Log {
+	return &models.Log{
+		Timestamp: time.Now(),
+		Level:     level,
+		Service:   service,
+		Message:   message,
+	}
+}
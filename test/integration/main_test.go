@@ -0,0 +1,302 @@
+//go:build integration
+
+// Package integration runs the collector, processor, API handlers and alert service against real
+// MySQL and Kafka instances. By default both are started as Docker containers with testcontainers; set
+// INTEGRATION_MYSQL_ADDR (host:port) and INTEGRATION_KAFKA_BROKERS to use existing ones instead.
+// With INTEGRATION_DB_DRIVER=postgres the suite runs against Postgres instead of MySQL, started
+// likewise unless INTEGRATION_POSTGRES_ADDR is set.
+//
+//	go test -tags integration ./test/integration/...
+//	INTEGRATION_DB_DRIVER=postgres go test -tags integration ./test/integration/...
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/docker/go-connections/nat"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+)
+
+const (
+	mysqlImage    = "mysql:8.0"
+	postgresImage = "postgres:16"
+	kafkaImage    = "apache/kafka:3.7.0"
+	dbPassword    = "password"      // of the root user of MySQL and the postgres user of Postgres alike
+	dbDatabase    = "log_analytics" // the first migration creates and selects this database
+	startTimeout  = 3 * time.Minute
+)
+
+// containers started by TestMain, removed when the suite finishes
+var containers []testcontainers.Container
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	defer removeContainers()
+
+	driver := os.Getenv("INTEGRATION_DB_DRIVER")
+	if driver == "" {
+		driver = constants.DBDriverMySQL
+	}
+	dbAddr, dbUser, err := startDatabase(driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start %s: %v\n", driver, err)
+		return 1
+	}
+
+	brokers := os.Getenv("INTEGRATION_KAFKA_BROKERS")
+	if brokers == "" {
+		addr, err := startKafka()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to start Kafka:", err)
+			return 1
+		}
+		brokers = addr
+	}
+
+	host, port, err := net.SplitHostPort(dbAddr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid database address:", err)
+		return 1
+	}
+
+	// The services read their configuration from the environment
+	env := map[string]string{
+		constants.EnvKeyDBDriver:             driver,
+		constants.EnvKeyDBSSLMode:            "disable",
+		constants.EnvKeyDBHost:               host,
+		constants.EnvKeyDBPort:               port,
+		constants.EnvKeyDBUser:               dbUser,
+		constants.EnvKeyDBPassword:           dbPassword,
+		constants.EnvKeyDBDatabase:           dbDatabase,
+		constants.EnvKeyKafkaBrokers:         brokers,
+		constants.EnvKeyKafkaTopic:           "it-logs",
+		constants.EnvKeyKafkaDeadLetterTopic: "it-logs-dlq",
+		constants.EnvKeyKafkaGroupID:         fmt.Sprintf("it-processor-%d", time.Now().UnixNano()),
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+
+	if err := waitForDatabase(driver, dbAddr, dbUser); err != nil {
+		fmt.Fprintf(os.Stderr, "%s not ready: %v\n", driver, err)
+		return 1
+	}
+	if err := createTopics(strings.Split(brokers, ","), env[constants.EnvKeyKafkaTopic], env[constants.EnvKeyKafkaDeadLetterTopic]); err != nil {
+		fmt.Fprintln(os.Stderr, "Kafka not ready:", err)
+		return 1
+	}
+	if err := runMigrations(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to run migrations:", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// startDatabase starts a MySQL or Postgres container unless the address of an existing server is set, and
+// returns its host address and the user to connect as
+func startDatabase(driver string) (string, string, error) {
+	switch driver {
+	case constants.DBDriverMySQL:
+		if addr := os.Getenv("INTEGRATION_MYSQL_ADDR"); addr != "" {
+			return addr, "root", nil
+		}
+		addr, err := startContainer(mysqlImage, "3306/tcp", map[string]string{"MYSQL_ROOT_PASSWORD": dbPassword})
+		return addr, "root", err
+	case constants.DBDriverPostgres:
+		if addr := os.Getenv("INTEGRATION_POSTGRES_ADDR"); addr != "" {
+			return addr, "postgres", nil
+		}
+		addr, err := startContainer(postgresImage, "5432/tcp", map[string]string{"POSTGRES_PASSWORD": dbPassword})
+		return addr, "postgres", err
+	default:
+		return "", "", fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// startContainer starts a container publishing the port on a random host port, and returns its host address
+func startContainer(image, port string, env map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	container, err := runContainer(ctx, testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{port},
+		Env:          env,
+	})
+	if err != nil {
+		return "", err
+	}
+	return container.PortEndpoint(ctx, nat.Port(port), "")
+}
+
+// startKafka starts a single-node KRaft Kafka container and returns its bootstrap address.
+// The broker advertises the host port, so it is chosen up front rather than by Docker.
+func startKafka() (string, error) {
+	port, err := freePort()
+	if err != nil {
+		return "", err
+	}
+	addr := fmt.Sprintf("localhost:%d", port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	_, err = runContainer(ctx, testcontainers.ContainerRequest{
+		Image:        kafkaImage,
+		ExposedPorts: []string{fmt.Sprintf("127.0.0.1:%d:9092/tcp", port)},
+		Env: map[string]string{
+			"KAFKA_NODE_ID":                                  "1",
+			"KAFKA_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_LISTENERS":                                "PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_ADVERTISED_LISTENERS":                     "PLAINTEXT://" + addr,
+			"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS":         "0",
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// runContainer starts a container and records it for removal, also when it failed after being created
+func runContainer(ctx context.Context, req testcontainers.ContainerRequest) (testcontainers.Container, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if container != nil {
+		containers = append(containers, container)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", req.Image, err)
+	}
+	return container, nil
+}
+
+// removeContainers terminates the started containers
+func removeContainers() {
+	for _, container := range containers {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to remove container:", err)
+		}
+	}
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitForDatabase polls until the server accepts connections
+func waitForDatabase(driver, addr, user string) error {
+	driverName, dsn := "mysql", fmt.Sprintf("%s:%s@tcp(%s)/", user, dbPassword, addr)
+	if driver == constants.DBDriverPostgres {
+		driverName, dsn = "pgx", fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, dbPassword, addr, constants.PGAdminDatabase)
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return eventually(startTimeout, func() error {
+		return db.Ping()
+	})
+}
+
+// createTopics creates the log and dead letter topics once the broker is reachable
+func createTopics(brokers []string, topics ...string) error {
+	config := sarama.NewConfig()
+	config.Version = sarama.V3_0_0_0
+
+	var admin sarama.ClusterAdmin
+	if err := eventually(startTimeout, func() error {
+		var err error
+		admin, err = sarama.NewClusterAdmin(brokers, config)
+		return err
+	}); err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	for _, topic := range topics {
+		err := admin.CreateTopic(topic, &sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false)
+		if err != nil && !strings.Contains(err.Error(), sarama.ErrTopicAlreadyExists.Error()) {
+			return fmt.Errorf("failed to create topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// runMigrations applies the SQL migrations with the migration command, from the repository root
+func runMigrations() error {
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "run", "./cmd/migration", "run")
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+// eventually retries fn until it succeeds or the timeout elapses, returning the last error
+func eventually(timeout time.Duration, fn func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := fn()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// testLogger only reports service errors unless the tests run verbosely
+func testLogger() *slog.Logger {
+	level := slog.LevelError
+	if testing.Verbose() {
+		level = slog.LevelInfo
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// withTimeout returns a context cancelled after d or at the end of the test
+func withTimeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
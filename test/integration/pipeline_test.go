@@ -0,0 +1,95 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// TestPipelineDeliversLogsToAPI sends logs through the collector and reads them back from the
+// logs, trace and service comparison endpoints once the processor has stored them
+func TestPipelineDeliversLogsToAPI(t *testing.T) {
+	h := newHarness(t)
+	ctx := withTimeout(t, pipelineTimeout)
+
+	checkout := uniqueService("it-checkout")
+	payments := uniqueService("it-payments")
+	traceID := fmt.Sprintf("trace-%s", checkout)
+
+	var batch []*models.Log
+	for i := 1; i <= 10; i++ {
+		log := newLog(checkout, models.LogLevelInfo, fmt.Sprintf("request %d", i))
+		status, latency := 200, i*10
+		if i > 8 {
+			log.Level = models.LogLevelError
+			status = 500
+		}
+		log.ResponseStatus = &status
+		log.ResponseTimeMs = &latency
+		batch = append(batch, log)
+	}
+	for i := 1; i <= 4; i++ {
+		log := newLog(payments, models.LogLevelInfo, fmt.Sprintf("charge %d", i))
+		log.TraceID = &traceID
+		batch = append(batch, log)
+	}
+	batch[0].TraceID = &traceID
+
+	if err := h.collector.SendLogs(ctx, batch); err != nil {
+		t.Fatalf("failed to send logs: %v", err)
+	}
+
+	stored := h.waitForLogs(t, checkout, 10)
+	if len(stored) != 10 {
+		t.Fatalf("got %d logs for %s, want 10", len(stored), checkout)
+	}
+	h.waitForLogs(t, payments, 4)
+
+	var errors struct {
+		Logs []models.Log `json:"logs"`
+	}
+	h.get(t, fmt.Sprintf("%s%s?service=%s&level=%s", constants.APIPrefix, constants.APILogsPath, checkout, models.LogLevelError), &errors)
+	if len(errors.Logs) != 2 {
+		t.Errorf("got %d error logs for %s, want 2", len(errors.Logs), checkout)
+	}
+	for _, log := range errors.Logs {
+		if log.ResponseStatus == nil || *log.ResponseStatus != 500 {
+			t.Errorf("error log %q lost its response status", log.Message)
+		}
+	}
+
+	var trace struct {
+		Logs  []models.Log `json:"logs"`
+		Count int          `json:"count"`
+	}
+	h.get(t, fmt.Sprintf("%s%s/trace/%s", constants.APIPrefix, constants.APILogsPath, traceID), &trace)
+	if trace.Count != 5 {
+		t.Errorf("got %d logs for trace %s, want 5", trace.Count, traceID)
+	}
+
+	var comparison struct {
+		Services []models.ServiceComparison `json:"services"`
+	}
+	h.get(t, fmt.Sprintf("%s%s/services/compare?services=%s,%s", constants.APIPrefix, constants.APIMetricsPath, checkout, payments), &comparison)
+	if len(comparison.Services) != 2 {
+		t.Fatalf("got %d compared services, want 2", len(comparison.Services))
+	}
+	got := comparison.Services[0]
+	if got.Service != checkout || got.TotalLogs != 10 || got.ErrorCount != 2 || got.Status5xxCount != 2 {
+		t.Errorf("unexpected comparison for %s: %+v", checkout, got)
+	}
+	if got.ErrorRate != 20 {
+		t.Errorf("got error rate %.2f for %s, want 20", got.ErrorRate, checkout)
+	}
+	// Nearest-rank percentiles of 10, 20, ..., 100
+	if got.P50ResponseTime == nil || *got.P50ResponseTime != 50 || got.P99ResponseTime == nil || *got.P99ResponseTime != 100 {
+		t.Errorf("unexpected latency percentiles for %s: p50=%v p99=%v", checkout, got.P50ResponseTime, got.P99ResponseTime)
+	}
+	if other := comparison.Services[1]; other.Service != payments || other.TotalLogs != 4 || other.P50ResponseTime != nil {
+		t.Errorf("unexpected comparison for %s: %+v", payments, other)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adeesh/log-analytics/internal/config"
+	"github.com/adeesh/log-analytics/internal/constants"
+	"github.com/adeesh/log-analytics/internal/database"
+	"github.com/adeesh/log-analytics/internal/database/alert_rules"
+	"github.com/adeesh/log-analytics/internal/database/alerts"
+	"github.com/adeesh/log-analytics/internal/database/logs"
+	"github.com/adeesh/log-analytics/internal/database/storage"
+	"github.com/adeesh/log-analytics/internal/models"
+)
+
+// TestStoreQueriesRunOnDriver runs the queries whose SQL differs between MySQL and Postgres against the
+// configured database: attribute, label and message filters, time series and heatmap buckets, alert stats,
+// the rollups of pruned alerts, table optimization and the storage report
+func TestStoreQueriesRunOnDriver(t *testing.T) {
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	db, err := database.NewGormDB(&cfg.Database)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := withTimeout(t, pipelineTimeout)
+
+	service := uniqueService("it-store")
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+	var batch []*models.Log
+	for i, latency := range []int{5, 50, 500} {
+		log := newLog(service, models.LogLevelInfo, "stored request")
+		log.Timestamp = start.Add(time.Duration(i) * time.Second)
+		log.ResponseTimeMs = &latency
+		log.Attributes = models.Attributes{"region": "eu"}
+		batch = append(batch, log)
+	}
+	batch[2].Attributes = models.Attributes{"region": "us"}
+
+	logRepo := logs.NewLogRepository(db)
+	if err := logRepo.CreateLogBatch(ctx, batch); err != nil {
+		t.Fatalf("failed to create logs: %v", err)
+	}
+
+	matched, err := logRepo.GetLogs(ctx, &models.LogFilter{Service: &service, Attributes: map[string]string{"region": "eu"}})
+	if err != nil {
+		t.Fatalf("failed to filter logs by attribute: %v", err)
+	}
+	if len(matched) != 2 {
+		t.Errorf("got %d logs with the attribute, want 2", len(matched))
+	}
+
+	end := start.Add(time.Minute)
+	series, err := logRepo.GetLogTimeSeries(ctx, service, nil, start, end, 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to get time series: %v", err)
+	}
+	if len(series) == 0 || series[0].Count != 3 {
+		t.Errorf("got time series %+v, want the 3 logs in the first bucket", series)
+	}
+
+	cells, err := logRepo.GetLatencyHeatmap(ctx, service, nil, start, end, 10*time.Second, []int{10, 100})
+	if err != nil {
+		t.Fatalf("failed to get latency heatmap: %v", err)
+	}
+	if len(cells) != 3 {
+		t.Errorf("got %d heatmap cells, want one per latency bucket", len(cells))
+	}
+
+	ruleRepo := alert_rules.NewAlertRuleRepository(db.GetDB())
+	rule := &models.AlertRule{
+		Name:       "Integration store " + service,
+		Condition:  "COUNT(*)",
+		Threshold:  1,
+		TimeWindow: 5,
+		Severity:   constants.AlertSeverityHigh,
+		Labels:     models.Attributes{"team": service},
+	}
+	if err := ruleRepo.CreateAlertRule(ctx, rule); err != nil {
+		t.Fatalf("failed to create alert rule: %v", err)
+	}
+
+	alertRepo := alerts.NewAlertRepository(db.GetDB())
+	created := time.Now().Add(-time.Hour)
+	for _, message := range []string{"checkout latency above threshold", "checkout errors above threshold"} {
+		resolved := created.Add(time.Minute)
+		alert := &models.Alert{
+			RuleID:     rule.ID,
+			Message:    message,
+			Severity:   constants.AlertSeverityHigh,
+			Value:      2,
+			Status:     constants.AlertStatusResolved,
+			CreatedAt:  created,
+			ResolvedAt: &resolved,
+		}
+		if err := alertRepo.CreateAlert(ctx, alert); err != nil {
+			t.Fatalf("failed to create alert: %v", err)
+		}
+	}
+
+	search := "latency"
+	found, err := alertRepo.GetAlerts(ctx, &models.AlertFilter{Labels: map[string]string{"team": service}, Search: &search})
+	if err != nil {
+		t.Fatalf("failed to filter alerts: %v", err)
+	}
+	if len(found) != 1 {
+		t.Errorf("got %d alerts matching the label and search, want 1", len(found))
+	}
+
+	// Alerts of the other tests are created later
+	until := created.Add(time.Second)
+	stats, err := alertRepo.GetAlertStats(ctx, &created, &until, nil)
+	if err != nil {
+		t.Fatalf("failed to get alert stats: %v", err)
+	}
+	if stats.AvgTimeToResolveSeconds == nil || *stats.AvgTimeToResolveSeconds < 59 || *stats.AvgTimeToResolveSeconds > 61 {
+		t.Errorf("got average time to resolve %v, want about 60 seconds", stats.AvgTimeToResolveSeconds)
+	}
+
+	// Only these alerts were resolved that long ago. Pruning them one at a time adds the second to the rollup
+	// the first created.
+	for range 2 {
+		pruned, err := alertRepo.PruneResolvedAlerts(ctx, created.Add(2*time.Minute), 1)
+		if err != nil {
+			t.Fatalf("failed to prune alerts: %v", err)
+		}
+		if pruned != 1 {
+			t.Fatalf("pruned %d alerts, want 1", pruned)
+		}
+	}
+	stats, err = alertRepo.GetAlertStats(ctx, &created, &until, nil)
+	if err != nil {
+		t.Fatalf("failed to get alert stats: %v", err)
+	}
+	if stats.PrunedAlerts < 2 {
+		t.Errorf("got %d pruned alerts, want at least the 2 pruned", stats.PrunedAlerts)
+	}
+	if err := alertRepo.OptimizeAlertsTable(ctx); err != nil {
+		t.Fatalf("failed to optimize alerts table: %v", err)
+	}
+
+	tables, err := storage.NewStorageRepository(db.GetDB()).GetTableStorage(ctx)
+	if err != nil {
+		t.Fatalf("failed to get table storage: %v", err)
+	}
+	var listed bool
+	for _, table := range tables {
+		listed = listed || table.Table == constants.DefaultLogsTable
+	}
+	if !listed {
+		t.Errorf("storage report %+v doesn't list the logs table", tables)
+	}
+}